@@ -0,0 +1,215 @@
+// Package bench load tests the remote API of a live daemon, to measure the
+// performance regressions between releases on real hardware.
+package bench
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api"
+	"github.com/docker/docker/engine"
+)
+
+// The operations run by each iteration, in order.
+var ops = []string{"create", "start", "list containers", "list images", "stop", "rm"}
+
+// Bench runs iterations of create, start, list, stop and rm of containers
+// against the API at PROTO://ADDR from concurrent workers, and writes the
+// latency percentiles of each operation in microseconds.
+func Bench(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s PROTO://ADDR", job.Name)
+	}
+	parts := strings.SplitN(job.Args[0], "://", 2)
+	if len(parts) != 2 {
+		return job.Errorf("Invalid address %s, use PROTO://ADDR", job.Args[0])
+	}
+	var (
+		b = &bench{
+			client:    newClient(parts[0], parts[1]),
+			image:     job.Getenv("Image"),
+			cmd:       job.GetenvList("Cmd"),
+			latencies: make(map[string][]time.Duration),
+			errors:    make(map[string]int),
+			lastError: make(map[string]string),
+		}
+		concurrency = job.GetenvInt("Concurrency")
+		iterations  = job.GetenvInt("Iterations")
+	)
+	if b.image == "" {
+		b.image = "busybox"
+	}
+	if len(b.cmd) == 0 {
+		b.cmd = []string{"sleep", "600"}
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	if iterations <= 0 {
+		iterations = 1
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				b.iteration()
+			}
+		}()
+	}
+	wg.Wait()
+
+	outs := engine.NewTable("", len(ops))
+	for _, op := range ops {
+		outs.Add(b.report(op))
+	}
+	if _, err := outs.WriteListTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+type bench struct {
+	client *apiClient
+	image  string
+	cmd    []string
+
+	sync.Mutex
+	latencies map[string][]time.Duration
+	errors    map[string]int
+	lastError map[string]string
+}
+
+// iteration runs each operation once on a new container. Once the container
+// is created, it is removed even if an operation failed.
+func (b *bench) iteration() {
+	var created struct {
+		Id string
+	}
+	config := map[string]interface{}{"Image": b.image, "Cmd": b.cmd}
+	if err := b.do("create", "POST", "/containers/create", config, &created); err != nil {
+		return
+	}
+	id := created.Id
+	b.do("start", "POST", "/containers/"+id+"/start", map[string]interface{}{}, nil)
+	b.do("list containers", "GET", "/containers/json", nil, nil)
+	b.do("list images", "GET", "/images/json", nil, nil)
+	b.do("stop", "POST", "/containers/"+id+"/stop?t=0", nil, nil)
+	b.do("rm", "DELETE", "/containers/"+id+"?force=1", nil, nil)
+}
+
+// do runs the request of op and records its latency, or its error.
+func (b *bench) do(op, method, path string, in, out interface{}) error {
+	start := time.Now()
+	err := b.client.call(method, path, in, out)
+	elapsed := time.Since(start)
+	b.Lock()
+	defer b.Unlock()
+	if err != nil {
+		b.errors[op]++
+		b.lastError[op] = err.Error()
+		return err
+	}
+	b.latencies[op] = append(b.latencies[op], elapsed)
+	return nil
+}
+
+func (b *bench) report(op string) *engine.Env {
+	b.Lock()
+	defer b.Unlock()
+	latencies := b.latencies[op]
+	sort.Sort(byDuration(latencies))
+	out := &engine.Env{}
+	out.Set("Op", op)
+	out.SetInt("Count", len(latencies))
+	out.SetInt("Errors", b.errors[op])
+	out.Set("LastError", b.lastError[op])
+	out.SetInt64("P50", percentile(latencies, 50).Nanoseconds()/1000)
+	out.SetInt64("P90", percentile(latencies, 90).Nanoseconds()/1000)
+	out.SetInt64("P99", percentile(latencies, 99).Nanoseconds()/1000)
+	out.SetInt64("Max", percentile(latencies, 100).Nanoseconds()/1000)
+	return out
+}
+
+// percentile returns the p-th percentile of the sorted latencies, by the
+// nearest rank, or 0 if there are none.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+type byDuration []time.Duration
+
+func (d byDuration) Len() int           { return len(d) }
+func (d byDuration) Less(i, j int) bool { return d[i] < d[j] }
+func (d byDuration) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+
+// apiClient calls the API of the daemon listening at proto://addr.
+type apiClient struct {
+	http *http.Client
+}
+
+func newClient(proto, addr string) *apiClient {
+	return &apiClient{
+		http: &http.Client{
+			Transport: &http.Transport{
+				Dial: func(_, _ string) (net.Conn, error) {
+					return net.Dial(proto, addr)
+				},
+			},
+		},
+	}
+}
+
+// call sends in as json to path, and decodes the json response in out if
+// it is not nil.
+func (c *apiClient) call(method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		buf, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(buf)
+	}
+	req, err := http.NewRequest(method, fmt.Sprintf("http://docker/v%s%s", api.APIVERSION, path), body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, bytes.TrimSpace(data))
+	}
+	if out != nil {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
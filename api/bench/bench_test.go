@@ -0,0 +1,72 @@
+package bench
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/engine"
+)
+
+func TestPercentile(t *testing.T) {
+	var latencies []time.Duration
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, time.Duration(i))
+	}
+	for p, expected := range map[int]time.Duration{50: 50, 90: 90, 99: 99, 100: 100, 0: 1} {
+		if d := percentile(latencies, p); d != expected {
+			t.Fatalf("Expected the %dth percentile to be %d, got %d", p, expected, d)
+		}
+	}
+	if d := percentile(latencies[:1], 99); d != 1 {
+		t.Fatalf("Expected the only latency, got %d", d)
+	}
+	if d := percentile(nil, 50); d != 0 {
+		t.Fatalf("Expected 0 without latencies, got %d", d)
+	}
+}
+
+func TestBench(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/containers/create"):
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"Id":"4fa6e0f0c678"}`))
+		case r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/stop"):
+			http.Error(w, "No such container", http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	eng := engine.New()
+	eng.Logging = false
+	if err := eng.Register("api_bench", Bench); err != nil {
+		t.Fatal(err)
+	}
+	job := eng.Job("api_bench", "tcp://"+strings.TrimPrefix(server.URL, "http://"))
+	job.SetenvInt("Concurrency", 2)
+	job.SetenvInt("Iterations", 3)
+	outs, err := job.Stdout.AddListTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := job.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if len(outs.Data) != len(ops) {
+		t.Fatalf("Expected a report for each of %v, got %d", ops, len(outs.Data))
+	}
+	for _, out := range outs.Data {
+		count, errors := 6, 0
+		if out.Get("Op") == "stop" {
+			count, errors = 0, 6
+		}
+		if out.GetInt("Count") != count || out.GetInt("Errors") != errors {
+			t.Fatalf("Expected %d %s and %d errors, got %v", count, out.Get("Op"), errors, out)
+		}
+	}
+}
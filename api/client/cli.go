@@ -3,31 +3,52 @@ package client
 import (
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"os"
 	"reflect"
 	"strings"
+	"sync"
 	"text/template"
+	"time"
 
+	"github.com/docker/docker/pkg/i18n"
 	flag "github.com/docker/docker/pkg/mflag"
 	"github.com/docker/docker/pkg/term"
 	"github.com/docker/docker/registry"
+	"github.com/docker/docker/utils"
 )
 
 // DockerCli结构
 type DockerCli struct {
-	proto      string               // (Client和Server直接的传输类型)协议类型 tcp、unix、fd
-	addr       string               // Docker需要访问host的目标
-	configFile *registry.ConfigFile // for what ?
-	in         io.ReadCloser        // 读和关闭接口
-	out        io.Writer            // 写接口
-	err        io.Writer            // 错误输出接口
-	isTerminal bool                 // 终端相关？
-	terminalFd uintptr              // 文件句柄
-	tlsConfig  *tls.Config          // tls配置
-	scheme     string               // 指示http或者https
+	proto       string               // (Client和Server直接的传输类型)协议类型 tcp、unix、fd
+	addr        string               // Docker需要访问host的目标
+	configFile  *registry.ConfigFile // for what ?
+	config      *ClientConfig        // defaults of the options, from ~/.docker/config.json
+	in          io.ReadCloser        // 读和关闭接口
+	out         io.Writer            // 写接口
+	err         io.Writer            // 错误输出接口
+	isTerminal  bool                 // 终端相关？
+	terminalFd  uintptr              // 文件句柄
+	progressTty bool                 // draw the progress bars in place
+	// connectTimeout and responseTimeout limit the requests to the
+	// daemon, when not 0
+	connectTimeout  time.Duration
+	responseTimeout time.Duration
+	tlsConfig   *tls.Config          // tls配置
+	// transports are the transports of the clients of httpClient, by
+	// response timeout
+	transports     map[time.Duration]*http.Transport
+	transportsLock sync.Mutex
+	scheme      string               // 指示http或者https
+	// catalog translates the messages of the client to the language of
+	// the locale of the user
+	catalog i18n.Catalog
+	// manPage makes the help of the commands a man page
+	manPage bool
 }
 
 // 将v序列化为json
@@ -36,6 +57,14 @@ var funcMap = template.FuncMap{
 		a, _ := json.Marshal(v)
 		return string(a)
 	},
+	// join joins the elements of a list, e.g. the Names of docker ps
+	"join": func(list []interface{}, sep string) string {
+		elems := make([]string, len(list))
+		for i, elem := range list {
+			elems[i] = fmt.Sprint(elem)
+		}
+		return strings.Join(elems, sep)
+	},
 }
 
 func (cli *DockerCli) getMethod(name string) (func(...string) error, bool) {
@@ -66,11 +95,11 @@ func (cli *DockerCli) Cmd(args ...string) error {
 		method, exists := cli.getMethod(args[0])
 		if !exists {
 			// 请求信息的方法不存在则输出help信息
-			fmt.Println("Error: Command not found:", args[0])
+			fmt.Println(cli.T("Error: Command not found:"), args[0])
 			return cli.CmdHelp(args[1:]...)
 		}
 		// 方法存在就调用相应的方法并返回结果
-		return method(args[1:]...)
+		return cli.translateError(method(args[1:]...))
 	}
 	log.Println("no cmd found! just show help info.")
 	// 没有请求信息则输出help信息
@@ -80,13 +109,85 @@ func (cli *DockerCli) Cmd(args ...string) error {
 func (cli *DockerCli) Subcmd(name, signature, description string) *flag.FlagSet {
 	flags := flag.NewFlagSet(name, flag.ContinueOnError)
 	flags.Usage = func() {
-		fmt.Fprintf(cli.err, "\nUsage: docker %s %s\n\n%s\n\n", name, signature, description)
+		if cli.manPage {
+			writeManPage(cli.out, flags, name, signature, description)
+			os.Exit(0)
+		}
+		fmt.Fprintf(cli.err, "\n%s docker %s %s\n\n%s\n\n", cli.T("Usage:"), name, signature, cli.T(description))
+		flags.VisitAll(func(f *flag.Flag) {
+			f.Usage = cli.T(f.Usage)
+		})
 		flags.PrintDefaults()
+		cli.printExamples(cli.err, name)
 		os.Exit(2)
 	}
 	return flags
 }
 
+// T returns the translation of a message of the client in the language of
+// the user, or the message itself when it has none.
+func (cli *DockerCli) T(msg string) string {
+	return cli.catalog.Translate(msg)
+}
+
+// tableHeader translates each column of the header of a table, the columns
+// being separated by tabs.
+func (cli *DockerCli) tableHeader(header string) string {
+	columns := strings.Split(header, "\t")
+	for i, column := range columns {
+		if column != "" {
+			columns[i] = cli.T(column)
+		}
+	}
+	return strings.Join(columns, "\t")
+}
+
+// translateError translates the error of a command when the catalog has a
+// translation of its whole message. The exit status of a StatusError is
+// kept.
+func (cli *DockerCli) translateError(err error) error {
+	if err == nil || cli.catalog == nil {
+		return err
+	}
+	if sterr, ok := err.(*utils.StatusError); ok {
+		return &utils.StatusError{Status: cli.T(sterr.Status), StatusCode: sterr.StatusCode}
+	}
+	if msg := err.Error(); cli.T(msg) != msg {
+		return errors.New(cli.T(msg))
+	}
+	return err
+}
+
+// SetProgress chooses how the progress of pulls, pushes and builds is
+// displayed: "tty" draws progress bars in place, "plain" writes a
+// timestamped line per update and "auto" uses tty when the output is a
+// terminal.
+func (cli *DockerCli) SetProgress(mode string) error {
+	switch mode {
+	case "auto":
+		cli.progressTty = cli.isTerminal
+	case "tty":
+		cli.progressTty = true
+	case "plain":
+		cli.progressTty = false
+	default:
+		return fmt.Errorf("Invalid progress output: %s, expected auto, plain or tty", mode)
+	}
+	return nil
+}
+
+// SetTimeouts sets how long the client waits for the connections to the
+// daemon, and for the headers of its responses. The streams and the waits
+// for containers to stop are not limited by responseTimeout. 0 means no
+// limit.
+func (cli *DockerCli) SetTimeouts(connectTimeout, responseTimeout time.Duration) {
+	cli.transportsLock.Lock()
+	defer cli.transportsLock.Unlock()
+	cli.connectTimeout = connectTimeout
+	cli.responseTimeout = responseTimeout
+	cli.transports = nil
+}
+
 func (cli *DockerCli) LoadConfigFile() (err error) {
 	cli.configFile, err = registry.LoadConfig(os.Getenv("HOME"))
 	if err != nil {
@@ -120,16 +221,27 @@ func NewDockerCli(in io.ReadCloser, out, err io.Writer, proto, addr string, tlsC
 	if err == nil {
 		err = out
 	}
+	config, cerr := LoadClientConfig(ConfigDir())
+	if cerr != nil {
+		fmt.Fprintf(err, "WARNING: %s\n", cerr)
+	}
+	catalog, cerr := i18n.Find(i18n.Locale())
+	if cerr != nil {
+		fmt.Fprintf(err, "WARNING: %s\n", cerr)
+	}
 	// 通过之前的参数处理创建DdockerCli对象。
 	return &DockerCli{
-		proto:      proto, // tcp://host:port, unix:///path/to/socket, fd://* or fd://socketfd
-		addr:       addr,  // host:port, /path/to/socket, socketfd
-		in:         in,
-		out:        out,
-		err:        err,
-		isTerminal: isTerminal,
-		terminalFd: terminalFd,
-		tlsConfig:  tlsConfig,
-		scheme:     scheme, // 协议 http\https
+		proto:       proto, // tcp://host:port, unix:///path/to/socket, fd://* or fd://socketfd
+		addr:        addr,  // host:port, /path/to/socket, socketfd
+		in:          in,
+		out:         out,
+		err:         err,
+		isTerminal:  isTerminal,
+		terminalFd:  terminalFd,
+		progressTty: isTerminal,
+		config:      config,
+		tlsConfig:   tlsConfig,
+		scheme:      scheme, // 协议 http\https
+		catalog:     catalog,
 	}
 }
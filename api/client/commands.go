@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -15,6 +16,7 @@ import (
 	"path"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
@@ -28,11 +30,13 @@ import (
 	"github.com/docker/docker/engine"
 	"github.com/docker/docker/nat"
 	"github.com/docker/docker/opts"
+	"github.com/docker/docker/pkg/certs"
 	"github.com/docker/docker/pkg/log"
 	"github.com/docker/docker/pkg/parsers"
 	"github.com/docker/docker/pkg/parsers/filters"
 	"github.com/docker/docker/pkg/signal"
 	"github.com/docker/docker/pkg/term"
+	"github.com/docker/docker/pkg/timeutils"
 	"github.com/docker/docker/pkg/units"
 	"github.com/docker/docker/registry"
 	"github.com/docker/docker/runconfig"
@@ -44,54 +48,21 @@ const (
 )
 
 func (cli *DockerCli) CmdHelp(args ...string) error {
+	if len(args) > 0 && args[0] == "--man" {
+		return cli.printManPage(args[1:]...)
+	}
 	if len(args) > 0 {
 		method, exists := cli.getMethod(args[0])
 		if !exists {
-			fmt.Fprintf(cli.err, "Error: Command not found: %s\n", args[0])
+			fmt.Fprintf(cli.err, "%s %s\n", cli.T("Error: Command not found:"), args[0])
 		} else {
-			method("--help")
+			method(helpArgs(args)...)
 			return nil
 		}
 	}
-	help := fmt.Sprintf("Usage: docker [OPTIONS] COMMAND [arg...]\n -H=[unix://%s]: tcp://host:port to bind/connect to or unix://path/to/socket to use\n\nA self-sufficient runtime for linux containers.\n\nCommands:\n", api.DEFAULTUNIXSOCKET)
-	for _, command := range [][]string{
-		{"attach", "Attach to a running container"},
-		{"build", "Build an image from a Dockerfile"},
-		{"commit", "Create a new image from a container's changes"},
-		{"cp", "Copy files/folders from a container's filesystem to the host path"},
-		{"diff", "Inspect changes on a container's filesystem"},
-		{"events", "Get real time events from the server"},
-		{"export", "Stream the contents of a container as a tar archive"},
-		{"history", "Show the history of an image"},
-		{"images", "List images"},
-		{"import", "Create a new filesystem image from the contents of a tarball"},
-		{"info", "Display system-wide information"},
-		{"inspect", "Return low-level information on a container"},
-		{"kill", "Kill a running container"},
-		{"load", "Load an image from a tar archive"},
-		{"login", "Register or log in to a Docker registry server"},
-		{"logout", "Log out from a Docker registry server"},
-		{"logs", "Fetch the logs of a container"},
-		{"port", "Lookup the public-facing port that is NAT-ed to PRIVATE_PORT"},
-		{"pause", "Pause all processes within a container"},
-		{"ps", "List containers"},
-		{"pull", "Pull an image or a repository from a Docker registry server"},
-		{"push", "Push an image or a repository to a Docker registry server"},
-		{"restart", "Restart a running container"},
-		{"rm", "Remove one or more containers"},
-		{"rmi", "Remove one or more images"},
-		{"run", "Run a command in a new container"},
-		{"save", "Save an image to a tar archive"},
-		{"search", "Search for an image on the Docker Hub"},
-		{"start", "Start a stopped container"},
-		{"stop", "Stop a running container"},
-		{"tag", "Tag an image into a repository"},
-		{"top", "Lookup the running processes of a container"},
-		{"unpause", "Unpause a paused container"},
-		{"version", "Show the Docker version information"},
-		{"wait", "Block until a container stops, then print its exit code"},
-	} {
-		help += fmt.Sprintf("    %-10.10s%s\n", command[0], command[1])
+	help := fmt.Sprintf(cli.T("Usage: docker [OPTIONS] COMMAND [arg...]\n -H=[unix://%s]: tcp://host:port to bind/connect to or unix://path/to/socket to use\n\nA self-sufficient runtime for linux containers.\n\nCommands:\n"), api.DEFAULTUNIXSOCKET)
+	for _, command := range dockerCommands {
+		help += fmt.Sprintf("    %-10.10s%s\n", command[0], cli.T(command[1]))
 	}
 	fmt.Fprintf(cli.err, "%s\n", help)
 	return nil
@@ -104,6 +75,12 @@ func (cli *DockerCli) CmdBuild(args ...string) error {
 	noCache := cmd.Bool([]string{"#no-cache", "-no-cache"}, false, "Do not use cache when building the image")
 	rm := cmd.Bool([]string{"#rm", "-rm"}, true, "Remove intermediate containers after a successful build")
 	forceRm := cmd.Bool([]string{"-force-rm"}, false, "Always remove intermediate containers, even after unsuccessful builds")
+	squash := cmd.Bool([]string{"-squash"}, false, "Squash the layers of the build into a single layer on top of the FROM image")
+	dockerfileName := cmd.String([]string{"f", "-file"}, "", "Name of the Dockerfile (default is 'PATH/Dockerfile')")
+	flBuildArg := opts.NewListOpts(opts.ValidateEnv)
+	cmd.Var(&flBuildArg, []string{"-build-arg"}, "Set a build-time variable declared with ARG (e.g. KEY=VALUE)")
+	flBuildContext := opts.NewListOpts(opts.ValidateBuildContext)
+	cmd.Var(&flBuildContext, []string{"-build-context"}, "Additional build context for COPY --from=NAME, as NAME=PATH, NAME=URL or NAME=docker-image://IMAGE")
 	if err := cmd.Parse(args); err != nil {
 		return nil
 	}
@@ -113,10 +90,34 @@ func (cli *DockerCli) CmdBuild(args ...string) error {
 	}
 
 	var (
-		context  archive.Archive
-		isRemote bool
-		err      error
+		context     archive.Archive
+		contextSize int64
+		isRemote    bool
+		err         error
+		// the additional contexts from directories are sent within the
+		// build context, the others are fetched by the daemon
+		localContexts  = make(map[string]string)
+		remoteContexts = make(map[string]string)
 	)
+	for _, val := range flBuildContext.GetAll() {
+		name, source, _ := utils.ParseBuildContext(val)
+		if _, exists := localContexts[name]; exists {
+			return fmt.Errorf("The build context %s is given more than once", name)
+		}
+		if _, exists := remoteContexts[name]; exists {
+			return fmt.Errorf("The build context %s is given more than once", name)
+		}
+		if strings.HasPrefix(source, utils.BuildContextImagePrefix) || utils.IsURL(source) || utils.IsGIT(source) {
+			remoteContexts[name] = source
+			continue
+		}
+		if fi, err := os.Stat(source); err != nil {
+			return err
+		} else if !fi.IsDir() {
+			return fmt.Errorf("The build context %s is not a directory: %s", name, source)
+		}
+		localContexts[name] = source
+	}
 
 	_, err = exec.LookPath("git")
 	hasGit := err == nil
@@ -129,6 +130,9 @@ func (cli *DockerCli) CmdBuild(args ...string) error {
 			return fmt.Errorf("failed to peek context header from STDIN: %v", err)
 		}
 		if !archive.IsArchive(magic) {
+			if *dockerfileName != "" {
+				return fmt.Errorf("The Dockerfile is read from STDIN, -f cannot be used")
+			}
 			dockerfile, err := ioutil.ReadAll(buf)
 			if err != nil {
 				return fmt.Errorf("failed to read Dockerfile from STDIN: %v", err)
@@ -138,6 +142,11 @@ func (cli *DockerCli) CmdBuild(args ...string) error {
 			context = ioutil.NopCloser(buf)
 		}
 	} else if utils.IsURL(cmd.Arg(0)) && (!utils.IsGIT(cmd.Arg(0)) || !hasGit) {
+		// the daemon builds the Dockerfile at the URL, or the one at the
+		// root of the repository, unless -f names another in it
+		if *dockerfileName != "" && !utils.IsGIT(cmd.Arg(0)) {
+			return fmt.Errorf("The Dockerfile is read from %s, -f cannot be used", cmd.Arg(0))
+		}
 		isRemote = true
 	} else {
 		root := cmd.Arg(0)
@@ -161,27 +170,60 @@ func (cli *DockerCli) CmdBuild(args ...string) error {
 			return err
 		}
 		filename := path.Join(root, "Dockerfile")
+		if *dockerfileName != "" {
+			// the Dockerfile is given relative to the current directory, or
+			// to the root of the repository cloned in a temporary directory,
+			// but sent relative to the root of the context
+			filename = *dockerfileName
+			if utils.IsGIT(cmd.Arg(0)) {
+				filename = filepath.Join(root, filename)
+			}
+			if filename, err = filepath.Abs(filename); err != nil {
+				return err
+			}
+			absRoot, err := filepath.Abs(root)
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(absRoot, filename)
+			if err != nil {
+				return err
+			}
+			if rel == ".." || strings.HasPrefix(rel, "../") {
+				return fmt.Errorf("The Dockerfile (%s) must be within the build context (%s)", *dockerfileName, cmd.Arg(0))
+			}
+			*dockerfileName = rel
+		}
 		if _, err = os.Stat(filename); os.IsNotExist(err) {
+			if *dockerfileName != "" {
+				return fmt.Errorf("Cannot locate Dockerfile: %s", filename)
+			}
 			return fmt.Errorf("no Dockerfile found in %s", cmd.Arg(0))
 		}
 		var excludes []string
-		ignore, err := ioutil.ReadFile(path.Join(root, ".dockerignore"))
-		if err != nil && !os.IsNotExist(err) {
-			return fmt.Errorf("Error reading .dockerignore: '%s'", err)
-		}
-		for _, pattern := range strings.Split(string(ignore), "\n") {
-			ok, err := filepath.Match(pattern, "Dockerfile")
+		if ignore, err := os.Open(path.Join(root, ".dockerignore")); err == nil {
+			excludes, err = utils.ReadDockerignore(ignore)
+			ignore.Close()
 			if err != nil {
-				return fmt.Errorf("Bad .dockerignore pattern: '%s', error: %s", pattern, err)
+				return err
 			}
-			if ok {
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("Error reading .dockerignore: '%s'", err)
+		}
+		dockerfileRel := "Dockerfile"
+		if *dockerfileName != "" {
+			dockerfileRel = *dockerfileName
+		}
+		for _, pattern := range excludes {
+			if ok, _ := filepath.Match(pattern, dockerfileRel); ok {
 				return fmt.Errorf("Dockerfile was excluded by .dockerignore pattern '%s'", pattern)
 			}
-			excludes = append(excludes, pattern)
 		}
 		if err = utils.ValidateContextDirectory(root, excludes); err != nil {
 			return fmt.Errorf("Error checking context is accessible: '%s'. Please check permissions and try again.", err)
 		}
+		// the excluded files are never sent, so they do not count either
+		contextSize = utils.ContextTarSize(root, excludes)
 		options := &archive.TarOptions{
 			Compression: archive.Uncompressed,
 			Excludes:    excludes,
@@ -191,12 +233,21 @@ func (cli *DockerCli) CmdBuild(args ...string) error {
 			return err
 		}
 	}
+	if len(localContexts) > 0 {
+		if isRemote {
+			return fmt.Errorf("The build contexts from directories can not be used with a remote build context")
+		}
+		for _, dir := range localContexts {
+			contextSize += utils.ContextTarSize(dir, nil)
+		}
+		context = addBuildContexts(context, localContexts)
+	}
 	var body io.Reader
 	// Setup an upload progress bar
 	// FIXME: ProgressReader shouldn't be this annoying to use
 	if context != nil {
 		sf := utils.NewStreamFormatter(false)
-		body = utils.ProgressReader(context, 0, cli.err, sf, true, "", "Sending build context to Docker daemon")
+		body = utils.ProgressReader(context, int(contextSize), cli.err, sf, true, "", "Sending build context to Docker daemon")
 	}
 	// Send the build context
 	v := &url.Values{}
@@ -230,10 +281,43 @@ func (cli *DockerCli) CmdBuild(args ...string) error {
 		v.Set("forcerm", "1")
 	}
 
+	if *squash {
+		v.Set("squash", "1")
+	}
+
+	if *dockerfileName != "" {
+		v.Set("dockerfile", *dockerfileName)
+	}
+
+	if buildArgs := flBuildArg.GetAll(); len(buildArgs) > 0 {
+		values := make(map[string]string, len(buildArgs))
+		for _, arg := range buildArgs {
+			parts := strings.SplitN(arg, "=", 2)
+			values[parts[0]] = parts[1]
+		}
+		buf, err := json.Marshal(values)
+		if err != nil {
+			return err
+		}
+		v.Set("buildargs", string(buf))
+	}
+
+	if len(remoteContexts) > 0 {
+		buf, err := json.Marshal(remoteContexts)
+		if err != nil {
+			return err
+		}
+		v.Set("buildcontexts", string(buf))
+	}
+
 	cli.LoadConfigFile()
 
 	headers := http.Header(make(map[string][]string))
-	buf, err := json.Marshal(cli.configFile)
+	configs, err := cli.credentialsStore().GetAll()
+	if err != nil {
+		return err
+	}
+	buf, err := json.Marshal(&registry.ConfigFile{Configs: configs})
 	if err != nil {
 		return err
 	}
@@ -268,7 +352,7 @@ func (cli *DockerCli) CmdLogin(args ...string) error {
 	}
 	serverAddress := registry.IndexServerAddress()
 	if len(cmd.Args()) > 0 {
-		serverAddress = cmd.Arg(0)
+		serverAddress = registry.NormalizeServerAddress(cmd.Arg(0))
 	}
 
 	promptDefault := func(prompt string, configDefault string) {
@@ -290,9 +374,10 @@ func (cli *DockerCli) CmdLogin(args ...string) error {
 	}
 
 	cli.LoadConfigFile()
-	authconfig, ok := cli.configFile.Configs[serverAddress]
-	if !ok {
-		authconfig = registry.AuthConfig{}
+	store := cli.credentialsStore()
+	authconfig, err := store.Get(serverAddress)
+	if err != nil {
+		return err
 	}
 
 	if username == "" {
@@ -332,12 +417,10 @@ func (cli *DockerCli) CmdLogin(args ...string) error {
 	authconfig.Password = password
 	authconfig.Email = email
 	authconfig.ServerAddress = serverAddress
-	cli.configFile.Configs[serverAddress] = authconfig
 
-	stream, statusCode, err := cli.call("POST", "/auth", cli.configFile.Configs[serverAddress], false)
+	stream, statusCode, err := cli.call("POST", "/auth", authconfig, false)
 	if statusCode == 401 {
-		delete(cli.configFile.Configs, serverAddress)
-		registry.SaveConfig(cli.configFile)
+		store.Erase(serverAddress)
 		return err
 	}
 	if err != nil {
@@ -346,10 +429,11 @@ func (cli *DockerCli) CmdLogin(args ...string) error {
 	var out2 engine.Env
 	err = out2.Decode(stream)
 	if err != nil {
-		cli.configFile, _ = registry.LoadConfig(os.Getenv("HOME"))
 		return err
 	}
-	registry.SaveConfig(cli.configFile)
+	if err := store.Store(authconfig); err != nil {
+		return fmt.Errorf("Failed to save the credentials: %v", err)
+	}
 	if out2.Get("Status") != "" {
 		fmt.Fprintf(cli.out, "%s\n", out2.Get("Status"))
 	}
@@ -365,18 +449,24 @@ func (cli *DockerCli) CmdLogout(args ...string) error {
 	}
 	serverAddress := registry.IndexServerAddress()
 	if len(cmd.Args()) > 0 {
-		serverAddress = cmd.Arg(0)
+		serverAddress = registry.NormalizeServerAddress(cmd.Arg(0))
 	}
 
 	cli.LoadConfigFile()
-	if _, ok := cli.configFile.Configs[serverAddress]; !ok {
+	// the credentials may be saved under a url of the registry
+	var found bool
+	for key := range cli.configFile.Configs {
+		if registry.NormalizeServerAddress(key) == serverAddress {
+			found = true
+		}
+	}
+	if !found {
 		fmt.Fprintf(cli.out, "Not logged in to %s\n", serverAddress)
 	} else {
 		fmt.Fprintf(cli.out, "Remove login credentials for %s\n", serverAddress)
-		delete(cli.configFile.Configs, serverAddress)
 
-		if err := registry.SaveConfig(cli.configFile); err != nil {
-			return fmt.Errorf("Failed to save docker config: %v", err)
+		if err := cli.credentialsStore().Erase(serverAddress); err != nil {
+			return fmt.Errorf("Failed to remove the credentials: %v", err)
 		}
 	}
 	return nil
@@ -408,6 +498,7 @@ func (cli *DockerCli) CmdWait(args ...string) error {
 // 'docker version': show version information
 func (cli *DockerCli) CmdVersion(args ...string) error {
 	cmd := cli.Subcmd("version", "", "Show the Docker version information.")
+	asJson := cmd.Bool([]string{"-json"}, false, "Output the versions as JSON")
 	if err := cmd.Parse(args); err != nil {
 		return nil
 	}
@@ -416,44 +507,189 @@ func (cli *DockerCli) CmdVersion(args ...string) error {
 		cmd.Usage()
 		return nil
 	}
-	if dockerversion.VERSION != "" {
-		fmt.Fprintf(cli.out, "Client version: %s\n", dockerversion.VERSION)
+	client := &versionInfo{
+		Version:      dockerversion.VERSION,
+		ApiVersion:   string(api.APIVERSION),
+		GoVersion:    runtime.Version(),
+		GitCommit:    dockerversion.GITCOMMIT,
+		Os:           runtime.GOOS,
+		Arch:         runtime.GOARCH,
+		Experimental: dockerversion.EXPERIMENTAL == "true",
+	}
+	server, serverErr := cli.serverVersion()
+
+	if *asJson {
+		b, err := json.MarshalIndent(map[string]*versionInfo{"Client": client, "Server": server}, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cli.out, "%s\n", b)
+		return serverErr
+	}
+
+	if server == nil {
+		server = &versionInfo{}
+	}
+	w := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
+	fmt.Fprintln(w, cli.tableHeader("\tCLIENT\tSERVER"))
+	fmt.Fprintf(w, "Version:\t%s\t%s\n", client.Version, server.Version)
+	fmt.Fprintf(w, "API version:\t%s\t%s\n", client.ApiVersion, server.ApiVersion)
+	fmt.Fprintf(w, "Go version:\t%s\t%s\n", client.GoVersion, server.GoVersion)
+	fmt.Fprintf(w, "Git commit:\t%s\t%s\n", client.GitCommit, server.GitCommit)
+	fmt.Fprintf(w, "OS/Arch:\t%s/%s\t%s\n", client.Os, client.Arch, server.osArch())
+	fmt.Fprintf(w, "Kernel version:\t\t%s\n", server.KernelVersion)
+	fmt.Fprintf(w, "Experimental:\t%t\t%s\n", client.Experimental, server.experimental())
+	w.Flush()
+	if serverErr != nil {
+		return serverErr
+	}
+	if server.ApiVersion != client.ApiVersion {
+		fmt.Fprintf(cli.err, "WARNING: the client (API %s) and the server (API %s) do not use the same API version\n", client.ApiVersion, server.ApiVersion)
+	}
+	return nil
+}
+
+// versionInfo is the version of the client or of the server shown by
+// docker version.
+type versionInfo struct {
+	Version       string
+	ApiVersion    string
+	GoVersion     string
+	GitCommit     string
+	Os            string
+	Arch          string
+	KernelVersion string `json:",omitempty"`
+	Experimental  bool
+}
+
+func (v *versionInfo) osArch() string {
+	if v.Os == "" {
+		return ""
 	}
-	fmt.Fprintf(cli.out, "Client API version: %s\n", api.APIVERSION)
-	fmt.Fprintf(cli.out, "Go version (client): %s\n", runtime.Version())
-	if dockerversion.GITCOMMIT != "" {
-		fmt.Fprintf(cli.out, "Git commit (client): %s\n", dockerversion.GITCOMMIT)
+	return v.Os + "/" + v.Arch
+}
+
+func (v *versionInfo) experimental() string {
+	if v.Version == "" {
+		return ""
 	}
-	fmt.Fprintf(cli.out, "OS/Arch (client): %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	return strconv.FormatBool(v.Experimental)
+}
 
-	body, _, err := readBody(cli.call("GET", "/version", nil, false))
+// serverVersion returns the version of the server. A server older than the
+// client refuses its API version, so the version is then asked again
+// without one.
+func (cli *DockerCli) serverVersion() (*versionInfo, error) {
+	body, statusCode, err := readBody(cli.call("GET", "/version", nil, false))
+	if statusCode == http.StatusNotFound {
+		body, err = cli.getUnversioned("/version")
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	out := engine.NewOutput()
 	remoteVersion, err := out.AddEnv()
 	if err != nil {
 		log.Errorf("Error reading remote version: %s", err)
-		return err
+		return nil, err
 	}
 	if _, err := out.Write(body); err != nil {
 		log.Errorf("Error reading remote version: %s", err)
+		return nil, err
+	}
+	out.Close()
+	return &versionInfo{
+		Version:       remoteVersion.Get("Version"),
+		ApiVersion:    remoteVersion.Get("ApiVersion"),
+		GoVersion:     remoteVersion.Get("GoVersion"),
+		GitCommit:     remoteVersion.Get("GitCommit"),
+		Os:            remoteVersion.Get("Os"),
+		Arch:          remoteVersion.Get("Arch"),
+		KernelVersion: remoteVersion.Get("KernelVersion"),
+		Experimental:  remoteVersion.GetBool("Experimental"),
+	}, nil
+}
+
+// getUnversioned GETs path without the API version of the client, which
+// the servers answer with their own.
+func (cli *DockerCli) getUnversioned(path string) ([]byte, error) {
+	req, err := http.NewRequest("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	cli.addConfigHeaders(req)
+	req.Header.Set("User-Agent", "Docker-Client/"+dockerversion.VERSION)
+	req.URL.Host = cli.host()
+	req.URL.Scheme = cli.scheme
+	resp, err := cli.HTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("Error response from daemon: %s", bytes.TrimSpace(body))
+	}
+	return body, nil
+}
+
+// checkConfig displays the state of the kernel features needed by the
+// containers, failing when a required one is missing.
+func (cli *DockerCli) checkConfig() error {
+	body, _, err := readBody(cli.call("GET", "/info/check", nil, false))
+	if err != nil {
+		return err
+	}
+	out := engine.NewOutput()
+	remoteCheck, err := out.AddEnv()
+	if err != nil {
+		return err
+	}
+	if _, err := out.Write(body); err != nil {
 		return err
 	}
 	out.Close()
-	fmt.Fprintf(cli.out, "Server version: %s\n", remoteVersion.Get("Version"))
-	if apiVersion := remoteVersion.Get("ApiVersion"); apiVersion != "" {
-		fmt.Fprintf(cli.out, "Server API version: %s\n", apiVersion)
+
+	var checks []struct {
+		Name     string
+		Required bool
+		Status   string
+	}
+	if err := remoteCheck.GetJson("Checks", &checks); err != nil {
+		return err
+	}
+	fmt.Fprintf(cli.out, "Kernel config: %s\n", remoteCheck.Get("KernelConfig"))
+	missing := 0
+	for _, required := range []bool{true, false} {
+		if required {
+			fmt.Fprintf(cli.out, "Required:\n")
+		} else {
+			fmt.Fprintf(cli.out, "Optional:\n")
+		}
+		for _, check := range checks {
+			if check.Required != required {
+				continue
+			}
+			fmt.Fprintf(cli.out, " %s: %s\n", check.Name, check.Status)
+			if required && check.Status == "missing" {
+				missing++
+			}
+		}
+	}
+	if missing > 0 {
+		return fmt.Errorf("%d required kernel features are missing", missing)
 	}
-	fmt.Fprintf(cli.out, "Go version (server): %s\n", remoteVersion.Get("GoVersion"))
-	fmt.Fprintf(cli.out, "Git commit (server): %s\n", remoteVersion.Get("GitCommit"))
 	return nil
 }
 
 // 'docker info': display system-wide information.
 func (cli *DockerCli) CmdInfo(args ...string) error {
 	cmd := cli.Subcmd("info", "", "Display system-wide information")
+	check := cmd.Bool([]string{"-check"}, false, "Check the kernel features needed by the containers instead")
 	if err := cmd.Parse(args); err != nil {
 		return nil
 	}
@@ -461,6 +697,9 @@ func (cli *DockerCli) CmdInfo(args ...string) error {
 		cmd.Usage()
 		return nil
 	}
+	if *check {
+		return cli.checkConfig()
+	}
 
 	body, _, err := readBody(cli.call("GET", "/info", nil, false))
 	if err != nil {
@@ -508,6 +747,26 @@ func (cli *DockerCli) CmdInfo(args ...string) error {
 		}
 	}
 
+	if mirrors := remoteInfo.GetList("RegistryMirrors"); len(mirrors) != 0 {
+		fmt.Fprintf(cli.out, "Registry Mirrors:\n")
+		for _, mirror := range mirrors {
+			fmt.Fprintf(cli.out, " %s\n", mirror)
+		}
+	}
+	if remoteInfo.Exists("Usage") {
+		var usage struct {
+			Since             string
+			ContainersStarted int64
+			ImagesPulled      int64
+			BuildMinutes      int64
+		}
+		if err := remoteInfo.GetJson("Usage", &usage); err == nil {
+			fmt.Fprintf(cli.out, "Usage since %s:\n", usage.Since)
+			fmt.Fprintf(cli.out, " Containers Started: %d\n", usage.ContainersStarted)
+			fmt.Fprintf(cli.out, " Images Pulled: %d\n", usage.ImagesPulled)
+			fmt.Fprintf(cli.out, " Build Minutes: %d\n", usage.BuildMinutes)
+		}
+	}
 	if len(remoteInfo.GetList("IndexServerAddress")) != 0 {
 		cli.LoadConfigFile()
 		u := cli.configFile.Configs[remoteInfo.Get("IndexServerAddress")].Username
@@ -516,6 +775,13 @@ func (cli *DockerCli) CmdInfo(args ...string) error {
 			fmt.Fprintf(cli.out, "Registry: %v\n", remoteInfo.GetList("IndexServerAddress"))
 		}
 	}
+	if remoteInfo.Exists("Warnings") {
+		for _, warning := range remoteInfo.GetList("Warnings") {
+			fmt.Fprintf(cli.err, "WARNING: %s\n", warning)
+		}
+		return nil
+	}
+	// the daemons without a list of warnings only tell about these ones
 	if !remoteInfo.GetBool("MemoryLimit") {
 		fmt.Fprintf(cli.err, "WARNING: No memory limit support\n")
 	}
@@ -525,6 +791,9 @@ func (cli *DockerCli) CmdInfo(args ...string) error {
 	if !remoteInfo.GetBool("IPv4Forwarding") {
 		fmt.Fprintf(cli.err, "WARNING: IPv4 forwarding is disabled.\n")
 	}
+	if remoteInfo.GetBool("Draining") {
+		fmt.Fprintf(cli.err, "WARNING: The daemon is draining and will not create or start containers.\n")
+	}
 	return nil
 }
 
@@ -722,6 +991,49 @@ func (cli *DockerCli) CmdUnpause(args ...string) error {
 	return encounteredError
 }
 
+func (cli *DockerCli) CmdUpdate(args ...string) error {
+	cmd := cli.Subcmd("update", "[OPTIONS] CONTAINER [CONTAINER...]", "Update the resource limits of one or more containers, without restarting them")
+	flMemory := cmd.String([]string{"m", "-memory"}, "", "Memory limit (format: <number><optional unit>, where unit = b, k, m or g)")
+	flCpuShares := cmd.Int64([]string{"c", "-cpu-shares"}, 0, "CPU shares (relative weight)")
+	flCpuset := cmd.String([]string{"-cpuset"}, "", "CPUs in which to allow execution (0-3, 0,1)")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() < 1 {
+		cmd.Usage()
+		return nil
+	}
+
+	config := engine.Env{}
+	if *flMemory != "" {
+		memory, err := units.RAMInBytes(*flMemory)
+		if err != nil {
+			return err
+		}
+		config.SetInt64("Memory", memory)
+	}
+	if *flCpuShares != 0 {
+		config.SetInt64("CpuShares", *flCpuShares)
+	}
+	if *flCpuset != "" {
+		config.Set("Cpuset", *flCpuset)
+	}
+	if len(config) == 0 {
+		return fmt.Errorf("Error: you must provide one or more flags when using this command.")
+	}
+
+	var encounteredError error
+	for _, name := range cmd.Args() {
+		if _, _, err := readBody(cli.call("POST", "/containers/"+name+"/update", config, false)); err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			encounteredError = fmt.Errorf("Error: failed to update one or more containers")
+		} else {
+			fmt.Fprintf(cli.out, "%s\n", name)
+		}
+	}
+	return encounteredError
+}
+
 func (cli *DockerCli) CmdPause(args ...string) error {
 	cmd := cli.Subcmd("pause", "CONTAINER", "Pause all processes within a container")
 	if err := cmd.Parse(args); err != nil {
@@ -759,10 +1071,8 @@ func (cli *DockerCli) CmdInspect(args ...string) error {
 	var tmpl *template.Template
 	if *tmplStr != "" {
 		var err error
-		if tmpl, err = template.New("").Funcs(funcMap).Parse(*tmplStr); err != nil {
-			fmt.Fprintf(cli.err, "Template parsing error: %v\n", err)
-			return &utils.StatusError{StatusCode: 64,
-				Status: "Template parsing error: " + err.Error()}
+		if tmpl, err = cli.parseFormat(*tmplStr); err != nil {
+			return err
 		}
 	}
 
@@ -799,10 +1109,11 @@ func (cli *DockerCli) CmdInspect(args ...string) error {
 				status = 1
 				continue
 			}
-			if err := tmpl.Execute(cli.out, value); err != nil {
-				return err
+			if err := executeFormat(cli.out, tmpl, value); err != nil {
+				fmt.Fprintf(cli.err, "%s: %s\n", name, err)
+				status = 1
+				continue
 			}
-			cli.out.Write([]byte{'\n'})
 		}
 		indented.WriteString(",")
 	}
@@ -825,6 +1136,79 @@ func (cli *DockerCli) CmdInspect(args ...string) error {
 	return nil
 }
 
+func (cli *DockerCli) CmdTlsconfig(args ...string) error {
+	certPath := os.Getenv("DOCKER_CERT_PATH")
+	if certPath == "" {
+		certPath = filepath.Join(os.Getenv("HOME"), ".docker")
+	}
+	cmd := cli.Subcmd("tlsconfig", "[OPTIONS]", "Generate a CA, a server certificate for the daemon and a client certificate signed by it")
+	flDir := cmd.String([]string{"d", "-dir"}, certPath, "Directory to write the certificates and keys to")
+	flDays := cmd.Int([]string{"-days"}, 1095, "Number of days the certificates are valid")
+	flForce := cmd.Bool([]string{"f", "-force"}, false, "Overwrite existing certificates and keys")
+	flHosts := opts.NewListOpts(nil)
+	cmd.Var(&flHosts, []string{"-host"}, "Host name or IP address the daemon is reached at (default: the -H host, localhost and 127.0.0.1)")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 0 || *flDays <= 0 {
+		cmd.Usage()
+		return nil
+	}
+
+	hosts := flHosts.GetAll()
+	if len(hosts) == 0 {
+		if cli.proto == "tcp" {
+			if host, _, err := net.SplitHostPort(cli.addr); err == nil && host != "" && host != "0.0.0.0" {
+				hosts = append(hosts, host)
+			}
+		}
+		hosts = append(hosts, "localhost", "127.0.0.1")
+	}
+
+	files := []string{"ca.pem", "ca-key.pem", "server-cert.pem", "server-key.pem", "cert.pem", "key.pem"}
+	if !*flForce {
+		for _, file := range files {
+			if _, err := os.Stat(filepath.Join(*flDir, file)); err == nil {
+				return fmt.Errorf("Error: %s already exists, use --force to overwrite it", filepath.Join(*flDir, file))
+			}
+		}
+	}
+
+	validity := time.Duration(*flDays) * 24 * time.Hour
+	ca, err := certs.NewCA("Docker CA", validity)
+	if err != nil {
+		return err
+	}
+	server, err := certs.NewServer(ca, hosts[0], hosts, validity)
+	if err != nil {
+		return err
+	}
+	client, err := certs.NewClient(ca, "client", validity)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(*flDir, 0700); err != nil {
+		return err
+	}
+	for i, content := range [][]byte{ca.CertPEM(), ca.KeyPEM(), server.CertPEM(), server.KeyPEM(), client.CertPEM(), client.KeyPEM()} {
+		// the odd files are the private keys
+		mode := os.FileMode(0644)
+		if i%2 == 1 {
+			mode = 0600
+		}
+		if err := ioutil.WriteFile(filepath.Join(*flDir, files[i]), content, mode); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(cli.out, "Generated the certificates in %s for %s\n\n", *flDir, strings.Join(hosts, ", "))
+	fmt.Fprintf(cli.out, "Start the daemon with:\n    docker -d --tlsverify --tlscacert=%s --tlscert=%s --tlskey=%s -H=0.0.0.0:2376\n",
+		filepath.Join(*flDir, "ca.pem"), filepath.Join(*flDir, "server-cert.pem"), filepath.Join(*flDir, "server-key.pem"))
+	fmt.Fprintf(cli.out, "Copy ca.pem, cert.pem and key.pem to the ~/.docker directory of the clients and run:\n    docker --tlsverify -H=tcp://%s:2376 version\n", hosts[0])
+	return nil
+}
+
 func (cli *DockerCli) CmdTop(args ...string) error {
 	cmd := cli.Subcmd("top", "CONTAINER [ps OPTIONS]", "Display the running processes of a container")
 	if err := cmd.Parse(args); err != nil {
@@ -861,48 +1245,92 @@ func (cli *DockerCli) CmdTop(args ...string) error {
 }
 
 func (cli *DockerCli) CmdPort(args ...string) error {
-	cmd := cli.Subcmd("port", "CONTAINER PRIVATE_PORT", "Lookup the public-facing port that is NAT-ed to PRIVATE_PORT")
+	cmd := cli.Subcmd("port", "[OPTIONS] CONTAINER [PRIVATE_PORT[/PROTO]]", "List the port mappings of a container, lookup the public-facing port that is NAT-ed to PRIVATE_PORT,\nor publish and unpublish ports of a running container")
+	var (
+		flPublish   = opts.NewListOpts(nil)
+		flUnpublish = opts.NewListOpts(nil)
+		flProto     = cmd.String([]string{"-proto"}, "", "Only list the ports of this protocol, tcp or udp")
+	)
+	cmd.Var(&flPublish, []string{"p", "-publish"}, fmt.Sprintf("Publish a port of the running container to the host\nformat: %s", nat.PortSpecTemplateFormat))
+	cmd.Var(&flUnpublish, []string{"-unpublish"}, "Stop publishing a port of the running container (e.g. 80/tcp)")
 	if err := cmd.Parse(args); err != nil {
 		return nil
 	}
-	if cmd.NArg() != 2 {
+	if flPublish.Len() > 0 || flUnpublish.Len() > 0 {
+		if cmd.NArg() != 1 {
+			cmd.Usage()
+			return nil
+		}
+		return cli.updatePorts(cmd.Arg(0), flPublish.GetAll(), flUnpublish.GetAll())
+	}
+	if cmd.NArg() != 1 && cmd.NArg() != 2 {
 		cmd.Usage()
 		return nil
 	}
 
-	var (
-		port  = cmd.Arg(1)
-		proto = "tcp"
-		parts = strings.SplitN(port, "/", 2)
-	)
+	v := url.Values{}
+	if *flProto != "" {
+		v.Set("proto", *flProto)
+	}
+	if cmd.NArg() == 2 {
+		v.Set("port", cmd.Arg(1))
+	}
+	body, _, err := readBody(cli.call("GET", "/containers/"+cmd.Arg(0)+"/ports?"+v.Encode(), nil, false))
+	if err != nil {
+		return err
+	}
+	outs := engine.NewTable("", 0)
+	if _, err := outs.ReadListFrom(body); err != nil {
+		return err
+	}
 
-	if len(parts) == 2 && len(parts[1]) != 0 {
-		port = parts[0]
-		proto = parts[1]
+	published := false
+	for _, out := range outs.Data {
+		if out.GetInt("PublicPort") == 0 {
+			// exposed, but not published
+			continue
+		}
+		published = true
+		if cmd.NArg() == 2 {
+			fmt.Fprintf(cli.out, "%s:%d\n", out.Get("IP"), out.GetInt("PublicPort"))
+		} else {
+			fmt.Fprintf(cli.out, "%d/%s -> %s:%d\n", out.GetInt("PrivatePort"), out.Get("Type"), out.Get("IP"), out.GetInt("PublicPort"))
+		}
+	}
+	if !published && cmd.NArg() == 2 {
+		return fmt.Errorf("Error: No public port '%s' published for %s", cmd.Arg(1), cmd.Arg(0))
 	}
+	return nil
+}
 
-	steam, _, err := cli.call("GET", "/containers/"+cmd.Arg(0)+"/json", nil, false)
+func (cli *DockerCli) updatePorts(name string, publish, unpublish []string) error {
+	config := engine.Env{}
+	config.SetList("Publish", publish)
+	config.SetList("Unpublish", unpublish)
+	body, _, err := readBody(cli.call("POST", "/containers/"+name+"/ports", config, false))
 	if err != nil {
 		return err
 	}
 
 	env := engine.Env{}
-	if err := env.Decode(steam); err != nil {
+	if err := env.Decode(bytes.NewReader(body)); err != nil {
 		return err
 	}
 	ports := nat.PortMap{}
-	if err := env.GetSubEnv("NetworkSettings").GetJson("Ports", &ports); err != nil {
+	if err := env.GetJson("Ports", &ports); err != nil {
 		return err
 	}
-
-	if frontends, exists := ports[nat.Port(port+"/"+proto)]; exists && frontends != nil {
-		for _, frontend := range frontends {
-			fmt.Fprintf(cli.out, "%s:%s\n", frontend.HostIp, frontend.HostPort)
+	var keys []string
+	for port := range ports {
+		keys = append(keys, string(port))
+	}
+	sort.Strings(keys)
+	for _, port := range keys {
+		for _, frontend := range ports[nat.Port(port)] {
+			fmt.Fprintf(cli.out, "%s -> %s:%s\n", port, frontend.HostIp, frontend.HostPort)
 		}
-		return nil
 	}
-
-	return fmt.Errorf("Error: No public port '%s' published for %s", cmd.Arg(1), cmd.Arg(0))
+	return nil
 }
 
 // 'docker rmi IMAGE' removes all images with the name IMAGE
@@ -957,6 +1385,7 @@ func (cli *DockerCli) CmdHistory(args ...string) error {
 	cmd := cli.Subcmd("history", "[OPTIONS] IMAGE", "Show the history of an image")
 	quiet := cmd.Bool([]string{"q", "-quiet"}, false, "Only show numeric IDs")
 	noTrunc := cmd.Bool([]string{"#notrunc", "-no-trunc"}, false, "Don't truncate output")
+	format := cmd.String([]string{"-format"}, "table", "Output format: table, or json for all the fields of each layer, never truncated")
 
 	if err := cmd.Parse(args); err != nil {
 		return nil
@@ -965,12 +1394,24 @@ func (cli *DockerCli) CmdHistory(args ...string) error {
 		cmd.Usage()
 		return nil
 	}
+	if *format != "table" && *format != "json" {
+		return fmt.Errorf("Invalid format %s: use table or json", *format)
+	}
 
 	body, _, err := readBody(cli.call("GET", "/images/"+cmd.Arg(0)+"/history", nil, false))
 	if err != nil {
 		return err
 	}
 
+	if *format == "json" {
+		indented := new(bytes.Buffer)
+		if err := json.Indent(indented, body, "", "    "); err != nil {
+			return err
+		}
+		fmt.Fprintf(cli.out, "%s\n", indented)
+		return nil
+	}
+
 	outs := engine.NewTable("Created", 0)
 	if _, err := outs.ReadListFrom(body); err != nil {
 		return err
@@ -978,7 +1419,7 @@ func (cli *DockerCli) CmdHistory(args ...string) error {
 
 	w := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
 	if !*quiet {
-		fmt.Fprintln(w, "IMAGE\tCREATED\tCREATED BY\tSIZE")
+		fmt.Fprintln(w, cli.tableHeader("IMAGE\tCREATED\tCREATED BY\tSIZE"))
 	}
 
 	for _, out := range outs.Data {
@@ -990,7 +1431,7 @@ func (cli *DockerCli) CmdHistory(args ...string) error {
 				fmt.Fprintf(w, "%s\t", utils.TruncateID(outID))
 			}
 
-			fmt.Fprintf(w, "%s ago\t", units.HumanDuration(time.Now().UTC().Sub(time.Unix(out.GetInt64("Created"), 0))))
+			fmt.Fprintf(w, "%s ago\t", units.HumanDuration(time.Now().UTC().Sub(createdTime(out))))
 
 			if *noTrunc {
 				fmt.Fprintf(w, "%s\t", out.Get("CreatedBy"))
@@ -1075,7 +1516,8 @@ func (cli *DockerCli) CmdKill(args ...string) error {
 }
 
 func (cli *DockerCli) CmdImport(args ...string) error {
-	cmd := cli.Subcmd("import", "URL|- [REPOSITORY[:TAG]]", "Create an empty filesystem image and import the contents of the tarball (.tar, .tar.gz, .tgz, .bzip, .tar.xz, .txz) into it, then optionally tag it.")
+	cmd := cli.Subcmd("import", "[OPTIONS] URL|- [REPOSITORY[:TAG]]", "Create an empty filesystem image and import the contents of the tarball (.tar, .tar.gz, .tgz, .bzip, .tar.xz, .txz) into it, then optionally tag it.")
+	quiet := cmd.Bool([]string{"q", "-quiet"}, false, "Don't display the progress of the import")
 
 	if err := cmd.Parse(args); err != nil {
 		return nil
@@ -1093,6 +1535,9 @@ func (cli *DockerCli) CmdImport(args ...string) error {
 
 	v.Set("fromSrc", src)
 	v.Set("repo", repository)
+	if *quiet {
+		v.Set("quiet", "1")
+	}
 
 	if cmd.NArg() == 3 {
 		fmt.Fprintf(cli.err, "[DEPRECATED] The format 'URL|- [REPOSITORY [TAG]]' as been deprecated. Please use URL|- [REPOSITORY[:TAG]]\n")
@@ -1137,7 +1582,10 @@ func (cli *DockerCli) CmdPush(args ...string) error {
 		return err
 	}
 	// Resolve the Auth config relevant for this server
-	authConfig := cli.configFile.ResolveAuthConfig(hostname)
+	authConfig, err := cli.credentialsStore().Get(hostname)
+	if err != nil {
+		return err
+	}
 	// If we're not using a custom registry, we know the restrictions
 	// applied to repository names and can warn the user in advance.
 	// Custom repositories can have different rules, and we must also
@@ -1172,7 +1620,10 @@ func (cli *DockerCli) CmdPush(args ...string) error {
 			if err := cli.CmdLogin(hostname); err != nil {
 				return err
 			}
-			authConfig := cli.configFile.ResolveAuthConfig(hostname)
+			authConfig, err := cli.credentialsStore().Get(hostname)
+			if err != nil {
+				return err
+			}
 			return push(authConfig)
 		}
 		return err
@@ -1181,7 +1632,7 @@ func (cli *DockerCli) CmdPush(args ...string) error {
 }
 
 func (cli *DockerCli) CmdPull(args ...string) error {
-	cmd := cli.Subcmd("pull", "NAME[:TAG]", "Pull an image or a repository from the registry")
+	cmd := cli.Subcmd("pull", "NAME[:TAG|@DIGEST]", "Pull an image or a repository from the registry")
 	// "#t", "#-tag" 已经弃用
 	tag := cmd.String([]string{"#t", "#-tag"}, "", "Download tagged image in a repository")
 	if err := cmd.Parse(args); err != nil {
@@ -1217,7 +1668,10 @@ func (cli *DockerCli) CmdPull(args ...string) error {
 	cli.LoadConfigFile()
 
 	// Resolve the Auth config relevant for this server
-	authConfig := cli.configFile.ResolveAuthConfig(hostname)
+	authConfig, err := cli.credentialsStore().Get(hostname)
+	if err != nil {
+		return err
+	}
 
 	// 局部函数
 	pull := func(authConfig registry.AuthConfig) error {
@@ -1241,7 +1695,10 @@ func (cli *DockerCli) CmdPull(args ...string) error {
 			if err := cli.CmdLogin(hostname); err != nil {
 				return err
 			}
-			authConfig := cli.configFile.ResolveAuthConfig(hostname)
+			authConfig, err := cli.credentialsStore().Get(hostname)
+			if err != nil {
+				return err
+			}
 			return pull(authConfig)
 		}
 		return err
@@ -1255,6 +1712,9 @@ func (cli *DockerCli) CmdImages(args ...string) error {
 	quiet := cmd.Bool([]string{"q", "-quiet"}, false, "Only show numeric IDs")
 	all := cmd.Bool([]string{"a", "-all"}, false, "Show all images (by default filter out the intermediate image layers)")
 	noTrunc := cmd.Bool([]string{"#notrunc", "-no-trunc"}, false, "Don't truncate output")
+	showDigests := cmd.Bool([]string{"-digests"}, false, "Show digests")
+	tmplStr := cmd.String([]string{"-format"}, "", "Format each image using the given go template, e.g. '{{.Id}} {{.VirtualSize}}'")
+	sortBy := cmd.String([]string{"-sort"}, "", "Sort the images by these comma separated keys, each prefixed by - to sort in descending order\nkeys: created, id, name, size")
 	// FIXME: --viz and --tree are deprecated. Remove them in a future version.
 	flViz := cmd.Bool([]string{"#v", "#viz", "#-viz"}, false, "Output graph in graphviz format")
 	flTree := cmd.Bool([]string{"#t", "#tree", "#-tree"}, false, "Output graph in tree format")
@@ -1269,6 +1729,9 @@ func (cli *DockerCli) CmdImages(args ...string) error {
 		cmd.Usage()
 		return nil
 	}
+	if *tmplStr == "" && !*quiet {
+		*tmplStr = cli.config.ImagesFormat
+	}
 
 	// Consolidate all filter flags, and sanity check them early.
 	// They'll get process in the daemon/server.
@@ -1372,12 +1835,26 @@ func (cli *DockerCli) CmdImages(args ...string) error {
 		if *all {
 			v.Set("all", "1")
 		}
+		if *sortBy != "" {
+			v.Set("sort", *sortBy)
+		}
+
+		var tmpl *template.Template
+		if *tmplStr != "" {
+			var err error
+			if tmpl, err = cli.parseFormat(*tmplStr); err != nil {
+				return err
+			}
+		}
 
 		body, _, err := readBody(cli.call("GET", "/images/json?"+v.Encode(), nil, false))
 
 		if err != nil {
 			return err
 		}
+		if tmpl != nil {
+			return cli.formatList(tmpl, body)
+		}
 
 		outs := engine.NewTable("Created", 0)
 		if _, err := outs.ReadListFrom(body); err != nil {
@@ -1386,7 +1863,11 @@ func (cli *DockerCli) CmdImages(args ...string) error {
 
 		w := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
 		if !*quiet {
-			fmt.Fprintln(w, "REPOSITORY\tTAG\tIMAGE ID\tCREATED\tVIRTUAL SIZE")
+			if *showDigests {
+				fmt.Fprintln(w, cli.tableHeader("REPOSITORY\tTAG\tDIGEST\tIMAGE ID\tCREATED\tVIRTUAL SIZE"))
+			} else {
+				fmt.Fprintln(w, cli.tableHeader("REPOSITORY\tTAG\tIMAGE ID\tCREATED\tVIRTUAL SIZE"))
+			}
 		}
 
 		for _, out := range outs.Data {
@@ -1399,7 +1880,16 @@ func (cli *DockerCli) CmdImages(args ...string) error {
 				}
 
 				if !*quiet {
-					fmt.Fprintf(w, "%s\t%s\t%s\t%s ago\t%s\n", repo, tag, outID, units.HumanDuration(time.Now().UTC().Sub(time.Unix(out.GetInt64("Created"), 0))), units.HumanSize(out.GetInt64("VirtualSize")))
+					if *showDigests {
+						digest := out.Get("Digest")
+						if digest == "" {
+							digest = "<none>"
+						}
+						fmt.Fprintf(w, "%s\t%s\t%s\t", repo, tag, digest)
+					} else {
+						fmt.Fprintf(w, "%s\t%s\t", repo, tag)
+					}
+					fmt.Fprintf(w, "%s\t%s ago\t%s\n", outID, units.HumanDuration(time.Now().UTC().Sub(createdTime(out))), units.HumanSize(out.GetInt64("VirtualSize")))
 				} else {
 					fmt.Fprintln(w, outID)
 				}
@@ -1481,23 +1971,112 @@ func (cli *DockerCli) printTreeNode(noTrunc bool, image *engine.Env, prefix stri
 	}
 }
 
-func (cli *DockerCli) CmdPs(args ...string) error {
-	cmd := cli.Subcmd("ps", "[OPTIONS]", "List containers")
-	quiet := cmd.Bool([]string{"q", "-quiet"}, false, "Only display numeric IDs")
-	size := cmd.Bool([]string{"s", "-size"}, false, "Display sizes")
-	all := cmd.Bool([]string{"a", "-all"}, false, "Show all containers. Only running containers are shown by default.")
-	noTrunc := cmd.Bool([]string{"#notrunc", "-no-trunc"}, false, "Don't truncate output")
-	nLatest := cmd.Bool([]string{"l", "-latest"}, false, "Show only the latest created container, include non-running ones.")
-	since := cmd.String([]string{"#sinceId", "#-since-id", "-since"}, "", "Show only containers created since Id or Name, include non-running ones.")
-	before := cmd.String([]string{"#beforeId", "#-before-id", "-before"}, "", "Show only container created before Id or Name, include non-running ones.")
-	last := cmd.Int([]string{"n"}, -1, "Show n last created containers, include non-running ones.")
-
+func (cli *DockerCli) CmdPrune(args ...string) error {
+	cmd := cli.Subcmd("prune", "[OPTIONS]", "Remove the stopped containers and the dangling images")
+	containers := cmd.Bool([]string{"-containers"}, false, "Only remove the stopped containers")
+	images := cmd.Bool([]string{"-images"}, false, "Only remove the dangling images")
+	dryRun := cmd.Bool([]string{"n", "-dry-run"}, false, "Only show what would be removed and the space it would free")
+	flFilter := opts.NewListOpts(nil)
+	cmd.Var(&flFilter, []string{"f", "-filter"}, "Provide filter values. Valid filters:\nuntil=<duration> - only objects created more than <duration> ago (e.g. 24h)\nexited=<int> - containers with exit code of <int>\nunused=<duration> - containers which did not run for <duration>")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 0 {
+		cmd.Usage()
+		return nil
+	}
+	if !*containers && !*images {
+		*containers, *images = true, true
+	}
+
+	pruneFilterArgs := filters.Args{}
+	for _, f := range flFilter.GetAll() {
+		var err error
+		pruneFilterArgs, err = filters.ParseFlag(f, pruneFilterArgs)
+		if err != nil {
+			return err
+		}
+	}
+
+	var reclaimed int64
+	prune := func(kind string, pruneFilterArgs filters.Args) error {
+		v := url.Values{}
+		if *dryRun {
+			v.Set("dryrun", "1")
+		}
+		if len(pruneFilterArgs) > 0 {
+			filterJson, err := filters.ToParam(pruneFilterArgs)
+			if err != nil {
+				return err
+			}
+			v.Set("filters", filterJson)
+		}
+		body, _, err := readBody(cli.call("POST", "/"+kind+"/prune?"+v.Encode(), nil, false))
+		if err != nil {
+			return err
+		}
+		out := &engine.Env{}
+		if err := out.Decode(bytes.NewReader(body)); err != nil {
+			return err
+		}
+		for _, id := range out.GetList("Deleted") {
+			if *dryRun {
+				fmt.Fprintf(cli.out, "Would delete: %s\n", id)
+			} else {
+				fmt.Fprintf(cli.out, "Deleted: %s\n", id)
+			}
+		}
+		reclaimed += out.GetInt64("SpaceReclaimed")
+		return nil
+	}
+	if *containers {
+		if err := prune("containers", pruneFilterArgs); err != nil {
+			return err
+		}
+	}
+	if *images {
+		// the exit code and the last use only make sense for containers
+		imageFilterArgs := filters.Args{}
+		for name, values := range pruneFilterArgs {
+			if name != "exited" && name != "unused" {
+				imageFilterArgs[name] = values
+			}
+		}
+		if err := prune("images", imageFilterArgs); err != nil {
+			return err
+		}
+	}
+	if *dryRun {
+		fmt.Fprintf(cli.out, "Total reclaimable space: %s\n", units.HumanSize(reclaimed))
+	} else {
+		fmt.Fprintf(cli.out, "Total reclaimed space: %s\n", units.HumanSize(reclaimed))
+	}
+	return nil
+}
+
+func (cli *DockerCli) CmdPs(args ...string) error {
+	cmd := cli.Subcmd("ps", "[OPTIONS]", "List containers")
+	quiet := cmd.Bool([]string{"q", "-quiet"}, false, "Only display numeric IDs")
+	size := cmd.Bool([]string{"s", "-size"}, false, "Display sizes")
+	all := cmd.Bool([]string{"a", "-all"}, false, "Show all containers. Only running containers are shown by default.")
+	noTrunc := cmd.Bool([]string{"#notrunc", "-no-trunc"}, false, "Don't truncate output")
+	nLatest := cmd.Bool([]string{"l", "-latest"}, false, "Show only the latest created container, include non-running ones.")
+	since := cmd.String([]string{"#sinceId", "#-since-id", "-since"}, "", "Show only containers created since Id or Name, include non-running ones.")
+	before := cmd.String([]string{"#beforeId", "#-before-id", "-before"}, "", "Show only container created before Id or Name, include non-running ones.")
+	last := cmd.Int([]string{"n"}, -1, "Show n last created containers, include non-running ones.")
+	digests := cmd.Bool([]string{"-digests"}, false, "Show the digest of the image each container was created from")
+	sortBy := cmd.String([]string{"-sort"}, "", "Sort the containers by these comma separated keys, each prefixed by - to sort in descending order\nkeys: created, id, image, name, size, status")
+	tmplStr := cmd.String([]string{"-format"}, "", "Format each container using the given go template, e.g. '{{.ID}} {{.Names}}'\nstart it with table to align the columns under headers, e.g. 'table {{.ID}}\\t{{.Status}}'")
+
 	flFilter := opts.NewListOpts(nil)
 	cmd.Var(&flFilter, []string{"f", "-filter"}, "Provide filter values. Valid filters:\nexited=<int> - containers with exit code of <int>")
 
 	if err := cmd.Parse(args); err != nil {
 		return nil
 	}
+	if *tmplStr == "" {
+		*tmplStr = cli.config.PsFormat
+	}
 	v := url.Values{}
 	if *last == -1 && *nLatest {
 		*last = 1
@@ -1517,6 +2096,9 @@ func (cli *DockerCli) CmdPs(args ...string) error {
 	if *size {
 		v.Set("size", "1")
 	}
+	if *sortBy != "" {
+		v.Set("sort", *sortBy)
+	}
 
 	// Consolidate all filter flags, and sanity check them.
 	// They'll get processed in the daemon/server.
@@ -1545,11 +2127,18 @@ func (cli *DockerCli) CmdPs(args ...string) error {
 	if _, err := outs.ReadListFrom(body); err != nil {
 		return err
 	}
+	if *tmplStr != "" && !*quiet {
+		return cli.formatContainers(*tmplStr, outs, !*noTrunc)
+	}
 	w := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
 	if !*quiet {
-		fmt.Fprint(w, "CONTAINER ID\tIMAGE\tCOMMAND\tCREATED\tSTATUS\tPORTS\tNAMES")
+		fmt.Fprint(w, cli.tableHeader("CONTAINER ID\tIMAGE\t"))
+		if *digests {
+			fmt.Fprint(w, cli.tableHeader("DIGEST\t"))
+		}
+		fmt.Fprint(w, cli.tableHeader("COMMAND\tCREATED\tSTATUS\tPORTS\tNAMES"))
 		if *size {
-			fmt.Fprintln(w, "\tSIZE")
+			fmt.Fprintln(w, cli.tableHeader("\tSIZE"))
 		} else {
 			fmt.Fprint(w, "\n")
 		}
@@ -1580,7 +2169,15 @@ func (cli *DockerCli) CmdPs(args ...string) error {
 				outCommand = utils.Trunc(outCommand, 20)
 			}
 			ports.ReadListFrom([]byte(out.Get("Ports")))
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s ago\t%s\t%s\t%s\t", outID, out.Get("Image"), outCommand, units.HumanDuration(time.Now().UTC().Sub(time.Unix(out.GetInt64("Created"), 0))), out.Get("Status"), api.DisplayablePorts(ports), strings.Join(outNames, ","))
+			outStatus := out.Get("Status")
+			if restarts := out.GetInt("RestartCount"); restarts > 0 {
+				outStatus = fmt.Sprintf("%s (restarted %d times)", outStatus, restarts)
+			}
+			fmt.Fprintf(w, "%s\t%s\t", outID, out.Get("Image"))
+			if *digests {
+				fmt.Fprintf(w, "%s\t", out.Get("ImageDigest"))
+			}
+			fmt.Fprintf(w, "%s\t%s ago\t%s\t%s\t%s\t", outCommand, units.HumanDuration(time.Now().UTC().Sub(createdTime(out))), outStatus, api.DisplayablePorts(ports), strings.Join(outNames, ","))
 			if *size {
 				if out.GetInt("SizeRootFs") > 0 {
 					fmt.Fprintf(w, "%s (virtual %s)\n", units.HumanSize(out.GetInt64("SizeRw")), units.HumanSize(out.GetInt64("SizeRootFs")))
@@ -1606,6 +2203,7 @@ func (cli *DockerCli) CmdCommit(args ...string) error {
 	flPause := cmd.Bool([]string{"p", "-pause"}, true, "Pause container during commit")
 	flComment := cmd.String([]string{"m", "-message"}, "", "Commit message")
 	flAuthor := cmd.String([]string{"a", "#author", "-author"}, "", "Author (e.g., \"John Hannibal Smith <hannibal@a-team.com>\")")
+	flSquash := cmd.Bool([]string{"-squash"}, false, "Squash the image into a single layer")
 	// FIXME: --run is deprecated, it will be replaced with inline Dockerfile commands.
 	flConfig := cmd.String([]string{"#run", "#-run"}, "", "This option is deprecated and will be removed in a future version in favor of inline Dockerfile-compatible commands")
 	if err := cmd.Parse(args); err != nil {
@@ -1639,6 +2237,9 @@ func (cli *DockerCli) CmdCommit(args ...string) error {
 	if *flPause != true {
 		v.Set("pause", "0")
 	}
+	if *flSquash {
+		v.Set("squash", "1")
+	}
 
 	var (
 		config *runconfig.Config
@@ -1679,11 +2280,7 @@ func (cli *DockerCli) CmdEvents(args ...string) error {
 		loc = time.FixedZone(time.Now().Zone())
 	)
 	var setTime = func(key, value string) {
-		format := time.RFC3339Nano
-		if len(value) < len(format) {
-			format = format[:len(value)]
-		}
-		if t, err := time.ParseInLocation(format, value, loc); err == nil {
+		if t, err := timeutils.Parse(value, loc); err == nil {
 			v.Set(key, strconv.FormatInt(t.Unix(), 10))
 		} else {
 			v.Set(key, value)
@@ -1702,7 +2299,15 @@ func (cli *DockerCli) CmdEvents(args ...string) error {
 }
 
 func (cli *DockerCli) CmdExport(args ...string) error {
-	cmd := cli.Subcmd("export", "CONTAINER", "Export the contents of a filesystem as a tar archive to STDOUT")
+	var (
+		cmd         = cli.Subcmd("export", "[OPTIONS] CONTAINER", "Export the contents of a filesystem as a tar archive to STDOUT")
+		flInclude   = opts.NewListOpts(nil)
+		flExclude   = opts.NewListOpts(nil)
+		skipVolumes = cmd.Bool([]string{"-skip-volumes"}, false, "Don't export the mountpoints of the volumes")
+		quiet       = cmd.Bool([]string{"q", "-quiet"}, false, "Don't display the progress of the export")
+	)
+	cmd.Var(&flInclude, []string{"-include"}, "Only export the paths matching a glob pattern (e.g. /etc)")
+	cmd.Var(&flExclude, []string{"-exclude"}, "Don't export the paths matching a glob pattern (e.g. /var/log/*.log)")
 	if err := cmd.Parse(args); err != nil {
 		return nil
 	}
@@ -1712,10 +2317,147 @@ func (cli *DockerCli) CmdExport(args ...string) error {
 		return nil
 	}
 
-	if err := cli.stream("GET", "/containers/"+cmd.Arg(0)+"/export", nil, cli.out, nil); err != nil {
+	v := url.Values{}
+	for _, include := range flInclude.GetAll() {
+		v.Add("include", include)
+	}
+	for _, exclude := range flExclude.GetAll() {
+		v.Add("exclude", exclude)
+	}
+	if *skipVolumes {
+		v.Set("skipvolumes", "1")
+	}
+	path := "/containers/" + cmd.Arg(0) + "/export?" + v.Encode()
+	if *quiet {
+		return cli.stream("GET", path, nil, cli.out, nil)
+	}
+	progress := cli.newTransferProgress(cli.out, cmd.Arg(0), "Exporting")
+	if err := cli.stream("GET", path, nil, progress, nil); err != nil {
+		progress.Close("Failed after")
 		return err
 	}
-	return nil
+	return progress.Close("Exported")
+}
+
+func (cli *DockerCli) CmdDf(args ...string) error {
+	cmd := cli.Subcmd("df", "", "Show the disk space used by images, containers and volumes")
+	verbose := cmd.Bool([]string{"v", "-verbose"}, false, "Show the usage of each image, container and volume")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 0 {
+		cmd.Usage()
+		return nil
+	}
+
+	body, _, err := readBody(cli.call("GET", "/system/df", nil, false))
+	if err != nil {
+		return err
+	}
+	out := &engine.Env{}
+	if err := out.Decode(bytes.NewReader(body)); err != nil {
+		return err
+	}
+	var (
+		images []struct {
+			Id               string
+			RepoTags         []string
+			Size, SharedSize int64
+			Containers       int
+		}
+		containers []struct {
+			Id, Name, Image, Status string
+			Running                 bool
+			SizeRw                  int64
+		}
+		volumes []struct {
+			Name, Driver string
+			Size         int64
+			Containers   int
+		}
+	)
+	if err := out.GetJson("Images", &images); err != nil {
+		return err
+	}
+	if err := out.GetJson("Containers", &containers); err != nil {
+		return err
+	}
+	if err := out.GetJson("Volumes", &volumes); err != nil {
+		return err
+	}
+
+	humanSize := func(size int64) string {
+		if size < 0 {
+			return "N/A"
+		}
+		return units.HumanSize(size)
+	}
+	w := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
+	if !*verbose {
+		var activeImages, activeContainers, activeVolumes int
+		var imagesReclaimable, containersSize, containersReclaimable, volumesSize, volumesReclaimable int64
+		for _, image := range images {
+			if image.Containers > 0 {
+				activeImages++
+			} else {
+				imagesReclaimable += image.Size - image.SharedSize
+			}
+		}
+		for _, container := range containers {
+			containersSize += container.SizeRw
+			if container.Running {
+				activeContainers++
+			} else {
+				containersReclaimable += container.SizeRw
+			}
+		}
+		for _, volume := range volumes {
+			if volume.Size > 0 {
+				volumesSize += volume.Size
+			}
+			if volume.Containers > 0 {
+				activeVolumes++
+			} else if volume.Size > 0 {
+				volumesReclaimable += volume.Size
+			}
+		}
+		fmt.Fprintln(w, cli.tableHeader("TYPE\tTOTAL\tACTIVE\tSIZE\tRECLAIMABLE"))
+		fmt.Fprintf(w, "Images\t%d\t%d\t%s\t%s\n", len(images), activeImages, units.HumanSize(out.GetInt64("LayersSize")), units.HumanSize(imagesReclaimable))
+		fmt.Fprintf(w, "Containers\t%d\t%d\t%s\t%s\n", len(containers), activeContainers, units.HumanSize(containersSize), units.HumanSize(containersReclaimable))
+		fmt.Fprintf(w, "Volumes\t%d\t%d\t%s\t%s\n", len(volumes), activeVolumes, units.HumanSize(volumesSize), units.HumanSize(volumesReclaimable))
+		return w.Flush()
+	}
+
+	fmt.Fprint(cli.out, "Images space usage:\n\n")
+	fmt.Fprintln(w, cli.tableHeader("REPOSITORY:TAG\tIMAGE ID\tSIZE\tSHARED SIZE\tUNIQUE SIZE\tCONTAINERS"))
+	for _, image := range images {
+		repoTags := image.RepoTags
+		if len(repoTags) == 0 {
+			repoTags = []string{"<none>:<none>"}
+		}
+		for _, repoTag := range repoTags {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\n", repoTag, utils.TruncateID(image.Id), units.HumanSize(image.Size), units.HumanSize(image.SharedSize), units.HumanSize(image.Size-image.SharedSize), image.Containers)
+		}
+	}
+	w.Flush()
+
+	fmt.Fprint(cli.out, "\nContainers space usage:\n\n")
+	fmt.Fprintln(w, cli.tableHeader("CONTAINER ID\tIMAGE\tNAME\tSTATUS\tSIZE"))
+	for _, container := range containers {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", utils.TruncateID(container.Id), container.Image, strings.TrimPrefix(container.Name, "/"), container.Status, units.HumanSize(container.SizeRw))
+	}
+	w.Flush()
+
+	fmt.Fprint(cli.out, "\nVolumes space usage:\n\n")
+	fmt.Fprintln(w, cli.tableHeader("VOLUME NAME\tDRIVER\tSIZE\tCONTAINERS"))
+	for _, volume := range volumes {
+		driver := volume.Driver
+		if driver == "" {
+			driver = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", volume.Name, driver, humanSize(volume.Size), volume.Containers)
+	}
+	return w.Flush()
 }
 
 func (cli *DockerCli) CmdDiff(args ...string) error {
@@ -1885,10 +2627,16 @@ func (cli *DockerCli) CmdSearch(args ...string) error {
 		return nil
 	}
 
+	// Send the credentials of the registry searched
+	hostname, _, err := registry.ResolveRepositoryName(cmd.Arg(0))
+	if err != nil {
+		return err
+	}
+
 	v := url.Values{}
 	v.Set("term", cmd.Arg(0))
 
-	body, _, err := readBody(cli.call("GET", "/images/search?"+v.Encode(), nil, true))
+	body, _, err := readBody(cli.callAuth("GET", "/images/search?"+v.Encode(), nil, hostname))
 
 	if err != nil {
 		return err
@@ -1898,7 +2646,7 @@ func (cli *DockerCli) CmdSearch(args ...string) error {
 		return err
 	}
 	w := tabwriter.NewWriter(cli.out, 10, 1, 3, ' ', 0)
-	fmt.Fprintf(w, "NAME\tDESCRIPTION\tSTARS\tOFFICIAL\tAUTOMATED\n")
+	fmt.Fprintln(w, cli.tableHeader("NAME\tDESCRIPTION\tSTARS\tOFFICIAL\tAUTOMATED"))
 	for _, out := range outs.Data {
 		if ((*automated || *trusted) && (!out.GetBool("is_trusted") && !out.GetBool("is_automated"))) || (*stars > out.GetInt("star_count")) {
 			continue
@@ -1926,6 +2674,36 @@ func (cli *DockerCli) CmdSearch(args ...string) error {
 // Ports type - Used to parse multiple -p flags
 type ports []int
 
+func (cli *DockerCli) CmdAnnotate(args ...string) error {
+	cmd := cli.Subcmd("annotate", "[OPTIONS] IMAGE [KEY=VALUE...]", "Set or remove annotations on an image, without creating a new layer")
+	flRemove := opts.NewListOpts(nil)
+	cmd.Var(&flRemove, []string{"-rm"}, "Remove the annotation with this key")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() < 1 || (cmd.NArg() == 1 && flRemove.Len() == 0) {
+		cmd.Usage()
+		return nil
+	}
+
+	set := make(map[string]string)
+	for _, annotation := range cmd.Args()[1:] {
+		parts := strings.SplitN(annotation, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return fmt.Errorf("Invalid annotation %s, expected KEY=VALUE", annotation)
+		}
+		set[parts[0]] = parts[1]
+	}
+	config := map[string]interface{}{
+		"Set":    set,
+		"Remove": flRemove.GetAll(),
+	}
+	if _, _, err := readBody(cli.call("POST", "/images/"+cmd.Arg(0)+"/annotations", config, false)); err != nil {
+		return err
+	}
+	return nil
+}
+
 func (cli *DockerCli) CmdTag(args ...string) error {
 	cmd := cli.Subcmd("tag", "[OPTIONS] IMAGE[:TAG] [REGISTRYHOST/][USERNAME/]NAME[:TAG]", "Tag an image into a repository")
 	force := cmd.Bool([]string{"f", "#force", "-force"}, false, "Force")
@@ -1979,7 +2757,10 @@ func (cli *DockerCli) pullImage(image string) error {
 	cli.LoadConfigFile()
 
 	// Resolve the Auth config relevant for this server
-	authConfig := cli.configFile.ResolveAuthConfig(hostname)
+	authConfig, err := cli.credentialsStore().Get(hostname)
+	if err != nil {
+		return err
+	}
 	buf, err := json.Marshal(authConfig)
 	if err != nil {
 		return err
@@ -2010,6 +2791,7 @@ func (cli *DockerCli) CmdRun(args ...string) error {
 		flName        = cmd.Lookup("name")
 		flRm          = cmd.Lookup("rm")
 		flSigProxy    = cmd.Lookup("sig-proxy")
+		pullPolicy    = cmd.Lookup("pull").Value.String()
 		autoRemove, _ = strconv.ParseBool(flRm.Value.String())
 		sigProxy, _   = strconv.ParseBool(flSigProxy.Value.String())
 	)
@@ -2048,11 +2830,18 @@ func (cli *DockerCli) CmdRun(args ...string) error {
 	if name := flName.Value.String(); name != "" {
 		containerValues.Set("name", name)
 	}
+	switch pullPolicy {
+	case "":
+	case "always", "missing", "never":
+		containerValues.Set("pull", pullPolicy)
+	default:
+		return fmt.Errorf("Invalid pull policy: %s (expected always, missing or never)", pullPolicy)
+	}
 
 	//create the container
-	stream, statusCode, err := cli.call("POST", "/containers/create?"+containerValues.Encode(), config, false)
-	//if image not found try to pull it
-	if statusCode == 404 {
+	stream, statusCode, err := cli.call("POST", "/containers/create?"+containerValues.Encode(), config, pullPolicy == "always" || pullPolicy == "missing")
+	//if image not found try to pull it, unless the daemon was told how to
+	if statusCode == 404 && pullPolicy == "" {
 		fmt.Fprintf(cli.err, "Unable to find image '%s' locally\n", config.Image)
 
 		if err = cli.pullImage(config.Image); err != nil {
@@ -2261,14 +3050,15 @@ func (cli *DockerCli) CmdCp(args ...string) error {
 }
 
 func (cli *DockerCli) CmdSave(args ...string) error {
-	cmd := cli.Subcmd("save", "IMAGE", "Save an image to a tar archive (streamed to STDOUT by default)")
+	cmd := cli.Subcmd("save", "IMAGE [IMAGE...]", "Save one or more images to a tar archive (streamed to STDOUT by default)")
 	outfile := cmd.String([]string{"o", "-output"}, "", "Write to an file, instead of STDOUT")
+	quiet := cmd.Bool([]string{"q", "-quiet"}, false, "Don't display the progress of the save")
 
 	if err := cmd.Parse(args); err != nil {
 		return err
 	}
 
-	if cmd.NArg() != 1 {
+	if cmd.NArg() < 1 {
 		cmd.Usage()
 		return nil
 	}
@@ -2283,16 +3073,29 @@ func (cli *DockerCli) CmdSave(args ...string) error {
 			return err
 		}
 	}
-	image := cmd.Arg(0)
-	if err := cli.stream("GET", "/images/"+image+"/get", nil, output, nil); err != nil {
+	path := "/images/" + cmd.Arg(0) + "/get"
+	if cmd.NArg() > 1 {
+		v := url.Values{}
+		for _, name := range cmd.Args() {
+			v.Add("names", name)
+		}
+		path = "/images/get?" + v.Encode()
+	}
+	if *quiet {
+		return cli.stream("GET", path, nil, output, nil)
+	}
+	progress := cli.newTransferProgress(output, strings.Join(cmd.Args(), " "), "Saving")
+	if err := cli.stream("GET", path, nil, progress, nil); err != nil {
+		progress.Close("Failed after")
 		return err
 	}
-	return nil
+	return progress.Close("Saved")
 }
 
 func (cli *DockerCli) CmdLoad(args ...string) error {
 	cmd := cli.Subcmd("load", "", "Load an image from a tar archive on STDIN")
 	infile := cmd.String([]string{"i", "-input"}, "", "Read from a tar archive file, instead of STDIN")
+	quiet := cmd.Bool([]string{"q", "-quiet"}, false, "Don't display the progress of the load")
 
 	if err := cmd.Parse(args); err != nil {
 		return err
@@ -2313,8 +3116,664 @@ func (cli *DockerCli) CmdLoad(args ...string) error {
 			return err
 		}
 	}
-	if err := cli.stream("POST", "/images/load", input, cli.out, nil); err != nil {
+	v := url.Values{}
+	if *quiet {
+		v.Set("quiet", "1")
+	}
+	if err := cli.stream("POST", "/images/load?"+v.Encode(), input, cli.out, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// 'docker network': manage the bridges containers can be attached to
+func (cli *DockerCli) CmdNetwork(args ...string) error {
+	cmd := cli.Subcmd("network", "COMMAND [OPTIONS]", "Manage networks\n\nCommands:\n    connect      Connect a running container to a network\n    create       Create a network\n    disconnect   Disconnect a container from a network\n    inspect      Return low-level information on a network\n    ls           List networks\n    rm           Remove one or more networks")
+	if len(args) == 0 {
+		cmd.Usage()
+		return nil
+	}
+	switch args[0] {
+	case "connect":
+		return cli.networkConnect("connect", args[1:]...)
+	case "create":
+		return cli.networkCreate(args[1:]...)
+	case "disconnect":
+		return cli.networkConnect("disconnect", args[1:]...)
+	case "inspect":
+		return cli.networkInspect(args[1:]...)
+	case "ls":
+		return cli.networkList(args[1:]...)
+	case "rm":
+		return cli.networkRemove(args[1:]...)
+	}
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	cmd.Usage()
+	return nil
+}
+
+func (cli *DockerCli) networkCreate(args ...string) error {
+	cmd := cli.Subcmd("network create", "[OPTIONS] NAME", "Create a new bridge network")
+	subnet := cmd.String([]string{"-subnet"}, "", "Gateway address and subnet of the network in CIDR notation, e.g. 10.1.0.1/24")
+	bridge := cmd.String([]string{"-bridge"}, "", "Name of the bridge interface to create (default: br-NAME)")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 1 {
+		cmd.Usage()
+		return nil
+	}
+
+	config := engine.Env{}
+	config.Set("Name", cmd.Arg(0))
+	config.Set("Subnet", *subnet)
+	config.Set("Bridge", *bridge)
+
+	body, _, err := readBody(cli.call("POST", "/networks/create", config, false))
+	if err != nil {
+		return err
+	}
+	network := &engine.Env{}
+	if err := network.Decode(bytes.NewReader(body)); err != nil {
+		return err
+	}
+	fmt.Fprintf(cli.out, "%s\n", network.Get("Name"))
+	return nil
+}
+
+func (cli *DockerCli) networkList(args ...string) error {
+	cmd := cli.Subcmd("network ls", "[OPTIONS]", "List networks")
+	quiet := cmd.Bool([]string{"q", "-quiet"}, false, "Only display network names")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 0 {
+		cmd.Usage()
+		return nil
+	}
+
+	body, _, err := readBody(cli.call("GET", "/networks", nil, false))
+	if err != nil {
+		return err
+	}
+	outs := engine.NewTable("", 0)
+	if _, err := outs.ReadListFrom(body); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
+	if !*quiet {
+		fmt.Fprintln(w, cli.tableHeader("NAME\tBRIDGE\tSUBNET\tGATEWAY"))
+	}
+	for _, out := range outs.Data {
+		if *quiet {
+			fmt.Fprintln(w, out.Get("Name"))
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", out.Get("Name"), out.Get("Bridge"), out.Get("Subnet"), out.Get("Gateway"))
+	}
+	w.Flush()
+	return nil
+}
+
+func (cli *DockerCli) networkInspect(args ...string) error {
+	cmd := cli.Subcmd("network inspect", "NETWORK [NETWORK...]", "Return low-level information on a network")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() < 1 {
+		cmd.Usage()
+		return nil
+	}
+
+	indented := new(bytes.Buffer)
+	indented.WriteByte('[')
+	status := 0
+	for _, name := range cmd.Args() {
+		obj, _, err := readBody(cli.call("GET", "/networks/"+name, nil, false))
+		if err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			status = 1
+			continue
+		}
+		if err := json.Indent(indented, obj, "", "    "); err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			status = 1
+			continue
+		}
+		indented.WriteString(",")
+	}
+	if indented.Len() > 1 {
+		// Remove trailing ','
+		indented.Truncate(indented.Len() - 1)
+	}
+	indented.WriteString("]\n")
+	if _, err := io.Copy(cli.out, indented); err != nil {
 		return err
 	}
+	if status != 0 {
+		return &utils.StatusError{StatusCode: status}
+	}
+	return nil
+}
+
+// networkConnect implements both "network connect" and "network disconnect"
+func (cli *DockerCli) networkConnect(action string, args ...string) error {
+	description := "Connect a running container to a network"
+	if action == "disconnect" {
+		description = "Disconnect a container from a network"
+	}
+	cmd := cli.Subcmd("network "+action, "NETWORK CONTAINER", description)
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 2 {
+		cmd.Usage()
+		return nil
+	}
+
+	config := engine.Env{}
+	config.Set("Container", cmd.Arg(1))
+	if _, _, err := readBody(cli.call("POST", "/networks/"+cmd.Arg(0)+"/"+action, config, false)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (cli *DockerCli) networkRemove(args ...string) error {
+	cmd := cli.Subcmd("network rm", "NETWORK [NETWORK...]", "Remove one or more networks")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() < 1 {
+		cmd.Usage()
+		return nil
+	}
+
+	var encounteredError error
+	for _, name := range cmd.Args() {
+		_, _, err := readBody(cli.call("DELETE", "/networks/"+name, nil, false))
+		if err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			encounteredError = fmt.Errorf("Error: failed to remove one or more networks")
+		} else {
+			fmt.Fprintf(cli.out, "%s\n", name)
+		}
+	}
+	return encounteredError
+}
+
+// 'docker drain': put the daemon in maintenance mode
+func (cli *DockerCli) CmdDrain(args ...string) error {
+	cmd := cli.Subcmd("drain", "[OPTIONS]", "Stop accepting new containers on the daemon, optionally stopping running ones")
+	stop := cmd.Bool([]string{"-stop"}, false, "Stop all running containers")
+	nSeconds := cmd.Int([]string{"t", "-time"}, 10, "Number of seconds to wait for each container to stop before killing it")
+	status := cmd.Bool([]string{"-status"}, false, "Only display the drain progress")
+	cancel := cmd.Bool([]string{"-cancel"}, false, "Leave maintenance mode and accept new containers again")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 0 || (*status && *cancel) {
+		cmd.Usage()
+		return nil
+	}
+
+	if *cancel {
+		_, _, err := readBody(cli.call("DELETE", "/drain", nil, false))
+		return err
+	}
+	if !*status {
+		v := url.Values{}
+		if *stop {
+			v.Set("stop", "1")
+		}
+		v.Set("t", strconv.Itoa(*nSeconds))
+		if _, _, err := readBody(cli.call("POST", "/drain?"+v.Encode(), nil, false)); err != nil {
+			return err
+		}
+	}
+
+	body, _, err := readBody(cli.call("GET", "/drain", nil, false))
+	if err != nil {
+		return err
+	}
+	out := &engine.Env{}
+	if err := out.Decode(bytes.NewReader(body)); err != nil {
+		return err
+	}
+	if !out.GetBool("Draining") {
+		fmt.Fprintf(cli.out, "Draining: false\n")
+		return nil
+	}
+	fmt.Fprintf(cli.out, "Draining: true\n")
+	fmt.Fprintf(cli.out, "Running containers: %d\n", out.GetInt("Running"))
+	fmt.Fprintf(cli.out, "Stopping: %d\n", out.GetInt("Pending"))
+	fmt.Fprintf(cli.out, "Stopped: %d\n", out.GetInt("Stopped"))
+	if failed := out.GetList("Failed"); len(failed) > 0 {
+		fmt.Fprintf(cli.out, "Failed to stop: %s\n", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func (cli *DockerCli) CmdPlugin(args ...string) error {
+	cmd := cli.Subcmd("plugin", "COMMAND [OPTIONS]", "Manage plugins\n\nCommands:\n    ls        List plugins")
+	if len(args) == 0 {
+		cmd.Usage()
+		return nil
+	}
+	switch args[0] {
+	case "ls":
+		return cli.pluginList(args[1:]...)
+	}
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	cmd.Usage()
+	return nil
+}
+
+func (cli *DockerCli) pluginList(args ...string) error {
+	cmd := cli.Subcmd("plugin ls", "[OPTIONS]", "List the plugins found by the daemon, active or not")
+	quiet := cmd.Bool([]string{"q", "-quiet"}, false, "Only display plugin names")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 0 {
+		cmd.Usage()
+		return nil
+	}
+
+	body, _, err := readBody(cli.call("GET", "/plugins", nil, false))
+	if err != nil {
+		return err
+	}
+	outs := engine.NewTable("", 0)
+	if _, err := outs.ReadListFrom(body); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
+	if !*quiet {
+		fmt.Fprintln(w, cli.tableHeader("NAME\tADDRESS\tIMPLEMENTS\tSTATUS"))
+	}
+	for _, out := range outs.Data {
+		if *quiet {
+			fmt.Fprintln(w, out.Get("Name"))
+			continue
+		}
+		status := out.Get("Status")
+		if err := out.Get("Err"); err != "" {
+			status = fmt.Sprintf("%s: %s", status, err)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", out.Get("Name"), out.Get("Addr"), strings.Join(out.GetList("Implements"), ","), status)
+	}
+	w.Flush()
+	return nil
+}
+
+func (cli *DockerCli) CmdVolume(args ...string) error {
+	cmd := cli.Subcmd("volume", "COMMAND [OPTIONS]", "Manage named volumes\n\nCommands:\n    create    Create a volume\n    inspect   Return low-level information on a volume\n    ls        List volumes\n    rm        Remove one or more volumes")
+	if len(args) == 0 {
+		cmd.Usage()
+		return nil
+	}
+	switch args[0] {
+	case "create":
+		return cli.volumeCreate(args[1:]...)
+	case "inspect":
+		return cli.volumeInspect(args[1:]...)
+	case "ls":
+		return cli.volumeList(args[1:]...)
+	case "rm":
+		return cli.volumeRemove(args[1:]...)
+	}
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	cmd.Usage()
+	return nil
+}
+
+func (cli *DockerCli) volumeCreate(args ...string) error {
+	cmd := cli.Subcmd("volume create", "[OPTIONS] [NAME]", "Create a named volume, a name is generated if none is given")
+	driver := cmd.String([]string{"d", "-driver"}, "local", "Volume driver providing the volume")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() > 1 {
+		cmd.Usage()
+		return nil
+	}
+
+	config := engine.Env{}
+	config.Set("Name", cmd.Arg(0))
+	config.Set("Driver", *driver)
+
+	body, _, err := readBody(cli.call("POST", "/volumes/create", config, false))
+	if err != nil {
+		return err
+	}
+	volume := &engine.Env{}
+	if err := volume.Decode(bytes.NewReader(body)); err != nil {
+		return err
+	}
+	fmt.Fprintf(cli.out, "%s\n", volume.Get("Name"))
+	return nil
+}
+
+func (cli *DockerCli) volumeList(args ...string) error {
+	cmd := cli.Subcmd("volume ls", "[OPTIONS]", "List volumes")
+	quiet := cmd.Bool([]string{"q", "-quiet"}, false, "Only display volume names")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 0 {
+		cmd.Usage()
+		return nil
+	}
+
+	body, _, err := readBody(cli.call("GET", "/volumes", nil, false))
+	if err != nil {
+		return err
+	}
+	outs := engine.NewTable("", 0)
+	if _, err := outs.ReadListFrom(body); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
+	if !*quiet {
+		fmt.Fprintln(w, cli.tableHeader("DRIVER\tNAME"))
+	}
+	for _, out := range outs.Data {
+		if *quiet {
+			fmt.Fprintln(w, out.Get("Name"))
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\n", out.Get("Driver"), out.Get("Name"))
+	}
+	w.Flush()
+	return nil
+}
+
+func (cli *DockerCli) volumeInspect(args ...string) error {
+	cmd := cli.Subcmd("volume inspect", "VOLUME [VOLUME...]", "Return low-level information on a volume")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() < 1 {
+		cmd.Usage()
+		return nil
+	}
+
+	indented := new(bytes.Buffer)
+	indented.WriteByte('[')
+	status := 0
+	for _, name := range cmd.Args() {
+		obj, _, err := readBody(cli.call("GET", "/volumes/"+name, nil, false))
+		if err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			status = 1
+			continue
+		}
+		if err := json.Indent(indented, obj, "", "    "); err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			status = 1
+			continue
+		}
+		indented.WriteString(",")
+	}
+	if indented.Len() > 1 {
+		// Remove trailing ','
+		indented.Truncate(indented.Len() - 1)
+	}
+	indented.WriteString("]\n")
+	if _, err := io.Copy(cli.out, indented); err != nil {
+		return err
+	}
+	if status != 0 {
+		return &utils.StatusError{StatusCode: status}
+	}
+	return nil
+}
+
+func (cli *DockerCli) volumeRemove(args ...string) error {
+	cmd := cli.Subcmd("volume rm", "VOLUME [VOLUME...]", "Remove one or more volumes")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() < 1 {
+		cmd.Usage()
+		return nil
+	}
+
+	var encounteredError error
+	for _, name := range cmd.Args() {
+		_, _, err := readBody(cli.call("DELETE", "/volumes/"+name, nil, false))
+		if err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			encounteredError = fmt.Errorf("Error: failed to remove one or more volumes")
+		} else {
+			fmt.Fprintf(cli.out, "%s\n", name)
+		}
+	}
+	return encounteredError
+}
+
+func (cli *DockerCli) CmdToken(args ...string) error {
+	cmd := cli.Subcmd("token", "COMMAND [OPTIONS]", "Manage the API tokens of the token socket\n\nCommands:\n    create    Create an API token\n    ls        List API tokens\n    rm        Revoke one or more API tokens")
+	if len(args) == 0 {
+		cmd.Usage()
+		return nil
+	}
+	switch args[0] {
+	case "create":
+		return cli.tokenCreate(args[1:]...)
+	case "ls":
+		return cli.tokenList(args[1:]...)
+	case "rm":
+		return cli.tokenRemove(args[1:]...)
+	}
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	cmd.Usage()
+	return nil
+}
+
+func (cli *DockerCli) tokenCreate(args ...string) error {
+	cmd := cli.Subcmd("token create", "[OPTIONS] NAME", "Create an API token and print it, it cannot be shown again")
+	flScopes := opts.NewListOpts(nil)
+	cmd.Var(&flScopes, []string{"s", "-scope"}, "Allow the token a scope of requests: read, pull, import, push, tag or build")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 1 {
+		cmd.Usage()
+		return nil
+	}
+
+	config := engine.Env{}
+	config.Set("Name", cmd.Arg(0))
+	config.SetList("Scopes", flScopes.GetAll())
+
+	body, _, err := readBody(cli.call("POST", "/tokens/create", config, false))
+	if err != nil {
+		return err
+	}
+	token := &engine.Env{}
+	if err := token.Decode(bytes.NewReader(body)); err != nil {
+		return err
+	}
+	fmt.Fprintf(cli.out, "%s\n", token.Get("Token"))
+	return nil
+}
+
+func (cli *DockerCli) tokenList(args ...string) error {
+	cmd := cli.Subcmd("token ls", "[OPTIONS]", "List the API tokens")
+	quiet := cmd.Bool([]string{"q", "-quiet"}, false, "Only display token names")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 0 {
+		cmd.Usage()
+		return nil
+	}
+
+	body, _, err := readBody(cli.call("GET", "/tokens", nil, false))
+	if err != nil {
+		return err
+	}
+	outs := engine.NewTable("", 0)
+	if _, err := outs.ReadListFrom(body); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
+	if !*quiet {
+		fmt.Fprintln(w, cli.tableHeader("NAME\tSCOPES\tCREATED"))
+	}
+	for _, out := range outs.Data {
+		if *quiet {
+			fmt.Fprintln(w, out.Get("Name"))
+			continue
+		}
+		created, err := time.Parse(time.RFC3339Nano, out.Get("Created"))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s ago\n", out.Get("Name"), strings.Join(out.GetList("Scopes"), ","), units.HumanDuration(time.Now().UTC().Sub(created)))
+	}
+	w.Flush()
+	return nil
+}
+
+func (cli *DockerCli) tokenRemove(args ...string) error {
+	cmd := cli.Subcmd("token rm", "NAME [NAME...]", "Revoke one or more API tokens")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() < 1 {
+		cmd.Usage()
+		return nil
+	}
+
+	var encounteredError error
+	for _, name := range cmd.Args() {
+		_, _, err := readBody(cli.call("DELETE", "/tokens/"+name, nil, false))
+		if err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			encounteredError = fmt.Errorf("Error: failed to revoke one or more API tokens")
+		} else {
+			fmt.Fprintf(cli.out, "%s\n", name)
+		}
+	}
+	return encounteredError
+}
+
+func (cli *DockerCli) CmdImage(args ...string) error {
+	cmd := cli.Subcmd("image", "COMMAND [OPTIONS]", "Mount images for inspection\n\nCommands:\n    mount     Mount an image or a stopped container read-only at a host path\n    mounts    List the mounted images and containers\n    umount    Unmount an image or a container")
+	if len(args) == 0 {
+		cmd.Usage()
+		return nil
+	}
+	switch args[0] {
+	case "mount":
+		return cli.imageMount(args[1:]...)
+	case "mounts":
+		return cli.imageMounts(args[1:]...)
+	case "umount":
+		return cli.imageUnmount(args[1:]...)
+	}
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	cmd.Usage()
+	return nil
+}
+
+func (cli *DockerCli) imageMount(args ...string) error {
+	cmd := cli.Subcmd("image mount", "IMAGE|CONTAINER PATH", "Mount the filesystem of an image or of a stopped container read-only at a path of the daemon host")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 2 {
+		cmd.Usage()
+		return nil
+	}
+	target, err := filepath.Abs(cmd.Arg(1))
+	if err != nil {
+		return err
+	}
+	v := url.Values{}
+	v.Set("path", target)
+	if _, _, err := readBody(cli.call("POST", "/images/"+cmd.Arg(0)+"/mount?"+v.Encode(), nil, false)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (cli *DockerCli) imageUnmount(args ...string) error {
+	cmd := cli.Subcmd("image umount", "PATH [PATH...]", "Unmount the images or containers mounted at the paths")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() < 1 {
+		cmd.Usage()
+		return nil
+	}
+
+	var encounteredError error
+	for _, arg := range cmd.Args() {
+		target, err := filepath.Abs(arg)
+		if err != nil {
+			return err
+		}
+		v := url.Values{}
+		v.Set("path", target)
+		if _, _, err := readBody(cli.call("POST", "/images/unmount?"+v.Encode(), nil, false)); err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			encounteredError = fmt.Errorf("Error: failed to unmount one or more paths")
+		}
+	}
+	return encounteredError
+}
+
+func (cli *DockerCli) imageMounts(args ...string) error {
+	cmd := cli.Subcmd("image mounts", "", "List the images and containers mounted for inspection")
+	noTrunc := cmd.Bool([]string{"#notrunc", "-no-trunc"}, false, "Don't truncate output")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 0 {
+		cmd.Usage()
+		return nil
+	}
+
+	body, _, err := readBody(cli.call("GET", "/images/mounts", nil, false))
+	if err != nil {
+		return err
+	}
+	outs := engine.NewTable("", 0)
+	if _, err := outs.ReadListFrom(body); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
+	fmt.Fprintln(w, cli.tableHeader("ID\tTYPE\tPATH"))
+	for _, out := range outs.Data {
+		id := out.Get("Id")
+		if !*noTrunc {
+			id = utils.TruncateID(id)
+		}
+		kind := "image"
+		if out.GetBool("Container") {
+			kind = "container"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", id, kind, out.Get("Target"))
+	}
+	w.Flush()
 	return nil
 }
@@ -0,0 +1,25 @@
+package client
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestCmdBuildDockerfileFlag(t *testing.T) {
+	for _, args := range [][]string{
+		// the Dockerfile is the one of STDIN
+		{"-f", "Dockerfile.dev", "-"},
+		// the Dockerfile is the one at the URL
+		{"-f", "Dockerfile.dev", "http://example.com/Dockerfile"},
+	} {
+		var out bytes.Buffer
+		stdin := ioutil.NopCloser(strings.NewReader("FROM busybox\n"))
+		cli := NewDockerCli(stdin, &out, &out, "unix", "/var/run/missing.sock", nil)
+		err := cli.CmdBuild(args...)
+		if err == nil || !strings.Contains(err.Error(), "-f cannot be used") {
+			t.Fatalf("Expected -f to be refused for %v, got %v", args, err)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// ClientConfigFileName is the name of the configuration file of the client
+// in the directory given by ConfigDir.
+const ClientConfigFileName = "config.json"
+
+// ClientConfig holds the defaults of the client, for the options which are
+// not given on the command line.
+type ClientConfig struct {
+	// Host is the daemon to connect to, as PROTO://ADDR, when neither -H nor
+	// DOCKER_HOST is given.
+	Host      string `json:"host,omitempty"`
+	TLS       bool   `json:"tls,omitempty"`
+	TLSVerify bool   `json:"tlsverify,omitempty"`
+	TLSCACert string `json:"tlscacert,omitempty"`
+	TLSCert   string `json:"tlscert,omitempty"`
+	TLSKey    string `json:"tlskey,omitempty"`
+	// Headers are sent with every request to the API, e.g. for a proxy in
+	// front of the daemon.
+	Headers map[string]string `json:"headers,omitempty"`
+	// PsFormat and ImagesFormat are the default --format of docker ps and
+	// docker images.
+	PsFormat     string `json:"psformat,omitempty"`
+	ImagesFormat string `json:"imagesformat,omitempty"`
+	Progress     string `json:"progress,omitempty"`
+}
+
+// ConfigDir returns the directory of the configuration of the client,
+// $DOCKER_CONFIG or ~/.docker.
+func ConfigDir() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.Getenv("HOME"), ".docker")
+}
+
+// LoadClientConfig reads the configuration of the client in dir. A missing
+// file is an empty configuration.
+func LoadClientConfig(dir string) (*ClientConfig, error) {
+	config := &ClientConfig{}
+	path := filepath.Join(dir, ClientConfigFileName)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return config, nil
+		}
+		return config, err
+	}
+	if err := json.Unmarshal(data, config); err != nil {
+		return &ClientConfig{}, fmt.Errorf("Invalid client configuration %s: %s", path, err)
+	}
+	return config, nil
+}
+
+// addConfigHeaders sets the headers of the configuration on req, and the
+// API token of $DOCKER_API_TOKEN. They are set first so that the headers of
+// the client itself take precedence.
+func (cli *DockerCli) addConfigHeaders(req *http.Request) {
+	for key, value := range cli.config.Headers {
+		req.Header.Set(key, value)
+	}
+	if token := os.Getenv("DOCKER_API_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Token "+token)
+	}
+}
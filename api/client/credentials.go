@@ -0,0 +1,161 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/docker/docker/registry"
+)
+
+// credentialsStore keeps the credentials of the registries the client
+// logged in to.
+type credentialsStore interface {
+	// Get returns the credentials of the registry serverAddress, empty if
+	// the client is not logged in to it.
+	Get(serverAddress string) (registry.AuthConfig, error)
+	// GetAll returns the credentials of all the registries, by address.
+	GetAll() (map[string]registry.AuthConfig, error)
+	Store(authConfig registry.AuthConfig) error
+	Erase(serverAddress string) error
+}
+
+// credentialsStore returns the store of the credentials named by the config
+// file, the config file itself unless it sets credsStore.
+func (cli *DockerCli) credentialsStore() credentialsStore {
+	if cli.configFile.CredentialsStore == "" {
+		return &fileStore{configFile: cli.configFile}
+	}
+	return &nativeStore{
+		configFile: cli.configFile,
+		program:    "docker-credential-" + cli.configFile.CredentialsStore,
+	}
+}
+
+// fileStore keeps the credentials base64 encoded in the config file.
+type fileStore struct {
+	configFile *registry.ConfigFile
+}
+
+func (s *fileStore) Get(serverAddress string) (registry.AuthConfig, error) {
+	return s.configFile.ResolveAuthConfig(serverAddress), nil
+}
+
+func (s *fileStore) GetAll() (map[string]registry.AuthConfig, error) {
+	return s.configFile.Configs, nil
+}
+
+func (s *fileStore) Store(authConfig registry.AuthConfig) error {
+	s.configFile.Configs[authConfig.ServerAddress] = authConfig
+	return registry.SaveConfig(s.configFile)
+}
+
+func (s *fileStore) Erase(serverAddress string) error {
+	eraseConfigs(s.configFile, serverAddress)
+	return registry.SaveConfig(s.configFile)
+}
+
+// eraseConfigs removes the entries of serverAddress from the config file,
+// including the ones saved under a url of the registry.
+func eraseConfigs(configFile *registry.ConfigFile, serverAddress string) {
+	serverAddress = registry.NormalizeServerAddress(serverAddress)
+	for key := range configFile.Configs {
+		if registry.NormalizeServerAddress(key) == serverAddress {
+			delete(configFile.Configs, key)
+		}
+	}
+}
+
+// nativeStore keeps the credentials in an external helper program, e.g. one
+// using the keychain of the OS, the config file only keeping the emails.
+//
+// The helper is run with the action as argument: "store" reads the
+// credentials as json on its stdin, "get" reads a server address on its
+// stdin and writes the credentials as json on its stdout, and "erase" reads
+// a server address on its stdin.
+type nativeStore struct {
+	configFile *registry.ConfigFile
+	program    string
+}
+
+// helperCredentials are the credentials exchanged with the helper.
+type helperCredentials struct {
+	ServerURL string
+	Username  string
+	Secret    string
+}
+
+// errCredentialsNotFound is the output of the helpers when they have no
+// credentials for a server address.
+const errCredentialsNotFound = "credentials not found in native keychain"
+
+func (s *nativeStore) Get(serverAddress string) (registry.AuthConfig, error) {
+	authConfig := s.configFile.ResolveAuthConfig(serverAddress)
+	out, err := s.run("get", registry.NormalizeServerAddress(serverAddress))
+	if err != nil {
+		if strings.Contains(err.Error(), errCredentialsNotFound) {
+			return authConfig, nil
+		}
+		return authConfig, err
+	}
+	var creds helperCredentials
+	if err := json.Unmarshal(out, &creds); err != nil {
+		return authConfig, fmt.Errorf("Invalid credentials from %s: %s", s.program, err)
+	}
+	authConfig.Username = creds.Username
+	authConfig.Password = creds.Secret
+	return authConfig, nil
+}
+
+func (s *nativeStore) GetAll() (map[string]registry.AuthConfig, error) {
+	configs := make(map[string]registry.AuthConfig, len(s.configFile.Configs))
+	for serverAddress := range s.configFile.Configs {
+		authConfig, err := s.Get(serverAddress)
+		if err != nil {
+			return nil, err
+		}
+		configs[serverAddress] = authConfig
+	}
+	return configs, nil
+}
+
+func (s *nativeStore) Store(authConfig registry.AuthConfig) error {
+	creds, err := json.Marshal(&helperCredentials{
+		ServerURL: authConfig.ServerAddress,
+		Username:  authConfig.Username,
+		Secret:    authConfig.Password,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := s.run("store", string(creds)); err != nil {
+		return err
+	}
+	s.configFile.Configs[authConfig.ServerAddress] = registry.AuthConfig{Email: authConfig.Email}
+	return registry.SaveConfig(s.configFile)
+}
+
+func (s *nativeStore) Erase(serverAddress string) error {
+	if _, err := s.run("erase", registry.NormalizeServerAddress(serverAddress)); err != nil && !strings.Contains(err.Error(), errCredentialsNotFound) {
+		return err
+	}
+	eraseConfigs(s.configFile, serverAddress)
+	return registry.SaveConfig(s.configFile)
+}
+
+// run runs the helper for action with input on its stdin, and returns its
+// stdout. The helpers report their errors on their stdout.
+func (s *nativeStore) run(action, input string) ([]byte, error) {
+	cmd := exec.Command(s.program, action)
+	cmd.Stdin = strings.NewReader(input)
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := string(bytes.TrimSpace(out)); msg != "" {
+			return nil, fmt.Errorf("Error running %s %s: %s", s.program, action, msg)
+		}
+		return nil, fmt.Errorf("Error running %s %s: %s", s.program, action, err)
+	}
+	return out, nil
+}
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/docker/docker/utils"
+)
+
+// parseFormat parses the go template of a --format flag. The fields
+// missing from the formatted value, or null, are written as empty strings
+// rather than as "<no value>", so a field only some of the objects have, or
+// which older daemons do not return, leaves an empty column.
+func (cli *DockerCli) parseFormat(format string) (*template.Template, error) {
+	tmpl, err := template.New("").Funcs(funcMap).Funcs(template.FuncMap{emptyIfNilFunc: emptyIfNil}).Parse(format)
+	if err != nil {
+		fmt.Fprintf(cli.err, "Template parsing error: %v\n", err)
+		return nil, &utils.StatusError{StatusCode: 64,
+			Status: "Template parsing error: " + err.Error()}
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			printEmptyIfNil(t.Tree.Root)
+		}
+	}
+	return tmpl, nil
+}
+
+// emptyIfNilFunc is the name the parsed templates call emptyIfNil by.
+const emptyIfNilFunc = "emptyIfNil"
+
+func emptyIfNil(v interface{}) interface{} {
+	if v == nil {
+		return ""
+	}
+	return v
+}
+
+// printEmptyIfNil pipes the value of each action of the template node
+// which prints one to emptyIfNil, text/template printing the missing and
+// nil values as "<no value>" whatever its missingkey option.
+func printEmptyIfNil(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			printEmptyIfNil(child)
+		}
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) == 0 {
+			n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+				NodeType: parse.NodeCommand,
+				Pos:      n.Pos,
+				Args:     []parse.Node{parse.NewIdentifier(emptyIfNilFunc).SetPos(n.Pos)},
+			})
+		}
+	case *parse.IfNode:
+		printEmptyIfNil(n.List)
+		printEmptyIfNil(n.ElseList)
+	case *parse.RangeNode:
+		printEmptyIfNil(n.List)
+		printEmptyIfNil(n.ElseList)
+	case *parse.WithNode:
+		printEmptyIfNil(n.List)
+		printEmptyIfNil(n.ElseList)
+	}
+}
+
+// executeFormat executes tmpl on value and writes it to w followed by a
+// newline.
+func executeFormat(w io.Writer, tmpl *template.Template, value interface{}) error {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, value); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// formatList writes each object of the json list body formatted with tmpl.
+// An object the template fails on, e.g. on a field of a null field, is
+// reported and skipped.
+func (cli *DockerCli) formatList(tmpl *template.Template, body []byte) error {
+	var list []interface{}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return err
+	}
+	status := 0
+	for _, value := range list {
+		if err := executeFormat(cli.out, tmpl, value); err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			status = 1
+		}
+	}
+	if status != 0 {
+		return &utils.StatusError{StatusCode: status}
+	}
+	return nil
+}
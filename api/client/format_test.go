@@ -0,0 +1,101 @@
+package client
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/docker/docker/utils"
+)
+
+func TestParseFormat(t *testing.T) {
+	var errOut bytes.Buffer
+	cli := &DockerCli{err: &errOut}
+
+	_, err := cli.parseFormat("{{.Id")
+	if err == nil {
+		t.Fatal("Expected an error for an invalid template")
+	}
+	if statusErr, ok := err.(*utils.StatusError); !ok || statusErr.StatusCode != 64 {
+		t.Fatalf("Expected a status error with the code 64, got %#v", err)
+	}
+	if errOut.Len() == 0 {
+		t.Fatal("Expected the parsing error to be reported")
+	}
+
+	if _, err := cli.parseFormat(`{{json .Config}} {{join .Names ","}}`); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExecuteFormat(t *testing.T) {
+	cli := &DockerCli{}
+	value := map[string]interface{}{
+		"Id":     "abc",
+		"Null":   nil,
+		"Text":   "<no value>",
+		"Config": map[string]interface{}{"Hostname": "web"},
+		"Names":  []interface{}{"/web", nil},
+	}
+	for format, expected := range map[string]string{
+		"{{.Id}}":                                    "abc\n",
+		"[{{.Missing}}]":                             "[]\n",
+		"[{{.Null}}]":                                "[]\n",
+		"[{{.Config.Missing}}]":                      "[]\n",
+		"{{.Text}}":                                  "<no value>\n",
+		"{{.Config.Hostname}}":                       "web\n",
+		"{{range .Names}}[{{.}}]{{end}}":             "[/web][]\n",
+		"{{with .Config}}{{.Hostname}}{{end}}":       "web\n",
+		"{{if .Missing}}x{{else}}[{{.Null}}]{{end}}": "[]\n",
+		"{{$id := .Id}}{{$id}}":                      "abc\n",
+		`{{define "id"}}[{{.Missing}}]{{end}}{{template "id" .}}`: "[]\n",
+	} {
+		tmpl, err := cli.parseFormat(format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		if err := executeFormat(&out, tmpl, value); err != nil {
+			t.Fatalf("%s: %s", format, err)
+		}
+		if out.String() != expected {
+			t.Errorf("%s: expected %q, got %q", format, expected, out.String())
+		}
+	}
+}
+
+func TestFormatList(t *testing.T) {
+	var out, errOut bytes.Buffer
+	cli := &DockerCli{out: &out, err: &errOut}
+
+	tmpl, err := cli.parseFormat("{{.Id}} {{.Config.Hostname}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cli.formatList(tmpl, []byte(`[{"Id": "a", "Config": {"Hostname": "web"}}, {"Id": "b"}]`)); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "a web\nb \n"; out.String() != expected {
+		t.Fatalf("Expected %q, got %q", expected, out.String())
+	}
+
+	// the objects the template fails on are reported and skipped
+	out.Reset()
+	tmpl, err = cli.parseFormat("{{.Id}} {{index .Names 1}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cli.formatList(tmpl, []byte(`[{"Id": "a", "Names": ["x"]}, {"Id": "b", "Names": ["x", "y"]}]`))
+	if statusErr, ok := err.(*utils.StatusError); !ok || statusErr.StatusCode != 1 {
+		t.Fatalf("Expected a status error with the code 1, got %#v", err)
+	}
+	if expected := "b y\n"; out.String() != expected {
+		t.Fatalf("Expected %q, got %q", expected, out.String())
+	}
+	if errOut.Len() == 0 {
+		t.Fatal("Expected the failing object to be reported")
+	}
+
+	if err := cli.formatList(tmpl, []byte("{}")); err == nil {
+		t.Fatal("Expected an error for a body which is not a list")
+	}
+}
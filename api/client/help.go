@@ -0,0 +1,274 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	flag "github.com/docker/docker/pkg/mflag"
+)
+
+// dockerCommands are the commands listed by docker help, with their
+// description.
+var dockerCommands = [][]string{
+	{"annotate", "Set or remove annotations on an image"},
+	{"attach", "Attach to a running container"},
+	{"build", "Build an image from a Dockerfile"},
+	{"commit", "Create a new image from a container's changes"},
+	{"cp", "Copy files/folders from a container's filesystem to the host path"},
+	{"df", "Show the disk space used by images, containers and volumes"},
+	{"diff", "Inspect changes on a container's filesystem"},
+	{"drain", "Put the daemon in maintenance mode"},
+	{"events", "Get real time events from the server"},
+	{"export", "Stream the contents of a container as a tar archive"},
+	{"history", "Show the history of an image"},
+	{"image", "Mount images for inspection"},
+	{"images", "List images"},
+	{"import", "Create a new filesystem image from the contents of a tarball"},
+	{"info", "Display system-wide information"},
+	{"inspect", "Return low-level information on a container"},
+	{"kill", "Kill a running container"},
+	{"load", "Load an image from a tar archive"},
+	{"login", "Register or log in to a Docker registry server"},
+	{"logout", "Log out from a Docker registry server"},
+	{"logs", "Fetch the logs of a container"},
+	{"network", "Manage networks"},
+	{"port", "Lookup the public-facing port that is NAT-ed to PRIVATE_PORT"},
+	{"pause", "Pause all processes within a container"},
+	{"plugin", "Manage plugins"},
+	{"prune", "Remove the stopped containers and the dangling images"},
+	{"ps", "List containers"},
+	{"pull", "Pull an image or a repository from a Docker registry server"},
+	{"push", "Push an image or a repository to a Docker registry server"},
+	{"restart", "Restart a running container"},
+	{"rm", "Remove one or more containers"},
+	{"rmi", "Remove one or more images"},
+	{"run", "Run a command in a new container"},
+	{"save", "Save one or more images to a tar archive"},
+	{"search", "Search for an image on the Docker Hub"},
+	{"start", "Start a stopped container"},
+	{"stop", "Stop a running container"},
+	{"tag", "Tag an image into a repository"},
+	{"tlsconfig", "Generate the TLS certificates of a daemon and its clients"},
+	{"token", "Manage the API tokens of the token socket"},
+	{"top", "Lookup the running processes of a container"},
+	{"unpause", "Unpause a paused container"},
+	{"update", "Update the resource limits of one or more containers"},
+	{"version", "Show the Docker version information"},
+	{"volume", "Manage named volumes"},
+	{"wait", "Block until a container stops, then print its exit code"},
+}
+
+// commandGroups are the subcommands of the commands which have some, such
+// as network create.
+var commandGroups = map[string][]string{
+	"image":   {"mount", "mounts", "umount"},
+	"network": {"connect", "create", "disconnect", "inspect", "ls", "rm"},
+	"plugin":  {"ls"},
+	"token":   {"create", "ls", "rm"},
+	"volume":  {"create", "inspect", "ls", "rm"},
+}
+
+// helpArgs returns the arguments showing the help of the command args[0],
+// or of its subcommand args[1] when it has some.
+func helpArgs(args []string) []string {
+	if len(args) > 1 && commandGroups[args[0]] != nil {
+		return []string{args[1], "--help"}
+	}
+	return []string{"--help"}
+}
+
+// printManPage writes the man page of the command of args, or without
+// arguments the commands and the subcommands, one per line, to generate
+// theirs.
+func (cli *DockerCli) printManPage(args ...string) error {
+	if len(args) == 0 {
+		for _, command := range dockerCommands {
+			fmt.Fprintln(cli.out, command[0])
+			for _, subcommand := range commandGroups[command[0]] {
+				fmt.Fprintln(cli.out, command[0], subcommand)
+			}
+		}
+		return nil
+	}
+	method, exists := cli.getMethod(args[0])
+	if !exists {
+		return fmt.Errorf("Error: Command not found: %s", args[0])
+	}
+	cli.manPage = true
+	return method(helpArgs(args)...)
+}
+
+// commandExample is an example of a command line, with what it does.
+type commandExample struct {
+	Description string
+	Command     string
+}
+
+// commandExamples are the examples shown by the help of the commands, by
+// the name given to Subcmd, and in their man pages.
+var commandExamples = map[string][]commandExample{
+	"attach": {
+		{"Attach to the output and the input of a running container", "docker attach web"},
+	},
+	"build": {
+		{"Build an image from the Dockerfile of the current directory and tag it", "docker build -t myapp:1.0 ."},
+		{"Build with another Dockerfile, without cache", "docker build --no-cache -f Dockerfile.debug -t myapp:debug ."},
+	},
+	"commit": {
+		{"Create an image from the changes of a container", "docker commit -m \"Add nginx\" web myapp:nginx"},
+	},
+	"cp": {
+		{"Copy the logs of a container to the current directory", "docker cp web:/var/log/nginx ."},
+	},
+	"diff": {
+		{"List the files changed in a container", "docker diff web"},
+	},
+	"events": {
+		{"Show the events since a date, then stream the new ones", "docker events --since 2014-09-01T10:00"},
+	},
+	"export": {
+		{"Export the filesystem of a container to a tar archive", "docker export web > web.tar"},
+	},
+	"history": {
+		{"Show how an image was built, layer by layer", "docker history ubuntu:14.04"},
+	},
+	"images": {
+		{"List the images, with their full IDs", "docker images --no-trunc"},
+		{"List the images which are not tagged anymore", "docker images --filter dangling=true"},
+	},
+	"import": {
+		{"Create an image from a tarball on the web", "docker import http://example.com/rootfs.tar.gz myimage"},
+	},
+	"inspect": {
+		{"Show the IP address of a container", "docker inspect --format '{{.NetworkSettings.IPAddress}}' web"},
+	},
+	"kill": {
+		{"Send SIGHUP to the main process of a container", "docker kill --signal HUP web"},
+	},
+	"load": {
+		{"Load the images of a tar archive", "docker load -i images.tar"},
+	},
+	"logs": {
+		{"Follow the logs of a container, from its last 100 lines", "docker logs -f --tail 100 web"},
+	},
+	"port": {
+		{"Show the host port published for port 80 of a container", "docker port web 80"},
+	},
+	"ps": {
+		{"List all the containers, running or not", "docker ps -a"},
+		{"List the containers which exited with status 0", "docker ps -a --filter exited=0"},
+	},
+	"pull": {
+		{"Pull an image from the Docker Hub", "docker pull ubuntu:14.04"},
+	},
+	"push": {
+		{"Push an image to a private registry", "docker push registry.example.com:5000/myapp:1.0"},
+	},
+	"rm": {
+		{"Remove a container and its volumes", "docker rm -v web"},
+	},
+	"rmi": {
+		{"Remove an image", "docker rmi myapp:debug"},
+	},
+	"run": {
+		{"Run an interactive shell in a new container, removed on exit", "docker run -t -i --rm ubuntu /bin/bash"},
+		{"Run a web server in the background, publishing its port 80 on port 8080 of the host", "docker run -d -p 8080:80 --name web nginx"},
+		{"Run a container linked to the web container", "docker run --link web:web busybox wget -O- http://web"},
+	},
+	"save": {
+		{"Save an image and its parents to a tar archive", "docker save -o ubuntu.tar ubuntu:14.04"},
+	},
+	"search": {
+		{"Search the images with at least 10 stars", "docker search -s 10 nginx"},
+	},
+	"stop": {
+		{"Stop a container, killing it if it still runs after 30 seconds", "docker stop -t 30 web"},
+	},
+	"tag": {
+		{"Tag an image for a private registry", "docker tag myapp:1.0 registry.example.com:5000/myapp:1.0"},
+	},
+	"top": {
+		{"List the processes of a container with their owner", "docker top web aux"},
+	},
+	"wait": {
+		{"Print the exit code of a container once it stops", "docker wait web"},
+	},
+}
+
+// printExamples writes the examples of a command for its help.
+func (cli *DockerCli) printExamples(out io.Writer, name string) {
+	examples := commandExamples[name]
+	if len(examples) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "\n%s\n\n", cli.T("Examples:"))
+	for _, example := range examples {
+		fmt.Fprintf(out, "  # %s\n  $ %s\n\n", cli.T(example.Description), example.Command)
+	}
+}
+
+// flagNames returns the names of a flag which are not deprecated, with
+// their dashes.
+func flagNames(f *flag.Flag) []string {
+	names := []string{}
+	for _, name := range f.Names {
+		if name[0] != '#' {
+			names = append(names, "-"+name)
+		}
+	}
+	return names
+}
+
+// writeManPage writes the man page of a command, in the Markdown format of
+// go-md2man, from its help: its description, its flags and its examples.
+func writeManPage(out io.Writer, flags *flag.FlagSet, name, signature, description string) {
+	page := "docker-" + strings.Replace(name, " ", "-", -1)
+	fmt.Fprintf(out, "%% DOCKER(1) Docker User Manuals\n%% Docker Community\n%% %s\n", strings.ToUpper(time.Now().Format("January 2006")))
+	fmt.Fprintf(out, "# NAME\n%s - %s\n\n", page, strings.SplitN(description, "\n", 2)[0])
+
+	fmt.Fprintf(out, "# SYNOPSIS\n**docker %s**\n", name)
+	var options []string
+	flags.VisitAll(func(f *flag.Flag) {
+		names := flagNames(f)
+		if len(names) == 0 {
+			return
+		}
+		// a value without default is named after the flag, e.g. NAME
+		value := f.DefValue
+		if value == "" {
+			value = strings.ToUpper(strings.TrimLeft(names[len(names)-1], "-"))
+		}
+		fmt.Fprintf(out, "[**%s**[=*%s*]]\n", strings.Join(names, "**|**"), value)
+
+		switch f.DefValue {
+		case "true", "false":
+			value = "*true*|*false*"
+		case "":
+			value = `""`
+		}
+		options = append(options, fmt.Sprintf("**%s**=%s\n   %s\n", strings.Join(names, "**, **"), value, strings.Replace(f.Usage, "\n", "\n   ", -1)))
+	})
+	if arguments := strings.TrimSpace(strings.Replace(signature, "[OPTIONS]", "", 1)); arguments != "" {
+		fmt.Fprintf(out, "%s\n", arguments)
+	}
+	fmt.Fprintf(out, "\n")
+
+	fmt.Fprintf(out, "# DESCRIPTION\n%s\n\n", description)
+
+	fmt.Fprintf(out, "# OPTIONS\n")
+	if len(options) == 0 {
+		fmt.Fprintf(out, "There are no available options.\n\n")
+	}
+	for _, option := range options {
+		fmt.Fprintf(out, "%s\n", option)
+	}
+
+	if examples := commandExamples[name]; len(examples) > 0 {
+		fmt.Fprintf(out, "# EXAMPLES\n")
+		for _, example := range examples {
+			fmt.Fprintf(out, "%s:\n\n    $ %s\n\n", example.Description, example.Command)
+		}
+	}
+}
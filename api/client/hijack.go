@@ -1,7 +1,6 @@
 package client
 
 import (
-	"crypto/tls"
 	"fmt"
 	"io"
 	"net"
@@ -9,20 +8,17 @@ import (
 	"net/http/httputil"
 	"os"
 	"runtime"
-	"strings"
 
 	"github.com/docker/docker/api"
 	"github.com/docker/docker/dockerversion"
 	"github.com/docker/docker/pkg/log"
 	"github.com/docker/docker/pkg/term"
+	"github.com/docker/docker/pkg/transport"
 	"github.com/docker/docker/utils"
 )
 
 func (cli *DockerCli) dial() (net.Conn, error) {
-	if cli.tlsConfig != nil && cli.proto != "unix" {
-		return tls.Dial(cli.proto, cli.addr, cli.tlsConfig)
-	}
-	return net.Dial(cli.proto, cli.addr)
+	return transport.Dial(cli.proto, cli.addr, cli.tlsConfig, cli.connectTimeout)
 }
 
 func (cli *DockerCli) hijack(method, path string, setRawTerminal bool, in io.ReadCloser, stdout, stderr io.Writer, started chan io.Closer) error {
@@ -36,14 +32,15 @@ func (cli *DockerCli) hijack(method, path string, setRawTerminal bool, in io.Rea
 	if err != nil {
 		return err
 	}
+	cli.addConfigHeaders(req)
 	req.Header.Set("User-Agent", "Docker-Client/"+dockerversion.VERSION)
 	req.Header.Set("Content-Type", "plain/text")
-	req.Host = cli.addr
+	req.Host = cli.host()
 
 	dial, err := cli.dial()
 	if err != nil {
-		if strings.Contains(err.Error(), "connection refused") {
-			return fmt.Errorf("Cannot connect to the Docker daemon. Is 'docker -d' running on this host?")
+		if transport.IsNoDaemon(err) {
+			return ErrConnectionRefused
 		}
 		return err
 	}
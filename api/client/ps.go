@@ -0,0 +1,191 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/docker/api"
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/pkg/units"
+	"github.com/docker/docker/utils"
+)
+
+// The columns of `docker ps --format table`, the same as without --format.
+const defaultPsTableFormat = "{{.ID}}\t{{.Image}}\t{{.Command}}\t{{.RunningFor}} ago\t{{.Status}}\t{{.Ports}}\t{{.Names}}"
+
+// containerContext is a container of the list of `docker ps`, as given to
+// the templates of its --format flag. Each field is a method, which records
+// the header of its column for the table directive.
+type containerContext struct {
+	trunc  bool
+	out    *engine.Env
+	header []string
+}
+
+func (c *containerContext) addHeader(header string) {
+	c.header = append(c.header, header)
+}
+
+func (c *containerContext) ID() string {
+	c.addHeader("CONTAINER ID")
+	if c.trunc {
+		return utils.TruncateID(c.out.Get("Id"))
+	}
+	return c.out.Get("Id")
+}
+
+// Id is ID, as the field of the json of the daemon is named.
+func (c *containerContext) Id() string {
+	return c.ID()
+}
+
+func (c *containerContext) Image() string {
+	c.addHeader("IMAGE")
+	return c.out.Get("Image")
+}
+
+func (c *containerContext) Digest() string {
+	c.addHeader("DIGEST")
+	return c.out.Get("ImageDigest")
+}
+
+func (c *containerContext) Command() string {
+	c.addHeader("COMMAND")
+	command := strconv.Quote(c.out.Get("Command"))
+	if c.trunc {
+		command = utils.Trunc(command, 20)
+	}
+	return command
+}
+
+func (c *containerContext) CreatedAt() string {
+	c.addHeader("CREATED AT")
+	return createdTime(c.out).Local().String()
+}
+
+func (c *containerContext) RunningFor() string {
+	c.addHeader("CREATED")
+	return units.HumanDuration(time.Now().UTC().Sub(createdTime(c.out)))
+}
+
+func (c *containerContext) Status() string {
+	c.addHeader("STATUS")
+	status := c.out.Get("Status")
+	if restarts := c.out.GetInt("RestartCount"); restarts > 0 {
+		status = fmt.Sprintf("%s (restarted %d times)", status, restarts)
+	}
+	return status
+}
+
+func (c *containerContext) Ports() string {
+	c.addHeader("PORTS")
+	ports := engine.NewTable("", 0)
+	ports.ReadListFrom([]byte(c.out.Get("Ports")))
+	return api.DisplayablePorts(ports)
+}
+
+func (c *containerContext) Names() string {
+	c.addHeader("NAMES")
+	names := c.out.GetList("Names")
+	for i, name := range names {
+		names[i] = strings.TrimPrefix(name, "/")
+	}
+	return strings.Join(names, ",")
+}
+
+func (c *containerContext) Size() string {
+	c.addHeader("SIZE")
+	if c.out.GetInt64("SizeRootFs") > 0 {
+		return fmt.Sprintf("%s (virtual %s)", units.HumanSize(c.out.GetInt64("SizeRw")), units.HumanSize(c.out.GetInt64("SizeRootFs")))
+	}
+	return units.HumanSize(c.out.GetInt64("SizeRw"))
+}
+
+// Labels returns the labels of the container, the annotations of its
+// image, as KEY=VALUE pairs sorted by key. It is empty when the daemon does
+// not report them.
+func (c *containerContext) Labels() string {
+	c.addHeader("LABELS")
+	labels := c.labels()
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Label returns the value of the label name of the container.
+func (c *containerContext) Label(name string) string {
+	c.addHeader(strings.ToUpper(name))
+	return c.labels()[name]
+}
+
+func (c *containerContext) labels() map[string]string {
+	var labels map[string]string
+	c.out.GetJson("Labels", &labels)
+	return labels
+}
+
+// formatContainers writes the containers of outs formatted with format. A
+// format starting with "table" writes aligned columns under the headers of
+// the fields of the template, and "table" alone the columns of docker ps.
+func (cli *DockerCli) formatContainers(format string, outs *engine.Table, trunc bool) error {
+	table := strings.HasPrefix(format, "table")
+	if table {
+		format = strings.TrimSpace(strings.TrimPrefix(format, "table"))
+		if format == "" {
+			format = defaultPsTableFormat
+		}
+		// a \t typed in the shell separates the columns too
+		format = strings.Replace(format, `\t`, "\t", -1)
+	}
+	tmpl, err := cli.parseFormat(format)
+	if err != nil {
+		return err
+	}
+
+	var (
+		buf    bytes.Buffer
+		header []string
+		status = 0
+	)
+	for i, out := range outs.Data {
+		c := &containerContext{trunc: trunc, out: out}
+		if err := executeFormat(&buf, tmpl, c); err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			status = 1
+			continue
+		}
+		if i == 0 {
+			header = c.header
+		}
+	}
+	if table {
+		if header == nil {
+			// without containers, the header is found on an empty one
+			c := &containerContext{out: &engine.Env{}}
+			tmpl.Execute(ioutil.Discard, c)
+			header = c.header
+		}
+		w := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
+		fmt.Fprintln(w, cli.tableHeader(strings.Join(header, "\t")))
+		buf.WriteTo(w)
+		err = w.Flush()
+	} else {
+		_, err = buf.WriteTo(cli.out)
+	}
+	if err != nil {
+		return err
+	}
+	if status != 0 {
+		return &utils.StatusError{StatusCode: status}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package client
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/utils"
+)
+
+func newTestContainers() *engine.Table {
+	outs := engine.NewTable("", 0)
+	web := &engine.Env{}
+	web.Set("Id", "4c01db0b339cbc4e8b4d6e4fcb7e0a9bb1c6bb2b6ba6f0dd4e5e4a2bdfd7b1a1")
+	web.SetList("Names", []string{"/webapp"})
+	web.Set("Image", "training/webapp:latest")
+	web.Set("Status", "Up 16 seconds")
+	web.SetJson("Labels", map[string]string{"tier": "front", "approved-by": "sec"})
+	outs.Add(web)
+	db := &engine.Env{}
+	db.Set("Id", "d7886598dbe24a1b6b9f1f1e4b8e4d1c6b2d8a4e6f3d2c1b0a9f8e7d6c5b4a39")
+	db.SetList("Names", []string{"/redis", "/webapp/db"})
+	db.Set("Image", "redis:2.8")
+	db.Set("Status", "Up 33 minutes")
+	outs.Add(db)
+	return outs
+}
+
+func TestFormatContainers(t *testing.T) {
+	var out, errOut bytes.Buffer
+	cli := &DockerCli{out: &out, err: &errOut}
+
+	for format, expected := range map[string]string{
+		"{{.ID}}: {{.Names}}": "4c01db0b339c: webapp\nd7886598dbe2: redis,webapp/db\n",
+		// .Id is the field of the json of the daemon
+		"{{.Id}}":                      "4c01db0b339c\nd7886598dbe2\n",
+		"{{.Labels}}":                  "approved-by=sec,tier=front\n\n",
+		`{{.Label "tier"}}`:            "front\n\n",
+		"{{.Image}} ({{.Status}})":     "training/webapp:latest (Up 16 seconds)\nredis:2.8 (Up 33 minutes)\n",
+		"{{.Missing}}{{.Image}}":       "",
+		"[{{with .Size}}{{.}}{{end}}]": "[0 B]\n[0 B]\n",
+	} {
+		out.Reset()
+		err := cli.formatContainers(format, newTestContainers(), true)
+		if expected == "" {
+			// a field the containers do not have fails the template
+			if err == nil {
+				t.Errorf("%s: expected an error", format)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %s", format, err)
+		}
+		if out.String() != expected {
+			t.Errorf("%s: expected %q, got %q", format, expected, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := cli.formatContainers("{{.ID}}", newTestContainers(), false); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "4c01db0b339cbc4e8b4d6e4fcb7e0a9bb1c6bb2b6ba6f0dd4e5e4a2bdfd7b1a1\n") {
+		t.Errorf("Expected the full ID without truncation, got %q", out.String())
+	}
+}
+
+func TestFormatContainersTable(t *testing.T) {
+	var out, errOut bytes.Buffer
+	cli := &DockerCli{out: &out, err: &errOut}
+
+	if err := cli.formatContainers(`table {{.ID}}\t{{.Names}}\t{{.Label "tier"}}`, newTestContainers(), true); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected a header and 2 rows, got %q", out.String())
+	}
+	for i, expected := range [][]string{
+		{"CONTAINER", "ID", "NAMES", "TIER"},
+		{"4c01db0b339c", "webapp", "front"},
+		{"d7886598dbe2", "redis,webapp/db"},
+	} {
+		if fields := strings.Fields(lines[i]); strings.Join(fields, " ") != strings.Join(expected, " ") {
+			t.Errorf("Expected the line %d to be %v, got %q", i, expected, lines[i])
+		}
+	}
+	// the columns are aligned
+	if strings.Index(lines[0], "NAMES") != strings.Index(lines[1], "webapp") {
+		t.Errorf("Expected aligned columns, got %q", out.String())
+	}
+
+	// "table" alone has the columns of docker ps, even without containers
+	out.Reset()
+	if err := cli.formatContainers("table", engine.NewTable("", 0), true); err != nil {
+		t.Fatal(err)
+	}
+	header := strings.Fields(out.String())
+	if expected := "CONTAINER ID IMAGE COMMAND CREATED STATUS PORTS NAMES"; strings.Join(header, " ") != expected {
+		t.Fatalf("Expected the header %q, got %q", expected, out.String())
+	}
+
+	out.Reset()
+	err := cli.formatContainers("table {{.Missing}}", newTestContainers(), true)
+	if statusErr, ok := err.(*utils.StatusError); !ok || statusErr.StatusCode != 1 {
+		t.Fatalf("Expected a status error with the code 1, got %#v", err)
+	}
+}
@@ -8,22 +8,29 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"net"
 	"net/http"
 	"net/url"
 	"os"
 	gosignal "os/signal"
+	"path"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/docker/docker/api"
+	"github.com/docker/docker/archive"
 	"github.com/docker/docker/dockerversion"
 	"github.com/docker/docker/engine"
 	"github.com/docker/docker/pkg/log"
 	"github.com/docker/docker/pkg/term"
+	"github.com/docker/docker/pkg/timeutils"
+	"github.com/docker/docker/pkg/transport"
+	"github.com/docker/docker/pkg/units"
 	"github.com/docker/docker/registry"
 	"github.com/docker/docker/utils"
+	"github.com/docker/docker/vendor/src/code.google.com/p/go/src/pkg/archive/tar"
 )
 
 var (
@@ -31,16 +38,60 @@ var (
 )
 
 func (cli *DockerCli) HTTPClient() *http.Client {
-	tr := &http.Transport{
-		TLSClientConfig: cli.tlsConfig,
-		Dial: func(network, addr string) (net.Conn, error) {
-			return net.Dial(cli.proto, cli.addr)
-		},
+	return cli.httpClient(cli.responseTimeout)
+}
+
+// httpClient returns a client waiting at most responseTimeout for the
+// headers of the responses, or forever if it is 0.
+// The clients share a transport per timeout, which keeps the connections to
+// the daemon alive between the requests.
+func (cli *DockerCli) httpClient(responseTimeout time.Duration) *http.Client {
+	cli.transportsLock.Lock()
+	defer cli.transportsLock.Unlock()
+	tr, exists := cli.transports[responseTimeout]
+	if !exists {
+		tr = transport.New(cli.proto, cli.addr, cli.tlsConfig, cli.connectTimeout, responseTimeout)
+		if cli.transports == nil {
+			cli.transports = make(map[time.Duration]*http.Transport)
+		}
+		cli.transports[responseTimeout] = tr
 	}
 	return &http.Client{Transport: tr}
 }
 
+// host returns the host of the requests to the daemon: its address over
+// tcp, and a placeholder over a socket or a pipe, whose path is not a valid
+// host.
+func (cli *DockerCli) host() string {
+	if cli.proto == "tcp" {
+		return cli.addr
+	}
+	return "docker"
+}
+
+// waitsForStop returns whether the daemon answers a request to path once
+// a container stopped, which can take any time.
+func waitsForStop(path string) bool {
+	path = strings.SplitN(path, "?", 2)[0]
+	for _, suffix := range []string{"/wait", "/stop", "/restart"} {
+		if strings.HasSuffix(path, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
 func (cli *DockerCli) call(method, path string, data interface{}, passAuthInfo bool) (io.ReadCloser, int, error) {
+	authHostname := ""
+	if passAuthInfo {
+		authHostname = registry.IndexServerAddress()
+	}
+	return cli.callAuth(method, path, data, authHostname)
+}
+
+// callAuth is call passing the credentials of the registry authHostname, if
+// not empty, to the daemon.
+func (cli *DockerCli) callAuth(method, path string, data interface{}, authHostname string) (io.ReadCloser, int, error) {
 	params := bytes.NewBuffer(nil)
 	if data != nil {
 		if env, ok := data.(engine.Env); ok {
@@ -62,10 +113,14 @@ func (cli *DockerCli) call(method, path string, data interface{}, passAuthInfo b
 	if err != nil {
 		return nil, -1, err
 	}
-	if passAuthInfo {
+	cli.addConfigHeaders(req)
+	if authHostname != "" {
 		cli.LoadConfigFile()
 		// Resolve the Auth config relevant for this server
-		authConfig := cli.configFile.ResolveAuthConfig(registry.IndexServerAddress())
+		authConfig, err := cli.credentialsStore().Get(authHostname)
+		if err != nil {
+			return nil, -1, err
+		}
 		getHeaders := func(authConfig registry.AuthConfig) (map[string][]string, error) {
 			buf, err := json.Marshal(authConfig)
 			if err != nil {
@@ -83,16 +138,20 @@ func (cli *DockerCli) call(method, path string, data interface{}, passAuthInfo b
 		}
 	}
 	req.Header.Set("User-Agent", "Docker-Client/"+dockerversion.VERSION)
-	req.URL.Host = cli.addr
+	req.URL.Host = cli.host()
 	req.URL.Scheme = cli.scheme
 	if data != nil {
 		req.Header.Set("Content-Type", "application/json")
 	} else if method == "POST" {
 		req.Header.Set("Content-Type", "plain/text")
 	}
-	resp, err := cli.HTTPClient().Do(req)
+	client := cli.HTTPClient()
+	if waitsForStop(path) {
+		client = cli.httpClient(0)
+	}
+	resp, err := client.Do(req)
 	if err != nil {
-		if strings.Contains(err.Error(), "connection refused") {
+		if transport.IsNoDaemon(err) {
 			return nil, -1, ErrConnectionRefused
 		}
 		return nil, -1, err
@@ -124,8 +183,9 @@ func (cli *DockerCli) streamHelper(method, path string, setRawTerminal bool, in
 	if err != nil {
 		return err
 	}
+	cli.addConfigHeaders(req)
 	req.Header.Set("User-Agent", "Docker-Client/"+dockerversion.VERSION)
-	req.URL.Host = cli.addr
+	req.URL.Host = cli.host()
 	req.URL.Scheme = cli.scheme
 	if method == "POST" {
 		req.Header.Set("Content-Type", "plain/text")
@@ -138,9 +198,10 @@ func (cli *DockerCli) streamHelper(method, path string, setRawTerminal bool, in
 	}
 	log.Debugf("Send req to daemon: %#v\n", req.URL)
 	// 发送封装的请求到docker daemon端
-	resp, err := cli.HTTPClient().Do(req)
+	// the streams answer as they go, e.g. the builds, without a time limit
+	resp, err := cli.httpClient(0).Do(req)
 	if err != nil {
-		if strings.Contains(err.Error(), "connection refused") {
+		if transport.IsNoDaemon(err) {
 			return fmt.Errorf("Cannot connect to the Docker daemon. Is 'docker -d' running on this host?")
 		}
 		return err
@@ -159,7 +220,7 @@ func (cli *DockerCli) streamHelper(method, path string, setRawTerminal bool, in
 	}
 
 	if api.MatchesContentType(resp.Header.Get("Content-Type"), "application/json") {
-		return utils.DisplayJSONMessagesStream(resp.Body, stdout, cli.terminalFd, cli.isTerminal)
+		return utils.DisplayJSONMessagesStream(resp.Body, stdout, cli.terminalFd, cli.progressTty)
 	}
 	if stdout != nil || stderr != nil {
 		// When TTY is ON, use regular copy
@@ -174,6 +235,57 @@ func (cli *DockerCli) streamHelper(method, path string, setRawTerminal bool, in
 	return nil
 }
 
+// transferProgress is a writer displaying the bytes written through it on
+// the error output of the client, for the transfers streaming raw data to
+// the output.
+type transferProgress struct {
+	out        io.Writer
+	display    *io.PipeWriter
+	displayed  chan error
+	sf         *utils.StreamFormatter
+	id         string
+	action     string
+	progress   utils.JSONProgress
+	lastUpdate int
+}
+
+func (cli *DockerCli) newTransferProgress(out io.Writer, id, action string) *transferProgress {
+	pr, pw := io.Pipe()
+	p := &transferProgress{
+		out:       out,
+		display:   pw,
+		displayed: make(chan error, 1),
+		sf:        utils.NewStreamFormatter(true),
+		id:        id,
+		action:    action,
+	}
+	go func() {
+		err := utils.DisplayJSONMessagesStream(pr, cli.err, cli.terminalFd, cli.progressTty)
+		// drain the messages left after an error
+		io.Copy(ioutil.Discard, pr)
+		p.displayed <- err
+	}()
+	return p
+}
+
+func (p *transferProgress) Write(b []byte) (int, error) {
+	n, err := p.out.Write(b)
+	p.progress.Current += n
+	if p.progress.Current-p.lastUpdate > 512*1024 {
+		p.display.Write(p.sf.FormatProgress(p.id, p.action, &p.progress))
+		p.lastUpdate = p.progress.Current
+	}
+	return n, err
+}
+
+// Close displays the total written as status, and waits for the display.
+func (p *transferProgress) Close(status string) error {
+	p.display.Write(p.sf.FormatProgress(p.id, p.action, &p.progress))
+	p.display.Write(p.sf.FormatStatus(p.id, "%s %s", status, units.HumanSize(int64(p.progress.Current))))
+	p.display.Close()
+	return <-p.displayed
+}
+
 func (cli *DockerCli) resizeTty(id string) {
 	height, width := cli.getTtySize()
 	if height == 0 && width == 0 {
@@ -261,3 +373,82 @@ func readBody(stream io.ReadCloser, statusCode int, err error) ([]byte, int, err
 	}
 	return body, statusCode, nil
 }
+
+// addBuildContexts appends the directories of the additional build contexts,
+// by name, to the build context, under api.BUILDCONTEXTSDIR.
+func addBuildContexts(context archive.Archive, dirs map[string]string) archive.Archive {
+	names := make([]string, 0, len(dirs))
+	for name := range dirs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	r, w := io.Pipe()
+	go func() {
+		defer context.Close()
+		tw := tar.NewWriter(w)
+		err := copyTar(tw, context, "")
+		for _, name := range names {
+			if err != nil {
+				break
+			}
+			var a archive.Archive
+			if a, err = archive.TarWithOptions(dirs[name], &archive.TarOptions{Compression: archive.Uncompressed}); err != nil {
+				break
+			}
+			err = copyTar(tw, a, path.Join(api.BUILDCONTEXTSDIR, name))
+			a.Close()
+		}
+		if err == nil {
+			err = tw.Close()
+		}
+		w.CloseWithError(err)
+	}()
+	return r
+}
+
+// copyTar copies the entries of the tar stream, possibly compressed, of in
+// to tw, with prefix prepended to their paths.
+func copyTar(tw *tar.Writer, in io.Reader, prefix string) error {
+	decompressed, err := archive.DecompressStream(in)
+	if err != nil {
+		return err
+	}
+	defer decompressed.Close()
+	tr := tar.NewReader(decompressed)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if prefix != "" {
+			isDir := strings.HasSuffix(hdr.Name, "/")
+			hdr.Name = path.Join(prefix, hdr.Name)
+			if isDir {
+				hdr.Name += "/"
+			}
+			if hdr.Typeflag == tar.TypeLink {
+				hdr.Linkname = path.Join(prefix, hdr.Linkname)
+			}
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// createdTime returns when the entry of a list was created, its Created
+// being a unix timestamp before API 1.15 and an RFC 3339 date since.
+func createdTime(out *engine.Env) time.Time {
+	t, err := timeutils.Parse(out.Get("Created"), time.UTC)
+	if err != nil {
+		return time.Unix(0, 0)
+	}
+	return t
+}
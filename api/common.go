@@ -12,9 +12,14 @@ import (
 )
 
 const (
-	APIVERSION        version.Version = "1.14"
+	APIVERSION        version.Version = "1.15"
 	DEFAULTHTTPHOST                   = "127.0.0.1"
 	DEFAULTUNIXSOCKET                 = "/var/run/docker.sock"
+	// DEFAULTNAMEDPIPE is the daemon of the clients on Windows
+	DEFAULTNAMEDPIPE = "//./pipe/docker_engine"
+	// BUILDCONTEXTSDIR is the directory of the build context under which
+	// the client sends the additional build contexts from its directories
+	BUILDCONTEXTSDIR = ".dockercontexts"
 )
 
 func ValidateHost(val string) (string, error) {
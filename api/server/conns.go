@@ -0,0 +1,279 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/pkg/log"
+)
+
+// slowWriteThreshold is how long a write to a client may block before its
+// connection is logged as slow.
+const slowWriteThreshold = 30 * time.Second
+
+// reapInterval is how often the tracked connections are checked.
+var reapInterval = 10 * time.Second
+
+// tracker tracks the connections of all the api listeners.
+var tracker = newConnTracker()
+
+// connTracker tracks the connections of the api listeners, to report them
+// and to find the slow or stuck ones. The hijacked connections, e.g. of the
+// attach requests, are not looked after by the http server anymore, so a
+// client going away without closing them would leak them and the
+// goroutines serving them forever; those idle for more than idleTimeout
+// are closed, if it is set.
+type connTracker struct {
+	sync.Mutex
+	conns       map[*trackedConn]struct{}
+	idleTimeout time.Duration
+}
+
+func newConnTracker() *connTracker {
+	return &connTracker{conns: make(map[*trackedConn]struct{})}
+}
+
+func (t *connTracker) setIdleTimeout(d time.Duration) {
+	t.Lock()
+	t.idleTimeout = d
+	t.Unlock()
+}
+
+// wrap returns a listener whose connections are tracked by t.
+func (t *connTracker) wrap(l net.Listener) net.Listener {
+	return &trackedListener{Listener: l, tracker: t}
+}
+
+func (t *connTracker) add(conn net.Conn) *trackedConn {
+	now := time.Now()
+	c := &trackedConn{
+		Conn:       conn,
+		tracker:    t,
+		start:      now,
+		lastActive: now,
+	}
+	t.Lock()
+	t.conns[c] = struct{}{}
+	t.Unlock()
+	return c
+}
+
+func (t *connTracker) remove(c *trackedConn) {
+	t.Lock()
+	_, exists := t.conns[c]
+	delete(t.conns, c)
+	t.Unlock()
+	if exists {
+		s := c.stats(time.Now())
+		log.Debugf("Closed the connection of %s after %s: %d bytes read, %d bytes written, writes blocked for %s",
+			s.remoteAddr, s.duration, s.bytesRead, s.bytesWritten, s.blocked)
+	}
+}
+
+func (t *connTracker) list() []*trackedConn {
+	t.Lock()
+	defer t.Unlock()
+	conns := make([]*trackedConn, 0, len(t.conns))
+	for c := range t.conns {
+		conns = append(conns, c)
+	}
+	return conns
+}
+
+// reapLoop checks the connections every reapInterval, for ever.
+func (t *connTracker) reapLoop() {
+	for now := range time.Tick(reapInterval) {
+		t.reap(now)
+	}
+}
+
+// reap logs the connections whose writes have been blocked for more than
+// slowWriteThreshold, once each, and closes the hijacked connections idle
+// for more than the idle timeout.
+func (t *connTracker) reap(now time.Time) {
+	t.Lock()
+	idleTimeout := t.idleTimeout
+	t.Unlock()
+	for _, c := range t.list() {
+		s := c.stats(now)
+		if s.writeBlocked > slowWriteThreshold && c.markSlow() {
+			log.Infof("Slow client %s: a write has been blocked for %s", s.remoteAddr, s.writeBlocked)
+		}
+		if idleTimeout > 0 && s.hijacked && s.idle > idleTimeout {
+			log.Infof("Closing the connection of %s to %s, idle for %s", s.remoteAddr, s.path, s.idle)
+			c.Close()
+		}
+	}
+}
+
+// ApiConnections lists the connections to the api.
+func ApiConnections(job *engine.Job) engine.Status {
+	var (
+		now   = time.Now()
+		conns = tracker.list()
+		stats = make([]connStats, len(conns))
+	)
+	for i, c := range conns {
+		stats[i] = c.stats(now)
+	}
+	sort.Sort(byStart(stats))
+	outs := engine.NewTable("", len(stats))
+	for _, s := range stats {
+		out := &engine.Env{}
+		out.Set("RemoteAddr", s.remoteAddr)
+		out.SetBool("Hijacked", s.hijacked)
+		out.Set("Path", s.path)
+		out.SetInt64("Started", s.start.Unix())
+		out.SetInt64("Duration", int64(s.duration/time.Second))
+		out.SetInt64("Idle", int64(s.idle/time.Second))
+		out.SetInt64("BytesRead", s.bytesRead)
+		out.SetInt64("BytesWritten", s.bytesWritten)
+		out.SetInt64("WriteBlocked", int64(s.blocked/time.Second))
+		out.SetBool("Slow", s.slow)
+		outs.Add(out)
+	}
+	if _, err := outs.WriteListTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+type trackedListener struct {
+	net.Listener
+	tracker *connTracker
+}
+
+func (l *trackedListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return l.tracker.add(conn), nil
+}
+
+// trackedConn counts the bytes read and written on a connection, and the
+// time its writes are blocked by a client not reading.
+type trackedConn struct {
+	net.Conn
+	tracker *connTracker
+	start   time.Time
+
+	mu           sync.Mutex
+	bytesRead    int64
+	bytesWritten int64
+	lastActive   time.Time
+	writeStart   time.Time // zero unless a write is in progress
+	blocked      time.Duration
+	hijacked     bool
+	path         string
+	slow         bool
+}
+
+// connStats is a snapshot of the counters of a trackedConn.
+type connStats struct {
+	remoteAddr   string
+	hijacked     bool
+	path         string
+	start        time.Time
+	duration     time.Duration
+	idle         time.Duration
+	bytesRead    int64
+	bytesWritten int64
+	blocked      time.Duration
+	writeBlocked time.Duration
+	slow         bool
+}
+
+type byStart []connStats
+
+func (s byStart) Len() int           { return len(s) }
+func (s byStart) Less(i, j int) bool { return s[i].start.Before(s[j].start) }
+func (s byStart) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+func (c *trackedConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.mu.Lock()
+	c.bytesRead += int64(n)
+	if n > 0 {
+		c.lastActive = time.Now()
+	}
+	c.mu.Unlock()
+	return n, err
+}
+
+func (c *trackedConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	c.writeStart = time.Now()
+	c.mu.Unlock()
+	n, err := c.Conn.Write(b)
+	now := time.Now()
+	c.mu.Lock()
+	c.blocked += now.Sub(c.writeStart)
+	c.writeStart = time.Time{}
+	c.bytesWritten += int64(n)
+	c.lastActive = now
+	c.mu.Unlock()
+	return n, err
+}
+
+func (c *trackedConn) Close() error {
+	c.tracker.remove(c)
+	return c.Conn.Close()
+}
+
+// CloseWrite shuts down the writing side of a tcp connection, and closes
+// the other connections.
+func (c *trackedConn) CloseWrite() error {
+	if tcpc, ok := c.Conn.(*net.TCPConn); ok {
+		return tcpc.CloseWrite()
+	}
+	return c.Close()
+}
+
+// hijack marks the connection as hijacked by the request r.
+func (c *trackedConn) hijack(r *http.Request) {
+	c.mu.Lock()
+	c.hijacked = true
+	c.path = r.URL.Path
+	c.mu.Unlock()
+}
+
+// markSlow marks the connection as slow, and returns whether it was not
+// already.
+func (c *trackedConn) markSlow() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.slow {
+		return false
+	}
+	c.slow = true
+	return true
+}
+
+func (c *trackedConn) stats(now time.Time) connStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := connStats{
+		hijacked:     c.hijacked,
+		path:         c.path,
+		start:        c.start,
+		duration:     now.Sub(c.start),
+		idle:         now.Sub(c.lastActive),
+		bytesRead:    c.bytesRead,
+		bytesWritten: c.bytesWritten,
+		blocked:      c.blocked,
+		slow:         c.slow,
+	}
+	if addr := c.RemoteAddr(); addr != nil {
+		s.remoteAddr = addr.String()
+	}
+	if !c.writeStart.IsZero() {
+		s.writeBlocked = now.Sub(c.writeStart)
+		s.blocked += s.writeBlocked
+	}
+	return s
+}
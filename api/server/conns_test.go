@@ -0,0 +1,79 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestConnTrackerReap(t *testing.T) {
+	ct := newConnTracker()
+	ct.setIdleTimeout(time.Minute)
+
+	server, client := net.Pipe()
+	defer client.Close()
+	hijacked := ct.add(server)
+	other, _ := net.Pipe()
+	idle := ct.add(other)
+	defer idle.Close()
+
+	go func() {
+		buf := make([]byte, 5)
+		client.Read(buf)
+	}()
+	if _, err := hijacked.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	r, err := http.NewRequest("POST", "/v1.14/containers/foo/attach", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hijacked.hijack(r)
+
+	s := hijacked.stats(time.Now())
+	if s.bytesWritten != 5 || !s.hijacked || s.path != "/v1.14/containers/foo/attach" {
+		t.Fatalf("Unexpected stats %#v", s)
+	}
+
+	ct.reap(time.Now())
+	if len(ct.list()) != 2 {
+		t.Fatalf("Expected no connection to be closed before the idle timeout, got %d left", len(ct.list()))
+	}
+
+	// only the hijacked connection is closed once idle
+	ct.reap(time.Now().Add(2 * time.Minute))
+	if conns := ct.list(); len(conns) != 1 || conns[0] != idle {
+		t.Fatalf("Expected only the idle hijacked connection to be closed, got %d left", len(conns))
+	}
+	if _, err := hijacked.Write([]byte("x")); err == nil {
+		t.Fatal("Expected the hijacked connection to be closed")
+	}
+}
+
+func TestConnTrackerSlowWrite(t *testing.T) {
+	ct := newConnTracker()
+	server, client := net.Pipe()
+	defer client.Close()
+	c := ct.add(server)
+
+	// nothing reads the client end, so the write blocks
+	go c.Write([]byte("blocked"))
+	for i := 0; c.stats(time.Now()).writeBlocked == 0; i++ {
+		if i > 100 {
+			t.Fatal("Expected the write to be blocked")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ct.reap(time.Now().Add(time.Minute))
+	s := c.stats(time.Now().Add(time.Minute))
+	if !s.slow || s.blocked < time.Minute {
+		t.Fatalf("Expected the connection to be slow, got %#v", s)
+	}
+	// without an idle timeout, it is not closed
+	if len(ct.list()) != 1 {
+		t.Fatal("Expected the slow connection to be kept")
+	}
+	c.Close()
+}
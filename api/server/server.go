@@ -18,6 +18,7 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"code.google.com/p/go.net/websocket"
 	"github.com/docker/libcontainer/user"
@@ -29,6 +30,7 @@ import (
 	"github.com/docker/docker/pkg/log"
 	"github.com/docker/docker/pkg/parsers"
 	"github.com/docker/docker/pkg/systemd"
+	"github.com/docker/docker/pkg/timeutils"
 	"github.com/docker/docker/pkg/version"
 	"github.com/docker/docker/registry"
 	"github.com/docker/docker/utils"
@@ -40,13 +42,21 @@ var (
 	activationLock chan struct{}
 )
 
+// closeWriter is a connection whose writing side can be shut down alone.
+type closeWriter interface {
+	CloseWrite() error
+}
+
 type HttpApiFunc func(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error
 
-func hijackServer(w http.ResponseWriter) (io.ReadCloser, io.Writer, error) {
+func hijackServer(w http.ResponseWriter, r *http.Request) (io.ReadCloser, io.Writer, error) {
 	conn, _, err := w.(http.Hijacker).Hijack()
 	if err != nil {
 		return nil, nil, err
 	}
+	if c, ok := conn.(*trackedConn); ok {
+		c.hijack(r)
+	}
 	// Flush the options to make sure the client sets the raw mode
 	conn.Write([]byte{})
 	return conn, conn, nil
@@ -87,6 +97,8 @@ func httpError(w http.ResponseWriter, err error) {
 		statusCode = http.StatusUnauthorized
 	} else if strings.Contains(err.Error(), "hasn't been activated") {
 		statusCode = http.StatusForbidden
+	} else if strings.Contains(err.Error(), "is draining") {
+		statusCode = http.StatusServiceUnavailable
 	}
 
 	if err != nil {
@@ -110,6 +122,58 @@ func streamJSON(job *engine.Job, w http.ResponseWriter, flush bool) {
 	}
 }
 
+// writeTimestampedList writes the list outs with the unix timestamps of its
+// key as RFC 3339 dates in UTC, as the API does since 1.15.
+func writeTimestampedList(w http.ResponseWriter, outs *engine.Table, key string) error {
+	for _, out := range outs.Data {
+		if out.Exists(key) {
+			out.Set(key, timeutils.FormatUnix(out.GetInt64(key)))
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err := outs.WriteListTo(w)
+	return err
+}
+
+// eventsWriter writes the events with their unix time as an RFC 3339 date
+// in UTC, as the API does since 1.15. Each write is a whole event.
+type eventsWriter struct {
+	w io.Writer
+}
+
+func (ew *eventsWriter) Write(p []byte) (int, error) {
+	var event map[string]json.RawMessage
+	if len(p) == 0 || json.Unmarshal(p, &event) != nil {
+		return ew.w.Write(p)
+	}
+	var sec int64
+	if err := json.Unmarshal(event["time"], &sec); err == nil {
+		event["time"], _ = json.Marshal(timeutils.FormatUnix(sec))
+	}
+	b, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := ew.w.Write(b); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// setTimestampEnv sets the key of the job to the unix time of value, which
+// is a unix timestamp or an RFC 3339 date, in UTC when it has no time zone.
+func setTimestampEnv(job *engine.Job, key, value string) error {
+	if value != "" {
+		t, err := timeutils.Parse(value, time.UTC)
+		if err != nil {
+			return err
+		}
+		value = strconv.FormatInt(t.Unix(), 10)
+	}
+	job.Setenv(key, value)
+	return nil
+}
+
 func getBoolParam(value string) (bool, error) {
 	if value == "" {
 		return false, nil
@@ -199,10 +263,20 @@ func postContainersUnpause(eng *engine.Engine, version version.Version, w http.R
 }
 
 func getContainersExport(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := parseForm(r); err != nil {
+		return err
+	}
 	if vars == nil {
 		return fmt.Errorf("Missing parameter")
 	}
+	skipVolumes, err := getBoolParam(r.Form.Get("skipvolumes"))
+	if err != nil {
+		return err
+	}
 	job := eng.Job("export", vars["name"])
+	job.SetenvList("Include", r.Form["include"])
+	job.SetenvList("Exclude", r.Form["exclude"])
+	job.SetenvBool("SkipVolumes", skipVolumes)
 	job.Stdout.Add(w)
 	if err := job.Run(); err != nil {
 		return err
@@ -225,8 +299,9 @@ func getImagesJSON(eng *engine.Engine, version version.Version, w http.ResponseW
 	// FIXME this parameter could just be a match filter
 	job.Setenv("filter", r.Form.Get("filter"))
 	job.Setenv("all", r.Form.Get("all"))
+	job.Setenv("sort", r.Form.Get("sort"))
 
-	if version.GreaterThanOrEqualTo("1.7") {
+	if version.GreaterThanOrEqualTo("1.7") && version.LessThan("1.15") {
 		streamJSON(job, w, false)
 	} else if outs, err = job.Stdout.AddListTable(); err != nil {
 		return err
@@ -236,6 +311,10 @@ func getImagesJSON(eng *engine.Engine, version version.Version, w http.ResponseW
 		return err
 	}
 
+	if version.GreaterThanOrEqualTo("1.15") {
+		return writeTimestampedList(w, outs, "Created")
+	}
+
 	if version.LessThan("1.7") && outs != nil { // Convert to legacy format
 		outsLegacy := engine.NewTable("Created", 0)
 		for _, out := range outs.Data {
@@ -268,21 +347,49 @@ func getImagesViz(eng *engine.Engine, version version.Version, w http.ResponseWr
 	return nil
 }
 
+func getImagesGraph(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := parseForm(r); err != nil {
+		return err
+	}
+	var job *engine.Job
+	if image := r.Form.Get("image"); image != "" {
+		job = eng.Job("images_graph", image)
+	} else {
+		job = eng.Job("images_graph")
+	}
+	streamJSON(job, w, false)
+	return job.Run()
+}
+
 func getInfo(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	w.Header().Set("Content-Type", "application/json")
 	eng.ServeHTTP(w, r)
 	return nil
 }
 
+func getInfoCheck(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	job := eng.Job("check_config")
+	streamJSON(job, w, false)
+	return job.Run()
+}
+
 func getEvents(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	if err := parseForm(r); err != nil {
 		return err
 	}
 
 	var job = eng.Job("events")
-	streamJSON(job, w, true)
-	job.Setenv("since", r.Form.Get("since"))
-	job.Setenv("until", r.Form.Get("until"))
+	if version.LessThan("1.15") {
+		streamJSON(job, w, true)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		job.Stdout.Add(&eventsWriter{utils.NewWriteFlusher(w)})
+	}
+	for _, key := range []string{"since", "until"} {
+		if err := setTimestampEnv(job, key, r.Form.Get(key)); err != nil {
+			return err
+		}
+	}
 	return job.Run()
 }
 
@@ -292,12 +399,19 @@ func getImagesHistory(eng *engine.Engine, version version.Version, w http.Respon
 	}
 
 	var job = eng.Job("history", vars["name"])
-	streamJSON(job, w, false)
+	if version.LessThan("1.15") {
+		streamJSON(job, w, false)
+		return job.Run()
+	}
 
+	outs, err := job.Stdout.AddListTable()
+	if err != nil {
+		return err
+	}
 	if err := job.Run(); err != nil {
 		return err
 	}
-	return nil
+	return writeTimestampedList(w, outs, "Created")
 }
 
 func getContainersChanges(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
@@ -310,6 +424,20 @@ func getContainersChanges(eng *engine.Engine, version version.Version, w http.Re
 	return job.Run()
 }
 
+func getContainersPorts(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	if err := parseForm(r); err != nil {
+		return err
+	}
+	job := eng.Job("container_portmap", vars["name"])
+	job.Setenv("Proto", r.Form.Get("proto"))
+	job.Setenv("Port", r.Form.Get("port"))
+	streamJSON(job, w, false)
+	return job.Run()
+}
+
 func getContainersTop(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	if version.LessThan("1.4") {
 		return fmt.Errorf("top was improved a lot since 1.3, Please upgrade your docker client.")
@@ -342,8 +470,13 @@ func getContainersJSON(eng *engine.Engine, version version.Version, w http.Respo
 	job.Setenv("before", r.Form.Get("before"))
 	job.Setenv("limit", r.Form.Get("limit"))
 	job.Setenv("filters", r.Form.Get("filters"))
+	job.Setenv("sort", r.Form.Get("sort"))
 
-	if version.GreaterThanOrEqualTo("1.5") {
+	if version.GreaterThanOrEqualTo("1.15") {
+		if outs, err = job.Stdout.AddListTable(); err != nil {
+			return err
+		}
+	} else if version.GreaterThanOrEqualTo("1.5") {
 		streamJSON(job, w, false)
 	} else if outs, err = job.Stdout.AddTable(); err != nil {
 		return err
@@ -351,6 +484,9 @@ func getContainersJSON(eng *engine.Engine, version version.Version, w http.Respo
 	if err = job.Run(); err != nil {
 		return err
 	}
+	if version.GreaterThanOrEqualTo("1.15") {
+		return writeTimestampedList(w, outs, "Created")
+	}
 	if version.LessThan("1.5") { // Convert to legacy format
 		for _, out := range outs.Data {
 			ports := engine.NewTable("", 0)
@@ -430,6 +566,34 @@ func postImagesTag(eng *engine.Engine, version version.Version, w http.ResponseW
 	return nil
 }
 
+func postImagesAnnotations(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	if !api.MatchesContentType(r.Header.Get("Content-Type"), "application/json") {
+		return fmt.Errorf("Content-Type of application/json is required")
+	}
+	var config struct {
+		Set    map[string]string
+		Remove []string
+	}
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		return err
+	}
+	set := make([]string, 0, len(config.Set))
+	for key, value := range config.Set {
+		set = append(set, key+"="+value)
+	}
+	job := eng.Job("image_annotate", vars["name"])
+	job.SetenvList("Set", set)
+	job.SetenvList("Remove", config.Remove)
+	if err := job.Run(); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
 func postCommit(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	if err := parseForm(r); err != nil {
 		return err
@@ -450,6 +614,7 @@ func postCommit(eng *engine.Engine, version version.Version, w http.ResponseWrit
 		job.Setenv("pause", r.FormValue("pause"))
 	}
 
+	job.Setenv("squash", r.Form.Get("squash"))
 	job.Setenv("repo", r.Form.Get("repo"))
 	job.Setenv("tag", r.Form.Get("tag"))
 	job.Setenv("author", r.Form.Get("author"))
@@ -504,8 +669,14 @@ func postImagesCreate(eng *engine.Engine, version version.Version, w http.Respon
 		if tag == "" {
 			repo, tag = parsers.ParseRepositoryTag(repo)
 		}
+		quiet, err := getBoolParam(r.Form.Get("quiet"))
+		if err != nil {
+			return err
+		}
 		job = eng.Job("import", r.Form.Get("fromSrc"), repo, tag)
 		job.Stdin.Add(r.Body)
+		job.SetenvInt64("Size", r.ContentLength)
+		job.SetenvBool("quiet", quiet)
 	}
 
 	if version.GreaterThan("1.0") {
@@ -610,20 +781,72 @@ func postImagesPush(eng *engine.Engine, version version.Version, w http.Response
 }
 
 func getImagesGet(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
-	if vars == nil {
+	if err := parseForm(r); err != nil {
+		return err
+	}
+	// GET /images/get saves the images named by the names parameters
+	names := r.Form["names"]
+	if name, exists := vars["name"]; exists {
+		names = []string{name}
+	}
+	if len(names) == 0 {
 		return fmt.Errorf("Missing parameter")
 	}
 	if version.GreaterThan("1.0") {
 		w.Header().Set("Content-Type", "application/x-tar")
 	}
-	job := eng.Job("image_export", vars["name"])
+	job := eng.Job("image_export", names...)
 	job.Stdout.Add(w)
 	return job.Run()
 }
 
 func postImagesLoad(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := parseForm(r); err != nil {
+		return err
+	}
+	quiet, err := getBoolParam(r.Form.Get("quiet"))
+	if err != nil {
+		return err
+	}
 	job := eng.Job("load")
 	job.Stdin.Add(r.Body)
+	// the older clients don't expect any output
+	if quiet || version.LessThan("1.14") {
+		return job.Run()
+	}
+	job.SetenvInt64("Size", r.ContentLength)
+	job.SetenvBool("json", true)
+	streamJSON(job, w, true)
+	if err := job.Run(); err != nil {
+		if !job.Stdout.Used() {
+			return err
+		}
+		sf := utils.NewStreamFormatter(true)
+		w.Write(sf.FormatError(err))
+	}
+	return nil
+}
+
+func postContainersPrune(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	return prune(eng, "containers", w, r)
+}
+
+func postImagesPrune(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	return prune(eng, "images", w, r)
+}
+
+func prune(eng *engine.Engine, kind string, w http.ResponseWriter, r *http.Request) error {
+	if err := parseForm(r); err != nil {
+		return err
+	}
+	dryRun, err := getBoolParam(r.Form.Get("dryrun"))
+	if err != nil {
+		return err
+	}
+	job := eng.Job("prune", kind)
+	job.Setenv("filters", r.Form.Get("filters"))
+	job.SetenvBool("dryRun", dryRun)
+	streamJSON(job, w, false)
 	return job.Run()
 }
 
@@ -641,6 +864,25 @@ func postContainersCreate(eng *engine.Engine, version version.Version, w http.Re
 	if err := job.DecodeEnv(r.Body); err != nil {
 		return err
 	}
+	if pull := r.Form.Get("pull"); pull != "" {
+		authConfig := &registry.AuthConfig{}
+		if authEncoded := r.Header.Get("X-Registry-Auth"); authEncoded != "" {
+			authJson := base64.NewDecoder(base64.URLEncoding, strings.NewReader(authEncoded))
+			if err := json.NewDecoder(authJson).Decode(authConfig); err != nil {
+				// as for a pull, it is not an error if no auth was given
+				authConfig = &registry.AuthConfig{}
+			}
+		}
+		metaHeaders := map[string][]string{}
+		for k, v := range r.Header {
+			if strings.HasPrefix(k, "X-Meta-") {
+				metaHeaders[k] = v
+			}
+		}
+		job.Setenv("PullPolicy", pull)
+		job.SetenvJson("authConfig", authConfig)
+		job.SetenvJson("metaHeaders", metaHeaders)
+	}
 	// Read container ID from the first line of stdout
 	job.Stdout.Add(stdoutBuffer)
 	// Read warnings from stderr
@@ -658,6 +900,39 @@ func postContainersCreate(eng *engine.Engine, version version.Version, w http.Re
 	return writeJSON(w, http.StatusCreated, out)
 }
 
+func postContainersClone(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := parseForm(r); err != nil {
+		return err
+	}
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	var (
+		out          engine.Env
+		job          = eng.Job("container_clone", vars["name"], r.Form.Get("name"))
+		outWarnings  []string
+		stdoutBuffer = bytes.NewBuffer(nil)
+		warnings     = bytes.NewBuffer(nil)
+	)
+	if api.MatchesContentType(r.Header.Get("Content-Type"), "application/json") {
+		if err := job.DecodeEnv(r.Body); err != nil {
+			return err
+		}
+	}
+	job.Stdout.Add(stdoutBuffer)
+	job.Stderr.Add(warnings)
+	if err := job.Run(); err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(warnings)
+	for scanner.Scan() {
+		outWarnings = append(outWarnings, scanner.Text())
+	}
+	out.Set("Id", engine.Tail(stdoutBuffer, 1))
+	out.SetList("Warnings", outWarnings)
+	return writeJSON(w, http.StatusCreated, out)
+}
+
 func postContainersRestart(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	if err := parseForm(r); err != nil {
 		return err
@@ -714,8 +989,9 @@ func postContainersStart(eng *engine.Engine, version version.Version, w http.Res
 		return fmt.Errorf("Missing parameter")
 	}
 	var (
-		name = vars["name"]
-		job  = eng.Job("start", name)
+		name  = vars["name"]
+		job   = eng.Job("start", name)
+		ports = bytes.NewBuffer(nil)
 	)
 
 	// allow a nil body for backwards compatibility
@@ -729,6 +1005,7 @@ func postContainersStart(eng *engine.Engine, version version.Version, w http.Res
 		}
 	}
 
+	job.Stdout.Add(ports)
 	if err := job.Run(); err != nil {
 		if err.Error() == "Container already started" {
 			w.WriteHeader(http.StatusNotModified)
@@ -736,8 +1013,14 @@ func postContainersStart(eng *engine.Engine, version version.Version, w http.Res
 		}
 		return err
 	}
-	w.WriteHeader(http.StatusNoContent)
-	return nil
+	// the older clients don't expect any output
+	if version.LessThan("1.15") {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	var out engine.Env
+	out.Set("Ports", ports.String())
+	return writeJSON(w, http.StatusOK, out)
 }
 
 func postContainersStop(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
@@ -810,20 +1093,20 @@ func postContainersAttach(eng *engine.Engine, version version.Version, w http.Re
 		return err
 	}
 
-	inStream, outStream, err := hijackServer(w)
+	inStream, outStream, err := hijackServer(w, r)
 	if err != nil {
 		return err
 	}
 	defer func() {
-		if tcpc, ok := inStream.(*net.TCPConn); ok {
-			tcpc.CloseWrite()
+		if cw, ok := inStream.(closeWriter); ok {
+			cw.CloseWrite()
 		} else {
 			inStream.Close()
 		}
 	}()
 	defer func() {
-		if tcpc, ok := outStream.(*net.TCPConn); ok {
-			tcpc.CloseWrite()
+		if cw, ok := outStream.(closeWriter); ok {
+			cw.CloseWrite()
 		} else if closer, ok := outStream.(io.Closer); ok {
 			closer.Close()
 		}
@@ -966,6 +1249,10 @@ func postBuild(eng *engine.Engine, version version.Version, w http.ResponseWrite
 	job.Setenv("q", r.FormValue("q"))
 	job.Setenv("nocache", r.FormValue("nocache"))
 	job.Setenv("forcerm", r.FormValue("forcerm"))
+	job.Setenv("squash", r.FormValue("squash"))
+	job.Setenv("buildargs", r.FormValue("buildargs"))
+	job.Setenv("buildcontexts", r.FormValue("buildcontexts"))
+	job.Setenv("dockerfile", r.FormValue("dockerfile"))
 	job.SetenvJson("authConfig", authConfig)
 	job.SetenvJson("configFile", configFile)
 
@@ -1017,6 +1304,268 @@ func postContainersCopy(eng *engine.Engine, version version.Version, w http.Resp
 	return nil
 }
 
+func getDrain(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	var job = eng.Job("drain_status")
+	streamJSON(job, w, false)
+	return job.Run()
+}
+
+func getConnections(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	var job = eng.Job("api_connections")
+	streamJSON(job, w, false)
+	return job.Run()
+}
+
+func getImagesContainers(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	var job = eng.Job("image_containers", vars["name"])
+	streamJSON(job, w, false)
+	return job.Run()
+}
+
+func getImagesMounts(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	var job = eng.Job("image_mounts")
+	streamJSON(job, w, false)
+	return job.Run()
+}
+
+func postImagesMount(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := parseForm(r); err != nil {
+		return err
+	}
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	if err := eng.Job("image_mount", vars["name"], r.Form.Get("path")).Run(); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func postImagesUnmount(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := parseForm(r); err != nil {
+		return err
+	}
+	if err := eng.Job("image_unmount", r.Form.Get("path")).Run(); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func getSystemDf(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	var job = eng.Job("system_df")
+	streamJSON(job, w, false)
+	return job.Run()
+}
+
+func postDrain(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := parseForm(r); err != nil {
+		return err
+	}
+	job := eng.Job("drain")
+	job.Setenv("stop", r.Form.Get("stop"))
+	if t := r.Form.Get("t"); t != "" {
+		job.Setenv("t", t)
+	}
+	if err := job.Run(); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func deleteDrain(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := eng.Job("undrain").Run(); err != nil {
+		if err.Error() == "Daemon is not draining" {
+			w.WriteHeader(http.StatusNotModified)
+			return nil
+		}
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func getNetworksJSON(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	var job = eng.Job("networks")
+	streamJSON(job, w, false)
+	return job.Run()
+}
+
+func getNetworksByName(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	var job = eng.Job("network_inspect", vars["name"])
+	streamJSON(job, w, false)
+	return job.Run()
+}
+
+func postNetworksCreate(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if !api.MatchesContentType(r.Header.Get("Content-Type"), "application/json") {
+		return fmt.Errorf("Content-Type of application/json is required")
+	}
+	var (
+		config       engine.Env
+		stdoutBuffer = bytes.NewBuffer(nil)
+	)
+	if err := config.Decode(r.Body); err != nil {
+		return err
+	}
+	job := eng.Job("network_create", config.Get("Name"))
+	job.Setenv("Subnet", config.Get("Subnet"))
+	job.Setenv("Bridge", config.Get("Bridge"))
+	job.Stdout.Add(stdoutBuffer)
+	if err := job.Run(); err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, err := io.Copy(w, stdoutBuffer)
+	return err
+}
+
+func postContainersPorts(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	if !api.MatchesContentType(r.Header.Get("Content-Type"), "application/json") {
+		return fmt.Errorf("Content-Type of application/json is required")
+	}
+	var config engine.Env
+	if err := config.Decode(r.Body); err != nil {
+		return err
+	}
+	job := eng.Job("container_ports", vars["name"])
+	job.SetenvList("Publish", config.GetList("Publish"))
+	job.SetenvList("Unpublish", config.GetList("Unpublish"))
+	streamJSON(job, w, false)
+	return job.Run()
+}
+
+func postContainersUpdate(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	if !api.MatchesContentType(r.Header.Get("Content-Type"), "application/json") {
+		return fmt.Errorf("Content-Type of application/json is required")
+	}
+	var config engine.Env
+	if err := config.Decode(r.Body); err != nil {
+		return err
+	}
+	job := eng.Job("container_update", vars["name"])
+	for _, key := range []string{"Memory", "CpuShares", "Cpuset"} {
+		if config.Exists(key) {
+			job.Setenv(key, config.Get(key))
+		}
+	}
+	if err := job.Run(); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func postNetworksConnect(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	return networkConnectHandler("network_connect", eng, w, r, vars)
+}
+
+func postNetworksDisconnect(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	return networkConnectHandler("network_disconnect", eng, w, r, vars)
+}
+
+func networkConnectHandler(name string, eng *engine.Engine, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	if !api.MatchesContentType(r.Header.Get("Content-Type"), "application/json") {
+		return fmt.Errorf("Content-Type of application/json is required")
+	}
+	var config engine.Env
+	if err := config.Decode(r.Body); err != nil {
+		return err
+	}
+	if err := eng.Job(name, vars["name"], config.Get("Container")).Run(); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func deleteNetworks(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	if err := eng.Job("network_rm", vars["name"]).Run(); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func getPluginsJSON(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	var job = eng.Job("plugins")
+	streamJSON(job, w, false)
+	return job.Run()
+}
+
+func getVolumesJSON(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := parseForm(r); err != nil {
+		return err
+	}
+	var job = eng.Job("volumes")
+	streamJSON(job, w, false)
+	return job.Run()
+}
+
+func getVolumesByName(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	var job = eng.Job("volume_inspect", vars["name"])
+	streamJSON(job, w, false)
+	return job.Run()
+}
+
+func postVolumesCreate(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if !api.MatchesContentType(r.Header.Get("Content-Type"), "application/json") {
+		return fmt.Errorf("Content-Type of application/json is required")
+	}
+	var (
+		config       engine.Env
+		stdoutBuffer = bytes.NewBuffer(nil)
+	)
+	if err := config.Decode(r.Body); err != nil {
+		return err
+	}
+	job := eng.Job("volume_create", config.Get("Name"))
+	job.Setenv("Driver", config.Get("Driver"))
+	job.Stdout.Add(stdoutBuffer)
+	if err := job.Run(); err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, err := io.Copy(w, stdoutBuffer)
+	return err
+}
+
+func deleteVolumes(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	if err := eng.Job("volume_rm", vars["name"]).Run(); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
 func optionsHandler(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	w.WriteHeader(http.StatusOK)
 	return nil
@@ -1101,14 +1650,22 @@ func createRouter(eng *engine.Engine, logging, enableCors bool, dockerVersion st
 	m := map[string]map[string]HttpApiFunc{
 		"GET": {
 			"/_ping":                          ping,
+			"/connections":                    getConnections,
+			"/drain":                          getDrain,
 			"/events":                         getEvents,
 			"/info":                           getInfo,
+			"/info/check":                     getInfoCheck,
+			"/system/df":                      getSystemDf,
 			"/version":                        getVersion,
 			"/images/json":                    getImagesJSON,
 			"/images/viz":                     getImagesViz,
+			"/images/graph":                   getImagesGraph,
 			"/images/search":                  getImagesSearch,
+			"/images/mounts":                  getImagesMounts,
+			"/images/get":                     getImagesGet,
 			"/images/{name:.*}/get":           getImagesGet,
 			"/images/{name:.*}/history":       getImagesHistory,
+			"/images/{name:.*}/containers":    getImagesContainers,
 			"/images/{name:.*}/json":          getImagesByName,
 			"/containers/ps":                  getContainersJSON,
 			"/containers/json":                getContainersJSON,
@@ -1116,32 +1673,57 @@ func createRouter(eng *engine.Engine, logging, enableCors bool, dockerVersion st
 			"/containers/{name:.*}/changes":   getContainersChanges,
 			"/containers/{name:.*}/json":      getContainersByName,
 			"/containers/{name:.*}/top":       getContainersTop,
+			"/containers/{name:.*}/ports":     getContainersPorts,
 			"/containers/{name:.*}/logs":      getContainersLogs,
 			"/containers/{name:.*}/attach/ws": wsContainersAttach,
+			"/networks":                       getNetworksJSON,
+			"/networks/{name:.*}":             getNetworksByName,
+			"/plugins":                        getPluginsJSON,
+			"/tokens":                         getTokensJSON,
+			"/volumes":                        getVolumesJSON,
+			"/volumes/{name:.*}":              getVolumesByName,
 		},
 		"POST": {
-			"/auth":                         postAuth,
-			"/commit":                       postCommit,
-			"/build":                        postBuild,
-			"/images/create":                postImagesCreate,
-			"/images/load":                  postImagesLoad,
-			"/images/{name:.*}/push":        postImagesPush,
-			"/images/{name:.*}/tag":         postImagesTag,
-			"/containers/create":            postContainersCreate,
-			"/containers/{name:.*}/kill":    postContainersKill,
-			"/containers/{name:.*}/pause":   postContainersPause,
-			"/containers/{name:.*}/unpause": postContainersUnpause,
-			"/containers/{name:.*}/restart": postContainersRestart,
-			"/containers/{name:.*}/start":   postContainersStart,
-			"/containers/{name:.*}/stop":    postContainersStop,
-			"/containers/{name:.*}/wait":    postContainersWait,
-			"/containers/{name:.*}/resize":  postContainersResize,
-			"/containers/{name:.*}/attach":  postContainersAttach,
-			"/containers/{name:.*}/copy":    postContainersCopy,
+			"/auth":                          postAuth,
+			"/commit":                        postCommit,
+			"/drain":                         postDrain,
+			"/build":                         postBuild,
+			"/images/create":                 postImagesCreate,
+			"/images/load":                   postImagesLoad,
+			"/images/prune":                  postImagesPrune,
+			"/images/unmount":                postImagesUnmount,
+			"/images/{name:.*}/mount":        postImagesMount,
+			"/images/{name:.*}/push":         postImagesPush,
+			"/images/{name:.*}/tag":          postImagesTag,
+			"/images/{name:.*}/annotations":  postImagesAnnotations,
+			"/containers/create":             postContainersCreate,
+			"/containers/prune":              postContainersPrune,
+			"/containers/{name:.*}/kill":     postContainersKill,
+			"/containers/{name:.*}/pause":    postContainersPause,
+			"/containers/{name:.*}/unpause":  postContainersUnpause,
+			"/containers/{name:.*}/restart":  postContainersRestart,
+			"/containers/{name:.*}/start":    postContainersStart,
+			"/containers/{name:.*}/stop":     postContainersStop,
+			"/containers/{name:.*}/wait":     postContainersWait,
+			"/containers/{name:.*}/resize":   postContainersResize,
+			"/containers/{name:.*}/attach":   postContainersAttach,
+			"/containers/{name:.*}/copy":     postContainersCopy,
+			"/containers/{name:.*}/ports":    postContainersPorts,
+			"/containers/{name:.*}/clone":    postContainersClone,
+			"/containers/{name:.*}/update":   postContainersUpdate,
+			"/networks/create":               postNetworksCreate,
+			"/networks/{name:.*}/connect":    postNetworksConnect,
+			"/networks/{name:.*}/disconnect": postNetworksDisconnect,
+			"/tokens/create":                 postTokensCreate,
+			"/volumes/create":                postVolumesCreate,
 		},
 		"DELETE": {
 			"/containers/{name:.*}": deleteContainers,
 			"/images/{name:.*}":     deleteImages,
+			"/networks/{name:.*}":   deleteNetworks,
+			"/tokens/{name:.*}":     deleteTokens,
+			"/volumes/{name:.*}":    deleteVolumes,
+			"/drain":                deleteDrain,
 		},
 		"OPTIONS": {
 			"": optionsHandler,
@@ -1186,14 +1768,99 @@ func ServeRequest(eng *engine.Engine, apiversion version.Version, w http.Respons
 	return nil
 }
 
+// socketOptions are the options of a socket activated socket, which can be
+// given after its name in the FileDescriptorName of its systemd socket unit,
+// e.g. "public,tls,ro".
+type socketOptions struct {
+	Tls      bool
+	Cors     bool
+	ReadOnly bool
+	// Tokens requires an API token on the socket
+	Tokens bool
+}
+
+// parseSocketOptions returns the options of the socket named name, the
+// ones it does not give being those of defaults.
+func parseSocketOptions(name string, defaults socketOptions) (socketOptions, error) {
+	options := defaults
+	for _, option := range strings.Split(name, ",")[1:] {
+		switch option {
+		case "tls":
+			options.Tls = true
+		case "notls":
+			options.Tls = false
+		case "cors":
+			options.Cors = true
+		case "nocors":
+			options.Cors = false
+		case "ro":
+			options.ReadOnly = true
+		case "rw":
+			options.ReadOnly = false
+		case "tokens":
+			options.Tokens = true
+		case "notokens":
+			options.Tokens = false
+		default:
+			return options, fmt.Errorf("Invalid option %s of the socket %s", option, name)
+		}
+	}
+	return options, nil
+}
+
+// readOnlyHandler only passes the requests which cannot change anything on
+// to handler: the GET, HEAD and OPTIONS ones, except the websocket attach
+// which can write to the container.
+func readOnlyHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET", "HEAD", "OPTIONS":
+			if !strings.HasSuffix(r.URL.Path, "/attach/ws") {
+				handler.ServeHTTP(w, r)
+				return
+			}
+		}
+		http.Error(w, "This socket is read-only", http.StatusForbidden)
+	})
+}
+
 // ServeFD creates an http.Server and sets it up to serve given a socket activated
-// argument.
-func ServeFd(addr string, handle http.Handler) error {
-	ls, e := systemd.ListenFD(addr)
+// argument. Each socket is served with the options given in its name, by
+// default without TLS and with CORS headers if they are enabled.
+func ServeFd(addr string, job *engine.Job) error {
+	ls, names, e := systemd.ListenFD(addr)
 	if e != nil {
 		return e
 	}
 
+	defaults := socketOptions{Cors: job.GetenvBool("EnableCors")}
+	handlers := make([]http.Handler, len(ls))
+	for i := range ls {
+		options, err := parseSocketOptions(names[i], defaults)
+		if err != nil {
+			return err
+		}
+		r, err := createRouter(job.Eng, job.GetenvBool("Logging"), options.Cors, job.Getenv("Version"))
+		if err != nil {
+			return err
+		}
+		handlers[i] = r
+		if options.ReadOnly {
+			handlers[i] = readOnlyHandler(r)
+		}
+		if options.Tokens {
+			handlers[i] = tokenHandler(job.Eng, handlers[i])
+		}
+		if options.Tls {
+			tlsConfig, err := newTlsConfig(job)
+			if err != nil {
+				return err
+			}
+			ls[i] = tls.NewListener(ls[i], tlsConfig)
+		}
+		ls[i] = tracker.wrap(ls[i])
+	}
+
 	chErrors := make(chan error, len(ls))
 
 	// We don't want to start serving on these sockets until the
@@ -1205,8 +1872,9 @@ func ServeFd(addr string, handle http.Handler) error {
 	// to create a go func to spawn off multiple serves
 	for i := range ls {
 		listener := ls[i]
+		handler := handlers[i]
 		go func() {
-			httpSrv := http.Server{Handler: handle}
+			httpSrv := http.Server{Handler: handler}
 			chErrors <- httpSrv.Serve(listener)
 		}()
 	}
@@ -1221,6 +1889,34 @@ func ServeFd(addr string, handle http.Handler) error {
 	return nil
 }
 
+// newTlsConfig returns the TLS configuration of the daemon, which verifies
+// the certificates of the clients with TlsVerify.
+func newTlsConfig(job *engine.Job) (*tls.Config, error) {
+	tlsCert := job.Getenv("TlsCert")
+	tlsKey := job.Getenv("TlsKey")
+	cert, err := tls.LoadX509KeyPair(tlsCert, tlsKey)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't load X509 key pair (%s, %s): %s. Key encrypted?",
+			tlsCert, tlsKey, err)
+	}
+	tlsConfig := &tls.Config{
+		NextProtos:   []string{"http/1.1"},
+		Certificates: []tls.Certificate{cert},
+	}
+	if job.GetenvBool("TlsVerify") {
+		certPool := x509.NewCertPool()
+		file, err := ioutil.ReadFile(job.Getenv("TlsCa"))
+		if err != nil {
+			return nil, fmt.Errorf("Couldn't read CA certificate: %s", err)
+		}
+		certPool.AppendCertsFromPEM(file)
+
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		tlsConfig.ClientCAs = certPool
+	}
+	return tlsConfig, nil
+}
+
 func lookupGidByName(nameOrGid string) (int, error) {
 	groups, err := user.ParseGroupFilter(func(g *user.Group) bool {
 		return g.Name == nameOrGid || strconv.Itoa(g.Gid) == nameOrGid
@@ -1247,14 +1943,26 @@ func changeGroup(addr string, nameOrGid string) error {
 // ListenAndServe sets up the required http.Server and gets it listening for
 // each addr passed in and does protocol specific checking.
 func ListenAndServe(proto, addr string, job *engine.Job) error {
-	var l net.Listener
+	if proto == "fd" {
+		return ServeFd(addr, job)
+	}
+	return listenAndServe(proto, addr, job, false)
+}
+
+// listenAndServe serves the API on addr. When tokens is set, the requests
+// need an API token, and the socket can be used by anyone.
+func listenAndServe(proto, addr string, job *engine.Job, tokens bool) error {
+	var (
+		l       net.Listener
+		handler http.Handler
+	)
 	r, err := createRouter(job.Eng, job.GetenvBool("Logging"), job.GetenvBool("EnableCors"), job.Getenv("Version"))
 	if err != nil {
 		return err
 	}
-
-	if proto == "fd" {
-		return ServeFd(addr, r)
+	handler = r
+	if tokens {
+		handler = tokenHandler(job.Eng, r)
 	}
 
 	if proto == "unix" {
@@ -1282,30 +1990,13 @@ func ListenAndServe(proto, addr string, job *engine.Job) error {
 	}
 
 	if proto != "unix" && (job.GetenvBool("Tls") || job.GetenvBool("TlsVerify")) {
-		tlsCert := job.Getenv("TlsCert")
-		tlsKey := job.Getenv("TlsKey")
-		cert, err := tls.LoadX509KeyPair(tlsCert, tlsKey)
+		tlsConfig, err := newTlsConfig(job)
 		if err != nil {
-			return fmt.Errorf("Couldn't load X509 key pair (%s, %s): %s. Key encrypted?",
-				tlsCert, tlsKey, err)
-		}
-		tlsConfig := &tls.Config{
-			NextProtos:   []string{"http/1.1"},
-			Certificates: []tls.Certificate{cert},
-		}
-		if job.GetenvBool("TlsVerify") {
-			certPool := x509.NewCertPool()
-			file, err := ioutil.ReadFile(job.Getenv("TlsCa"))
-			if err != nil {
-				return fmt.Errorf("Couldn't read CA certificate: %s", err)
-			}
-			certPool.AppendCertsFromPEM(file)
-
-			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
-			tlsConfig.ClientCAs = certPool
+			return err
 		}
 		l = tls.NewListener(l, tlsConfig)
 	}
+	l = tracker.wrap(l)
 
 	// Basic error and sanity checking
 	switch proto {
@@ -1314,6 +2005,13 @@ func ListenAndServe(proto, addr string, job *engine.Job) error {
 			log.Infof("/!\\ DON'T BIND ON ANOTHER IP ADDRESS THAN 127.0.0.1 IF YOU DON'T KNOW WHAT YOU'RE DOING /!\\")
 		}
 	case "unix":
+		if tokens {
+			// the tokens protect the socket, not its permissions
+			if err := os.Chmod(addr, 0666); err != nil {
+				return err
+			}
+			break
+		}
 		socketGroup := job.Getenv("SocketGroup")
 		if socketGroup != "" {
 			if err := changeGroup(addr, socketGroup); err != nil {
@@ -1332,7 +2030,7 @@ func ListenAndServe(proto, addr string, job *engine.Job) error {
 		return fmt.Errorf("Invalid protocol format.")
 	}
 
-	httpSrv := http.Server{Addr: addr, Handler: r}
+	httpSrv := http.Server{Addr: addr, Handler: handler}
 	return httpSrv.Serve(l)
 }
 
@@ -1343,11 +2041,21 @@ func ServeApi(job *engine.Job) engine.Status {
 		return job.Errorf("usage: %s PROTO://ADDR [PROTO://ADDR ...]", job.Name)
 	}
 	var (
-		protoAddrs = job.Args
-		chErrors   = make(chan error, len(protoAddrs))
+		protoAddrs  = job.Args
+		tokenSocket = job.Getenv("TokenSocket")
+		chErrors    = make(chan error, len(protoAddrs)+1)
 	)
 	activationLock = make(chan struct{})
 
+	if idleTimeout := job.Getenv("IdleTimeout"); idleTimeout != "" {
+		d, err := time.ParseDuration(idleTimeout)
+		if err != nil {
+			return job.Errorf("Invalid idle timeout %s: %s", idleTimeout, err)
+		}
+		tracker.setIdleTimeout(d)
+	}
+	go tracker.reapLoop()
+
 	for _, protoAddr := range protoAddrs {
 		protoAddrParts := strings.SplitN(protoAddr, "://", 2)
 		if len(protoAddrParts) != 2 {
@@ -1359,7 +2067,16 @@ func ServeApi(job *engine.Job) engine.Status {
 		}()
 	}
 
-	for i := 0; i < len(protoAddrs); i += 1 {
+	serving := len(protoAddrs)
+	if tokenSocket != "" {
+		serving++
+		go func() {
+			log.Infof("Listening for HTTP with API tokens on unix (%s)", tokenSocket)
+			chErrors <- listenAndServe("unix", tokenSocket, job, true)
+		}()
+	}
+
+	for i := 0; i < serving; i += 1 {
 		err := <-chErrors
 		if err != nil {
 			return job.Error(err)
@@ -2,11 +2,13 @@ package server
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"reflect"
 	"strings"
 	"testing"
@@ -14,6 +16,7 @@ import (
 	"github.com/docker/docker/api"
 	"github.com/docker/docker/engine"
 	"github.com/docker/docker/pkg/version"
+	"github.com/docker/docker/registry"
 )
 
 func TestGetBoolParam(t *testing.T) {
@@ -116,6 +119,29 @@ func TestGetInfo(t *testing.T) {
 	assertContentType(r, "application/json", t)
 }
 
+func TestGetSystemDf(t *testing.T) {
+	eng := engine.New()
+	var called bool
+	eng.Register("system_df", func(job *engine.Job) engine.Status {
+		called = true
+		v := &engine.Env{}
+		v.SetInt64("LayersSize", 1048576)
+		if _, err := v.WriteTo(job.Stdout); err != nil {
+			return job.Error(err)
+		}
+		return engine.StatusOK
+	})
+	r := serveRequest("GET", "/system/df", nil, eng, t)
+	if !called {
+		t.Fatalf("handler was not called")
+	}
+	v := readEnv(r.Body, t)
+	if v.GetInt64("LayersSize") != 1048576 {
+		t.Fatalf("%#v\n", v)
+	}
+	assertContentType(r, "application/json", t)
+}
+
 func TestGetImagesJSON(t *testing.T) {
 	eng := engine.New()
 	var called bool
@@ -127,7 +153,7 @@ func TestGetImagesJSON(t *testing.T) {
 		}
 		return engine.StatusOK
 	})
-	r := serveRequest("GET", "/images/json", nil, eng, t)
+	r := serveRequestUsingVersion("GET", "/images/json", "1.14", nil, eng, t)
 	if !called {
 		t.Fatal("handler was not called")
 	}
@@ -142,6 +168,34 @@ func TestGetImagesJSON(t *testing.T) {
 	}
 }
 
+func TestGetImagesJSONTimestamps(t *testing.T) {
+	eng := engine.New()
+	eng.Register("images", func(job *engine.Job) engine.Status {
+		images := engine.NewTable("Created", 0)
+		images.Add(createEnvFromGetImagesJSONStruct(sampleImage))
+		if _, err := images.WriteListTo(job.Stdout); err != nil {
+			return job.Error(err)
+		}
+		return engine.StatusOK
+	})
+	r := serveRequestUsingVersion("GET", "/images/json", "1.15", nil, eng, t)
+	assertHttpNotError(r, t)
+	assertContentType(r, "application/json", t)
+	var observed []struct {
+		Id      string
+		Created string
+	}
+	if err := json.Unmarshal(r.Body.Bytes(), &observed); err != nil {
+		t.Fatal(err)
+	}
+	if len(observed) != 1 || observed[0].Id != "ID" {
+		t.Fatalf("Expected the image ID, got %#v", observed)
+	}
+	if expected := "1970-01-01T00:16:39.000000000Z"; observed[0].Created != expected {
+		t.Fatalf("Expected Created %q, got %q", expected, observed[0].Created)
+	}
+}
+
 func TestGetImagesJSONFilter(t *testing.T) {
 	eng := engine.New()
 	filter := "nothing"
@@ -291,6 +345,31 @@ func TestGetEvents(t *testing.T) {
 	}
 }
 
+func TestGetEventsTimestamps(t *testing.T) {
+	eng := engine.New()
+	eng.Register("events", func(job *engine.Job) engine.Status {
+		if since := job.Getenv("since"); since != "1409598000" {
+			t.Fatalf("'since' should be 1409598000, found %#v instead", since)
+		}
+		if _, err := job.Stdout.Write([]byte(`{"status":"start","id":"abc","time":1409598000}`)); err != nil {
+			return job.Error(err)
+		}
+		return engine.StatusOK
+	})
+	r := serveRequestUsingVersion("GET", "/events?since=2014-09-01T21:00:00%2B02:00", "1.15", nil, eng, t)
+	assertContentType(r, "application/json", t)
+	var event struct {
+		Status string
+		Time   string
+	}
+	if err := json.Unmarshal(r.Body.Bytes(), &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Status != "start" || event.Time != "2014-09-01T19:00:00.000000000Z" {
+		t.Fatalf("Unexpected event %#v", event)
+	}
+}
+
 func TestLogs(t *testing.T) {
 	eng := engine.New()
 	var inspect bool
@@ -474,6 +553,172 @@ func TestDeleteContainers(t *testing.T) {
 	}
 }
 
+func TestPostContainersClone(t *testing.T) {
+	eng := engine.New()
+	var called bool
+	eng.Register("container_clone", func(job *engine.Job) engine.Status {
+		called = true
+		if len(job.Args) != 2 || job.Args[0] != "web" || job.Args[1] != "web2" {
+			t.Fatalf("Unexpected job arguments: %#v", job.Args)
+		}
+		if tag := job.Getenv("Tag"); tag != "2.0" {
+			t.Fatalf("Tag != '2.0': %#v", tag)
+		}
+		job.Printf("%s\n", "4386fb97867d")
+		return engine.StatusOK
+	})
+	req, err := http.NewRequest("POST", "/containers/web/clone?name=web2", toJson(map[string]string{"Tag": "2.0"}, t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	r := httptest.NewRecorder()
+	if err := ServeRequest(eng, api.APIVERSION, r, req); err != nil {
+		t.Fatal(err)
+	}
+	if !called {
+		t.Fatalf("handler was not called")
+	}
+	if r.Code != http.StatusCreated {
+		t.Fatalf("Got status %d, expected %d", r.Code, http.StatusCreated)
+	}
+	if id := readEnv(r.Body, t).Get("Id"); id != "4386fb97867d" {
+		t.Fatalf("Id != '4386fb97867d': %#v", id)
+	}
+}
+
+func TestPostContainersUpdate(t *testing.T) {
+	eng := engine.New()
+	var called bool
+	eng.Register("container_update", func(job *engine.Job) engine.Status {
+		called = true
+		if len(job.Args) != 1 || job.Args[0] != "web" {
+			t.Fatalf("Unexpected job arguments: %#v", job.Args)
+		}
+		if memory := job.GetenvInt64("Memory"); memory != 134217728 {
+			t.Fatalf("Memory != 134217728: %d", memory)
+		}
+		if job.EnvExists("CpuShares") {
+			t.Fatalf("CpuShares should not be set")
+		}
+		return engine.StatusOK
+	})
+	req, err := http.NewRequest("POST", "/containers/web/update", toJson(map[string]int64{"Memory": 134217728}, t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	r := httptest.NewRecorder()
+	if err := ServeRequest(eng, api.APIVERSION, r, req); err != nil {
+		t.Fatal(err)
+	}
+	if !called {
+		t.Fatalf("handler was not called")
+	}
+	if r.Code != http.StatusNoContent {
+		t.Fatalf("Got status %d, expected %d", r.Code, http.StatusNoContent)
+	}
+}
+
+func TestPostContainersCreatePullPolicy(t *testing.T) {
+	eng := engine.New()
+	var called bool
+	eng.Register("create", func(job *engine.Job) engine.Status {
+		called = true
+		if policy := job.Getenv("PullPolicy"); policy != "always" {
+			t.Fatalf("PullPolicy != always: %s", policy)
+		}
+		authConfig := &registry.AuthConfig{}
+		job.GetenvJson("authConfig", authConfig)
+		if authConfig.Username != "jdoe" {
+			t.Fatalf("Unexpected auth config: %#v", authConfig)
+		}
+		job.Printf("%s\n", "4386fb97867d")
+		return engine.StatusOK
+	})
+	authJson, err := json.Marshal(&registry.AuthConfig{Username: "jdoe"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest("POST", "/containers/create?pull=always", toJson(map[string]string{"Image": "busybox"}, t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Registry-Auth", base64.URLEncoding.EncodeToString(authJson))
+	r := httptest.NewRecorder()
+	if err := ServeRequest(eng, api.APIVERSION, r, req); err != nil {
+		t.Fatal(err)
+	}
+	if !called {
+		t.Fatalf("handler was not called")
+	}
+	if r.Code != http.StatusCreated {
+		t.Fatalf("Got status %d, expected %d", r.Code, http.StatusCreated)
+	}
+}
+
+func TestPostContainersStart(t *testing.T) {
+	eng := engine.New()
+	eng.Register("start", func(job *engine.Job) engine.Status {
+		job.Stdout.Write([]byte(`[{"IP":"0.0.0.0","PrivatePort":80,"PublicPort":49153,"Type":"tcp"}]`))
+		return engine.StatusOK
+	})
+	r := serveRequest("POST", "/containers/foo/start", nil, eng, t)
+	if r.Code != http.StatusOK {
+		t.Fatalf("Got status %d, expected %d", r.Code, http.StatusOK)
+	}
+	var out struct {
+		Ports []struct {
+			PrivatePort int
+			PublicPort  int
+		}
+	}
+	if err := json.NewDecoder(r.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Ports) != 1 || out.Ports[0].PrivatePort != 80 || out.Ports[0].PublicPort != 49153 {
+		t.Fatalf("Unexpected ports %v", out.Ports)
+	}
+
+	r = serveRequestUsingVersion("POST", "/containers/foo/start", "1.14", nil, eng, t)
+	if r.Code != http.StatusNoContent {
+		t.Fatalf("Got status %d, expected %d", r.Code, http.StatusNoContent)
+	}
+}
+
+func TestPostImagesPrune(t *testing.T) {
+	eng := engine.New()
+	var called bool
+	eng.Register("prune", func(job *engine.Job) engine.Status {
+		called = true
+		if len(job.Args) != 1 || job.Args[0] != "images" {
+			t.Fatalf("Unexpected job arguments: %#v", job.Args)
+		}
+		if !job.GetenvBool("dryRun") {
+			t.Fatalf("dryRun should be set")
+		}
+		if filters := job.Getenv("filters"); filters != `{"until":["24h"]}` {
+			t.Fatalf("Unexpected filters: %s", filters)
+		}
+		v := &engine.Env{}
+		v.SetList("Deleted", []string{"8dfafdbc3a40"})
+		v.SetInt64("SpaceReclaimed", 4096)
+		if _, err := v.WriteTo(job.Stdout); err != nil {
+			return job.Error(err)
+		}
+		return engine.StatusOK
+	})
+	r := serveRequest("POST", "/images/prune?dryrun=1&filters="+url.QueryEscape(`{"until":["24h"]}`), bytes.NewBuffer(nil), eng, t)
+	if !called {
+		t.Fatalf("handler was not called")
+	}
+	v := readEnv(r.Body, t)
+	if v.GetInt64("SpaceReclaimed") != 4096 {
+		t.Fatalf("%#v\n", v)
+	}
+}
+
 func serveRequest(method, target string, body io.Reader, eng *engine.Engine, t *testing.T) *httptest.ResponseRecorder {
 	return serveRequestUsingVersion(method, target, api.APIVERSION, body, eng, t)
 }
@@ -528,6 +773,133 @@ func assertHttpNotError(r *httptest.ResponseRecorder, t *testing.T) {
 	}
 }
 
+func TestParseSocketOptions(t *testing.T) {
+	defaults := socketOptions{Cors: true}
+	for name, expected := range map[string]socketOptions{
+		"":                  {Cors: true},
+		"docker":            {Cors: true},
+		"public,tls,ro":     {Tls: true, Cors: true, ReadOnly: true},
+		"local,nocors,rw":   {},
+		"public,notls,tls":  {Tls: true, Cors: true},
+		"containers,tokens": {Cors: true, Tokens: true},
+	} {
+		options, err := parseSocketOptions(name, defaults)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if options != expected {
+			t.Fatalf("Expected the options of %q to be %#v, got %#v", name, expected, options)
+		}
+	}
+	if _, err := parseSocketOptions("public,secure", defaults); err == nil {
+		t.Fatal("Expected an error for an unknown option")
+	}
+}
+
+func TestReadOnlyHandler(t *testing.T) {
+	handler := readOnlyHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	for request, expected := range map[string]int{
+		"GET /containers/json":           http.StatusOK,
+		"HEAD /_ping":                    http.StatusOK,
+		"POST /containers/create":        http.StatusForbidden,
+		"DELETE /images/busybox":         http.StatusForbidden,
+		"GET /containers/web/attach/ws":  http.StatusForbidden,
+		"GET /v1.14/containers/web/json": http.StatusOK,
+	} {
+		parts := strings.SplitN(request, " ", 2)
+		req, err := http.NewRequest(parts[0], parts[1], nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRecorder()
+		handler.ServeHTTP(r, req)
+		if r.Code != expected {
+			t.Fatalf("Expected %d for %s, got %d", expected, request, r.Code)
+		}
+	}
+}
+
+func TestTokenHandler(t *testing.T) {
+	eng := engine.New()
+	eng.Register("token_scopes", func(job *engine.Job) engine.Status {
+		out := &engine.Env{}
+		switch job.Args[0] {
+		case "secret":
+			out.SetList("Scopes", []string{"read", "pull"})
+		case "import":
+			out.SetList("Scopes", []string{"import"})
+		default:
+			return job.Errorf("Invalid API token")
+		}
+		if _, err := out.WriteTo(job.Stdout); err != nil {
+			return job.Error(err)
+		}
+		return engine.StatusOK
+	})
+	handler := tokenHandler(eng, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	for request, expected := range map[string]int{
+		"secret GET /containers/json":                          http.StatusOK,
+		"secret GET /v1.15/containers/web/top":                 http.StatusOK,
+		"secret GET /images/json":                              http.StatusOK,
+		"secret GET /images/busybox/history":                   http.StatusOK,
+		"secret GET /info":                                     http.StatusOK,
+		"secret GET /version":                                  http.StatusOK,
+		"secret GET /containers/web/json":                      http.StatusForbidden,
+		"secret GET /containers/web/export":                    http.StatusForbidden,
+		"secret GET /containers/web/logs":                      http.StatusForbidden,
+		"secret GET /images/get":                               http.StatusForbidden,
+		"secret GET /images/busybox/get":                       http.StatusForbidden,
+		"secret GET /events":                                   http.StatusForbidden,
+		"secret GET /connections":                              http.StatusForbidden,
+		"secret HEAD /containers/json":                         http.StatusForbidden,
+		"secret POST /v1.15/images/create?fromImage=busybox":   http.StatusOK,
+		"secret POST /images/create":                           http.StatusForbidden,
+		"secret POST /images/create?fromSrc=http://host/a.tar": http.StatusForbidden,
+		"secret POST /images/create?fromSrc=-&repo=busybox":    http.StatusForbidden,
+		"import POST /images/create?fromSrc=http://host/a.tar": http.StatusOK,
+		"import POST /images/create?fromImage=busybox":         http.StatusOK,
+		"secret GET /_ping":                                    http.StatusOK,
+		"secret POST /images/busybox/push":                     http.StatusForbidden,
+		"secret POST /containers/create":                       http.StatusForbidden,
+		"secret GET /containers/web/attach/ws":                 http.StatusForbidden,
+		"secret GET /v1.15/tokens":                             http.StatusForbidden,
+		"wrong GET /containers/json":                           http.StatusUnauthorized,
+		" GET /_ping":                                          http.StatusUnauthorized,
+	} {
+		parts := strings.SplitN(request, " ", 3)
+		req, err := http.NewRequest(parts[1], parts[2], strings.NewReader(""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parts[0] != "" {
+			req.Header.Set("Authorization", "Token "+parts[0])
+		}
+		r := httptest.NewRecorder()
+		handler.ServeHTTP(r, req)
+		if r.Code != expected {
+			t.Fatalf("Expected %d for %s, got %d", expected, request, r.Code)
+		}
+	}
+
+	// The empty fromImage of the body hides that of the URL from the handler,
+	// which imports fromSrc
+	req, err := http.NewRequest("POST", "/images/create?fromImage=busybox&fromSrc=http://host/a.tar", strings.NewReader("fromImage="))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Token secret")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r := httptest.NewRecorder()
+	handler.ServeHTTP(r, req)
+	if r.Code != http.StatusForbidden {
+		t.Fatalf("Expected %d for an import hidden by the body, got %d", http.StatusForbidden, r.Code)
+	}
+}
+
 func createEnvFromGetImagesJSONStruct(data getImagesJSONStruct) *engine.Env {
 	v := &engine.Env{}
 	v.SetList("RepoTags", data.RepoTags)
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api"
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/pkg/version"
+)
+
+// tokenRule is a request an API token can make: its method, a pattern of
+// its path, without the version, and the parameter it must set, if any.
+type tokenRule struct {
+	method string
+	path   *regexp.Regexp
+	param  string
+}
+
+func newTokenRule(method, path string) tokenRule {
+	return tokenRule{method: method, path: regexp.MustCompile("^" + path + "$")}
+}
+
+// newTokenParamRule returns a rule of the requests setting param, such as
+// the pulls among the requests creating an image.
+func newTokenParamRule(method, path, param string) tokenRule {
+	rule := newTokenRule(method, path)
+	rule.param = param
+	return rule
+}
+
+// tokenScopes are the requests allowed by each scope of the API tokens.
+// The read scope only lists and inspects what does not hold the data of the
+// containers, such as their environment, logs or files. The build scope
+// runs the RUN instructions of the builds in containers, a build token runs
+// any command in a container of the daemon. The pull scope only creates the
+// images of fromImage: the imports of fromSrc fetch any URL from the daemon,
+// and need the import scope.
+var tokenScopes = map[string][]tokenRule{
+	"read": {
+		newTokenRule("GET", `/containers/(json|ps)`),
+		newTokenRule("GET", `/containers/[^/]+/top`),
+		newTokenRule("GET", `/images/json`),
+		newTokenRule("GET", `/images/.+/history`),
+		newTokenRule("GET", `/info`),
+	},
+	"pull":   {newTokenParamRule("POST", `/images/create`, "fromImage")},
+	"import": {newTokenRule("POST", `/images/create`)},
+	"push":   {newTokenRule("POST", `/images/.+/push`)},
+	"tag":    {newTokenRule("POST", `/images/.+/tag`)},
+	"build":  {newTokenRule("POST", `/build`)},
+}
+
+var (
+	// tokenAlways are the requests of any valid token
+	tokenAlways = []tokenRule{
+		newTokenRule("GET", `/_ping`),
+		newTokenRule("GET", `/version`),
+	}
+	// tokenNever are the requests no token can make, whatever its scopes
+	tokenNever = []tokenRule{
+		newTokenRule("GET", `/containers/.+/attach/ws`),
+		newTokenRule("GET", `/containers/.+/export`),
+		newTokenRule("GET", `/containers/.+/logs`),
+		newTokenRule("GET", `/images/get`),
+		newTokenRule("GET", `/images/.+/get`),
+		newTokenRule("GET", `/tokens`),
+	}
+	versionPrefix = regexp.MustCompile(`^/v[0-9.]+`)
+)
+
+func matchTokenRules(rules []tokenRule, method, path string, form url.Values) bool {
+	for _, rule := range rules {
+		if rule.method == method && rule.path.MatchString(path) && (rule.param == "" || form.Get(rule.param) != "") {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenAllows returns whether a token with scopes can make a request with
+// the parameters of form.
+func tokenAllows(scopes []string, method, path string, form url.Values) bool {
+	path = versionPrefix.ReplaceAllString(path, "")
+	if matchTokenRules(tokenNever, method, path, form) {
+		return false
+	}
+	if matchTokenRules(tokenAlways, method, path, form) {
+		return true
+	}
+	for _, scope := range scopes {
+		if matchTokenRules(tokenScopes[scope], method, path, form) {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenHandler only passes the requests made with a valid API token, in the
+// "Authorization: Token TOKEN" header, and allowed by its scopes on to
+// handler.
+func tokenHandler(eng *engine.Engine, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Token ")
+		if token == "" {
+			http.Error(w, "This socket needs an API token", http.StatusUnauthorized)
+			return
+		}
+		job := eng.Job("token_scopes", token)
+		out, err := job.Stdout.AddEnv()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := job.Run(); err != nil {
+			http.Error(w, "Invalid API token", http.StatusUnauthorized)
+			return
+		}
+		// The handlers read the parameters of the form, which also holds
+		// those of an urlencoded body, rather than of the URL only
+		if err := parseForm(r); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !tokenAllows(out.GetList("Scopes"), r.Method, r.URL.Path, r.Form) {
+			log.Infof("Denied %s %s to an API token", r.Method, r.URL.Path)
+			http.Error(w, fmt.Sprintf("The API token does not allow %s %s", r.Method, versionPrefix.ReplaceAllString(r.URL.Path, "")), http.StatusForbidden)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func getTokensJSON(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	var job = eng.Job("tokens")
+	streamJSON(job, w, false)
+	return job.Run()
+}
+
+func postTokensCreate(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if !api.MatchesContentType(r.Header.Get("Content-Type"), "application/json") {
+		return fmt.Errorf("Content-Type of application/json is required")
+	}
+	config := engine.Env{}
+	if err := config.Decode(r.Body); err != nil {
+		return err
+	}
+	scopes := config.GetList("Scopes")
+	for _, scope := range scopes {
+		if _, exists := tokenScopes[scope]; !exists {
+			return fmt.Errorf("Invalid scope %s, valid scopes are %s", scope, strings.Join(tokenScopeNames(), ", "))
+		}
+	}
+	job := eng.Job("token_create", config.Get("Name"))
+	job.SetenvList("Scopes", scopes)
+	streamJSON(job, w, false)
+	w.WriteHeader(http.StatusCreated)
+	return job.Run()
+}
+
+func deleteTokens(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	if err := eng.Job("token_rm", vars["name"]).Run(); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func tokenScopeNames() []string {
+	names := make([]string, 0, len(tokenScopes))
+	for name := range tokenScopes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -168,10 +168,19 @@ func addTarFile(path, name string, tw *tar.Writer, twBuf *bufio.Writer) error {
 
 	}
 
-	capability, _ := system.Lgetxattr(path, "security.capability")
-	if capability != nil {
-		hdr.Xattrs = make(map[string]string)
-		hdr.Xattrs["security.capability"] = string(capability)
+	xattrs, _ := system.Llistxattr(path)
+	for _, key := range xattrs {
+		if !keepXattr(key) {
+			continue
+		}
+		value, err := system.Lgetxattr(path, key)
+		if err != nil || value == nil {
+			continue
+		}
+		if hdr.Xattrs == nil {
+			hdr.Xattrs = make(map[string]string)
+		}
+		hdr.Xattrs[key] = string(value)
 	}
 
 	if err := tw.WriteHeader(hdr); err != nil {
@@ -200,6 +209,20 @@ func addTarFile(path, name string, tw *tar.Writer, twBuf *bufio.Writer) error {
 	return nil
 }
 
+// keepXattr returns whether the extended attribute key is archived: the
+// security ones, e.g. the file capabilities, and the POSIX ACLs. The SELinux
+// labels are left out, as the containers are labeled when they start.
+func keepXattr(key string) bool {
+	if key == "security.selinux" {
+		return false
+	}
+	return strings.HasPrefix(key, "security.") || isACL(key)
+}
+
+func isACL(key string) bool {
+	return key == "system.posix_acl_access" || key == "system.posix_acl_default"
+}
+
 func createTarFile(path, extractDir string, hdr *tar.Header, reader io.Reader, Lchown bool) error {
 	// hdr.Mode is in linux format, which we can use for sycalls,
 	// but for os.Foo() calls we need the mode converted to os.FileMode,
@@ -222,11 +245,15 @@ func createTarFile(path, extractDir string, hdr *tar.Header, reader io.Reader, L
 		if err != nil {
 			return err
 		}
-		if _, err := io.Copy(file, reader); err != nil {
-			file.Close()
-			return err
+		if hdr.Size < sparseMinSize {
+			_, err = io.Copy(file, reader)
+		} else {
+			err = copySparse(file, reader)
 		}
 		file.Close()
+		if err != nil {
+			return err
+		}
 
 	case tar.TypeBlock, tar.TypeChar, tar.TypeFifo:
 		mode := uint32(hdr.Mode & 07777)
@@ -267,6 +294,11 @@ func createTarFile(path, extractDir string, hdr *tar.Header, reader io.Reader, L
 
 	for key, value := range hdr.Xattrs {
 		if err := system.Lsetxattr(path, key, []byte(value), 0); err != nil {
+			// the ACLs are lost on filesystems without them, e.g. tmpfs
+			if isACL(key) && err == syscall.ENOTSUP {
+				log.Debugf("Can't set %s on %s: %s", key, path, err)
+				continue
+			}
 			return err
 		}
 	}
@@ -293,6 +325,68 @@ func createTarFile(path, extractDir string, hdr *tar.Header, reader io.Reader, L
 	return nil
 }
 
+// copySparse copies reader to file, seeking over the blocks of zeros rather
+// than writing them, so that the holes of sparse files are recreated rather
+// than filled.
+func copySparse(file *os.File, reader io.Reader) error {
+	var (
+		buf  = make([]byte, trBufSize)
+		size int64
+		hole bool
+	)
+	for {
+		n, err := io.ReadFull(reader, buf)
+		// write the runs of blocks with data, and seek over the others
+		for start := 0; start < n; {
+			end := start + sparseBlockSize
+			if end > n {
+				end = n
+			}
+			zeros := isZeros(buf[start:end])
+			for end < n {
+				next := end + sparseBlockSize
+				if next > n {
+					next = n
+				}
+				if isZeros(buf[end:next]) != zeros {
+					break
+				}
+				end = next
+			}
+			if zeros {
+				if _, err := file.Seek(int64(end-start), os.SEEK_CUR); err != nil {
+					return err
+				}
+			} else if _, err := file.Write(buf[start:end]); err != nil {
+				return err
+			}
+			hole = zeros
+			size += int64(end - start)
+			start = end
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	// a file ending with a hole is only as long as its last write
+	if hole {
+		return file.Truncate(size)
+	}
+	return nil
+}
+
+func isZeros(buf []byte) bool {
+	for _, b := range buf {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
 // Tar creates an archive from the directory at `path`, and returns it as a
 // stream of bytes.
 func Tar(path string, compression Compression) (io.ReadCloser, error) {
@@ -410,6 +504,11 @@ func Untar(archive io.Reader, dest string, options *TarOptions) error {
 	}
 	defer decompressedArchive.Close()
 
+	if len(options.Excludes) == 0 && useExternalTar(dest) {
+		_, err := untarExternal(decompressedArchive, dest, !options.NoLchown)
+		return err
+	}
+
 	tr := tar.NewReader(decompressedArchive)
 	trBuf := bufio.NewReaderSize(nil, trBufSize)
 
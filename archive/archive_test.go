@@ -8,9 +8,12 @@ import (
 	"os"
 	"os/exec"
 	"path"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/docker/docker/pkg/system"
 	"github.com/docker/docker/vendor/src/code.google.com/p/go/src/pkg/archive/tar"
 )
 
@@ -204,6 +207,159 @@ func TestUntarUstarGnuConflict(t *testing.T) {
 	}
 }
 
+// posixACL encodes an access ACL granting read to the users 1000 to
+// 1000+users-1, in the format of the system.posix_acl_access xattr.
+func posixACL(users int) []byte {
+	var buf bytes.Buffer
+	entry := func(tag, perm uint16, id uint32) {
+		buf.Write([]byte{byte(tag), byte(tag >> 8), byte(perm), byte(perm >> 8), byte(id), byte(id >> 8), byte(id >> 16), byte(id >> 24)})
+	}
+	buf.Write([]byte{2, 0, 0, 0})
+	entry(0x01, 6, 0xffffffff)
+	for i := 0; i < users; i++ {
+		entry(0x02, 4, uint32(1000+i))
+	}
+	entry(0x04, 4, 0xffffffff)
+	entry(0x10, 4, 0xffffffff)
+	entry(0x20, 4, 0xffffffff)
+	return buf.Bytes()
+}
+
+func TestTarUntarXattrs(t *testing.T) {
+	origin, err := ioutil.TempDir("", "docker-test-untar-origin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(origin)
+	if err := ioutil.WriteFile(path.Join(origin, "1"), []byte("hello world"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	// more than 128 bytes, which Lgetxattr reads at once
+	if err := system.Lsetxattr(path.Join(origin, "1"), "system.posix_acl_access", posixACL(20), 0); err != nil {
+		t.Skipf("ACLs not supported: %s", err)
+	}
+	acl, err := system.Lgetxattr(path.Join(origin, "1"), "system.posix_acl_access")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dest, err := ioutil.TempDir("", "docker-test-untar-dest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+	if err := TarUntar(origin, dest); err != nil {
+		t.Fatal(err)
+	}
+	untarred, err := system.Lgetxattr(path.Join(dest, "1"), "system.posix_acl_access")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(untarred, acl) {
+		t.Fatalf("Expected the ACL %v, got %v", acl, untarred)
+	}
+}
+
+func TestKeepXattr(t *testing.T) {
+	for key, expected := range map[string]bool{
+		"security.capability":     true,
+		"security.ima":            true,
+		"security.selinux":        false,
+		"system.posix_acl_access": true,
+		"user.comment":            false,
+		"trusted.overlay.opaque":  false,
+	} {
+		if keepXattr(key) != expected {
+			t.Fatalf("Expected keepXattr(%s) to be %v", key, expected)
+		}
+	}
+}
+
+func TestTarUntarSparse(t *testing.T) {
+	origin, err := ioutil.TempDir("", "docker-test-untar-origin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(origin)
+	f, err := os.Create(path.Join(origin, "sparse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteAt([]byte("hello"), 512*1024); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Truncate(1024 * 1024); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	dest, err := ioutil.TempDir("", "docker-test-untar-dest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+	if err := TarUntar(origin, dest); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path.Join(dest, "sparse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 1024*1024 || string(data[512*1024:512*1024+5]) != "hello" {
+		t.Fatalf("Wrong content of the sparse file, %d bytes", len(data))
+	}
+	fi, err := os.Stat(path.Join(dest, "sparse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blocks := fi.Sys().(*syscall.Stat_t).Blocks; blocks*512 >= 512*1024 {
+		t.Fatalf("Expected the holes of the file to be kept, %d blocks are allocated", blocks)
+	}
+}
+
+func TestApplyLayerExternalTar(t *testing.T) {
+	tarPath, err := exec.LookPath("tar")
+	if err != nil {
+		t.Skip("tar not found")
+	}
+	defer func() { ExternalTar = "" }()
+	ExternalTar = tarPath
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, hdr := range []*tar.Header{
+		{Name: "etc/", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: "etc/hosts", Typeflag: tar.TypeReg, Mode: 0644, Size: 9},
+		{Name: "etc/.wh.motd", Typeflag: tar.TypeReg, Mode: 0644},
+		{Name: ".wh..wh.plnk/", Typeflag: tar.TypeDir, Mode: 0700},
+	} {
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Size > 0 {
+			tw.Write([]byte("127.0.0.1"))
+		}
+	}
+	tw.Close()
+
+	dest, err := ioutil.TempDir("", "docker-test-applylayer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+	if err := ApplyLayer(dest, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := ioutil.ReadFile(path.Join(dest, "etc", "hosts")); err != nil || string(data) != "127.0.0.1" {
+		t.Fatalf("Expected etc/hosts to be extracted, got %q (%v)", data, err)
+	}
+	for _, name := range []string{"etc/.wh.motd", ".wh..wh.plnk"} {
+		if _, err := os.Lstat(path.Join(dest, name)); !os.IsNotExist(err) {
+			t.Fatalf("Expected %s to be removed, got %v", name, err)
+		}
+	}
+}
+
 func prepareUntarSourceDirectory(numberOfFiles int, targetPath string) (int, error) {
 	fileData := []byte("fooo")
 	for n := 0; n < numberOfFiles; n++ {
@@ -242,3 +398,53 @@ func BenchmarkTarUntar(b *testing.B) {
 		os.RemoveAll(target)
 	}
 }
+
+func TestRemapIDs(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, hdr := range []*tar.Header{
+		{Name: "root", Typeflag: tar.TypeReg, Mode: 0644, Uid: 0, Gid: 0},
+		{Name: "user", Typeflag: tar.TypeReg, Mode: 0644, Uid: 1000, Gid: 50, Size: 4},
+	} {
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte("data")[:hdr.Size])
+	}
+	tw.Close()
+
+	maps := []idtools.IDMap{{ContainerID: 0, HostID: 100000, Size: 65536}}
+	tr := tar.NewReader(ToHostIDs(&buf, maps, maps))
+	for _, expected := range []int{100000, 101000} {
+		hdr, err := tr.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Uid != expected {
+			t.Fatalf("Expected %s to be owned by %d, got %d", hdr.Name, expected, hdr.Uid)
+		}
+	}
+	if data, err := ioutil.ReadAll(tr); err != nil || string(data) != "data" {
+		t.Fatalf("Expected the content to be kept, got %q (%v)", data, err)
+	}
+
+	buf.Reset()
+	tw = tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "hosts", Typeflag: tar.TypeReg, Mode: 0644, Uid: 0, Gid: 100050})
+	tw.Close()
+	hdr, err := tar.NewReader(ToContainerIDs(&buf, maps, maps)).Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Uid != idtools.OverflowID || hdr.Gid != 50 {
+		t.Fatalf("Expected hosts to be owned by %d:50, got %d:%d", idtools.OverflowID, hdr.Uid, hdr.Gid)
+	}
+
+	buf.Reset()
+	tw = tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "far", Typeflag: tar.TypeReg, Mode: 0644, Uid: 70000})
+	tw.Close()
+	if _, err := ioutil.ReadAll(ToHostIDs(&buf, maps, maps)); err == nil {
+		t.Fatal("Expected an error for an ID which is not mapped")
+	}
+}
@@ -78,7 +78,7 @@ func Changes(layers []string, rw string) ([]Change, error) {
 		}
 
 		// Skip AUFS metadata
-		if matched, err := filepath.Match("/.wh..wh.*", path); err != nil || matched {
+		if matched, err := filepath.Match("/"+WhiteoutMetaPrefix+"*", path); err != nil || matched {
 			return err
 		}
 
@@ -88,9 +88,19 @@ func Changes(layers []string, rw string) ([]Change, error) {
 
 		// Find out what kind of modification happened
 		file := filepath.Base(path)
+		// An opaque directory removed the content it has in the lower layers
+		// and does not have in rw
+		if file == WhiteoutOpaqueDir {
+			deleted, err := opaqueDeletes(layers, rw, filepath.Dir(path))
+			if err != nil {
+				return err
+			}
+			changes = append(changes, deleted...)
+			return nil
+		}
 		// If there is a whiteout, then the file was removed
-		if strings.HasPrefix(file, ".wh.") {
-			originalFile := file[len(".wh."):]
+		if strings.HasPrefix(file, WhiteoutPrefix) {
+			originalFile := file[len(WhiteoutPrefix):]
 			change.Path = filepath.Join(filepath.Dir(path), originalFile)
 			change.Kind = ChangeDelete
 		} else {
@@ -318,13 +328,25 @@ func ChangesDirs(newDir, oldDir string) ([]Change, error) {
 	return newRoot.Changes(oldRoot), nil
 }
 
+// ChangesSize returns the size of the files added or modified by changes in
+// newDir, counting the files hard linked together once.
 func ChangesSize(newDir string, changes []Change) int64 {
-	var size int64
+	var (
+		size   int64
+		inodes = make(map[uint64]bool)
+	)
 	for _, change := range changes {
 		if change.Kind == ChangeModify || change.Kind == ChangeAdd {
 			file := filepath.Join(newDir, change.Path)
 			fileInfo, _ := os.Lstat(file)
 			if fileInfo != nil && !fileInfo.IsDir() {
+				if stat, ok := fileInfo.Sys().(*syscall.Stat_t); ok && stat.Nlink > 1 {
+					// inode is not a uint64 on all platforms. Cast it to avoid issues.
+					if inodes[uint64(stat.Ino)] {
+						continue
+					}
+					inodes[uint64(stat.Ino)] = true
+				}
 				size += fileInfo.Size()
 			}
 		}
@@ -340,6 +362,61 @@ func minor(device uint64) uint64 {
 	return (device & 0xff) | ((device >> 12) & 0xfff00)
 }
 
+// opaqueDeletes returns the deletions of the entries the opaque directory
+// dir has in the lower layers, from the top one down, and not in rw.
+func opaqueDeletes(layers []string, rw, dir string) ([]Change, error) {
+	var (
+		changes []Change
+		seen    = make(map[string]bool)
+	)
+	for _, layer := range layers {
+		names, err := readDirNames(filepath.Join(layer, dir))
+		if err != nil {
+			return nil, err
+		}
+		opaque := false
+		for _, name := range names {
+			// the entries whited out by a layer are not in the layers below
+			if name == WhiteoutOpaqueDir {
+				opaque = true
+				continue
+			}
+			if strings.HasPrefix(name, WhiteoutPrefix) {
+				seen[name[len(WhiteoutPrefix):]] = true
+				continue
+			}
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			if _, err := os.Lstat(filepath.Join(rw, dir, name)); err == nil {
+				continue
+			} else if !os.IsNotExist(err) {
+				return nil, err
+			}
+			changes = append(changes, Change{Path: filepath.Join(dir, name), Kind: ChangeDelete})
+		}
+		if opaque {
+			break
+		}
+	}
+	return changes, nil
+}
+
+// readDirNames returns the names of the entries of dir, or none if it does
+// not exist.
+func readDirNames(dir string) ([]string, error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	return f.Readdirnames(-1)
+}
+
 func ExportChanges(dir string, changes []Change) (Archive, error) {
 	reader, writer := io.Pipe()
 	tw := tar.NewWriter(writer)
@@ -354,7 +431,7 @@ func ExportChanges(dir string, changes []Change) (Archive, error) {
 			if change.Kind == ChangeDelete {
 				whiteOutDir := filepath.Dir(change.Path)
 				whiteOutBase := filepath.Base(change.Path)
-				whiteOut := filepath.Join(whiteOutDir, ".wh."+whiteOutBase)
+				whiteOut := filepath.Join(whiteOutDir, WhiteoutPrefix+whiteOutBase)
 				timestamp := time.Now()
 				hdr := &tar.Header{
 					Name:       whiteOut[1:],
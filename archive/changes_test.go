@@ -1,13 +1,17 @@
 package archive
 
 import (
+	"bytes"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path"
+	"reflect"
 	"sort"
 	"testing"
 	"time"
+
+	"github.com/docker/docker/vendor/src/code.google.com/p/go/src/pkg/archive/tar"
 )
 
 func max(x, y int) int {
@@ -299,3 +303,124 @@ func TestApplyLayer(t *testing.T) {
 		t.Fatalf("Unexpected differences after reapplying mutation: %v", changes2)
 	}
 }
+
+func TestApplyLayerOpaqueDir(t *testing.T) {
+	dest, err := ioutil.TempDir("", "docker-changes-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+	if err := os.MkdirAll(path.Join(dest, "dir", "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"dir/lower", "dir/sub/lower", "other"} {
+		if err := ioutil.WriteFile(path.Join(dest, name), []byte("lower"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// an AUFS layer which replaced dir, with an entry sorted before the
+	// opaque marker
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, hdr := range []*tar.Header{
+		{Name: "dir/", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: "dir/-first", Typeflag: tar.TypeReg, Mode: 0644},
+		{Name: "dir/" + WhiteoutOpaqueDir, Typeflag: tar.TypeReg, Mode: 0644},
+		{Name: "dir/upper", Typeflag: tar.TypeReg, Mode: 0644},
+	} {
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+
+	if err := ApplyLayer(dest, &buf); err != nil {
+		t.Fatal(err)
+	}
+	names, err := readDirNames(path.Join(dest, "dir"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(names)
+	if expected := []string{"-first", "upper"}; !reflect.DeepEqual(names, expected) {
+		t.Fatalf("Expected dir to contain %v, got %v", expected, names)
+	}
+	if _, err := os.Stat(path.Join(dest, "other")); err != nil {
+		t.Fatalf("Expected the content out of dir to be kept, got %s", err)
+	}
+}
+
+func TestChangesOpaqueDir(t *testing.T) {
+	lower, err := ioutil.TempDir("", "docker-changes-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(lower)
+	rw, err := ioutil.TempDir("", "docker-changes-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(rw)
+	for root, names := range map[string][]string{
+		lower: {"dir/gone", "dir/kept", "dir/.wh.whited"},
+		rw:    {"dir/" + WhiteoutOpaqueDir, "dir/kept", "dir/new"},
+	} {
+		if err := os.MkdirAll(path.Join(root, "dir"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range names {
+			if err := ioutil.WriteFile(path.Join(root, name), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// dir itself is left unchanged
+	mtime := time.Now().Add(-time.Hour)
+	for _, root := range []string{lower, rw} {
+		if err := os.Chtimes(path.Join(root, "dir"), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	changes, err := Changes([]string{lower}, rw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Sort(byPath{changes})
+	expected := []Change{
+		{"/dir/gone", ChangeDelete},
+		{"/dir/kept", ChangeModify},
+		{"/dir/new", ChangeAdd},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("Expected the changes %v, got %v", expected, changes)
+	}
+}
+
+func TestChangesSizeHardLinks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-changes-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(path.Join(dir, "file"), make([]byte, 100), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(path.Join(dir, "file"), path.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path.Join(dir, "other"), make([]byte, 10), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	changes := []Change{
+		{"/file", ChangeAdd},
+		{"/link", ChangeAdd},
+		{"/other", ChangeModify},
+	}
+	if size := ChangesSize(dir, changes); size != 110 {
+		t.Fatalf("Expected the hard linked files to be counted once, got a size of %d", size)
+	}
+}
@@ -2,3 +2,10 @@ package archive
 
 const twBufSize = 32 * 1024
 const trBufSize = 32 * 1024
+
+// The files of at least sparseMinSize bytes are extracted without their
+// blocks of sparseBlockSize zeros, which recreates their holes.
+const (
+	sparseBlockSize = 4 * 1024
+	sparseMinSize   = 64 * 1024
+)
@@ -32,11 +32,22 @@ func ApplyLayer(dest string, layer ArchiveReader) error {
 		return err
 	}
 
+	if useExternalTar(dest) {
+		names, err := untarExternal(layer, dest, true)
+		if err != nil {
+			return err
+		}
+		return removeWhiteouts(dest, names)
+	}
+
 	tr := tar.NewReader(layer)
 	trBuf := bufio.NewReaderSize(nil, trBufSize)
 
 	var dirs []*tar.Header
 
+	// the paths extracted so far, which the opaque directories keep
+	unpacked := make(map[string]bool)
+
 	aufsTempdir := ""
 	aufsHardlinks := make(map[string]*tar.Header)
 
@@ -69,11 +80,11 @@ func ApplyLayer(dest string, layer ArchiveReader) error {
 		}
 
 		// Skip AUFS metadata dirs
-		if strings.HasPrefix(hdr.Name, ".wh..wh.") {
+		if strings.HasPrefix(hdr.Name, WhiteoutMetaPrefix) {
 			// Regular files inside /.wh..wh.plnk can be used as hardlink targets
 			// We don't want this directory, but we need the files in them so that
 			// such hardlinks can be resolved.
-			if strings.HasPrefix(hdr.Name, ".wh..wh.plnk") && hdr.Typeflag == tar.TypeReg {
+			if strings.HasPrefix(hdr.Name, WhiteoutLinkDir) && hdr.Typeflag == tar.TypeReg {
 				basename := filepath.Base(hdr.Name)
 				aufsHardlinks[basename] = hdr
 				if aufsTempdir == "" {
@@ -91,8 +102,12 @@ func ApplyLayer(dest string, layer ArchiveReader) error {
 
 		path := filepath.Join(dest, hdr.Name)
 		base := filepath.Base(path)
-		if strings.HasPrefix(base, ".wh.") {
-			originalBase := base[len(".wh."):]
+		if base == WhiteoutOpaqueDir {
+			if err := removeOpaque(filepath.Dir(path), unpacked); err != nil {
+				return err
+			}
+		} else if strings.HasPrefix(base, WhiteoutPrefix) {
+			originalBase := base[len(WhiteoutPrefix):]
 			originalPath := filepath.Join(filepath.Dir(path), originalBase)
 			if err := os.RemoveAll(originalPath); err != nil {
 				return err
@@ -116,7 +131,7 @@ func ApplyLayer(dest string, layer ArchiveReader) error {
 
 			// Hard links into /.wh..wh.plnk don't work, as we don't extract that directory, so
 			// we manually retarget these into the temporary files we extracted them into
-			if hdr.Typeflag == tar.TypeLink && strings.HasPrefix(filepath.Clean(hdr.Linkname), ".wh..wh.plnk") {
+			if hdr.Typeflag == tar.TypeLink && strings.HasPrefix(filepath.Clean(hdr.Linkname), WhiteoutLinkDir) {
 				linkBasename := filepath.Base(hdr.Linkname)
 				srcHdr = aufsHardlinks[linkBasename]
 				if srcHdr == nil {
@@ -133,6 +148,7 @@ func ApplyLayer(dest string, layer ArchiveReader) error {
 			if err := createTarFile(path, dest, srcHdr, srcData, true); err != nil {
 				return err
 			}
+			unpacked[path] = true
 
 			// Directory mtimes must be handled at the end to avoid further
 			// file creation in them to modify the directory mtime
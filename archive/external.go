@@ -0,0 +1,80 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ExternalTar is the path of a GNU tar binary which Untar and ApplyLayer
+// extract the archives into empty directories with, e.g. the layers pulled
+// on top of no parent, as it is faster than the tar package on archives of
+// many small files. The tar package is used when it is empty.
+var ExternalTar string
+
+// useExternalTar returns whether the archives extracted into dest go
+// through ExternalTar.
+func useExternalTar(dest string) bool {
+	if ExternalTar == "" {
+		return false
+	}
+	f, err := os.Open(dest)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	_, err = f.Readdirnames(1)
+	return err == io.EOF
+}
+
+// untarExternal extracts the uncompressed archive into dest with
+// ExternalTar, keeping the extended attributes the tar package keeps, and
+// returns the names of the entries.
+func untarExternal(archive io.Reader, dest string, lchown bool) ([]string, error) {
+	args := []string{"-x", "-v", "-p", "--numeric-owner", "--quoting-style=literal",
+		"--xattrs", "--xattrs-include=security.*", "--xattrs-include=system.posix_acl_*", "--xattrs-exclude=security.selinux",
+		"-C", dest, "-f", "-"}
+	if !lchown {
+		args = append(args, "--no-same-owner")
+	}
+	var (
+		cmd    = exec.Command(ExternalTar, args...)
+		stderr bytes.Buffer
+	)
+	cmd.Stdin = archive
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	var names []string
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		names = append(names, filepath.Clean(scanner.Text()))
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("%s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return names, scanner.Err()
+}
+
+// removeWhiteouts removes the whiteouts and the AUFS metadata of a layer
+// extracted into an empty directory, where they have nothing to hide.
+func removeWhiteouts(dest string, names []string) error {
+	for _, name := range names {
+		if strings.HasPrefix(filepath.Base(name), ".wh.") {
+			if err := os.RemoveAll(filepath.Join(dest, name)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package archive
+
+import (
+	"io"
+
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/docker/docker/vendor/src/code.google.com/p/go/src/pkg/archive/tar"
+)
+
+// ToHostIDs returns the layer, possibly compressed, uncompressed and with the
+// owners of its files mapped from the IDs of the containers to the ones of
+// the host, for a daemon running the containers in a user namespace.
+func ToHostIDs(layer ArchiveReader, uidMaps, gidMaps []idtools.IDMap) Archive {
+	return remapIDs(layer, func(hdr *tar.Header) error {
+		uid, err := idtools.ToHost(hdr.Uid, uidMaps)
+		if err != nil {
+			return err
+		}
+		gid, err := idtools.ToHost(hdr.Gid, gidMaps)
+		if err != nil {
+			return err
+		}
+		hdr.Uid, hdr.Gid = uid, gid
+		return nil
+	})
+}
+
+// ToContainerIDs returns the archive with the owners of its files mapped
+// from the IDs of the host to the ones of the containers. The IDs of the
+// host which are not mapped are changed to idtools.OverflowID.
+func ToContainerIDs(archive ArchiveReader, uidMaps, gidMaps []idtools.IDMap) Archive {
+	return remapIDs(archive, func(hdr *tar.Header) error {
+		hdr.Uid = idtools.ToContainer(hdr.Uid, uidMaps)
+		hdr.Gid = idtools.ToContainer(hdr.Gid, gidMaps)
+		return nil
+	})
+}
+
+func remapIDs(archive ArchiveReader, remap func(*tar.Header) error) Archive {
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		pipeWriter.CloseWithError(rewriteHeaders(archive, pipeWriter, remap))
+	}()
+	return pipeReader
+}
+
+func rewriteHeaders(archive ArchiveReader, dest io.Writer, remap func(*tar.Header) error) error {
+	decompressed, err := DecompressStream(archive)
+	if err != nil {
+		return err
+	}
+	defer decompressed.Close()
+
+	tr := tar.NewReader(decompressed)
+	tw := tar.NewWriter(dest)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := remap(hdr); err != nil {
+			return err
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
@@ -0,0 +1,41 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// The layers mark their deletions in the AUFS format, whatever the driver
+// which created them, and every driver applies them the same way:
+//  - a file named WhiteoutPrefix+NAME removes NAME of the lower layers
+//  - a file named WhiteoutOpaqueDir in a directory hides all the content
+//    the directory has in the lower layers
+//  - the names starting with WhiteoutMetaPrefix at the root are AUFS
+//    metadata, which is not extracted; the hard links of the layer can
+//    target the files of WhiteoutLinkDir.
+const (
+	WhiteoutPrefix     = ".wh."
+	WhiteoutMetaPrefix = WhiteoutPrefix + WhiteoutPrefix
+	WhiteoutOpaqueDir  = WhiteoutMetaPrefix + ".opq"
+	WhiteoutLinkDir    = WhiteoutMetaPrefix + "plnk"
+)
+
+// removeOpaque removes the content of dir, but for the paths in unpacked,
+// which the layer marking dir as opaque extracted before the marker.
+func removeOpaque(dir string, unpacked map[string]bool) error {
+	return filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dir || unpacked[path] {
+			return nil
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
@@ -40,7 +40,10 @@ func remote(eng *engine.Engine) error {
 	if err := eng.Register("serveapi", apiserver.ServeApi); err != nil {
 		return err
 	}
-	return eng.Register("acceptconnections", apiserver.AcceptConnections)
+	if err := eng.Register("acceptconnections", apiserver.AcceptConnections); err != nil {
+		return err
+	}
+	return eng.Register("api_connections", apiserver.ApiConnections)
 }
 
 // daemon: a default execution and storage backend for Docker on Linux,
@@ -71,6 +74,7 @@ func dockerVersion(job *engine.Job) engine.Status {
 	v.Set("GoVersion", runtime.Version())
 	v.Set("Os", runtime.GOOS)
 	v.Set("Arch", runtime.GOARCH)
+	v.SetBool("Experimental", dockerversion.EXPERIMENTAL == "true")
 	if kernelVersion, err := kernel.GetKernelVersion(); err == nil {
 		v.Set("KernelVersion", kernelVersion.String())
 	}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/docker/docker/api/bench"
+	"github.com/docker/docker/engine"
+)
+
+func main() {
+	var (
+		host        = flag.String("H", "unix:///var/run/docker.sock", "Address of the daemon, as PROTO://ADDR")
+		image       = flag.String("image", "busybox", "Image of the containers created")
+		cmd         = flag.String("cmd", "sleep 600", "Command of the containers created")
+		concurrency = flag.Int("c", 4, "Number of concurrent workers")
+		iterations  = flag.Int("n", 10, "Number of containers created, started, stopped and removed by each worker")
+	)
+	flag.Parse()
+
+	eng := engine.New()
+	eng.Logging = false
+	if err := eng.Register("api_bench", bench.Bench); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	job := eng.Job("api_bench", *host)
+	job.Setenv("Image", *image)
+	job.SetenvList("Cmd", strings.Fields(*cmd))
+	job.SetenvInt("Concurrency", *concurrency)
+	job.SetenvInt("Iterations", *iterations)
+	outs, err := job.Stdout.AddListTable()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := job.Run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 20, 1, 3, ' ', 0)
+	fmt.Fprintln(w, "OPERATION\tCOUNT\tERRORS\tP50\tP90\tP99\tMAX")
+	failed := false
+	for _, out := range outs.Data {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%s\t%s\n", out.Get("Op"), out.GetInt("Count"), out.GetInt("Errors"),
+			ms(out.GetInt64("P50")), ms(out.GetInt64("P90")), ms(out.GetInt64("P99")), ms(out.GetInt64("Max")))
+		if out.GetInt("Errors") > 0 {
+			failed = true
+		}
+	}
+	w.Flush()
+	for _, out := range outs.Data {
+		if lastError := out.Get("LastError"); lastError != "" {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", out.Get("Op"), lastError)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// ms formats a latency in microseconds as milliseconds.
+func ms(us int64) string {
+	return fmt.Sprintf("%.1fms", float64(us)/1000)
+}
@@ -16,18 +16,22 @@ import (
 	"reflect"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/docker/docker/archive"
 	"github.com/docker/docker/engine"
+	"github.com/docker/docker/image"
 	"github.com/docker/docker/nat"
+	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/log"
 	"github.com/docker/docker/pkg/parsers"
 	"github.com/docker/docker/pkg/symlink"
 	"github.com/docker/docker/pkg/system"
 	"github.com/docker/docker/pkg/tarsum"
+	"github.com/docker/docker/pkg/units"
 	"github.com/docker/docker/registry"
 	"github.com/docker/docker/runconfig"
 	"github.com/docker/docker/utils"
@@ -44,15 +48,35 @@ func (daemon *Daemon) CmdBuild(job *engine.Job) engine.Status {
 		noCache        = job.GetenvBool("nocache")
 		rm             = job.GetenvBool("rm")
 		forceRm        = job.GetenvBool("forcerm")
+		squash         = job.GetenvBool("squash")
+		dockerfileName = job.Getenv("dockerfile")
 		authConfig     = &registry.AuthConfig{}
 		configFile     = &registry.ConfigFile{}
+		buildArgs      = make(map[string]string)
+		buildContexts  = make(map[string]string)
 		tag            string
 		context        io.ReadCloser
 	)
 	job.GetenvJson("authConfig", authConfig)
 	job.GetenvJson("configFile", configFile)
+	if err := job.GetenvJson("buildargs", &buildArgs); err != nil {
+		return job.Errorf("Invalid build args: %s", err)
+	}
+	if err := job.GetenvJson("buildcontexts", &buildContexts); err != nil {
+		return job.Errorf("Invalid build contexts: %s", err)
+	}
+	for name, source := range buildContexts {
+		if _, _, err := utils.ParseBuildContext(name + "=" + source); err != nil {
+			return job.Error(err)
+		}
+	}
 	repoName, tag = parsers.ParseRepositoryTag(repoName)
 
+	start := time.Now()
+	defer func() {
+		daemon.usage.buildDone(time.Since(start))
+	}()
+
 	if remoteURL == "" {
 		context = ioutil.NopCloser(job.Stdin)
 	} else if utils.IsGIT(remoteURL) {
@@ -89,8 +113,13 @@ func (daemon *Daemon) CmdBuild(job *engine.Job) engine.Status {
 			return job.Error(err)
 		}
 		context = c
+		// the downloaded file is the Dockerfile, whatever its name
+		dockerfileName = ""
 	}
 	defer context.Close()
+	if max := daemon.config.MaxBuildContext; max > 0 {
+		context = &contextLimiter{ReadCloser: context, max: int64(max) * 1024 * 1024}
+	}
 
 	sf := utils.NewStreamFormatter(job.GetenvBool("json"))
 	b := NewBuildFile(daemon, daemon.eng,
@@ -102,7 +131,7 @@ func (daemon *Daemon) CmdBuild(job *engine.Job) engine.Status {
 			Writer:          job.Stdout,
 			StreamFormatter: sf,
 		},
-		!suppressOutput, !noCache, rm, forceRm, job.Stdout, sf, authConfig, configFile)
+		!suppressOutput, !noCache, rm, forceRm, squash, job.Stdout, sf, authConfig, configFile, buildArgs, buildContexts, dockerfileName)
 	id, err := b.Build(context)
 	if err != nil {
 		return job.Error(err)
@@ -117,6 +146,23 @@ var (
 	ErrDockerfileEmpty = errors.New("Dockerfile cannot be empty")
 )
 
+// contextLimiter fails the build once more than max bytes of context were
+// read.
+type contextLimiter struct {
+	io.ReadCloser
+	read int64
+	max  int64
+}
+
+func (l *contextLimiter) Read(p []byte) (int, error) {
+	n, err := l.ReadCloser.Read(p)
+	l.read += int64(n)
+	if l.read > l.max {
+		return n, fmt.Errorf("The build context is larger than %s, the limit set on the daemon", units.HumanSize(l.max))
+	}
+	return n, err
+}
+
 type BuildFile interface {
 	Build(io.Reader) (string, error)
 	CmdFrom(string) error
@@ -128,16 +174,24 @@ type buildFile struct {
 	eng    *engine.Engine
 
 	image      string
+	fromImage  string
 	maintainer string
 	config     *runconfig.Config
 
 	contextPath string
 	context     *tarsum.TarSum
 
+	// contextSources are the additional build contexts the daemon fetches,
+	// by name, and contexts the directories of all of them once prepared.
+	contextSources  map[string]string
+	contexts        map[string]string
+	contextReleases []func()
+
 	verbose      bool
 	utilizeCache bool
 	rm           bool
 	forceRm      bool
+	squash       bool
 
 	authConfig *registry.AuthConfig
 	configFile *registry.ConfigFile
@@ -154,6 +208,18 @@ type buildFile struct {
 
 	// cmdSet indicates is CMD was set in current Dockerfile
 	cmdSet bool
+
+	// cacheHit indicates if the current step used the cache
+	cacheHit bool
+
+	// buildArgs are the values given with --build-arg, args the KEY=VALUE
+	// pairs of the build args declared with ARG which have a value.
+	buildArgs    map[string]string
+	args         []string
+	declaredArgs map[string]struct{}
+
+	// dockerfileName is the path of the Dockerfile in the context
+	dockerfileName string
 }
 
 func (b *buildFile) clearTmp(containers map[string]struct{}) {
@@ -168,8 +234,9 @@ func (b *buildFile) clearTmp(containers map[string]struct{}) {
 	}
 }
 
-func (b *buildFile) CmdFrom(name string) error {
-	image, err := b.daemon.Repositories().LookupImage(name)
+// pullImage returns the image name, pulling it if it is not there.
+func (b *buildFile) pullImage(name string) (*image.Image, error) {
+	img, err := b.daemon.Repositories().LookupImage(name)
 	if err != nil {
 		if b.daemon.Graph().IsNotExist(err) {
 			remote, tag := parsers.ParseRepositoryTag(name)
@@ -178,7 +245,7 @@ func (b *buildFile) CmdFrom(name string) error {
 				// The request came with a full auth config file, we prefer to use that
 				endpoint, _, err := registry.ResolveRepositoryName(remote)
 				if err != nil {
-					return err
+					return nil, err
 				}
 				resolvedAuth := b.configFile.ResolveAuthConfig(endpoint)
 				pullRegistryAuth = &resolvedAuth
@@ -189,17 +256,30 @@ func (b *buildFile) CmdFrom(name string) error {
 			job.SetenvJson("authConfig", pullRegistryAuth)
 			job.Stdout.Add(b.outOld)
 			if err := job.Run(); err != nil {
-				return err
+				return nil, err
 			}
-			image, err = b.daemon.Repositories().LookupImage(name)
-			if err != nil {
-				return err
-			}
-		} else {
-			return err
+			return b.daemon.Repositories().LookupImage(name)
 		}
+		return nil, err
+	}
+	// the pull checks the names of the images it pulls
+	warning, err := b.daemon.Repositories().CheckImplicitTag(name)
+	if err != nil {
+		return nil, err
+	}
+	if warning != "" {
+		fmt.Fprintf(b.outStream, "%s\n", warning)
+	}
+	return img, nil
+}
+
+func (b *buildFile) CmdFrom(name string) error {
+	image, err := b.pullImage(name)
+	if err != nil {
+		return err
 	}
 	b.image = image.ID
+	b.fromImage = image.ID
 	b.config = &runconfig.Config{}
 	if image.Config != nil {
 		b.config = image.Config
@@ -265,6 +345,7 @@ func (b *buildFile) probeCache() (bool, error) {
 			fmt.Fprintf(b.outStream, " ---> Using cache\n")
 			log.Debugf("[BUILDER] Use cached version")
 			b.image = cache.ID
+			b.cacheHit = true
 			return true, nil
 		} else {
 			log.Debugf("[BUILDER] Cache miss")
@@ -289,6 +370,17 @@ func (b *buildFile) CmdRun(args string) error {
 
 	defer func(cmd []string) { b.config.Cmd = cmd }(cmd)
 
+	// The build args are given to the command through its environment,
+	// which also makes them part of the cache key, but they must not be
+	// committed in the config of the image.
+	imageConfig := b.config
+	if len(b.args) > 0 {
+		runConfig := *b.config
+		runConfig.Env = b.buildEnv()
+		b.config = &runConfig
+		defer func() { b.config = imageConfig }()
+	}
+
 	log.Debugf("Command to be executed: %v", b.config.Cmd)
 
 	hit, err := b.probeCache()
@@ -312,6 +404,7 @@ func (b *buildFile) CmdRun(args string) error {
 	if err != nil {
 		return err
 	}
+	b.config = imageConfig
 	if err := b.commit(c.ID, cmd, "run"); err != nil {
 		return err
 	}
@@ -339,7 +432,7 @@ func (b *buildFile) ReplaceEnvMatches(value string) (string, error) {
 		match = match[strings.Index(match, "$"):]
 		matchKey := strings.Trim(match, "${}")
 
-		for _, envVar := range b.config.Env {
+		for _, envVar := range b.buildEnv() {
 			envParts := strings.SplitN(envVar, "=", 2)
 			envKey := envParts[0]
 			envValue := envParts[1]
@@ -376,6 +469,52 @@ func (b *buildFile) CmdEnv(args string) error {
 	return b.commit("", b.config.Cmd, fmt.Sprintf("ENV %s", replacedVar))
 }
 
+// buildEnv returns the build args with a value followed by the environment
+// of the image, which takes precedence over them.
+func (b *buildFile) buildEnv() []string {
+	env := make([]string, len(b.args))
+	copy(env, b.args)
+	return utils.ReplaceOrAppendEnvValues(env, b.config.Env)
+}
+
+// The ARG command declares a build arg, optionally with a default value,
+// which can be set with --build-arg. Unlike ENV its value is only
+// available to the following instructions and is not saved in the image.
+func (b *buildFile) CmdArg(args string) error {
+	key, err := b.declareArg(args)
+	if err != nil {
+		return err
+	}
+	return b.commit("", b.config.Cmd, fmt.Sprintf("ARG %s", key))
+}
+
+func (b *buildFile) declareArg(args string) (string, error) {
+	var (
+		parts      = strings.SplitN(args, "=", 2)
+		key        = strings.Trim(parts[0], " \t")
+		value      string
+		hasDefault = len(parts) == 2
+	)
+	if key == "" || strings.ContainsAny(key, " \t") {
+		return "", fmt.Errorf("Invalid ARG format")
+	}
+	if hasDefault {
+		replacedValue, err := b.ReplaceEnvMatches(strings.Trim(parts[1], " \t"))
+		if err != nil {
+			return "", err
+		}
+		value = replacedValue
+	}
+	b.declaredArgs[key] = struct{}{}
+	if buildArg, exists := b.buildArgs[key]; exists {
+		value, hasDefault = buildArg, true
+	}
+	if hasDefault {
+		b.args = utils.ReplaceOrAppendEnvValues(b.args, []string{fmt.Sprintf("%s=%s", key, value)})
+	}
+	return key, nil
+}
+
 func (b *buildFile) buildCmdFromJson(args string) []string {
 	var cmd []string
 	if err := json.Unmarshal([]byte(args), &cmd); err != nil {
@@ -474,8 +613,9 @@ func (b *buildFile) CmdVolume(args string) error {
 	return nil
 }
 
-func (b *buildFile) checkPathForAddition(orig string) error {
-	origPath := path.Join(b.contextPath, orig)
+// checkPathForAddition checks that orig exists within the context at root.
+func (b *buildFile) checkPathForAddition(root, orig string) error {
+	origPath := path.Join(root, orig)
 	if p, err := filepath.EvalSymlinks(origPath); err != nil {
 		if os.IsNotExist(err) {
 			return fmt.Errorf("%s: no such file or directory", orig)
@@ -484,7 +624,7 @@ func (b *buildFile) checkPathForAddition(orig string) error {
 	} else {
 		origPath = p
 	}
-	if !strings.HasPrefix(origPath, b.contextPath) {
+	if !strings.HasPrefix(origPath, root) {
 		return fmt.Errorf("Forbidden path outside the build context: %s (%s)", orig, origPath)
 	}
 	_, err := os.Stat(origPath)
@@ -497,11 +637,58 @@ func (b *buildFile) checkPathForAddition(orig string) error {
 	return nil
 }
 
-func (b *buildFile) addContext(container *Container, orig, dest string, decompress bool) error {
+// contentHash returns a checksum of the file or the directory tree at root
+// made of the relative paths, the modes and the content of its files. The
+// modification times and the owners are left out, as they change with each
+// checkout or copy of the same files, so the same context gives the same
+// checksums on any machine.
+func contentHash(root string) (string, error) {
+	root, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+	hasher := sha256.New()
+	err = filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		var content string
+		switch {
+		case fi.Mode()&os.ModeSymlink != 0:
+			if content, err = os.Readlink(p); err != nil {
+				return err
+			}
+		case fi.Mode().IsRegular():
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			h := sha256.New()
+			_, err = io.Copy(h, f)
+			f.Close()
+			if err != nil {
+				return err
+			}
+			content = hex.EncodeToString(h.Sum(nil))
+		}
+		fmt.Fprintf(hasher, "%s\x00%o\x00%s\n", rel, fi.Mode(), content)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+func (b *buildFile) addContext(container *Container, root, orig, dest string, decompress bool, uid, gid int) error {
 	var (
 		err        error
 		destExists = true
-		origPath   = path.Join(b.contextPath, orig)
+		origPath   = path.Join(root, orig)
 		destPath   = path.Join(container.RootfsPath(), dest)
 	)
 
@@ -534,7 +721,7 @@ func (b *buildFile) addContext(container *Container, orig, dest string, decompre
 	}
 
 	if fi.IsDir() {
-		return copyAsDirectory(origPath, destPath, destExists)
+		return copyAsDirectory(origPath, destPath, destExists, uid, gid)
 	}
 
 	// If we are adding a remote file (or we've been told not to decompress), do not try to untar it
@@ -549,7 +736,7 @@ func (b *buildFile) addContext(container *Container, orig, dest string, decompre
 		}
 
 		// try to successfully untar the orig
-		if err := archive.UntarPath(origPath, tarDest); err == nil {
+		if err := b.untarPath(origPath, tarDest); err == nil {
 			return nil
 		} else if err != io.EOF {
 			log.Debugf("Couldn't untar %s to %s: %s", origPath, tarDest, err)
@@ -568,13 +755,46 @@ func (b *buildFile) addContext(container *Container, orig, dest string, decompre
 		resPath = path.Join(destPath, path.Base(origPath))
 	}
 
-	return fixPermissions(resPath, 0, 0)
+	return fixPermissions(resPath, uid, gid)
 }
 
 func (b *buildFile) runContextCommand(args string, allowRemote bool, allowDecompression bool, cmdName string) error {
 	if b.context == nil {
 		return fmt.Errorf("No context given. Impossible to use %s", cmdName)
 	}
+	var (
+		chown    string
+		checksum string
+		from     string
+		// the owner and the context are part of the cache key and of the
+		// history
+		instruction = cmdName
+		root        = b.contextPath
+	)
+	for strings.HasPrefix(args, "--chown=") || (allowRemote && strings.HasPrefix(args, "--checksum=")) || (cmdName == "COPY" && strings.HasPrefix(args, "--from=")) {
+		tmp := strings.SplitN(args, " ", 2)
+		if len(tmp) != 2 {
+			return fmt.Errorf("Invalid %s format", cmdName)
+		}
+		if strings.HasPrefix(tmp[0], "--chown=") {
+			if chown = strings.TrimPrefix(tmp[0], "--chown="); chown == "" {
+				return fmt.Errorf("Invalid %s format", cmdName)
+			}
+			instruction = fmt.Sprintf("%s --chown=%s", instruction, chown)
+		} else if strings.HasPrefix(tmp[0], "--from=") {
+			from = strings.TrimPrefix(tmp[0], "--from=")
+			if root = b.contexts[from]; root == "" {
+				return fmt.Errorf("Unknown build context %s, given with --build-context NAME=SOURCE", from)
+			}
+			instruction = fmt.Sprintf("%s --from=%s", instruction, from)
+		} else {
+			checksum = strings.TrimPrefix(tmp[0], "--checksum=")
+			if _, _, err := parseChecksum(checksum); err != nil {
+				return err
+			}
+		}
+		args = strings.TrimLeft(tmp[1], " \t")
+	}
 	tmp := strings.SplitN(args, " ", 2)
 	if len(tmp) != 2 {
 		return fmt.Errorf("Invalid %s format", cmdName)
@@ -591,14 +811,13 @@ func (b *buildFile) runContextCommand(args string, allowRemote bool, allowDecomp
 	}
 
 	cmd := b.config.Cmd
-	b.config.Cmd = []string{"/bin/sh", "-c", fmt.Sprintf("#(nop) %s %s in %s", cmdName, orig, dest)}
+	b.config.Cmd = []string{"/bin/sh", "-c", fmt.Sprintf("#(nop) %s %s in %s", instruction, orig, dest)}
 	defer func(cmd []string) { b.config.Cmd = cmd }(cmd)
 	b.config.Image = b.image
 
 	var (
 		origPath   = orig
 		destPath   = dest
-		remoteHash string
 		isRemote   bool
 		decompress = true
 	)
@@ -606,33 +825,23 @@ func (b *buildFile) runContextCommand(args string, allowRemote bool, allowDecomp
 	isRemote = utils.IsURL(orig)
 	if isRemote && !allowRemote {
 		return fmt.Errorf("Source can't be an URL for %s", cmdName)
+	} else if !isRemote && checksum != "" {
+		return fmt.Errorf("The --checksum of %s is only valid for an URL", cmdName)
 	} else if utils.IsURL(orig) {
-		// Initiate the download
-		resp, err := utils.Download(orig)
-		if err != nil {
-			return err
-		}
-
 		// Create a tmp dir
 		tmpDirName, err := ioutil.TempDir(b.contextPath, "docker-remote")
 		if err != nil {
 			return err
 		}
 
-		// Create a tmp file within our tmp dir
-		tmpFileName := path.Join(tmpDirName, "tmp")
-		tmpFile, err := os.OpenFile(tmpFileName, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
-		if err != nil {
-			return err
-		}
 		defer os.RemoveAll(tmpDirName)
 
-		// Download and dump result to tmp file
-		if _, err := io.Copy(tmpFile, resp.Body); err != nil {
-			tmpFile.Close()
+		// Download the file, or reuse the one of a previous build, to a
+		// tmp file within our tmp dir
+		tmpFileName := path.Join(tmpDirName, "tmp")
+		if err := b.daemon.downloads.fetch(orig, checksum, tmpFileName); err != nil {
 			return err
 		}
-		tmpFile.Close()
 
 		// Remove the mtime of the newly created tmp file
 		if err := system.UtimesNano(tmpFileName, make([]syscall.Timespec, 2)); err != nil {
@@ -641,18 +850,6 @@ func (b *buildFile) runContextCommand(args string, allowRemote bool, allowDecomp
 
 		origPath = path.Join(filepath.Base(tmpDirName), filepath.Base(tmpFileName))
 
-		// Process the checksum
-		r, err := archive.Tar(tmpFileName, archive.Uncompressed)
-		if err != nil {
-			return err
-		}
-		tarSum := &tarsum.TarSum{Reader: r, DisableCompression: true}
-		if _, err := io.Copy(ioutil.Discard, tarSum); err != nil {
-			return err
-		}
-		remoteHash = tarSum.Sum(nil)
-		r.Close()
-
 		// If the destination is a directory, figure out the filename.
 		if strings.HasSuffix(dest, "/") {
 			u, err := url.Parse(orig)
@@ -672,50 +869,31 @@ func (b *buildFile) runContextCommand(args string, allowRemote bool, allowDecomp
 		}
 	}
 
-	if err := b.checkPathForAddition(origPath); err != nil {
+	if err := b.checkPathForAddition(root, origPath); err != nil {
 		return err
 	}
 
-	// Hash path and check the cache
+	// Hash the content of the files added and check the cache
 	if b.utilizeCache {
-		var (
-			hash string
-			sums = b.context.GetSums()
-		)
-
-		if remoteHash != "" {
-			hash = remoteHash
-		} else if fi, err := os.Stat(path.Join(b.contextPath, origPath)); err != nil {
+		absOrigPath := path.Join(root, origPath)
+		fi, err := os.Stat(absOrigPath)
+		if err != nil {
 			return err
-		} else if fi.IsDir() {
-			var subfiles []string
-			for file, sum := range sums {
-				absFile := path.Join(b.contextPath, file)
-				absOrigPath := path.Join(b.contextPath, origPath)
-				if strings.HasPrefix(absFile, absOrigPath) {
-					subfiles = append(subfiles, sum)
-				}
-			}
-			sort.Strings(subfiles)
-			hasher := sha256.New()
-			hasher.Write([]byte(strings.Join(subfiles, ",")))
-			hash = "dir:" + hex.EncodeToString(hasher.Sum(nil))
-		} else {
-			if origPath[0] == '/' && len(origPath) > 1 {
-				origPath = origPath[1:]
-			}
-			origPath = strings.TrimPrefix(origPath, "./")
-			if h, ok := sums[origPath]; ok {
-				hash = "file:" + h
-			}
 		}
-		b.config.Cmd = []string{"/bin/sh", "-c", fmt.Sprintf("#(nop) %s %s in %s", cmdName, hash, dest)}
+		sum, err := contentHash(absOrigPath)
+		if err != nil {
+			return err
+		}
+		hash := "file:" + sum
+		if fi.IsDir() {
+			hash = "dir:" + sum
+		}
+		b.config.Cmd = []string{"/bin/sh", "-c", fmt.Sprintf("#(nop) %s %s in %s", instruction, hash, dest)}
 		hit, err := b.probeCache()
 		if err != nil {
 			return err
 		}
-		// If we do not have a hash, never use the cache
-		if hit && hash != "" {
+		if hit {
 			return nil
 		}
 	}
@@ -735,11 +913,21 @@ func (b *buildFile) runContextCommand(args string, allowRemote bool, allowDecomp
 	if !allowDecompression || isRemote {
 		decompress = false
 	}
-	if err := b.addContext(container, origPath, destPath, decompress); err != nil {
+	uid, gid, err := chownIds(container.RootfsPath(), chown)
+	if err != nil {
+		return err
+	}
+	if uid, err = idtools.ToHost(uid, b.daemon.uidMaps); err != nil {
+		return err
+	}
+	if gid, err = idtools.ToHost(gid, b.daemon.gidMaps); err != nil {
+		return err
+	}
+	if err := b.addContext(container, root, origPath, destPath, decompress, uid, gid); err != nil {
 		return err
 	}
 
-	if err := b.commit(container.ID, cmd, fmt.Sprintf("%s %s in %s", cmdName, orig, dest)); err != nil {
+	if err := b.commit(container.ID, cmd, fmt.Sprintf("%s %s in %s", instruction, orig, dest)); err != nil {
 		return err
 	}
 	return nil
@@ -860,8 +1048,6 @@ func (b *buildFile) commit(id string, autoCmd []string, comment string) error {
 }
 
 // Long lines can be split with a backslash
-var lineContinuation = regexp.MustCompile(`\\\s*\n`)
-
 func (b *buildFile) Build(context io.Reader) (string, error) {
 	tmpdirPath, err := ioutil.TempDir("", "docker-build")
 	if err != nil {
@@ -880,9 +1066,21 @@ func (b *buildFile) Build(context io.Reader) (string, error) {
 	defer os.RemoveAll(tmpdirPath)
 
 	b.contextPath = tmpdirPath
-	filename := path.Join(tmpdirPath, "Dockerfile")
-	if _, err := os.Stat(filename); os.IsNotExist(err) {
+	defer b.releaseContexts()
+	if err := b.prepareContexts(); err != nil {
+		return "", err
+	}
+	filename, err := dockerfilePath(tmpdirPath, b.dockerfileName)
+	if err != nil {
+		return "", err
+	}
+	if fi, err := os.Stat(filename); os.IsNotExist(err) {
+		if b.dockerfileName != "" {
+			return "", fmt.Errorf("Cannot locate the Dockerfile %s in the build context", b.dockerfileName)
+		}
 		return "", fmt.Errorf("Can't build a directory with no Dockerfile")
+	} else if err == nil && fi.IsDir() {
+		return "", fmt.Errorf("The Dockerfile %s is a directory", b.dockerfileName)
 	}
 	fileBytes, err := ioutil.ReadFile(filename)
 	if err != nil {
@@ -891,16 +1089,18 @@ func (b *buildFile) Build(context io.Reader) (string, error) {
 	if len(fileBytes) == 0 {
 		return "", ErrDockerfileEmpty
 	}
-	var (
-		dockerfile = lineContinuation.ReplaceAllString(stripComments(fileBytes), "")
-		stepN      = 0
-	)
-	for _, line := range strings.Split(dockerfile, "\n") {
-		line = strings.Trim(strings.Replace(line, "\t", " ", -1), " \t\r\n")
-		if len(line) == 0 {
+	lines, err := parseDockerfile(fileBytes)
+	if err != nil {
+		return "", err
+	}
+	stepN := 0
+	for _, line := range lines {
+		if instruction := strings.SplitN(line.text, " ", 2)[0]; !b.isInstruction(instruction) {
+			fmt.Fprintf(b.errStream, "# Skipping unknown instruction %s at line %d\n", strings.ToUpper(instruction), line.number)
+			stepN += 1
 			continue
 		}
-		if err := b.BuildStep(fmt.Sprintf("%d", stepN), line); err != nil {
+		if err := b.BuildStep(fmt.Sprintf("%d", stepN), line.text); err != nil {
 			if b.forceRm {
 				b.clearTmp(b.tmpContainers)
 			}
@@ -910,6 +1110,24 @@ func (b *buildFile) Build(context io.Reader) (string, error) {
 		}
 		stepN += 1
 	}
+	var unused []string
+	for key := range b.buildArgs {
+		if _, exists := b.declaredArgs[key]; !exists {
+			unused = append(unused, key)
+		}
+	}
+	if len(unused) > 0 {
+		sort.Strings(unused)
+		fmt.Fprintf(b.errStream, "# Build args %s were not declared with ARG and were ignored\n", strings.Join(unused, ", "))
+	}
+	if b.squash && b.image != "" && b.image != b.fromImage {
+		img, err := b.daemon.Graph().Squash(b.image, b.fromImage)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(b.outStream, "Squashed the layers on top of %s into %s\n", utils.TruncateID(b.fromImage), utils.TruncateID(img.ID))
+		b.image = img.ID
+	}
 	if b.image != "" {
 		fmt.Fprintf(b.outStream, "Successfully built %s\n", utils.TruncateID(b.image))
 		return b.image, nil
@@ -927,36 +1145,81 @@ func (b *buildFile) BuildStep(name, expression string) error {
 	instruction := strings.ToLower(strings.Trim(tmp[0], " "))
 	arguments := strings.Trim(tmp[1], " ")
 
-	method, exists := reflect.TypeOf(b).MethodByName("Cmd" + strings.ToUpper(instruction[:1]) + strings.ToLower(instruction[1:]))
+	method, exists := b.instructionMethod(instruction)
 	if !exists {
 		fmt.Fprintf(b.errStream, "# Skipping unknown instruction %s\n", strings.ToUpper(instruction))
 		return nil
 	}
 
+	start := time.Now()
+	b.cacheHit = false
 	ret := method.Func.Call([]reflect.Value{reflect.ValueOf(b), reflect.ValueOf(arguments)})[0].Interface()
 	if ret != nil {
 		return ret.(error)
 	}
 
 	fmt.Fprintf(b.outStream, " ---> %s\n", utils.TruncateID(b.image))
+	// a record of the step for the clients reading the json stream
+	if record := b.sf.FormatBuildStep(&utils.JSONBuildStep{
+		Step:        name,
+		Instruction: strings.ToUpper(instruction),
+		Cached:      b.cacheHit,
+		ImageID:     b.image,
+		Duration:    time.Since(start),
+	}); record != nil {
+		if _, err := b.outOld.Write(record); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func stripComments(raw []byte) string {
-	var (
-		out   []string
-		lines = strings.Split(string(raw), "\n")
-	)
-	for _, l := range lines {
-		if len(l) == 0 || l[0] == '#' {
-			continue
-		}
-		out = append(out, l)
+// dockerfilePath returns the path of the Dockerfile name, Dockerfile by
+// default, in the build context, which it must not leave even through
+// symlinks.
+func dockerfilePath(contextPath, name string) (string, error) {
+	if name == "" {
+		name = "Dockerfile"
+	}
+	filename := filepath.Join(contextPath, name)
+	if !strings.HasPrefix(filename, contextPath+string(filepath.Separator)) {
+		return "", fmt.Errorf("The Dockerfile %s must be within the build context", name)
+	}
+	return symlink.FollowSymlinkInScope(filename, contextPath)
+}
+
+// instructionMethod returns the method running instruction, as CmdFrom for
+// FROM, if any.
+func (b *buildFile) instructionMethod(instruction string) (reflect.Method, bool) {
+	if instruction == "" {
+		return reflect.Method{}, false
+	}
+	return reflect.TypeOf(b).MethodByName("Cmd" + strings.ToUpper(instruction[:1]) + strings.ToLower(instruction[1:]))
+}
+
+func (b *buildFile) isInstruction(instruction string) bool {
+	_, exists := b.instructionMethod(instruction)
+	return exists
+}
+
+// untarPath unpacks the archive at src into dst, with the owners of its
+// files mapped to the IDs of the host when the root of the containers is
+// remapped.
+func (b *buildFile) untarPath(src, dst string) error {
+	if b.daemon.uidMaps == nil {
+		return archive.UntarPath(src, dst)
+	}
+	f, err := os.Open(src)
+	if err != nil {
+		return err
 	}
-	return strings.Join(out, "\n")
+	defer f.Close()
+	layer := archive.ToHostIDs(f, b.daemon.uidMaps, b.daemon.gidMaps)
+	defer layer.Close()
+	return archive.Untar(layer, dst, nil)
 }
 
-func copyAsDirectory(source, destination string, destinationExists bool) error {
+func copyAsDirectory(source, destination string, destinationExists bool, uid, gid int) error {
 	if err := archive.CopyWithTar(source, destination); err != nil {
 		return err
 	}
@@ -968,14 +1231,63 @@ func copyAsDirectory(source, destination string, destinationExists bool) error {
 		}
 
 		for _, file := range files {
-			if err := fixPermissions(filepath.Join(destination, file.Name()), 0, 0); err != nil {
+			if err := fixPermissions(filepath.Join(destination, file.Name()), uid, gid); err != nil {
 				return err
 			}
 		}
 		return nil
 	}
 
-	return fixPermissions(destination, 0, 0)
+	return fixPermissions(destination, uid, gid)
+}
+
+// chownIds returns the uid and gid of the owner given to the --chown flag
+// of ADD and COPY, user[:group] with names looked up in the /etc/passwd and
+// /etc/group files of the rootfs. Without a group the gid is the uid, and
+// without an owner the files belong to root.
+func chownIds(rootfs, chown string) (int, int, error) {
+	if chown == "" {
+		return 0, 0, nil
+	}
+	parts := strings.SplitN(chown, ":", 2)
+	uid, err := lookupId(rootfs, "/etc/passwd", parts[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(parts) == 1 {
+		return uid, uid, nil
+	}
+	gid, err := lookupId(rootfs, "/etc/group", parts[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	return uid, gid, nil
+}
+
+// lookupId returns the numeric id of name, which is either already numeric
+// or the first field of a line of file, in the passwd or group format.
+func lookupId(rootfs, file, name string) (int, error) {
+	if id, err := strconv.Atoi(name); err == nil {
+		if id < 0 {
+			return 0, fmt.Errorf("Invalid id %s", name)
+		}
+		return id, nil
+	}
+	filePath, err := symlink.FollowSymlinkInScope(path.Join(rootfs, file), rootfs)
+	if err != nil {
+		return 0, err
+	}
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Split(line, ":")
+		if len(fields) >= 3 && fields[0] == name {
+			return strconv.Atoi(fields[2])
+		}
+	}
+	return 0, fmt.Errorf("Unable to find %s in %s", name, file)
 }
 
 func fixPermissions(destination string, uid, gid int) error {
@@ -987,22 +1299,27 @@ func fixPermissions(destination string, uid, gid int) error {
 	})
 }
 
-func NewBuildFile(d *Daemon, eng *engine.Engine, outStream, errStream io.Writer, verbose, utilizeCache, rm bool, forceRm bool, outOld io.Writer, sf *utils.StreamFormatter, auth *registry.AuthConfig, authConfigFile *registry.ConfigFile) BuildFile {
+func NewBuildFile(d *Daemon, eng *engine.Engine, outStream, errStream io.Writer, verbose, utilizeCache, rm bool, forceRm, squash bool, outOld io.Writer, sf *utils.StreamFormatter, auth *registry.AuthConfig, authConfigFile *registry.ConfigFile, buildArgs, buildContexts map[string]string, dockerfileName string) BuildFile {
 	return &buildFile{
-		daemon:        d,
-		eng:           eng,
-		config:        &runconfig.Config{},
-		outStream:     outStream,
-		errStream:     errStream,
-		tmpContainers: make(map[string]struct{}),
-		tmpImages:     make(map[string]struct{}),
-		verbose:       verbose,
-		utilizeCache:  utilizeCache,
-		rm:            rm,
-		forceRm:       forceRm,
-		sf:            sf,
-		authConfig:    auth,
-		configFile:    authConfigFile,
-		outOld:        outOld,
+		daemon:         d,
+		eng:            eng,
+		config:         &runconfig.Config{},
+		outStream:      outStream,
+		errStream:      errStream,
+		tmpContainers:  make(map[string]struct{}),
+		tmpImages:      make(map[string]struct{}),
+		verbose:        verbose,
+		utilizeCache:   utilizeCache,
+		rm:             rm,
+		forceRm:        forceRm,
+		squash:         squash,
+		sf:             sf,
+		authConfig:     auth,
+		configFile:     authConfigFile,
+		outOld:         outOld,
+		buildArgs:      buildArgs,
+		contextSources: buildContexts,
+		declaredArgs:   make(map[string]struct{}),
+		dockerfileName: dockerfileName,
 	}
 }
@@ -0,0 +1,136 @@
+package daemon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api"
+	"github.com/docker/docker/archive"
+	"github.com/docker/docker/utils"
+)
+
+// prepareContexts makes the directories of the additional contexts of the
+// build, which COPY --from=NAME reads: the ones the client sent under
+// api.BUILDCONTEXTSDIR of the build context, which are moved out of it, and
+// the ones of b.contextSources, which are images, git repositories or URLs
+// of tarballs. releaseContexts must be called once the build is done.
+func (b *buildFile) prepareContexts() error {
+	b.contexts = make(map[string]string)
+
+	sent := path.Join(b.contextPath, api.BUILDCONTEXTSDIR)
+	if fi, err := os.Lstat(sent); err == nil && fi.IsDir() {
+		tmp, err := ioutil.TempDir("", "docker-build-contexts")
+		if err != nil {
+			return err
+		}
+		b.contextReleases = append(b.contextReleases, func() { os.RemoveAll(tmp) })
+		dir := path.Join(tmp, "contexts")
+		if err := os.Rename(sent, dir); err != nil {
+			return err
+		}
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, fi := range infos {
+			if fi.IsDir() {
+				b.contexts[fi.Name()] = path.Join(dir, fi.Name())
+			}
+		}
+	}
+
+	names := make([]string, 0, len(b.contextSources))
+	for name := range b.contextSources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		source := b.contextSources[name]
+		if _, exists := b.contexts[name]; exists {
+			return fmt.Errorf("The build context %s is given more than once", name)
+		}
+		var (
+			dir string
+			err error
+		)
+		switch {
+		case strings.HasPrefix(source, utils.BuildContextImagePrefix):
+			dir, err = b.imageContext(strings.TrimPrefix(source, utils.BuildContextImagePrefix))
+		case utils.IsGIT(source):
+			dir, err = b.gitContext(source)
+		case utils.IsURL(source):
+			dir, err = b.tarballContext(source)
+		default:
+			err = fmt.Errorf("Invalid source of the build context %s: %s", name, source)
+		}
+		if err != nil {
+			return err
+		}
+		b.contexts[name] = dir
+	}
+	return nil
+}
+
+// releaseContexts unmounts the images and removes the directories of the
+// additional contexts.
+func (b *buildFile) releaseContexts() {
+	for i := len(b.contextReleases) - 1; i >= 0; i-- {
+		b.contextReleases[i]()
+	}
+	b.contextReleases = nil
+	b.contexts = nil
+}
+
+// imageContext mounts the filesystem of the image name, pulling it if
+// needed.
+func (b *buildFile) imageContext(name string) (string, error) {
+	img, err := b.pullImage(name)
+	if err != nil {
+		return "", err
+	}
+	dir, err := b.daemon.driver.Get(img.ID, "")
+	if err != nil {
+		return "", fmt.Errorf("Error mounting the image %s: %s", name, err)
+	}
+	b.contextReleases = append(b.contextReleases, func() { b.daemon.driver.Put(img.ID) })
+	return dir, nil
+}
+
+func (b *buildFile) gitContext(remoteURL string) (string, error) {
+	if !strings.HasPrefix(remoteURL, "git://") && !strings.HasPrefix(remoteURL, "git@") && !utils.IsURL(remoteURL) {
+		remoteURL = "https://" + remoteURL
+	}
+	root, err := ioutil.TempDir("", "docker-build-git")
+	if err != nil {
+		return "", err
+	}
+	b.contextReleases = append(b.contextReleases, func() { os.RemoveAll(root) })
+	if output, err := exec.Command("git", "clone", "--recursive", remoteURL, root).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("Error trying to use git: %s (%s)", err, output)
+	}
+	return root, nil
+}
+
+// tarballContext downloads and extracts the tarball, possibly compressed,
+// at rawurl.
+func (b *buildFile) tarballContext(rawurl string) (string, error) {
+	resp, err := utils.Download(rawurl)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	root, err := ioutil.TempDir("", "docker-build-tarball")
+	if err != nil {
+		return "", err
+	}
+	b.contextReleases = append(b.contextReleases, func() { os.RemoveAll(root) })
+	if err := archive.Untar(resp.Body, root, nil); err != nil {
+		return "", fmt.Errorf("Error extracting the build context %s: %s", rawurl, err)
+	}
+	return root, nil
+}
@@ -0,0 +1,291 @@
+package daemon
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api"
+	"github.com/docker/docker/archive"
+	"github.com/docker/docker/runconfig"
+)
+
+func TestContextLimiter(t *testing.T) {
+	context := ioutil.NopCloser(bytes.NewReader(make([]byte, 2048)))
+	if _, err := ioutil.ReadAll(&contextLimiter{ReadCloser: context, max: 2048}); err != nil {
+		t.Fatalf("Expected a context of the size of the limit to be accepted: %s", err)
+	}
+
+	context = ioutil.NopCloser(bytes.NewReader(make([]byte, 2049)))
+	if _, err := ioutil.ReadAll(&contextLimiter{ReadCloser: context, max: 2048}); err == nil {
+		t.Fatal("Expected an error for a context larger than the limit")
+	}
+}
+
+func TestDeclareArg(t *testing.T) {
+	b := &buildFile{
+		config:       &runconfig.Config{Env: []string{"PATH=/bin", "HOME=/root"}},
+		buildArgs:    map[string]string{"VERSION": "1.2", "HOME": "/home"},
+		declaredArgs: make(map[string]struct{}),
+	}
+	for _, args := range []string{"VERSION=1.0", "DIR=$HOME/src", "HOME", "UNSET"} {
+		if _, err := b.declareArg(args); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := b.declareArg("A B"); err == nil {
+		t.Fatal("Expected an error for an invalid ARG")
+	}
+
+	// the image environment takes precedence over the build args
+	expected := []string{"VERSION=1.2", "DIR=/root/src", "HOME=/root", "PATH=/bin"}
+	if env := b.buildEnv(); !reflect.DeepEqual(env, expected) {
+		t.Fatalf("Expected the build environment %v, got %v", expected, env)
+	}
+	value, err := b.ReplaceEnvMatches("/opt/$VERSION/${DIR}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "/opt/1.2//root/src" {
+		t.Fatalf("Expected the build args to be substituted, got %s", value)
+	}
+	if len(b.config.Env) != 2 {
+		t.Fatalf("Expected the build args not to be added to the config, got %v", b.config.Env)
+	}
+}
+
+func TestChownIds(t *testing.T) {
+	rootfs, err := ioutil.TempDir("", "docker-test-chown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(rootfs)
+	if err := os.MkdirAll(path.Join(rootfs, "etc"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path.Join(rootfs, "etc", "passwd"), []byte("root:x:0:0:root:/root:/bin/sh\napp:x:1000:1000::/home/app:/bin/sh\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path.Join(rootfs, "etc", "group"), []byte("root:x:0:\nstaff:x:50:app\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for chown, expected := range map[string][2]int{
+		"":          {0, 0},
+		"app":       {1000, 1000},
+		"app:staff": {1000, 50},
+		"10:20":     {10, 20},
+		"app:30":    {1000, 30},
+	} {
+		uid, gid, err := chownIds(rootfs, chown)
+		if err != nil {
+			t.Fatalf("%s: %s", chown, err)
+		}
+		if uid != expected[0] || gid != expected[1] {
+			t.Fatalf("Expected %s to be %d:%d, got %d:%d", chown, expected[0], expected[1], uid, gid)
+		}
+	}
+	for _, chown := range []string{"nobody", "app:nogroup", "-1"} {
+		if _, _, err := chownIds(rootfs, chown); err == nil {
+			t.Fatalf("Expected an error for %s", chown)
+		}
+	}
+}
+
+func TestDockerfilePath(t *testing.T) {
+	context, err := ioutil.TempDir("", "docker-test-dockerfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(context)
+	if err := os.Symlink("/etc/passwd", path.Join(context, "escape")); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, expected := range map[string]string{
+		"":                    "Dockerfile",
+		"Dockerfile.dev":      "Dockerfile.dev",
+		"dev/Dockerfile":      "dev/Dockerfile",
+		"./dev/../Dockerfile": "Dockerfile",
+		"escape":              "etc/passwd",
+	} {
+		filename, err := dockerfilePath(context, name)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if filename != path.Join(context, expected) {
+			t.Fatalf("Expected %s to be %s in the context, got %s", name, expected, filename)
+		}
+	}
+	for _, name := range []string{"../Dockerfile", "dev/../../Dockerfile", "."} {
+		if _, err := dockerfilePath(context, name); err == nil {
+			t.Fatalf("Expected an error for %s outside of the context", name)
+		}
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	root, err := ioutil.TempDir("", "docker-test-content-hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	write := func(name, content string, mode os.FileMode) {
+		p := path.Join(root, name)
+		if err := os.MkdirAll(path.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(p, mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hash := func(name string) string {
+		sum, err := contentHash(path.Join(root, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sum
+	}
+
+	write("a/file", "hello", 0644)
+	write("b/file", "hello", 0644)
+	if hash("a") != hash("b") || hash("a/file") != hash("b/file") {
+		t.Fatal("Expected the same files to have the same checksum")
+	}
+
+	// the modification time is not part of the checksum
+	if err := os.Chtimes(path.Join(root, "b/file"), time.Unix(0, 0), time.Unix(0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if hash("a") != hash("b") {
+		t.Fatal("Expected the checksum not to depend on the modification time")
+	}
+
+	previous := hash("b")
+	write("b/file", "hello", 0755)
+	if hash("b") == previous {
+		t.Fatal("Expected the checksum to change with the mode")
+	}
+	previous = hash("b")
+	write("b/file", "world", 0755)
+	if hash("b") == previous {
+		t.Fatal("Expected the checksum to change with the content")
+	}
+	previous = hash("b")
+	if err := os.Rename(path.Join(root, "b/file"), path.Join(root, "b/other")); err != nil {
+		t.Fatal(err)
+	}
+	if hash("b") == previous {
+		t.Fatal("Expected the checksum to change with the file names")
+	}
+}
+
+func TestPrepareContexts(t *testing.T) {
+	contextPath, err := ioutil.TempDir("", "docker-test-context")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(contextPath)
+	if err := os.MkdirAll(path.Join(contextPath, api.BUILDCONTEXTSDIR, "lib"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path.Join(contextPath, api.BUILDCONTEXTSDIR, "lib", "a"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := ioutil.TempDir("", "docker-test-tarball")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(src)
+	if err := ioutil.WriteFile(path.Join(src, "b"), []byte("b"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tarball, err := archive.Tar(src, archive.Gzip)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer tarball.Close()
+		io.Copy(w, tarball)
+	}))
+	defer server.Close()
+
+	b := &buildFile{
+		contextPath:    contextPath,
+		contextSources: map[string]string{"src": server.URL + "/src.tar.gz"},
+	}
+	if err := b.prepareContexts(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path.Join(contextPath, api.BUILDCONTEXTSDIR)); !os.IsNotExist(err) {
+		t.Fatalf("Expected the contexts to be moved out of the build context, got %v", err)
+	}
+	for name, file := range map[string]string{"lib": "a", "src": "b"} {
+		data, err := ioutil.ReadFile(path.Join(b.contexts[name], file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != file {
+			t.Fatalf("Expected %q in the context %s, got %q", file, name, data)
+		}
+	}
+	dirs := []string{b.contexts["lib"], b.contexts["src"]}
+	b.releaseContexts()
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			t.Fatalf("Expected %s to be removed, got %v", dir, err)
+		}
+	}
+
+	for _, sources := range []map[string]string{
+		{"lib": "docker-image://busybox"},
+		{"x": "../lib"},
+	} {
+		if err := os.MkdirAll(path.Join(contextPath, api.BUILDCONTEXTSDIR, "lib"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		b := &buildFile{contextPath: contextPath, contextSources: sources}
+		if err := b.prepareContexts(); err == nil {
+			t.Fatalf("Expected an error for %v", sources)
+		}
+		b.releaseContexts()
+	}
+}
+
+func TestParseDockerfile(t *testing.T) {
+	dockerfile := "\xef\xbb\xbf# comment\r\nFROM busybox \r\n\r\n\tRUN echo a \\\r\n  # skipped\r\n  && echo\tb  \r\nENV A 1\\\r\n"
+	lines, err := parseDockerfile([]byte(dockerfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []dockerfileLine{
+		{number: 2, text: "FROM busybox"},
+		{number: 4, text: "RUN echo a   && echo b"},
+		{number: 7, text: "ENV A 1"},
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("Expected %v, got %v", expected, lines)
+	}
+
+	for dockerfile, expected := range map[string]string{
+		"FROM busybox\n  R\xc3\xa9N true\n": "Dockerfile line 2, column 4: Invalid character 'é' in the instruction \"RéN\"",
+		"FROM busybox\r\nRUN \r\n":          "Dockerfile line 2, column 4: The instruction RUN has no arguments",
+		"FROM\xef\xbb\xbf busybox\n":        "Dockerfile line 1, column 5: Invalid character '\\ufeff' in the instruction \"FROM\\ufeff\"",
+	} {
+		if _, err := parseDockerfile([]byte(dockerfile)); err == nil || err.Error() != expected {
+			t.Fatalf("Expected %q for %q, got %v", expected, dockerfile, err)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package daemon
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/pkg/parsers/kernel"
+	"github.com/docker/libcontainer/cgroups"
+)
+
+var (
+	// the kernel options without which containers cannot run
+	requiredKernelConfig = []string{
+		"NAMESPACES", "NET_NS", "PID_NS", "IPC_NS", "UTS_NS",
+		"DEVPTS_MULTIPLE_INSTANCES",
+		"CGROUPS", "CGROUP_CPUACCT", "CGROUP_DEVICE", "CGROUP_FREEZER", "CGROUP_SCHED",
+		"MACVLAN", "VETH", "BRIDGE",
+		"NF_NAT_IPV4", "IP_NF_TARGET_MASQUERADE",
+		"NETFILTER_XT_MATCH_ADDRTYPE", "NETFILTER_XT_MATCH_CONNTRACK",
+		"NF_NAT", "NF_NAT_NEEDED",
+	}
+	// the kernel options of the resource limits and of the storage drivers
+	optionalKernelConfig = []string{
+		"MEMCG_SWAP", "RESOURCE_COUNTERS",
+		"AUFS_FS", "OVERLAY_FS", "BTRFS_FS",
+		"BLK_DEV_DM", "DM_THIN_PROVISIONING", "EXT4_FS",
+	}
+	// the cgroup subsystems which must be mounted, memory being optional
+	requiredCgroups = []string{"cpu", "cpuacct", "devices", "freezer"}
+)
+
+// kernelCheck is the state of a kernel feature used by docker.
+type kernelCheck struct {
+	Name     string
+	Required bool
+	Status   string // enabled, module or missing
+}
+
+// CmdCheckConfig reports the kernel options and the cgroup subsystems
+// needed by the containers, read from the configuration of the running
+// kernel, as "KernelConfig", its path, and "Checks".
+func (daemon *Daemon) CmdCheckConfig(job *engine.Job) engine.Status {
+	var release string
+	if kv, err := kernel.GetKernelVersion(); err == nil {
+		release = kv.String()
+	}
+	configPath, config, err := readKernelConfig(release)
+	if err != nil {
+		return job.Error(err)
+	}
+	filesystems, _ := ioutil.ReadFile("/proc/filesystems")
+
+	checks := checkKernelConfig(config, string(filesystems))
+	for _, subsystem := range requiredCgroups {
+		status := "enabled"
+		if _, err := cgroups.FindCgroupMountpoint(subsystem); err != nil {
+			status = "missing"
+		}
+		checks = append(checks, kernelCheck{Name: "cgroup " + subsystem, Required: true, Status: status})
+	}
+
+	v := &engine.Env{}
+	v.Set("KernelConfig", configPath)
+	v.SetJson("Checks", checks)
+	if _, err := v.WriteTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+// readKernelConfig looks for the configuration of the kernel release where
+// the distributions usually install it and returns its path and options.
+func readKernelConfig(release string) (string, map[string]string, error) {
+	paths := []string{
+		"/proc/config.gz",
+		"/boot/config-" + release,
+		"/usr/src/linux-" + release + "/.config",
+		"/usr/src/linux/.config",
+	}
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		defer f.Close()
+		var r io.Reader = f
+		if strings.HasSuffix(path, ".gz") {
+			gz, err := gzip.NewReader(f)
+			if err != nil {
+				return "", nil, err
+			}
+			r = gz
+		}
+		config, err := parseKernelConfig(r)
+		if err != nil {
+			return "", nil, err
+		}
+		return path, config, nil
+	}
+	return "", nil, fmt.Errorf("Cannot find the kernel config in %s", strings.Join(paths, ", "))
+}
+
+// parseKernelConfig returns the options of a kernel .config set to y or m,
+// without their CONFIG_ prefix.
+func parseKernelConfig(r io.Reader) (map[string]string, error) {
+	config := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "CONFIG_") {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimPrefix(line, "CONFIG_"), "=", 2)
+		if len(parts) == 2 && (parts[1] == "y" || parts[1] == "m") {
+			config[parts[0]] = parts[1]
+		}
+	}
+	return config, scanner.Err()
+}
+
+// checkKernelConfig checks the required and optional options against
+// config. The filesystems of /proc/filesystems tell about the aufs and
+// overlay patches built without their option.
+func checkKernelConfig(config map[string]string, filesystems string) []kernelCheck {
+	var checks []kernelCheck
+	check := func(option string, required bool) {
+		status := "missing"
+		switch config[option] {
+		case "y":
+			status = "enabled"
+		case "m":
+			status = "module"
+		default:
+			if (option == "AUFS_FS" && hasFilesystem(filesystems, "aufs")) ||
+				(option == "OVERLAY_FS" && hasFilesystem(filesystems, "overlay")) {
+				status = "enabled"
+			}
+		}
+		checks = append(checks, kernelCheck{Name: "CONFIG_" + option, Required: required, Status: status})
+	}
+	for _, option := range requiredKernelConfig {
+		check(option, true)
+	}
+	for _, option := range optionalKernelConfig {
+		check(option, false)
+	}
+	return checks
+}
+
+func hasFilesystem(filesystems, name string) bool {
+	for _, line := range strings.Split(filesystems, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && fields[len(fields)-1] == name {
+			return true
+		}
+	}
+	return false
+}
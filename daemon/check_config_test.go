@@ -0,0 +1,42 @@
+package daemon
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckKernelConfig(t *testing.T) {
+	config, err := parseKernelConfig(strings.NewReader(`#
+# Automatically generated file; DO NOT EDIT.
+CONFIG_NAMESPACES=y
+CONFIG_NET_NS=y
+CONFIG_VETH=m
+# CONFIG_BRIDGE is not set
+CONFIG_NF_NAT=n
+CONFIG_HZ=250
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config) != 3 || config["NAMESPACES"] != "y" || config["VETH"] != "m" {
+		t.Fatalf("Unexpected kernel config %v", config)
+	}
+
+	checks := checkKernelConfig(config, "nodev\tsysfs\nnodev\taufs\n\text4\n")
+	if len(checks) != len(requiredKernelConfig)+len(optionalKernelConfig) {
+		t.Fatalf("Expected a check per option, got %d", len(checks))
+	}
+	expected := map[string]kernelCheck{
+		"CONFIG_NAMESPACES": {"CONFIG_NAMESPACES", true, "enabled"},
+		"CONFIG_VETH":       {"CONFIG_VETH", true, "module"},
+		"CONFIG_BRIDGE":     {"CONFIG_BRIDGE", true, "missing"},
+		"CONFIG_NF_NAT":     {"CONFIG_NF_NAT", true, "missing"},
+		"CONFIG_AUFS_FS":    {"CONFIG_AUFS_FS", false, "enabled"},
+		"CONFIG_BTRFS_FS":   {"CONFIG_BTRFS_FS", false, "missing"},
+	}
+	for _, check := range checks {
+		if e, exists := expected[check.Name]; exists && check != e {
+			t.Fatalf("Expected %v, got %v", e, check)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package daemon
+
+import (
+	"encoding/json"
+
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/graph"
+	"github.com/docker/docker/pkg/parsers"
+	"github.com/docker/docker/runconfig"
+	"github.com/docker/docker/utils"
+)
+
+// ContainerClone creates a new container with the configuration and host
+// configuration of an existing one. "Image" replaces the image, "Tag" only
+// replaces the tag of the image, and the variables of "Env" are added to or
+// replace the ones of the source container.
+func (daemon *Daemon) ContainerClone(job *engine.Job) engine.Status {
+	if len(job.Args) < 1 || len(job.Args) > 2 {
+		return job.Errorf("Usage: %s CONTAINER [NAME]", job.Name)
+	}
+	if err := daemon.checkNotDraining(); err != nil {
+		return job.Error(err)
+	}
+	source := daemon.Get(job.Args[0])
+	if source == nil {
+		return job.Errorf("No such container: %s", job.Args[0])
+	}
+	var name string
+	if len(job.Args) == 2 {
+		name = job.Args[1]
+	}
+
+	config, err := cloneConfig(source, job.Getenv("Image"), job.Getenv("Tag"), job.GetenvList("Env"))
+	if err != nil {
+		return job.Error(err)
+	}
+	hostConfig := &runconfig.HostConfig{}
+	if err := deepCopy(source.hostConfig, hostConfig); err != nil {
+		return job.Error(err)
+	}
+	hostConfig.ContainerIDFile = ""
+
+	container, warnings, err := daemon.Create(config, name)
+	if err != nil {
+		if daemon.Graph().IsNotExist(err) {
+			_, tag := parsers.ParseRepositoryTag(config.Image)
+			if tag == "" {
+				tag = graph.DEFAULTTAG
+			}
+			return job.Errorf("No such image: %s (tag: %s)", config.Image, tag)
+		}
+		return job.Error(err)
+	}
+	if err := daemon.setHostConfig(container, hostConfig); err != nil {
+		return job.Error(err)
+	}
+	container.LogEvent("create")
+	job.Printf("%s\n", container.ID)
+	for _, warning := range warnings {
+		job.Errorf("%s\n", warning)
+	}
+	return engine.StatusOK
+}
+
+// cloneConfig returns a copy of the configuration of source with the
+// given overrides applied.
+func cloneConfig(source *Container, image, tag string, env []string) (*runconfig.Config, error) {
+	config := &runconfig.Config{}
+	if err := deepCopy(source.Config, config); err != nil {
+		return nil, err
+	}
+	// let the clone get its own hostname unless one was chosen
+	if config.Hostname == utils.TruncateID(source.ID) {
+		config.Hostname = ""
+	}
+	if image != "" {
+		config.Image = image
+	}
+	if tag != "" {
+		repo, _ := parsers.ParseRepositoryTag(config.Image)
+		config.Image = repo + ":" + tag
+	}
+	if len(env) > 0 {
+		config.Env = utils.ReplaceOrAppendEnvValues(config.Env, env)
+	}
+	return config, nil
+}
+
+func deepCopy(src, dst interface{}) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
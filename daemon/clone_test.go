@@ -0,0 +1,45 @@
+package daemon
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/runconfig"
+)
+
+func TestCloneConfig(t *testing.T) {
+	source := &Container{
+		ID: "4386fb97867d0bb1e1a8f36e6d1bd4ee0d2d4f6f1ec1c8f1a6b8c1b4cfd5c0a2",
+		Config: &runconfig.Config{
+			Hostname: "4386fb97867d",
+			Image:    "localhost:5000/web:1.0",
+			Env:      []string{"PATH=/bin", "MODE=prod"},
+		},
+	}
+
+	config, err := cloneConfig(source, "", "1.1", []string{"MODE=debug", "VERBOSE=1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Image != "localhost:5000/web:1.1" {
+		t.Fatalf("Expected the tag to be replaced, got %s", config.Image)
+	}
+	if config.Hostname != "" {
+		t.Fatalf("Expected the generated hostname to be reset, got %s", config.Hostname)
+	}
+	if expected := []string{"PATH=/bin", "MODE=debug", "VERBOSE=1"}; !reflect.DeepEqual(config.Env, expected) {
+		t.Fatalf("Expected env %v, got %v", expected, config.Env)
+	}
+	if source.Config.Env[1] != "MODE=prod" || source.Config.Image != "localhost:5000/web:1.0" {
+		t.Fatalf("The source configuration was modified: %v", source.Config)
+	}
+
+	source.Config.Hostname = "web"
+	config, err = cloneConfig(source, "busybox", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Image != "busybox" || config.Hostname != "web" {
+		t.Fatalf("Unexpected clone configuration %v", config)
+	}
+}
@@ -3,6 +3,7 @@ package daemon
 import (
 	"github.com/docker/docker/engine"
 	"github.com/docker/docker/image"
+	"github.com/docker/docker/pkg/log"
 	"github.com/docker/docker/runconfig"
 )
 
@@ -30,10 +31,33 @@ func (daemon *Daemon) ContainerCommit(job *engine.Job) engine.Status {
 		return job.Error(err)
 	}
 
-	img, err := daemon.Commit(container, job.Getenv("repo"), job.Getenv("tag"), job.Getenv("comment"), job.Getenv("author"), job.GetenvBool("pause"), &newConfig)
+	var (
+		repo   = job.Getenv("repo")
+		tag    = job.Getenv("tag")
+		squash = job.GetenvBool("squash")
+	)
+	if squash {
+		// the squashed image is tagged, not the committed one
+		repo, tag = "", ""
+	}
+	img, err := daemon.Commit(container, repo, tag, job.Getenv("comment"), job.Getenv("author"), job.GetenvBool("pause"), &newConfig)
 	if err != nil {
 		return job.Error(err)
 	}
+	if squash {
+		committed := img.ID
+		if img, err = daemon.graph.Squash(committed, ""); err != nil {
+			return job.Error(err)
+		}
+		if err := daemon.DeleteImage(job.Eng, committed, engine.NewTable("", 0), true, false, true); err != nil {
+			log.Errorf("Error removing the image %s squashed into %s: %s", committed, img.ID, err)
+		}
+		if repo = job.Getenv("repo"); repo != "" {
+			if err := daemon.repositories.Set(repo, job.Getenv("tag"), img.ID, true); err != nil {
+				return job.Error(err)
+			}
+		}
+	}
 	job.Printf("%s\n", img.ID)
 	return engine.StatusOK
 }
@@ -20,21 +20,48 @@ const (
 type Config struct {
 	Pidfile                     string
 	Root                        string
+	TmpDir                      string
 	AutoRestart                 bool
 	Dns                         []string
 	DnsSearch                   []string
+	EmbeddedDns                 bool
+	HostTimezone                bool
 	EnableIptables              bool
 	EnableIpForward             bool
+	EnableUserlandProxy         bool
 	DefaultIp                   net.IP
+	PublishedPortsRange         string
 	BridgeIface                 string
 	BridgeIP                    string
 	InterContainerCommunication bool
 	GraphDriver                 string
 	GraphOptions                []string
 	ExecDriver                  string
+	ExternalTar                 string
+	RemappedRoot                string
 	Mtu                         int
+	NetPoolSize                 int
 	DisableNetwork              bool
 	EnableSelinuxSupport        bool
+	RestartFlapCount            int
+	RestartFlapWindow           int
+	Hooks                       []string
+	LifecycleHooks              bool
+	DefaultUlimits              []string
+	CgroupParent                string
+	MaxBuildContext             int
+	MaxConcurrentDownloads      int
+	SelfCheckInterval           int
+	SelfCheckGoroutines         int
+	SelfCheckFds                int
+	SelfCheckHijacked           int
+	Mirrors                     []string
+	InsecureRegistries          []string
+	RegistryProxy               string
+	AllowedRegistries           []string
+	DeniedRegistries            []string
+	Offline                     bool
+	ImplicitTag                 string
 	Context                     map[string][]string
 }
 
@@ -45,21 +72,48 @@ type Config struct {
 func (config *Config) InstallFlags() {
 	flag.StringVar(&config.Pidfile, []string{"p", "-pidfile"}, "/var/run/docker.pid", "Path to use for daemon PID file")
 	flag.StringVar(&config.Root, []string{"g", "-graph"}, "/var/lib/docker", "Path to use as the root of the Docker runtime")
+	flag.StringVar(&config.TmpDir, []string{"-tmpdir"}, "", "Path to use for the temporary files of the builds, pushes, imports and exports\nif no value is provided: default to $DOCKER_TMPDIR or the tmp directory of the root")
 	flag.BoolVar(&config.AutoRestart, []string{"#r", "#-restart"}, true, "--restart on the daemon has been deprecated infavor of --restart policies on docker run")
 	flag.BoolVar(&config.EnableIptables, []string{"#iptables", "-iptables"}, true, "Enable Docker's addition of iptables rules")
 	flag.BoolVar(&config.EnableIpForward, []string{"#ip-forward", "-ip-forward"}, true, "Enable net.ipv4.ip_forward")
+	flag.BoolVar(&config.EnableUserlandProxy, []string{"-userland-proxy"}, true, "Run a docker-proxy process for each published port\nfalse forwards all the traffic to the published ports with iptables hairpin NAT")
 	flag.StringVar(&config.BridgeIP, []string{"#bip", "-bip"}, "", "Use this CIDR notation address for the network bridge's IP, not compatible with -b")
 	flag.StringVar(&config.BridgeIface, []string{"b", "-bridge"}, "", "Attach containers to a pre-existing network bridge\nuse 'none' to disable container networking")
 	flag.BoolVar(&config.InterContainerCommunication, []string{"#icc", "-icc"}, true, "Enable inter-container communication")
 	flag.StringVar(&config.GraphDriver, []string{"s", "-storage-driver"}, "", "Force the Docker runtime to use a specific storage driver")
 	flag.StringVar(&config.ExecDriver, []string{"e", "-exec-driver"}, "native", "Force the Docker runtime to use a specific exec driver")
+	flag.StringVar(&config.ExternalTar, []string{"-external-tar"}, "", "Path of a GNU tar binary to extract the layers without parent with, faster than the built-in tar for layers of many small files")
+	flag.StringVar(&config.RemappedRoot, []string{"-userns-remap"}, "", "Run the containers in a user namespace, with their root mapped to the subordinate IDs of this USER[:GROUP] in /etc/subuid and /etc/subgid\nthe images and containers of each mapping are kept in their own directory of the root")
 	flag.BoolVar(&config.EnableSelinuxSupport, []string{"-selinux-enabled"}, false, "Enable selinux support. SELinux does not presently support the BTRFS storage driver")
 	flag.IntVar(&config.Mtu, []string{"#mtu", "-mtu"}, 0, "Set the containers network MTU\nif no value is provided: default to the default route MTU or 1500 if no default route is available")
+	flag.IntVar(&config.NetPoolSize, []string{"-net-pool-size"}, 0, "Number of network namespaces, with their veth pair attached to the bridge, kept ready by the native driver to speed up the start of the containers\n0 disables the pool")
+	flag.IntVar(&config.RestartFlapCount, []string{"-restart-flap-count"}, 0, "Stop restarting a container once its restart policy restarted it this many times within --restart-flap-window\n0 disables flapping detection")
+	flag.IntVar(&config.RestartFlapWindow, []string{"-restart-flap-window"}, 10, "Number of minutes considered by --restart-flap-count")
+	flag.IntVar(&config.MaxBuildContext, []string{"-max-build-context"}, 0, "Reject the build contexts larger than this size in megabytes\n0 means no limit")
+	flag.IntVar(&config.SelfCheckInterval, []string{"-selfcheck-interval"}, 1, "Number of minutes between the checks of the goroutines, open fds and hijacked API connections of the daemon\n0 disables the checks")
+	flag.IntVar(&config.SelfCheckGoroutines, []string{"-selfcheck-goroutines"}, 10000, "Emit a warning event and dump the daemon state when it runs more goroutines than this\n0 means no limit")
+	flag.IntVar(&config.SelfCheckFds, []string{"-selfcheck-fds"}, 0, "Emit a warning event and dump the daemon state when it has more open fds than this\n0 means 80% of the open files limit of the daemon")
+	flag.IntVar(&config.SelfCheckHijacked, []string{"-selfcheck-hijacked"}, 1000, "Emit a warning event and dump the daemon state when more API connections than this are hijacked, e.g. by attach\n0 means no limit")
+	flag.IntVar(&config.MaxConcurrentDownloads, []string{"-max-concurrent-downloads"}, 3, "Maximum number of layers pulled at the same time by all the pulls\n0 means no limit")
+	flag.StringVar(&config.PublishedPortsRange, []string{"-published-ports-range"}, "", "Range of the host ports chosen for the ports published without a host port, as BEGIN-END\nif no value is provided: default to 49153-65535")
 	opts.IPVar(&config.DefaultIp, []string{"#ip", "-ip"}, "0.0.0.0", "Default IP address to use when binding container ports")
+	opts.MirrorListVar(&config.Mirrors, []string{"-registry-mirror"}, "Try this registry mirror, as scheme://host[:port], before the official index when pulling its images")
+	opts.ListVar(&config.InsecureRegistries, []string{"-insecure-registry"}, "Allow plain HTTP, or HTTPS without verifying the certificate, for this registry, as HOST[:PORT] or a CIDR network\nthe registries on the loopback network are always allowed")
+	flag.StringVar(&config.RegistryProxy, []string{"-registry-proxy"}, "", "Connect to the registries through this proxy, as http://host[:port], instead of the one of HTTP_PROXY, HTTPS_PROXY and NO_PROXY\n'none' connects to the registries directly")
+	opts.ListVar(&config.AllowedRegistries, []string{"-registry-allow"}, "Only pull from and push to the registries matching this glob pattern of a host or host:port (e.g. *.example.com)\nthe official index is index.docker.io")
+	opts.ListVar(&config.DeniedRegistries, []string{"-registry-deny"}, "Never pull from or push to the registries matching this glob pattern of a host or host:port, even when allowed by --registry-allow")
+	flag.BoolVar(&config.Offline, []string{"-offline"}, false, "Never reach the registries: the pulls, pushes, searches and logins fail right away, for the hosts without network access")
+	flag.StringVar(&config.ImplicitTag, []string{"-implicit-tag"}, "allow", "What to do with the images pulled, run or built from without a tag or digest, which mean their latest tag: allow, warn or deny\nthe IDs of the images are always allowed")
 	opts.ListVar(&config.GraphOptions, []string{"-storage-opt"}, "Set storage driver options")
+	flag.StringVar(&config.CgroupParent, []string{"-cgroup-parent"}, "", "Create the cgroups of the containers in this cgroup, relative to the one of the daemon, unless they are run with --cgroup-parent\nwith systemd: a slice (e.g. docker.slice)\nif no value is provided: default to docker")
+	opts.UlimitListVar(&config.DefaultUlimits, []string{"-default-ulimit"}, "Set the default ulimits of the containers, as NAME=SOFT[:HARD] (e.g. nofile=1024:2048)")
+	flag.BoolVar(&config.LifecycleHooks, []string{"-lifecycle-hooks"}, false, "Allow the containers to run with --pre-start and --post-stop hooks, which run shell commands as root on the host")
+	opts.ListVar(&config.Hooks, []string{"-hook"}, "Run a program on container events, as EVENT:PATH (events: start, die, oom)\nthe program receives a JSON description of the event on stdin")
 	// FIXME: why the inconsistency between "hosts" and "sockets"?
 	opts.IPListVar(&config.Dns, []string{"#dns", "-dns"}, "Force Docker to use specific DNS servers")
 	opts.DnsSearchListVar(&config.DnsSearch, []string{"-dns-search"}, "Force Docker to use specific DNS search domains")
+	flag.BoolVar(&config.HostTimezone, []string{"-host-timezone"}, false, "Give the containers the time zone of the host: its /etc/localtime is mounted read only and TZ is set to read it\na container setting TZ or mounting a volume on /etc/localtime keeps its own")
+	flag.BoolVar(&config.EmbeddedDns, []string{"-embedded-dns"}, false, "Resolve the link aliases of the containers of the default bridge to their current address, with a DNS server on the bridge\nthe other queries of the containers are forwarded to the servers of --dns or of the host")
 }
 
 func GetDefaultNetworkMtu() int {
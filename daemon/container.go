@@ -1,6 +1,7 @@
 package daemon
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -29,6 +30,7 @@ import (
 	"github.com/docker/docker/pkg/networkfs/etchosts"
 	"github.com/docker/docker/pkg/networkfs/resolvconf"
 	"github.com/docker/docker/pkg/symlink"
+	"github.com/docker/docker/pkg/ulimit"
 	"github.com/docker/docker/runconfig"
 	"github.com/docker/docker/utils"
 )
@@ -57,15 +59,26 @@ type Container struct {
 	Config *runconfig.Config
 	State  *State
 	Image  string
+	// ImageDigest pins the content of Image at the time of the creation
+	ImageDigest string
 
 	NetworkSettings *NetworkSettings
 
 	ResolvConfPath string
-	HostnamePath   string
-	HostsPath      string
-	Name           string
-	Driver         string
-	ExecDriver     string
+	// ResolvConfHash is the hash of the resolv.conf written by the daemon,
+	// which is only updated on a change of the one of the host while the
+	// container did not change it
+	ResolvConfHash string
+	// EmbeddedDns is set when the resolv.conf of the container points to
+	// the resolver of the daemon
+	EmbeddedDns  bool
+	HostnamePath string
+	HostsPath    string
+	// MetadataPath is the file telling the container about itself
+	MetadataPath string
+	Name         string
+	Driver       string
+	ExecDriver   string
 
 	command   *execdriver.Command
 	stdout    *broadcastwriter.BroadcastWriter
@@ -76,8 +89,13 @@ type Container struct {
 	daemon                   *Daemon
 	MountLabel, ProcessLabel string
 	RestartCount             int
+	// SeccompProfile is the name of the seccomp profile the container last
+	// started with: default, unconfined or custom
+	SeccompProfile string
 
 	Volumes map[string]string
+	// Volumes provided by hostConfig.VolumeDriver, by path in the container
+	NamedVolumes map[string]string
 	// Store rw/ro in a separate structure to preserve reverse-compatibility on-disk.
 	// Easier than migrating older container configs :)
 	VolumesRW  map[string]bool
@@ -176,6 +194,7 @@ func (container *Container) LogEvent(action string) {
 	if err := d.eng.Job("log", action, container.ID, d.Repositories().ImageName(container.Image)).Run(); err != nil {
 		log.Errorf("Error logging event %s for %s: %s", action, container.ID, err)
 	}
+	d.runHooks(container, action)
 }
 
 func (container *Container) getResourcePath(path string) (string, error) {
@@ -214,6 +233,7 @@ func populateCommand(c *Container, env []string) error {
 				Bridge:      network.Bridge,
 				IPAddress:   network.IPAddress,
 				IPPrefixLen: network.IPPrefixLen,
+				HairpinMode: !c.daemon.config.EnableUserlandProxy,
 			}
 		}
 	case "container":
@@ -226,28 +246,55 @@ func populateCommand(c *Container, env []string) error {
 		return fmt.Errorf("invalid network mode: %s", c.hostConfig.NetworkMode)
 	}
 
+	pidContainer, err := c.getNamespaceContainer("PID", c.hostConfig.PidMode.Container())
+	if err != nil {
+		return err
+	}
+	ipcContainer, err := c.getNamespaceContainer("IPC", c.hostConfig.IpcMode.Container())
+	if err != nil {
+		return err
+	}
+
 	// Build lists of devices allowed and created within the container.
 	userSpecifiedDevices := make([]*devices.Device, len(c.hostConfig.Devices))
 	for i, deviceMapping := range c.hostConfig.Devices {
 		device, err := devices.GetDevice(deviceMapping.PathOnHost, deviceMapping.CgroupPermissions)
-		device.Path = deviceMapping.PathInContainer
 		if err != nil {
 			return fmt.Errorf("error gathering device information while adding custom device %s", err)
 		}
+		device.Path = deviceMapping.PathInContainer
 		userSpecifiedDevices[i] = device
 	}
 	allowedDevices := append(devices.DefaultAllowedDevices, userSpecifiedDevices...)
 
-	autoCreatedDevices := append(devices.DefaultAutoCreatedDevices, userSpecifiedDevices...)
+	autoCreatedDevices := devices.DefaultAutoCreatedDevices
+	// the devices cannot be created in a user namespace, they are bind
+	// mounted from the host with the mounts of the container
+	if c.daemon.uidMaps == nil {
+		autoCreatedDevices = append(autoCreatedDevices, userSpecifiedDevices...)
+	}
+
+	seccompProfile, seccompName, err := c.daemon.seccompProfile(c.hostConfig)
+	if err != nil {
+		return err
+	}
+	c.SeccompProfile = seccompName
 
 	// TODO: this can be removed after lxc-conf is fully deprecated
 	mergeLxcConfIntoOptions(c.hostConfig, context)
 
 	resources := &execdriver.Resources{
-		Memory:     c.Config.Memory,
-		MemorySwap: c.Config.MemorySwap,
-		CpuShares:  c.Config.CpuShares,
-		Cpuset:     c.Config.Cpuset,
+		Memory:      c.Config.Memory,
+		MemorySwap:  c.Config.MemorySwap,
+		CpuShares:   c.Config.CpuShares,
+		CpuQuota:    c.Config.CpuQuota,
+		CpuPeriod:   c.Config.CpuPeriod,
+		Cpuset:      c.Config.Cpuset,
+		BlkioWeight: c.Config.BlkioWeight,
+		Ulimits:     c.ulimits(),
+
+		MemorySwappiness: c.Config.MemorySwappiness,
+		OomKillDisable:   c.Config.OomKillDisable,
 	}
 	c.command = &execdriver.Command{
 		ID:                 c.ID,
@@ -266,12 +313,59 @@ func populateCommand(c *Container, env []string) error {
 		AutoCreatedDevices: autoCreatedDevices,
 		CapAdd:             c.hostConfig.CapAdd,
 		CapDrop:            c.hostConfig.CapDrop,
+		ReadonlyRootfs:     c.hostConfig.ReadonlyRootfs,
+		Tmpfs:              c.hostConfig.Tmpfs,
+		HostPid:            c.hostConfig.PidMode.IsHost(),
+		HostIpc:            c.hostConfig.IpcMode.IsHost(),
+		HostUts:            c.hostConfig.UTSMode.IsHost(),
+		PidContainerID:     pidContainer,
+		IpcContainerID:     ipcContainer,
+		UidMappings:        c.daemon.uidMaps,
+		GidMappings:        c.daemon.gidMaps,
+		Seccomp:            seccompProfile,
+		CgroupParent:       c.cgroupParent(),
+		OnOOM: func(*execdriver.Command) {
+			// the processes are paused rather than killed when the OOM
+			// killer is disabled
+			if !c.Config.OomKillDisable {
+				c.State.SetOOMKilled()
+			}
+			c.LogEvent("oom")
+		},
 	}
 	c.command.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
 	c.command.Env = env
 	return nil
 }
 
+// ulimits returns the ulimits of the container, completed with the
+// defaults of the daemon.
+func (container *Container) ulimits() []*ulimit.Ulimit {
+	ulimits := append([]*ulimit.Ulimit{}, container.hostConfig.Ulimits...)
+	for _, d := range container.daemon.defaultUlimits {
+		overridden := false
+		for _, u := range container.hostConfig.Ulimits {
+			if u.Name == d.Name {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			ulimits = append(ulimits, d)
+		}
+	}
+	return ulimits
+}
+
+// cgroupParent returns the cgroup the cgroup of the container is created
+// in, the default of the driver when empty.
+func (container *Container) cgroupParent() string {
+	if container.hostConfig.CgroupParent != "" {
+		return container.hostConfig.CgroupParent
+	}
+	return container.daemon.config.CgroupParent
+}
+
 func (container *Container) Start() (err error) {
 	container.Lock()
 	defer container.Unlock()
@@ -291,12 +385,25 @@ func (container *Container) Start() (err error) {
 	if err := container.setupContainerDns(); err != nil {
 		return err
 	}
+	// the container must not change under the tools inspecting it
+	container.daemon.inspectMounts.forceUnmount(container.daemon, container.ID)
 	if err := container.Mount(); err != nil {
 		return err
 	}
+	if err := container.chownToRemappedRoot(); err != nil {
+		return err
+	}
+	if container.hostConfig.UTSMode.IsHost() {
+		if err := container.useHostHostname(); err != nil {
+			return err
+		}
+	}
 	if err := container.initializeNetworking(); err != nil {
 		return err
 	}
+	if err := container.writeMetadataFile(); err != nil {
+		return err
+	}
 	container.verifyDaemonSettings()
 	if err := prepareVolumesForContainer(container); err != nil {
 		return err
@@ -315,8 +422,12 @@ func (container *Container) Start() (err error) {
 	if err := setupMountsForContainer(container); err != nil {
 		return err
 	}
-
-	return container.waitForStart()
+	if err := container.waitForStart(); err != nil {
+		return err
+	}
+	// the containers linking to this one know it by its previous address
+	container.daemon.linkedHosts.update(container.daemon, container)
+	return nil
 }
 
 func (container *Container) Run() error {
@@ -408,10 +519,21 @@ func (container *Container) buildHostnameAndHostsFiles(IP string) error {
 		return err
 	}
 
+	// the resolver of the daemon answers with the current address of the
+	// linked containers, which /etc/hosts would shadow
+	if container.EmbeddedDns {
+		children = nil
+	}
 	for linkAlias, child := range children {
 		_, alias := path.Split(linkAlias)
 		extraContent[alias] = child.NetworkSettings.IPAddress
 	}
+	container.daemon.linkedHosts.track(container, children)
+
+	for _, extraHost := range container.hostConfig.ExtraHosts {
+		parts := strings.SplitN(extraHost, ":", 2)
+		extraContent[parts[0]] = parts[1]
+	}
 
 	return etchosts.Build(container.HostsPath, IP, container.Config.Hostname, container.Config.Domainname, &extraContent)
 }
@@ -491,6 +613,11 @@ func (container *Container) releaseNetwork() {
 	}
 	eng := container.daemon.eng
 
+	for network := range container.NetworkSettings.Networks {
+		if err := container.daemon.disconnectNetwork(container, network); err != nil {
+			log.Errorf("Error disconnecting %s from network %s: %s", container.ID, network, err)
+		}
+	}
 	eng.Job("release_interface", container.ID).Run()
 	container.NetworkSettings = &NetworkSettings{}
 }
@@ -500,6 +627,8 @@ func (container *Container) releaseNetwork() {
 func (container *Container) cleanup() {
 	container.releaseNetwork()
 
+	unmountNamedVolumes(container)
+
 	// Disable all active links
 	if container.activeLinks != nil {
 		for _, link := range container.activeLinks {
@@ -640,6 +769,7 @@ func (container *Container) ExportRw() (archive.Archive, error) {
 		container.Unmount()
 		return nil, err
 	}
+	archive = container.daemon.toContainerIDs(archive)
 	return utils.NewReadCloserWrapper(archive, func() error {
 			err := archive.Close()
 			container.Unmount()
@@ -648,16 +778,26 @@ func (container *Container) ExportRw() (archive.Archive, error) {
 		nil
 }
 
-func (container *Container) Export() (archive.Archive, error) {
+// Export returns a tar archive of the filesystem of the container. With
+// includes, only the paths matching these glob patterns are archived; the
+// paths matching excludes are left out, as well as the mountpoints of the
+// volumes with skipVolumes.
+func (container *Container) Export(includes, excludes []string, skipVolumes bool) (archive.Archive, error) {
 	if err := container.Mount(); err != nil {
 		return nil, err
 	}
 
-	archive, err := archive.Tar(container.basefs, archive.Uncompressed)
+	options, err := container.exportOptions(includes, excludes, skipVolumes)
 	if err != nil {
 		container.Unmount()
 		return nil, err
 	}
+	archive, err := archive.TarWithOptions(container.basefs, options)
+	if err != nil {
+		container.Unmount()
+		return nil, err
+	}
+	archive = container.daemon.toContainerIDs(archive)
 	return utils.NewReadCloserWrapper(archive, func() error {
 			err := archive.Close()
 			container.Unmount()
@@ -794,6 +934,7 @@ func (container *Container) Copy(resource string) (io.ReadCloser, error) {
 		container.Unmount()
 		return nil, err
 	}
+	archive = container.daemon.toContainerIDs(archive)
 	return utils.NewReadCloserWrapper(archive, func() error {
 			err := archive.Close()
 			container.Unmount()
@@ -840,14 +981,18 @@ func (container *Container) DisableLink(name string) {
 }
 
 func (container *Container) setupContainerDns() error {
-	if container.ResolvConfPath != "" {
-		return nil
-	}
-
 	var (
 		config = container.hostConfig
 		daemon = container.daemon
+		// the resolver of the daemon forwards the queries for the other
+		// names to the servers of the daemon
+		embeddedDns = daemon.resolver != nil && !container.Config.NetworkDisabled && len(config.Dns) == 0 && (config.NetworkMode == "" || config.NetworkMode == "bridge")
 	)
+	// rewritten when the daemon starts or stops using its resolver
+	if container.ResolvConfPath != "" && container.EmbeddedDns == embeddedDns {
+		return nil
+	}
+	container.EmbeddedDns = embeddedDns
 
 	resolvConf, err := resolvconf.Get()
 	if err != nil {
@@ -857,6 +1002,38 @@ func (container *Container) setupContainerDns() error {
 	if err != nil {
 		return err
 	}
+	return container.writeResolvConf(resolvConf)
+}
+
+// writeResolvConf writes the resolv.conf of the container from the one of
+// the host, resolvConf, and the DNS settings of the container and of the
+// daemon, and keeps its hash to tell whether it was changed afterwards.
+func (container *Container) writeResolvConf(resolvConf []byte) error {
+	if err := container.buildResolvConf(resolvConf); err != nil {
+		return err
+	}
+	written, err := ioutil.ReadFile(container.ResolvConfPath)
+	if err != nil {
+		return err
+	}
+	container.ResolvConfHash, err = utils.HashData(bytes.NewReader(written))
+	return err
+}
+
+func (container *Container) buildResolvConf(resolvConf []byte) error {
+	var (
+		config = container.hostConfig
+		daemon = container.daemon
+	)
+	if container.EmbeddedDns {
+		dnsSearch := resolvconf.GetSearchDomains(resolvConf)
+		if len(config.DnsSearch) > 0 {
+			dnsSearch = config.DnsSearch
+		} else if len(daemon.config.DnsSearch) > 0 {
+			dnsSearch = daemon.config.DnsSearch
+		}
+		return resolvconf.Build(container.ResolvConfPath, []string{daemon.resolver.Addr().IP.String()}, dnsSearch)
+	}
 
 	if config.NetworkMode != "host" && (len(config.Dns) > 0 || len(daemon.config.Dns) > 0 || len(config.DnsSearch) > 0 || len(daemon.config.DnsSearch) > 0) {
 		var (
@@ -878,20 +1055,27 @@ func (container *Container) setupContainerDns() error {
 	return ioutil.WriteFile(container.ResolvConfPath, resolvConf, 0644)
 }
 
+// useHostHostname gives the container the hostname of the host, for the
+// containers sharing its network or UTS namespace.
+func (container *Container) useHostHostname() error {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	parts := strings.SplitN(hostname, ".", 2)
+	container.Config.Hostname = parts[0]
+	if len(parts) > 1 {
+		container.Config.Domainname = parts[1]
+	}
+	return nil
+}
+
 func (container *Container) initializeNetworking() error {
-	var err error
 	if container.hostConfig.NetworkMode.IsHost() {
-		container.Config.Hostname, err = os.Hostname()
-		if err != nil {
+		if err := container.useHostHostname(); err != nil {
 			return err
 		}
 
-		parts := strings.SplitN(container.Config.Hostname, ".", 2)
-		if len(parts) > 1 {
-			container.Config.Hostname = parts[0]
-			container.Config.Domainname = parts[1]
-		}
-
 		content, err := ioutil.ReadFile("/etc/hosts")
 		if os.IsNotExist(err) {
 			return container.buildHostnameAndHostsFiles("")
@@ -909,7 +1093,16 @@ func (container *Container) initializeNetworking() error {
 		}
 		container.HostsPath = hostsPath
 
-		return ioutil.WriteFile(container.HostsPath, content, 0644)
+		if err := ioutil.WriteFile(container.HostsPath, content, 0644); err != nil {
+			return err
+		}
+		for _, extraHost := range container.hostConfig.ExtraHosts {
+			parts := strings.SplitN(extraHost, ":", 2)
+			if err := etchosts.Add(container.HostsPath, parts[1], parts[0]); err != nil {
+				return err
+			}
+		}
+		return nil
 	} else if container.hostConfig.NetworkMode.IsContainer() {
 		// we need to get the hosts files from the container to join
 		nc, err := container.getNetworkedContainer()
@@ -942,6 +1135,23 @@ func (container *Container) verifyDaemonSettings() {
 		log.Infof("WARNING: Your kernel does not support swap limit capabilities. Limitation discarded.")
 		container.Config.MemorySwap = -1
 	}
+	if (container.Config.CpuQuota > 0 || container.Config.CpuPeriod > 0) && !container.daemon.sysInfo.CpuCfsQuota {
+		log.Infof("WARNING: Your kernel does not support CPU cfs quota. Quota discarded.")
+		container.Config.CpuQuota = 0
+		container.Config.CpuPeriod = 0
+	}
+	if container.Config.BlkioWeight > 0 && !container.daemon.sysInfo.BlkioWeight {
+		log.Infof("WARNING: Your kernel does not support block IO weight. Weight discarded.")
+		container.Config.BlkioWeight = 0
+	}
+	if container.Config.MemorySwappiness != nil && !container.daemon.sysInfo.MemorySwappiness {
+		log.Infof("WARNING: Your kernel does not support memory swappiness. Swappiness discarded.")
+		container.Config.MemorySwappiness = nil
+	}
+	if container.Config.OomKillDisable && !container.daemon.sysInfo.OomKillDisable {
+		log.Infof("WARNING: Your kernel does not support OOM kill disable. The OOM killer stays enabled.")
+		container.Config.OomKillDisable = false
+	}
 	if container.daemon.sysInfo.IPv4ForwardingDisabled {
 		log.Infof("WARNING: IPv4 forwarding is disabled. Networking will not work")
 	}
@@ -1014,6 +1224,11 @@ func (container *Container) createDaemonEnvironment(linkedEnv []string) []string
 	if container.Config.Tty {
 		env = append(env, "TERM=xterm")
 	}
+	if container.daemon.config.HostTimezone {
+		// the time zone of the host, mounted at /etc/localtime, rather than
+		// one of the zoneinfo files of the image, which may not have them
+		env = append(env, "TZ=:"+localtimePath)
+	}
 	env = append(env, linkedEnv...)
 	// because the env on the container can override certain default values
 	// we need to replace the 'env' keys where they match and append anything
@@ -1041,6 +1256,9 @@ func (container *Container) setupWorkingDirectory() error {
 			if err := os.MkdirAll(pth, 0755); err != nil {
 				return err
 			}
+			if err := container.daemon.chownRemappedPath(container.basefs, pth); err != nil {
+				return err
+			}
 		}
 		if pthInfo != nil && !pthInfo.IsDir() {
 			return fmt.Errorf("Cannot mkdir: %s is not a directory", container.Config.WorkingDir)
@@ -1133,15 +1351,46 @@ func (container *Container) getNetworkedContainer() (*Container, error) {
 	parts := strings.SplitN(string(container.hostConfig.NetworkMode), ":", 2)
 	switch parts[0] {
 	case "container":
-		nc := container.daemon.Get(parts[1])
-		if nc == nil {
-			return nil, fmt.Errorf("no such container to join network: %s", parts[1])
-		}
-		if !nc.State.IsRunning() {
-			return nil, fmt.Errorf("cannot join network of a non running container: %s", parts[1])
-		}
-		return nc, nil
+		return container.getJoinedContainer("network", parts[1])
 	default:
 		return nil, fmt.Errorf("network mode not set to container")
 	}
 }
+
+// getJoinedContainer returns the container called name whose namespace of
+// what, e.g. network, is joined. It must be running, as the namespaces of a
+// stopped container are gone.
+func (container *Container) getJoinedContainer(what, name string) (*Container, error) {
+	c := container.daemon.Get(name)
+	if c == nil {
+		return nil, fmt.Errorf("no such container to join %s: %s", what, name)
+	}
+	if c.ID == container.ID {
+		return nil, fmt.Errorf("cannot join %s of the container itself", what)
+	}
+	if !c.State.IsRunning() {
+		return nil, fmt.Errorf("cannot join %s of a non running container: %s", what, name)
+	}
+	return c, nil
+}
+
+// getNamespaceContainer returns the ID of the container called name whose PID
+// or IPC namespace, as given by what, is joined, or "" when name is empty. The joined container must have its
+// own namespace: they are not joined transitively.
+func (container *Container) getNamespaceContainer(what, name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	c, err := container.getJoinedContainer(what+" namespace", name)
+	if err != nil {
+		return "", err
+	}
+	joinedMode := string(c.hostConfig.PidMode)
+	if what == "IPC" {
+		joinedMode = string(c.hostConfig.IpcMode)
+	}
+	if joinedMode != "" {
+		return "", fmt.Errorf("cannot join %s namespace of %s, which uses --%s=%s", what, name, strings.ToLower(what), joinedMode)
+	}
+	return c.ID, nil
+}
@@ -1,8 +1,11 @@
 package daemon
 
 import (
-	"github.com/docker/docker/nat"
+	"strings"
 	"testing"
+
+	"github.com/docker/docker/nat"
+	"github.com/docker/docker/runconfig"
 )
 
 func TestParseNetworkOptsPrivateOnly(t *testing.T) {
@@ -178,3 +181,37 @@ func TestGetFullName(t *testing.T) {
 		t.Fatal("Error should not be nil")
 	}
 }
+
+func TestHostTimezoneEnvironment(t *testing.T) {
+	container := &Container{
+		Config: &runconfig.Config{Hostname: "db"},
+		daemon: &Daemon{config: &Config{HostTimezone: true}},
+	}
+	env := container.createDaemonEnvironment(nil)
+	if !hasEnv(env, "TZ=:/etc/localtime") {
+		t.Fatalf("Expected the time zone of the host in %v", env)
+	}
+
+	container.Config.Env = []string{"TZ=Asia/Tokyo"}
+	env = container.createDaemonEnvironment(nil)
+	if !hasEnv(env, "TZ=Asia/Tokyo") || hasEnv(env, "TZ=:/etc/localtime") {
+		t.Fatalf("Expected the time zone of the container in %v", env)
+	}
+
+	container.daemon.config.HostTimezone = false
+	container.Config.Env = nil
+	for _, e := range container.createDaemonEnvironment(nil) {
+		if strings.HasPrefix(e, "TZ=") {
+			t.Fatalf("Expected no time zone without --host-timezone, got %s", e)
+		}
+	}
+}
+
+func hasEnv(env []string, e string) bool {
+	for _, v := range env {
+		if v == e {
+			return true
+		}
+	}
+	return false
+}
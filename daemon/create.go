@@ -1,6 +1,8 @@
 package daemon
 
 import (
+	"fmt"
+
 	"github.com/docker/docker/engine"
 	"github.com/docker/docker/graph"
 	"github.com/docker/docker/pkg/parsers"
@@ -14,6 +16,9 @@ func (daemon *Daemon) ContainerCreate(job *engine.Job) engine.Status {
 	} else if len(job.Args) > 1 {
 		return job.Errorf("Usage: %s", job.Name)
 	}
+	if err := daemon.checkNotDraining(); err != nil {
+		return job.Error(err)
+	}
 	config := runconfig.ContainerConfigFromJob(job)
 	if config.Memory != 0 && config.Memory < 524288 {
 		return job.Errorf("Minimum memory limit allowed is 512k")
@@ -26,6 +31,51 @@ func (daemon *Daemon) ContainerCreate(job *engine.Job) engine.Status {
 		job.Errorf("Your kernel does not support swap limit capabilities. Limitation discarded.\n")
 		config.MemorySwap = -1
 	}
+	if config.MemorySwap > 0 && config.MemorySwap < config.Memory {
+		return job.Errorf("Minimum memory swap limit should be larger than the memory limit")
+	}
+	if config.CpuPeriod != 0 && (config.CpuPeriod < 1000 || config.CpuPeriod > 1000000) {
+		return job.Errorf("CPU period must be between 1000 and 1000000 microseconds")
+	}
+	if config.CpuQuota != 0 && config.CpuQuota < 1000 {
+		return job.Errorf("Minimum CPU quota allowed is 1000 microseconds")
+	}
+	if config.BlkioWeight != 0 && (config.BlkioWeight < 10 || config.BlkioWeight > 1000) {
+		return job.Errorf("Block IO weight must be between 10 and 1000")
+	}
+	if (config.CpuQuota > 0 || config.CpuPeriod > 0) && !daemon.SystemConfig().CpuCfsQuota {
+		job.Errorf("Your kernel does not support CPU cfs quota. Quota discarded.\n")
+		config.CpuQuota = 0
+		config.CpuPeriod = 0
+	}
+	if config.BlkioWeight > 0 && !daemon.SystemConfig().BlkioWeight {
+		job.Errorf("Your kernel does not support block IO weight. Weight discarded.\n")
+		config.BlkioWeight = 0
+	}
+	if config.MemorySwappiness != nil && (*config.MemorySwappiness < 0 || *config.MemorySwappiness > 100) {
+		return job.Errorf("Memory swappiness must be between 0 and 100")
+	}
+	if config.MemorySwappiness != nil && !daemon.SystemConfig().MemorySwappiness {
+		job.Errorf("Your kernel does not support memory swappiness. Swappiness discarded.\n")
+		config.MemorySwappiness = nil
+	}
+	if config.OomKillDisable && !daemon.SystemConfig().OomKillDisable {
+		job.Errorf("Your kernel does not support OOM kill disable. The OOM killer stays enabled.\n")
+		config.OomKillDisable = false
+	}
+	if config.OomKillDisable && config.Memory == 0 {
+		job.Errorf("WARNING: The OOM killer is disabled without a memory limit, the container can exhaust the memory of the host.\n")
+	}
+	warning, err := daemon.repositories.CheckImplicitTag(config.Image)
+	if err != nil {
+		return job.Error(err)
+	}
+	if warning != "" {
+		job.Errorf("%s\n", warning)
+	}
+	if err := daemon.pullImage(job, config.Image); err != nil {
+		return job.Error(err)
+	}
 	container, buildWarnings, err := daemon.Create(config, name)
 	if err != nil {
 		if daemon.Graph().IsNotExist(err) {
@@ -53,6 +103,35 @@ func (daemon *Daemon) ContainerCreate(job *engine.Job) engine.Status {
 	return engine.StatusOK
 }
 
+// pullImage pulls the image of a new container according to the
+// "PullPolicy" of the create job: "always" pulls it each time, "missing"
+// only when it is not in the graph, and "never" leaves it to the caller.
+func (daemon *Daemon) pullImage(job *engine.Job, image string) error {
+	switch policy := job.Getenv("PullPolicy"); policy {
+	case "", "never":
+		return nil
+	case "missing":
+		if img, _ := daemon.repositories.LookupImage(image); img != nil {
+			return nil
+		}
+	case "always":
+	default:
+		return fmt.Errorf("Invalid pull policy: %s", policy)
+	}
+
+	repo, tag := parsers.ParseRepositoryTag(image)
+	if tag == "" {
+		tag = graph.DEFAULTTAG
+	}
+	pull := daemon.eng.Job("pull", repo, tag)
+	pull.Setenv("authConfig", job.Getenv("authConfig"))
+	pull.Setenv("metaHeaders", job.Getenv("metaHeaders"))
+	if err := pull.Run(); err != nil {
+		return fmt.Errorf("Error pulling image %s: %s", image, err)
+	}
+	return nil
+}
+
 // Create creates a new container from the given configuration with a given name.
 func (daemon *Daemon) Create(config *runconfig.Config, name string) (*Container, []string, error) {
 	var (
@@ -5,6 +5,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path"
 	"regexp"
 	"runtime"
@@ -22,19 +23,26 @@ import (
 	_ "github.com/docker/docker/daemon/graphdriver/vfs"
 	_ "github.com/docker/docker/daemon/networkdriver/bridge"
 	"github.com/docker/docker/daemon/networkdriver/portallocator"
+	"github.com/docker/docker/daemon/networkdriver/resolver"
+	"github.com/docker/docker/daemon/volumedriver"
 	"github.com/docker/docker/dockerversion"
 	"github.com/docker/docker/engine"
 	"github.com/docker/docker/graph"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/pkg/broadcastwriter"
 	"github.com/docker/docker/pkg/graphdb"
+	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/log"
 	"github.com/docker/docker/pkg/namesgenerator"
 	"github.com/docker/docker/pkg/networkfs/resolvconf"
 	"github.com/docker/docker/pkg/parsers"
 	"github.com/docker/docker/pkg/parsers/kernel"
+	"github.com/docker/docker/pkg/plugins"
+	"github.com/docker/docker/pkg/seccomp"
 	"github.com/docker/docker/pkg/sysinfo"
 	"github.com/docker/docker/pkg/truncindex"
+	"github.com/docker/docker/pkg/ulimit"
+	"github.com/docker/docker/registry"
 	"github.com/docker/docker/runconfig"
 	"github.com/docker/docker/utils"
 )
@@ -94,6 +102,22 @@ type Daemon struct {
 	containerGraph *graphdb.Database
 	driver         graphdriver.Driver
 	execDriver     execdriver.Driver
+	drain          *drainState
+	hooks          []*hook
+	namedVolumes   *volumeStore
+	inspectMounts  *inspectMounts
+	defaultUlimits []*ulimit.Ulimit
+	usage          *usageCounters
+	downloads      *downloadCache
+	linkedHosts    *linkedHosts
+	tokens         *tokenStore
+	// uidMaps and gidMaps map the IDs of the containers to the ones of the
+	// host with --userns-remap, nil otherwise
+	uidMaps []idtools.IDMap
+	gidMaps []idtools.IDMap
+	// resolver answers the DNS queries of the containers of the default
+	// bridge with --embedded-dns
+	resolver *resolver.Resolver
 }
 
 // Install installs daemon capabilities to eng.
@@ -102,28 +126,53 @@ func (daemon *Daemon) Install(eng *engine.Engine) error {
 	// FIXME: rename ContainerDestroy to ContainerRm for consistency with the CLI command
 	// FIXME: remove ImageDelete's dependency on Daemon, then move to graph/
 	for name, method := range map[string]engine.Handler{
-		"attach":            daemon.ContainerAttach,
-		"build":             daemon.CmdBuild,
-		"commit":            daemon.ContainerCommit,
-		"container_changes": daemon.ContainerChanges,
-		"container_copy":    daemon.ContainerCopy,
-		"container_inspect": daemon.ContainerInspect,
-		"containers":        daemon.Containers,
-		"create":            daemon.ContainerCreate,
-		"delete":            daemon.ContainerDestroy,
-		"export":            daemon.ContainerExport,
-		"info":              daemon.CmdInfo,
-		"kill":              daemon.ContainerKill,
-		"logs":              daemon.ContainerLogs,
-		"pause":             daemon.ContainerPause,
-		"resize":            daemon.ContainerResize,
-		"restart":           daemon.ContainerRestart,
-		"start":             daemon.ContainerStart,
-		"stop":              daemon.ContainerStop,
-		"top":               daemon.ContainerTop,
-		"unpause":           daemon.ContainerUnpause,
-		"wait":              daemon.ContainerWait,
-		"image_delete":      daemon.ImageDelete, // FIXME: see above
+		"attach":             daemon.ContainerAttach,
+		"build":              daemon.CmdBuild,
+		"check_config":       daemon.CmdCheckConfig,
+		"commit":             daemon.ContainerCommit,
+		"container_changes":  daemon.ContainerChanges,
+		"container_clone":    daemon.ContainerClone,
+		"container_copy":     daemon.ContainerCopy,
+		"container_inspect":  daemon.ContainerInspect,
+		"container_portmap":  daemon.ContainerPortMap,
+		"container_ports":    daemon.ContainerPorts,
+		"container_update":   daemon.ContainerUpdate,
+		"containers":         daemon.Containers,
+		"create":             daemon.ContainerCreate,
+		"delete":             daemon.ContainerDestroy,
+		"drain":              daemon.ContainerDrain,
+		"drain_status":       daemon.ContainerDrainStatus,
+		"export":             daemon.ContainerExport,
+		"info":               daemon.CmdInfo,
+		"kill":               daemon.ContainerKill,
+		"logs":               daemon.ContainerLogs,
+		"network_connect":    daemon.ContainerNetworkConnect,
+		"network_disconnect": daemon.ContainerNetworkDisconnect,
+		"pause":              daemon.ContainerPause,
+		"plugins":            daemon.PluginList,
+		"prune":              daemon.Prune,
+		"resize":             daemon.ContainerResize,
+		"restart":            daemon.ContainerRestart,
+		"start":              daemon.ContainerStart,
+		"stop":               daemon.ContainerStop,
+		"system_df":          daemon.SystemDf,
+		"tokens":             daemon.TokenList,
+		"token_create":       daemon.TokenCreate,
+		"token_rm":           daemon.TokenRemove,
+		"token_scopes":       daemon.TokenScopes,
+		"top":                daemon.ContainerTop,
+		"undrain":            daemon.ContainerUndrain,
+		"unpause":            daemon.ContainerUnpause,
+		"wait":               daemon.ContainerWait,
+		"volumes":            daemon.VolumeList,
+		"volume_create":      daemon.VolumeCreate,
+		"volume_inspect":     daemon.VolumeInspect,
+		"volume_rm":          daemon.VolumeRemove,
+		"image_delete":       daemon.ImageDelete, // FIXME: see above
+		"image_containers":   daemon.ImageContainers,
+		"image_mount":        daemon.ImageMount,
+		"image_mounts":       daemon.ImageMounts,
+		"image_unmount":      daemon.ImageUnmount,
 	} {
 		if err := eng.Register(name, method); err != nil {
 			return err
@@ -359,6 +408,20 @@ func (daemon *Daemon) restore() error {
 		registeredContainers = append(registeredContainers, container)
 	}
 
+	// the containers still running kept their hosts files, whose entries
+	// for the linked containers are tracked again
+	for _, container := range registeredContainers {
+		if !container.State.IsRunning() || container.HostsPath == "" || container.EmbeddedDns {
+			continue
+		}
+		children, err := daemon.Children(container.Name)
+		if err != nil {
+			log.Debugf("Failed to get the links of container %s: %s", container.ID, err)
+			continue
+		}
+		daemon.linkedHosts.track(container, children)
+	}
+
 	// check the restart policy on the containers and restart any container with
 	// the restart policy of "always"
 	if daemon.config.AutoRestart {
@@ -526,6 +589,11 @@ func (daemon *Daemon) newContainer(name string, config *runconfig.Config, img *i
 	daemon.generateHostname(id, config)
 	entrypoint, args := daemon.getEntrypointAndArgs(config)
 
+	digest, err := img.Digest()
+	if err != nil {
+		return nil, err
+	}
+
 	container := &Container{
 		// FIXME: we should generate the ID here instead of receiving it as an argument
 		ID:              id,
@@ -535,6 +603,7 @@ func (daemon *Daemon) newContainer(name string, config *runconfig.Config, img *i
 		Config:          config,
 		hostConfig:      &runconfig.HostConfig{},
 		Image:           img.ID, // Always use the resolved image id
+		ImageDigest:     digest,
 		NetworkSettings: &NetworkSettings{},
 		Name:            name,
 		Driver:          daemon.driver.String(),
@@ -568,6 +637,15 @@ func (daemon *Daemon) createRootfs(container *Container, img *image.Image) error
 	if err := graph.SetupInitLayer(initPath); err != nil {
 		return err
 	}
+	if daemon.uidMaps != nil {
+		uid, gid, err := idtools.RootPair(daemon.uidMaps, daemon.gidMaps)
+		if err != nil {
+			return err
+		}
+		if err := graph.ChownInitLayer(initPath, uid, gid); err != nil {
+			return err
+		}
+	}
 
 	if err := daemon.driver.Create(container.ID, initID); err != nil {
 		return err
@@ -623,13 +701,34 @@ func (daemon *Daemon) Children(name string) (map[string]*Container, error) {
 	return children, nil
 }
 
+// Parents returns the containers linking to the container of id, by the
+// full name of their link, e.g. /web/db.
+func (daemon *Daemon) Parents(id string) map[string]*Container {
+	parents := make(map[string]*Container)
+	for _, edge := range daemon.containerGraph.RefPaths(id) {
+		// the edge of the root is the name of the container
+		if edge.ParentID == "0" {
+			continue
+		}
+		if parent := daemon.containers.Get(edge.ParentID); parent != nil {
+			parents[path.Join(parent.Name, edge.Name)] = parent
+		}
+	}
+	return parents
+}
+
+// RegisterLink links parent to child under alias. The same child can be
+// linked under several aliases, but an alias names a single child.
 func (daemon *Daemon) RegisterLink(parent, child *Container, alias string) error {
 	fullName := path.Join(parent.Name, alias)
-	if !daemon.containerGraph.Exists(fullName) {
-		_, err := daemon.containerGraph.Set(fullName, child.ID)
-		return err
+	if e := daemon.containerGraph.Get(fullName); e != nil {
+		if e.ID() != child.ID {
+			return fmt.Errorf("Conflicting link alias %s: already used for another container", alias)
+		}
+		return nil
 	}
-	return nil
+	_, err := daemon.containerGraph.Set(fullName, child.ID)
+	return err
 }
 
 func (daemon *Daemon) RegisterLinks(container *Container, hostConfig *runconfig.HostConfig) error {
@@ -686,6 +785,24 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine) (*Daemon, error)
 	if !config.EnableIptables && !config.InterContainerCommunication {
 		return nil, fmt.Errorf("You specified --iptables=false with --icc=false. ICC uses iptables to function. Please set --icc or --iptables to true.")
 	}
+	if !config.EnableIptables && !config.EnableUserlandProxy {
+		return nil, fmt.Errorf("You specified --iptables=false with --userland-proxy=false. The published ports need one of them. Please set --iptables or --userland-proxy to true.")
+	}
+	hooks, err := parseHooks(config.Hooks)
+	if err != nil {
+		return nil, err
+	}
+	var defaultUlimits []*ulimit.Ulimit
+	for _, val := range config.DefaultUlimits {
+		u, err := ulimit.Parse(val)
+		if err != nil {
+			return nil, err
+		}
+		defaultUlimits = append(defaultUlimits, u)
+	}
+	if config.CgroupParent != "" && !runconfig.ValidCgroupParent(config.CgroupParent) {
+		return nil, fmt.Errorf("Invalid --cgroup-parent %s, it must be relative to the cgroup of the daemon", config.CgroupParent)
+	}
 	// FIXME: DisableNetworkBidge doesn't need to be public anymore
 	// DisableNetworkBridge = "none"
 	// 如果没有网桥，则禁用网络
@@ -724,9 +841,13 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine) (*Daemon, error)
 	// set up the TempDir to use a canonical path
 	// 配置工作路径，用于存放镜像，文件系统，临时文件等等，非常重要
 	// 在工作路径创建一个tmp目录，如果成功说明root正常，否则爆炸
-	tmp, err := utils.TempDir(config.Root)
-	if err != nil {
-		log.Fatalf("Unable to get the TempDir under %s: %s", config.Root, err)
+	tmp := config.TmpDir
+	if tmp == "" {
+		if tmp, err = utils.TempDir(config.Root); err != nil {
+			log.Fatalf("Unable to get the TempDir under %s: %s", config.Root, err)
+		}
+	} else if err := os.MkdirAll(tmp, 0700); err != nil {
+		log.Fatalf("Unable to create the TempDir %s: %s", tmp, err)
 	}
 	realTmp, err := utils.ReadSymlinkedDirectory(tmp)
 	if err != nil {
@@ -752,9 +873,19 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine) (*Daemon, error)
 	if err := os.MkdirAll(config.Root, 0700); err != nil && !os.IsExist(err) {
 		return nil, err
 	}
+	uidMaps, gidMaps, err := setupRemappedRoot(config)
+	if err != nil {
+		return nil, err
+	}
 
 	// Set the default driver 默认为空
 	graphdriver.DefaultDriver = config.GraphDriver
+	if config.ExternalTar != "" {
+		if _, err := exec.LookPath(config.ExternalTar); err != nil {
+			return nil, fmt.Errorf("Invalid --external-tar: %s", err)
+		}
+	}
+	archive.ExternalTar = config.ExternalTar
 
 	// Load storage driver config.GraphOptions默认为空
 	// 四种模型 aufs\btrfs\devicemapper\vfs
@@ -791,6 +922,7 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine) (*Daemon, error)
 	if err != nil {
 		return nil, err
 	}
+	g.SetIDMappings(uidMaps, gidMaps)
 
 	// We don't want to use a complex driver like aufs or devmapper
 	// for volumes, just a plain filesystem
@@ -804,12 +936,52 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine) (*Daemon, error)
 	if err != nil {
 		return nil, err
 	}
+
+	localVolumes, err := volumedriver.NewLocalDriver(path.Join(config.Root, "named-volumes"))
+	if err != nil {
+		return nil, err
+	}
+	namedVolumes, err := newVolumeStore(path.Join(config.Root, "named-volumes.json"), localVolumes)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := newTokenStore(path.Join(config.Root, "api-tokens.json"))
+	if err != nil {
+		return nil, err
+	}
 	log.Debugf("Creating repository list")
 	// TagStore用于管理存储镜像的仓库列表
 	repositories, err := graph.NewTagStore(path.Join(config.Root, "repositories-"+driver.String()), g)
 	if err != nil {
 		return nil, fmt.Errorf("Couldn't create Tag store: %s", err)
 	}
+	repositories.SetMaxConcurrentDownloads(config.MaxConcurrentDownloads)
+	repositories.SetMirrors(config.Mirrors)
+	if err := repositories.SetImplicitTagPolicy(config.ImplicitTag); err != nil {
+		return nil, err
+	}
+	registry.SetInsecureRegistries(config.InsecureRegistries)
+	if err := registry.SetProxy(config.RegistryProxy); err != nil {
+		return nil, err
+	}
+	if err := registry.SetRegistryPolicy(config.AllowedRegistries, config.DeniedRegistries); err != nil {
+		return nil, err
+	}
+	registry.SetOffline(config.Offline)
+	if !config.Offline {
+		registry.PreflightIndex()
+	}
+
+	usage, err := newUsageCounters(path.Join(config.Root, "usage.json"))
+	if err != nil {
+		return nil, err
+	}
+	repositories.OnPull(usage.imagePulled)
+
+	downloads, err := newDownloadCache(path.Join(config.Root, "downloads"))
+	if err != nil {
+		return nil, err
+	}
 
 	if !config.DisableNetwork {
 		job := eng.Job("init_networkdriver")
@@ -817,9 +989,12 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine) (*Daemon, error)
 		job.SetenvBool("EnableIptables", config.EnableIptables)
 		job.SetenvBool("InterContainerCommunication", config.InterContainerCommunication)
 		job.SetenvBool("EnableIpForward", config.EnableIpForward)
+		job.SetenvBool("EnableUserlandProxy", config.EnableUserlandProxy)
 		job.Setenv("BridgeIface", config.BridgeIface)
 		job.Setenv("BridgeIP", config.BridgeIP)
 		job.Setenv("DefaultBindingIP", config.DefaultIp.String())
+		job.Setenv("PublishedPortsRange", config.PublishedPortsRange)
+		job.Setenv("NetworksPath", path.Join(config.Root, "networks.json"))
 
 		if err := job.Run(); err != nil {
 			return nil, err
@@ -853,10 +1028,13 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine) (*Daemon, error)
 	}
 
 	sysInfo := sysinfo.New(false)
-	ed, err := execdrivers.NewDriver(config.ExecDriver, config.Root, sysInitPath, sysInfo)
+	ed, err := execdrivers.NewDriver(config.ExecDriver, config.Root, sysInitPath, sysInfo, config.NetPoolSize)
 	if err != nil {
 		return nil, err
 	}
+	if config.ExecDriver == "native" && !seccomp.Supported() {
+		log.Infof("WARNING: Your kernel or architecture does not support seccomp, the containers run without the default seccomp profile")
+	}
 
 	daemon := &Daemon{
 		repository:     daemonRepo,
@@ -872,13 +1050,42 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine) (*Daemon, error)
 		sysInitPath:    sysInitPath,
 		execDriver:     ed,
 		eng:            eng,
+		drain:          &drainState{},
+		hooks:          hooks,
+		namedVolumes:   namedVolumes,
+		inspectMounts:  newInspectMounts(),
+		linkedHosts:    newLinkedHosts(),
+		defaultUlimits: defaultUlimits,
+		usage:          usage,
+		downloads:      downloads,
+		tokens:         tokens,
+		uidMaps:        uidMaps,
+		gidMaps:        gidMaps,
+	}
+	if err := daemon.chownRemappedPath(config.Root, sysInitPath); err != nil {
+		return nil, err
+	}
+	if config.ExecDriver == "native" {
+		if err := daemon.chownRemappedPath(config.Root, path.Join(config.Root, "execdriver", "native")); err != nil {
+			return nil, err
+		}
 	}
 	if err := daemon.checkLocaldns(); err != nil {
 		return nil, err
 	}
+	if config.EmbeddedDns && !config.DisableNetwork {
+		if err := daemon.startResolver(); err != nil {
+			return nil, err
+		}
+	}
 	if err := daemon.restore(); err != nil {
 		return nil, err
 	}
+	go plugins.WatchHealth(pluginsHealthInterval)
+	go daemon.watchResolvConf(resolvConfInterval)
+	if config.SelfCheckInterval > 0 {
+		go newSelfCheck(daemon).run(time.Duration(config.SelfCheckInterval) * time.Minute)
+	}
 	// Setup shutdown handlers
 	// FIXME: can these shutdown handlers be registered closer to their source?
 	eng.OnShutdown(func() {
@@ -891,6 +1098,11 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine) (*Daemon, error)
 		if err := portallocator.ReleaseAll(); err != nil {
 			log.Errorf("portallocator.ReleaseAll(): %s", err)
 		}
+		if daemon.resolver != nil {
+			if err := daemon.resolver.Close(); err != nil {
+				log.Errorf("daemon.resolver.Close(): %s", err)
+			}
+		}
 		if err := daemon.driver.Cleanup(); err != nil {
 			log.Errorf("daemon.driver.Cleanup(): %s", err.Error())
 		}
@@ -922,6 +1134,7 @@ func (daemon *Daemon) shutdown() error {
 		}
 	}
 	group.Wait()
+	daemon.inspectMounts.unmountAll(daemon)
 
 	return nil
 }
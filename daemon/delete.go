@@ -95,12 +95,16 @@ func (daemon *Daemon) ContainerDestroy(job *engine.Job) engine.Status {
 			}
 
 			// Store all the deleted containers volumes
-			for _, volumeId := range container.Volumes {
+			for volPath, volumeId := range container.Volumes {
 				// Skip the volumes mounted from external
 				// bind mounts here will will be evaluated for a symlink
 				if _, exists := binds[volumeId]; exists {
 					continue
 				}
+				// Named volumes belong to their volume driver
+				if _, exists := container.NamedVolumes[volPath]; exists {
+					continue
+				}
 
 				volumeId = getVolumeId(volumeId)
 				volumes[volumeId] = struct{}{}
@@ -155,6 +159,8 @@ func (daemon *Daemon) Destroy(container *Container) error {
 		log.Debugf("Unable to remove container from link graph: %s", err)
 	}
 
+	daemon.linkedHosts.forget(container.ID)
+	daemon.inspectMounts.forceUnmount(daemon, container.ID)
 	if err := daemon.driver.Remove(container.ID); err != nil {
 		return fmt.Errorf("Driver %s failed to remove root filesystem %s: %s", daemon.driver, container.ID, err)
 	}
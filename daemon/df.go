@@ -0,0 +1,203 @@
+package daemon
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/daemon/volumedriver"
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/utils"
+)
+
+type imageDiskUsage struct {
+	Id         string
+	RepoTags   []string
+	Size       int64 // size of all the layers of the image
+	SharedSize int64 // size of the layers also used by other images
+	Containers int
+}
+
+type containerDiskUsage struct {
+	Id         string
+	Name       string
+	Image      string
+	Status     string
+	Running    bool
+	SizeRw     int64
+	SizeRootFs int64
+}
+
+type volumeDiskUsage struct {
+	Name       string
+	Driver     string
+	Size       int64 // -1 when the size cannot be computed
+	Containers int
+}
+
+// SystemDf reports the disk space used by the images, the writable layers
+// of the containers and the volumes.
+func (daemon *Daemon) SystemDf(job *engine.Job) engine.Status {
+	layers, err := daemon.graph.Map()
+	if err != nil {
+		return job.Error(err)
+	}
+	var layersSize int64
+	for _, layer := range layers {
+		if layer.Size > 0 {
+			layersSize += layer.Size
+		}
+	}
+
+	var (
+		containers []*containerDiskUsage
+		imageUsers = make(map[string]int)
+	)
+	for _, container := range daemon.List() {
+		imageUsers[container.Image]++
+		sizeRw, sizeRootFs := container.GetSize()
+		containers = append(containers, &containerDiskUsage{
+			Id:         container.ID,
+			Name:       container.Name,
+			Image:      daemon.repositories.ImageName(container.Image),
+			Status:     container.State.String(),
+			Running:    container.State.IsRunning(),
+			SizeRw:     sizeRw,
+			SizeRootFs: sizeRootFs,
+		})
+	}
+
+	// list the tagged images, the dangling ones and the ones used by containers
+	tags := daemon.repositories.ByID()
+	heads, err := daemon.graph.Heads()
+	if err != nil {
+		return job.Error(err)
+	}
+	var listed []string
+	for id := range layers {
+		_, tagged := tags[id]
+		_, head := heads[id]
+		if tagged || head || imageUsers[id] > 0 {
+			listed = append(listed, id)
+		}
+	}
+	sort.Strings(listed)
+	sizes := imageSizes(listed, layers)
+	images := make([]*imageDiskUsage, 0, len(listed))
+	for _, id := range listed {
+		images = append(images, &imageDiskUsage{
+			Id:         id,
+			RepoTags:   tags[id],
+			Size:       sizes[id][0],
+			SharedSize: sizes[id][1],
+			Containers: imageUsers[id],
+		})
+	}
+
+	out := &engine.Env{}
+	out.SetInt64("LayersSize", layersSize)
+	out.SetJson("Images", images)
+	out.SetJson("Containers", containers)
+	out.SetJson("Volumes", daemon.volumesDiskUsage())
+	if _, err := out.WriteTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+// imageSizes returns the size of each of the listed images and the part of
+// it made of layers also used by other listed images.
+func imageSizes(listed []string, layers map[string]*image.Image) map[string][2]int64 {
+	history := func(id string) []*image.Image {
+		var h []*image.Image
+		for layer := layers[id]; layer != nil; layer = layers[layer.Parent] {
+			h = append(h, layer)
+		}
+		return h
+	}
+
+	users := make(map[string]int)
+	for _, id := range listed {
+		for _, layer := range history(id) {
+			users[layer.ID]++
+		}
+	}
+	sizes := make(map[string][2]int64)
+	for _, id := range listed {
+		var size, shared int64
+		for _, layer := range history(id) {
+			if layer.Size <= 0 {
+				continue
+			}
+			size += layer.Size
+			if users[layer.ID] > 1 {
+				shared += layer.Size
+			}
+		}
+		sizes[id] = [2]int64{size, shared}
+	}
+	return sizes
+}
+
+// volumesDiskUsage returns the usage of the volumes created by the daemon
+// and of the named volumes of the local driver. The size of the volumes of
+// the other drivers is unknown.
+func (daemon *Daemon) volumesDiskUsage() []*volumeDiskUsage {
+	// the volume id is always the base of the path
+	getVolumeId := func(p string) string {
+		return filepath.Base(strings.TrimSuffix(p, "/layer"))
+	}
+	var (
+		volumes     []*volumeDiskUsage
+		volumeUsers = make(map[string]int)
+	)
+	for _, container := range daemon.List() {
+		for volPath, hostPath := range container.Volumes {
+			if _, exists := container.NamedVolumes[volPath]; exists {
+				continue
+			}
+			volumeUsers[getVolumeId(hostPath)]++
+		}
+	}
+
+	if ids, err := daemon.volumes.Map(); err != nil {
+		log.Errorf("Error listing the volumes: %s", err)
+	} else {
+		driver := daemon.volumes.Driver()
+		for id := range ids {
+			size := int64(-1)
+			if hostPath, err := driver.Get(id, ""); err == nil {
+				if size, err = utils.TreeSize(hostPath); err != nil {
+					size = -1
+				}
+				driver.Put(id)
+			}
+			volumes = append(volumes, &volumeDiskUsage{
+				Name:       id,
+				Size:       size,
+				Containers: volumeUsers[id],
+			})
+		}
+	}
+
+	for _, name := range daemon.namedVolumes.List() {
+		driverName, _ := daemon.namedVolumes.Get(name)
+		size := int64(-1)
+		if driverName == volumedriver.LocalDriverName {
+			if mountpoint, err := daemon.namedVolumes.local.Path(name); err == nil {
+				if size, err = utils.TreeSize(mountpoint); err != nil {
+					size = -1
+				}
+			}
+		}
+		volumes = append(volumes, &volumeDiskUsage{
+			Name:       name,
+			Driver:     driverName,
+			Size:       size,
+			Containers: len(daemon.volumeUsedBy(name)),
+		})
+	}
+	return volumes
+}
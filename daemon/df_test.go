@@ -0,0 +1,29 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/docker/docker/image"
+)
+
+func TestImageSizes(t *testing.T) {
+	layers := map[string]*image.Image{
+		"base":   {ID: "base", Size: 100},
+		"python": {ID: "python", Parent: "base", Size: 20},
+		"web":    {ID: "web", Parent: "python", Size: 3},
+		"worker": {ID: "worker", Parent: "python", Size: 5},
+		"alpine": {ID: "alpine", Size: 7},
+	}
+	sizes := imageSizes([]string{"web", "worker", "alpine"}, layers)
+
+	expected := map[string][2]int64{
+		"web":    {123, 120},
+		"worker": {125, 120},
+		"alpine": {7, 0},
+	}
+	for id, size := range expected {
+		if sizes[id] != size {
+			t.Fatalf("Expected the size of %s to be %v, got %v", id, size, sizes[id])
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A dockerfileLine is an instruction of a Dockerfile, with its continuation
+// lines joined, and the number of the line it starts at.
+type dockerfileLine struct {
+	number int
+	text   string
+}
+
+// A dockerfileError is a syntax error of a Dockerfile, at a line and a
+// column counted from 1.
+type dockerfileError struct {
+	line   int
+	column int
+	msg    string
+}
+
+func (e *dockerfileError) Error() string {
+	return fmt.Sprintf("Dockerfile line %d, column %d: %s", e.line, e.column, e.msg)
+}
+
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+// parseDockerfile splits a Dockerfile into its instructions. It accepts the
+// CRLF line endings, the UTF-8 byte order mark and the trailing whitespace
+// which the Windows editors write. The blank lines and the comments, whose
+// first non-blank character is #, are skipped, also between the
+// continuation lines of an instruction, and a line ending with \ continues
+// on the next one.
+func parseDockerfile(data []byte) ([]dockerfileLine, error) {
+	var (
+		lines   []dockerfileLine
+		current *dockerfileLine
+		indent  int
+	)
+	end := func() error {
+		text := strings.TrimSpace(current.text)
+		if err := checkInstruction(text, current.number, indent+1); err != nil {
+			return err
+		}
+		lines = append(lines, dockerfileLine{number: current.number, text: text})
+		current = nil
+		return nil
+	}
+	for i, l := range strings.Split(string(bytes.TrimPrefix(data, utf8BOM)), "\n") {
+		l = strings.Replace(strings.TrimRightFunc(l, unicode.IsSpace), "\t", " ", -1)
+		trimmed := strings.TrimLeft(l, " ")
+		if trimmed == "" || trimmed[0] == '#' {
+			continue
+		}
+		if current == nil {
+			current = &dockerfileLine{number: i + 1}
+			indent = utf8.RuneCountInString(l) - utf8.RuneCountInString(trimmed)
+		}
+		if strings.HasSuffix(l, "\\") {
+			current.text += strings.TrimSuffix(l, "\\")
+			continue
+		}
+		current.text += l
+		if err := end(); err != nil {
+			return nil, err
+		}
+	}
+	if current != nil {
+		// the last instruction ends with a \ at the end of the file
+		if err := end(); err != nil {
+			return nil, err
+		}
+	}
+	return lines, nil
+}
+
+// checkInstruction checks that the instruction of text, which starts at
+// column of line, is a word of ASCII letters followed by its arguments.
+func checkInstruction(text string, line, column int) error {
+	parts := strings.SplitN(text, " ", 2)
+	for i, r := range []rune(parts[0]) {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return &dockerfileError{
+				line:   line,
+				column: column + i,
+				msg:    fmt.Sprintf("Invalid character %q in the instruction %q", r, parts[0]),
+			}
+		}
+	}
+	if len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+		return &dockerfileError{
+			line:   line,
+			column: column + utf8.RuneCountInString(parts[0]),
+			msg:    fmt.Sprintf("The instruction %s has no arguments", strings.ToUpper(parts[0])),
+		}
+	}
+	return nil
+}
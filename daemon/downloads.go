@@ -0,0 +1,224 @@
+package daemon
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// downloadCache keeps the files downloaded by the ADD instructions of the
+// builds, with the validators of the HTTP responses they came with, under
+// the root of the daemon. A file is only downloaded again when the server
+// says it changed, and never when it has the checksum the build expects.
+type downloadCache struct {
+	sync.Mutex
+	root string
+}
+
+// A download is the entry of an URL in the cache; the file itself is next
+// to its json file.
+type download struct {
+	URL          string
+	ETag         string
+	LastModified string
+	// Digest is the sha256 of the file, as sha256:HEX
+	Digest string
+}
+
+func newDownloadCache(root string) (*downloadCache, error) {
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return nil, err
+	}
+	return &downloadCache{root: root}, nil
+}
+
+// parseChecksum checks the --checksum of an ADD instruction, as ALGO:HEX.
+func parseChecksum(checksum string) (algorithm string, sum string, err error) {
+	parts := strings.SplitN(checksum, ":", 2)
+	if len(parts) != 2 || newChecksumHash(parts[0]) == nil {
+		return "", "", fmt.Errorf("Invalid checksum %s: expected sha256:HEX or sha512:HEX", checksum)
+	}
+	if _, err := hex.DecodeString(parts[1]); err != nil || len(parts[1]) != 2*newChecksumHash(parts[0]).Size() {
+		return "", "", fmt.Errorf("Invalid checksum %s: expected sha256:HEX or sha512:HEX", checksum)
+	}
+	return parts[0], strings.ToLower(parts[1]), nil
+}
+
+func newChecksumHash(algorithm string) hash.Hash {
+	switch algorithm {
+	case "sha256":
+		return sha256.New()
+	case "sha512":
+		return sha512.New()
+	}
+	return nil
+}
+
+func (c *downloadCache) paths(rawurl string) (data, meta string) {
+	key := sha256.Sum256([]byte(rawurl))
+	name := hex.EncodeToString(key[:])
+	return path.Join(c.root, name), path.Join(c.root, name+".json")
+}
+
+// lookup returns the entry of rawurl, or nil if the cache does not have it.
+func (c *downloadCache) lookup(rawurl string) *download {
+	data, meta := c.paths(rawurl)
+	jsonData, err := ioutil.ReadFile(meta)
+	if err != nil {
+		return nil
+	}
+	d := &download{}
+	if err := json.Unmarshal(jsonData, d); err != nil || d.URL != rawurl {
+		return nil
+	}
+	if _, err := os.Stat(data); err != nil {
+		return nil
+	}
+	return d
+}
+
+// fetch copies the file at rawurl to dest, downloading it unless the cache
+// has it with the given checksum, as validated by parseChecksum, or the
+// server answers that the cached file did not change. A downloaded file
+// which does not have the checksum is an error.
+func (c *downloadCache) fetch(rawurl, checksum, dest string) error {
+	data, meta := c.paths(rawurl)
+
+	c.Lock()
+	cached := c.lookup(rawurl)
+	if cached != nil && checksum != "" {
+		if ok, err := hasChecksum(data, checksum); err == nil && ok {
+			err := copyFile(data, dest)
+			c.Unlock()
+			return err
+		}
+	}
+	c.Unlock()
+
+	req, err := http.NewRequest("GET", rawurl, nil)
+	if err != nil {
+		return err
+	}
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		c.Lock()
+		defer c.Unlock()
+		if d := c.lookup(rawurl); d == nil || d.Digest != cached.Digest {
+			return fmt.Errorf("The cached download of %s changed while validating it, try again", rawurl)
+		}
+		if err := verifyChecksum(rawurl, data, checksum); err != nil {
+			return err
+		}
+		return copyFile(data, dest)
+	}
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("Got HTTP status code >= 400: %s", resp.Status)
+	}
+
+	tmp, err := ioutil.TempFile(c.root, "download-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	digest := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, digest), resp.Body)
+	tmp.Close()
+	if err != nil {
+		return err
+	}
+	if err := verifyChecksum(rawurl, tmp.Name(), checksum); err != nil {
+		return err
+	}
+
+	jsonData, err := json.Marshal(&download{
+		URL:          rawurl,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Digest:       "sha256:" + hex.EncodeToString(digest.Sum(nil)),
+	})
+	if err != nil {
+		return err
+	}
+	c.Lock()
+	defer c.Unlock()
+	if err := os.Rename(tmp.Name(), data); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(meta, jsonData, 0600); err != nil {
+		return err
+	}
+	return copyFile(data, dest)
+}
+
+// verifyChecksum returns an error if the file downloaded from rawurl does
+// not have checksum, if not empty.
+func verifyChecksum(rawurl, file, checksum string) error {
+	if checksum == "" {
+		return nil
+	}
+	ok, err := hasChecksum(file, checksum)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("The file downloaded from %s does not have the checksum %s", rawurl, checksum)
+	}
+	return nil
+}
+
+func hasChecksum(file, checksum string) (bool, error) {
+	algorithm, sum, err := parseChecksum(checksum)
+	if err != nil {
+		return false, err
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	h := newChecksumHash(algorithm)
+	if _, err := io.Copy(h, f); err != nil {
+		return false, err
+	}
+	return hex.EncodeToString(h.Sum(nil)) == sum, nil
+}
+
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,102 @@
+package daemon
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestDownloadCache(t *testing.T) {
+	var (
+		content   = "hello"
+		downloads = 0
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := fmt.Sprintf("%q", content)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set("ETag", etag)
+		fmt.Fprint(w, content)
+	}))
+	defer server.Close()
+
+	root, err := ioutil.TempDir("", "docker-test-downloads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	cache, err := newDownloadCache(path.Join(root, "downloads"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	fetch := func(checksum, expected string) error {
+		n++
+		dest := path.Join(root, fmt.Sprintf("dest%d", n))
+		if err := cache.fetch(server.URL+"/file", checksum, dest); err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(dest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != expected {
+			t.Fatalf("Expected %q, got %q", expected, data)
+		}
+		return nil
+	}
+	sum := func(s string) string {
+		h := sha256.Sum256([]byte(s))
+		return "sha256:" + hex.EncodeToString(h[:])
+	}
+
+	if err := fetch("", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fetch("", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if downloads != 1 {
+		t.Fatalf("Expected the unchanged file to be downloaded once, got %d downloads", downloads)
+	}
+
+	content = "world"
+	if err := fetch(sum("hello"), "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if downloads != 1 {
+		t.Fatal("Expected the file with the expected checksum to be taken from the cache")
+	}
+	if err := fetch("", "world"); err != nil {
+		t.Fatal(err)
+	}
+	if downloads != 2 {
+		t.Fatalf("Expected the changed file to be downloaded again, got %d downloads", downloads)
+	}
+	content = "corrupted"
+	if err := fetch(sum("other"), ""); err == nil {
+		t.Fatal("Expected an error for a file without the expected checksum")
+	}
+}
+
+func TestParseChecksum(t *testing.T) {
+	h := sha256.Sum256([]byte("hello"))
+	valid := "sha256:" + hex.EncodeToString(h[:])
+	if _, _, err := parseChecksum(valid); err != nil {
+		t.Fatal(err)
+	}
+	for _, invalid := range []string{"", "sha256", "md5:5d41402abc4b2a76b9719d911017c592", "sha256:abc", "sha512:" + valid[len("sha256:"):]} {
+		if _, _, err := parseChecksum(invalid); err == nil {
+			t.Fatalf("Expected %q to be an invalid checksum", invalid)
+		}
+	}
+}
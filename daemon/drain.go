@@ -0,0 +1,139 @@
+package daemon
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/pkg/log"
+)
+
+// drainState tracks whether the daemon has been put in maintenance mode
+// and the progress of stopping its containers.
+type drainState struct {
+	sync.Mutex
+	active  bool
+	started time.Time
+	pending map[string]bool // containers still being stopped
+	stopped int
+	failed  []string
+}
+
+func (s *drainState) IsActive() bool {
+	s.Lock()
+	defer s.Unlock()
+	return s.active
+}
+
+func (daemon *Daemon) checkNotDraining() error {
+	if daemon.drain.IsActive() {
+		return fmt.Errorf("Daemon is draining, new containers cannot be created or started")
+	}
+	return nil
+}
+
+// ContainerDrain puts the daemon in maintenance mode: create and start
+// requests are refused from now on. If "stop" is set, running containers
+// are stopped in the background, each given "t" seconds before being killed.
+func (daemon *Daemon) ContainerDrain(job *engine.Job) engine.Status {
+	var (
+		stop = job.GetenvBool("stop")
+		t    = 10
+	)
+	if job.EnvExists("t") {
+		t = job.GetenvInt("t")
+	}
+
+	s := daemon.drain
+	s.Lock()
+	if !s.active {
+		s.active = true
+		s.started = time.Now().UTC()
+		s.pending = make(map[string]bool)
+		s.stopped = 0
+		s.failed = nil
+	}
+	var toStop []*Container
+	if stop {
+		for _, container := range daemon.List() {
+			if container.State.IsRunning() && !s.pending[container.ID] {
+				s.pending[container.ID] = true
+				toStop = append(toStop, container)
+			}
+		}
+	}
+	s.Unlock()
+
+	for _, c := range toStop {
+		go daemon.drainContainer(c, t)
+	}
+	return engine.StatusOK
+}
+
+func (daemon *Daemon) drainContainer(container *Container, seconds int) {
+	err := container.Stop(seconds)
+	if err == nil {
+		container.LogEvent("stop")
+	} else {
+		log.Errorf("Cannot stop container %s while draining: %s", container.ID, err)
+	}
+
+	s := daemon.drain
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.pending[container.ID]; !ok {
+		// the drain was cancelled in the meantime
+		return
+	}
+	delete(s.pending, container.ID)
+	if err != nil {
+		s.failed = append(s.failed, container.ID)
+	} else {
+		s.stopped++
+	}
+}
+
+// ContainerUndrain takes the daemon out of maintenance mode.
+func (daemon *Daemon) ContainerUndrain(job *engine.Job) engine.Status {
+	s := daemon.drain
+	s.Lock()
+	if !s.active {
+		s.Unlock()
+		return job.Errorf("Daemon is not draining")
+	}
+	s.active = false
+	s.pending = nil
+	s.Unlock()
+	return engine.StatusOK
+}
+
+// ContainerDrainStatus reports whether the daemon is draining and how far
+// along stopping its containers is.
+func (daemon *Daemon) ContainerDrainStatus(job *engine.Job) engine.Status {
+	running := 0
+	for _, container := range daemon.List() {
+		if container.State.IsRunning() {
+			running++
+		}
+	}
+
+	s := daemon.drain
+	s.Lock()
+	out := &engine.Env{}
+	out.SetBool("Draining", s.active)
+	if s.active {
+		out.Set("Started", s.started.Format(time.RFC3339Nano))
+		out.SetInt("Pending", len(s.pending))
+		out.SetInt("Stopped", s.stopped)
+		out.SetList("Failed", s.failed)
+	}
+	out.SetInt("Running", running)
+	out.SetBool("Done", s.active && len(s.pending) == 0)
+	s.Unlock()
+
+	if _, err := out.WriteTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
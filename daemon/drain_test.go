@@ -0,0 +1,64 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/docker/docker/engine"
+)
+
+func TestDrainStatus(t *testing.T) {
+	eng := engine.New()
+	eng.Logging = false
+	daemon := &Daemon{
+		containers: &contStore{s: make(map[string]*Container)},
+		drain:      &drainState{},
+	}
+	for name, handler := range map[string]engine.Handler{
+		"drain":        daemon.ContainerDrain,
+		"drain_status": daemon.ContainerDrainStatus,
+		"undrain":      daemon.ContainerUndrain,
+	} {
+		if err := eng.Register(name, handler); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	status := func() *engine.Env {
+		job := eng.Job("drain_status")
+		out, err := job.Stdout.AddEnv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := job.Run(); err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	if status().GetBool("Draining") || daemon.checkNotDraining() != nil {
+		t.Fatal("Daemon should not be draining")
+	}
+	if err := eng.Job("undrain").Run(); err == nil {
+		t.Fatal("Expected an error when cancelling a drain that was not started")
+	}
+
+	job := eng.Job("drain")
+	job.SetenvBool("stop", true)
+	if err := job.Run(); err != nil {
+		t.Fatal(err)
+	}
+	out := status()
+	if !out.GetBool("Draining") || !out.GetBool("Done") {
+		t.Fatalf("Expected a finished drain, got %v", out)
+	}
+	if daemon.checkNotDraining() == nil {
+		t.Fatal("Creating containers should be refused while draining")
+	}
+
+	if err := eng.Job("undrain").Run(); err != nil {
+		t.Fatal(err)
+	}
+	if status().GetBool("Draining") {
+		t.Fatal("Daemon should have left maintenance mode")
+	}
+}
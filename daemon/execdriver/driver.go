@@ -6,6 +6,9 @@ import (
 	"os"
 	"os/exec"
 
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/docker/docker/pkg/seccomp"
+	"github.com/docker/docker/pkg/ulimit"
 	"github.com/docker/libcontainer/devices"
 )
 
@@ -22,6 +25,9 @@ var (
 
 type StartCallback func(*Command)
 
+// OOMCallback is called each time a container hits its memory limit
+type OOMCallback func(*Command)
+
 // Driver specific information based on
 // processes registered with the driver
 type Info interface {
@@ -49,6 +55,7 @@ type Driver interface {
 	Info(id string) Info                          // "temporary" hack (until we move state from core to plugins)
 	GetPidsForContainer(id string) ([]int, error) // Returns a list of pids for the given container.
 	Terminate(c *Command) error                   // kill it with fire
+	Update(c *Command) error                      // apply the resources of c to the running container
 }
 
 // Network settings of the container
@@ -64,13 +71,24 @@ type NetworkInterface struct {
 	IPAddress   string `json:"ip"`
 	Bridge      string `json:"bridge"`
 	IPPrefixLen int    `json:"ip_prefix_len"`
+	// HairpinMode lets the container reach its own published ports, which
+	// iptables forwards back to it when there is no userland proxy
+	HairpinMode bool `json:"hairpin_mode"`
 }
 
 type Resources struct {
-	Memory     int64  `json:"memory"`
-	MemorySwap int64  `json:"memory_swap"`
-	CpuShares  int64  `json:"cpu_shares"`
-	Cpuset     string `json:"cpuset"`
+	Memory      int64            `json:"memory"`
+	MemorySwap  int64            `json:"memory_swap"`
+	CpuShares   int64            `json:"cpu_shares"`
+	CpuQuota    int64            `json:"cpu_quota"`
+	CpuPeriod   int64            `json:"cpu_period"`
+	Cpuset      string           `json:"cpuset"`
+	BlkioWeight int64            `json:"blkio_weight"`
+	Ulimits     []*ulimit.Ulimit `json:"ulimits"`
+	// MemorySwappiness is the swappiness of the memory cgroup, the one of
+	// the host when nil
+	MemorySwappiness *int64 `json:"memory_swappiness"`
+	OomKillDisable   bool   `json:"oom_kill_disable"` // pause the processes rather than kill them at the memory limit
 }
 
 type Mount struct {
@@ -102,10 +120,22 @@ type Command struct {
 	AutoCreatedDevices []*devices.Device   `json:"autocreated_devices"`
 	CapAdd             []string            `json:"cap_add"`
 	CapDrop            []string            `json:"cap_drop"`
-
-	Terminal     Terminal `json:"-"`             // standard or tty terminal
-	Console      string   `json:"-"`             // dev/console path
-	ContainerPid int      `json:"container_pid"` // the pid for the process inside a container
+	ReadonlyRootfs     bool                `json:"readonly_rootfs"`
+	Tmpfs              map[string]string   `json:"tmpfs"`            // mount options keyed by destination
+	HostPid            bool                `json:"host_pid"`         // share the PID namespace of the host
+	HostIpc            bool                `json:"host_ipc"`         // share the IPC namespace of the host
+	HostUts            bool                `json:"host_uts"`         // share the UTS namespace of the host
+	PidContainerID     string              `json:"pid_container_id"` // id of the container to join the PID namespace of
+	IpcContainerID     string              `json:"ipc_container_id"` // id of the container to join the IPC namespace of
+	UidMappings        []idtools.IDMap     `json:"uid_mappings"`     // run in a user namespace with these uids when set
+	GidMappings        []idtools.IDMap     `json:"gid_mappings"`
+	Seccomp            *seccomp.Profile    `json:"seccomp"`       // filter of the system calls, none when nil
+	CgroupParent       string              `json:"cgroup_parent"` // cgroup or systemd slice to create the cgroup in, the default of the driver when empty
+
+	Terminal     Terminal    `json:"-"`             // standard or tty terminal
+	Console      string      `json:"-"`             // dev/console path
+	ContainerPid int         `json:"container_pid"` // the pid for the process inside a container
+	OnOOM        OOMCallback `json:"-"`             // only called by drivers able to detect out of memory events
 }
 
 // Return the pid of the process
@@ -19,6 +19,7 @@ import (
 	"github.com/docker/docker/daemon/execdriver"
 	"github.com/docker/docker/pkg/log"
 	"github.com/docker/docker/pkg/term"
+	"github.com/docker/docker/pkg/ulimit"
 	"github.com/docker/docker/utils"
 	"github.com/docker/libcontainer/cgroups"
 	"github.com/docker/libcontainer/label"
@@ -59,6 +60,19 @@ func (d *driver) Run(c *execdriver.Command, pipes *execdriver.Pipes, startCallba
 		err  error
 	)
 
+	if c.HostPid || c.HostIpc || c.HostUts {
+		return -1, fmt.Errorf("The lxc driver does not share the PID, IPC or UTS namespace of the host, use the native driver")
+	}
+	if c.PidContainerID != "" || c.IpcContainerID != "" {
+		return -1, fmt.Errorf("The lxc driver does not join the PID or IPC namespace of another container, use the native driver")
+	}
+	if c.UidMappings != nil {
+		return -1, fmt.Errorf("The lxc driver does not run the containers in a user namespace, use the native driver")
+	}
+	if c.CgroupParent != "" {
+		return -1, fmt.Errorf("The lxc driver does not create the cgroups of the containers in another cgroup, use the native driver")
+	}
+
 	if c.Tty {
 		term, err = NewTtyConsole(c, pipes)
 	} else {
@@ -122,6 +136,10 @@ func (d *driver) Run(c *execdriver.Command, pipes *execdriver.Pipes, startCallba
 		params = append(params, fmt.Sprintf("-cap-drop=%s", strings.Join(c.CapDrop, ":")))
 	}
 
+	if c.Resources != nil && len(c.Resources.Ulimits) > 0 {
+		params = append(params, "-ulimits", ulimit.FormatList(c.Resources.Ulimits))
+	}
+
 	params = append(params, "--", c.Entrypoint)
 	params = append(params, c.Arguments...)
 
@@ -236,6 +254,16 @@ func (d *driver) Terminate(c *execdriver.Command) error {
 	return KillLxc(c.ID, 9)
 }
 
+func (d *driver) Update(c *execdriver.Command) error {
+	return execdriver.UpdateResources(c.Resources, func(subsystem, file, value string) error {
+		output, err := exec.Command("lxc-cgroup", "-n", c.ID, file, value).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("Err: %s Output: %s", err, output)
+		}
+		return nil
+	})
+}
+
 func (d *driver) version() string {
 	var (
 		version string
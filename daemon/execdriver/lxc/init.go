@@ -13,6 +13,7 @@ import (
 	"strings"
 	"syscall"
 
+	"github.com/docker/docker/pkg/ulimit"
 	"github.com/docker/docker/reexec"
 	"github.com/docker/libcontainer/netlink"
 )
@@ -32,6 +33,7 @@ type InitArgs struct {
 	Root       string
 	CapAdd     string
 	CapDrop    string
+	Ulimits    string
 }
 
 func init() {
@@ -65,6 +67,10 @@ func setupNamespace(args *InitArgs) error {
 	if err := setupNetworking(args); err != nil {
 		return err
 	}
+	// set the ulimits while CAP_SYS_RESOURCE is still there
+	if err := ulimit.ApplyList(args.Ulimits); err != nil {
+		return err
+	}
 	if err := finalizeNamespace(args); err != nil {
 		return err
 	}
@@ -93,6 +99,7 @@ func getArgs() *InitArgs {
 		mtu        = flag.Int("mtu", 1500, "interface mtu")
 		capAdd     = flag.String("cap-add", "", "capabilities to add")
 		capDrop    = flag.String("cap-drop", "", "capabilities to drop")
+		ulimits    = flag.String("ulimits", "", "ulimits to set")
 	)
 
 	flag.Parse()
@@ -107,6 +114,7 @@ func getArgs() *InitArgs {
 		Mtu:        *mtu,
 		CapAdd:     *capAdd,
 		CapDrop:    *capDrop,
+		Ulimits:    *ulimits,
 	}
 }
 
@@ -1,6 +1,7 @@
 package lxc
 
 import (
+	"path"
 	"strings"
 	"text/template"
 
@@ -27,6 +28,9 @@ lxc.network.mtu = {{.Network.Mtu}}
 # root filesystem
 {{$ROOTFS := .Rootfs}}
 lxc.rootfs = {{$ROOTFS}}
+{{if .ReadonlyRootfs}}
+lxc.rootfs.options = ro
+{{end}}
 
 # use a dedicated pts for the container (and limit the number of pseudo terminal
 # available)
@@ -73,6 +77,10 @@ lxc.mount.entry = {{.Console}} {{escapeFstabSpaces $ROOTFS}}/dev/console none bi
 lxc.mount.entry = devpts {{escapeFstabSpaces $ROOTFS}}/dev/pts devpts {{formatMountLabel "newinstance,ptmxmode=0666,nosuid,noexec" $MOUNTLABEL}} 0 0
 lxc.mount.entry = shm {{escapeFstabSpaces $ROOTFS}}/dev/shm tmpfs {{formatMountLabel "size=65536k,nosuid,nodev,noexec" $MOUNTLABEL}} 0 0
 
+{{range $dest, $options := .Tmpfs}}
+lxc.mount.entry = tmpfs {{escapeFstabSpaces (joinPath $ROOTFS $dest)}} tmpfs {{formatMountLabel $options $MOUNTLABEL}},create=dir 0 0
+{{end}}
+
 {{range $value := .Mounts}}
 {{if $value.Writable}}
 lxc.mount.entry = {{$value.Source}} {{escapeFstabSpaces $ROOTFS}}/{{escapeFstabSpaces $value.Destination}} none rbind,rw 0 0
@@ -101,9 +109,24 @@ lxc.cgroup.memory.memsw.limit_in_bytes = {{$memSwap}}
 {{if .Resources.CpuShares}}
 lxc.cgroup.cpu.shares = {{.Resources.CpuShares}}
 {{end}}
+{{if .Resources.CpuPeriod}}
+lxc.cgroup.cpu.cfs_period_us = {{.Resources.CpuPeriod}}
+{{end}}
+{{if .Resources.CpuQuota}}
+lxc.cgroup.cpu.cfs_quota_us = {{.Resources.CpuQuota}}
+{{end}}
 {{if .Resources.Cpuset}}
 lxc.cgroup.cpuset.cpus = {{.Resources.Cpuset}}
 {{end}}
+{{if .Resources.BlkioWeight}}
+lxc.cgroup.blkio.weight = {{.Resources.BlkioWeight}}
+{{end}}
+{{with .Resources.MemorySwappiness}}
+lxc.cgroup.memory.swappiness = {{.}}
+{{end}}
+{{if .Resources.OomKillDisable}}
+lxc.cgroup.memory.oom_control = 1
+{{end}}
 {{end}}
 
 {{if .Config.lxc}}
@@ -122,12 +145,7 @@ func escapeFstabSpaces(field string) string {
 }
 
 func getMemorySwap(v *execdriver.Resources) int64 {
-	// By default, MemorySwap is set to twice the size of RAM.
-	// If you want to omit MemorySwap, set it to `-1'.
-	if v.MemorySwap < 0 {
-		return 0
-	}
-	return v.Memory * 2
+	return execdriver.MemorySwapLimit(v)
 }
 
 func getLabel(c map[string][]string, name string) string {
@@ -147,6 +165,7 @@ func init() {
 		"getMemorySwap":     getMemorySwap,
 		"escapeFstabSpaces": escapeFstabSpaces,
 		"formatMountLabel":  label.FormatMountLabel,
+		"joinPath":          path.Join,
 	}
 	LxcTemplateCompiled, err = template.New("lxc").Funcs(funcMap).Parse(LxcTemplate)
 	if err != nil {
@@ -121,6 +121,97 @@ func grepFile(t *testing.T, path string, pattern string) {
 	t.Fatalf("grepFile: pattern \"%s\" not found in \"%s\"", pattern, path)
 }
 
+func TestLXCConfigCpuQuotaAndBlkioWeight(t *testing.T) {
+	root, err := ioutil.TempDir("", "TestLXCConfigCpuQuotaAndBlkioWeight")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	os.MkdirAll(path.Join(root, "containers", "1"), 0777)
+
+	driver, err := NewDriver(root, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	command := &execdriver.Command{
+		ID: "1",
+		Resources: &execdriver.Resources{
+			CpuQuota:    50000,
+			CpuPeriod:   100000,
+			BlkioWeight: 300,
+		},
+		Network: &execdriver.Network{
+			Mtu:       1500,
+			Interface: nil,
+		},
+	}
+	p, err := driver.generateLXCConfig(command)
+	if err != nil {
+		t.Fatal(err)
+	}
+	grepFile(t, p, "lxc.cgroup.cpu.cfs_quota_us = 50000")
+	grepFile(t, p, "lxc.cgroup.cpu.cfs_period_us = 100000")
+	grepFile(t, p, "lxc.cgroup.blkio.weight = 300")
+}
+
+func TestLXCConfigReadonlyRootfs(t *testing.T) {
+	root, err := ioutil.TempDir("", "TestLXCConfigReadonlyRootfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	os.MkdirAll(path.Join(root, "containers", "1"), 0777)
+
+	driver, err := NewDriver(root, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	command := &execdriver.Command{
+		ID: "1",
+		Network: &execdriver.Network{
+			Mtu:       1500,
+			Interface: nil,
+		},
+		ReadonlyRootfs: true,
+	}
+	p, err := driver.generateLXCConfig(command)
+	if err != nil {
+		t.Fatal(err)
+	}
+	grepFile(t, p, "lxc.rootfs.options = ro")
+}
+
+func TestLXCConfigTmpfs(t *testing.T) {
+	root, err := ioutil.TempDir("", "TestLXCConfigTmpfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	os.MkdirAll(path.Join(root, "containers", "1"), 0777)
+
+	driver, err := NewDriver(root, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	command := &execdriver.Command{
+		ID:     "1",
+		Rootfs: "/var/lib/docker/rootfs",
+		Network: &execdriver.Network{
+			Mtu:       1500,
+			Interface: nil,
+		},
+		Tmpfs: map[string]string{"/run": "rw,size=64m"},
+	}
+	p, err := driver.generateLXCConfig(command)
+	if err != nil {
+		t.Fatal(err)
+	}
+	grepFile(t, p, "lxc.mount.entry = tmpfs /var/lib/docker/rootfs/run tmpfs rw,size=64m,create=dir 0 0")
+}
+
 func TestEscapeFstabSpaces(t *testing.T) {
 	var testInputs = map[string]string{
 		" ":                      "\\040",
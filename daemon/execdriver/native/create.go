@@ -7,12 +7,14 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
 	"github.com/docker/docker/daemon/execdriver"
 	"github.com/docker/docker/daemon/execdriver/native/configuration"
 	"github.com/docker/docker/daemon/execdriver/native/template"
 	"github.com/docker/libcontainer"
 	"github.com/docker/libcontainer/apparmor"
+	"github.com/docker/libcontainer/cgroups/systemd"
 	"github.com/docker/libcontainer/devices"
 	"github.com/docker/libcontainer/mount"
 	"github.com/docker/libcontainer/security/capabilities"
@@ -34,8 +36,22 @@ func (d *driver) createContainer(c *execdriver.Command) (*libcontainer.Config, e
 
 	// check to see if we are running in ramdisk to disable pivot root
 	container.MountConfig.NoPivotRoot = os.Getenv("DOCKER_RAMDISK") != ""
+	container.MountConfig.ReadonlyFs = c.ReadonlyRootfs
 	container.RestrictSys = true
 
+	// the joined namespaces are entered by the init of the container
+	if c.HostPid || c.PidContainerID != "" {
+		container.Namespaces["NEWPID"] = false
+	}
+	if c.HostIpc || c.IpcContainerID != "" {
+		container.Namespaces["NEWIPC"] = false
+	}
+	if c.HostUts {
+		container.Namespaces["NEWUTS"] = false
+		// the hostname of the host must not be changed
+		container.Hostname = ""
+	}
+
 	if err := d.createNetwork(container, c); err != nil {
 		return nil, err
 	}
@@ -58,6 +74,10 @@ func (d *driver) createContainer(c *execdriver.Command) (*libcontainer.Config, e
 		return nil, err
 	}
 
+	if c.UidMappings != nil {
+		d.setupUserNamespace(container, c)
+	}
+
 	if err := d.setupLabels(container, c); err != nil {
 		return nil, err
 	}
@@ -104,16 +124,10 @@ func (d *driver) createNetwork(container *libcontainer.Config, c *execdriver.Com
 	}
 
 	if c.Network.ContainerID != "" {
-		d.Lock()
-		active := d.activeContainers[c.Network.ContainerID]
-		d.Unlock()
-
-		if active == nil || active.cmd.Process == nil {
-			return fmt.Errorf("%s is not a valid running container to join", c.Network.ContainerID)
+		nspath, err := d.namespacePath(c.Network.ContainerID, "net")
+		if err != nil {
+			return err
 		}
-		cmd := active.cmd
-
-		nspath := filepath.Join("/proc", fmt.Sprint(cmd.Process.Pid), "ns", "net")
 		container.Networks = append(container.Networks, &libcontainer.Network{
 			Type:   "netns",
 			NsPath: nspath,
@@ -123,6 +137,40 @@ func (d *driver) createNetwork(container *libcontainer.Config, c *execdriver.Com
 	return nil
 }
 
+// namespacePath returns the path of the namespace ns, e.g. net, of the
+// running container id.
+func (d *driver) namespacePath(id, ns string) (string, error) {
+	d.Lock()
+	active := d.activeContainers[id]
+	d.Unlock()
+
+	if active == nil || active.cmd.Process == nil {
+		return "", fmt.Errorf("%s is not a valid running container to join", id)
+	}
+	return filepath.Join("/proc", fmt.Sprint(active.cmd.Process.Pid), "ns", ns), nil
+}
+
+// joinNamespaceParams returns the parameters of the init telling it the PID
+// and IPC namespaces of other containers to join.
+func (d *driver) joinNamespaceParams(c *execdriver.Command) ([]string, error) {
+	var params []string
+	if c.PidContainerID != "" {
+		nspath, err := d.namespacePath(c.PidContainerID, "pid")
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, "-join-pid", nspath)
+	}
+	if c.IpcContainerID != "" {
+		nspath, err := d.namespacePath(c.IpcContainerID, "ipc")
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, "-join-ipc", nspath)
+	}
+	return params, nil
+}
+
 func (d *driver) setPrivileged(container *libcontainer.Config) (err error) {
 	container.Capabilities = capabilities.GetAllCapabilities()
 	container.Cgroups.AllowAllDevices = true
@@ -150,12 +198,26 @@ func (d *driver) setCapabilities(container *libcontainer.Config, c *execdriver.C
 func (d *driver) setupCgroups(container *libcontainer.Config, c *execdriver.Command) error {
 	if c.Resources != nil {
 		container.Cgroups.CpuShares = c.Resources.CpuShares
+		container.Cgroups.CpuQuota = c.Resources.CpuQuota
+		container.Cgroups.CpuPeriod = c.Resources.CpuPeriod
 		container.Cgroups.Memory = c.Resources.Memory
 		container.Cgroups.MemoryReservation = c.Resources.Memory
 		container.Cgroups.MemorySwap = c.Resources.MemorySwap
 		container.Cgroups.CpusetCpus = c.Resources.Cpuset
 	}
 
+	if c.CgroupParent != "" {
+		if systemd.UseSystemd() {
+			// systemd only nests the scopes of the containers in slices
+			if !strings.HasSuffix(c.CgroupParent, ".slice") || strings.Contains(c.CgroupParent, "/") {
+				return fmt.Errorf("Invalid cgroup parent %s, with systemd it must be a slice (e.g. docker.slice)", c.CgroupParent)
+			}
+			container.Cgroups.Slice = c.CgroupParent
+		} else {
+			container.Cgroups.Parent = c.CgroupParent
+		}
+	}
+
 	return nil
 }
 
@@ -173,6 +235,22 @@ func (d *driver) setupMounts(container *libcontainer.Config, c *execdriver.Comma
 	return nil
 }
 
+// setupUserNamespace runs the container in a user namespace. Its root cannot
+// create device nodes there, the devices of the host are bind mounted
+// instead.
+func (d *driver) setupUserNamespace(container *libcontainer.Config, c *execdriver.Command) {
+	container.Namespaces["NEWUSER"] = true
+	for _, node := range container.MountConfig.DeviceNodes {
+		container.MountConfig.Mounts = append(container.MountConfig.Mounts, mount.Mount{
+			Type:        "bind",
+			Source:      node.Path,
+			Destination: node.Path,
+			Writable:    true,
+		})
+	}
+	container.MountConfig.DeviceNodes = nil
+}
+
 func (d *driver) setupLabels(container *libcontainer.Config, c *execdriver.Command) error {
 	container.ProcessLabel = c.Config["process_label"][0]
 	container.MountConfig.MountLabel = c.Config["mount_label"][0]
@@ -10,12 +10,17 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
 
 	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/pkg/seccomp"
 	"github.com/docker/docker/pkg/term"
+	"github.com/docker/docker/pkg/ulimit"
 	"github.com/docker/libcontainer"
 	"github.com/docker/libcontainer/apparmor"
 	"github.com/docker/libcontainer/cgroups/fs"
@@ -39,10 +44,14 @@ type driver struct {
 	root             string
 	initPath         string
 	activeContainers map[string]*activeContainer
+	netPool          *netPool
 	sync.Mutex
 }
 
-func NewDriver(root, initPath string) (*driver, error) {
+// NewDriver returns the native driver. When netPoolSize is not 0, the driver
+// keeps this many network namespaces ready for the containers attached to
+// the bridge, to speed up their start.
+func NewDriver(root, initPath string, netPoolSize int) (*driver, error) {
 	if err := os.MkdirAll(root, 0700); err != nil {
 		return nil, err
 	}
@@ -52,11 +61,19 @@ func NewDriver(root, initPath string) (*driver, error) {
 		return nil, err
 	}
 
-	return &driver{
+	d := &driver{
 		root:             root,
 		initPath:         initPath,
 		activeContainers: make(map[string]*activeContainer),
-	}, nil
+	}
+	if netPoolSize > 0 {
+		pool, err := newNetPool(filepath.Join(root, "netns"), netPoolSize)
+		if err != nil {
+			return nil, err
+		}
+		d.netPool = pool
+	}
+	return d, nil
 }
 
 func (d *driver) Run(c *execdriver.Command, pipes *execdriver.Pipes, startCallback execdriver.StartCallback) (int, error) {
@@ -65,6 +82,10 @@ func (d *driver) Run(c *execdriver.Command, pipes *execdriver.Pipes, startCallba
 	if err != nil {
 		return -1, err
 	}
+	joinParams, err := d.joinNamespaceParams(c)
+	if err != nil {
+		return -1, err
+	}
 
 	var term execdriver.Terminal
 
@@ -95,24 +116,64 @@ func (d *driver) Run(c *execdriver.Command, pipes *execdriver.Pipes, startCallba
 	}
 	defer d.removeContainerRoot(c.ID)
 
+	if c.UidMappings != nil {
+		if err := chownToRemappedRoot(c, dataPath, c.Console); err != nil {
+			return -1, err
+		}
+	}
+
 	if err := d.writeContainerFile(container, c.ID); err != nil {
 		return -1, err
 	}
+	if c.Seccomp != nil {
+		if err := d.writeSeccompFile(c.Seccomp, c.ID); err != nil {
+			return -1, err
+		}
+	}
+
+	if len(c.Tmpfs) > 0 {
+		if err := d.writeTmpfsFile(c.Tmpfs, c.ID); err != nil {
+			return -1, err
+		}
+	}
+
+	var nspath string
+	// the namespaces of the pool belong to the user namespace of the host
+	if d.netPool != nil && c.UidMappings == nil {
+		if nspath, err = d.netPool.claim(container); err != nil {
+			log.Errorf("Error claiming a network namespace for %s, creating one: %s", c.ID, err)
+		}
+		defer d.netPool.release(nspath)
+	}
 
 	return namespaces.Exec(container, c.Stdin, c.Stdout, c.Stderr, c.Console, c.Rootfs, dataPath, args, func(container *libcontainer.Config, console, rootfs, dataPath, init string, child *os.File, args []string) *exec.Cmd {
 		c.Path = d.initPath
-		c.Args = append([]string{
+		params := []string{
 			DriverName,
 			"-console", console,
 			"-pipe", "3",
 			"-root", filepath.Join(d.root, c.ID),
-			"--",
-		}, args...)
+		}
+		if c.Resources != nil && len(c.Resources.Ulimits) > 0 {
+			params = append(params, "-ulimits", ulimit.FormatList(c.Resources.Ulimits))
+		}
+		if c.Seccomp != nil {
+			params = append(params, "-seccomp", filepath.Join(d.root, c.ID, "seccomp.json"))
+		}
+		if len(c.Tmpfs) > 0 {
+			params = append(params, "-tmpfs", filepath.Join(d.root, c.ID, "tmpfs.json"))
+		}
+		params = append(params, joinParams...)
+		c.Args = append(append(params, "--"), args...)
 
 		// set this to nil so that when we set the clone flags anything else is reset
 		c.SysProcAttr = &syscall.SysProcAttr{
-			Cloneflags: uintptr(namespaces.GetNamespaceFlags(container.Namespaces)),
+			Cloneflags:  uintptr(namespaces.GetNamespaceFlags(container.Namespaces)),
+			UidMappings: sysProcIDMaps(c.UidMappings),
+			GidMappings: sysProcIDMaps(c.GidMappings),
 		}
+		// the init sets the groups of the user of the container
+		c.SysProcAttr.GidMappingsEnableSetgroups = c.GidMappings != nil
 		c.ExtraFiles = []*os.File{child}
 
 		c.Env = container.Env
@@ -120,6 +181,36 @@ func (d *driver) Run(c *execdriver.Command, pipes *execdriver.Pipes, startCallba
 
 		return &c.Cmd
 	}, func() {
+		if c.Resources != nil && c.Resources.BlkioWeight != 0 {
+			if err := setBlkioWeight(dataPath, c.Resources.BlkioWeight); err != nil {
+				log.Errorf("Error setting the block IO weight of %s: %s", c.ID, err)
+			}
+		}
+		if c.Resources != nil && c.Resources.MemorySwap > 0 {
+			// libcontainer only sets the default swap limit
+			if err := writeCgroupFile(dataPath, "memory", "memory.memsw.limit_in_bytes", strconv.FormatInt(c.Resources.MemorySwap, 10)); err != nil {
+				log.Errorf("Error setting the memory swap limit of %s: %s", c.ID, err)
+			}
+		}
+		if c.Resources != nil && c.Resources.MemorySwappiness != nil {
+			// libcontainer does not manage the swappiness nor the OOM killer
+			if err := writeCgroupFile(dataPath, "memory", "memory.swappiness", strconv.FormatInt(*c.Resources.MemorySwappiness, 10)); err != nil {
+				log.Errorf("Error setting the memory swappiness of %s: %s", c.ID, err)
+			}
+		}
+		if c.Resources != nil && c.Resources.OomKillDisable {
+			if err := writeCgroupFile(dataPath, "memory", "memory.oom_control", "1"); err != nil {
+				log.Errorf("Error disabling the OOM killer of %s: %s", c.ID, err)
+			}
+		}
+		if c.OnOOM != nil {
+			notifyOnOOM(container, c)
+		}
+		if c.Network.Interface != nil && c.Network.Interface.HairpinMode {
+			if err := setHairpinMode(dataPath, nspath); err != nil {
+				log.Errorf("Error setting the hairpin mode of %s: %s", c.ID, err)
+			}
+		}
 		if startCallback != nil {
 			c.ContainerPid = c.Process.Pid
 			startCallback(c)
@@ -127,6 +218,87 @@ func (d *driver) Run(c *execdriver.Command, pipes *execdriver.Pipes, startCallba
 	})
 }
 
+func sysProcIDMaps(maps []idtools.IDMap) []syscall.SysProcIDMap {
+	var sysMaps []syscall.SysProcIDMap
+	for _, m := range maps {
+		sysMaps = append(sysMaps, syscall.SysProcIDMap{ContainerID: m.ContainerID, HostID: m.HostID, Size: m.Size})
+	}
+	return sysMaps
+}
+
+// chownToRemappedRoot gives the paths, when not empty, to the user of the
+// host the root of the user namespace of the container is mapped to.
+func chownToRemappedRoot(c *execdriver.Command, paths ...string) error {
+	uid, gid, err := idtools.RootPair(c.UidMappings, c.GidMappings)
+	if err != nil {
+		return err
+	}
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		if err := os.Chown(p, uid, gid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setHairpinMode lets the traffic of a started container come back through
+// its port of the bridge, for its own published ports. The host side of its
+// veth pair is named after the namespace of the pool at nspath, if any.
+func setHairpinMode(dataPath, nspath string) error {
+	veth := filepath.Base(nspath)
+	if nspath == "" {
+		state, err := libcontainer.GetState(dataPath)
+		if err != nil {
+			return err
+		}
+		veth = state.NetworkState.VethHost
+	}
+	if veth == "" {
+		return nil
+	}
+	return ioutil.WriteFile(filepath.Join("/sys/class/net", veth, "brport/hairpin_mode"), []byte{'1', '\n'}, 0644)
+}
+
+// setBlkioWeight writes the block IO weight of a started container in its
+// blkio cgroup, as libcontainer does not manage it.
+func setBlkioWeight(dataPath string, weight int64) error {
+	return writeCgroupFile(dataPath, "blkio", "blkio.weight", strconv.FormatInt(weight, 10))
+}
+
+// writeCgroupFile writes a value in a cgroup file of a running container.
+func writeCgroupFile(dataPath, subsystem, file, value string) error {
+	state, err := libcontainer.GetState(dataPath)
+	if err != nil {
+		return err
+	}
+	dir, exists := state.CgroupPaths[subsystem]
+	if !exists {
+		return fmt.Errorf("%s cgroup not found", subsystem)
+	}
+	return ioutil.WriteFile(filepath.Join(dir, file), []byte(value), 0644)
+}
+
+// notifyOnOOM calls the OnOOM callback of c each time the memory cgroup of
+// the container reaches its limit. Nothing is reported if the cgroup
+// cannot be watched.
+func notifyOnOOM(container *libcontainer.Config, c *execdriver.Command) {
+	if container.Cgroups == nil {
+		return
+	}
+	oom, err := fs.NotifyOnOOM(container.Cgroups)
+	if err != nil {
+		return
+	}
+	go func() {
+		for _ = range oom {
+			c.OnOOM(c)
+		}
+	}()
+}
+
 func (d *driver) Kill(p *execdriver.Command, sig int) error {
 	return syscall.Kill(p.Process.Pid, syscall.Signal(sig))
 }
@@ -191,6 +363,22 @@ func (d *driver) Terminate(p *execdriver.Command) error {
 
 }
 
+func (d *driver) Update(c *execdriver.Command) error {
+	d.Lock()
+	active := d.activeContainers[c.ID]
+	d.Unlock()
+	if active == nil {
+		return fmt.Errorf("active container for %s does not exist", c.ID)
+	}
+	dataPath := filepath.Join(d.root, c.ID)
+	if err := execdriver.UpdateResources(c.Resources, func(subsystem, file, value string) error {
+		return writeCgroupFile(dataPath, subsystem, file, value)
+	}); err != nil {
+		return err
+	}
+	return d.setupCgroups(active.container, c)
+}
+
 func (d *driver) Info(id string) execdriver.Info {
 	return &info{
 		ID:     id,
@@ -226,6 +414,26 @@ func (d *driver) writeContainerFile(container *libcontainer.Config, id string) e
 	return ioutil.WriteFile(filepath.Join(d.root, id, "container.json"), data, 0655)
 }
 
+// writeSeccompFile writes the seccomp profile the init compiles and loads
+// before executing the process of the container.
+func (d *driver) writeSeccompFile(profile *seccomp.Profile, id string) error {
+	data, err := json.Marshal(profile)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(d.root, id, "seccomp.json"), data, 0644)
+}
+
+// writeTmpfsFile writes the tmpfs requested for the container, which the
+// init mounts in the mount namespace of the container.
+func (d *driver) writeTmpfsFile(tmpfs map[string]string, id string) error {
+	data, err := json.Marshal(tmpfs)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(d.root, id, "tmpfs.json"), data, 0644)
+}
+
 func (d *driver) createContainerRoot(id string) error {
 	return os.MkdirAll(filepath.Join(d.root, id), 0655)
 }
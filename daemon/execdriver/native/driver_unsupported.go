@@ -8,6 +8,6 @@ import (
 	"github.com/docker/docker/daemon/execdriver"
 )
 
-func NewDriver(root, initPath string) (execdriver.Driver, error) {
+func NewDriver(root, initPath string, netPoolSize int) (execdriver.Driver, error) {
 	return nil, fmt.Errorf("native driver not supported on non-linux")
 }
@@ -6,14 +6,29 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"runtime"
+	"syscall"
 
+	"github.com/docker/docker/pkg/seccomp"
+	"github.com/docker/docker/pkg/ulimit"
 	"github.com/docker/docker/reexec"
 	"github.com/docker/libcontainer"
+	"github.com/docker/libcontainer/apparmor"
+	consolepkg "github.com/docker/libcontainer/console"
+	"github.com/docker/libcontainer/label"
+	"github.com/docker/libcontainer/mount"
 	"github.com/docker/libcontainer/namespaces"
+	"github.com/docker/libcontainer/netlink"
+	"github.com/docker/libcontainer/network"
+	"github.com/docker/libcontainer/security/restrict"
 	"github.com/docker/libcontainer/syncpipe"
+	"github.com/docker/libcontainer/system"
+	"github.com/docker/libcontainer/utils"
 )
 
 func init() {
@@ -27,10 +42,26 @@ func initializer() {
 		pipe    = flag.Int("pipe", 0, "sync pipe fd")
 		console = flag.String("console", "", "console (pty slave) path")
 		root    = flag.String("root", ".", "root path for configuration files")
+		ulimits = flag.String("ulimits", "", "ulimits to set")
+		joinPid = flag.String("join-pid", "", "path of the PID namespace to join")
+		joinIpc = flag.String("join-ipc", "", "path of the IPC namespace to join")
+		profile = flag.String("seccomp", "", "path of the seccomp profile to load")
+		tmpfs   = flag.String("tmpfs", "", "path of the tmpfs to mount")
 	)
 
 	flag.Parse()
 
+	// only the children enter a PID namespace: the init runs again in it
+	if *joinPid != "" {
+		args := []string{"-pipe", "3", "-console", *console, "-root", *root, "-ulimits", *ulimits, "-join-ipc", *joinIpc, "-seccomp", *profile, "-tmpfs", *tmpfs, "--"}
+		os.Exit(runInPidNamespace(*joinPid, os.NewFile(uintptr(*pipe), "pipe"), append(args, flag.Args()...)))
+	}
+	if *joinIpc != "" {
+		if err := enterNamespace(*joinIpc, syscall.CLONE_NEWIPC); err != nil {
+			writeError(err)
+		}
+	}
+
 	var container *libcontainer.Config
 	f, err := os.Open(filepath.Join(*root, "container.json"))
 	if err != nil {
@@ -53,13 +84,211 @@ func initializer() {
 		writeError(err)
 	}
 
-	if err := namespaces.Init(container, rootfs, *console, syncPipe, flag.Args()); err != nil {
+	// libcontainer does not know about ulimits, set them before it drops
+	// the capabilities
+	if err := ulimit.ApplyList(*ulimits); err != nil {
+		writeError(err)
+	}
+
+	var filter []syscall.SockFilter
+	if *profile != "" {
+		if filter, err = compileSeccompProfile(*profile); err != nil {
+			writeError(err)
+		}
+	}
+
+	var tmpfsMounts map[string]string
+	if *tmpfs != "" {
+		if tmpfsMounts, err = readTmpfsFile(*tmpfs); err != nil {
+			writeError(err)
+		}
+	}
+
+	if err := initContainer(container, rootfs, *console, syncPipe, flag.Args(), filter, tmpfsMounts); err != nil {
 		writeError(err)
 	}
 
 	panic("Unreachable")
 }
 
+// compileSeccompProfile compiles the seccomp profile written by the driver
+// at path.
+func compileSeccompProfile(path string) ([]syscall.SockFilter, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := seccomp.ParseProfile(data)
+	if err != nil {
+		return nil, err
+	}
+	return seccomp.Compile(profile)
+}
+
+// initContainer is namespaces.Init, step by step, with the tmpfs mounted
+// before the mount namespace is set up and the seccomp filter loaded once
+// the container is set up, as libcontainer knows about neither. The filter
+// is loaded before the capabilities are dropped, as it needs CAP_SYS_ADMIN,
+// so the profile must allow the system calls of
+// namespaces.FinalizeNamespace and execve.
+func initContainer(container *libcontainer.Config, uncleanRootfs, consolePath string, syncPipe *syncpipe.SyncPipe, args []string, filter []syscall.SockFilter, tmpfs map[string]string) (err error) {
+	defer func() {
+		if err != nil {
+			syncPipe.ReportChildError(err)
+		}
+	}()
+
+	rootfs, err := utils.ResolveRootfs(uncleanRootfs)
+	if err != nil {
+		return err
+	}
+	if err := namespaces.LoadContainerEnvironment(container); err != nil {
+		return err
+	}
+
+	// the parent sends the state of the network once it is set up
+	var networkState *network.NetworkState
+	if err := syncPipe.ReadFromParent(&networkState); err != nil {
+		return err
+	}
+
+	if consolePath != "" {
+		if err := consolepkg.OpenAndDup(consolePath); err != nil {
+			return err
+		}
+	}
+	if _, err := syscall.Setsid(); err != nil {
+		return fmt.Errorf("setsid %s", err)
+	}
+	if consolePath != "" {
+		if err := system.Setctty(); err != nil {
+			return fmt.Errorf("setctty %s", err)
+		}
+	}
+	for _, config := range container.Networks {
+		strategy, err := network.GetStrategy(config.Type)
+		if err != nil {
+			return fmt.Errorf("setup networking %s", err)
+		}
+		if err := strategy.Initialize((*network.Network)(config), networkState); err != nil {
+			return fmt.Errorf("setup networking %s", err)
+		}
+	}
+	for _, route := range container.Routes {
+		if err := netlink.AddRoute(route.Destination, route.Source, route.Gateway, route.InterfaceName); err != nil {
+			return fmt.Errorf("setup route %s", err)
+		}
+	}
+
+	label.Init()
+
+	if err := mountTmpfs(container, rootfs, tmpfs); err != nil {
+		return err
+	}
+	if err := mount.InitializeMountNamespace(rootfs, consolePath, container.RestrictSys, (*mount.MountConfig)(container.MountConfig)); err != nil {
+		return fmt.Errorf("setup mount namespace %s", err)
+	}
+	if container.Hostname != "" {
+		if err := syscall.Sethostname([]byte(container.Hostname)); err != nil {
+			return fmt.Errorf("sethostname %s", err)
+		}
+	}
+	if err := apparmor.ApplyProfile(container.AppArmorProfile); err != nil {
+		return fmt.Errorf("set apparmor profile %s: %s", container.AppArmorProfile, err)
+	}
+	if err := label.SetProcessLabel(container.ProcessLabel); err != nil {
+		return fmt.Errorf("set process label %s", err)
+	}
+	if container.RestrictSys {
+		if err := restrict.Restrict("proc/sys", "proc/sysrq-trigger", "proc/irq", "proc/bus"); err != nil {
+			return err
+		}
+	}
+
+	pdeathSignal, err := system.GetParentDeathSignal()
+	if err != nil {
+		return fmt.Errorf("get parent death signal %s", err)
+	}
+
+	if filter != nil {
+		if err := seccomp.Load(filter); err != nil {
+			return err
+		}
+	}
+
+	if err := namespaces.FinalizeNamespace(container); err != nil {
+		return fmt.Errorf("finalize namespace %s", err)
+	}
+	// FinalizeNamespace can change the user, which clears the parent death
+	// signal
+	if err := namespaces.RestoreParentDeathSignal(pdeathSignal); err != nil {
+		return fmt.Errorf("restore parent death signal %s", err)
+	}
+
+	return system.Execv(args[0], args[0:], os.Environ())
+}
+
+// enterNamespace enters the namespace at nspath with the calling thread,
+// which is locked.
+func enterNamespace(nspath string, nstype uintptr) error {
+	f, err := os.Open(nspath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return system.Setns(f.Fd(), nstype)
+}
+
+// runInPidNamespace runs the init with args in the PID namespace at nspath,
+// waits for it and returns its exit status. The namespace is left right
+// after the fork, as the threads of the runtime cannot be created in it, and
+// the signals are forwarded to the init which runs the container.
+func runInPidNamespace(nspath string, pipe *os.File, args []string) int {
+	signals := make(chan os.Signal, 32)
+	signal.Notify(signals)
+
+	cmd := &exec.Cmd{
+		Path:       "/proc/self/exe",
+		Args:       append([]string{DriverName}, args...),
+		Stdin:      os.Stdin,
+		Stdout:     os.Stdout,
+		Stderr:     os.Stderr,
+		ExtraFiles: []*os.File{pipe},
+		// the container does not outlive its init being killed
+		SysProcAttr: &syscall.SysProcAttr{Pdeathsig: syscall.SIGKILL},
+	}
+	self, err := os.Open("/proc/self/ns/pid")
+	if err != nil {
+		writeError(err)
+	}
+	if err := enterNamespace(nspath, syscall.CLONE_NEWPID); err != nil {
+		writeError(err)
+	}
+	err = cmd.Start()
+	if err := system.Setns(self.Fd(), syscall.CLONE_NEWPID); err != nil {
+		writeError(err)
+	}
+	if err != nil {
+		writeError(err)
+	}
+	self.Close()
+
+	go func() {
+		for sig := range signals {
+			if sig != syscall.SIGCHLD {
+				cmd.Process.Signal(sig)
+			}
+		}
+	}()
+
+	cmd.Wait()
+	status := cmd.ProcessState.Sys().(syscall.WaitStatus)
+	if status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return status.ExitStatus()
+}
+
 func writeError(err error) {
 	fmt.Fprint(os.Stderr, err)
 	os.Exit(1)
@@ -0,0 +1,288 @@
+// +build linux,cgo
+
+package native
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"syscall"
+
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/pkg/mount"
+	"github.com/docker/libcontainer"
+	"github.com/docker/libcontainer/netlink"
+	"github.com/docker/libcontainer/network"
+	"github.com/docker/libcontainer/system"
+	"github.com/docker/libcontainer/utils"
+)
+
+// netPool keeps network namespaces ready for the containers attached to the
+// bridge, each with an eth0 whose veth peer is already attached to the
+// bridge and up. Creating the veth pair is most of the network setup of a
+// container start, so claiming a namespace from the pool only leaves the
+// address and the gateway to set.
+type netPool struct {
+	root string
+	size int
+
+	sync.Mutex
+	bridge  string
+	mtu     int
+	free    []string
+	filling bool
+}
+
+// newNetPool creates a pool of size namespaces, bind mounted under root.
+// The namespaces left over by a previous daemon are removed.
+func newNetPool(root string, size int) (*netPool, error) {
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return nil, err
+	}
+	leftovers, err := ioutil.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, fi := range leftovers {
+		destroyNamespace(filepath.Join(root, fi.Name()))
+	}
+	return &netPool{root: root, size: size}, nil
+}
+
+// claim replaces the veth network of container by a namespace of the pool
+// with its address and gateway set, and returns the path of the namespace.
+// It returns an empty path, leaving the container unchanged, if the
+// container has no veth network or the pool is empty.
+func (p *netPool) claim(container *libcontainer.Config) (string, error) {
+	i := vethNetwork(container)
+	if i < 0 {
+		return "", nil
+	}
+	n := container.Networks[i]
+
+	p.Lock()
+	if n.Bridge != p.bridge || n.Mtu != p.mtu {
+		// the namespaces of the pool were set up for another bridge
+		for _, path := range p.free {
+			destroyNamespace(path)
+		}
+		p.free = nil
+		p.bridge, p.mtu = n.Bridge, n.Mtu
+	}
+	var path string
+	if len(p.free) > 0 {
+		path, p.free = p.free[0], p.free[1:]
+	}
+	if !p.filling {
+		p.filling = true
+		go p.fill()
+	}
+	p.Unlock()
+
+	if path == "" {
+		return "", nil
+	}
+	if err := inNamespace(path, func() error {
+		if err := network.SetInterfaceIp("eth0", n.Address); err != nil {
+			return fmt.Errorf("set eth0 ip %s", err)
+		}
+		if err := network.InterfaceUp("eth0"); err != nil {
+			return fmt.Errorf("eth0 up %s", err)
+		}
+		if n.Gateway != "" {
+			if err := network.SetDefaultGateway(n.Gateway, "eth0"); err != nil {
+				return fmt.Errorf("set gateway to %s on device eth0 failed with %s", n.Gateway, err)
+			}
+		}
+		return nil
+	}); err != nil {
+		destroyNamespace(path)
+		return "", err
+	}
+	container.Networks[i] = &libcontainer.Network{
+		Type:   "netns",
+		NsPath: path,
+	}
+	return path, nil
+}
+
+// release removes a namespace returned by claim, which also removes its
+// veth pair. Namespaces are not reused, so that no address or route of a
+// container leaks into the next one.
+func (p *netPool) release(path string) {
+	if path != "" {
+		destroyNamespace(path)
+	}
+}
+
+// fill creates namespaces until the pool is full.
+func (p *netPool) fill() {
+	for {
+		p.Lock()
+		bridge, mtu := p.bridge, p.mtu
+		if len(p.free) >= p.size {
+			p.filling = false
+			p.Unlock()
+			return
+		}
+		p.Unlock()
+
+		path, err := p.provision(bridge, mtu)
+
+		p.Lock()
+		if err != nil {
+			log.Errorf("Error adding a network namespace to the pool: %s", err)
+			p.filling = false
+			p.Unlock()
+			return
+		}
+		if bridge != p.bridge || mtu != p.mtu {
+			destroyNamespace(path)
+		} else {
+			p.free = append(p.free, path)
+		}
+		p.Unlock()
+	}
+}
+
+// provision creates a namespace with an eth0 whose peer is attached to
+// bridge and up.
+func (p *netPool) provision(bridge string, mtu int) (string, error) {
+	name, err := utils.GenerateRandomName("veth", 4)
+	if err != nil {
+		return "", err
+	}
+	child, err := utils.GenerateRandomName("veth", 4)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(p.root, name)
+	if err := newNamespace(path); err != nil {
+		return "", err
+	}
+	if err := network.CreateVethPair(name, child); err != nil {
+		destroyNamespace(path)
+		return "", err
+	}
+	if err := setupVethPair(path, name, child, bridge, mtu); err != nil {
+		netlink.NetworkLinkDel(name)
+		destroyNamespace(path)
+		return "", err
+	}
+	return path, nil
+}
+
+func setupVethPair(path, name, child, bridge string, mtu int) error {
+	if err := network.SetInterfaceMaster(name, bridge); err != nil {
+		return err
+	}
+	if err := network.SetMtu(name, mtu); err != nil {
+		return err
+	}
+	if err := network.InterfaceUp(name); err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := network.SetInterfaceInNamespaceFd(child, f.Fd()); err != nil {
+		return err
+	}
+	return inNamespace(path, func() error {
+		if err := network.InterfaceUp("lo"); err != nil {
+			return err
+		}
+		if err := network.ChangeInterfaceName(child, "eth0"); err != nil {
+			return err
+		}
+		return network.SetMtu("eth0", mtu)
+	})
+}
+
+// vethNetwork returns the index of the veth network of container, or -1.
+func vethNetwork(container *libcontainer.Config) int {
+	for i, n := range container.Networks {
+		if n.Type == "veth" {
+			return i
+		}
+	}
+	return -1
+}
+
+// newNamespace creates a network namespace that lives as long as its bind
+// mount on path.
+func newNamespace(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	f.Close()
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	origin, err := os.Open(threadNamespace())
+	if err != nil {
+		return err
+	}
+	defer origin.Close()
+	if err := syscall.Unshare(syscall.CLONE_NEWNET); err != nil {
+		os.Remove(path)
+		return err
+	}
+	defer setns(origin)
+	if err := mount.ForceMount(threadNamespace(), path, "none", "bind"); err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+// inNamespace runs fn in the network namespace bind mounted on path.
+func inNamespace(path string, fn func() error) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	origin, err := os.Open(threadNamespace())
+	if err != nil {
+		return err
+	}
+	defer origin.Close()
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := system.Setns(f.Fd(), syscall.CLONE_NEWNET); err != nil {
+		return err
+	}
+	defer setns(origin)
+	return fn()
+}
+
+func destroyNamespace(path string) {
+	if err := mount.Unmount(path); err != nil {
+		log.Debugf("Error unmounting network namespace %s: %s", path, err)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Errorf("Error removing network namespace %s: %s", path, err)
+	}
+}
+
+func threadNamespace() string {
+	return fmt.Sprintf("/proc/self/task/%d/ns/net", syscall.Gettid())
+}
+
+// setns moves the locked thread back to the namespace of origin. A thread
+// left in a container namespace would run the daemon goroutines in it, so
+// failing to restore it is fatal.
+func setns(origin *os.File) {
+	if err := system.Setns(origin.Fd(), syscall.CLONE_NEWNET); err != nil {
+		log.Fatalf("Error restoring the network namespace of the daemon: %s", err)
+	}
+}
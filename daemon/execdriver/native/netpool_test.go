@@ -0,0 +1,170 @@
+// +build linux,cgo
+
+package native
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/libcontainer"
+)
+
+// newTestNetPool returns a pool holding plain files as free namespaces,
+// which is not refilled.
+func newTestNetPool(t *testing.T, bridge string, mtu int, free ...string) (*netPool, string) {
+	root, err := ioutil.TempDir("", "docker-netpool-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &netPool{root: root, size: len(free), bridge: bridge, mtu: mtu, filling: true}
+	for _, name := range free {
+		path := filepath.Join(root, name)
+		if err := ioutil.WriteFile(path, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+		p.free = append(p.free, path)
+	}
+	return p, root
+}
+
+func newTestContainer(bridge string, mtu int) *libcontainer.Config {
+	return &libcontainer.Config{
+		Networks: []*libcontainer.Network{
+			{Type: "loopback", Address: "127.0.0.1/0", Gateway: "localhost"},
+			{Type: "veth", Bridge: bridge, Mtu: mtu, Address: "172.17.0.5/16", Gateway: "172.17.42.1"},
+		},
+	}
+}
+
+func assertVethUnchanged(t *testing.T, container *libcontainer.Config, bridge string) {
+	if len(container.Networks) != 2 {
+		t.Fatalf("Expected 2 networks, got %d", len(container.Networks))
+	}
+	if n := container.Networks[1]; n.Type != "veth" || n.Bridge != bridge || n.NsPath != "" {
+		t.Fatalf("Expected the veth network to be left unchanged, got %v", n)
+	}
+}
+
+func assertRemoved(t *testing.T, paths ...string) {
+	for _, path := range paths {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("Expected %s to be removed, got %v", path, err)
+		}
+	}
+}
+
+func TestNetPoolClaimOtherBridge(t *testing.T) {
+	p, root := newTestNetPool(t, "docker0", 1500, "veth1", "veth2")
+	defer os.RemoveAll(root)
+	free := p.free
+
+	container := newTestContainer("br0", 1500)
+	path, err := p.claim(container)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "" {
+		t.Fatalf("Expected no namespace for another bridge, got %s", path)
+	}
+	assertVethUnchanged(t, container, "br0")
+	assertRemoved(t, free...)
+	if len(p.free) != 0 || p.bridge != "br0" || p.mtu != 1500 {
+		t.Fatalf("Expected an empty pool for br0, got %d namespaces for %s", len(p.free), p.bridge)
+	}
+}
+
+func TestNetPoolClaimOtherMtu(t *testing.T) {
+	p, root := newTestNetPool(t, "docker0", 1500, "veth1")
+	defer os.RemoveAll(root)
+	free := p.free
+
+	container := newTestContainer("docker0", 9000)
+	if path, err := p.claim(container); err != nil || path != "" {
+		t.Fatalf("Expected no namespace for another MTU, got %q (%v)", path, err)
+	}
+	assertVethUnchanged(t, container, "docker0")
+	assertRemoved(t, free...)
+	if len(p.free) != 0 || p.mtu != 9000 {
+		t.Fatalf("Expected an empty pool for a MTU of 9000, got %d namespaces for %d", len(p.free), p.mtu)
+	}
+}
+
+func TestNetPoolClaimEmpty(t *testing.T) {
+	p, root := newTestNetPool(t, "docker0", 1500)
+	defer os.RemoveAll(root)
+
+	container := newTestContainer("docker0", 1500)
+	if path, err := p.claim(container); err != nil || path != "" {
+		t.Fatalf("Expected no namespace from an empty pool, got %q (%v)", path, err)
+	}
+	assertVethUnchanged(t, container, "docker0")
+}
+
+func TestNetPoolClaimError(t *testing.T) {
+	// a plain file is no namespace to set the address of eth0 in
+	p, root := newTestNetPool(t, "docker0", 1500, "veth1")
+	defer os.RemoveAll(root)
+	free := p.free
+
+	container := newTestContainer("docker0", 1500)
+	if path, err := p.claim(container); err == nil {
+		t.Fatalf("Expected an error claiming an invalid namespace, got %s", path)
+	}
+	assertVethUnchanged(t, container, "docker0")
+	assertRemoved(t, free...)
+	if len(p.free) != 0 {
+		t.Fatalf("Expected the invalid namespace to leave the pool, got %v", p.free)
+	}
+}
+
+func TestNetPoolClaimWithoutVeth(t *testing.T) {
+	p, root := newTestNetPool(t, "docker0", 1500, "veth1")
+	defer os.RemoveAll(root)
+
+	container := &libcontainer.Config{Networks: []*libcontainer.Network{{Type: "loopback", Address: "127.0.0.1/0"}}}
+	if path, err := p.claim(container); err != nil || path != "" {
+		t.Fatalf("Expected no namespace without a veth network, got %q (%v)", path, err)
+	}
+	if len(p.free) != 1 {
+		t.Fatalf("Expected the pool to be left unchanged, got %v", p.free)
+	}
+}
+
+func TestNewNetPoolLeftovers(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "docker-netpool-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	root := filepath.Join(tmp, "netns")
+	if err := os.MkdirAll(root, 0700); err != nil {
+		t.Fatal(err)
+	}
+	var leftovers []string
+	for _, name := range []string{"veth1", "veth2"} {
+		path := filepath.Join(root, name)
+		if err := ioutil.WriteFile(path, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+		leftovers = append(leftovers, path)
+	}
+
+	p, err := newNetPool(root, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertRemoved(t, leftovers...)
+	if p.root != root || p.size != 4 || len(p.free) != 0 {
+		t.Fatalf("Unexpected pool %v", p)
+	}
+
+	// the root is created when missing
+	if _, err := newNetPool(filepath.Join(tmp, "missing"), 4); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "missing")); err != nil {
+		t.Fatal(err)
+	}
+}
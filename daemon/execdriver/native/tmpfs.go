@@ -0,0 +1,70 @@
+// +build linux
+
+package native
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+
+	"github.com/docker/docker/pkg/mount"
+	"github.com/docker/docker/pkg/symlink"
+	"github.com/docker/libcontainer"
+	"github.com/docker/libcontainer/label"
+)
+
+// readTmpfsFile reads the tmpfs written by the driver, the mount options
+// keyed by destination.
+func readTmpfsFile(path string) (map[string]string, error) {
+	var tmpfs map[string]string
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &tmpfs); err != nil {
+		return nil, err
+	}
+	return tmpfs, nil
+}
+
+// mountTmpfs mounts the tmpfs under the rootfs, from the init, in the
+// mount namespace of the container. libcontainer only sets up bind mounts,
+// but it binds the rootfs recursively so the tmpfs stay under it once it
+// becomes the root. The mounts of the namespace are made private first, as
+// libcontainer does, so that the tmpfs never show up on the host.
+func mountTmpfs(container *libcontainer.Config, rootfs string, tmpfs map[string]string) error {
+	if len(tmpfs) == 0 {
+		return nil
+	}
+	flag := syscall.MS_PRIVATE
+	if container.MountConfig.NoPivotRoot {
+		flag = syscall.MS_SLAVE
+	}
+	if err := syscall.Mount("", "/", "", uintptr(flag|syscall.MS_REC), ""); err != nil {
+		return fmt.Errorf("mounting / with flags %X %s", flag|syscall.MS_REC, err)
+	}
+
+	// the parents before their children
+	dests := make([]string, 0, len(tmpfs))
+	for dest := range tmpfs {
+		dests = append(dests, dest)
+	}
+	sort.Strings(dests)
+	for _, dest := range dests {
+		target, err := symlink.FollowSymlinkInScope(filepath.Join(rootfs, dest), rootfs)
+		if err == nil {
+			err = os.MkdirAll(target, 0755)
+		}
+		if err == nil {
+			err = mount.ForceMount("tmpfs", target, "tmpfs", label.FormatMountLabel(tmpfs[dest], container.MountConfig.MountLabel))
+		}
+		if err != nil {
+			return fmt.Errorf("Cannot mount tmpfs on %s: %s", dest, err)
+		}
+	}
+	return nil
+}
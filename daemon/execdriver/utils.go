@@ -2,17 +2,22 @@ package execdriver
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/docker/docker/utils"
 	"github.com/docker/libcontainer/security/capabilities"
 )
 
+// TweakCapabilities returns the capabilities basics with the adds added and
+// the drops dropped. The capabilities are named with or without the CAP_
+// prefix of capabilities(7), and "all" stands for all of them.
 func TweakCapabilities(basics, adds, drops []string) ([]string, error) {
 	var (
 		newCaps []string
 		allCaps = capabilities.GetAllCapabilities()
 	)
+	adds, drops = trimCapPrefix(adds), trimCapPrefix(drops)
 
 	// look for invalid cap in the drop list
 	for _, cap := range drops {
@@ -61,3 +66,69 @@ func TweakCapabilities(basics, adds, drops []string) ([]string, error) {
 
 	return newCaps, nil
 }
+
+func trimCapPrefix(caps []string) []string {
+	trimmed := make([]string, len(caps))
+	for i, cap := range caps {
+		if strings.HasPrefix(strings.ToUpper(cap), "CAP_") {
+			cap = cap[len("CAP_"):]
+		}
+		trimmed[i] = cap
+	}
+	return trimmed
+}
+
+// MemorySwapLimit returns the limit of memory and swap usage of r: its
+// MemorySwap if set, or twice its memory limit by default. It is 0 when
+// the swap usage is not limited.
+func MemorySwapLimit(r *Resources) int64 {
+	if r.Memory == 0 || r.MemorySwap < 0 {
+		return 0
+	}
+	if r.MemorySwap > 0 {
+		return r.MemorySwap
+	}
+	return r.Memory * 2
+}
+
+// UpdateResources applies the memory, cpu shares and cpuset of r to a
+// running container. set writes a value in a cgroup file of the container.
+func UpdateResources(r *Resources, set func(subsystem, file, value string) error) error {
+	if r.Memory != 0 {
+		limit := strconv.FormatInt(r.Memory, 10)
+		if swap := MemorySwapLimit(r); swap == 0 {
+			if err := set("memory", "memory.limit_in_bytes", limit); err != nil {
+				return err
+			}
+		} else {
+			// As memory.memsw.limit_in_bytes cannot be lower than
+			// memory.limit_in_bytes, the swap limit is raised first and
+			// lowered last.
+			memsw := strconv.FormatInt(swap, 10)
+			if err := set("memory", "memory.memsw.limit_in_bytes", memsw); err != nil {
+				if err := set("memory", "memory.limit_in_bytes", limit); err != nil {
+					return err
+				}
+				if err := set("memory", "memory.memsw.limit_in_bytes", memsw); err != nil {
+					return err
+				}
+			} else if err := set("memory", "memory.limit_in_bytes", limit); err != nil {
+				return err
+			}
+		}
+		if err := set("memory", "memory.soft_limit_in_bytes", limit); err != nil {
+			return err
+		}
+	}
+	if r.CpuShares != 0 {
+		if err := set("cpu", "cpu.shares", strconv.FormatInt(r.CpuShares, 10)); err != nil {
+			return err
+		}
+	}
+	if r.Cpuset != "" {
+		if err := set("cpuset", "cpuset.cpus", r.Cpuset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package execdriver
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// unlimited is the value of the memory limits of a new cgroup.
+const unlimited = "9223372036854771712"
+
+// fakeCgroup holds the files of the cgroups of a container, and refuses the
+// values the kernel refuses.
+type fakeCgroup struct {
+	files map[string]string
+	// the kernel accounts the swap, with the memory.memsw files
+	swapAccounting bool
+}
+
+func newFakeCgroup(swapAccounting bool) *fakeCgroup {
+	c := &fakeCgroup{
+		files:          map[string]string{"memory.limit_in_bytes": unlimited},
+		swapAccounting: swapAccounting,
+	}
+	if swapAccounting {
+		c.files["memory.memsw.limit_in_bytes"] = unlimited
+	}
+	return c
+}
+
+func (c *fakeCgroup) limit(file string) int64 {
+	limit, _ := strconv.ParseInt(c.files[file], 10, 64)
+	return limit
+}
+
+func (c *fakeCgroup) set(subsystem, file, value string) error {
+	if !strings.HasPrefix(file, subsystem+".") {
+		return fmt.Errorf("%s is not a file of the %s cgroup", file, subsystem)
+	}
+	if strings.HasPrefix(file, "memory.memsw.") && !c.swapAccounting {
+		return fmt.Errorf("open %s: no such file or directory", file)
+	}
+	// the memory limit cannot exceed the limit of memory and swap
+	switch file {
+	case "memory.limit_in_bytes", "memory.memsw.limit_in_bytes":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("write %s: invalid argument", file)
+		}
+		if file == "memory.limit_in_bytes" && c.swapAccounting && n > c.limit("memory.memsw.limit_in_bytes") {
+			return fmt.Errorf("write %s: invalid argument", file)
+		}
+		if file == "memory.memsw.limit_in_bytes" && n < c.limit("memory.limit_in_bytes") {
+			return fmt.Errorf("write %s: invalid argument", file)
+		}
+	}
+	c.files[file] = value
+	return nil
+}
+
+func TestUpdateResources(t *testing.T) {
+	c := newFakeCgroup(true)
+	c.files["memory.limit_in_bytes"] = "1073741824"
+	c.files["memory.memsw.limit_in_bytes"] = "2147483648"
+
+	// lowered, the memory limit is written before the swap limit
+	if err := UpdateResources(&Resources{Memory: 1048576, CpuShares: 512, Cpuset: "0,1"}, c.set); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"memory.limit_in_bytes":       "1048576",
+		"memory.memsw.limit_in_bytes": "2097152",
+		"memory.soft_limit_in_bytes":  "1048576",
+		"cpu.shares":                  "512",
+		"cpuset.cpus":                 "0,1",
+	}
+	if !reflect.DeepEqual(c.files, expected) {
+		t.Fatalf("Expected %v, got %v", expected, c.files)
+	}
+
+	// raised, the swap limit is written first
+	if err := UpdateResources(&Resources{Memory: 134217728, MemorySwap: 268435456}, c.set); err != nil {
+		t.Fatal(err)
+	}
+	expected["memory.limit_in_bytes"] = "134217728"
+	expected["memory.memsw.limit_in_bytes"] = "268435456"
+	expected["memory.soft_limit_in_bytes"] = "134217728"
+	if !reflect.DeepEqual(c.files, expected) {
+		t.Fatalf("Expected %v, got %v", expected, c.files)
+	}
+
+	// without a swap limit, only the memory limit changes
+	if err := UpdateResources(&Resources{Memory: 268435456, MemorySwap: -1}, c.set); err != nil {
+		t.Fatal(err)
+	}
+	expected["memory.limit_in_bytes"] = "268435456"
+	expected["memory.soft_limit_in_bytes"] = "268435456"
+	if !reflect.DeepEqual(c.files, expected) {
+		t.Fatalf("Expected %v, got %v", expected, c.files)
+	}
+
+	if err := UpdateResources(&Resources{Memory: 536870912, MemorySwap: -1}, c.set); err == nil {
+		t.Fatal("Expected an error for a memory limit above the swap limit")
+	}
+}
+
+func TestUpdateResourcesWithoutSwapAccounting(t *testing.T) {
+	c := newFakeCgroup(false)
+	if err := UpdateResources(&Resources{Memory: 1048576, MemorySwap: -1}, c.set); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"memory.limit_in_bytes":      "1048576",
+		"memory.soft_limit_in_bytes": "1048576",
+	}
+	if !reflect.DeepEqual(c.files, expected) {
+		t.Fatalf("Expected %v, got %v", expected, c.files)
+	}
+
+	if err := UpdateResources(&Resources{Memory: 1048576, MemorySwap: 4194304}, c.set); err == nil {
+		t.Fatal("Expected an error for a swap limit without swap accounting")
+	}
+}
+
+func TestTweakCapabilities(t *testing.T) {
+	basics := []string{"CHOWN", "NET_RAW", "KILL"}
+	caps, err := TweakCapabilities(basics, []string{"sys_time", "CAP_NET_ADMIN"}, []string{"cap_net_raw"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"CHOWN", "KILL", "SYS_TIME", "NET_ADMIN"}
+	if !reflect.DeepEqual(caps, expected) {
+		t.Fatalf("Expected %v, got %v", expected, caps)
+	}
+
+	if caps, err = TweakCapabilities(basics, []string{"MKNOD"}, []string{"ALL"}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(caps, []string{"MKNOD"}) {
+		t.Fatalf("Expected only MKNOD, got %v", caps)
+	}
+
+	if _, err := TweakCapabilities(basics, []string{"CAP_CHPASS"}, nil); err == nil {
+		t.Fatal("Expected an error adding an unknown capability")
+	}
+	if _, err := TweakCapabilities(basics, nil, []string{"CHPASS"}); err == nil {
+		t.Fatal("Expected an error dropping an unknown capability")
+	}
+}
@@ -1,18 +1,32 @@
 package daemon
 
 import (
+	"fmt"
 	"io"
+	"path/filepath"
+	"strings"
 
+	"github.com/docker/docker/archive"
 	"github.com/docker/docker/engine"
+	"github.com/docker/docker/pkg/symlink"
 )
 
+// ContainerExport streams the filesystem of a container as a tar archive.
+//
+// Option environment:
+//	'Include': glob patterns of the paths to export, all of them by default.
+//	'Exclude': glob patterns of the paths not to export.
+//	'SkipVolumes': leave out the mountpoints of the volumes.
+//
+// The patterns are matched against the whole path in the container, e.g.
+// "/etc" or "/var/log/*.log".
 func (daemon *Daemon) ContainerExport(job *engine.Job) engine.Status {
 	if len(job.Args) != 1 {
 		return job.Errorf("Usage: %s container_id", job.Name)
 	}
 	name := job.Args[0]
 	if container := daemon.Get(name); container != nil {
-		data, err := container.Export()
+		data, err := container.Export(job.GetenvList("Include"), job.GetenvList("Exclude"), job.GetenvBool("SkipVolumes"))
 		if err != nil {
 			return job.Errorf("%s: %s", name, err)
 		}
@@ -28,3 +42,71 @@ func (daemon *Daemon) ContainerExport(job *engine.Job) engine.Status {
 	}
 	return job.Errorf("No such container: %s", name)
 }
+
+// exportOptions returns the options archiving the paths of the mounted
+// container selected by includes, excludes and skipVolumes.
+func (container *Container) exportOptions(includes, excludes []string, skipVolumes bool) (*archive.TarOptions, error) {
+	options := &archive.TarOptions{Compression: archive.Uncompressed}
+	included := make(map[string]bool)
+	for _, pattern := range includes {
+		paths, err := globInScope(container.basefs, pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(paths) == 0 {
+			return nil, fmt.Errorf("No path matches %s", pattern)
+		}
+		for _, path := range paths {
+			if !included[path] {
+				included[path] = true
+				options.Includes = append(options.Includes, path)
+			}
+		}
+	}
+	for _, pattern := range excludes {
+		pattern = relativePattern(pattern)
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("Invalid exclude pattern %s: %s", pattern, err)
+		}
+		options.Excludes = append(options.Excludes, pattern)
+	}
+	if skipVolumes {
+		for volPath := range container.Volumes {
+			options.Excludes = append(options.Excludes, relativePattern(volPath))
+		}
+	}
+	return options, nil
+}
+
+// relativePattern makes a pattern of a path in the container relative to
+// its root, as the archive matches them.
+func relativePattern(pattern string) string {
+	return strings.TrimPrefix(filepath.Clean("/"+pattern), "/")
+}
+
+// globInScope returns the paths relative to root matching pattern, their
+// parent directories resolved inside root so a symlink cannot take them
+// out of it.
+func globInScope(root, pattern string) ([]string, error) {
+	pattern = relativePattern(pattern)
+	if pattern == "" {
+		return []string{"."}, nil
+	}
+	matches, err := filepath.Glob(filepath.Join(root, pattern))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid include pattern %s: %s", pattern, err)
+	}
+	var paths []string
+	for _, match := range matches {
+		dir, err := symlink.FollowSymlinkInScope(filepath.Dir(match), root)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(root, filepath.Join(dir, filepath.Base(match)))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, rel)
+	}
+	return paths, nil
+}
@@ -0,0 +1,50 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestExportOptions(t *testing.T) {
+	root, err := ioutil.TempDir("", "docker-export-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	for _, dir := range []string{"etc/app", "var/lib/app", "var/lib/other", "data"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a symlink out of the container is resolved inside of it
+	if err := os.Symlink("/", filepath.Join(root, "host")); err != nil {
+		t.Fatal(err)
+	}
+
+	container := &Container{
+		basefs:  root,
+		Volumes: map[string]string{"/data": "/var/lib/docker/vfs/dir/foo"},
+	}
+	options, err := container.exportOptions([]string{"/etc", "/var/lib/*", "var/lib/app", "/host/etc"}, []string{"/var/log/*.log"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(options.Includes)
+	if expected := []string{"etc", "var/lib/app", "var/lib/other"}; !reflect.DeepEqual(options.Includes, expected) {
+		t.Fatalf("Expected the includes %v, got %v", expected, options.Includes)
+	}
+	if expected := []string{"var/log/*.log", "data"}; !reflect.DeepEqual(options.Excludes, expected) {
+		t.Fatalf("Expected the excludes %v, got %v", expected, options.Excludes)
+	}
+
+	if _, err := container.exportOptions([]string{"/missing"}, nil, false); err == nil {
+		t.Fatal("Expected an error for an include matching no path")
+	}
+	if _, err := container.exportOptions(nil, []string{"[a-"}, false); err == nil {
+		t.Fatal("Expected an error for an invalid exclude pattern")
+	}
+}
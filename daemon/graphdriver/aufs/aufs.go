@@ -296,8 +296,10 @@ func (a *Driver) Put(id string) {
 
 // Returns an archive of the contents for the id
 func (a *Driver) Diff(id string) (archive.Archive, error) {
+	// the layers keep the AUFS hard link targets, but not its other metadata
 	return archive.TarWithOptions(path.Join(a.rootPath(), "diff", id), &archive.TarOptions{
 		Compression: archive.Uncompressed,
+		Excludes:    []string{archive.WhiteoutMetaPrefix + "aufs", archive.WhiteoutMetaPrefix + "orph"},
 	})
 }
 
@@ -0,0 +1,152 @@
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/pkg/log"
+)
+
+// hookEvents lists the container events hooks can be attached to.
+var hookEvents = map[string]bool{
+	"start": true,
+	"die":   true,
+	"oom":   true,
+}
+
+// A hook is a program executed by the daemon on each occurrence of a
+// container event. It receives a JSON description of the event on stdin.
+type hook struct {
+	Event string
+	Path  string
+}
+
+// parseHooks parses hooks given as EVENT:PATH.
+func parseHooks(specs []string) ([]*hook, error) {
+	var hooks []*hook
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, ":", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return nil, fmt.Errorf("Invalid hook %s, expected EVENT:PATH", spec)
+		}
+		if !hookEvents[parts[0]] {
+			return nil, fmt.Errorf("Invalid hook %s, unknown event %s", spec, parts[0])
+		}
+		if !filepath.IsAbs(parts[1]) {
+			return nil, fmt.Errorf("Invalid hook %s, the path must be absolute", spec)
+		}
+		hooks = append(hooks, &hook{Event: parts[0], Path: parts[1]})
+	}
+	return hooks, nil
+}
+
+// runHooks executes in the background the hooks attached to event.
+func (daemon *Daemon) runHooks(container *Container, event string) {
+	var payload *bytes.Buffer
+	for _, h := range daemon.hooks {
+		if h.Event != event {
+			continue
+		}
+		if payload == nil {
+			payload = bytes.NewBuffer(nil)
+			if err := hookPayload(container, event).Encode(payload); err != nil {
+				log.Errorf("Error encoding the %s hook payload for %s: %s", event, container.ID, err)
+				return
+			}
+		}
+		go h.run(container.ID, payload.Bytes())
+	}
+}
+
+func hookPayload(container *Container, event string) *engine.Env {
+	out := &engine.Env{}
+	out.Set("Event", event)
+	out.Set("ID", container.ID)
+	out.Set("Name", container.Name)
+	out.Set("Image", container.daemon.Repositories().ImageName(container.Image))
+	out.SetInt64("Time", time.Now().UTC().Unix())
+	switch event {
+	case "start", "oom":
+		out.SetInt("Pid", container.State.GetPid())
+	case "die":
+		out.SetInt("ExitCode", container.State.GetExitCode())
+	}
+	return out
+}
+
+// hookTimeout is how long a hook, of an event or of the lifecycle of a
+// container, can run before it is killed and fails, a variable for the tests.
+var hookTimeout = time.Minute
+
+// runLifecycleHooks runs the hook commands configured for a container one
+// after the other with /bin/sh on the host, stopping at the first failure.
+// The commands find the container in their DOCKER_CONTAINER_ID and
+// DOCKER_CONTAINER_NAME environment variables. They only run when the
+// daemon runs with --lifecycle-hooks, as they run as root on the host.
+// There is no mode running them in the namespaces of the container yet: the
+// pre-start hooks run before they exist and the post-stop ones after they
+// are gone, it needs hooks of the exec driver once the container runs.
+func (container *Container) runLifecycleHooks(stage string, cmds []string, env ...string) error {
+	if len(cmds) == 0 {
+		return nil
+	}
+	if !container.daemon.config.LifecycleHooks {
+		return fmt.Errorf("%s hooks are disabled, the daemon runs without --lifecycle-hooks", stage)
+	}
+	env = append([]string{
+		"PATH=" + DefaultPathEnv,
+		"DOCKER_HOOK=" + stage,
+		"DOCKER_CONTAINER_ID=" + container.ID,
+		"DOCKER_CONTAINER_NAME=" + strings.TrimPrefix(container.Name, "/"),
+	}, env...)
+	for _, c := range cmds {
+		cmd := exec.Command("/bin/sh", "-c", c)
+		cmd.Env = env
+		if output, err := runHookCommand(cmd); err != nil {
+			return fmt.Errorf("%s hook %q failed: %s (%s)", stage, c, err, strings.TrimSpace(string(output)))
+		}
+	}
+	return nil
+}
+
+// runHookCommand runs the command of a hook in its own process group,
+// killed with all the processes it started after hookTimeout, and returns
+// its combined output.
+func runHookCommand(cmd *exec.Cmd) ([]byte, error) {
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	select {
+	case err := <-done:
+		return output.Bytes(), err
+	case <-time.After(hookTimeout):
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-done
+		return output.Bytes(), fmt.Errorf("timed out after %s", hookTimeout)
+	}
+}
+
+func (h *hook) run(id string, payload []byte) error {
+	cmd := exec.Command(h.Path)
+	cmd.Stdin = bytes.NewReader(payload)
+	if output, err := runHookCommand(cmd); err != nil {
+		log.Errorf("%s hook %s failed for %s: %s (%s)", h.Event, h.Path, id, err, strings.TrimSpace(string(output)))
+		return err
+	}
+	return nil
+}
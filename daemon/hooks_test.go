@@ -0,0 +1,96 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseHooks(t *testing.T) {
+	hooks, err := parseHooks([]string{"die:/usr/local/bin/on-die", "oom:/bin/notify:oom"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hooks) != 2 {
+		t.Fatalf("Expected 2 hooks, got %d", len(hooks))
+	}
+	if hooks[0].Event != "die" || hooks[0].Path != "/usr/local/bin/on-die" {
+		t.Fatalf("Unexpected hook %v", hooks[0])
+	}
+	if hooks[1].Event != "oom" || hooks[1].Path != "/bin/notify:oom" {
+		t.Fatalf("Unexpected hook %v", hooks[1])
+	}
+
+	for _, invalid := range []string{"die", "die:", "stop:/bin/true", "start:bin/true"} {
+		if _, err := parseHooks([]string{invalid}); err == nil {
+			t.Fatalf("Expected an error for hook %s", invalid)
+		}
+	}
+}
+
+func TestContainerLifecycleHooks(t *testing.T) {
+	container := &Container{ID: "4386fb97867d", Name: "/sleepy", daemon: &Daemon{config: &Config{LifecycleHooks: true}}}
+
+	hooks := []string{
+		`test "$DOCKER_CONTAINER_ID" = 4386fb97867d`,
+		`test "$DOCKER_CONTAINER_NAME" = sleepy`,
+		`test "$DOCKER_EXIT_CODE" = 137`,
+	}
+	if err := container.runLifecycleHooks("post-stop", hooks, "DOCKER_EXIT_CODE=137"); err != nil {
+		t.Fatal(err)
+	}
+	if err := container.runLifecycleHooks("pre-start", []string{"true", "exit 3", "touch /should/not/run"}); err == nil {
+		t.Fatal("Expected a failing hook to return an error")
+	}
+}
+
+func TestContainerLifecycleHooksTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { hookTimeout = timeout }(hookTimeout)
+	hookTimeout = 100 * time.Millisecond
+	container := &Container{ID: "4386fb97867d", Name: "/sleepy", daemon: &Daemon{config: &Config{LifecycleHooks: true}}}
+
+	start := time.Now()
+	err := container.runLifecycleHooks("pre-start", []string{"sleep 10 & wait"})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("Expected a hanging hook to time out, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Expected the hook and its children to be killed, it took %s", elapsed)
+	}
+}
+
+func TestContainerLifecycleHooksDisabled(t *testing.T) {
+	container := &Container{ID: "4386fb97867d", Name: "/sleepy", daemon: &Daemon{config: &Config{}}}
+	if err := container.runLifecycleHooks("pre-start", []string{"true"}); err == nil {
+		t.Fatal("Expected the hooks to fail without --lifecycle-hooks")
+	}
+	if err := container.runLifecycleHooks("pre-start", nil); err != nil {
+		t.Fatalf("Expected no hooks to run without error, got %s", err)
+	}
+}
+
+func TestHookTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { hookTimeout = timeout }(hookTimeout)
+	hookTimeout = 100 * time.Millisecond
+	tmp, err := ioutil.TempDir("", "docker-hooks-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	path := filepath.Join(tmp, "on-die")
+	if err := ioutil.WriteFile(path, []byte("#!/bin/sh\ncat >/dev/null\nsleep 10 & wait\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	h := &hook{Event: "die", Path: path}
+	if err := h.run("4386fb97867d", []byte(`{"Event":"die"}`)); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("Expected a hanging hook to time out, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Expected the hook and its children to be killed, it took %s", elapsed)
+	}
+}
@@ -6,7 +6,6 @@ import (
 
 	"github.com/docker/docker/engine"
 	"github.com/docker/docker/graph"
-	"github.com/docker/docker/image"
 	"github.com/docker/docker/pkg/parsers"
 	"github.com/docker/docker/utils"
 )
@@ -91,7 +90,12 @@ func (daemon *Daemon) DeleteImage(eng *engine.Engine, name string, imgs *engine.
 		}
 		if tagDeleted {
 			out := &engine.Env{}
-			out.Set("Untagged", repoName+":"+tag)
+			if strings.Contains(tag, ":") {
+				// a digest, given as NAME@DIGEST
+				out.Set("Untagged", repoName+"@"+tag)
+			} else {
+				out.Set("Untagged", repoName+":"+tag)
+			}
 			imgs.Add(out)
 			eng.Job("log", "untag", img.ID, "").Run()
 		}
@@ -105,6 +109,7 @@ func (daemon *Daemon) DeleteImage(eng *engine.Engine, name string, imgs *engine.
 			if err := daemon.Repositories().DeleteAll(img.ID); err != nil {
 				return err
 			}
+			daemon.inspectMounts.forceUnmount(daemon, img.ID)
 			if err := daemon.Graph().Delete(img.ID); err != nil {
 				return err
 			}
@@ -130,27 +135,38 @@ func (daemon *Daemon) canDeleteImage(imgID string, force, untagged bool) error {
 	if untagged {
 		message = " (docker untagged the image)"
 	}
-	for _, container := range daemon.List() {
-		parent, err := daemon.Repositories().LookupImage(container.Image)
-		if err != nil {
-			return err
+	containers, err := daemon.imageUsers(imgID)
+	if err != nil {
+		return err
+	}
+	var running, stopped []string
+	for _, container := range containers {
+		if container.State.IsRunning() {
+			running = append(running, utils.TruncateID(container.ID))
+		} else {
+			stopped = append(stopped, utils.TruncateID(container.ID))
 		}
-
-		if err := parent.WalkHistory(func(p *image.Image) error {
-			if imgID == p.ID {
-				if container.State.IsRunning() {
-					if force {
-						return fmt.Errorf("Conflict, cannot force delete %s because the running container %s is using it%s, stop it and retry", utils.TruncateID(imgID), utils.TruncateID(container.ID), message)
-					}
-					return fmt.Errorf("Conflict, cannot delete %s because the running container %s is using it%s, stop it and use -f to force", utils.TruncateID(imgID), utils.TruncateID(container.ID), message)
-				} else if !force {
-					return fmt.Errorf("Conflict, cannot delete %s because the container %s is using it%s, use -f to force", utils.TruncateID(imgID), utils.TruncateID(container.ID), message)
-				}
-			}
-			return nil
-		}); err != nil {
-			return err
+	}
+	if len(running) > 0 {
+		them := "it"
+		if len(running) > 1 {
+			them = "them"
+		}
+		if force {
+			return fmt.Errorf("Conflict, cannot force delete %s because %s using it%s, stop %s and retry", utils.TruncateID(imgID), describeUsers("running ", running), message, them)
 		}
+		return fmt.Errorf("Conflict, cannot delete %s because %s using it%s, stop %s and use -f to force", utils.TruncateID(imgID), describeUsers("running ", running), message, them)
+	} else if len(stopped) > 0 && !force {
+		return fmt.Errorf("Conflict, cannot delete %s because %s using it%s, use -f to force", utils.TruncateID(imgID), describeUsers("", stopped), message)
 	}
 	return nil
 }
+
+// describeUsers formats the containers using an image for the conflict
+// errors, e.g. "the running containers 4386fb97867d, 8dfafdbc3a40 are".
+func describeUsers(state string, ids []string) string {
+	if len(ids) == 1 {
+		return fmt.Sprintf("the %scontainer %s is", state, ids[0])
+	}
+	return fmt.Sprintf("the %scontainers %s are", state, strings.Join(ids, ", "))
+}
@@ -0,0 +1,209 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/pkg/mount"
+)
+
+// inspectMount is the filesystem of an image or of a stopped container,
+// mounted read-only at a host path for inspection and scanning tools.
+type inspectMount struct {
+	ID        string
+	Container bool
+	Target    string
+}
+
+// inspectMounts tracks the inspection mounts. The filesystem of an image or
+// container is got from the graph driver once, for all the paths it is
+// mounted at, and put back with its last mount.
+type inspectMounts struct {
+	sync.Mutex
+	byTarget map[string]*inspectMount
+	refs     map[string]int
+	paths    map[string]string
+}
+
+func newInspectMounts() *inspectMounts {
+	return &inspectMounts{
+		byTarget: make(map[string]*inspectMount),
+		refs:     make(map[string]int),
+		paths:    make(map[string]string),
+	}
+}
+
+// ImageMount mounts the filesystem of an image, or of a stopped container,
+// read-only at a host path.
+func (daemon *Daemon) ImageMount(job *engine.Job) engine.Status {
+	if len(job.Args) != 2 {
+		return job.Errorf("Usage: %s IMAGE|CONTAINER PATH", job.Name)
+	}
+	var (
+		name   = job.Args[0]
+		target = filepath.Clean(job.Args[1])
+		m      = &inspectMount{Target: target}
+	)
+	if !filepath.IsAbs(target) {
+		return job.Errorf("The mount path %s is not absolute", job.Args[1])
+	}
+	if fi, err := os.Stat(target); err != nil {
+		return job.Error(err)
+	} else if !fi.IsDir() {
+		return job.Errorf("The mount path %s is not a directory", target)
+	}
+
+	if container := daemon.Get(name); container != nil {
+		if container.State.IsRunning() {
+			return job.Errorf("Cannot mount the running container %s, stop it first", name)
+		}
+		m.ID = container.ID
+		m.Container = true
+	} else {
+		img, err := daemon.Repositories().LookupImage(name)
+		if err != nil {
+			return job.Error(err)
+		}
+		if img == nil {
+			return job.Errorf("No such image or container: %s", name)
+		}
+		m.ID = img.ID
+	}
+
+	if err := daemon.inspectMounts.mount(daemon, m); err != nil {
+		return job.Error(err)
+	}
+	job.Eng.Job("log", "mount", m.ID, target).Run()
+	return engine.StatusOK
+}
+
+// ImageUnmount unmounts an image or container mounted by ImageMount.
+func (daemon *Daemon) ImageUnmount(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s PATH", job.Name)
+	}
+	target := filepath.Clean(job.Args[0])
+	id, err := daemon.inspectMounts.unmount(daemon, target)
+	if err != nil {
+		return job.Error(err)
+	}
+	job.Eng.Job("log", "unmount", id, target).Run()
+	return engine.StatusOK
+}
+
+// ImageMounts lists the mounts made by ImageMount.
+func (daemon *Daemon) ImageMounts(job *engine.Job) engine.Status {
+	s := daemon.inspectMounts
+	s.Lock()
+	targets := make([]string, 0, len(s.byTarget))
+	for target := range s.byTarget {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	outs := engine.NewTable("", len(targets))
+	for _, target := range targets {
+		m := s.byTarget[target]
+		out := &engine.Env{}
+		out.Set("Id", m.ID)
+		out.SetBool("Container", m.Container)
+		out.Set("Target", m.Target)
+		outs.Add(out)
+	}
+	s.Unlock()
+	if _, err := outs.WriteListTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+func (s *inspectMounts) mount(daemon *Daemon, m *inspectMount) error {
+	s.Lock()
+	defer s.Unlock()
+	if _, exists := s.byTarget[m.Target]; exists {
+		return fmt.Errorf("%s is already mounted", m.Target)
+	}
+	if s.refs[m.ID] == 0 {
+		path, err := daemon.driver.Get(m.ID, "")
+		if err != nil {
+			return fmt.Errorf("Error getting %s from driver %s: %s", m.ID, daemon.driver, err)
+		}
+		s.paths[m.ID] = path
+	}
+	if err := mount.Mount(s.paths[m.ID], m.Target, "none", "bind,ro"); err != nil {
+		s.release(daemon, m.ID)
+		return fmt.Errorf("Error mounting %s at %s: %s", m.ID, m.Target, err)
+	}
+	s.refs[m.ID]++
+	s.byTarget[m.Target] = m
+	return nil
+}
+
+func (s *inspectMounts) unmount(daemon *Daemon, target string) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+	m, exists := s.byTarget[target]
+	if !exists {
+		return "", fmt.Errorf("No image or container mounted at %s", target)
+	}
+	if err := mount.Unmount(target); err != nil {
+		return "", fmt.Errorf("Error unmounting %s: %s", target, err)
+	}
+	delete(s.byTarget, target)
+	s.refs[m.ID]--
+	s.release(daemon, m.ID)
+	return m.ID, nil
+}
+
+// release puts the filesystem of id back to the graph driver once it is
+// not mounted anymore. It is called with s locked.
+func (s *inspectMounts) release(daemon *Daemon, id string) {
+	if s.refs[id] > 0 {
+		return
+	}
+	delete(s.refs, id)
+	delete(s.paths, id)
+	daemon.driver.Put(id)
+}
+
+// forceUnmount unmounts every inspection mount of id, before the container
+// id starts or the image or container id is removed.
+func (s *inspectMounts) forceUnmount(daemon *Daemon, id string) {
+	if s == nil {
+		return
+	}
+	s.Lock()
+	var targets []string
+	for target, m := range s.byTarget {
+		if m.ID == id {
+			targets = append(targets, target)
+		}
+	}
+	s.Unlock()
+	for _, target := range targets {
+		log.Infof("Unmounting %s from %s", id, target)
+		if _, err := s.unmount(daemon, target); err != nil {
+			log.Errorf("%s", err)
+		}
+	}
+}
+
+// unmountAll unmounts all the inspection mounts, when the daemon shuts down.
+func (s *inspectMounts) unmountAll(daemon *Daemon) {
+	if s == nil {
+		return
+	}
+	s.Lock()
+	ids := make(map[string]bool)
+	for _, m := range s.byTarget {
+		ids[m.ID] = true
+	}
+	s.Unlock()
+	for id := range ids {
+		s.forceUnmount(daemon, id)
+	}
+}
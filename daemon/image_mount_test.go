@@ -0,0 +1,82 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// countingDriver is a graph driver giving the same directory for every
+// layer and counting the layers in use.
+type countingDriver struct {
+	dir  string
+	refs map[string]int
+}
+
+func (d *countingDriver) String() string                 { return "counting" }
+func (d *countingDriver) Create(id, parent string) error { return nil }
+func (d *countingDriver) Remove(id string) error         { return nil }
+func (d *countingDriver) Exists(id string) bool          { return true }
+func (d *countingDriver) Status() [][2]string            { return nil }
+func (d *countingDriver) Cleanup() error                 { return nil }
+func (d *countingDriver) Put(id string)                  { d.refs[id]-- }
+func (d *countingDriver) Get(id, label string) (string, error) {
+	d.refs[id]++
+	return d.dir, nil
+}
+
+func TestInspectMounts(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("mounting requires root")
+	}
+	root, err := ioutil.TempDir("", "docker-image-mount-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	for _, dir := range []string{"layer", "mnt1", "mnt2"} {
+		if err := os.Mkdir(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "layer", "file"), []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	driver := &countingDriver{dir: filepath.Join(root, "layer"), refs: make(map[string]int)}
+	daemon := &Daemon{driver: driver, inspectMounts: newInspectMounts()}
+	s := daemon.inspectMounts
+
+	for _, target := range []string{"mnt1", "mnt2"} {
+		if err := s.mount(daemon, &inspectMount{ID: "image", Target: filepath.Join(root, target)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if driver.refs["image"] != 1 {
+		t.Fatalf("Expected the layer to be got once, got %d", driver.refs["image"])
+	}
+	if err := s.mount(daemon, &inspectMount{ID: "other", Target: filepath.Join(root, "mnt1")}); err == nil {
+		t.Fatal("Expected an error mounting at a used path")
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(root, "mnt2", "file")); err != nil || string(data) != "content" {
+		t.Fatalf("Expected the layer content, got %q (%v)", data, err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "mnt2", "new"), nil, 0644); err == nil {
+		t.Fatal("Expected the mount to be read-only")
+	}
+
+	if _, err := s.unmount(daemon, filepath.Join(root, "mnt1")); err != nil {
+		t.Fatal(err)
+	}
+	if driver.refs["image"] != 1 {
+		t.Fatal("Expected the layer to be kept while still mounted")
+	}
+	s.forceUnmount(daemon, "image")
+	if driver.refs["image"] != 0 || len(s.byTarget) != 0 {
+		t.Fatalf("Expected all the mounts to be released, got %d refs and %d mounts", driver.refs["image"], len(s.byTarget))
+	}
+	if _, err := s.unmount(daemon, filepath.Join(root, "mnt1")); err == nil {
+		t.Fatal("Expected an error unmounting a path not mounted")
+	}
+}
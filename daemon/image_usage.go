@@ -0,0 +1,79 @@
+package daemon
+
+import (
+	"time"
+
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/image"
+)
+
+// ImageContainers lists the containers, running or not, created from an
+// image or from one of its children, with the last time each was used.
+func (daemon *Daemon) ImageContainers(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s IMAGE", job.Name)
+	}
+	img, err := daemon.repositories.LookupImage(job.Args[0])
+	if err != nil {
+		return job.Error(err)
+	}
+	if img == nil {
+		return job.Errorf("No such image: %s", job.Args[0])
+	}
+	containers, err := daemon.imageUsers(img.ID)
+	if err != nil {
+		return job.Error(err)
+	}
+
+	outs := engine.NewTable("", 0)
+	for _, container := range containers {
+		out := &engine.Env{}
+		out.Set("Id", container.ID)
+		out.Set("Name", container.Name)
+		out.Set("Image", container.Image)
+		out.SetBool("Running", container.State.IsRunning())
+		out.SetInt64("Created", container.Created.Unix())
+		out.SetInt64("LastUsed", container.lastUsed().Unix())
+		outs.Add(out)
+	}
+	if _, err := outs.WriteListTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+// imageUsers returns the containers whose image is imgID or one of its
+// children.
+func (daemon *Daemon) imageUsers(imgID string) ([]*Container, error) {
+	var containers []*Container
+	for _, container := range daemon.List() {
+		parent, err := daemon.repositories.LookupImage(container.Image)
+		if err != nil {
+			return nil, err
+		}
+		if err := parent.WalkHistory(func(p *image.Image) error {
+			if p.ID == imgID {
+				containers = append(containers, container)
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return containers, nil
+}
+
+// lastUsed returns the last time the container ran, or its creation time
+// if it never did.
+func (container *Container) lastUsed() time.Time {
+	if container.State.IsRunning() {
+		return time.Now().UTC()
+	}
+	last := container.Created
+	for _, t := range []time.Time{container.State.StartedAt, container.State.FinishedAt} {
+		if t.After(last) {
+			last = t
+		}
+	}
+	return last
+}
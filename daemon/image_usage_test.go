@@ -0,0 +1,29 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+)
+
+func TestContainerLastUsed(t *testing.T) {
+	created := time.Date(2014, 9, 1, 12, 0, 0, 0, time.UTC)
+	container := &Container{Created: created, State: NewState()}
+	if last := container.lastUsed(); !last.Equal(created) {
+		t.Fatalf("Expected a container which never ran to be last used at its creation, got %s", last)
+	}
+
+	container.State.StartedAt = created.Add(time.Hour)
+	container.State.FinishedAt = created.Add(2 * time.Hour)
+	if last := container.lastUsed(); !last.Equal(created.Add(2 * time.Hour)) {
+		t.Fatalf("Expected the container to be last used when it finished, got %s", last)
+	}
+}
+
+func TestDescribeUsers(t *testing.T) {
+	if s := describeUsers("running ", []string{"4386fb97867d"}); s != "the running container 4386fb97867d is" {
+		t.Fatalf("Unexpected description %q", s)
+	}
+	if s := describeUsers("", []string{"4386fb97867d", "8dfafdbc3a40"}); s != "the containers 4386fb97867d, 8dfafdbc3a40 are" {
+		t.Fatalf("Unexpected description %q", s)
+	}
+}
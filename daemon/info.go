@@ -1,6 +1,7 @@
 package daemon
 
 import (
+	"fmt"
 	"os"
 	"runtime"
 
@@ -22,7 +23,8 @@ func (daemon *Daemon) CmdInfo(job *engine.Job) engine.Status {
 		imgcount = len(images)
 	}
 	kernelVersion := "<unknown>"
-	if kv, err := kernel.GetKernelVersion(); err == nil {
+	kv, err := kernel.GetKernelVersion()
+	if err == nil {
 		kernelVersion = kv.String()
 	}
 
@@ -65,10 +67,55 @@ func (daemon *Daemon) CmdInfo(job *engine.Job) engine.Status {
 	v.Set("KernelVersion", kernelVersion)
 	v.Set("OperatingSystem", operatingSystem)
 	v.Set("IndexServerAddress", registry.IndexServerAddress())
+	v.SetList("RegistryMirrors", daemon.config.Mirrors)
 	v.Set("InitSha1", dockerversion.INITSHA1)
 	v.Set("InitPath", initPath)
+	v.SetBool("Draining", daemon.drain.IsActive())
+	v.SetList("Warnings", daemon.warnings(job.Eng, kv))
+	if daemon.usage != nil {
+		v.SetJson("Usage", daemon.usage.info())
+	}
 	if _, err := v.WriteTo(job.Stdout); err != nil {
 		return job.Error(err)
 	}
 	return engine.StatusOK
 }
+
+// warnings returns the problems found with the host and the configuration
+// of the daemon which are worth fixing but do not prevent it from running.
+func (daemon *Daemon) warnings(eng *engine.Engine, kv *kernel.KernelVersionInfo) []string {
+	warnings := []string{}
+	if sys := daemon.SystemConfig(); sys != nil {
+		if !sys.MemoryLimit {
+			warnings = append(warnings, "No memory limit support")
+		}
+		if !sys.SwapLimit {
+			warnings = append(warnings, "No swap limit support")
+		}
+		if sys.IPv4ForwardingDisabled {
+			warnings = append(warnings, "IPv4 forwarding is disabled, containers cannot reach the outside network")
+		}
+	}
+	if kv != nil && os.Getenv("DOCKER_NOWARN_KERNEL_VERSION") == "" && kernel.CompareKernelVersion(kv, &kernel.KernelVersionInfo{Kernel: 3, Major: 8, Minor: 0}) < 0 {
+		warnings = append(warnings, fmt.Sprintf("Linux kernel %s might be unstable running docker, please upgrade it to 3.8.0 or later", kv))
+	}
+	for _, pair := range daemon.GraphDriver().Status() {
+		if pair[0] == "Data file" && pair[1] != "" {
+			warnings = append(warnings, fmt.Sprintf("The %s storage driver uses loopback devices, which are slow and not suitable for production", daemon.GraphDriver()))
+			break
+		}
+	}
+	if !daemon.config.DisableNetwork {
+		job := eng.Job("network_warnings")
+		out, _ := job.Stdout.AddEnv()
+		if err := job.Run(); err != nil {
+			log.Errorf("Error checking the network: %s", err)
+		} else {
+			warnings = append(warnings, out.GetList("Warnings")...)
+		}
+	}
+	if daemon.drain.IsActive() {
+		warnings = append(warnings, "The daemon is draining and will not create or start containers")
+	}
+	return warnings
+}
@@ -3,6 +3,7 @@ package daemon
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 
 	"github.com/docker/docker/engine"
 	"github.com/docker/docker/runconfig"
@@ -35,16 +36,20 @@ func (daemon *Daemon) ContainerInspect(job *engine.Job) engine.Status {
 		out.SetList("Args", container.Args)
 		out.SetJson("Config", container.Config)
 		out.SetJson("State", container.State)
+		out.SetInt("RestartCount", container.RestartCount)
 		out.Set("Image", container.Image)
+		out.Set("ImageDigest", container.ImageDigest)
 		out.SetJson("NetworkSettings", container.NetworkSettings)
 		out.Set("ResolvConfPath", container.ResolvConfPath)
 		out.Set("HostnamePath", container.HostnamePath)
 		out.Set("HostsPath", container.HostsPath)
+		out.Set("MetadataPath", container.MetadataPath)
 		out.Set("Name", container.Name)
 		out.Set("Driver", container.Driver)
 		out.Set("ExecDriver", container.ExecDriver)
 		out.Set("MountLabel", container.MountLabel)
 		out.Set("ProcessLabel", container.ProcessLabel)
+		out.Set("SeccompProfile", container.SeccompProfile)
 		out.SetJson("Volumes", container.Volumes)
 		out.SetJson("VolumesRW", container.VolumesRW)
 
@@ -56,6 +61,15 @@ func (daemon *Daemon) ContainerInspect(job *engine.Job) engine.Status {
 
 		out.SetJson("HostConfig", container.hostConfig)
 
+		// the links to this container, as PARENT:LINK like the links of
+		// HostConfig
+		linkedBy := []string{}
+		for linkName, parent := range daemon.Parents(container.ID) {
+			linkedBy = append(linkedBy, fmt.Sprintf("%s:%s", parent.Name, linkName))
+		}
+		sort.Strings(linkedBy)
+		out.SetList("LinkedBy", linkedBy)
+
 		container.hostConfig.Links = nil
 		if _, err := out.WriteTo(job.Stdout); err != nil {
 			return job.Error(err)
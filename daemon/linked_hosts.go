@@ -0,0 +1,83 @@
+package daemon
+
+import (
+	"path"
+	"sync"
+
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/pkg/networkfs/etchosts"
+)
+
+// linkedHosts tracks the entries the hosts files of the containers have for
+// the containers they link to. A linked container gets a new address each
+// time it starts, so the entries of its parents are then rewritten.
+type linkedHosts struct {
+	sync.Mutex
+	// parents maps the ID of a linked container to its aliases in the
+	// hosts files of its parents, by parent ID
+	parents map[string]map[string][]string
+}
+
+func newLinkedHosts() *linkedHosts {
+	return &linkedHosts{
+		parents: make(map[string]map[string][]string),
+	}
+}
+
+// track records the entries the hosts file of parent has for its children,
+// by link name, in place of the previous ones.
+func (l *linkedHosts) track(parent *Container, children map[string]*Container) {
+	l.Lock()
+	defer l.Unlock()
+
+	for _, aliases := range l.parents {
+		delete(aliases, parent.ID)
+	}
+	for linkName, child := range children {
+		_, alias := path.Split(linkName)
+		if l.parents[child.ID] == nil {
+			l.parents[child.ID] = make(map[string][]string)
+		}
+		l.parents[child.ID][parent.ID] = append(l.parents[child.ID][parent.ID], alias)
+	}
+}
+
+// forget stops tracking a removed container, as a parent and as a child.
+func (l *linkedHosts) forget(id string) {
+	l.Lock()
+	defer l.Unlock()
+
+	delete(l.parents, id)
+	for _, aliases := range l.parents {
+		delete(aliases, id)
+	}
+}
+
+// update rewrites the entries the hosts files of the running parents of
+// child have for it with its current address.
+func (l *linkedHosts) update(daemon *Daemon, child *Container) {
+	ip := child.NetworkSettings.IPAddress
+	if ip == "" {
+		return
+	}
+
+	l.Lock()
+	parents := make(map[string][]string, len(l.parents[child.ID]))
+	for id, aliases := range l.parents[child.ID] {
+		parents[id] = aliases
+	}
+	l.Unlock()
+
+	for id, aliases := range parents {
+		parent := daemon.containers.Get(id)
+		// the hosts file of a stopped parent is written again when it starts
+		if parent == nil || !parent.State.IsRunning() || parent.HostsPath == "" {
+			continue
+		}
+		for _, alias := range aliases {
+			if err := etchosts.Add(parent.HostsPath, ip, alias); err != nil {
+				log.Errorf("Error updating the hosts file of %s: %s", parent.ID, err)
+			}
+		}
+	}
+}
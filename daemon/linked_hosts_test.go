@@ -0,0 +1,59 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLinkedHostsUpdate(t *testing.T) {
+	root, err := ioutil.TempDir("", "docker-linked-hosts-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	newContainer := func(id string) *Container {
+		return &Container{
+			ID:              id,
+			State:           NewState(),
+			NetworkSettings: &NetworkSettings{},
+			HostsPath:       filepath.Join(root, id),
+		}
+	}
+	web, db := newContainer("web"), newContainer("db")
+	if err := ioutil.WriteFile(web.HostsPath, []byte("172.17.0.5\tdb\n172.17.0.5\tdatabase\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	web.State.SetRunning(42)
+
+	daemon := &Daemon{
+		containers:  &contStore{s: map[string]*Container{"web": web, "db": db}},
+		linkedHosts: newLinkedHosts(),
+	}
+	daemon.linkedHosts.track(web, map[string]*Container{"/web/db": db, "/web/database": db})
+
+	db.NetworkSettings.IPAddress = "172.17.0.9"
+	daemon.linkedHosts.update(daemon, db)
+	content, err := ioutil.ReadFile(web.HostsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, alias := range []string{"db", "database"} {
+		if !strings.Contains(string(content), "172.17.0.9\t"+alias+"\n") {
+			t.Fatalf("Expected %s to be updated, got %q", alias, content)
+		}
+	}
+	if strings.Contains(string(content), "172.17.0.5") {
+		t.Fatalf("Expected the previous address to be removed, got %q", content)
+	}
+
+	daemon.linkedHosts.forget(web.ID)
+	db.NetworkSettings.IPAddress = "172.17.0.10"
+	daemon.linkedHosts.update(daemon, db)
+	if content, _ := ioutil.ReadFile(web.HostsPath); strings.Contains(string(content), "172.17.0.10") {
+		t.Fatalf("Expected a forgotten parent not to be updated, got %q", content)
+	}
+}
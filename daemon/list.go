@@ -12,6 +12,17 @@ import (
 	"github.com/docker/docker/pkg/parsers/filters"
 )
 
+// containerSortKeys maps the names the containers can be sorted by to
+// the keys of their description.
+var containerSortKeys = map[string]string{
+	"created": "Created",
+	"id":      "Id",
+	"image":   "Image",
+	"name":    "Names",
+	"size":    "SizeRw",
+	"status":  "Status",
+}
+
 // List returns an array of all containers registered in the daemon.
 func (daemon *Daemon) List() []*Container {
 	return daemon.containers.List()
@@ -29,7 +40,14 @@ func (daemon *Daemon) Containers(job *engine.Job) engine.Status {
 		psFilters   filters.Args
 		filt_exited []int
 	)
-	outs := engine.NewTable("Created", 0)
+	outs := engine.NewTable("", 0)
+	// the ids order the entries equal by the requested keys
+	outs.SetKeys("-Created", "Id")
+	if sort := job.Getenv("sort"); sort != "" {
+		if err := outs.SetSort(sort+",id", containerSortKeys); err != nil {
+			return job.Error(err)
+		}
+	}
 
 	psFilters, err := filters.FromParam(job.Getenv("filters"))
 	if err != nil {
@@ -104,6 +122,10 @@ func (daemon *Daemon) Containers(job *engine.Job) engine.Status {
 		out.Set("Id", container.ID)
 		out.SetList("Names", names[container.ID])
 		out.Set("Image", daemon.Repositories().ImageName(container.Image))
+		out.Set("ImageDigest", container.ImageDigest)
+		if labels, err := daemon.graph.Annotations(container.Image); err == nil && len(labels) > 0 {
+			out.SetJson("Labels", labels)
+		}
 		if len(container.Args) > 0 {
 			args := []string{}
 			for _, arg := range container.Args {
@@ -121,6 +143,7 @@ func (daemon *Daemon) Containers(job *engine.Job) engine.Status {
 		}
 		out.SetInt64("Created", container.Created.Unix())
 		out.Set("Status", container.State.String())
+		out.SetInt("RestartCount", container.RestartCount)
 		str, err := container.NetworkSettings.PortMappingAPI().ToListString()
 		if err != nil {
 			return err
@@ -143,7 +166,7 @@ func (daemon *Daemon) Containers(job *engine.Job) engine.Status {
 			break
 		}
 	}
-	outs.ReverseSort()
+	outs.Sort()
 	if _, err := outs.WriteListTo(job.Stdout); err != nil {
 		return job.Error(err)
 	}
@@ -0,0 +1,78 @@
+package daemon
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+
+	"github.com/docker/docker/pkg/ulimit"
+)
+
+// metadataMountPath is where the metadata of a container is mounted, read
+// only, inside it.
+const metadataMountPath = "/etc/docker/container.json"
+
+// containerMetadata is what a container is told of itself, so that its
+// applications can identify themselves without the socket of the daemon.
+type containerMetadata struct {
+	ID       string
+	Name     string
+	Hostname string
+	Image    string
+	ImageID  string
+	// Labels are the annotations of the image of the container
+	Labels    map[string]string
+	Resources containerResources
+}
+
+// containerResources are the resource limits of a container, 0 or empty
+// when unlimited.
+type containerResources struct {
+	Memory      int64
+	MemorySwap  int64
+	CpuShares   int64
+	CpuQuota    int64
+	CpuPeriod   int64
+	Cpuset      string
+	BlkioWeight int64
+	Ulimits     []*ulimit.Ulimit
+}
+
+// writeMetadataFile writes the metadata of the container, mounted at
+// metadataMountPath. It is written again when the container starts and
+// when its resources are updated.
+func (container *Container) writeMetadataFile() error {
+	metadataPath, err := container.getRootResourcePath("metadata.json")
+	if err != nil {
+		return err
+	}
+	container.MetadataPath = metadataPath
+
+	labels, err := container.daemon.graph.Annotations(container.Image)
+	if err != nil {
+		return err
+	}
+	metadata := &containerMetadata{
+		ID:       container.ID,
+		Name:     strings.TrimPrefix(container.Name, "/"),
+		Hostname: container.Config.Hostname,
+		Image:    container.Config.Image,
+		ImageID:  container.Image,
+		Labels:   labels,
+		Resources: containerResources{
+			Memory:      container.Config.Memory,
+			MemorySwap:  container.Config.MemorySwap,
+			CpuShares:   container.Config.CpuShares,
+			CpuQuota:    container.Config.CpuQuota,
+			CpuPeriod:   container.Config.CpuPeriod,
+			Cpuset:      container.Config.Cpuset,
+			BlkioWeight: container.Config.BlkioWeight,
+			Ulimits:     container.hostConfig.Ulimits,
+		},
+	}
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(container.MetadataPath, append(data, '\n'), 0644)
+}
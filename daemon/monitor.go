@@ -1,6 +1,7 @@
 package daemon
 
 import (
+	"fmt"
 	"io"
 	"os/exec"
 	"sync"
@@ -49,6 +50,10 @@ type containerMonitor struct {
 
 	// lastStartTime is the time which the monitor last exec'd the container's process
 	lastStartTime time.Time
+
+	// restartTimes holds the times at which the restart policy restarted the
+	// container within the daemon's flapping window
+	restartTimes []time.Time
 }
 
 // newContainerMonitor returns an initialized containerMonitor for the provided container
@@ -127,9 +132,16 @@ func (m *containerMonitor) Start() error {
 			return err
 		}
 
+		if err := m.container.runLifecycleHooks("pre-start", m.container.hostConfig.PreStart); err != nil {
+			m.resetContainer()
+
+			return err
+		}
+
 		pipes := execdriver.NewPipes(m.container.stdin, m.container.stdout, m.container.stderr, m.container.Config.OpenStdin)
 
 		m.container.LogEvent("start")
+		m.container.daemon.usage.containerStarted()
 
 		m.lastStartTime = time.Now()
 
@@ -150,20 +162,33 @@ func (m *containerMonitor) Start() error {
 
 		m.resetMonitor(err == nil && exitStatus == 0)
 
-		if m.shouldRestart(exitStatus) {
+		shouldRestart := m.shouldRestart(exitStatus)
+		if shouldRestart && m.isFlapping(time.Now()) {
+			log.Infof("container %s restarted more than %d times in %d minutes, giving up", m.container.ID, m.container.daemon.config.RestartFlapCount, m.container.daemon.config.RestartFlapWindow)
+
+			m.container.LogEvent("flapping")
+
+			shouldRestart = false
+		}
+
+		if shouldRestart {
+			m.restartTimes = append(m.restartTimes, time.Now())
+
 			m.container.State.SetRestarting(exitStatus)
 
 			m.container.LogEvent("die")
 
 			m.resetContainer()
 
+			m.runPostStopHooks(exitStatus)
+
 			// sleep with a small time increment between each restart to help avoid issues cased by quickly
 			// restarting the container because of some types of errors ( networking cut out, etc... )
 			m.waitForNextRestart()
 
 			// we need to check this before reentering the loop because the waitForNextRestart could have
-			// been terminated by a request from a user
-			if m.shouldStop {
+			// been terminated by a request from a user, or the daemon started draining in the meantime
+			if m.shouldStop || m.isDraining() {
 				m.container.State.SetStopped(exitStatus)
 
 				return err
@@ -178,6 +203,8 @@ func (m *containerMonitor) Start() error {
 
 		m.resetContainer()
 
+		m.runPostStopHooks(exitStatus)
+
 		break
 	}
 
@@ -226,6 +253,12 @@ func (m *containerMonitor) shouldRestart(exitStatus int) bool {
 		return false
 	}
 
+	// nor while the daemon is draining, which refuses to start containers
+	if m.isDraining() {
+		log.Infof("not restarting container %s, the daemon is draining", m.container.ID)
+		return false
+	}
+
 	switch m.restartPolicy.Name {
 	case "always":
 		return true
@@ -242,6 +275,34 @@ func (m *containerMonitor) shouldRestart(exitStatus int) bool {
 	return false
 }
 
+// isDraining returns true if the daemon of the container is draining
+func (m *containerMonitor) isDraining() bool {
+	drain := m.container.daemon.drain
+	return drain != nil && drain.IsActive()
+}
+
+// isFlapping returns true if the container has already been restarted the
+// maximum number of times allowed by the daemon within the flapping window
+func (m *containerMonitor) isFlapping(now time.Time) bool {
+	config := m.container.daemon.config
+	if config == nil || config.RestartFlapCount <= 0 {
+		return false
+	}
+
+	var (
+		window = time.Duration(config.RestartFlapWindow) * time.Minute
+		recent = m.restartTimes[:0]
+	)
+	for _, t := range m.restartTimes {
+		if now.Sub(t) < window {
+			recent = append(recent, t)
+		}
+	}
+	m.restartTimes = recent
+
+	return len(recent) >= config.RestartFlapCount
+}
+
 // callback ensures that the container's state is properly updated after we
 // received ack from the execution drivers
 func (m *containerMonitor) callback(command *execdriver.Command) {
@@ -269,6 +330,14 @@ func (m *containerMonitor) callback(command *execdriver.Command) {
 	}
 }
 
+// runPostStopHooks runs the post-stop hooks of the container with its exit
+// status, only logging their failures as the container already stopped.
+func (m *containerMonitor) runPostStopHooks(exitStatus int) {
+	if err := m.container.runLifecycleHooks("post-stop", m.container.hostConfig.PostStop, fmt.Sprintf("DOCKER_EXIT_CODE=%d", exitStatus)); err != nil {
+		log.Errorf("%s: %s", m.container.ID, err)
+	}
+}
+
 // resetContainer resets the container's IO and ensures that the command is able to be executed again
 // by copying the data into a new struct
 func (m *containerMonitor) resetContainer() {
@@ -0,0 +1,55 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/docker/runconfig"
+)
+
+func TestMonitorIsFlapping(t *testing.T) {
+	var (
+		config    = &Config{RestartFlapCount: 3, RestartFlapWindow: 1}
+		container = &Container{daemon: &Daemon{config: config}}
+		m         = &containerMonitor{container: container}
+		now       = time.Now()
+	)
+
+	m.restartTimes = []time.Time{now.Add(-2 * time.Minute), now.Add(-30 * time.Second), now.Add(-10 * time.Second)}
+	if m.isFlapping(now) {
+		t.Fatal("Restarts outside of the window should not be counted")
+	}
+	if len(m.restartTimes) != 2 {
+		t.Fatalf("Expected expired restarts to be dropped, got %d restarts", len(m.restartTimes))
+	}
+
+	m.restartTimes = append(m.restartTimes, now.Add(-1*time.Second))
+	if !m.isFlapping(now) {
+		t.Fatal("Container restarted 3 times in a minute should be flapping")
+	}
+
+	config.RestartFlapCount = 0
+	if m.isFlapping(now) {
+		t.Fatal("Flapping detection should be disabled")
+	}
+}
+
+func TestMonitorShouldRestartWhileDraining(t *testing.T) {
+	var (
+		daemon    = &Daemon{config: &Config{}, drain: &drainState{}}
+		container = &Container{ID: "4386fb97867d", daemon: daemon}
+		m         = &containerMonitor{container: container, restartPolicy: runconfig.RestartPolicy{Name: "always"}}
+	)
+
+	if !m.shouldRestart(0) {
+		t.Fatal("Container should be restarted by the always policy")
+	}
+	daemon.drain.active = true
+	if m.shouldRestart(0) {
+		t.Fatal("Container should not be restarted while the daemon is draining")
+	}
+	daemon.drain.active = false
+	if !m.shouldRestart(1) {
+		t.Fatal("Container should be restarted again once the drain is over")
+	}
+}
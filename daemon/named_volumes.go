@@ -0,0 +1,267 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/daemon/volumedriver"
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/utils"
+)
+
+// volumeStore keeps track of the named volumes and of the driver
+// providing each of them.
+type volumeStore struct {
+	path    string
+	local   volumedriver.Driver
+	Volumes map[string]string // volume name -> driver name
+	sync.Mutex
+}
+
+func newVolumeStore(path string, local volumedriver.Driver) (*volumeStore, error) {
+	store := &volumeStore{
+		path:    path,
+		local:   local,
+		Volumes: make(map[string]string),
+	}
+	// Load the json file if it exists, otherwise create it.
+	if err := store.reload(); os.IsNotExist(err) {
+		if err := store.save(); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (store *volumeStore) save() error {
+	jsonData, err := json.Marshal(store)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(store.path, jsonData, 0600)
+}
+
+func (store *volumeStore) reload() error {
+	jsonData, err := ioutil.ReadFile(store.path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(jsonData, store)
+}
+
+func (store *volumeStore) driver(name string) (volumedriver.Driver, error) {
+	if name == "" || name == volumedriver.LocalDriverName {
+		return store.local, nil
+	}
+	driver, err := volumedriver.GetDriver(name)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot use volume driver %s: %s", name, err)
+	}
+	return driver, nil
+}
+
+// Create makes sure the volume exists with the given driver. It is not an
+// error to create an existing volume as long as the driver is the same.
+func (store *volumeStore) Create(name, driverName string) (volumedriver.Driver, error) {
+	if driverName == "" {
+		driverName = volumedriver.LocalDriverName
+	}
+	if !validVolumeNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("Invalid volume name (%s), only [a-zA-Z0-9][a-zA-Z0-9_.-] are allowed", name)
+	}
+	driver, err := store.driver(driverName)
+	if err != nil {
+		return nil, err
+	}
+
+	store.Lock()
+	defer store.Unlock()
+	if existing, exists := store.Volumes[name]; exists && existing != driverName {
+		return nil, fmt.Errorf("Conflict, volume %s already exists with driver %s", name, existing)
+	}
+	if err := driver.Create(name); err != nil {
+		return nil, err
+	}
+	store.Volumes[name] = driverName
+	return driver, store.save()
+}
+
+// Get returns the name of the driver of a volume.
+func (store *volumeStore) Get(name string) (string, bool) {
+	store.Lock()
+	defer store.Unlock()
+	driverName, exists := store.Volumes[name]
+	return driverName, exists
+}
+
+func (store *volumeStore) Remove(name string) error {
+	driverName, exists := store.Get(name)
+	if !exists {
+		return fmt.Errorf("No such volume: %s", name)
+	}
+	driver, err := store.driver(driverName)
+	if err != nil {
+		return err
+	}
+	if err := driver.Remove(name); err != nil {
+		return err
+	}
+
+	store.Lock()
+	defer store.Unlock()
+	delete(store.Volumes, name)
+	return store.save()
+}
+
+// List returns the names of the volumes, sorted.
+func (store *volumeStore) List() []string {
+	store.Lock()
+	names := make([]string, 0, len(store.Volumes))
+	for name := range store.Volumes {
+		names = append(names, name)
+	}
+	store.Unlock()
+	sort.Strings(names)
+	return names
+}
+
+func (store *volumeStore) env(name string) (*engine.Env, error) {
+	driverName, exists := store.Get(name)
+	if !exists {
+		return nil, fmt.Errorf("No such volume: %s", name)
+	}
+	out := &engine.Env{}
+	out.Set("Name", name)
+	out.Set("Driver", driverName)
+	if driver, err := store.driver(driverName); err == nil {
+		// plugins may not know the path of a volume which is not mounted
+		if mountpoint, err := driver.Path(name); err == nil {
+			out.Set("Mountpoint", mountpoint)
+		}
+	}
+	return out, nil
+}
+
+// volumeUsedBy returns the containers having the volume among their named volumes.
+func (daemon *Daemon) volumeUsedBy(name string) []*Container {
+	driverName, _ := daemon.namedVolumes.Get(name)
+	var containers []*Container
+	for _, container := range daemon.List() {
+		if container.hostConfig == nil {
+			continue
+		}
+		containerDriver := container.hostConfig.VolumeDriver
+		if containerDriver == "" {
+			containerDriver = volumedriver.LocalDriverName
+		}
+		if containerDriver != driverName {
+			continue
+		}
+		if containerUsesVolume(container, name) {
+			containers = append(containers, container)
+		}
+	}
+	return containers
+}
+
+// containerUsesVolume returns whether the named volume is among the volumes
+// the container mounted, or among its binds, which are only mounted once it
+// starts.
+func containerUsesVolume(container *Container, name string) bool {
+	for _, volume := range container.NamedVolumes {
+		if volume == name {
+			return true
+		}
+	}
+	for _, bind := range container.hostConfig.Binds {
+		if vol, err := parseBindVolumeSpec(bind); err == nil && vol.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// List the named volumes
+func (daemon *Daemon) VolumeList(job *engine.Job) engine.Status {
+	outs := engine.NewTable("", 0)
+	for _, name := range daemon.namedVolumes.List() {
+		out, err := daemon.namedVolumes.env(name)
+		if err != nil {
+			continue
+		}
+		outs.Add(out)
+	}
+	if _, err := outs.WriteListTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+// Create a named volume. A name is generated when none is given.
+func (daemon *Daemon) VolumeCreate(job *engine.Job) engine.Status {
+	if len(job.Args) > 1 {
+		return job.Errorf("Usage: %s [NAME]", job.Name)
+	}
+	var name string
+	if len(job.Args) == 1 {
+		name = job.Args[0]
+	}
+	if name == "" {
+		name = utils.GenerateRandomID()
+	}
+	if _, err := daemon.namedVolumes.Create(name, job.Getenv("Driver")); err != nil {
+		return job.Error(err)
+	}
+	out, err := daemon.namedVolumes.env(name)
+	if err != nil {
+		return job.Error(err)
+	}
+	if _, err := out.WriteTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+// Return low-level information about a named volume
+func (daemon *Daemon) VolumeInspect(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s NAME", job.Name)
+	}
+	out, err := daemon.namedVolumes.env(job.Args[0])
+	if err != nil {
+		return job.Error(err)
+	}
+	if _, err := out.WriteTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+// Remove a named volume and its data, unless a container uses it
+func (daemon *Daemon) VolumeRemove(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s NAME", job.Name)
+	}
+	name := job.Args[0]
+	if _, exists := daemon.namedVolumes.Get(name); !exists {
+		return job.Errorf("No such volume: %s", name)
+	}
+	if containers := daemon.volumeUsedBy(name); len(containers) > 0 {
+		ids := make([]string, len(containers))
+		for i, c := range containers {
+			ids[i] = utils.TruncateID(c.ID)
+		}
+		return job.Errorf("Conflict, volume %s is used by container(s) %s", name, strings.Join(ids, ", "))
+	}
+	if err := daemon.namedVolumes.Remove(name); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
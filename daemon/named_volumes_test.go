@@ -0,0 +1,110 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/daemon/volumedriver"
+	"github.com/docker/docker/runconfig"
+)
+
+func TestVolumeStore(t *testing.T) {
+	root, err := ioutil.TempDir("", "docker-volume-store-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	local, err := volumedriver.NewLocalDriver(filepath.Join(root, "volumes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := newVolumeStore(filepath.Join(root, "volumes.json"), local)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Create("data", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Create("data", "local"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Create("data", "missing"); err == nil {
+		t.Fatal("Expected an error with an unknown driver")
+	}
+	if _, err := store.Create("../data", ""); err == nil {
+		t.Fatal("Expected an error with an invalid name")
+	}
+	if _, err := os.Stat(filepath.Join(root, "volumes", "data")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the volumes are persisted
+	store, err = newVolumeStore(filepath.Join(root, "volumes.json"), local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if driver, exists := store.Get("data"); !exists || driver != "local" {
+		t.Fatalf("Expected volume data with driver local, got %q", driver)
+	}
+
+	if err := store.Remove("data"); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.List()) != 0 {
+		t.Fatalf("Expected no volumes, got %v", store.List())
+	}
+	if _, err := os.Stat(filepath.Join(root, "volumes", "data")); !os.IsNotExist(err) {
+		t.Fatal("The volume data should have been removed")
+	}
+}
+
+func TestVolumeUsedBy(t *testing.T) {
+	root, err := ioutil.TempDir("", "docker-volume-used-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	local, err := volumedriver.NewLocalDriver(filepath.Join(root, "volumes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := newVolumeStore(filepath.Join(root, "volumes.json"), local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"data", "logs", "cache"} {
+		if _, err := store.Create(name, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var (
+		// started, the volume is mounted
+		web = &Container{ID: "web", hostConfig: &runconfig.HostConfig{Binds: []string{"data:/data"}}, NamedVolumes: map[string]string{"/data": "data"}}
+		// created, the volume is only among the binds
+		db = &Container{ID: "db", hostConfig: &runconfig.HostConfig{Binds: []string{"/var/db:/db", "logs:/var/log:ro"}}}
+		// another driver provides its volume
+		other  = &Container{ID: "other", hostConfig: &runconfig.HostConfig{Binds: []string{"cache:/cache"}, VolumeDriver: "flocker"}}
+		daemon = &Daemon{
+			namedVolumes: store,
+			containers:   &contStore{s: map[string]*Container{"web": web, "db": db, "other": other}},
+		}
+	)
+	for name, expected := range map[string]string{"data": "web", "logs": "db", "cache": ""} {
+		containers := daemon.volumeUsedBy(name)
+		if expected == "" {
+			if len(containers) != 0 {
+				t.Fatalf("Expected volume %s to be unused, got %v", name, containers)
+			}
+			continue
+		}
+		if len(containers) != 1 || containers[0].ID != expected {
+			t.Fatalf("Expected volume %s to be used by %s, got %v", name, expected, containers)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package daemon
+
+import (
+	"strings"
+
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/pkg/networkfs/etchosts"
+)
+
+// ContainerNetworkConnect plugs a running container in an additional
+// network without restarting it. The containers already attached to the
+// network can reach it by name through their /etc/hosts.
+func (daemon *Daemon) ContainerNetworkConnect(job *engine.Job) engine.Status {
+	if len(job.Args) != 2 {
+		return job.Errorf("Usage: %s NETWORK CONTAINER\n", job.Name)
+	}
+	network, name := job.Args[0], job.Args[1]
+	container := daemon.Get(name)
+	if container == nil {
+		return job.Errorf("No such container: %s\n", name)
+	}
+	if !container.State.IsRunning() {
+		return job.Errorf("Container %s is not running\n", name)
+	}
+	mode := container.hostConfig.NetworkMode
+	if container.Config.NetworkDisabled || mode.IsContainer() || mode.IsHost() {
+		return job.Errorf("Cannot connect container %s: it does not use a bridge network\n", name)
+	}
+	if _, exists := container.NetworkSettings.Networks[network]; exists {
+		return job.Errorf("Conflict: container %s is already connected to network %s\n", name, network)
+	}
+
+	iface := daemon.eng.Job("connect_interface", container.ID, network)
+	iface.SetenvInt("Pid", container.State.GetPid())
+	iface.SetenvInt("Mtu", daemon.config.Mtu)
+	env, err := iface.Stdout.AddEnv()
+	if err != nil {
+		return job.Error(err)
+	}
+	if err := iface.Run(); err != nil {
+		return job.Error(err)
+	}
+
+	endpoint := &NetworkEndpoint{
+		IPAddress:   env.Get("IP"),
+		IPPrefixLen: env.GetInt("IPPrefixLen"),
+		Gateway:     env.Get("Gateway"),
+		Bridge:      env.Get("Bridge"),
+		Interface:   env.Get("Interface"),
+	}
+	peers := daemon.networkPeers(network, container)
+	if container.NetworkSettings.Networks == nil {
+		container.NetworkSettings.Networks = make(map[string]*NetworkEndpoint)
+	}
+	container.NetworkSettings.Networks[network] = endpoint
+	if err := container.ToDisk(); err != nil {
+		log.Errorf("Error saving container %s: %s", container.ID, err)
+	}
+
+	hostname := strings.TrimPrefix(container.Name, "/")
+	for _, peer := range peers {
+		if err := etchosts.Add(peer.HostsPath, endpoint.IPAddress, hostname); err != nil {
+			log.Errorf("Error updating the hosts file of %s: %s", peer.ID, err)
+		}
+		if container.HostsPath == "" {
+			continue
+		}
+		peerName := strings.TrimPrefix(peer.Name, "/")
+		if err := etchosts.Add(container.HostsPath, peer.NetworkSettings.Networks[network].IPAddress, peerName); err != nil {
+			log.Errorf("Error updating the hosts file of %s: %s", container.ID, err)
+		}
+	}
+	container.LogEvent("connect")
+	return engine.StatusOK
+}
+
+// ContainerNetworkDisconnect unplugs a container from a network it joined
+// with ContainerNetworkConnect.
+func (daemon *Daemon) ContainerNetworkDisconnect(job *engine.Job) engine.Status {
+	if len(job.Args) != 2 {
+		return job.Errorf("Usage: %s NETWORK CONTAINER\n", job.Name)
+	}
+	network, name := job.Args[0], job.Args[1]
+	container := daemon.Get(name)
+	if container == nil {
+		return job.Errorf("No such container: %s\n", name)
+	}
+	if _, exists := container.NetworkSettings.Networks[network]; !exists {
+		return job.Errorf("Container %s is not connected to network %s\n", name, network)
+	}
+	if err := daemon.disconnectNetwork(container, network); err != nil {
+		return job.Error(err)
+	}
+	if err := container.ToDisk(); err != nil {
+		log.Errorf("Error saving container %s: %s", container.ID, err)
+	}
+	container.LogEvent("disconnect")
+	return engine.StatusOK
+}
+
+func (daemon *Daemon) disconnectNetwork(container *Container, network string) error {
+	if err := daemon.eng.Job("disconnect_interface", container.ID, network).Run(); err != nil {
+		return err
+	}
+	delete(container.NetworkSettings.Networks, network)
+
+	hostname := strings.TrimPrefix(container.Name, "/")
+	for _, peer := range daemon.networkPeers(network, container) {
+		if err := etchosts.Remove(peer.HostsPath, hostname); err != nil {
+			log.Errorf("Error updating the hosts file of %s: %s", peer.ID, err)
+		}
+		if container.HostsPath == "" {
+			continue
+		}
+		if err := etchosts.Remove(container.HostsPath, strings.TrimPrefix(peer.Name, "/")); err != nil {
+			log.Errorf("Error updating the hosts file of %s: %s", container.ID, err)
+		}
+	}
+	return nil
+}
+
+// networkPeers returns the running containers other than container which
+// are connected to network.
+func (daemon *Daemon) networkPeers(network string, container *Container) []*Container {
+	var peers []*Container
+	for _, c := range daemon.List() {
+		if c.ID == container.ID || !c.State.IsRunning() || c.HostsPath == "" {
+			continue
+		}
+		if _, exists := c.NetworkSettings.Networks[network]; exists {
+			peers = append(peers, c)
+		}
+	}
+	return peers
+}
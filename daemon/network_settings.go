@@ -15,6 +15,17 @@ type NetworkSettings struct {
 	Bridge      string
 	PortMapping map[string]PortMapping // Deprecated
 	Ports       nat.PortMap
+	Networks    map[string]*NetworkEndpoint // networks joined with network_connect
+}
+
+// NetworkEndpoint describes an interface plugged in a running container
+// in addition to the one it was started with.
+type NetworkEndpoint struct {
+	IPAddress   string
+	IPPrefixLen int
+	Gateway     string
+	Bridge      string
+	Interface   string
 }
 
 func (settings *NetworkSettings) PortMappingAPI() *engine.Table {
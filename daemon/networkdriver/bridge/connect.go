@@ -0,0 +1,237 @@
+package bridge
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"syscall"
+
+	"github.com/docker/docker/daemon/networkdriver/ipallocator"
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/libcontainer/netlink"
+	"github.com/docker/libcontainer/system"
+	"github.com/docker/libcontainer/utils"
+)
+
+const vethPrefix = "veth"
+
+func connectedKey(id, network string) string {
+	return id + "/" + network
+}
+
+// connectedTo returns true if any running container has been plugged
+// in the network with connect_interface
+func (i *ifaces) connectedTo(n *bridgeNetworkInfo) bool {
+	i.Lock()
+	defer i.Unlock()
+	for _, iface := range i.c {
+		if iface.Network == n {
+			return true
+		}
+	}
+	return false
+}
+
+// Plug a new interface attached to a network in the network namespace of
+// a running container
+func ConnectInterface(job *engine.Job) engine.Status {
+	if len(job.Args) != 2 {
+		return job.Errorf("Usage: %s CONTAINER NETWORK", job.Name)
+	}
+	var (
+		id   = job.Args[0]
+		name = job.Args[1]
+		pid  = job.GetenvInt("Pid")
+		mtu  = job.GetenvInt("Mtu")
+		key  = connectedKey(id, name)
+	)
+	n := networks.Get(name)
+	if n == nil {
+		return job.Errorf("No such network: %s", name)
+	}
+	if n.isDefault() {
+		return job.Errorf("Conflict: containers are attached to the %s network when they start", name)
+	}
+	if currentInterfaces.Get(key) != nil {
+		return job.Errorf("Conflict: container %s is already connected to network %s", id, name)
+	}
+	if pid <= 0 {
+		return job.Errorf("Container %s is not running", id)
+	}
+
+	ip, err := ipallocator.RequestIP(n.Network, nil)
+	if err != nil {
+		return job.Error(err)
+	}
+	hostVeth, ifaceName, err := plugVeth(n, *ip, pid, mtu)
+	if err != nil {
+		ipallocator.ReleaseIP(n.Network, ip)
+		return job.Error(err)
+	}
+	currentInterfaces.Set(key, &networkInterface{
+		IP:       *ip,
+		Network:  n,
+		HostVeth: hostVeth,
+	})
+
+	out := engine.Env{}
+	out.Set("IP", ip.String())
+	out.Set("Gateway", n.Network.IP.String())
+	out.Set("Bridge", n.Bridge)
+	out.Set("Interface", ifaceName)
+	size, _ := n.Network.Mask.Size()
+	out.SetInt("IPPrefixLen", size)
+	if _, err := out.WriteTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+// Unplug an interface added with connect_interface and release its address
+func DisconnectInterface(job *engine.Job) engine.Status {
+	if len(job.Args) != 2 {
+		return job.Errorf("Usage: %s CONTAINER NETWORK", job.Name)
+	}
+	var (
+		id    = job.Args[0]
+		name  = job.Args[1]
+		key   = connectedKey(id, name)
+		iface = currentInterfaces.Get(key)
+	)
+	if iface == nil {
+		return job.Errorf("Container %s is not connected to network %s", id, name)
+	}
+	// Deleting one end of the pair removes the other one from the
+	// container. The pair is already gone if the container stopped.
+	if err := netlink.NetworkLinkDel(iface.HostVeth); err != nil {
+		log.Debugf("Unable to delete %s: %s", iface.HostVeth, err)
+	}
+	if err := ipallocator.ReleaseIP(iface.Network.Network, &iface.IP); err != nil {
+		log.Infof("Unable to release ip %s", err)
+	}
+	currentInterfaces.Delete(key)
+	return engine.StatusOK
+}
+
+// plugVeth creates a veth pair, attaches one end to the bridge of the
+// network and moves the other one in the network namespace of pid where it
+// is configured with ip. It returns the name of the host end and of the
+// interface inside the container.
+func plugVeth(n *bridgeNetworkInfo, ip net.IP, pid, mtu int) (string, string, error) {
+	hostVeth, err := utils.GenerateRandomName(vethPrefix, 7)
+	if err != nil {
+		return "", "", err
+	}
+	childVeth, err := utils.GenerateRandomName(vethPrefix, 7)
+	if err != nil {
+		return "", "", err
+	}
+	if err := netlink.NetworkCreateVethPair(hostVeth, childVeth); err != nil {
+		return "", "", err
+	}
+
+	var ifaceName string
+	err = func() error {
+		host, err := net.InterfaceByName(hostVeth)
+		if err != nil {
+			return err
+		}
+		bridge, err := net.InterfaceByName(n.Bridge)
+		if err != nil {
+			return err
+		}
+		if err := netlink.AddToBridge(host, bridge); err != nil {
+			return err
+		}
+		if mtu > 0 {
+			if err := netlink.NetworkSetMTU(host, mtu); err != nil {
+				return err
+			}
+		}
+		if err := netlink.NetworkLinkUp(host); err != nil {
+			return err
+		}
+		child, err := net.InterfaceByName(childVeth)
+		if err != nil {
+			return err
+		}
+		if err := netlink.NetworkSetNsPid(child, pid); err != nil {
+			return err
+		}
+		return inNetNs(pid, func() error {
+			child, err := net.InterfaceByName(childVeth)
+			if err != nil {
+				return err
+			}
+			if ifaceName, err = nextInterfaceName(); err != nil {
+				return err
+			}
+			if err := netlink.NetworkChangeName(child, ifaceName); err != nil {
+				return err
+			}
+			if child, err = net.InterfaceByName(ifaceName); err != nil {
+				return err
+			}
+			ipNet := &net.IPNet{IP: ip, Mask: n.Network.Mask}
+			if err := netlink.NetworkLinkAddIp(child, ip, ipNet); err != nil {
+				return err
+			}
+			if mtu > 0 {
+				if err := netlink.NetworkSetMTU(child, mtu); err != nil {
+					return err
+				}
+			}
+			return netlink.NetworkLinkUp(child)
+		})
+	}()
+	if err != nil {
+		netlink.NetworkLinkDel(hostVeth)
+		return "", "", err
+	}
+	return hostVeth, ifaceName, nil
+}
+
+// nextInterfaceName returns the first ethN name not used in the current
+// network namespace
+func nextInterfaceName() (string, error) {
+	for i := 0; ; i++ {
+		name := fmt.Sprintf("eth%d", i)
+		if _, err := net.InterfaceByName(name); err != nil {
+			return name, nil
+		}
+		if i > 1000 {
+			return "", fmt.Errorf("Unable to find a free interface name")
+		}
+	}
+}
+
+// inNetNs runs fn with the current thread switched to the network
+// namespace of pid
+func inNetNs(pid int, fn func() error) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	origin, err := os.Open(fmt.Sprintf("/proc/self/task/%d/ns/net", syscall.Gettid()))
+	if err != nil {
+		return err
+	}
+	defer origin.Close()
+
+	target, err := os.Open(fmt.Sprintf("/proc/%d/ns/net", pid))
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+
+	if err := system.Setns(target.Fd(), syscall.CLONE_NEWNET); err != nil {
+		return err
+	}
+	defer func() {
+		if err := system.Setns(origin.Fd(), syscall.CLONE_NEWNET); err != nil {
+			log.Errorf("Unable to restore the network namespace of the daemon: %s", err)
+		}
+	}()
+	return fn()
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net"
+	"path"
 	"strings"
 	"sync"
 
@@ -15,12 +16,14 @@ import (
 	"github.com/docker/docker/pkg/iptables"
 	"github.com/docker/docker/pkg/log"
 	"github.com/docker/docker/pkg/networkfs/resolvconf"
+	"github.com/docker/docker/pkg/parsers"
 	"github.com/docker/docker/pkg/parsers/kernel"
 	"github.com/docker/libcontainer/netlink"
 )
 
 const (
 	DefaultNetworkBridge     = "docker0"
+	DefaultNetworkName       = "bridge"
 	MaxAllocatedPortAttempts = 10
 )
 
@@ -28,6 +31,10 @@ const (
 type networkInterface struct {
 	IP           net.IP
 	PortMappings []net.Addr // there are mappings to the host interfaces
+
+	// set for the interfaces plugged in a running container by connect_interface
+	Network  *bridgeNetworkInfo
+	HostVeth string
 }
 
 type ifaces struct {
@@ -48,6 +55,12 @@ func (i *ifaces) Get(key string) *networkInterface {
 	return res
 }
 
+func (i *ifaces) Delete(key string) {
+	i.Lock()
+	delete(i.c, key)
+	i.Unlock()
+}
+
 var (
 	addrs = []string{
 		// Here we don't follow the convention of using the 1st IP of the range for the gateway.
@@ -72,6 +85,12 @@ var (
 	bridgeIface   string
 	bridgeNetwork *net.IPNet
 
+	iptablesEnabled             bool
+	interContainerCommunication bool
+	// hairpinMode forwards the published ports with iptables alone, when
+	// the userland proxy is disabled
+	hairpinMode bool
+
 	defaultBindingIP  = net.ParseIP("0.0.0.0")
 	currentInterfaces = ifaces{c: make(map[string]*networkInterface)}
 )
@@ -83,7 +102,24 @@ func InitDriver(job *engine.Job) engine.Status {
 		icc            = job.GetenvBool("InterContainerCommunication")
 		ipForward      = job.GetenvBool("EnableIpForward")
 		bridgeIP       = job.Getenv("BridgeIP")
+		userlandProxy  = true
 	)
+	if job.EnvExists("EnableUserlandProxy") {
+		userlandProxy = job.GetenvBool("EnableUserlandProxy")
+	}
+	if !userlandProxy && !enableIPTables {
+		return job.Errorf("The published ports need the userland proxy or iptables")
+	}
+
+	if portRange := job.Getenv("PublishedPortsRange"); portRange != "" {
+		begin, end, err := parsers.ParsePortRange(portRange)
+		if err != nil {
+			return job.Errorf("Invalid published ports range %s: %s", portRange, err)
+		}
+		if err := portallocator.SetPortRange(int(begin), int(end)); err != nil {
+			return job.Error(err)
+		}
+	}
 
 	if defaultIP := job.Getenv("DefaultBindingIP"); defaultIP != "" {
 		defaultBindingIP = net.ParseIP(defaultIP)
@@ -105,7 +141,7 @@ func InitDriver(job *engine.Job) engine.Status {
 		}
 		// If the iface is not found, try to create it
 		job.Logf("creating new bridge for %s", bridgeIface)
-		if err := createBridge(bridgeIP); err != nil {
+		if err := createBridge(bridgeIface, bridgeIP); err != nil {
 			return job.Error(err)
 		}
 
@@ -131,10 +167,17 @@ func InitDriver(job *engine.Job) engine.Status {
 
 	// Configure iptables for link support
 	if enableIPTables {
-		if err := setupIPTables(addr, icc); err != nil {
+		if err := setupIPTables(bridgeIface, addr, icc, !userlandProxy); err != nil {
 			return job.Error(err)
 		}
 	}
+	if !userlandProxy {
+		// the traffic from the host to the published ports of the
+		// loopback addresses is routed to the bridge
+		if err := ioutil.WriteFile(path.Join("/proc/sys/net/ipv4/conf", bridgeIface, "route_localnet"), []byte{'1', '\n'}, 0644); err != nil {
+			return job.Errorf("Unable to route the loopback traffic to %s: %s", bridgeIface, err)
+		}
+	}
 
 	if ipForward {
 		// Enable IPv4 forwarding
@@ -149,7 +192,7 @@ func InitDriver(job *engine.Job) engine.Status {
 	}
 
 	if enableIPTables {
-		chain, err := iptables.NewChain("DOCKER", bridgeIface)
+		chain, err := iptables.NewChain("DOCKER", bridgeIface, !userlandProxy)
 		if err != nil {
 			return job.Error(err)
 		}
@@ -157,15 +200,40 @@ func InitDriver(job *engine.Job) engine.Status {
 	}
 
 	bridgeNetwork = network
+	iptablesEnabled = enableIPTables
+	interContainerCommunication = icc
+	hairpinMode = !userlandProxy
+	portmapper.SetUserlandProxy(userlandProxy)
+
+	if err := networks.Add(&bridgeNetworkInfo{
+		Name:    DefaultNetworkName,
+		Bridge:  bridgeIface,
+		Network: bridgeNetwork,
+	}); err != nil {
+		return job.Error(err)
+	}
+	if path := job.Getenv("NetworksPath"); path != "" {
+		if err := restoreNetworks(path); err != nil {
+			return job.Error(err)
+		}
+	}
 
 	// https://github.com/docker/docker/issues/2768
 	job.Eng.Hack_SetGlobalVar("httpapi.bridgeIP", bridgeNetwork.IP)
 
 	for name, f := range map[string]engine.Handler{
-		"allocate_interface": Allocate,
-		"release_interface":  Release,
-		"allocate_port":      AllocatePort,
-		"link":               LinkContainers,
+		"allocate_interface":   Allocate,
+		"release_interface":    Release,
+		"allocate_port":        AllocatePort,
+		"release_port":         ReleasePort,
+		"link":                 LinkContainers,
+		"connect_interface":    ConnectInterface,
+		"disconnect_interface": DisconnectInterface,
+		"networks":             Networks,
+		"network_create":       NetworkCreate,
+		"network_inspect":      NetworkInspect,
+		"network_rm":           NetworkRemove,
+		"network_warnings":     NetworkWarnings,
 	} {
 		if err := job.Eng.Register(name, f); err != nil {
 			return job.Error(err)
@@ -174,9 +242,9 @@ func InitDriver(job *engine.Job) engine.Status {
 	return engine.StatusOK
 }
 
-func setupIPTables(addr net.Addr, icc bool) error {
+func setupIPTables(bridge string, addr net.Addr, icc, hairpin bool) error {
 	// Enable NAT
-	natArgs := []string{"POSTROUTING", "-t", "nat", "-s", addr.String(), "!", "-o", bridgeIface, "-j", "MASQUERADE"}
+	natArgs := []string{"POSTROUTING", "-t", "nat", "-s", addr.String(), "!", "-o", bridge, "-j", "MASQUERADE"}
 
 	if !iptables.Exists(natArgs...) {
 		if output, err := iptables.Raw(append([]string{"-I"}, natArgs...)...); err != nil {
@@ -186,8 +254,21 @@ func setupIPTables(addr net.Addr, icc bool) error {
 		}
 	}
 
+	// Without the userland proxy, the connections of the host to the
+	// published ports reach the containers from the address of the bridge
+	localArgs := []string{"POSTROUTING", "-t", "nat", "-m", "addrtype", "--src-type", "LOCAL", "-o", bridge, "-j", "MASQUERADE"}
+	if !hairpin {
+		iptables.Raw(append([]string{"-D"}, localArgs...)...)
+	} else if !iptables.Exists(localArgs...) {
+		if output, err := iptables.Raw(append([]string{"-I"}, localArgs...)...); err != nil {
+			return fmt.Errorf("Unable to enable hairpin NAT: %s", err)
+		} else if len(output) != 0 {
+			return fmt.Errorf("Error iptables postrouting: %s", output)
+		}
+	}
+
 	var (
-		args       = []string{"FORWARD", "-i", bridgeIface, "-o", bridgeIface, "-j"}
+		args       = []string{"FORWARD", "-i", bridge, "-o", bridge, "-j"}
 		acceptArgs = append(args, "ACCEPT")
 		dropArgs   = append(args, "DROP")
 	)
@@ -217,7 +298,7 @@ func setupIPTables(addr net.Addr, icc bool) error {
 	}
 
 	// Accept all non-intercontainer outgoing packets
-	outgoingArgs := []string{"FORWARD", "-i", bridgeIface, "!", "-o", bridgeIface, "-j", "ACCEPT"}
+	outgoingArgs := []string{"FORWARD", "-i", bridge, "!", "-o", bridge, "-j", "ACCEPT"}
 	if !iptables.Exists(outgoingArgs...) {
 		if output, err := iptables.Raw(append([]string{"-I"}, outgoingArgs...)...); err != nil {
 			return fmt.Errorf("Unable to allow outgoing packets: %s", err)
@@ -227,7 +308,7 @@ func setupIPTables(addr net.Addr, icc bool) error {
 	}
 
 	// Accept incoming packets for existing connections
-	existingArgs := []string{"FORWARD", "-o", bridgeIface, "-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "ACCEPT"}
+	existingArgs := []string{"FORWARD", "-o", bridge, "-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "ACCEPT"}
 
 	if !iptables.Exists(existingArgs...) {
 		if output, err := iptables.Raw(append([]string{"-I"}, existingArgs...)...); err != nil {
@@ -242,7 +323,7 @@ func setupIPTables(addr net.Addr, icc bool) error {
 // CreateBridgeIface creates a network bridge interface on the host system with the name `ifaceName`,
 // and attempts to configure it with an address which doesn't conflict with any other interface on the host.
 // If it can't find an address which doesn't conflict, it will return an error.
-func createBridge(bridgeIP string) error {
+func createBridge(name, bridgeIP string) error {
 	nameservers := []string{}
 	resolvConf, _ := resolvconf.Get()
 	// we don't check for an error here, because we don't really care
@@ -278,15 +359,15 @@ func createBridge(bridgeIP string) error {
 	}
 
 	if ifaceAddr == "" {
-		return fmt.Errorf("Could not find a free IP address range for interface '%s'. Please configure its address manually and run 'docker -b %s'", bridgeIface, bridgeIface)
+		return fmt.Errorf("Could not find a free IP address range for interface '%s'. Please configure its address manually and run 'docker -b %s'", name, name)
 	}
-	log.Debugf("Creating bridge %s with network %s", bridgeIface, ifaceAddr)
+	log.Debugf("Creating bridge %s with network %s", name, ifaceAddr)
 
-	if err := createBridgeIface(bridgeIface); err != nil {
+	if err := createBridgeIface(name); err != nil {
 		return err
 	}
 
-	iface, err := net.InterfaceByName(bridgeIface)
+	iface, err := net.InterfaceByName(name)
 	if err != nil {
 		return err
 	}
@@ -381,7 +462,7 @@ func AllocatePort(job *engine.Job) engine.Status {
 		ip            = defaultBindingIP
 		id            = job.Args[0]
 		hostIP        = job.Getenv("HostIP")
-		hostPort      = job.GetenvInt("HostPort")
+		hostPort      = job.Getenv("HostPort")
 		containerPort = job.GetenvInt("ContainerPort")
 		proto         = job.Getenv("Proto")
 		network       = currentInterfaces.Get(id)
@@ -391,6 +472,17 @@ func AllocatePort(job *engine.Job) engine.Status {
 		ip = net.ParseIP(hostIP)
 	}
 
+	// the host port is any free port if empty or 0, and a free port of the
+	// range if it is a range, as 8000-8100
+	var hostPortStart, hostPortEnd int
+	if hostPort != "" {
+		start, end, err := parsers.ParsePortRange(hostPort)
+		if err != nil {
+			return job.Errorf("Invalid host port %s: %s", hostPort, err)
+		}
+		hostPortStart, hostPortEnd = int(start), int(end)
+	}
+
 	// host ip, proto, and host port
 	var container net.Addr
 	switch proto {
@@ -411,14 +503,14 @@ func AllocatePort(job *engine.Job) engine.Status {
 
 	var host net.Addr
 	for i := 0; i < MaxAllocatedPortAttempts; i++ {
-		if host, err = portmapper.Map(container, ip, hostPort); err == nil {
+		if host, err = portmapper.MapRange(container, ip, hostPortStart, hostPortEnd); err == nil {
 			break
 		}
 
 		if allocerr, ok := err.(portallocator.ErrPortAlreadyAllocated); ok {
 			// There is no point in immediately retrying to map an explicitly
 			// chosen port.
-			if hostPort != 0 {
+			if hostPortStart != 0 {
 				job.Logf("Failed to bind %s for container address %s: %s", allocerr.IPPort(), container.String(), allocerr.Error())
 				break
 			}
@@ -454,6 +546,48 @@ func AllocatePort(job *engine.Job) engine.Status {
 	return engine.StatusOK
 }
 
+// Unmap a port allocated with allocate_port
+func ReleasePort(job *engine.Job) engine.Status {
+	var (
+		id       = job.Args[0]
+		hostIP   = net.ParseIP(job.Getenv("HostIP"))
+		hostPort = job.GetenvInt("HostPort")
+		proto    = job.Getenv("Proto")
+		network  = currentInterfaces.Get(id)
+	)
+	if network == nil {
+		return job.Errorf("No network information for %s", id)
+	}
+
+	for i, host := range network.PortMappings {
+		var (
+			ip   net.IP
+			port int
+		)
+		switch netAddr := host.(type) {
+		case *net.TCPAddr:
+			if proto != "tcp" {
+				continue
+			}
+			ip, port = netAddr.IP, netAddr.Port
+		case *net.UDPAddr:
+			if proto != "udp" {
+				continue
+			}
+			ip, port = netAddr.IP, netAddr.Port
+		}
+		if port != hostPort || !ip.Equal(hostIP) {
+			continue
+		}
+		network.PortMappings = append(network.PortMappings[:i], network.PortMappings[i+1:]...)
+		if err := portmapper.Unmap(host); err != nil {
+			return job.Error(err)
+		}
+		return engine.StatusOK
+	}
+	return job.Errorf("Port %s:%d/%s is not mapped to %s", hostIP, hostPort, proto, id)
+}
+
 func LinkContainers(job *engine.Job) engine.Status {
 	var (
 		action       = job.Args[0]
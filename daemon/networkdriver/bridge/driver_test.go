@@ -3,6 +3,7 @@ package bridge
 import (
 	"net"
 	"strconv"
+	"sync"
 	"testing"
 
 	"github.com/docker/docker/daemon/networkdriver/portmapper"
@@ -28,6 +29,22 @@ func findFreePort(t *testing.T) int {
 	return result.Port
 }
 
+var (
+	initDriverOnce   sync.Once
+	initDriverStatus engine.Status
+)
+
+// initTestDriver initializes the driver once for all the tests, as the
+// default network cannot be added twice.
+func initTestDriver(t *testing.T, eng *engine.Engine) {
+	initDriverOnce.Do(func() {
+		initDriverStatus = InitDriver(eng.Job("initdriver"))
+	})
+	if initDriverStatus != engine.StatusOK {
+		t.Fatal("Failed to initialize network driver")
+	}
+}
+
 func newPortAllocationJob(eng *engine.Engine, port int) (job *engine.Job) {
 	strPort := strconv.Itoa(port)
 
@@ -46,13 +63,10 @@ func TestAllocatePortDetection(t *testing.T) {
 	freePort := findFreePort(t)
 
 	// Init driver
-	job := eng.Job("initdriver")
-	if res := InitDriver(job); res != engine.StatusOK {
-		t.Fatal("Failed to initialize network driver")
-	}
+	initTestDriver(t, eng)
 
 	// Allocate interface
-	job = eng.Job("allocate_interface", "container_id")
+	job := eng.Job("allocate_interface", "container_id")
 	if res := Allocate(job); res != engine.StatusOK {
 		t.Fatal("Failed to allocate network interface")
 	}
@@ -66,3 +80,56 @@ func TestAllocatePortDetection(t *testing.T) {
 		t.Fatal("Duplicate port allocation granted by AllocatePort")
 	}
 }
+
+func TestReleasePort(t *testing.T) {
+	eng := engine.New()
+	eng.Logging = false
+
+	freePort := findFreePort(t)
+
+	initTestDriver(t, eng)
+	if res := Allocate(eng.Job("allocate_interface", "release_id")); res != engine.StatusOK {
+		t.Fatal("Failed to allocate network interface")
+	}
+	defer Release(eng.Job("release_interface", "release_id"))
+
+	job := eng.Job("allocate_port", "release_id")
+	job.Setenv("HostIP", "127.0.0.1")
+	job.Setenv("HostPort", strconv.Itoa(freePort))
+	job.Setenv("Proto", "tcp")
+	job.Setenv("ContainerPort", "80")
+	if res := AllocatePort(job); res != engine.StatusOK {
+		t.Fatal("Failed to allocate the port")
+	}
+
+	release := func(id, hostIP, proto string) engine.Status {
+		job := eng.Job("release_port", id)
+		job.Setenv("HostIP", hostIP)
+		job.Setenv("HostPort", strconv.Itoa(freePort))
+		job.Setenv("Proto", proto)
+		return ReleasePort(job)
+	}
+	if res := release("unknown_id", "127.0.0.1", "tcp"); res == engine.StatusOK {
+		t.Fatal("Released a port of a container without network")
+	}
+	if res := release("release_id", "127.0.0.1", "udp"); res == engine.StatusOK {
+		t.Fatal("Released a port of another protocol")
+	}
+	if res := release("release_id", "127.0.0.2", "tcp"); res == engine.StatusOK {
+		t.Fatal("Released a port of another address")
+	}
+	if res := release("release_id", "127.0.0.1", "tcp"); res != engine.StatusOK {
+		t.Fatal("Failed to release the port")
+	}
+	if mappings := currentInterfaces.Get("release_id").PortMappings; len(mappings) != 0 {
+		t.Fatalf("Expected no port mapping left, got %v", mappings)
+	}
+	if res := release("release_id", "127.0.0.1", "tcp"); res == engine.StatusOK {
+		t.Fatal("Released a port twice")
+	}
+
+	// the host port is free again
+	if res := AllocatePort(job); res != engine.StatusOK {
+		t.Fatal("Failed to allocate the released port")
+	}
+}
@@ -0,0 +1,367 @@
+package bridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/docker/docker/daemon/networkdriver"
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/pkg/iptables"
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/libcontainer/netlink"
+)
+
+const maxBridgeNameLen = 15 // IFNAMSIZ - 1
+
+var validNetworkNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// bridgeNetworkInfo describes a bridge and the address range handed out
+// to the interfaces attached to it.
+type bridgeNetworkInfo struct {
+	Name    string
+	Bridge  string
+	Network *net.IPNet
+}
+
+func (n *bridgeNetworkInfo) isDefault() bool {
+	return n.Name == DefaultNetworkName
+}
+
+func (n *bridgeNetworkInfo) env() *engine.Env {
+	out := &engine.Env{}
+	out.Set("Name", n.Name)
+	out.Set("Bridge", n.Bridge)
+	out.Set("Subnet", n.Network.String())
+	out.Set("Gateway", n.Network.IP.String())
+	out.SetBool("Default", n.isDefault())
+	return out
+}
+
+type networkStore struct {
+	n map[string]*bridgeNetworkInfo
+	// the file the created networks are saved to, so that they outlive
+	// the daemon; they are not saved when it is empty
+	path string
+	sync.Mutex
+}
+
+// Add registers a network, refusing duplicate names, bridges and
+// overlapping address ranges.
+func (s *networkStore) Add(n *bridgeNetworkInfo) error {
+	s.Lock()
+	defer s.Unlock()
+	if _, exists := s.n[n.Name]; exists {
+		return fmt.Errorf("Network %s already exists", n.Name)
+	}
+	for _, other := range s.n {
+		if other.Bridge == n.Bridge {
+			return fmt.Errorf("Bridge %s is already used by network %s", n.Bridge, other.Name)
+		}
+		if networkdriver.NetworkOverlaps(other.Network, n.Network) {
+			return fmt.Errorf("Subnet %s overlaps with network %s (%s)", n.Network, other.Name, other.Network)
+		}
+	}
+	s.n[n.Name] = n
+	return nil
+}
+
+func (s *networkStore) Get(name string) *bridgeNetworkInfo {
+	s.Lock()
+	res := s.n[name]
+	s.Unlock()
+	return res
+}
+
+func (s *networkStore) Delete(name string) {
+	s.Lock()
+	delete(s.n, name)
+	s.Unlock()
+}
+
+// List returns the registered networks sorted by name.
+func (s *networkStore) List() []*bridgeNetworkInfo {
+	s.Lock()
+	res := make([]*bridgeNetworkInfo, 0, len(s.n))
+	for _, n := range s.n {
+		res = append(res, n)
+	}
+	s.Unlock()
+	sort.Sort(networksByName(res))
+	return res
+}
+
+// save writes the networks other than the default one to the file of the
+// store.
+func (s *networkStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+	saved := []*bridgeNetworkInfo{}
+	for _, n := range s.List() {
+		if !n.isDefault() {
+			saved = append(saved, n)
+		}
+	}
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.path, data, 0600)
+}
+
+// saved returns the networks written to the file of the store by save.
+func (s *networkStore) saved() ([]*bridgeNetworkInfo, error) {
+	var saved []*bridgeNetworkInfo
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("Invalid networks file %s: %s", s.path, err)
+	}
+	return saved, nil
+}
+
+type networksByName []*bridgeNetworkInfo
+
+func (l networksByName) Len() int           { return len(l) }
+func (l networksByName) Less(i, j int) bool { return l[i].Name < l[j].Name }
+func (l networksByName) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+
+var networks = &networkStore{n: make(map[string]*bridgeNetworkInfo)}
+
+// restoreNetworks registers the networks saved at path by a previous
+// daemon, adopting their bridges when they are still there and creating
+// them again otherwise, and saves the created networks there from now on.
+func restoreNetworks(path string) error {
+	networks.path = path
+	saved, err := networks.saved()
+	if err != nil {
+		return err
+	}
+	for _, n := range saved {
+		if err := restoreNetwork(n); err != nil {
+			log.Errorf("Unable to restore the network %s: %s", n.Name, err)
+		}
+	}
+	// forget the networks which could not be restored
+	return networks.save()
+}
+
+func restoreNetwork(n *bridgeNetworkInfo) error {
+	if n.Network == nil {
+		return fmt.Errorf("no subnet")
+	}
+	created := false
+	addr, err := networkdriver.GetIfaceAddr(n.Bridge)
+	if err != nil {
+		if err := createBridge(n.Bridge, n.Network.String()); err != nil {
+			return err
+		}
+		created = true
+		if addr, err = networkdriver.GetIfaceAddr(n.Bridge); err != nil {
+			deleteBridge(n.Bridge)
+			return err
+		}
+	}
+	// the address of a bridge left by the previous daemon may have changed
+	n.Network = addr.(*net.IPNet)
+	if err := networks.Add(n); err != nil {
+		if created {
+			deleteBridge(n.Bridge)
+		}
+		return err
+	}
+	if iptablesEnabled {
+		if err := setupIPTables(n.Bridge, n.Network, interContainerCommunication, hairpinMode); err != nil {
+			networks.Delete(n.Name)
+			if created {
+				deleteBridge(n.Bridge)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func defaultBridgeName(name string) string {
+	bridge := "br-" + name
+	if len(bridge) > maxBridgeNameLen {
+		bridge = bridge[:maxBridgeNameLen]
+	}
+	return bridge
+}
+
+// List the networks managed by the daemon
+func Networks(job *engine.Job) engine.Status {
+	outs := engine.NewTable("", 0)
+	for _, n := range networks.List() {
+		outs.Add(n.env())
+	}
+	if _, err := outs.WriteListTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+// Create a new bridge network
+func NetworkCreate(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s NAME", job.Name)
+	}
+	var (
+		name   = job.Args[0]
+		bridge = job.Getenv("Bridge")
+		subnet = job.Getenv("Subnet")
+	)
+	if !validNetworkNamePattern.MatchString(name) {
+		return job.Errorf("Invalid network name (%s), only [a-zA-Z0-9][a-zA-Z0-9_.-] are allowed", name)
+	}
+	if networks.Get(name) != nil {
+		return job.Errorf("Network %s already exists", name)
+	}
+	if bridge == "" {
+		bridge = defaultBridgeName(name)
+	}
+	if len(bridge) > maxBridgeNameLen {
+		return job.Errorf("Bridge name %s is too long (maximum is %d characters)", bridge, maxBridgeNameLen)
+	}
+	if _, err := net.InterfaceByName(bridge); err == nil {
+		return job.Errorf("Interface %s already exists", bridge)
+	}
+
+	if err := createBridge(bridge, subnet); err != nil {
+		return job.Error(err)
+	}
+	addr, err := networkdriver.GetIfaceAddr(bridge)
+	if err != nil {
+		deleteBridge(bridge)
+		return job.Error(err)
+	}
+	n := &bridgeNetworkInfo{
+		Name:    name,
+		Bridge:  bridge,
+		Network: addr.(*net.IPNet),
+	}
+	if err := networks.Add(n); err != nil {
+		deleteBridge(bridge)
+		return job.Error(err)
+	}
+	if iptablesEnabled {
+		if err := setupIPTables(bridge, addr, interContainerCommunication, hairpinMode); err != nil {
+			networks.Delete(name)
+			deleteBridge(bridge)
+			return job.Error(err)
+		}
+	}
+	if err := networks.save(); err != nil {
+		log.Errorf("Unable to save the network %s, it is lost when the daemon restarts: %s", name, err)
+	}
+	job.Logf("created network %s on bridge %s (%s)", name, bridge, n.Network)
+
+	if _, err := n.env().WriteTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+// Return low-level information about a network
+func NetworkInspect(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s NAME", job.Name)
+	}
+	n := networks.Get(job.Args[0])
+	if n == nil {
+		return job.Errorf("No such network: %s", job.Args[0])
+	}
+	if _, err := n.env().WriteTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+// Remove a network and the bridge backing it
+func NetworkRemove(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s NAME", job.Name)
+	}
+	name := job.Args[0]
+	n := networks.Get(name)
+	if n == nil {
+		return job.Errorf("No such network: %s", name)
+	}
+	if n.isDefault() {
+		return job.Errorf("Cannot remove the default network %s", name)
+	}
+	if currentInterfaces.connectedTo(n) {
+		return job.Errorf("Conflict: network %s has connected containers", name)
+	}
+	if iptablesEnabled {
+		removeIPTables(n.Bridge, n.Network)
+	}
+	if err := deleteBridge(n.Bridge); err != nil {
+		return job.Errorf("Unable to remove bridge %s: %s", n.Bridge, err)
+	}
+	networks.Delete(name)
+	if err := networks.save(); err != nil {
+		log.Errorf("Unable to save the removal of the network %s: %s", name, err)
+	}
+	return engine.StatusOK
+}
+
+func deleteBridge(name string) error {
+	if iface, err := net.InterfaceByName(name); err == nil {
+		if err := netlink.NetworkLinkDown(iface); err != nil {
+			log.Debugf("Unable to bring down bridge %s: %s", name, err)
+		}
+	}
+	return netlink.DeleteBridge(name)
+}
+
+// removeIPTables removes the rules installed by setupIPTables for a bridge.
+// Errors are ignored as the rules may already be gone.
+func removeIPTables(bridge string, addr net.Addr) {
+	for _, args := range [][]string{
+		{"POSTROUTING", "-t", "nat", "-s", addr.String(), "!", "-o", bridge, "-j", "MASQUERADE"},
+		{"FORWARD", "-i", bridge, "-o", bridge, "-j", "ACCEPT"},
+		{"FORWARD", "-i", bridge, "-o", bridge, "-j", "DROP"},
+		{"FORWARD", "-i", bridge, "!", "-o", bridge, "-j", "ACCEPT"},
+		{"FORWARD", "-o", bridge, "-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "ACCEPT"},
+	} {
+		if iptables.Exists(args...) {
+			iptables.Raw(append([]string{"-D"}, args...)...)
+		}
+	}
+}
+
+// NetworkWarnings lists, as "Warnings", the problems found with the
+// default bridge which do not prevent the daemon from starting.
+func NetworkWarnings(job *engine.Job) engine.Status {
+	var warnings []string
+	routes, err := networkdriver.OverlappingRoutes(bridgeNetwork, bridgeIface)
+	if err != nil {
+		return job.Error(err)
+	}
+	for _, route := range routes {
+		via := ""
+		if route.Iface != nil {
+			via = " on " + route.Iface.Name
+		}
+		warnings = append(warnings, fmt.Sprintf("The network %s of the bridge %s overlaps with the route to %s%s, containers cannot reach the hosts of that network. Use --bip to choose another network", bridgeNetwork, bridgeIface, route.IPNet, via))
+	}
+	out := &engine.Env{}
+	out.SetList("Warnings", warnings)
+	if _, err := out.WriteTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
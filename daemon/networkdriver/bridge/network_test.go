@@ -0,0 +1,88 @@
+package bridge
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"testing"
+)
+
+func newTestNetwork(t *testing.T, name, bridge, cidr string) *bridgeNetworkInfo {
+	ip, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	network.IP = ip
+	return &bridgeNetworkInfo{Name: name, Bridge: bridge, Network: network}
+}
+
+func TestNetworkStoreAdd(t *testing.T) {
+	store := &networkStore{n: make(map[string]*bridgeNetworkInfo)}
+
+	if err := store.Add(newTestNetwork(t, "bridge", "docker0", "172.17.42.1/16")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Add(newTestNetwork(t, "bridge", "br-other", "10.0.42.1/24")); err == nil {
+		t.Fatal("Expected an error when adding a duplicate network name")
+	}
+	if err := store.Add(newTestNetwork(t, "other", "docker0", "10.0.42.1/24")); err == nil {
+		t.Fatal("Expected an error when reusing a bridge")
+	}
+	if err := store.Add(newTestNetwork(t, "other", "br-other", "172.17.0.1/24")); err == nil {
+		t.Fatal("Expected an error when adding an overlapping subnet")
+	}
+	if err := store.Add(newTestNetwork(t, "other", "br-other", "10.0.42.1/24")); err != nil {
+		t.Fatal(err)
+	}
+
+	list := store.List()
+	if len(list) != 2 || list[0].Name != "bridge" || list[1].Name != "other" {
+		t.Fatalf("Unexpected network list: %v", list)
+	}
+
+	store.Delete("other")
+	if store.Get("other") != nil {
+		t.Fatal("Network should have been removed")
+	}
+}
+
+func TestNetworkStoreSave(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "docker-networks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	store := &networkStore{n: make(map[string]*bridgeNetworkInfo), path: path.Join(tmp, "networks.json")}
+
+	if saved, err := store.saved(); err != nil || saved != nil {
+		t.Fatalf("Expected no saved networks, got %v (%v)", saved, err)
+	}
+	if err := store.Add(newTestNetwork(t, DefaultNetworkName, "docker0", "172.17.42.1/16")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Add(newTestNetwork(t, "other", "br-other", "10.0.42.1/24")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the default network is not saved, it is set up by each daemon
+	saved, err := store.saved()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != 1 || saved[0].Name != "other" || saved[0].Bridge != "br-other" || saved[0].Network.String() != "10.0.42.1/24" {
+		t.Fatalf("Unexpected saved networks: %v", saved)
+	}
+}
+
+func TestDefaultBridgeName(t *testing.T) {
+	if name := defaultBridgeName("db"); name != "br-db" {
+		t.Fatalf("Expected br-db, got %s", name)
+	}
+	if name := defaultBridgeName("averyveryverylongname"); len(name) != maxBridgeNameLen {
+		t.Fatalf("Expected bridge name to be truncated to %d characters, got %s", maxBridgeNameLen, name)
+	}
+}
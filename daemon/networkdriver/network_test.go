@@ -61,6 +61,32 @@ func TestCheckRouteOverlaps(t *testing.T) {
 	}
 }
 
+func TestOverlappingRoutes(t *testing.T) {
+	orig := networkGetRoutesFct
+	defer func() {
+		networkGetRoutesFct = orig
+	}()
+	networkGetRoutesFct = func() ([]netlink.Route, error) {
+		routesData := map[string]string{"172.17.0.0/16": "docker0", "172.17.42.0/24": "eth1", "10.0.0.0/8": "eth0"}
+
+		routes := []netlink.Route{}
+		for addr, iface := range routesData {
+			_, netX, _ := net.ParseCIDR(addr)
+			routes = append(routes, netlink.Route{IPNet: netX, Iface: &net.Interface{Name: iface}})
+		}
+		return routes, nil
+	}
+
+	_, netX, _ := net.ParseCIDR("172.17.42.1/16")
+	routes, err := OverlappingRoutes(netX, "docker0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 1 || routes[0].Iface.Name != "eth1" {
+		t.Fatalf("Expected only the route of eth1 to overlap, got %v", routes)
+	}
+}
+
 func TestCheckNameserverOverlaps(t *testing.T) {
 	nameservers := []string{"10.0.2.3/32", "192.168.102.1/32"}
 
@@ -44,6 +44,10 @@ var (
 
 	defaultIP = net.ParseIP("0.0.0.0")
 	globalMap = ipMapping{}
+
+	// the range the ports are chosen from when no port is requested
+	beginPortRange = BeginPortRange
+	endPortRange   = EndPortRange
 )
 
 type ErrPortAlreadyAllocated struct {
@@ -78,6 +82,13 @@ func (e ErrPortAlreadyAllocated) Error() string {
 // If port is 0 it returns first free port. Otherwise it cheks port availability
 // in pool and return that port or error if port is already busy.
 func RequestPort(ip net.IP, proto string, port int) (int, error) {
+	return RequestPortInRange(ip, proto, port, port)
+}
+
+// RequestPortInRange requests the first free port between start and end
+// from the global ports pool for specified ip and proto, or any free port
+// if start and end are 0.
+func RequestPortInRange(ip net.IP, proto string, start, end int) (int, error) {
 	mutex.Lock()
 	defer mutex.Unlock()
 
@@ -95,12 +106,21 @@ func RequestPort(ip net.IP, proto string, port int) (int, error) {
 		globalMap[ipstr] = protomap
 	}
 	mapping := protomap[proto]
-	if port > 0 {
-		if _, ok := mapping.p[port]; !ok {
-			mapping.p[port] = struct{}{}
-			return port, nil
+	if start > 0 && start == end {
+		if _, ok := mapping.p[start]; !ok {
+			mapping.p[start] = struct{}{}
+			return start, nil
 		}
-		return 0, NewErrPortAlreadyAllocated(ipstr, port)
+		return 0, NewErrPortAlreadyAllocated(ipstr, start)
+	}
+	if start > 0 {
+		for port := start; port <= end; port++ {
+			if _, ok := mapping.p[port]; !ok {
+				mapping.p[port] = struct{}{}
+				return port, nil
+			}
+		}
+		return 0, fmt.Errorf("Bind for %s:%d-%d failed: all the ports of the range are allocated", ipstr, start, end)
 	}
 
 	port, err := mapping.findPort()
@@ -110,6 +130,18 @@ func RequestPort(ip net.IP, proto string, port int) (int, error) {
 	return port, nil
 }
 
+// SetPortRange sets the range the ports are chosen from when no port is
+// requested, BeginPortRange to EndPortRange by default.
+func SetPortRange(begin, end int) error {
+	if begin <= 0 || end > 65535 || begin > end {
+		return fmt.Errorf("Invalid port range: %d-%d", begin, end)
+	}
+	mutex.Lock()
+	beginPortRange, endPortRange = begin, end
+	mutex.Unlock()
+	return nil
+}
+
 // ReleasePort releases port from global ports pool for specified ip and proto.
 func ReleasePort(ip net.IP, proto string, port int) error {
 	mutex.Lock()
@@ -135,15 +167,11 @@ func ReleaseAll() error {
 }
 
 func (pm *portMap) findPort() (int, error) {
-	if pm.last == 0 {
-		pm.p[BeginPortRange] = struct{}{}
-		pm.last = BeginPortRange
-		return BeginPortRange, nil
-	}
-
-	for port := pm.last + 1; port != pm.last; port++ {
-		if port > EndPortRange {
-			port = BeginPortRange
+	port := pm.last
+	for i := 0; i <= endPortRange-beginPortRange; i++ {
+		port++
+		if port < beginPortRange || port > endPortRange {
+			port = beginPortRange
 		}
 
 		if _, ok := pm.p[port]; !ok {
@@ -214,3 +214,56 @@ func TestPortAllocation(t *testing.T) {
 		t.Fatal("Requesting a dynamic port should never allocate a used port")
 	}
 }
+
+func TestRequestPortInRange(t *testing.T) {
+	defer reset()
+
+	if _, err := RequestPort(defaultIP, "tcp", 8000); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []int{8001, 8002} {
+		port, err := RequestPortInRange(defaultIP, "tcp", 8000, 8002)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if port != expected {
+			t.Fatalf("Expected port %d got %d", expected, port)
+		}
+	}
+	if _, err := RequestPortInRange(defaultIP, "tcp", 8000, 8002); err == nil {
+		t.Fatal("Expected an error when all the ports of the range are allocated")
+	}
+	if err := ReleasePort(defaultIP, "tcp", 8001); err != nil {
+		t.Fatal(err)
+	}
+	if port, err := RequestPortInRange(defaultIP, "tcp", 8000, 8002); err != nil || port != 8001 {
+		t.Fatalf("Expected port 8001 got %d (%v)", port, err)
+	}
+}
+
+func TestSetPortRange(t *testing.T) {
+	defer func() {
+		SetPortRange(BeginPortRange, EndPortRange)
+		reset()
+	}()
+
+	if err := SetPortRange(10000, 10001); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []int{10000, 10001} {
+		port, err := RequestPort(defaultIP, "tcp", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if port != expected {
+			t.Fatalf("Expected port %d got %d", expected, port)
+		}
+	}
+	if _, err := RequestPort(defaultIP, "tcp", 0); err != ErrAllPortsAllocated {
+		t.Fatalf("Expected error %s got %v", ErrAllPortsAllocated, err)
+	}
+
+	if err := SetPortRange(20000, 10000); err == nil {
+		t.Fatal("Expected an error for an invalid range")
+	}
+}
@@ -25,6 +25,10 @@ var (
 	currentMappings = make(map[string]*mapping)
 
 	NewProxy = NewProxyCommand
+
+	// userlandProxy is false when iptables forwards all the traffic to
+	// the published ports, the host ports being only held by a listener
+	userlandProxy = true
 )
 
 var (
@@ -37,7 +41,28 @@ func SetIptablesChain(c *iptables.Chain) {
 	chain = c
 }
 
+// SetUserlandProxy enables or disables the userland proxy of the ports
+// mapped afterwards.
+func SetUserlandProxy(enabled bool) {
+	lock.Lock()
+	userlandProxy = enabled
+	lock.Unlock()
+}
+
+func newProxy(proto string, hostIP net.IP, hostPort int, containerIP net.IP, containerPort int) UserlandProxy {
+	if !userlandProxy {
+		return newDummyProxy(proto, hostIP, hostPort)
+	}
+	return NewProxy(proto, hostIP, hostPort, containerIP, containerPort)
+}
+
 func Map(container net.Addr, hostIP net.IP, hostPort int) (host net.Addr, err error) {
+	return MapRange(container, hostIP, hostPort, hostPort)
+}
+
+// MapRange maps container to the first free port between hostPortStart and
+// hostPortEnd of hostIP, or to any free port if they are 0.
+func MapRange(container net.Addr, hostIP net.IP, hostPortStart, hostPortEnd int) (host net.Addr, err error) {
 	lock.Lock()
 	defer lock.Unlock()
 
@@ -51,7 +76,7 @@ func Map(container net.Addr, hostIP net.IP, hostPort int) (host net.Addr, err er
 	switch container.(type) {
 	case *net.TCPAddr:
 		proto = "tcp"
-		if allocatedHostPort, err = portallocator.RequestPort(hostIP, proto, hostPort); err != nil {
+		if allocatedHostPort, err = portallocator.RequestPortInRange(hostIP, proto, hostPortStart, hostPortEnd); err != nil {
 			return nil, err
 		}
 
@@ -61,10 +86,10 @@ func Map(container net.Addr, hostIP net.IP, hostPort int) (host net.Addr, err er
 			container: container,
 		}
 
-		proxy = NewProxy(proto, hostIP, allocatedHostPort, container.(*net.TCPAddr).IP, container.(*net.TCPAddr).Port)
+		proxy = newProxy(proto, hostIP, allocatedHostPort, container.(*net.TCPAddr).IP, container.(*net.TCPAddr).Port)
 	case *net.UDPAddr:
 		proto = "udp"
-		if allocatedHostPort, err = portallocator.RequestPort(hostIP, proto, hostPort); err != nil {
+		if allocatedHostPort, err = portallocator.RequestPortInRange(hostIP, proto, hostPortStart, hostPortEnd); err != nil {
 			return nil, err
 		}
 
@@ -74,7 +99,7 @@ func Map(container net.Addr, hostIP net.IP, hostPort int) (host net.Addr, err er
 			container: container,
 		}
 
-		proxy = NewProxy(proto, hostIP, allocatedHostPort, container.(*net.UDPAddr).IP, container.(*net.UDPAddr).Port)
+		proxy = newProxy(proto, hostIP, allocatedHostPort, container.(*net.UDPAddr).IP, container.(*net.UDPAddr).Port)
 	default:
 		return nil, ErrUnknownBackendAddressType
 	}
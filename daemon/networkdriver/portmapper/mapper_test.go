@@ -81,6 +81,46 @@ func TestMapPorts(t *testing.T) {
 	}
 }
 
+func TestMapPortsWithoutUserlandProxy(t *testing.T) {
+	defer reset()
+	SetUserlandProxy(false)
+	defer SetUserlandProxy(true)
+
+	hostIP := net.ParseIP("127.0.0.1")
+	for _, container := range []net.Addr{
+		&net.TCPAddr{IP: net.ParseIP("172.16.0.1"), Port: 80},
+		&net.UDPAddr{IP: net.ParseIP("172.16.0.1"), Port: 53},
+	} {
+		host, err := Map(container, hostIP, 0)
+		if err != nil {
+			t.Fatalf("Failed to allocate port: %s", err)
+		}
+		listen := func() error {
+			if _, ok := host.(*net.TCPAddr); ok {
+				l, err := net.Listen("tcp", host.String())
+				if err == nil {
+					l.Close()
+				}
+				return err
+			}
+			l, err := net.ListenPacket("udp", host.String())
+			if err == nil {
+				l.Close()
+			}
+			return err
+		}
+		if err := listen(); err == nil {
+			t.Fatalf("Expected %s to be held by the mapping", host)
+		}
+		if err := Unmap(host); err != nil {
+			t.Fatal(err)
+		}
+		if err := listen(); err != nil {
+			t.Fatalf("Expected %s to be released: %s", host, err)
+		}
+	}
+}
+
 func TestGetUDPKey(t *testing.T) {
 	addr := &net.UDPAddr{IP: net.ParseIP("192.168.1.5"), Port: 53}
 
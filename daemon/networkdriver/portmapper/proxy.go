@@ -2,6 +2,8 @@ package portmapper
 
 import (
 	"flag"
+	"fmt"
+	"io"
 	"log"
 	"net"
 	"os"
@@ -117,3 +119,54 @@ func (p *proxyCommand) Stop() error {
 
 	return err
 }
+
+// dummyProxy holds the host port of a mapping forwarded by iptables alone,
+// with a listener which is never served, so that no other process binds the
+// port.
+type dummyProxy struct {
+	proto    string
+	addr     string
+	listener io.Closer
+}
+
+func newDummyProxy(proto string, hostIP net.IP, hostPort int) UserlandProxy {
+	return &dummyProxy{
+		proto: proto,
+		addr:  net.JoinHostPort(hostIP.String(), strconv.Itoa(hostPort)),
+	}
+}
+
+func (p *dummyProxy) Start() error {
+	switch p.proto {
+	case "tcp":
+		addr, err := net.ResolveTCPAddr("tcp", p.addr)
+		if err != nil {
+			return err
+		}
+		l, err := net.ListenTCP("tcp", addr)
+		if err != nil {
+			return err
+		}
+		p.listener = l
+	case "udp":
+		addr, err := net.ResolveUDPAddr("udp", p.addr)
+		if err != nil {
+			return err
+		}
+		l, err := net.ListenUDP("udp", addr)
+		if err != nil {
+			return err
+		}
+		p.listener = l
+	default:
+		return fmt.Errorf("unsupported protocol %s", p.proto)
+	}
+	return nil
+}
+
+func (p *dummyProxy) Stop() error {
+	if p.listener != nil {
+		return p.listener.Close()
+	}
+	return nil
+}
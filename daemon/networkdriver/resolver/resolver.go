@@ -0,0 +1,356 @@
+// Package resolver is a small DNS server answering the queries of the
+// containers for the names of the other containers, with their current
+// address, and forwarding the other queries to the upstream servers.
+package resolver
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/docker/docker/pkg/log"
+)
+
+const (
+	typeA    = 1
+	typeAAAA = 28
+	typeANY  = 255
+	classIN  = 1
+
+	headerLen = 12
+	// forwardTimeout is how long an upstream server has to answer
+	forwardTimeout = 2 * time.Second
+	// tcpTimeout is how long a TCP connection of a client stays idle
+	tcpTimeout = 10 * time.Second
+)
+
+var errInvalidQuery = errors.New("Invalid DNS query")
+
+// LookupFunc returns the addresses of the containers called name for the
+// container of address client, or none.
+type LookupFunc func(client net.IP, name string) []net.IP
+
+// Resolver serves the DNS queries received on an address, over UDP and
+// over TCP, on which the clients retry the truncated responses.
+type Resolver struct {
+	conn      *net.UDPConn
+	listener  *net.TCPListener
+	clients   *net.IPNet
+	lookup    LookupFunc
+	upstreams []string
+	// protects upstreams, replaced when the ones of the host change
+	upstreamsLock sync.RWMutex
+	wg            sync.WaitGroup
+	// rotates the addresses of the names of several containers
+	next uint32
+}
+
+// New listens on addr, as IP:PORT, for the queries of the addresses of
+// clients, answered with lookup. The other queries of the clients are
+// forwarded to the upstreams, as IP or IP:PORT, in turn until one answers.
+// The queries of the other addresses are dropped, so that the resolver is
+// not an open resolver for the networks the host is on.
+func New(addr string, clients *net.IPNet, upstreams []string, lookup LookupFunc) (*Resolver, error) {
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return nil, err
+	}
+	// the same port as over UDP, which is chosen by the system for port 0
+	udpAddr = conn.LocalAddr().(*net.UDPAddr)
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: udpAddr.IP, Port: udpAddr.Port, Zone: udpAddr.Zone})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	r := &Resolver{
+		conn:     conn,
+		listener: listener,
+		clients:  clients,
+		lookup:   lookup,
+	}
+	r.SetUpstreams(upstreams)
+	return r, nil
+}
+
+// SetUpstreams replaces the upstream servers, as IP or IP:PORT, the queries
+// are forwarded to.
+func (r *Resolver) SetUpstreams(upstreams []string) {
+	var addrs []string
+	for _, upstream := range upstreams {
+		if _, _, err := net.SplitHostPort(upstream); err != nil {
+			upstream = net.JoinHostPort(upstream, "53")
+		}
+		addrs = append(addrs, upstream)
+	}
+	r.upstreamsLock.Lock()
+	r.upstreams = addrs
+	r.upstreamsLock.Unlock()
+}
+
+// Addr returns the address the resolver listens on.
+func (r *Resolver) Addr() *net.UDPAddr {
+	return r.conn.LocalAddr().(*net.UDPAddr)
+}
+
+// Serve answers the queries until the resolver is closed.
+func (r *Resolver) Serve() {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.serveTCP()
+	}()
+
+	buf := make([]byte, 512)
+	for {
+		n, client, err := r.conn.ReadFromUDP(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+			r.wg.Wait()
+			return
+		}
+		query := make([]byte, n)
+		copy(query, buf[:n])
+
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			if !r.clients.Contains(client.IP) {
+				log.Debugf("Dropping the DNS query of %s, not in %s", client, r.clients)
+				return
+			}
+			response, err := r.resolve("udp", query, client.IP)
+			if err != nil {
+				log.Debugf("Error forwarding a DNS query of %s: %s", client, err)
+				return
+			}
+			if _, err := r.conn.WriteToUDP(response, client); err != nil {
+				log.Debugf("Error answering the DNS query of %s: %s", client, err)
+			}
+		}()
+	}
+}
+
+// serveTCP answers the queries of the TCP connections until the resolver is
+// closed.
+func (r *Resolver) serveTCP() {
+	for {
+		conn, err := r.listener.AcceptTCP()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+			return
+		}
+		client := conn.RemoteAddr().(*net.TCPAddr)
+		if !r.clients.Contains(client.IP) {
+			log.Debugf("Dropping the DNS connection of %s, not in %s", client, r.clients)
+			conn.Close()
+			continue
+		}
+
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			defer conn.Close()
+			for {
+				conn.SetDeadline(time.Now().Add(tcpTimeout))
+				query, err := readTCPMessage(conn)
+				if err != nil {
+					return
+				}
+				response, err := r.resolve("tcp", query, client.IP)
+				if err != nil {
+					log.Debugf("Error forwarding a DNS query of %s: %s", client, err)
+					return
+				}
+				if err := writeTCPMessage(conn, response); err != nil {
+					log.Debugf("Error answering the DNS query of %s: %s", client, err)
+					return
+				}
+			}
+		}()
+	}
+}
+
+// Close stops the resolver.
+func (r *Resolver) Close() error {
+	r.listener.Close()
+	return r.conn.Close()
+}
+
+// resolve returns the response to the query of client, received over
+// network, "udp" or "tcp": the addresses of the containers of the name, or
+// else the response of an upstream server over the same network.
+func (r *Resolver) resolve(network string, query []byte, client net.IP) ([]byte, error) {
+	if name, qtype, end, err := parseQuestion(query); err == nil && qtype != 0 {
+		if ips := r.lookup(client, name); len(ips) > 0 {
+			return answer(query[:end], qtype, rotate(ips, atomic.AddUint32(&r.next, 1))), nil
+		}
+	}
+	return r.forward(network, query)
+}
+
+// forward returns the response of the first upstream server answering the
+// query over network.
+func (r *Resolver) forward(network string, query []byte) ([]byte, error) {
+	r.upstreamsLock.RLock()
+	upstreams := r.upstreams
+	r.upstreamsLock.RUnlock()
+
+	err := errors.New("No upstream DNS server")
+	for _, upstream := range upstreams {
+		var response []byte
+		if response, err = exchange(network, upstream, query); err == nil {
+			return response, nil
+		}
+	}
+	return nil, err
+}
+
+func exchange(network, upstream string, query []byte) ([]byte, error) {
+	conn, err := net.DialTimeout(network, upstream, forwardTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(forwardTimeout))
+	if network == "tcp" {
+		if err := writeTCPMessage(conn, query); err != nil {
+			return nil, err
+		}
+		return readTCPMessage(conn)
+	}
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 65535)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// readTCPMessage reads a DNS message over TCP, prefixed with its length.
+func readTCPMessage(r io.Reader) ([]byte, error) {
+	var length uint16
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// writeTCPMessage writes a DNS message over TCP, prefixed with its length.
+func writeTCPMessage(w io.Writer, msg []byte) error {
+	buf := make([]byte, 2, 2+len(msg))
+	binary.BigEndian.PutUint16(buf, uint16(len(msg)))
+	_, err := w.Write(append(buf, msg...))
+	return err
+}
+
+// parseQuestion returns the name, in lower case without the final dot, and
+// the type of the single question of a standard query, with the offset of
+// its end. The type is 0 for the questions the resolver does not answer.
+func parseQuestion(msg []byte) (string, uint16, int, error) {
+	if len(msg) < headerLen {
+		return "", 0, 0, errInvalidQuery
+	}
+	flags := binary.BigEndian.Uint16(msg[2:])
+	// a response, or another opcode than QUERY
+	if flags&0x8000 != 0 || flags&0x7800 != 0 {
+		return "", 0, 0, nil
+	}
+	if binary.BigEndian.Uint16(msg[4:]) != 1 {
+		return "", 0, 0, nil
+	}
+
+	var (
+		labels []string
+		i      = headerLen
+	)
+	for {
+		if i >= len(msg) {
+			return "", 0, 0, errInvalidQuery
+		}
+		l := int(msg[i])
+		i++
+		if l == 0 {
+			break
+		}
+		// compression pointers are not expected in a question
+		if l > 63 || i+l > len(msg) {
+			return "", 0, 0, errInvalidQuery
+		}
+		labels = append(labels, string(msg[i:i+l]))
+		i += l
+	}
+	if i+4 > len(msg) {
+		return "", 0, 0, errInvalidQuery
+	}
+	qtype := binary.BigEndian.Uint16(msg[i:])
+	qclass := binary.BigEndian.Uint16(msg[i+2:])
+	i += 4
+	if qclass != classIN || (qtype != typeA && qtype != typeAAAA && qtype != typeANY) {
+		qtype = 0
+	}
+	return strings.ToLower(strings.Join(labels, ".")), qtype, i, nil
+}
+
+// rotate returns the addresses of ips starting at the nth modulo their
+// number, so that the clients, which mostly use the first address, are
+// spread round-robin over the containers of a name.
+func rotate(ips []net.IP, n uint32) []net.IP {
+	i := int(n % uint32(len(ips)))
+	return append(append([]net.IP{}, ips[i:]...), ips[:i]...)
+}
+
+// answer returns the response to the question of query, its header and
+// question, with the addresses ips. A container has no IPv6 address, so an
+// AAAA question is answered without record, which resolvers take as the
+// name existing for IPv4 only.
+func answer(query []byte, qtype uint16, ips []net.IP) []byte {
+	response := make([]byte, len(query), len(query)+16*len(ips))
+	copy(response, query)
+	// QR and AA set, RD kept, RA set, no error
+	flags := binary.BigEndian.Uint16(query[2:])
+	binary.BigEndian.PutUint16(response[2:], 0x8400|flags&0x0100|0x0080)
+	// no authority nor additional records
+	binary.BigEndian.PutUint16(response[8:], 0)
+	binary.BigEndian.PutUint16(response[10:], 0)
+
+	var count uint16
+	for _, ip := range ips {
+		ip4 := ip.To4()
+		if qtype == typeAAAA || ip4 == nil {
+			continue
+		}
+		record := make([]byte, 16)
+		// the name is a pointer to the question
+		binary.BigEndian.PutUint16(record[0:], 0xc000|headerLen)
+		binary.BigEndian.PutUint16(record[2:], typeA)
+		binary.BigEndian.PutUint16(record[4:], classIN)
+		// a TTL of 0, the address changes when the container restarts
+		binary.BigEndian.PutUint32(record[6:], 0)
+		binary.BigEndian.PutUint16(record[10:], 4)
+		copy(record[12:], ip4)
+		response = append(response, record...)
+		count++
+	}
+	binary.BigEndian.PutUint16(response[6:], count)
+	return response
+}
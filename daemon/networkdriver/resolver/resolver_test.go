@@ -0,0 +1,305 @@
+package resolver
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newQuery(id uint16, name string, qtype uint16) []byte {
+	msg := make([]byte, headerLen)
+	binary.BigEndian.PutUint16(msg[0:], id)
+	// recursion desired
+	binary.BigEndian.PutUint16(msg[2:], 0x0100)
+	binary.BigEndian.PutUint16(msg[4:], 1)
+	for _, label := range strings.Split(name, ".") {
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint16(msg[len(msg)-4:], qtype)
+	binary.BigEndian.PutUint16(msg[len(msg)-2:], classIN)
+	return msg
+}
+
+var localClients = &net.IPNet{IP: net.IPv4(127, 0, 0, 0), Mask: net.CIDRMask(8, 32)}
+
+func exchangeWith(t *testing.T, r *Resolver, query []byte) []byte {
+	response, err := exchange("udp", r.Addr().String(), query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(response[:2], query[:2]) {
+		t.Fatalf("Expected the ID of the query, got %v", response[:2])
+	}
+	return response
+}
+
+func TestResolverLookup(t *testing.T) {
+	var clients []net.IP
+	r, err := New("127.0.0.1:0", localClients, nil, func(client net.IP, name string) []net.IP {
+		clients = append(clients, client)
+		if name == "db" {
+			return []net.IP{net.ParseIP("172.17.0.5")}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go r.Serve()
+	defer r.Close()
+
+	response := exchangeWith(t, r, newQuery(42, "DB", typeA))
+	if flags := binary.BigEndian.Uint16(response[2:]); flags&0x8000 == 0 || flags&0x000f != 0 {
+		t.Fatalf("Expected a successful response, got flags %x", flags)
+	}
+	if count := binary.BigEndian.Uint16(response[6:]); count != 1 {
+		t.Fatalf("Expected 1 answer, got %d", count)
+	}
+	if ip := net.IP(response[len(response)-4:]); !ip.Equal(net.ParseIP("172.17.0.5")) {
+		t.Fatalf("Expected 172.17.0.5, got %s", ip)
+	}
+	if len(clients) != 1 || !clients[0].Equal(net.ParseIP("127.0.0.1")) {
+		t.Fatalf("Expected the lookup for 127.0.0.1, got %v", clients)
+	}
+
+	response = exchangeWith(t, r, newQuery(43, "db", typeAAAA))
+	if count := binary.BigEndian.Uint16(response[6:]); count != 0 {
+		t.Fatalf("Expected no IPv6 answer, got %d", count)
+	}
+}
+
+func TestResolverRoundRobin(t *testing.T) {
+	ips := []net.IP{net.ParseIP("172.17.0.5"), net.ParseIP("172.17.0.6")}
+	r, err := New("127.0.0.1:0", localClients, nil, func(client net.IP, name string) []net.IP {
+		return ips
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go r.Serve()
+	defer r.Close()
+
+	var firsts []string
+	for id := uint16(1); id <= 2; id++ {
+		response := exchangeWith(t, r, newQuery(id, "web", typeA))
+		if count := binary.BigEndian.Uint16(response[6:]); count != 2 {
+			t.Fatalf("Expected 2 answers, got %d", count)
+		}
+		// the address of the first of the 2 records of 16 bytes
+		firsts = append(firsts, net.IP(response[len(response)-20:len(response)-16]).String())
+	}
+	if firsts[0] == firsts[1] {
+		t.Fatalf("Expected the first address to rotate, got %v", firsts)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	ips := []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2"), net.ParseIP("10.0.0.3")}
+	rotated := rotate(ips, 4)
+	if !rotated[0].Equal(ips[1]) || !rotated[1].Equal(ips[2]) || !rotated[2].Equal(ips[0]) {
+		t.Fatalf("Expected the addresses from the second, got %v", rotated)
+	}
+	if !ips[0].Equal(net.ParseIP("10.0.0.1")) {
+		t.Fatalf("Expected the addresses of the lookup to be left as is, got %v", ips)
+	}
+}
+
+func TestResolverForward(t *testing.T) {
+	upstream, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer upstream.Close()
+	go func() {
+		buf := make([]byte, 512)
+		n, addr, err := upstream.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		// answer with the query marked as a response
+		buf[2] |= 0x80
+		upstream.WriteToUDP(buf[:n], addr)
+	}()
+
+	r, err := New("127.0.0.1:0", localClients, []string{upstream.LocalAddr().String()}, func(net.IP, string) []net.IP {
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go r.Serve()
+	defer r.Close()
+
+	response := exchangeWith(t, r, newQuery(7, "example.com", typeA))
+	if response[2]&0x80 == 0 {
+		t.Fatal("Expected the response of the upstream server")
+	}
+}
+
+func TestResolverTCP(t *testing.T) {
+	upstream, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer upstream.Close()
+	go func() {
+		conn, err := upstream.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		query, err := readTCPMessage(conn)
+		if err != nil {
+			return
+		}
+		// answer with the query marked as a response, too large for UDP
+		query[2] |= 0x80
+		writeTCPMessage(conn, append(query, make([]byte, 1024)...))
+	}()
+
+	r, err := New("127.0.0.1:0", localClients, []string{upstream.Addr().String()}, func(client net.IP, name string) []net.IP {
+		if name == "db" {
+			return []net.IP{net.ParseIP("172.17.0.5")}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go r.Serve()
+	defer r.Close()
+
+	// the queries of a connection are answered in turn
+	conn, err := net.Dial("tcp", r.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if err := writeTCPMessage(conn, newQuery(10, "db", typeA)); err != nil {
+		t.Fatal(err)
+	}
+	response, err := readTCPMessage(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip := net.IP(response[len(response)-4:]); !ip.Equal(net.ParseIP("172.17.0.5")) {
+		t.Fatalf("Expected 172.17.0.5, got %s", ip)
+	}
+	if err := writeTCPMessage(conn, newQuery(11, "example.com", typeA)); err != nil {
+		t.Fatal(err)
+	}
+	if response, err = readTCPMessage(conn); err != nil {
+		t.Fatal(err)
+	}
+	if binary.BigEndian.Uint16(response) != 11 || response[2]&0x80 == 0 || len(response) < 1024 {
+		t.Fatalf("Expected the response of the upstream server over TCP, got %d bytes", len(response))
+	}
+}
+
+func TestResolverSetUpstreams(t *testing.T) {
+	upstream, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer upstream.Close()
+	go func() {
+		buf := make([]byte, 512)
+		n, addr, err := upstream.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		buf[2] |= 0x80
+		upstream.WriteToUDP(buf[:n], addr)
+	}()
+
+	r, err := New("127.0.0.1:0", localClients, []string{"10.0.0.1"}, func(net.IP, string) []net.IP {
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.upstreams) != 1 || r.upstreams[0] != "10.0.0.1:53" {
+		t.Fatalf("Expected the upstream on port 53, got %v", r.upstreams)
+	}
+	go r.Serve()
+	defer r.Close()
+
+	r.SetUpstreams([]string{upstream.LocalAddr().String()})
+	response := exchangeWith(t, r, newQuery(9, "example.com", typeA))
+	if response[2]&0x80 == 0 {
+		t.Fatal("Expected the response of the new upstream server")
+	}
+}
+
+func TestResolverOtherClients(t *testing.T) {
+	lookups := 0
+	clients := &net.IPNet{IP: net.IPv4(172, 17, 0, 0), Mask: net.CIDRMask(16, 32)}
+	r, err := New("127.0.0.1:0", clients, []string{"127.0.0.1:1"}, func(net.IP, string) []net.IP {
+		lookups++
+		return []net.IP{net.ParseIP("172.17.0.5")}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go r.Serve()
+	defer r.Close()
+
+	// neither answered nor forwarded
+	if _, err := exchange("udp", r.Addr().String(), newQuery(8, "db", typeA)); err == nil {
+		t.Fatal("Expected the query of an address outside of the clients to be dropped")
+	}
+	conn, err := net.Dial("tcp", r.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	writeTCPMessage(conn, newQuery(9, "db", typeA))
+	if _, err := readTCPMessage(conn); err == nil {
+		t.Fatal("Expected the connection of an address outside of the clients to be closed")
+	}
+	if lookups != 0 {
+		t.Fatalf("Expected no lookup, got %d", lookups)
+	}
+}
+
+func TestResolverClose(t *testing.T) {
+	r, err := New("127.0.0.1:0", localClients, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		r.Serve()
+		close(done)
+	}()
+	r.Close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Serve did not return once the resolver was closed")
+	}
+}
+
+func TestParseQuestion(t *testing.T) {
+	if _, _, _, err := parseQuestion([]byte{0, 1, 2}); err == nil {
+		t.Fatal("Expected an error for a truncated query")
+	}
+	query := newQuery(1, "web.local", typeA)
+	name, qtype, end, err := parseQuestion(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "web.local" || qtype != typeA || end != len(query) {
+		t.Fatalf("Got %q, type %d, end %d", name, qtype, end)
+	}
+	if _, qtype, _, _ := parseQuestion(newQuery(1, "web", 15)); qtype != 0 {
+		t.Fatalf("Expected MX questions not to be answered, got type %d", qtype)
+	}
+}
@@ -43,6 +43,25 @@ func CheckRouteOverlaps(toCheck *net.IPNet) error {
 	return nil
 }
 
+// OverlappingRoutes returns the routes overlapping with toCheck which do not
+// go through iface.
+func OverlappingRoutes(toCheck *net.IPNet, iface string) ([]netlink.Route, error) {
+	routes, err := networkGetRoutesFct()
+	if err != nil {
+		return nil, err
+	}
+	var overlapping []netlink.Route
+	for _, route := range routes {
+		if route.IPNet == nil || (route.Iface != nil && route.Iface.Name == iface) {
+			continue
+		}
+		if NetworkOverlaps(toCheck, route.IPNet) {
+			overlapping = append(overlapping, route)
+		}
+	}
+	return overlapping, nil
+}
+
 // Detects overlap between one IPNet and another
 func NetworkOverlaps(netX *net.IPNet, netY *net.IPNet) bool {
 	if firstIP, _ := NetworkRange(netX); netY.Contains(firstIP) {
@@ -0,0 +1,34 @@
+package daemon
+
+import (
+	"time"
+
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/pkg/plugins"
+)
+
+// pluginsHealthInterval is the time between the health checks of the
+// active plugins.
+const pluginsHealthInterval = 30 * time.Second
+
+// PluginList lists the plugins found by the daemon, active or not.
+func (daemon *Daemon) PluginList(job *engine.Job) engine.Status {
+	list, err := plugins.List()
+	if err != nil {
+		return job.Error(err)
+	}
+	outs := engine.NewTable("", 0)
+	for _, p := range list {
+		out := &engine.Env{}
+		out.Set("Name", p.Name)
+		out.Set("Addr", p.Addr)
+		out.SetList("Implements", p.Implements)
+		out.Set("Status", p.Status)
+		out.Set("Err", p.Err)
+		outs.Add(out)
+	}
+	if _, err := outs.WriteListTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
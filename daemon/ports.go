@@ -0,0 +1,209 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/nat"
+	"github.com/docker/docker/pkg/log"
+)
+
+// ContainerPorts publishes and unpublishes ports of a running container
+// without restarting it. "Publish" takes specs in the format of
+// `docker run -p`, "Unpublish" takes container ports such as 80/tcp.
+// The change is saved in the host config so it survives restarts. The
+// unpublished ports are only released once all the new ones are bound, so
+// that a failure leaves the container as it was.
+func (daemon *Daemon) ContainerPorts(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s CONTAINER\n", job.Name)
+	}
+	name := job.Args[0]
+	container := daemon.Get(name)
+	if container == nil {
+		return job.Errorf("No such container: %s\n", name)
+	}
+
+	exposed, bindings, err := nat.ParsePortSpecs(job.GetenvList("Publish"))
+	if err != nil {
+		return job.Error(err)
+	}
+	unpublish, _, err := nat.ParsePortSpecs(job.GetenvList("Unpublish"))
+	if err != nil {
+		return job.Error(err)
+	}
+
+	container.Lock()
+	defer container.Unlock()
+
+	if !container.State.IsRunning() {
+		return job.Errorf("Container %s is not running\n", name)
+	}
+	if container.NetworkSettings.IPAddress == "" {
+		return job.Errorf("Cannot publish ports of container %s: it does not use the bridge network\n", name)
+	}
+
+	ports := make(nat.PortMap)
+	for port, binding := range container.NetworkSettings.Ports {
+		ports[port] = binding
+	}
+	for port := range unpublish {
+		if _, exists := ports[port]; !exists {
+			return job.Errorf("Port %s of container %s is not published\n", port, name)
+		}
+	}
+	for port := range exposed {
+		if _, unpublished := unpublish[port]; len(ports[port]) > 0 && !unpublished {
+			return job.Errorf("Port %s of container %s is already published\n", port, name)
+		}
+	}
+
+	allocated := make(nat.PortMap)
+	for port := range exposed {
+		binding := bindings[port]
+		if len(binding) == 0 {
+			binding = []nat.PortBinding{{}}
+		}
+		for _, b := range binding {
+			portJob := daemon.eng.Job("allocate_port", container.ID)
+			portJob.Setenv("HostIP", b.HostIp)
+			portJob.Setenv("HostPort", b.HostPort)
+			portJob.Setenv("Proto", port.Proto())
+			portJob.Setenv("ContainerPort", port.Port())
+			portEnv, err := portJob.Stdout.AddEnv()
+			if err == nil {
+				err = portJob.Run()
+			}
+			if err != nil {
+				daemon.releasePortMap(container, allocated)
+				return job.Error(err)
+			}
+			allocated[port] = append(allocated[port], nat.PortBinding{
+				HostIp:   portEnv.Get("HostIP"),
+				HostPort: portEnv.Get("HostPort"),
+			})
+		}
+	}
+
+	var (
+		released     = make(nat.PortMap)
+		portBindings = make(nat.PortMap)
+		exposedPorts = make(nat.PortSet)
+	)
+	for port, binding := range container.hostConfig.PortBindings {
+		portBindings[port] = binding
+	}
+	for port := range container.Config.ExposedPorts {
+		exposedPorts[port] = struct{}{}
+	}
+	for port := range unpublish {
+		released[port] = ports[port]
+		delete(ports, port)
+		delete(portBindings, port)
+	}
+	for port, binding := range allocated {
+		ports[port] = binding
+		exposedPorts[port] = struct{}{}
+		portBindings[port] = bindings[port]
+	}
+
+	oldPorts, oldPortBindings, oldExposedPorts := container.NetworkSettings.Ports, container.hostConfig.PortBindings, container.Config.ExposedPorts
+	container.NetworkSettings.Ports, container.hostConfig.PortBindings, container.Config.ExposedPorts = ports, portBindings, exposedPorts
+	if err := container.toDisk(); err != nil {
+		container.NetworkSettings.Ports, container.hostConfig.PortBindings, container.Config.ExposedPorts = oldPorts, oldPortBindings, oldExposedPorts
+		if err := container.toDisk(); err != nil {
+			log.Errorf("Error restoring the ports of %s: %s", container.ID, err)
+		}
+		daemon.releasePortMap(container, allocated)
+		return job.Error(err)
+	}
+	daemon.releasePortMap(container, released)
+
+	out := &engine.Env{}
+	out.SetJson("Ports", ports)
+	if _, err := out.WriteTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+// ContainerPortMap lists the ports of a container, with the host address
+// each published port is bound to, as the port mapper bound it. "Proto"
+// only lists the ports of a protocol, and "Port" a port of the container,
+// such as 53/udp, or 80 for the tcp one unless "Proto" says otherwise.
+func (daemon *Daemon) ContainerPortMap(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s CONTAINER\n", job.Name)
+	}
+	name := job.Args[0]
+	container := daemon.Get(name)
+	if container == nil {
+		return job.Errorf("No such container: %s\n", name)
+	}
+	proto, port, err := parsePortFilter(job.Getenv("Proto"), job.Getenv("Port"))
+	if err != nil {
+		return job.Error(err)
+	}
+
+	container.Lock()
+	ports := container.NetworkSettings.PortMappingAPI()
+	container.Unlock()
+
+	outs := engine.NewTable("PrivatePort", 0)
+	for _, out := range ports.Data {
+		if (proto == "" || out.Get("Type") == proto) && (port == 0 || out.GetInt("PrivatePort") == port) {
+			outs.Add(out)
+		}
+	}
+	outs.Sort()
+	if _, err := outs.WriteListTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+// parsePortFilter returns the protocol and the port of the container to
+// list the mappings of, given the protocol and the port filters of
+// ContainerPortMap. An empty protocol or a 0 port match them all.
+func parsePortFilter(proto, spec string) (string, int, error) {
+	if proto != "" && proto != "tcp" && proto != "udp" {
+		return "", 0, fmt.Errorf("Invalid protocol: %s", proto)
+	}
+	if spec == "" {
+		return proto, 0, nil
+	}
+	specProto, rawPort := nat.SplitProtoPort(spec)
+	if strings.Contains(spec, "/") && proto != "" && proto != specProto {
+		return "", 0, fmt.Errorf("Port %s does not have the protocol %s", spec, proto)
+	}
+	if proto == "" {
+		proto = specProto
+	}
+	port, err := nat.ParsePort(rawPort)
+	if err != nil || port == 0 || (proto != "tcp" && proto != "udp") {
+		return "", 0, fmt.Errorf("Invalid port: %s", spec)
+	}
+	return proto, port, nil
+}
+
+// releasePortMap unmaps the host ports bound to the ports of the container
+// in ports.
+func (daemon *Daemon) releasePortMap(container *Container, ports nat.PortMap) {
+	for port, binding := range ports {
+		daemon.releasePorts(container, port, binding)
+	}
+}
+
+// releasePorts unmaps the host ports bound to a port of the container
+func (daemon *Daemon) releasePorts(container *Container, port nat.Port, binding []nat.PortBinding) {
+	for _, b := range binding {
+		job := daemon.eng.Job("release_port", container.ID)
+		job.Setenv("HostIP", b.HostIp)
+		job.Setenv("HostPort", b.HostPort)
+		job.Setenv("Proto", port.Proto())
+		if err := job.Run(); err != nil {
+			log.Errorf("Error releasing port %s of %s: %s", port, container.ID, err)
+		}
+	}
+}
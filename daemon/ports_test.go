@@ -0,0 +1,201 @@
+package daemon
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/nat"
+	"github.com/docker/docker/pkg/truncindex"
+	"github.com/docker/docker/runconfig"
+)
+
+// newTestPortsDaemon returns a daemon with a running container "web", its
+// port 80 published on 8080, and the events of its fake port allocator.
+func newTestPortsDaemon(t *testing.T, root string) (*Daemon, *Container, *[]string) {
+	var events []string
+	eng := engine.New()
+	eng.Logging = false
+	eng.Register("allocate_port", func(job *engine.Job) engine.Status {
+		hostPort := job.Getenv("HostPort")
+		if hostPort == "1" {
+			return job.Errorf("Bind for 0.0.0.0:1 failed: port is already allocated")
+		}
+		if hostPort == "" {
+			hostPort = "49153"
+		}
+		events = append(events, "allocate "+hostPort+"/"+job.Getenv("Proto"))
+		out := &engine.Env{}
+		out.Set("HostIP", "0.0.0.0")
+		out.Set("HostPort", hostPort)
+		out.WriteTo(job.Stdout)
+		return engine.StatusOK
+	})
+	eng.Register("release_port", func(job *engine.Job) engine.Status {
+		events = append(events, "release "+job.Getenv("HostPort")+"/"+job.Getenv("Proto"))
+		return engine.StatusOK
+	})
+
+	container := &Container{
+		ID:    "web",
+		root:  root,
+		State: NewState(),
+		Config: &runconfig.Config{
+			ExposedPorts: nat.PortSet{"80/tcp": struct{}{}},
+		},
+		hostConfig: &runconfig.HostConfig{
+			PortBindings: nat.PortMap{"80/tcp": {{HostPort: "8080"}}},
+		},
+		NetworkSettings: &NetworkSettings{
+			IPAddress: "172.17.0.5",
+			Ports:     nat.PortMap{"80/tcp": {{HostIp: "0.0.0.0", HostPort: "8080"}}},
+		},
+	}
+	container.State.SetRunning(42)
+	daemon := &Daemon{
+		eng:        eng,
+		containers: &contStore{s: map[string]*Container{"web": container}},
+		idIndex:    truncindex.NewTruncIndex([]string{"web"}),
+	}
+	if err := eng.Register("ports", daemon.ContainerPorts); err != nil {
+		t.Fatal(err)
+	}
+	return daemon, container, &events
+}
+
+func runPortsJob(daemon *Daemon, publish, unpublish []string) error {
+	job := daemon.eng.Job("ports", "web")
+	job.SetenvList("Publish", publish)
+	job.SetenvList("Unpublish", unpublish)
+	return job.Run()
+}
+
+func TestContainerPorts(t *testing.T) {
+	root, err := ioutil.TempDir("", "docker-ports-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	daemon, container, events := newTestPortsDaemon(t, root)
+
+	if err := runPortsJob(daemon, []string{"443"}, []string{"80/tcp"}); err != nil {
+		t.Fatal(err)
+	}
+	// the unpublished port is released once the new one is bound
+	if expected := []string{"allocate 49153/tcp", "release 8080/tcp"}; !reflect.DeepEqual(*events, expected) {
+		t.Fatalf("Expected %v, got %v", expected, *events)
+	}
+	expected := nat.PortMap{"443/tcp": {{HostIp: "0.0.0.0", HostPort: "49153"}}}
+	if !reflect.DeepEqual(container.NetworkSettings.Ports, expected) {
+		t.Fatalf("Expected the ports %v, got %v", expected, container.NetworkSettings.Ports)
+	}
+	if _, exists := container.Config.ExposedPorts["443/tcp"]; !exists {
+		t.Fatalf("Expected 443/tcp to be exposed, got %v", container.Config.ExposedPorts)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(root, "hostconfig.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hostConfig runconfig.HostConfig
+	if err := json.Unmarshal(data, &hostConfig); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := hostConfig.PortBindings["80/tcp"]; exists {
+		t.Fatalf("Expected 80/tcp to be unpublished on disk, got %v", hostConfig.PortBindings)
+	}
+	if _, exists := hostConfig.PortBindings["443/tcp"]; !exists {
+		t.Fatalf("Expected 443/tcp to be published on disk, got %v", hostConfig.PortBindings)
+	}
+
+	for _, c := range []struct {
+		publish, unpublish []string
+	}{
+		{nil, []string{"80/tcp"}},
+		{[]string{"443"}, nil},
+	} {
+		*events = nil
+		if err := runPortsJob(daemon, c.publish, c.unpublish); err == nil {
+			t.Fatalf("Expected an error publishing %v and unpublishing %v", c.publish, c.unpublish)
+		}
+		if len(*events) != 0 {
+			t.Fatalf("Expected no port to change, got %v", *events)
+		}
+	}
+
+	container.State.SetStopped(0)
+	if err := runPortsJob(daemon, []string{"8443:8443"}, nil); err == nil {
+		t.Fatal("Expected an error publishing a port of a stopped container")
+	}
+}
+
+func TestContainerPortsRollback(t *testing.T) {
+	root, err := ioutil.TempDir("", "docker-ports-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	daemon, container, events := newTestPortsDaemon(t, root)
+
+	if err := runPortsJob(daemon, []string{"8443:443", "1:9000"}, []string{"80/tcp"}); err == nil {
+		t.Fatal("Expected an error for a port already allocated")
+	}
+	// the port allocated before the failure, if any, is released, and the
+	// unpublished one is kept
+	var allocated, released bool
+	for _, event := range *events {
+		switch event {
+		case "allocate 8443/tcp":
+			allocated = true
+		case "release 8443/tcp":
+			released = true
+		case "release 8080/tcp":
+			t.Fatalf("Expected the port 80 to stay published, got %v", *events)
+		}
+	}
+	if allocated != released {
+		t.Fatalf("Expected every allocated port to be released, got %v", *events)
+	}
+	expected := nat.PortMap{"80/tcp": {{HostIp: "0.0.0.0", HostPort: "8080"}}}
+	if !reflect.DeepEqual(container.NetworkSettings.Ports, expected) {
+		t.Fatalf("Expected the ports %v, got %v", expected, container.NetworkSettings.Ports)
+	}
+	if expected := (nat.PortMap{"80/tcp": {{HostPort: "8080"}}}); !reflect.DeepEqual(container.hostConfig.PortBindings, expected) {
+		t.Fatalf("Expected the bindings %v, got %v", expected, container.hostConfig.PortBindings)
+	}
+	if _, err := os.Stat(filepath.Join(root, "hostconfig.json")); !os.IsNotExist(err) {
+		t.Fatalf("Expected the host config not to be saved, got %v", err)
+	}
+}
+
+func TestParsePortFilter(t *testing.T) {
+	for _, c := range []struct {
+		proto, spec string
+		expected    string
+		port        int
+	}{
+		{"", "", "", 0},
+		{"udp", "", "udp", 0},
+		{"", "80", "tcp", 80},
+		{"", "53/udp", "udp", 53},
+		{"udp", "53", "udp", 53},
+		{"tcp", "80/tcp", "tcp", 80},
+	} {
+		proto, port, err := parsePortFilter(c.proto, c.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if proto != c.expected || port != c.port {
+			t.Fatalf("Expected %q and %q to filter %d/%s, got %d/%s", c.proto, c.spec, c.port, c.expected, port, proto)
+		}
+	}
+	for _, c := range [][2]string{{"sctp", ""}, {"tcp", "53/udp"}, {"", "http"}, {"", "0"}, {"", "80/ip"}} {
+		if _, _, err := parsePortFilter(c[0], c[1]); err == nil {
+			t.Fatalf("Expected an error for %q and %q", c[0], c[1])
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package daemon
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/pkg/parsers/filters"
+)
+
+// Prune removes the containers which are not running ("containers") or the
+// dangling images ("images") matching the "filters". With "dryRun" nothing
+// is removed, the job only reports what would be and the space it frees.
+//
+// Both accept the "until" filter, a duration such as 24h, to keep the
+// objects created more recently; containers also accept "exited" to only
+// remove the ones which exited with the given codes, and "unused" to keep
+// the ones which ran more recently than the given duration.
+func (daemon *Daemon) Prune(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s containers|images", job.Name)
+	}
+	pruneFilters, err := filters.FromParam(job.Getenv("filters"))
+	if err != nil {
+		return job.Error(err)
+	}
+	var until time.Time
+	for _, value := range pruneFilters["until"] {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return job.Errorf("Invalid until filter %s: %s", value, err)
+		}
+		if t := time.Now().UTC().Add(-d); until.IsZero() || t.Before(until) {
+			until = t
+		}
+	}
+
+	var (
+		deleted   []string
+		reclaimed int64
+		dryRun    = job.GetenvBool("dryRun")
+	)
+	switch job.Args[0] {
+	case "containers":
+		deleted, reclaimed, err = daemon.pruneContainers(job.Eng, pruneFilters, until, dryRun)
+	case "images":
+		deleted, reclaimed, err = daemon.pruneImages(job.Eng, pruneFilters, until, dryRun)
+	default:
+		return job.Errorf("Cannot prune %s, expected containers or images", job.Args[0])
+	}
+	if err != nil {
+		return job.Error(err)
+	}
+
+	out := &engine.Env{}
+	out.SetList("Deleted", deleted)
+	out.SetInt64("SpaceReclaimed", reclaimed)
+	out.SetBool("DryRun", dryRun)
+	if _, err := out.WriteTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+func (daemon *Daemon) pruneContainers(eng *engine.Engine, pruneFilters filters.Args, until time.Time, dryRun bool) ([]string, int64, error) {
+	var (
+		exitCodes = make(map[int]bool)
+		unused    time.Time
+	)
+	for name, values := range pruneFilters {
+		switch name {
+		case "until":
+		case "unused":
+			for _, value := range values {
+				d, err := time.ParseDuration(value)
+				if err != nil {
+					return nil, 0, fmt.Errorf("Invalid unused filter %s: %s", value, err)
+				}
+				if t := time.Now().UTC().Add(-d); unused.IsZero() || t.Before(unused) {
+					unused = t
+				}
+			}
+		case "exited":
+			for _, value := range values {
+				code, err := strconv.Atoi(value)
+				if err != nil {
+					return nil, 0, fmt.Errorf("Invalid exited filter %s: %s", value, err)
+				}
+				exitCodes[code] = true
+			}
+		default:
+			return nil, 0, fmt.Errorf("Invalid filter %s for containers", name)
+		}
+	}
+
+	var (
+		deleted   []string
+		reclaimed int64
+	)
+	for _, container := range daemon.List() {
+		if container.State.IsRunning() || container.State.IsRestarting() {
+			continue
+		}
+		if !until.IsZero() && container.Created.After(until) {
+			continue
+		}
+		if len(exitCodes) > 0 && !exitCodes[container.State.GetExitCode()] {
+			continue
+		}
+		if !unused.IsZero() && container.lastUsed().After(unused) {
+			continue
+		}
+		sizeRw, _ := container.GetSize()
+		if !dryRun {
+			if err := eng.Job("delete", container.ID).Run(); err != nil {
+				return deleted, reclaimed, err
+			}
+		}
+		deleted = append(deleted, container.ID)
+		reclaimed += sizeRw
+	}
+	return deleted, reclaimed, nil
+}
+
+// pruneImages removes the images which have no tag, are not the parent of
+// another image and are not used by any container, along with their
+// parents which become dangling in turn.
+func (daemon *Daemon) pruneImages(eng *engine.Engine, pruneFilters filters.Args, until time.Time, dryRun bool) ([]string, int64, error) {
+	for name := range pruneFilters {
+		if name != "until" {
+			return nil, 0, fmt.Errorf("Invalid filter %s for images", name)
+		}
+	}
+
+	layers, err := daemon.graph.Map()
+	if err != nil {
+		return nil, 0, err
+	}
+	heads, err := daemon.graph.Heads()
+	if err != nil {
+		return nil, 0, err
+	}
+	var (
+		tags       = daemon.repositories.ByID()
+		imageUsers = make(map[string]bool)
+		listed     []string
+		dangling   []string
+	)
+	for _, container := range daemon.List() {
+		imageUsers[container.Image] = true
+	}
+	for id := range layers {
+		_, tagged := tags[id]
+		_, head := heads[id]
+		if !tagged && head && !imageUsers[id] {
+			dangling = append(dangling, id)
+		}
+		if tagged || head || imageUsers[id] {
+			listed = append(listed, id)
+		}
+	}
+	sort.Strings(dangling)
+	sizes := imageSizes(listed, layers)
+
+	var (
+		deleted   []string
+		reclaimed int64
+	)
+	for _, id := range dangling {
+		if !until.IsZero() && layers[id].Created.After(until) {
+			continue
+		}
+		if dryRun {
+			deleted = append(deleted, id)
+		} else {
+			job := eng.Job("image_delete", id)
+			imgs, err := job.Stdout.AddListTable()
+			if err != nil {
+				return deleted, reclaimed, err
+			}
+			if err := job.Run(); err != nil {
+				return deleted, reclaimed, err
+			}
+			for _, img := range imgs.Data {
+				if d := img.Get("Deleted"); d != "" {
+					deleted = append(deleted, d)
+				}
+			}
+		}
+		reclaimed += sizes[id][0] - sizes[id][1]
+	}
+	return deleted, reclaimed, nil
+}
@@ -0,0 +1,75 @@
+package daemon
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/pkg/networkfs/resolvconf"
+	"github.com/docker/docker/utils"
+)
+
+// resolvConfInterval is how often the resolv.conf of the host is checked
+// for changes, e.g. of a DHCP lease renewal or a VPN coming up.
+const resolvConfInterval = 5 * time.Second
+
+// watchResolvConf updates the resolv.conf of the containers when the one of
+// the host changes.
+func (daemon *Daemon) watchResolvConf(interval time.Duration) {
+	last, _ := resolvconf.Get()
+	for _ = range time.Tick(interval) {
+		current, err := resolvconf.Get()
+		if err != nil || bytes.Equal(current, last) {
+			continue
+		}
+		last = current
+		// the resolver runs on the host, it forwards to its new servers
+		if len(daemon.config.Dns) == 0 && daemon.resolver != nil {
+			daemon.resolver.SetUpstreams(resolvconf.GetNameservers(current))
+		}
+		// the containers cannot reach the resolver of the host on its
+		// loopback, they keep the servers they have
+		if len(daemon.config.Dns) == 0 && utils.CheckLocalDns(current) {
+			log.Infof("Local (127.0.0.1) DNS resolver found in the new resolv.conf, not updating the containers")
+			continue
+		}
+		log.Debugf("The resolv.conf of the host changed, updating the containers")
+		for _, container := range daemon.List() {
+			if err := container.updateResolvConf(current); err != nil {
+				log.Errorf("Error updating the resolv.conf of %s: %s", container.ID, err)
+			}
+		}
+	}
+}
+
+// updateResolvConf writes the resolv.conf of the container again from the
+// new one of the host, resolvConf, unless the container changed it since it
+// was written.
+func (container *Container) updateResolvConf(resolvConf []byte) error {
+	container.Lock()
+	defer container.Unlock()
+
+	// the containers sharing the network of another one share its file
+	if container.ResolvConfPath == "" || container.ResolvConfHash == "" || container.hostConfig == nil || container.hostConfig.NetworkMode.IsContainer() {
+		return nil
+	}
+	if container.EmbeddedDns && container.daemon.resolver == nil {
+		return nil
+	}
+	content, err := ioutil.ReadFile(container.ResolvConfPath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if hash, err := utils.HashData(bytes.NewReader(content)); err != nil || hash != container.ResolvConfHash {
+		log.Debugf("The resolv.conf of %s was changed, not updating it", container.ID)
+		return err
+	}
+	if err := container.writeResolvConf(resolvConf); err != nil {
+		return err
+	}
+	return container.toDisk()
+}
@@ -0,0 +1,62 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/runconfig"
+)
+
+func TestUpdateResolvConf(t *testing.T) {
+	root, err := ioutil.TempDir("", "docker-resolvconf-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	container := &Container{
+		ID:             "db",
+		root:           root,
+		State:          NewState(),
+		Config:         &runconfig.Config{},
+		hostConfig:     &runconfig.HostConfig{NetworkMode: "bridge"},
+		daemon:         &Daemon{config: &Config{}},
+		ResolvConfPath: filepath.Join(root, "resolv.conf"),
+	}
+	if err := container.writeResolvConf([]byte("nameserver 10.0.0.1\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the container follows the host
+	if err := container.updateResolvConf([]byte("nameserver 10.0.0.2\n")); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := ioutil.ReadFile(container.ResolvConfPath); string(content) != "nameserver 10.0.0.2\n" {
+		t.Fatalf("Expected the new resolv.conf of the host, got %q", content)
+	}
+
+	// but not once it changed its resolv.conf
+	if err := ioutil.WriteFile(container.ResolvConfPath, []byte("nameserver 8.8.8.8\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := container.updateResolvConf([]byte("nameserver 10.0.0.3\n")); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := ioutil.ReadFile(container.ResolvConfPath); string(content) != "nameserver 8.8.8.8\n" {
+		t.Fatalf("Expected the resolv.conf of the container to be kept, got %q", content)
+	}
+
+	// the servers of --dns are kept, the search domains follow the host
+	container.hostConfig.Dns = []string{"8.8.4.4"}
+	if err := container.writeResolvConf([]byte("nameserver 10.0.0.3\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := container.updateResolvConf([]byte("nameserver 10.0.0.4\nsearch corp.example.com\n")); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := ioutil.ReadFile(container.ResolvConfPath); string(content) != "nameserver 8.8.4.4\nsearch corp.example.com\n" {
+		t.Fatalf("Expected the servers of --dns with the search domains of the host, got %q", content)
+	}
+}
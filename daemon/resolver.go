@@ -0,0 +1,101 @@
+package daemon
+
+import (
+	"net"
+	"path"
+	"strings"
+
+	"github.com/docker/docker/daemon/networkdriver/resolver"
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/pkg/networkfs/resolvconf"
+)
+
+// startResolver starts the DNS resolver of the containers of the default
+// bridge, on the address of the bridge, so their links keep resolving to
+// the current address of the linked containers after these restart.
+func (daemon *Daemon) startResolver() error {
+	job := daemon.eng.Job("network_inspect", "bridge")
+	network, err := job.Stdout.AddEnv()
+	if err != nil {
+		return err
+	}
+	if err := job.Run(); err != nil {
+		return err
+	}
+
+	upstreams := daemon.config.Dns
+	if len(upstreams) == 0 {
+		resolvConf, err := resolvconf.Get()
+		if err != nil {
+			return err
+		}
+		upstreams = resolvconf.GetNameservers(resolvConf)
+	}
+	_, subnet, err := net.ParseCIDR(network.Get("Subnet"))
+	if err != nil {
+		return err
+	}
+	r, err := resolver.New(net.JoinHostPort(network.Get("Gateway"), "53"), subnet, upstreams, daemon.resolveContainer)
+	if err != nil {
+		return err
+	}
+	daemon.resolver = r
+	go r.Serve()
+	log.Debugf("Resolving the names of the containers on %s", r.Addr())
+	return nil
+}
+
+// resolveContainer returns the addresses of the containers called name for
+// the container of address client: a container it links to under this
+// alias, else the containers of this service alias. The containers it does
+// not link to are not resolved by name, as with the /etc/hosts of the links.
+// Only the running containers of the default bridge are resolved.
+func (daemon *Daemon) resolveContainer(client net.IP, name string) []net.IP {
+	var (
+		querier    *Container
+		containers = daemon.List()
+	)
+	for _, c := range containers {
+		if c.State.IsRunning() && client.Equal(net.ParseIP(c.NetworkSettings.IPAddress)) {
+			querier = c
+			break
+		}
+	}
+	if querier == nil {
+		return nil
+	}
+
+	if children, err := daemon.Children(querier.Name); err == nil {
+		for p, child := range children {
+			if strings.ToLower(path.Base(p)) == name {
+				if ip := containerIP(child); ip != nil {
+					return []net.IP{ip}
+				}
+				return nil
+			}
+		}
+	}
+
+	// the order of the addresses is stable, for the resolver to rotate them
+	var ips []net.IP
+	for _, c := range containers {
+		for _, alias := range c.hostConfig.ServiceAliases {
+			if alias == name {
+				if ip := containerIP(c); ip != nil {
+					ips = append(ips, ip)
+				}
+				break
+			}
+		}
+	}
+	return ips
+}
+
+// containerIP returns the address of a running container on the default
+// bridge, or nil.
+func containerIP(container *Container) net.IP {
+	if !container.State.IsRunning() || container.NetworkSettings.IPAddress == "" {
+		return nil
+	}
+	return net.ParseIP(container.NetworkSettings.IPAddress)
+}
@@ -16,6 +16,9 @@ func (daemon *Daemon) ContainerRestart(job *engine.Job) engine.Status {
 		t = job.GetenvInt("t")
 	}
 	if container := daemon.Get(name); container != nil {
+		if err := daemon.checkNotDraining(); err != nil {
+			return job.Error(err)
+		}
 		if err := container.Restart(int(t)); err != nil {
 			return job.Errorf("Cannot restart container %s: %s\n", name, err)
 		}
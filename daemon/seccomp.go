@@ -0,0 +1,54 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/pkg/seccomp"
+	"github.com/docker/docker/runconfig"
+)
+
+// The names of the seccomp profiles shown by inspect.
+const (
+	seccompDefault    = "default"
+	seccompUnconfined = "unconfined"
+	seccompCustom     = "custom"
+)
+
+// seccompProfile returns the seccomp profile filtering the system calls of
+// a container, from its "seccomp=" security option, and its name. The
+// privileged containers, and the ones of the lxc driver or of a kernel
+// without seccomp, are unconfined unless they are given a profile, which
+// is then an error.
+func (daemon *Daemon) seccompProfile(hostConfig *runconfig.HostConfig) (*seccomp.Profile, string, error) {
+	var (
+		profile *seccomp.Profile
+		name    = seccompDefault
+	)
+	for _, opt := range hostConfig.SecurityOpt {
+		parts := strings.SplitN(opt, "=", 2)
+		if len(parts) != 2 || parts[0] != "seccomp" {
+			return nil, "", fmt.Errorf("Invalid security option: %s", opt)
+		}
+		if parts[1] == seccompUnconfined {
+			profile, name = nil, seccompUnconfined
+			continue
+		}
+		parsed, err := seccomp.ParseProfile([]byte(parts[1]))
+		if err != nil {
+			return nil, "", err
+		}
+		profile, name = parsed, seccompCustom
+	}
+
+	supported := daemon.config.ExecDriver == "native" && seccomp.Supported()
+	switch {
+	case name == seccompCustom && !supported:
+		return nil, "", fmt.Errorf("Cannot filter the system calls of the container with a seccomp profile: the native driver and a kernel with seccomp are needed")
+	case name == seccompDefault && (hostConfig.Privileged || !supported):
+		return nil, seccompUnconfined, nil
+	case name == seccompDefault:
+		return seccomp.DefaultProfile(), seccompDefault, nil
+	}
+	return profile, name, nil
+}
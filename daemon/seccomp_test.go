@@ -0,0 +1,46 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/docker/docker/pkg/seccomp"
+	"github.com/docker/docker/runconfig"
+)
+
+func TestSeccompProfile(t *testing.T) {
+	custom := `seccomp={"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"name": "mkdir", "action": "SCMP_ACT_ERRNO"}]}`
+
+	lxc := &Daemon{config: &Config{ExecDriver: "lxc"}}
+	if profile, name, err := lxc.seccompProfile(&runconfig.HostConfig{}); err != nil || profile != nil || name != seccompUnconfined {
+		t.Fatalf("Expected the containers of the lxc driver to be unconfined, got %v, %s, %v", profile, name, err)
+	}
+	if _, _, err := lxc.seccompProfile(&runconfig.HostConfig{SecurityOpt: []string{custom}}); err == nil {
+		t.Fatal("Expected an error for a seccomp profile with the lxc driver")
+	}
+
+	native := &Daemon{config: &Config{ExecDriver: "native"}}
+	for _, invalid := range []string{"seccomp", "apparmor=unconfined", "seccomp={"} {
+		if _, _, err := native.seccompProfile(&runconfig.HostConfig{SecurityOpt: []string{invalid}}); err == nil {
+			t.Fatalf("Expected an error for the security option %s", invalid)
+		}
+	}
+	if profile, name, err := native.seccompProfile(&runconfig.HostConfig{SecurityOpt: []string{"seccomp=unconfined"}}); err != nil || profile != nil || name != seccompUnconfined {
+		t.Fatalf("Expected an unconfined container, got %v, %s, %v", profile, name, err)
+	}
+	if profile, name, err := native.seccompProfile(&runconfig.HostConfig{Privileged: true}); err != nil || profile != nil || name != seccompUnconfined {
+		t.Fatalf("Expected a privileged container to be unconfined, got %v, %s, %v", profile, name, err)
+	}
+	if !seccomp.Supported() {
+		t.Skip("seccomp is not supported")
+	}
+	if profile, name, err := native.seccompProfile(&runconfig.HostConfig{}); err != nil || profile == nil || name != seccompDefault {
+		t.Fatalf("Expected the default profile, got %v, %s, %v", profile, name, err)
+	}
+	profile, name, err := native.seccompProfile(&runconfig.HostConfig{SecurityOpt: []string{custom}})
+	if err != nil || name != seccompCustom {
+		t.Fatalf("Expected the custom profile, got %s, %v", name, err)
+	}
+	if profile.DefaultAction != seccomp.ActAllow || len(profile.Syscalls) != 1 || profile.Syscalls[0].Name != "mkdir" {
+		t.Fatalf("Unexpected profile %#v", profile)
+	}
+}
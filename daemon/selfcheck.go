@@ -0,0 +1,174 @@
+package daemon
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/utils"
+)
+
+// The resources counted by the self check.
+const (
+	resGoroutines = "goroutines"
+	resFds        = "fds"
+	resHijacked   = "hijacked connections"
+)
+
+// selfCheck periodically counts the goroutines, the open fds and the
+// hijacked api connections of the daemon, to catch their leaks in long
+// running daemons before the host falls over. When a count goes over its
+// limit, a warning event is emitted and the stacks of the goroutines and the
+// open fds are dumped under the root of the daemon; the warning is emitted
+// again only once the count went back under the limit.
+type selfCheck struct {
+	daemon *Daemon
+	limits map[string]int
+	over   map[string]bool
+}
+
+func newSelfCheck(daemon *Daemon) *selfCheck {
+	config := daemon.config
+	limits := map[string]int{
+		resGoroutines: config.SelfCheckGoroutines,
+		resFds:        config.SelfCheckFds,
+		resHijacked:   config.SelfCheckHijacked,
+	}
+	if limits[resFds] == 0 {
+		var rlim syscall.Rlimit
+		if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlim); err == nil {
+			limits[resFds] = int(rlim.Cur * 8 / 10)
+		}
+	}
+	return &selfCheck{
+		daemon: daemon,
+		limits: limits,
+		over:   make(map[string]bool),
+	}
+}
+
+// run checks the counts every interval, for ever.
+func (s *selfCheck) run(interval time.Duration) {
+	for _ = range time.Tick(interval) {
+		counts := map[string]int{
+			resGoroutines: runtime.NumGoroutine(),
+			resFds:        utils.GetTotalUsedFds(),
+			resHijacked:   s.hijacked(),
+		}
+		exceeded := s.exceeded(counts)
+		if len(exceeded) == 0 {
+			continue
+		}
+		dump, err := s.dump(counts)
+		if err != nil {
+			log.Errorf("Error dumping the state of the daemon: %s", err)
+		}
+		for _, res := range exceeded {
+			log.Infof("Warning: %d %s, more than %d, dumped to %s", counts[res], res, s.limits[res], dump)
+			s.daemon.eng.Job("log", "warning", "daemon", fmt.Sprintf("%d %s", counts[res], res)).Run()
+		}
+	}
+}
+
+// exceeded returns the resources whose counts went over their limits since
+// the last check, and records which ones are over.
+func (s *selfCheck) exceeded(counts map[string]int) []string {
+	var exceeded []string
+	for res, count := range counts {
+		limit := s.limits[res]
+		if limit <= 0 || count < 0 {
+			continue
+		}
+		if count > limit {
+			if !s.over[res] {
+				exceeded = append(exceeded, res)
+			}
+			s.over[res] = true
+		} else {
+			s.over[res] = false
+		}
+	}
+	sort.Strings(exceeded)
+	return exceeded
+}
+
+// hijacked returns the number of hijacked api connections, -1 if they
+// cannot be listed.
+func (s *selfCheck) hijacked() int {
+	job := s.daemon.eng.Job("api_connections")
+	conns, err := job.Stdout.AddListTable()
+	if err != nil {
+		return -1
+	}
+	if err := job.Run(); err != nil {
+		return -1
+	}
+	n := 0
+	for _, conn := range conns.Data {
+		if conn.GetBool("Hijacked") {
+			n++
+		}
+	}
+	return n
+}
+
+// dump writes the counts, the stacks of the goroutines, the open fds and
+// the api connections to a new file under the root of the daemon, and
+// returns its path.
+func (s *selfCheck) dump(counts map[string]int) (string, error) {
+	dir := path.Join(s.daemon.config.Root, "dumps")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	p := path.Join(dir, fmt.Sprintf("selfcheck-%s.dump", time.Now().UTC().Format("20060102T150405Z")))
+	f, err := os.Create(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	for _, res := range []string{resGoroutines, resFds, resHijacked} {
+		fmt.Fprintf(f, "%s: %d (limit %d)\n", res, counts[res], s.limits[res])
+	}
+	fmt.Fprintf(f, "\n# goroutines\n")
+	if err := pprof.Lookup("goroutine").WriteTo(f, 2); err != nil {
+		return p, err
+	}
+	fmt.Fprintf(f, "\n# fds\n")
+	if err := dumpFds(f); err != nil {
+		return p, err
+	}
+	fmt.Fprintf(f, "\n# api connections\n")
+	job := s.daemon.eng.Job("api_connections")
+	job.Stdout.Add(f)
+	if err := job.Run(); err != nil {
+		fmt.Fprintf(f, "%s\n", err)
+	}
+	return p, nil
+}
+
+// dumpFds writes the open fds of the daemon, and what they are open on.
+func dumpFds(w io.Writer) error {
+	dir := fmt.Sprintf("/proc/%d/fd", os.Getpid())
+	fds, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, fd := range fds {
+		target, err := os.Readlink(filepath.Join(dir, fd.Name()))
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(w, "%s -> %s\n", fd.Name(), target)
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package daemon
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelfCheckExceeded(t *testing.T) {
+	s := &selfCheck{
+		limits: map[string]int{resGoroutines: 100, resFds: 10, resHijacked: 0},
+		over:   make(map[string]bool),
+	}
+	for _, c := range []struct {
+		counts   map[string]int
+		expected []string
+	}{
+		{map[string]int{resGoroutines: 50, resFds: 5, resHijacked: 1000}, nil},
+		{map[string]int{resGoroutines: 150, resFds: 20, resHijacked: 1000}, []string{resFds, resGoroutines}},
+		// still over, not warned again
+		{map[string]int{resGoroutines: 200, resFds: 20, resHijacked: 1000}, nil},
+		{map[string]int{resGoroutines: 80, resFds: 20, resHijacked: 1000}, nil},
+		{map[string]int{resGoroutines: 120, resFds: -1, resHijacked: 1000}, []string{resGoroutines}},
+	} {
+		if exceeded := s.exceeded(c.counts); !reflect.DeepEqual(exceeded, c.expected) {
+			t.Fatalf("Expected %v to exceed %v, got %v", c.counts, c.expected, exceeded)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/docker/docker/engine"
+	"github.com/docker/docker/opts"
 	"github.com/docker/docker/runconfig"
 )
 
@@ -22,6 +23,10 @@ func (daemon *Daemon) ContainerStart(job *engine.Job) engine.Status {
 		return job.Errorf("No such container: %s", name)
 	}
 
+	if err := daemon.checkNotDraining(); err != nil {
+		return job.Error(err)
+	}
+
 	if container.State.IsRunning() {
 		return job.Errorf("Container already started")
 	}
@@ -37,10 +42,60 @@ func (daemon *Daemon) ContainerStart(job *engine.Job) engine.Status {
 		return job.Errorf("Cannot start container %s: %s", name, err)
 	}
 
+	// report the host ports the published ports were bound to
+	container.Lock()
+	ports := container.NetworkSettings.PortMappingAPI()
+	container.Unlock()
+	ports.SetKey("PrivatePort")
+	ports.Sort()
+	if _, err := ports.WriteListTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+
 	return engine.StatusOK
 }
 
 func (daemon *Daemon) setHostConfig(container *Container, hostConfig *runconfig.HostConfig) error {
+	if !hostConfig.PidMode.Valid() {
+		return fmt.Errorf("Invalid PID mode: %s", hostConfig.PidMode)
+	}
+	if !hostConfig.IpcMode.Valid() {
+		return fmt.Errorf("Invalid IPC mode: %s", hostConfig.IpcMode)
+	}
+	if !hostConfig.UTSMode.Valid() {
+		return fmt.Errorf("Invalid UTS mode: %s", hostConfig.UTSMode)
+	}
+	for _, device := range hostConfig.Devices {
+		if !runconfig.ValidDevicePermissions(device.CgroupPermissions) {
+			return fmt.Errorf("Invalid permissions %s of the device %s", device.CgroupPermissions, device.PathOnHost)
+		}
+	}
+	if hostConfig.CgroupParent != "" && !runconfig.ValidCgroupParent(hostConfig.CgroupParent) {
+		return fmt.Errorf("Invalid cgroup parent %s, it must be relative to the cgroup of the daemon", hostConfig.CgroupParent)
+	}
+	if (len(hostConfig.PreStart) > 0 || len(hostConfig.PostStop) > 0) && !daemon.config.LifecycleHooks {
+		return fmt.Errorf("The pre-start and post-stop hooks run as root on the host, the daemon must run with --lifecycle-hooks to allow them")
+	}
+	if len(hostConfig.ServiceAliases) > 0 && !daemon.config.EmbeddedDns {
+		return fmt.Errorf("The service aliases are resolved by the embedded DNS, the daemon must run with --embedded-dns to allow them")
+	}
+	// the resolver compares the aliases with the names of the queries, in
+	// lower case
+	for i, alias := range hostConfig.ServiceAliases {
+		validated, err := opts.ValidateServiceAlias(alias)
+		if err != nil {
+			return fmt.Errorf("Invalid service alias: %s", err)
+		}
+		hostConfig.ServiceAliases[i] = validated
+	}
+	if _, _, err := daemon.seccompProfile(hostConfig); err != nil {
+		return err
+	}
+	if daemon.uidMaps != nil {
+		if err := checkRemappedRoot(hostConfig); err != nil {
+			return err
+		}
+	}
 	// Validate the HostConfig binds. Make sure that:
 	// the source exists
 	for _, bind := range hostConfig.Binds {
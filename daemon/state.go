@@ -11,14 +11,16 @@ import (
 
 type State struct {
 	sync.RWMutex
-	Running    bool
-	Paused     bool
-	Restarting bool
-	Pid        int
-	ExitCode   int
-	StartedAt  time.Time
-	FinishedAt time.Time
-	waitChan   chan struct{}
+	Running     bool
+	Paused      bool
+	Restarting  bool
+	Pid         int
+	ExitCode    int
+	StartedAt   time.Time
+	FinishedAt  time.Time
+	RestartedAt time.Time
+	OOMKilled   bool // the kernel killed a process of the container out of memory since it started
+	waitChan    chan struct{}
 }
 
 func NewState() *State {
@@ -132,10 +134,14 @@ func (s *State) GetExitCode() int {
 
 func (s *State) SetRunning(pid int) {
 	s.Lock()
+	if s.Restarting {
+		s.RestartedAt = time.Now().UTC()
+	}
 	s.Running = true
 	s.Paused = false
 	s.Restarting = false
 	s.ExitCode = 0
+	s.OOMKilled = false
 	s.Pid = pid
 	s.StartedAt = time.Now().UTC()
 	close(s.waitChan) // fire waiters for start
@@ -171,6 +177,14 @@ func (s *State) SetRestarting(exitCode int) {
 	s.Unlock()
 }
 
+// SetOOMKilled records that the kernel killed a process of the container
+// because the container reached its memory limit.
+func (s *State) SetOOMKilled() {
+	s.Lock()
+	s.OOMKilled = true
+	s.Unlock()
+}
+
 func (s *State) IsRestarting() bool {
 	s.RLock()
 	res := s.Restarting
@@ -100,3 +100,17 @@ func TestStateTimeoutWait(t *testing.T) {
 	}
 
 }
+
+func TestStateOOMKilled(t *testing.T) {
+	s := NewState()
+	s.SetRunning(100)
+	s.SetOOMKilled()
+	s.SetStopped(137)
+	if !s.OOMKilled {
+		t.Fatal("Expected the state to record the OOM kill once stopped")
+	}
+	s.SetRunning(101)
+	if s.OOMKilled {
+		t.Fatal("Expected the OOM kill to be forgotten when the container starts again")
+	}
+}
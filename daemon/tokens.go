@@ -0,0 +1,191 @@
+package daemon
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/utils"
+)
+
+// apiToken gives access to the API on the token sockets, limited to its
+// scopes. Only the hash of the token is kept.
+type apiToken struct {
+	Hash    string
+	Scopes  []string
+	Created time.Time
+}
+
+// tokenStore keeps the API tokens by name.
+type tokenStore struct {
+	path   string
+	Tokens map[string]*apiToken
+	sync.Mutex
+}
+
+func newTokenStore(path string) (*tokenStore, error) {
+	store := &tokenStore{
+		path:   path,
+		Tokens: make(map[string]*apiToken),
+	}
+	if err := store.reload(); os.IsNotExist(err) {
+		if err := store.save(); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (store *tokenStore) save() error {
+	jsonData, err := json.Marshal(store)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(store.path, jsonData, 0600)
+}
+
+func (store *tokenStore) reload() error {
+	jsonData, err := ioutil.ReadFile(store.path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(jsonData, store)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// create returns a new token called name with scopes.
+func (store *tokenStore) create(name string, scopes []string) (string, error) {
+	store.Lock()
+	defer store.Unlock()
+	if _, exists := store.Tokens[name]; exists {
+		return "", fmt.Errorf("Conflict: the API token %s already exists", name)
+	}
+	token := utils.GenerateRandomID()
+	store.Tokens[name] = &apiToken{
+		Hash:    hashToken(token),
+		Scopes:  scopes,
+		Created: time.Now().UTC(),
+	}
+	if err := store.save(); err != nil {
+		delete(store.Tokens, name)
+		return "", err
+	}
+	return token, nil
+}
+
+func (store *tokenStore) remove(name string) error {
+	store.Lock()
+	defer store.Unlock()
+	t, exists := store.Tokens[name]
+	if !exists {
+		return fmt.Errorf("No such API token: %s", name)
+	}
+	delete(store.Tokens, name)
+	if err := store.save(); err != nil {
+		store.Tokens[name] = t
+		return err
+	}
+	return nil
+}
+
+// scopes returns the scopes of token, and false when it is not valid.
+func (store *tokenStore) scopes(token string) ([]string, bool) {
+	store.Lock()
+	defer store.Unlock()
+	hash := hashToken(token)
+	for _, t := range store.Tokens {
+		if t.Hash == hash {
+			return t.Scopes, true
+		}
+	}
+	return nil, false
+}
+
+// TokenCreate creates an API token with the scopes of "Scopes" and writes
+// it, as it cannot be read again.
+func (daemon *Daemon) TokenCreate(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s NAME", job.Name)
+	}
+	scopes := job.GetenvList("Scopes")
+	if len(scopes) == 0 {
+		return job.Errorf("An API token needs at least one scope")
+	}
+	token, err := daemon.tokens.create(job.Args[0], scopes)
+	if err != nil {
+		return job.Error(err)
+	}
+	out := &engine.Env{}
+	out.Set("Name", job.Args[0])
+	out.Set("Token", token)
+	if _, err := out.WriteTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+// TokenList lists the API tokens, without the tokens themselves.
+func (daemon *Daemon) TokenList(job *engine.Job) engine.Status {
+	daemon.tokens.Lock()
+	names := make([]string, 0, len(daemon.tokens.Tokens))
+	for name := range daemon.tokens.Tokens {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	outs := engine.NewTable("", 0)
+	for _, name := range names {
+		t := daemon.tokens.Tokens[name]
+		out := &engine.Env{}
+		out.Set("Name", name)
+		out.SetList("Scopes", t.Scopes)
+		out.SetAuto("Created", t.Created)
+		outs.Add(out)
+	}
+	daemon.tokens.Unlock()
+	if _, err := outs.WriteListTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+// TokenRemove revokes an API token.
+func (daemon *Daemon) TokenRemove(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s NAME", job.Name)
+	}
+	if err := daemon.tokens.remove(job.Args[0]); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+// TokenScopes writes the scopes of a valid API token, for the API server to
+// check the requests made with it.
+func (daemon *Daemon) TokenScopes(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s TOKEN", job.Name)
+	}
+	scopes, valid := daemon.tokens.scopes(job.Args[0])
+	if !valid {
+		return job.Errorf("Invalid API token")
+	}
+	out := &engine.Env{}
+	out.SetList("Scopes", scopes)
+	if _, err := out.WriteTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
@@ -0,0 +1,54 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTokenStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-tokens-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "api-tokens.json")
+
+	store, err := newTokenStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := store.create("ci", []string{"read", "build"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.create("ci", []string{"read"}); err == nil {
+		t.Fatal("Expected an error creating a token twice")
+	}
+
+	// the tokens are kept across restarts, hashed
+	store, err = newTokenStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.Tokens["ci"].Hash == token {
+		t.Fatal("Expected the token not to be stored as is")
+	}
+	if scopes, valid := store.scopes(token); !valid || len(scopes) != 2 || scopes[1] != "build" {
+		t.Fatalf("Expected the scopes read and build, got %v %v", scopes, valid)
+	}
+	if _, valid := store.scopes("wrong"); valid {
+		t.Fatal("Expected an unknown token to be invalid")
+	}
+
+	if err := store.remove("ci"); err != nil {
+		t.Fatal(err)
+	}
+	if _, valid := store.scopes(token); valid {
+		t.Fatal("Expected a revoked token to be invalid")
+	}
+	if err := store.remove("ci"); err == nil {
+		t.Fatal("Expected an error revoking a missing token")
+	}
+}
@@ -0,0 +1,85 @@
+package daemon
+
+import (
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/pkg/log"
+)
+
+// ContainerUpdate changes the memory limit, cpu shares and cpuset of a
+// container. The cgroups of a running container are rewritten in place,
+// and the new values are saved so they also apply to the next starts.
+func (daemon *Daemon) ContainerUpdate(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s CONTAINER\n", job.Name)
+	}
+	name := job.Args[0]
+	container := daemon.Get(name)
+	if container == nil {
+		return job.Errorf("No such container: %s\n", name)
+	}
+
+	var (
+		memory     = container.Config.Memory
+		memorySwap = container.Config.MemorySwap
+		cpuShares  = container.Config.CpuShares
+		cpuset     = container.Config.Cpuset
+	)
+	if job.EnvExists("Memory") {
+		memory = job.GetenvInt64("Memory")
+		if memory < 524288 {
+			return job.Errorf("Minimum memory limit allowed is 512k")
+		}
+		if !daemon.SystemConfig().MemoryLimit {
+			return job.Errorf("Your kernel does not support memory limit capabilities")
+		}
+		if !daemon.SystemConfig().SwapLimit {
+			memorySwap = -1
+		}
+		if memorySwap > 0 && memorySwap < memory {
+			return job.Errorf("Memory limit should be smaller than the memory swap limit %d", memorySwap)
+		}
+	}
+	if job.EnvExists("CpuShares") {
+		if cpuShares = job.GetenvInt64("CpuShares"); cpuShares <= 0 {
+			return job.Errorf("Invalid CPU shares: %d", cpuShares)
+		}
+	}
+	if job.EnvExists("Cpuset") {
+		if cpuset = job.Getenv("Cpuset"); cpuset == "" {
+			return job.Errorf("Invalid empty cpuset")
+		}
+	}
+
+	container.Lock()
+	defer container.Unlock()
+
+	if container.State.IsRunning() {
+		resources := *container.command.Resources
+		resources.Memory = memory
+		resources.MemorySwap = memorySwap
+		resources.CpuShares = cpuShares
+		resources.Cpuset = cpuset
+
+		previous := container.command.Resources
+		container.command.Resources = &resources
+		if err := daemon.execDriver.Update(container.command); err != nil {
+			container.command.Resources = previous
+			return job.Errorf("Cannot update container %s: %s", name, err)
+		}
+	}
+
+	container.Config.Memory = memory
+	container.Config.MemorySwap = memorySwap
+	container.Config.CpuShares = cpuShares
+	container.Config.Cpuset = cpuset
+	if err := container.toDisk(); err != nil {
+		return job.Error(err)
+	}
+	if container.MetadataPath != "" {
+		if err := container.writeMetadataFile(); err != nil {
+			log.Errorf("Error updating the metadata of %s: %s", container.ID, err)
+		}
+	}
+	container.LogEvent("update")
+	return engine.StatusOK
+}
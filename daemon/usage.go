@@ -0,0 +1,104 @@
+package daemon
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/pkg/log"
+)
+
+// usageCounters counts the use of the daemon for capacity planning. The
+// counters are kept in a json file under the root of the daemon, so they
+// survive restarts; nothing is sent anywhere.
+type usageCounters struct {
+	sync.Mutex
+	path string
+
+	Since             time.Time
+	ContainersStarted int64
+	ImagesPulled      int64
+	BuildSeconds      int64
+}
+
+func newUsageCounters(path string) (*usageCounters, error) {
+	usage := &usageCounters{
+		path:  path,
+		Since: time.Now().UTC(),
+	}
+	// Load the json file if it exists, otherwise create it.
+	if err := usage.reload(); os.IsNotExist(err) {
+		if err := usage.save(); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+func (usage *usageCounters) save() error {
+	jsonData, err := json.Marshal(usage)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(usage.path, jsonData, 0600)
+}
+
+func (usage *usageCounters) reload() error {
+	jsonData, err := ioutil.ReadFile(usage.path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(jsonData, usage)
+}
+
+// add adds n to counter and saves the counters. Failing to save only loses
+// the count, so the error is logged rather than returned.
+func (usage *usageCounters) add(counter *int64, n int64) {
+	usage.Lock()
+	defer usage.Unlock()
+	*counter += n
+	if err := usage.save(); err != nil {
+		log.Errorf("Error saving the usage counters to %s: %s", usage.path, err)
+	}
+}
+
+func (usage *usageCounters) containerStarted() {
+	if usage != nil {
+		usage.add(&usage.ContainersStarted, 1)
+	}
+}
+
+func (usage *usageCounters) imagePulled() {
+	if usage != nil {
+		usage.add(&usage.ImagesPulled, 1)
+	}
+}
+
+func (usage *usageCounters) buildDone(d time.Duration) {
+	if usage != nil {
+		usage.add(&usage.BuildSeconds, int64(d/time.Second))
+	}
+}
+
+// usageInfo is the usage reported by the info job.
+type usageInfo struct {
+	Since             string
+	ContainersStarted int64
+	ImagesPulled      int64
+	BuildMinutes      int64
+}
+
+func (usage *usageCounters) info() *usageInfo {
+	usage.Lock()
+	defer usage.Unlock()
+	return &usageInfo{
+		Since:             usage.Since.Format(time.RFC3339),
+		ContainersStarted: usage.ContainersStarted,
+		ImagesPulled:      usage.ImagesPulled,
+		BuildMinutes:      usage.BuildSeconds / 60,
+	}
+}
@@ -0,0 +1,47 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUsageCounters(t *testing.T) {
+	root, err := ioutil.TempDir("", "docker-usage-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	path := filepath.Join(root, "usage.json")
+	usage, err := newUsageCounters(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usage.containerStarted()
+	usage.containerStarted()
+	usage.imagePulled()
+	usage.buildDone(90 * time.Second)
+	usage.buildDone(45 * time.Second)
+
+	// the counters survive a restart
+	reloaded, err := newUsageCounters(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := reloaded.info()
+	if info.ContainersStarted != 2 || info.ImagesPulled != 1 || info.BuildMinutes != 2 {
+		t.Fatalf("Unexpected usage %+v", info)
+	}
+	if !reloaded.Since.Equal(usage.Since) {
+		t.Fatalf("Expected the counters to start at %s, got %s", usage.Since, reloaded.Since)
+	}
+
+	// a daemon without counters, as in the tests, counts nothing
+	var none *usageCounters
+	none.containerStarted()
+	none.imagePulled()
+	none.buildDone(time.Minute)
+}
@@ -0,0 +1,108 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/docker/docker/archive"
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/docker/docker/runconfig"
+	"github.com/docker/docker/utils"
+)
+
+// setupRemappedRoot reads the ID mappings of the --userns-remap USER[:GROUP]
+// and moves the root of the daemon to a directory of the mapping, owned by
+// the user of the host the root of the containers is mapped to. The layers
+// of each mapping are owned by different IDs, so they cannot be shared.
+func setupRemappedRoot(config *Config) ([]idtools.IDMap, []idtools.IDMap, error) {
+	if config.RemappedRoot == "" {
+		return nil, nil, nil
+	}
+	if config.ExecDriver != "native" {
+		return nil, nil, fmt.Errorf("The %s driver does not run the containers in a user namespace, use the native driver with --userns-remap", config.ExecDriver)
+	}
+	parts := strings.SplitN(config.RemappedRoot, ":", 2)
+	username, groupname := parts[0], parts[0]
+	if len(parts) == 2 {
+		groupname = parts[1]
+	}
+	uidMaps, gidMaps, err := idtools.NewIDMappings(username, groupname)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Invalid --userns-remap %s: %s", config.RemappedRoot, err)
+	}
+	uid, gid, err := idtools.RootPair(uidMaps, gidMaps)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the root of the containers only goes through the original root
+	if err := os.Chmod(config.Root, 0701); err != nil {
+		return nil, nil, err
+	}
+	config.Root = path.Join(config.Root, fmt.Sprintf("%d.%d", uid, gid))
+	if err := os.MkdirAll(config.Root, 0700); err != nil {
+		return nil, nil, err
+	}
+	if err := os.Chown(config.Root, uid, gid); err != nil {
+		return nil, nil, err
+	}
+	return uidMaps, gidMaps, nil
+}
+
+// checkRemappedRoot rejects the settings which would give the root of a
+// container running in a user namespace the privileges of the host, or
+// share a namespace owned by the user namespace of the host.
+func checkRemappedRoot(hostConfig *runconfig.HostConfig) error {
+	if hostConfig.Privileged {
+		return fmt.Errorf("Cannot run a privileged container with --userns-remap")
+	}
+	switch {
+	case hostConfig.NetworkMode.IsHost(), hostConfig.NetworkMode.IsContainer():
+		return fmt.Errorf("Cannot share the network namespace of %s with --userns-remap", hostConfig.NetworkMode)
+	case hostConfig.PidMode.IsHost(), hostConfig.PidMode.IsContainer():
+		return fmt.Errorf("Cannot share the PID namespace of %s with --userns-remap", hostConfig.PidMode)
+	case hostConfig.IpcMode.IsHost(), hostConfig.IpcMode.IsContainer():
+		return fmt.Errorf("Cannot share the IPC namespace of %s with --userns-remap", hostConfig.IpcMode)
+	case hostConfig.UTSMode.IsHost():
+		return fmt.Errorf("Cannot share the UTS namespace of the host with --userns-remap")
+	}
+	return nil
+}
+
+// chownRemappedPath gives the directories between root and path owned by
+// the root of the host to the root of the containers, when it is remapped,
+// for it to reach path.
+func (daemon *Daemon) chownRemappedPath(root, path string) error {
+	if daemon.uidMaps == nil {
+		return nil
+	}
+	uid, gid, err := idtools.RootPair(daemon.uidMaps, daemon.gidMaps)
+	if err != nil {
+		return err
+	}
+	return idtools.ChownPath(root, path, uid, gid)
+}
+
+// chownToRemappedRoot lets the root of the container reach its rootfs and
+// the files of its directory bind mounted in it.
+func (container *Container) chownToRemappedRoot() error {
+	if err := container.daemon.chownRemappedPath(container.daemon.config.Root, container.basefs); err != nil {
+		return err
+	}
+	return container.daemon.chownRemappedPath(container.daemon.config.Root, container.root)
+}
+
+// toContainerIDs maps the owners of the files of an archive of the host to
+// the IDs of the containers, when their root is remapped.
+func (daemon *Daemon) toContainerIDs(arch archive.Archive) archive.Archive {
+	if daemon.uidMaps == nil {
+		return arch
+	}
+	remapped := archive.ToContainerIDs(arch, daemon.uidMaps, daemon.gidMaps)
+	return utils.NewReadCloserWrapper(remapped, func() error {
+		remapped.Close()
+		return arch.Close()
+	})
+}
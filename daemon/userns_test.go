@@ -0,0 +1,27 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/docker/docker/runconfig"
+)
+
+func TestCheckRemappedRoot(t *testing.T) {
+	if err := checkRemappedRoot(&runconfig.HostConfig{NetworkMode: "bridge"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, hostConfig := range []*runconfig.HostConfig{
+		{Privileged: true},
+		{NetworkMode: "host"},
+		{NetworkMode: "container:db"},
+		{PidMode: "host"},
+		{PidMode: "container:db"},
+		{IpcMode: "host"},
+		{IpcMode: "container:db"},
+		{UTSMode: "host"},
+	} {
+		if err := checkRemappedRoot(hostConfig); err == nil {
+			t.Fatalf("Expected %+v to be rejected with --userns-remap", hostConfig)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package volumedriver
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/docker/docker/pkg/plugins"
+)
+
+var (
+	ErrNotFound = errors.New("volume driver not found")
+
+	drivers = make(map[string]Driver)
+	lock    sync.Mutex
+)
+
+// Driver manages named volumes stored outside of the daemon root.
+type Driver interface {
+	Name() string
+
+	// Create makes sure the volume exists, creating it if needed
+	Create(name string) error
+	Remove(name string) error
+
+	// Path returns the host path of a mounted volume
+	Path(name string) (string, error)
+	// Mount makes the volume available on the host and returns its path.
+	// Each call to Mount is balanced by a call to Unmount.
+	Mount(name string) (string, error)
+	Unmount(name string) error
+}
+
+// Register makes a driver available under its name.
+func Register(d Driver) error {
+	lock.Lock()
+	defer lock.Unlock()
+	if _, exists := drivers[d.Name()]; exists {
+		return fmt.Errorf("Name already registered %s", d.Name())
+	}
+	drivers[d.Name()] = d
+	return nil
+}
+
+// GetDriver returns the driver registered under name. When there is none,
+// the plugin called name implementing VolumeDriver is looked for.
+func GetDriver(name string) (Driver, error) {
+	lock.Lock()
+	defer lock.Unlock()
+	if d, exists := drivers[name]; exists {
+		return d, nil
+	}
+	if _, err := plugins.Get(name, "VolumeDriver"); err == plugins.ErrNotFound {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	d := &plugin{name: name}
+	drivers[name] = d
+	return d, nil
+}
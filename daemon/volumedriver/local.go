@@ -0,0 +1,51 @@
+package volumedriver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LocalDriverName is the driver used for named volumes when none is given.
+const LocalDriverName = "local"
+
+// localDriver stores each volume in a directory under root.
+type localDriver struct {
+	root string
+}
+
+// NewLocalDriver returns a driver keeping its volumes under root.
+func NewLocalDriver(root string) (Driver, error) {
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return nil, err
+	}
+	return &localDriver{root: root}, nil
+}
+
+func (d *localDriver) Name() string {
+	return LocalDriverName
+}
+
+func (d *localDriver) Create(name string) error {
+	return os.MkdirAll(filepath.Join(d.root, name), 0755)
+}
+
+func (d *localDriver) Remove(name string) error {
+	return os.RemoveAll(filepath.Join(d.root, name))
+}
+
+func (d *localDriver) Path(name string) (string, error) {
+	return filepath.Join(d.root, name), nil
+}
+
+func (d *localDriver) Mount(name string) (string, error) {
+	p := filepath.Join(d.root, name)
+	if _, err := os.Stat(p); err != nil {
+		return "", fmt.Errorf("Volume %s does not exist: %s", name, err)
+	}
+	return p, nil
+}
+
+func (d *localDriver) Unmount(name string) error {
+	return nil
+}
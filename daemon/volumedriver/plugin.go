@@ -0,0 +1,77 @@
+package volumedriver
+
+import (
+	"fmt"
+
+	"github.com/docker/docker/pkg/plugins"
+)
+
+type pluginRequest struct {
+	Name string
+}
+
+type pluginResponse struct {
+	Mountpoint string
+	Err        string
+}
+
+// plugin forwards the calls of the Driver interface to a plugin implementing
+// VolumeDriver. Each method is a call to VolumeDriver.<Method> with a
+// {"Name": ...} body, answered with {"Mountpoint": ..., "Err": ...}.
+type plugin struct {
+	name string
+}
+
+func (p *plugin) call(method, name string) (*pluginResponse, error) {
+	// the plugin is activated again if it failed since the last call
+	pl, err := plugins.Get(p.name, "VolumeDriver")
+	if err != nil {
+		return nil, fmt.Errorf("Volume driver %s: %s", p.name, err)
+	}
+	var out pluginResponse
+	if err := pl.Call("VolumeDriver."+method, &pluginRequest{Name: name}, &out); err != nil {
+		return nil, fmt.Errorf("Volume driver %s: %s", p.name, err)
+	}
+	if out.Err != "" {
+		return nil, fmt.Errorf("Volume driver %s: %s", p.name, out.Err)
+	}
+	return &out, nil
+}
+
+func (p *plugin) Name() string {
+	return p.name
+}
+
+func (p *plugin) Create(name string) error {
+	_, err := p.call("Create", name)
+	return err
+}
+
+func (p *plugin) Remove(name string) error {
+	_, err := p.call("Remove", name)
+	return err
+}
+
+func (p *plugin) Path(name string) (string, error) {
+	resp, err := p.call("Path", name)
+	if err != nil {
+		return "", err
+	}
+	return resp.Mountpoint, nil
+}
+
+func (p *plugin) Mount(name string) (string, error) {
+	resp, err := p.call("Mount", name)
+	if err != nil {
+		return "", err
+	}
+	if resp.Mountpoint == "" {
+		return "", fmt.Errorf("Volume driver %s returned no mountpoint for %s", p.name, name)
+	}
+	return resp.Mountpoint, nil
+}
+
+func (p *plugin) Unmount(name string) error {
+	_, err := p.call("Unmount", name)
+	return err
+}
@@ -0,0 +1,66 @@
+package volumedriver
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/pkg/plugins"
+)
+
+func TestPluginDriver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-volumedriver-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	l, err := net.Listen("unix", filepath.Join(dir, "fake.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/VolumeDriver.Mount", func(w http.ResponseWriter, r *http.Request) {
+		var req pluginRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		json.NewEncoder(w).Encode(&pluginResponse{Mountpoint: "/mnt/" + req.Name})
+	})
+	mux.HandleFunc("/VolumeDriver.Remove", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&pluginResponse{Err: "volume is in use"})
+	})
+	go http.Serve(l, mux)
+
+	plugins.SpecsPaths = []string{dir}
+	if _, err := GetDriver("missing"); err != ErrNotFound {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+	d, err := GetDriver("fake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Name() != "fake" {
+		t.Fatalf("Expected driver fake, got %s", d.Name())
+	}
+
+	path, err := d.Mount("data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "/mnt/data" {
+		t.Fatalf("Expected /mnt/data, got %s", path)
+	}
+	if err := d.Remove("data"); err == nil {
+		t.Fatal("Expected the error returned by the plugin")
+	}
+	if err := d.Create("data"); err == nil {
+		t.Fatal("Expected an error for a method the plugin does not implement")
+	}
+}
@@ -5,18 +5,23 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"syscall"
 
 	"github.com/docker/docker/archive"
 	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/docker/pkg/log"
 	"github.com/docker/docker/pkg/symlink"
 )
 
+var validVolumeNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
 type Volume struct {
 	HostPath    string
 	VolPath     string
 	Mode        string
+	Name        string // set for volumes provided by a volume driver
 	isBindMount bool
 }
 
@@ -48,6 +53,10 @@ func prepareVolumesForContainer(container *Container) error {
 	return nil
 }
 
+// localtimePath is the time zone of the host, mounted in the containers
+// with --host-timezone
+const localtimePath = "/etc/localtime"
+
 func setupMountsForContainer(container *Container) error {
 	mounts := []execdriver.Mount{
 		{container.ResolvConfPath, "/etc/resolv.conf", true, true},
@@ -61,6 +70,24 @@ func setupMountsForContainer(container *Container) error {
 		mounts = append(mounts, execdriver.Mount{container.HostsPath, "/etc/hosts", true, true})
 	}
 
+	if container.MetadataPath != "" {
+		mounts = append(mounts, execdriver.Mount{container.MetadataPath, metadataMountPath, false, true})
+	}
+
+	if container.daemon.uidMaps != nil {
+		for _, device := range container.hostConfig.Devices {
+			mounts = append(mounts, execdriver.Mount{device.PathOnHost, device.PathInContainer, strings.Contains(device.CgroupPermissions, "w"), true})
+		}
+	}
+
+	if container.daemon.config.HostTimezone {
+		if _, exists := container.Volumes[localtimePath]; !exists {
+			if _, err := os.Stat(localtimePath); err == nil {
+				mounts = append(mounts, execdriver.Mount{localtimePath, localtimePath, false, true})
+			}
+		}
+	}
+
 	// Mount user specified volumes
 	// Note, these are not private because you may want propagation of (un)mounts from host
 	// volumes. For instance if you use -v /usr:/usr and the host later mounts /usr/share you
@@ -159,6 +186,11 @@ func parseBindVolumeSpec(spec string) (Volume, error) {
 		return vol, fmt.Errorf("Invalid volume specification: %s", spec)
 	}
 
+	if vol.HostPath != "" && validVolumeNamePattern.MatchString(vol.HostPath) {
+		vol.Name, vol.HostPath = vol.HostPath, ""
+		return vol, nil
+	}
+
 	if !filepath.IsAbs(vol.HostPath) {
 		return vol, fmt.Errorf("cannot bind mount volume: %s volume paths must be absolute.", vol.HostPath)
 	}
@@ -241,6 +273,22 @@ func (v *Volume) initialize(container *Container) error {
 	var err error
 	v.VolPath = filepath.Clean(v.VolPath)
 
+	// Named volumes are mounted on each start as the driver may hand out a
+	// different path every time
+	if v.Name != "" {
+		if v.HostPath, err = mountNamedVolume(container, v.Name); err != nil {
+			return err
+		}
+		if container.NamedVolumes == nil {
+			container.NamedVolumes = make(map[string]string)
+		}
+		container.NamedVolumes[v.VolPath] = v.Name
+		if _, exists := container.Volumes[v.VolPath]; exists {
+			container.Volumes[v.VolPath] = v.HostPath
+			return nil
+		}
+	}
+
 	// Do not initialize an existing volume
 	if _, exists := container.Volumes[v.VolPath]; exists {
 		return nil
@@ -278,6 +326,14 @@ func (v *Volume) initialize(container *Container) error {
 	if err := createIfNotExists(fullVolPath, volIsDir); err != nil {
 		return err
 	}
+	if err := container.daemon.chownRemappedPath(container.basefs, fullVolPath); err != nil {
+		return err
+	}
+	if !v.isBindMount {
+		if err := container.daemon.chownRemappedPath(container.daemon.config.Root, hostPath); err != nil {
+			return err
+		}
+	}
 
 	// Do not copy or change permissions if we are mounting from the host
 	if v.isRw() && !v.isBindMount {
@@ -286,6 +342,32 @@ func (v *Volume) initialize(container *Container) error {
 	return nil
 }
 
+func mountNamedVolume(container *Container, name string) (string, error) {
+	driver, err := container.daemon.namedVolumes.Create(name, container.hostConfig.VolumeDriver)
+	if err != nil {
+		return "", err
+	}
+	return driver.Mount(name)
+}
+
+// unmountNamedVolumes tells the volume driver the container does not use
+// its volumes anymore.
+func unmountNamedVolumes(container *Container) {
+	if len(container.NamedVolumes) == 0 {
+		return
+	}
+	driver, err := container.daemon.namedVolumes.driver(container.hostConfig.VolumeDriver)
+	if err != nil {
+		log.Errorf("%s", err)
+		return
+	}
+	for _, name := range container.NamedVolumes {
+		if err := driver.Unmount(name); err != nil {
+			log.Errorf("Error unmounting volume %s of %s: %s", name, container.ID, err)
+		}
+	}
+}
+
 func createIfNotExists(destination string, isDir bool) error {
 	if _, err := os.Stat(destination); err == nil || !os.IsNotExist(err) {
 		return nil
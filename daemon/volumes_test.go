@@ -0,0 +1,27 @@
+package daemon
+
+import (
+	"testing"
+)
+
+func TestParseBindVolumeSpec(t *testing.T) {
+	vol, err := parseBindVolumeSpec("/host:/container:ro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vol.HostPath != "/host" || vol.VolPath != "/container" || vol.Mode != "ro" || vol.Name != "" {
+		t.Fatalf("Unexpected volume %v", vol)
+	}
+
+	vol, err = parseBindVolumeSpec("dbdata:/container")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vol.Name != "dbdata" || vol.HostPath != "" || vol.VolPath != "/container" {
+		t.Fatalf("Expected a named volume, got %v", vol)
+	}
+
+	if _, err := parseBindVolumeSpec("../host:/container"); err == nil {
+		t.Fatal("Expected an error for a relative host path")
+	}
+}
@@ -93,6 +93,8 @@ func mainDaemon() {
 	job.Setenv("TlsCert", *flCert)
 	job.Setenv("TlsKey", *flKey)
 	job.SetenvBool("BufferRequests", true)
+	job.Setenv("IdleTimeout", flIdleTimeout.String())
+	job.Setenv("TokenSocket", *flTokenSocket)
 	// 运行job
 	if err := job.Run(); err != nil {
 		log.Fatal(err)
@@ -7,7 +7,9 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"runtime"
 	"strings"
+	"time"
 
 	"github.com/docker/docker/api"
 	"github.com/docker/docker/api/client"
@@ -48,12 +50,27 @@ func main() {
 		os.Setenv("DEBUG", "1")
 	}
 
+	// the defaults of the client options come from ~/.docker/config.json,
+	// whose errors NewDockerCli reports
+	clientConfig := &client.ClientConfig{}
+	if !*flDaemon {
+		clientConfig, _ = client.LoadClientConfig(client.ConfigDir())
+		set := setFlags()
+		applyClientConfig(clientConfig, set)
+		if err := applyTimeoutEnv(set); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	// ftHosts的作用是为 Docker Client 提供所要连接的host对象，也就是为 Docker Server 提供所要监昕的对象。
 	if len(flHosts) == 0 {
 		// 如果长度是0,说明用户没有传入地址
 
 		// 从环境变量只能中提取DOCKER_HOST参数赋值
 		defaultHost := os.Getenv("DOCKER_HOST")
+		if defaultHost == "" && !*flDaemon {
+			defaultHost = clientConfig.Host
+		}
 
 		if defaultHost == "" || *flDaemon {
 			// If we do not have a host, default to unix socket
@@ -62,6 +79,9 @@ func main() {
 			// 该常量位于docker/api/common.go ，值为 "/var/run/docker.sock" ，故 defaultHost 为 "unix:///var/runldocker.sock" 。
 			// DEFAULTUNIXSOCKET = "/var/run/docker.sock"
 			defaultHost = fmt.Sprintf("unix://%s", api.DEFAULTUNIXSOCKET)
+			if runtime.GOOS == "windows" && !*flDaemon {
+				defaultHost = fmt.Sprintf("npipe://%s", api.DEFAULTNAMEDPIPE)
+			}
 		}
 		// 验证该 defaultHost 的合法性之后，将 defaultHost 的值追加至 flHost 的末尾， 继续往下执行。
 		if _, err := api.ValidateHost(defaultHost); err != nil {
@@ -137,6 +157,10 @@ func main() {
 		// 实例化 type DockerCli struct 对象
 		cli = client.NewDockerCli(os.Stdin, os.Stdout, os.Stderr, protoAddrParts[0], protoAddrParts[1], nil)
 	}
+	if err := cli.SetProgress(*flProgress); err != nil {
+		log.Fatal(err)
+	}
+	cli.SetTimeouts(*flConnectTimeout, *flResponseTimeout)
 
 	// 使用 Docker Client实例句柄 执行相应的命令
 	// func Args() []string { return CommandLine.args }
@@ -155,6 +179,66 @@ func main() {
 	}
 }
 
+// setFlags returns the names of the flags given on the command line.
+func setFlags() map[string]bool {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		for _, name := range f.Names {
+			set[name] = true
+		}
+	})
+	return set
+}
+
+// applyClientConfig sets the client flags which are not in set, the flags
+// given on the command line, to their value in config, if any.
+func applyClientConfig(config *client.ClientConfig, set map[string]bool) {
+	if config.TLS && !set["-tls"] {
+		*flTls = true
+	}
+	if config.TLSVerify && !set["-tlsverify"] {
+		*flTlsVerify = true
+	}
+	for _, f := range []struct {
+		name  string
+		flag  *string
+		value string
+	}{
+		{"-tlscacert", flCa, config.TLSCACert},
+		{"-tlscert", flCert, config.TLSCert},
+		{"-tlskey", flKey, config.TLSKey},
+		{"-progress", flProgress, config.Progress},
+	} {
+		if f.value != "" && !set[f.name] {
+			*f.flag = f.value
+		}
+	}
+}
+
+// applyTimeoutEnv sets the timeout flags which are not in set to the
+// duration in their environment variable, if any.
+func applyTimeoutEnv(set map[string]bool) error {
+	for _, t := range []struct {
+		name string
+		env  string
+		flag *time.Duration
+	}{
+		{"-connect-timeout", "DOCKER_CONNECT_TIMEOUT", flConnectTimeout},
+		{"-response-timeout", "DOCKER_RESPONSE_TIMEOUT", flResponseTimeout},
+	} {
+		value := os.Getenv(t.env)
+		if value == "" || set[t.name] {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("Invalid %s: %s", t.env, err)
+		}
+		*t.flag = d
+	}
+	return nil
+}
+
 func showVersion() {
 	fmt.Printf("Docker version %s, build %s\n", dockerversion.VERSION, dockerversion.GITCOMMIT)
 }
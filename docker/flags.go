@@ -27,9 +27,14 @@ var (
 	flDebug       = flag.Bool([]string{"D", "-debug"}, false, "Enable debug mode")
 	flSocketGroup = flag.String([]string{"G", "-group"}, "docker", `Group to assign the unix socket specified by -H when running in daemon mode
 use '' (the empty string) to disable setting of a group`)
-	flEnableCors  = flag.Bool([]string{"#api-enable-cors", "-api-enable-cors"}, false, "Enable CORS headers in the remote API")
-	flTls         = flag.Bool([]string{"-tls"}, false, "Use TLS; implied by tls-verify flags")
-	flTlsVerify   = flag.Bool([]string{"-tlsverify"}, false, "Use TLS and verify the remote (daemon: verify client, client: verify daemon)")
+	flEnableCors      = flag.Bool([]string{"#api-enable-cors", "-api-enable-cors"}, false, "Enable CORS headers in the remote API")
+	flTls             = flag.Bool([]string{"-tls"}, false, "Use TLS; implied by tls-verify flags")
+	flTlsVerify       = flag.Bool([]string{"-tlsverify"}, false, "Use TLS and verify the remote (daemon: verify client, client: verify daemon)")
+	flIdleTimeout     = flag.Duration([]string{"-api-idle-timeout"}, 0, "Close the hijacked connections to the remote API, e.g. of attach, idle for longer than this duration\n0 never closes them")
+	flTokenSocket     = flag.String([]string{"-api-token-socket"}, "", "Also serve the remote API on this unix socket, to the clients with an API token only\nthe socket can be mounted in containers, see docker token")
+	flConnectTimeout  = flag.Duration([]string{"-connect-timeout"}, 0, "Give up connecting to the daemon after this duration, e.g. 10s; default to $DOCKER_CONNECT_TIMEOUT\n0 waits as long as the system does")
+	flResponseTimeout = flag.Duration([]string{"-response-timeout"}, 0, "Give up waiting for the daemon to answer a request after this duration, e.g. 1m; default to $DOCKER_RESPONSE_TIMEOUT\nthe streams, e.g. of attach, logs -f and events, and the waits for containers to stop are not limited\n0 waits forever")
+	flProgress        = flag.String([]string{"-progress"}, "auto", "Progress output of the client: auto, plain or tty\nauto draws progress bars only when the output is a terminal")
 
 	// these are initialized in init() below since their default values depend on dockerCertPath which isn't fully initialized until init() runs
 	// 先实例化，但是没有赋有效值，默认是类型零值，直到init()中赋值
@@ -9,6 +9,8 @@ var (
 	GITCOMMIT string // 通过编译参数注入值
 	VERSION   string // 通过编译参数注入值
 
+	EXPERIMENTAL string // "true" when Docker was compiled with DOCKER_EXPERIMENTAL set
+
 	IAMSTATIC bool   // whether or not Docker itself was compiled statically via ./hack/make.sh binary
 	INITSHA1  string // sha1sum of separate static dockerinit, if Docker itself was compiled dynamically via ./hack/make.sh dynbinary
 	INITPATH  string // custom location to search for a valid dockerinit binary (available for packagers as a last resort escape hatch)
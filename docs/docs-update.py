@@ -79,29 +79,25 @@ def update_cli_reference():
         print_usage(outtext, docker_cmd, command)
 
 def update_man_pages():
-    cmds = []
-    try:
-        help = subprocess.check_output("".join((docker_cmd)), stderr=subprocess.STDOUT, shell=True)
-    except subprocess.CalledProcessError, e:
-        help = e.output
-    for l in str(help).strip().split("\n"):
-        l = l.rstrip()
-        if l != "":
-            match = re.match("    (.*?) .*", l)
-            if match:
-                cmds.append(match.group(1))
+    # the client writes the man pages of its commands from their help, with
+    # their flags and their examples; the hand written descriptions, examples
+    # and history of the existing pages are kept, the examples of the help
+    # are only used for the pages without any
+    cmds = subprocess.check_output("".join((docker_cmd, " help --man")), shell=True)
 
     desc_re = re.compile(r".*# DESCRIPTION(.*?)# (OPTIONS|EXAMPLES?).*", re.MULTILINE|re.DOTALL)
     example_re = re.compile(r".*# EXAMPLES?(.*)# HISTORY.*", re.MULTILINE|re.DOTALL)
+    generated_example_re = re.compile(r"(.*?)# EXAMPLES?\n.*", re.MULTILINE|re.DOTALL)
     history_re = re.compile(r".*# HISTORY(.*)", re.MULTILINE|re.DOTALL)
 
-    for command in cmds:
+    for command in str(cmds).strip().split("\n"):
         print "COMMAND: "+command
+        page = "docs/man/docker-"+command.replace(" ", "-")+".1.md"
         history = ""
         description = ""
         examples = ""
-        if os.path.isfile("docs/man/docker-"+command+".1.md"):
-            intext = open("docs/man/docker-"+command+".1.md", "r")
+        if os.path.isfile(page):
+            intext = open(page, "r")
             txt = intext.read()
             intext.close()
             match = desc_re.match(txt)
@@ -113,98 +109,20 @@ def update_man_pages():
             match = history_re.match(txt)
             if match:
                 history = match.group(1).strip()
-        
-        usage = ""
-        usage_description = ""
-        params = {}
-        key_params = {}
-        
-        help = ""
-        try:
-            help = subprocess.check_output("".join((docker_cmd, " ", command, " --help")), stderr=subprocess.STDOUT, shell=True)
-        except subprocess.CalledProcessError, e:
-            help = e.output
-        last_key = ""
-        for l in str(help).split("\n"):
-            l = l.rstrip()
-            if l != "":
-                match = re.match("Usage: docker "+command+"(.*)", l)
-                if match:
-                    usage = match.group(1).strip()
-                else:
-                    #print ">>>>"+l
-                    match = re.match("  (-+)(.*) \s+(.*)", l)
-                    if match:
-                        last_key = match.group(2).rstrip()
-                        #print "    found "+match.group(1)
-                        key_params[last_key] = match.group(1)+last_key
-                        params[last_key] = match.group(3)
-                    else:
-                        if last_key != "":
-                            params[last_key] = params[last_key] + "\n" + l
-                        else:
-                            if usage_description != "":
-                                usage_description = usage_description + "\n"
-                            usage_description = usage_description + l
-        
-        # replace [OPTIONS] with the list of params     
-        options = ""
-        match = re.match("\[OPTIONS\](.*)", usage)
-        if match:
-            usage = match.group(1)
 
-        new_usage = ""
-        # TODO: sort without the `-`'s
-        for key in sorted(params.keys(), key=lambda s: s.lower()):
-            # split on commas, remove --?.*=.*, put in *'s mumble
-            ps = []
-            opts = []
-            for k in key_params[key].split(","):
-                #print "......"+k
-                match = re.match("(-+)([A-Za-z-0-9]*)(?:=(.*))?", k.lstrip())
-                if match:
-                    p = "**"+match.group(1)+match.group(2)+"**"
-                    o = "**"+match.group(1)+match.group(2)+"**"
-                    if match.group(3):
-                        # if ="" then use UPPERCASE(group(2))"
-                        val = match.group(3)
-                        if val == "\"\"":
-                            val = match.group(2).upper()
-                        p = p+"[=*"+val+"*]"
-                        val = match.group(3)
-                        if val in ("true", "false"):
-                            params[key] = params[key].rstrip()
-                            if not params[key].endswith('.'):
-                                params[key] = params[key]+ "."
-                            params[key] = params[key] + " The default is *"+val+"*."
-                            val = "*true*|*false*"
-                        o = o+"="+val
-                    ps.append(p)
-                    opts.append(o)
-                else:
-                    print "nomatch:"+k
-            new_usage = new_usage+ "\n["+"|".join(ps)+"]"
-            options = options + ", ".join(opts) + "\n   "+ params[key]+"\n\n"
-        if new_usage != "":
-            new_usage = new_usage.strip() + "\n"
-        usage = new_usage + usage
-            
-        
-        outtext = open("docs/man/docker-"+command+".1.md", "w")
-        outtext.write("""% DOCKER(1) Docker User Manuals
-% Docker Community
-% JUNE 2014
-# NAME
-""")
-        outtext.write("docker-"+command+" - "+usage_description+"\n\n")
-        outtext.write("# SYNOPSIS\n**docker "+command+"**\n"+usage+"\n\n")
+        man = subprocess.check_output("".join((docker_cmd, " help --man ", command)), shell=True)
         if description != "":
-            outtext.write("# DESCRIPTION"+description)
-        if options == "":
-            options = "There are no available options.\n\n"
-        outtext.write("# OPTIONS\n"+options)
+            match = desc_re.match(man)
+            if match:
+                man = man.replace("# DESCRIPTION"+match.group(1), "# DESCRIPTION"+description, 1)
         if examples != "":
-           outtext.write("# EXAMPLES"+examples)
+            match = generated_example_re.match(man)
+            if match:
+                man = match.group(1)
+            man = man + "# EXAMPLES" + examples
+
+        outtext = open(page, "w")
+        outtext.write(man)
         outtext.write("# HISTORY\n")
         if history != "":
            outtext.write(history+"\n")
@@ -3,27 +3,56 @@ package engine
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"sort"
 	"strconv"
+	"strings"
 )
 
 type Table struct {
-	Data    []*Env
-	sortKey string
-	Chan    chan *Env
+	Data     []*Env
+	sortKeys []string
+	Chan     chan *Env
 }
 
 func NewTable(sortKey string, sizeHint int) *Table {
 	return &Table{
 		make([]*Env, 0, sizeHint),
-		sortKey,
+		[]string{sortKey},
 		make(chan *Env),
 	}
 }
 
 func (t *Table) SetKey(sortKey string) {
-	t.sortKey = sortKey
+	t.sortKeys = []string{sortKey}
+}
+
+// SetKeys sorts the table by several keys: the entries equal by the first
+// key are sorted by the second one, and so on. A key prefixed by "-" sorts
+// in descending order.
+func (t *Table) SetKeys(sortKeys ...string) {
+	t.sortKeys = sortKeys
+}
+
+// SetSort sorts the table as spec requests it, as a comma separated list
+// of names, each prefixed by "-" to sort in descending order. names maps
+// the names which can be requested to the keys of the table.
+func (t *Table) SetSort(spec string, names map[string]string) error {
+	var keys []string
+	for _, name := range strings.Split(spec, ",") {
+		prefix := ""
+		if strings.HasPrefix(name, "-") {
+			prefix, name = "-", name[1:]
+		}
+		key, exists := names[name]
+		if !exists {
+			return fmt.Errorf("Invalid sort key: %s", name)
+		}
+		keys = append(keys, prefix+key)
+	}
+	t.sortKeys = keys
+	return nil
 }
 
 func (t *Table) Add(env *Env) {
@@ -35,7 +64,19 @@ func (t *Table) Len() int {
 }
 
 func (t *Table) Less(a, b int) bool {
-	return t.lessBy(a, b, t.sortKey)
+	for _, key := range t.sortKeys {
+		desc := strings.HasPrefix(key, "-")
+		if desc {
+			key = key[1:]
+		}
+		if t.lessBy(a, b, key) {
+			return !desc
+		}
+		if t.lessBy(b, a, key) {
+			return desc
+		}
+	}
+	return false
 }
 
 func (t *Table) lessBy(a, b int, by string) bool {
@@ -55,12 +96,13 @@ func (t *Table) Swap(a, b int) {
 	t.Data[b] = tmp
 }
 
+// Sort sorts the table by its keys, keeping the order of the equal entries.
 func (t *Table) Sort() {
-	sort.Sort(t)
+	sort.Stable(t)
 }
 
 func (t *Table) ReverseSort() {
-	sort.Sort(sort.Reverse(t))
+	sort.Stable(sort.Reverse(t))
 }
 
 func (t *Table) WriteListTo(dst io.Writer) (n int64, err error) {
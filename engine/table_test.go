@@ -110,3 +110,36 @@ func TestTableReverseSortStringValue(t *testing.T) {
 		t.Fatalf("Expected A, got %s", value)
 	}
 }
+
+func TestTableSortKeys(t *testing.T) {
+	table := NewTable("", 0)
+	for _, v := range [][3]string{
+		{"a", "2", "10"},
+		{"b", "1", "10"},
+		{"c", "2", "20"},
+		{"d", "1", "10"},
+	} {
+		e := &Env{}
+		e.Set("Id", v[0])
+		e.Set("Name", v[1])
+		e.Set("Size", v[2])
+		table.Add(e)
+	}
+
+	if err := table.SetSort("name,-size", map[string]string{"name": "Name", "size": "Size"}); err != nil {
+		t.Fatal(err)
+	}
+	table.Sort()
+	var ids string
+	for _, e := range table.Data {
+		ids += e.Get("Id")
+	}
+	// b and d are equal and keep their order
+	if ids != "bdca" {
+		t.Fatalf("Expected bdca, got %s", ids)
+	}
+
+	if err := table.SetSort("id", map[string]string{"name": "Name"}); err == nil {
+		t.Fatal("Expected an error for an unknown sort key")
+	}
+}
@@ -0,0 +1,103 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/docker/docker/engine"
+)
+
+// CmdAnnotate sets the annotations of "Set", a list of KEY=VALUE, on an
+// image and removes the ones of "Remove". Annotations are kept beside the
+// image in the graph: they can change at any time without creating a new
+// layer and do not change the image ID.
+func (s *TagStore) CmdAnnotate(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s IMAGE", job.Name)
+	}
+	img, err := s.LookupImage(job.Args[0])
+	if err != nil {
+		return job.Error(err)
+	}
+	if img == nil {
+		return job.Errorf("No such image: %s", job.Args[0])
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	annotations, err := s.graph.Annotations(img.ID)
+	if err != nil {
+		return job.Error(err)
+	}
+	for _, key := range job.GetenvList("Remove") {
+		delete(annotations, key)
+	}
+	for _, annotation := range job.GetenvList("Set") {
+		parts := strings.SplitN(annotation, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return job.Errorf("Invalid annotation %s, expected KEY=VALUE", annotation)
+		}
+		annotations[parts[0]] = parts[1]
+	}
+	if err := s.graph.SetAnnotations(img.ID, annotations); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+// matchAnnotations returns the annotations of an image and whether they
+// match all the annotation filters, each either KEY or KEY=VALUE.
+func (s *TagStore) matchAnnotations(id string, annotationFilters []string) (map[string]string, bool) {
+	annotations, err := s.graph.Annotations(id)
+	if err != nil {
+		return nil, false
+	}
+	for _, filter := range annotationFilters {
+		parts := strings.SplitN(filter, "=", 2)
+		value, exists := annotations[parts[0]]
+		if !exists || (len(parts) == 2 && value != parts[1]) {
+			return annotations, false
+		}
+	}
+	return annotations, true
+}
+
+// Annotations returns the annotations of the image id, which has none
+// until SetAnnotations is called.
+func (graph *Graph) Annotations(id string) (map[string]string, error) {
+	annotations := make(map[string]string)
+	data, err := ioutil.ReadFile(annotationsPath(graph.ImageRoot(id)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return annotations, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &annotations); err != nil {
+		return nil, fmt.Errorf("Error reading the annotations of %s: %s", id, err)
+	}
+	return annotations, nil
+}
+
+// SetAnnotations replaces the annotations of the image id.
+func (graph *Graph) SetAnnotations(id string, annotations map[string]string) error {
+	data, err := json.Marshal(annotations)
+	if err != nil {
+		return err
+	}
+	// write to a temporary file first so readers never see a partial file
+	root := graph.ImageRoot(id)
+	tmp := annotationsPath(root) + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, annotationsPath(root))
+}
+
+func annotationsPath(root string) string {
+	return path.Join(root, "annotations")
+}
@@ -0,0 +1,64 @@
+package graph
+
+import (
+	"os"
+	"testing"
+
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/utils"
+)
+
+func TestAnnotate(t *testing.T) {
+	tmp, err := utils.TestDirectory("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	store := mkTestTagStore(tmp, t)
+	defer store.graph.driver.Cleanup()
+
+	eng := engine.New()
+	if err := eng.Register("image_annotate", store.CmdAnnotate); err != nil {
+		t.Fatal(err)
+	}
+	annotate := func(set, remove []string) error {
+		job := eng.Job("image_annotate", testImageName)
+		job.SetenvList("Set", set)
+		job.SetenvList("Remove", remove)
+		return job.Run()
+	}
+
+	if err := annotate([]string{"scanned=2014-09-01", "approved-by=sec"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := annotate([]string{"scanned=2014-09-02"}, []string{"approved-by"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := annotate([]string{"invalid"}, nil); err == nil {
+		t.Fatal("Expected an error for an annotation without a value")
+	}
+
+	annotations, err := store.graph.Annotations(testImageID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(annotations) != 1 || annotations["scanned"] != "2014-09-02" {
+		t.Fatalf("Unexpected annotations %v", annotations)
+	}
+
+	for filters, expected := range map[string]bool{
+		"":                   true,
+		"scanned":            true,
+		"scanned=2014-09-02": true,
+		"scanned=2014-09-01": false,
+		"approved-by":        false,
+	} {
+		var annotationFilters []string
+		if filters != "" {
+			annotationFilters = []string{filters}
+		}
+		if _, match := store.matchAnnotations(testImageID, annotationFilters); match != expected {
+			t.Fatalf("Expected the filter %q to match %v, got %v", filters, expected, match)
+		}
+	}
+}
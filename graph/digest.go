@@ -0,0 +1,245 @@
+package graph
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/registry"
+)
+
+const digestPrefix = "sha256:"
+
+// isDigest returns whether the tag of a reference is a digest, as given
+// with NAME@DIGEST.
+func isDigest(tag string) bool {
+	return strings.HasPrefix(tag, digestPrefix)
+}
+
+// Digest returns the digest of the image id, the same on every daemon and
+// registry holding it.
+func (graph *Graph) Digest(id string) (string, error) {
+	graph.digestsLock.Lock()
+	digest, exists := graph.digests[id]
+	graph.digestsLock.Unlock()
+	if exists {
+		return digest, nil
+	}
+
+	img, err := graph.Get(id)
+	if err != nil {
+		return "", err
+	}
+	if digest, err = img.Digest(); err != nil {
+		return "", err
+	}
+
+	graph.digestsLock.Lock()
+	if graph.digests == nil {
+		graph.digests = make(map[string]string)
+	}
+	graph.digests[id] = digest
+	graph.digestsLock.Unlock()
+	return digest, nil
+}
+
+// remoteImages computes the digests of the images of a registry without
+// pulling their layers. The tags of a repository mostly share their parents,
+// so the ancestries and the JSON fetched are cached, and the images already
+// in the graph are not fetched at all.
+type remoteImages struct {
+	graph      *Graph
+	getHistory func(id, endpoint string) ([]string, error)
+	getJSON    func(id, endpoint string) ([]byte, error)
+	ancestries map[string][]string
+	jsons      map[string][]byte
+}
+
+func newRemoteImages(r *registry.Session, graph *Graph, token []string) *remoteImages {
+	return &remoteImages{
+		graph: graph,
+		getHistory: func(id, endpoint string) ([]string, error) {
+			return r.GetRemoteHistory(id, endpoint, token)
+		},
+		getJSON: func(id, endpoint string) ([]byte, error) {
+			imgJSON, _, err := r.GetRemoteImageJSON(id, endpoint, token)
+			return imgJSON, err
+		},
+		ancestries: make(map[string][]string),
+		jsons:      make(map[string][]byte),
+	}
+}
+
+// ancestry returns the image id and its parents, the image first. The
+// ancestries of the parents are the tail of it, they are cached along.
+func (ri *remoteImages) ancestry(id, endpoint string) ([]string, error) {
+	if history, exists := ri.ancestries[id]; exists {
+		return history, nil
+	}
+	history, err := ri.getHistory(id, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	for i, parent := range history {
+		ri.ancestries[parent] = history[i:]
+	}
+	ri.ancestries[id] = history
+	return history, nil
+}
+
+// json returns the JSON of the image id, from the graph when it holds it.
+func (ri *remoteImages) json(id, endpoint string) ([]byte, error) {
+	if imgJSON, exists := ri.jsons[id]; exists {
+		return imgJSON, nil
+	}
+	var (
+		imgJSON []byte
+		err     error
+	)
+	if img, gerr := ri.graph.Get(id); gerr == nil {
+		imgJSON, err = img.RawJson()
+	} else {
+		imgJSON, err = ri.getJSON(id, endpoint)
+	}
+	if err != nil {
+		return nil, err
+	}
+	ri.jsons[id] = imgJSON
+	return imgJSON, nil
+}
+
+// digest returns the digest of the image id, from the JSON of its ancestry.
+func (ri *remoteImages) digest(id, endpoint string) (string, error) {
+	if ri.graph.Exists(id) {
+		return ri.graph.Digest(id)
+	}
+	history, err := ri.ancestry(id, endpoint)
+	if err != nil {
+		return "", err
+	}
+	data := make([][]byte, 0, len(history))
+	for _, parent := range history {
+		imgJSON, err := ri.json(parent, endpoint)
+		if err != nil {
+			return "", err
+		}
+		data = append(data, imgJSON)
+	}
+	return image.HistoryDigest(data), nil
+}
+
+// find returns the ID of the tagged image with the given digest, or an empty
+// ID when there is none. The images are checked one at a time, in the order
+// of their tags, until one matches.
+func (ri *remoteImages) find(endpoints []string, tagsList map[string]string, digest string) (string, error) {
+	tags := make([]string, 0, len(tagsList))
+	for tag := range tagsList {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	checked := make(map[string]bool)
+	for _, tag := range tags {
+		id := tagsList[tag]
+		if checked[id] {
+			continue
+		}
+		checked[id] = true
+		var lastErr error
+		for _, ep := range endpoints {
+			d, err := ri.digest(id, ep)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			if d == digest {
+				return id, nil
+			}
+			lastErr = nil
+			break
+		}
+		if lastErr != nil {
+			return "", lastErr
+		}
+	}
+	return "", nil
+}
+
+// findRemoteDigest returns the ID of the tagged image of the repository
+// with the given digest, or an empty ID when there is none.
+func findRemoteDigest(r *registry.Session, graph *Graph, repoData *registry.RepositoryData, tagsList map[string]string, digest string) (string, error) {
+	return newRemoteImages(r, graph, repoData.Tokens).find(repoData.Endpoints, tagsList, digest)
+}
+
+// SetDigest records that the digest of repoName refers to the image id,
+// for the images pulled by digest which have no tag.
+func (store *TagStore) SetDigest(repoName, digest, id string) error {
+	return store.recordDigest(&store.Digests, repoName, digest, id)
+}
+
+// SetManifestDigest records that the digest of the v2 manifest of repoName
+// refers to the image id, for the images pulled by manifest digest.
+func (store *TagStore) SetManifestDigest(repoName, digest, id string) error {
+	return store.recordDigest(&store.ManifestDigests, repoName, digest, id)
+}
+
+func (store *TagStore) recordDigest(digests *map[string]Repository, repoName, digest, id string) error {
+	store.Lock()
+	defer store.Unlock()
+	if err := store.reload(); err != nil {
+		return err
+	}
+	if *digests == nil {
+		*digests = make(map[string]Repository)
+	}
+	if _, exists := (*digests)[repoName]; !exists {
+		(*digests)[repoName] = make(Repository)
+	}
+	(*digests)[repoName][digest] = id
+	return store.save()
+}
+
+// lookupDigest returns the image of repo with the given digest, either
+// recorded by a pull by digest or by manifest digest, or tagged in repo. It
+// is called with the store locked.
+func (store *TagStore) lookupDigest(repoName string, repo Repository, digest string) (*image.Image, error) {
+	if id, exists := store.Digests[repoName][digest]; exists && store.graph.Exists(id) {
+		return store.graph.Get(id)
+	}
+	if id, exists := store.ManifestDigests[repoName][digest]; exists && store.graph.Exists(id) {
+		return store.graph.Get(id)
+	}
+	for _, id := range repo {
+		if d, err := store.graph.Digest(id); err == nil && d == digest {
+			return store.graph.Get(id)
+		}
+	}
+	return nil, nil
+}
+
+// deleteDigests forgets the digests referring to the image id.
+func (store *TagStore) deleteDigests(id string) error {
+	store.Lock()
+	defer store.Unlock()
+	if err := store.reload(); err != nil {
+		return err
+	}
+	deleted := false
+	for _, repos := range []map[string]Repository{store.Digests, store.ManifestDigests} {
+		for repoName, digests := range repos {
+			for digest, digestID := range digests {
+				if digestID == id {
+					delete(digests, digest)
+					deleted = true
+				}
+			}
+			if len(digests) == 0 {
+				delete(repos, repoName)
+			}
+		}
+	}
+	if !deleted {
+		return nil
+	}
+	return store.save()
+}
@@ -0,0 +1,39 @@
+package graph
+
+// downloadManager bounds the number of layers fetched at the same time by
+// all the pulls of a TagStore. Identical layers requested by simultaneous
+// pulls are deduplicated by the pulling pool before a slot is taken, so a
+// layer is only ever fetched once.
+type downloadManager struct {
+	slots chan struct{}
+}
+
+// newDownloadManager returns a manager running at most max downloads at a
+// time, or any number of them when max is 0.
+func newDownloadManager(max int) *downloadManager {
+	dm := &downloadManager{}
+	if max > 0 {
+		dm.slots = make(chan struct{}, max)
+	}
+	return dm
+}
+
+// acquire blocks until a download can start.
+func (dm *downloadManager) acquire() {
+	if dm != nil && dm.slots != nil {
+		dm.slots <- struct{}{}
+	}
+}
+
+// release frees the slot of a finished download.
+func (dm *downloadManager) release() {
+	if dm != nil && dm.slots != nil {
+		<-dm.slots
+	}
+}
+
+// SetMaxConcurrentDownloads limits the number of layers pulled at the same
+// time, 0 meaning no limit. It must be called before any pull starts.
+func (s *TagStore) SetMaxConcurrentDownloads(max int) {
+	s.downloads = newDownloadManager(max)
+}
@@ -0,0 +1,49 @@
+package graph
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDownloadManager(t *testing.T) {
+	dm := newDownloadManager(2)
+
+	var (
+		mu              sync.Mutex
+		running, maxRun int
+		wg              sync.WaitGroup
+	)
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dm.acquire()
+			defer dm.release()
+			mu.Lock()
+			running++
+			if running > maxRun {
+				maxRun = running
+			}
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if maxRun != 2 {
+		t.Fatalf("Expected at most 2 concurrent downloads, got %d", maxRun)
+	}
+
+	// no limit, and a TagStore without a manager, never block
+	for _, dm := range []*downloadManager{newDownloadManager(0), nil} {
+		for i := 0; i < 10; i++ {
+			dm.acquire()
+		}
+		for i := 0; i < 10; i++ {
+			dm.release()
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"strings"
 
 	"github.com/docker/docker/archive"
 	"github.com/docker/docker/engine"
@@ -13,16 +14,16 @@ import (
 	"github.com/docker/docker/pkg/parsers"
 )
 
-// CmdImageExport exports all images with the given tag. All versions
+// CmdImageExport exports all images with the given tags. All versions
 // containing the same tag are exported. The resulting output is an
-// uncompressed tar ball.
-// name is the set of tags to export.
+// uncompressed tar ball, with a single repositories file for all the
+// tags, so several images or repositories can be saved together.
+// The arguments are the set of tags to export.
 // out is the writer where the images are written to.
 func (s *TagStore) CmdImageExport(job *engine.Job) engine.Status {
-	if len(job.Args) != 1 {
-		return job.Errorf("Usage: %s IMAGE\n", job.Name)
+	if len(job.Args) < 1 {
+		return job.Errorf("Usage: %s IMAGE [IMAGE...]\n", job.Name)
 	}
-	name := job.Args[0]
 	// get image json
 	tempdir, err := ioutil.TempDir("", "docker-export-")
 	if err != nil {
@@ -30,43 +31,49 @@ func (s *TagStore) CmdImageExport(job *engine.Job) engine.Status {
 	}
 	defer os.RemoveAll(tempdir)
 
-	log.Debugf("Serializing %s", name)
-
 	rootRepoMap := map[string]Repository{}
-	rootRepo, err := s.Get(name)
-	if err != nil {
-		return job.Error(err)
-	}
-	if rootRepo != nil {
-		// this is a base repo name, like 'busybox'
-
-		for _, id := range rootRepo {
-			if err := s.exportImage(job.Eng, id, tempdir); err != nil {
-				return job.Error(err)
-			}
+	addTag := func(repoName, tag, id string) {
+		if _, exists := rootRepoMap[repoName]; !exists {
+			rootRepoMap[repoName] = Repository{}
 		}
-		rootRepoMap[name] = rootRepo
-	} else {
-		img, err := s.LookupImage(name)
+		rootRepoMap[repoName][tag] = id
+	}
+	for _, name := range job.Args {
+		log.Debugf("Serializing %s", name)
+		rootRepo, err := s.Get(name)
 		if err != nil {
 			return job.Error(err)
 		}
-		if img != nil {
-			// This is a named image like 'busybox:latest'
-			repoName, repoTag := parsers.ParseRepositoryTag(name)
-			if err := s.exportImage(job.Eng, img.ID, tempdir); err != nil {
-				return job.Error(err)
-			}
-			// check this length, because a lookup of a truncated has will not have a tag
-			// and will not need to be added to this map
-			if len(repoTag) > 0 {
-				rootRepoMap[repoName] = Repository{repoTag: img.ID}
+		if rootRepo != nil {
+			// this is a base repo name, like 'busybox'
+			for tag, id := range rootRepo {
+				if err := s.exportImage(job.Eng, id, tempdir); err != nil {
+					return job.Error(err)
+				}
+				addTag(name, tag, id)
 			}
 		} else {
-			// this must be an ID that didn't get looked up just right?
-			if err := s.exportImage(job.Eng, name, tempdir); err != nil {
+			img, err := s.LookupImage(name)
+			if err != nil {
 				return job.Error(err)
 			}
+			if img != nil {
+				// This is a named image like 'busybox:latest'
+				repoName, repoTag := parsers.ParseRepositoryTag(name)
+				if err := s.exportImage(job.Eng, img.ID, tempdir); err != nil {
+					return job.Error(err)
+				}
+				// check this length, because a lookup of a truncated has will not have a tag
+				// and will not need to be added to this map
+				if len(repoTag) > 0 {
+					addTag(repoName, repoTag, img.ID)
+				}
+			} else {
+				// this must be an ID that didn't get looked up just right?
+				if err := s.exportImage(job.Eng, name, tempdir); err != nil {
+					return job.Error(err)
+				}
+			}
 		}
 	}
 	// write repositories, if there is something to write
@@ -89,7 +96,7 @@ func (s *TagStore) CmdImageExport(job *engine.Job) engine.Status {
 	if _, err := io.Copy(job.Stdout, fs); err != nil {
 		return job.Error(err)
 	}
-	log.Debugf("End Serializing %s", name)
+	log.Debugf("End Serializing %s", strings.Join(job.Args, ", "))
 	return engine.StatusOK
 }
 
@@ -0,0 +1,66 @@
+package graph
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/utils"
+)
+
+func TestExportLoadSeveralImages(t *testing.T) {
+	tmp, err := utils.TestDirectory("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	store := mkTestTagStore(path.Join(tmp, "src"), t)
+	defer store.graph.driver.Cleanup()
+	if err := store.Set("other", "v1", testImageID, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("other", "v2", testImageID, false); err != nil {
+		t.Fatal(err)
+	}
+	eng := engine.New()
+	if err := store.Install(eng); err != nil {
+		t.Fatal(err)
+	}
+
+	var bundle bytes.Buffer
+	job := eng.Job("image_export", testImageName, "other:v1")
+	job.Stdout.Add(&bundle)
+	if err := job.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := mkTestTagStore(path.Join(tmp, "dst"), t)
+	defer dst.graph.driver.Cleanup()
+	dst.DeleteAll(testImageName)
+	dstEng := engine.New()
+	if err := dst.Install(dstEng); err != nil {
+		t.Fatal(err)
+	}
+	var progress bytes.Buffer
+	job = dstEng.Job("load")
+	job.Stdin.Add(&bundle)
+	job.Stdout.Add(&progress)
+	if err := job.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(progress.String(), "Loaded image: other:v1") {
+		t.Fatalf("Expected the loaded tags in the progress, got %q", progress.String())
+	}
+
+	for _, name := range []string{testImageName, "other:v1"} {
+		if img, err := dst.LookupImage(name); err != nil || img == nil || img.ID != testImageID {
+			t.Fatalf("Expected %s to be loaded as %s, got %v (%v)", name, testImageID, img, err)
+		}
+	}
+	if img, _ := dst.LookupImage("other:v2"); img != nil {
+		t.Fatal("Expected other:v2, not saved, not to be loaded")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -16,6 +17,7 @@ import (
 	"github.com/docker/docker/daemon/graphdriver"
 	"github.com/docker/docker/dockerversion"
 	"github.com/docker/docker/image"
+	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/log"
 	"github.com/docker/docker/pkg/truncindex"
 	"github.com/docker/docker/runconfig"
@@ -27,6 +29,15 @@ type Graph struct {
 	Root    string
 	idIndex *truncindex.TruncIndex
 	driver  graphdriver.Driver
+
+	// the digests already computed, images never change
+	digests     map[string]string
+	digestsLock sync.Mutex
+
+	// uidMaps and gidMaps map the owners of the files of the layers, kept
+	// with the IDs of the host, to the IDs of the containers
+	uidMaps []idtools.IDMap
+	gidMaps []idtools.IDMap
 }
 
 // NewGraph instantiates a new graph at the given root path in the filesystem.
@@ -54,6 +65,15 @@ func NewGraph(root string, driver graphdriver.Driver) (*Graph, error) {
 	return graph, nil
 }
 
+// SetIDMappings makes the graph keep the layers with the owners of their
+// files mapped to the IDs of the host, for the containers running in a user
+// namespace with these mappings. The layers coming in and out of the graph
+// have the IDs of the containers.
+func (graph *Graph) SetIDMappings(uidMaps, gidMaps []idtools.IDMap) {
+	graph.uidMaps = uidMaps
+	graph.gidMaps = gidMaps
+}
+
 func (graph *Graph) restore() error {
 	dir, err := ioutil.ReadDir(graph.Root)
 	if err != nil {
@@ -159,9 +179,92 @@ func (graph *Graph) Create(layerData archive.ArchiveReader, containerID, contain
 	return img, nil
 }
 
+// Squash creates an image with the filesystem and the metadata of the image
+// id in a single layer on top of parent, an ancestor of id, or on top of
+// nothing if parent is empty. The layers of id above parent are merged into
+// that layer, so the files some of them remove are not in it anymore.
+func (graph *Graph) Squash(id, parent string) (*image.Image, error) {
+	img, err := graph.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if parent != "" {
+		isAncestor := false
+		for p, err := img.GetParent(); p != nil; p, err = p.GetParent() {
+			if err != nil {
+				return nil, err
+			}
+			if p.ID == parent {
+				isAncestor = true
+				break
+			}
+		}
+		if !isAncestor {
+			return nil, fmt.Errorf("Cannot squash %s on top of %s, which is not one of its parents", utils.TruncateID(id), utils.TruncateID(parent))
+		}
+	}
+
+	dir, err := graph.driver.Get(img.ID, "")
+	if err != nil {
+		return nil, fmt.Errorf("Driver %s failed to get image rootfs %s: %s", graph.driver, img.ID, err)
+	}
+	defer graph.driver.Put(img.ID)
+	var parentDir string
+	if parent != "" {
+		if parentDir, err = graph.driver.Get(parent, ""); err != nil {
+			return nil, fmt.Errorf("Driver %s failed to get image rootfs %s: %s", graph.driver, parent, err)
+		}
+		defer graph.driver.Put(parent)
+	} else {
+		if parentDir, err = graph.Mktemp(""); err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(parentDir)
+	}
+
+	changes, err := archive.ChangesDirs(dir, parentDir)
+	if err != nil {
+		return nil, err
+	}
+	layerData, err := archive.ExportChanges(dir, changes)
+	if err != nil {
+		return nil, err
+	}
+	defer layerData.Close()
+
+	squashed := &image.Image{
+		ID:              utils.GenerateRandomID(),
+		Parent:          parent,
+		Comment:         img.Comment,
+		Created:         time.Now().UTC(),
+		Container:       img.Container,
+		ContainerConfig: img.ContainerConfig,
+		DockerVersion:   dockerversion.VERSION,
+		Author:          img.Author,
+		Config:          img.Config,
+		Architecture:    img.Architecture,
+		OS:              img.OS,
+	}
+	if err := graph.register(nil, layerData, squashed); err != nil {
+		return nil, err
+	}
+	return squashed, nil
+}
+
 // Register imports a pre-existing image into the graph.
 // FIXME: pass img as first argument
-func (graph *Graph) Register(jsonData []byte, layerData archive.ArchiveReader, img *image.Image) (err error) {
+func (graph *Graph) Register(jsonData []byte, layerData archive.ArchiveReader, img *image.Image) error {
+	if layerData != nil && graph.uidMaps != nil {
+		layer := archive.ToHostIDs(layerData, graph.uidMaps, graph.gidMaps)
+		defer layer.Close()
+		layerData = layer
+	}
+	return graph.register(jsonData, layerData, img)
+}
+
+// register imports an image with a layer whose files are owned by the IDs
+// of the host.
+func (graph *Graph) register(jsonData []byte, layerData archive.ArchiveReader, img *image.Image) (err error) {
 	defer func() {
 		// If any error occurs, remove the new dir from the driver.
 		// Don't check for errors since the dir might not have been created.
@@ -220,7 +323,8 @@ func (graph *Graph) Register(jsonData []byte, layerData archive.ArchiveReader, i
 }
 
 // TempLayerArchive creates a temporary archive of the given image's filesystem layer.
-//   The archive is stored on disk and will be automatically deleted as soon as has been read.
+//   The archive is stored on disk, in the temporary directory of the daemon rather than
+//   in the graph, and will be automatically deleted as soon as has been read.
 //   If output is not nil, a human-readable progress bar will be written to it.
 //   FIXME: does this belong in Graph? How about MktempFile, let the caller use it for archives?
 func (graph *Graph) TempLayerArchive(id string, compression archive.Compression, sf *utils.StreamFormatter, output io.Writer) (*archive.TempArchive, error) {
@@ -228,17 +332,27 @@ func (graph *Graph) TempLayerArchive(id string, compression archive.Compression,
 	if err != nil {
 		return nil, err
 	}
-	tmp, err := graph.Mktemp("")
-	if err != nil {
-		return nil, err
-	}
-	a, err := image.TarLayer()
+	a, err := graph.TarLayer(image)
 	if err != nil {
 		return nil, err
 	}
 	progress := utils.ProgressReader(a, 0, output, sf, false, utils.TruncateID(id), "Buffering to disk")
 	defer progress.Close()
-	return archive.NewTempArchive(progress, tmp)
+	return archive.NewTempArchive(progress, "")
+}
+
+// TarLayer returns a tar archive of the filesystem layer of img, with the
+// owners of its files mapped to the IDs of the containers.
+func (graph *Graph) TarLayer(img *image.Image) (archive.Archive, error) {
+	layer, err := img.TarLayer()
+	if err != nil || graph.uidMaps == nil {
+		return layer, err
+	}
+	remapped := archive.ToContainerIDs(layer, graph.uidMaps, graph.gidMaps)
+	return utils.NewReadCloserWrapper(remapped, func() error {
+		remapped.Close()
+		return layer.Close()
+	}), nil
 }
 
 // Mktemp creates a temporary sub-directory inside the graph's filesystem.
@@ -250,6 +364,21 @@ func (graph *Graph) Mktemp(id string) (string, error) {
 	return dir, nil
 }
 
+// initLayerPaths are the mountpoints set up in the init layer, and their type
+var initLayerPaths = map[string]string{
+	"/dev/pts":         "dir",
+	"/dev/shm":         "dir",
+	"/proc":            "dir",
+	"/sys":             "dir",
+	"/.dockerinit":     "file",
+	"/.dockerenv":      "file",
+	"/etc/resolv.conf": "file",
+	"/etc/hosts":       "file",
+	"/etc/hostname":    "file",
+	"/dev/console":     "file",
+	"/etc/mtab":        "/proc/mounts",
+}
+
 // setupInitLayer populates a directory with mountpoints suitable
 // for bind-mounting dockerinit into the container. The mountpoint is simply an
 // empty file at /.dockerinit
@@ -257,19 +386,7 @@ func (graph *Graph) Mktemp(id string) (string, error) {
 // This extra layer is used by all containers as the top-most ro layer. It protects
 // the container from unwanted side-effects on the rw layer.
 func SetupInitLayer(initLayer string) error {
-	for pth, typ := range map[string]string{
-		"/dev/pts":         "dir",
-		"/dev/shm":         "dir",
-		"/proc":            "dir",
-		"/sys":             "dir",
-		"/.dockerinit":     "file",
-		"/.dockerenv":      "file",
-		"/etc/resolv.conf": "file",
-		"/etc/hosts":       "file",
-		"/etc/hostname":    "file",
-		"/dev/console":     "file",
-		"/etc/mtab":        "/proc/mounts",
-	} {
+	for pth, typ := range initLayerPaths {
 		parts := strings.Split(pth, "/")
 		prev := "/"
 		for _, p := range parts[1:] {
@@ -308,6 +425,18 @@ func SetupInitLayer(initLayer string) error {
 	return nil
 }
 
+// ChownInitLayer gives the mountpoints of the init layer, and their parent
+// directories it created, to the root of the containers running in a user
+// namespace, mapped to uid and gid.
+func ChownInitLayer(initLayer string, uid, gid int) error {
+	for pth := range initLayerPaths {
+		if err := idtools.ChownPath(initLayer, path.Join(initLayer, pth), uid, gid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Check if given error is "not empty".
 // Note: this is the way golang does it internally with os.IsNotExists.
 func isNotEmpty(err error) bool {
@@ -0,0 +1,64 @@
+package graph
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/utils"
+	"github.com/docker/docker/vendor/src/code.google.com/p/go/src/pkg/archive/tar"
+)
+
+func TestSquash(t *testing.T) {
+	tmp, err := utils.TestDirectory("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	store := mkTestTagStore(tmp, t)
+	graph := store.graph
+	defer graph.driver.Cleanup()
+
+	// bar, on top of foo, removes /etc/passwd and adds /etc/hosts
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	for _, name := range []string{"/etc/.wh.passwd", "/etc/hosts"} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Uid: os.Getuid(), Gid: os.Getgid(), Mode: 0644}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+	bar := &image.Image{ID: "bar", Parent: testImageID, Comment: "bar"}
+	if err := graph.Register(nil, buf, bar); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := graph.Squash(testImageID, "bar"); err == nil {
+		t.Fatal("Expected squashing on top of a child to fail")
+	}
+
+	for _, parent := range []string{testImageID, ""} {
+		img, err := graph.Squash("bar", parent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if img.Parent != parent || img.Comment != "bar" {
+			t.Fatalf("Expected the squashed image on top of %q with the comment of bar, got %#v", parent, img)
+		}
+		dir, err := graph.driver.Get(img.ID, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(path.Join(dir, "etc/passwd")); !os.IsNotExist(err) {
+			t.Fatalf("Expected /etc/passwd to be removed, got %v", err)
+		}
+		for _, name := range []string{"etc/hosts", "etc/postgres/postgres.conf"} {
+			if _, err := os.Stat(path.Join(dir, name)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		graph.driver.Put(img.ID)
+	}
+}
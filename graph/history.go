@@ -1,6 +1,7 @@
 package graph
 
 import (
+	"sort"
 	"strings"
 
 	"github.com/docker/docker/engine"
@@ -27,13 +28,17 @@ func (s *TagStore) CmdHistory(job *engine.Job) engine.Status {
 			lookupMap[id] = append(lookupMap[id], name+":"+tag)
 		}
 	}
+	for _, tags := range lookupMap {
+		sort.Strings(tags)
+	}
 
 	outs := engine.NewTable("Created", 0)
 	err = foundImage.WalkHistory(func(img *image.Image) error {
 		out := &engine.Env{}
 		out.Set("Id", img.ID)
 		out.SetInt64("Created", img.Created.Unix())
-		out.Set("CreatedBy", strings.Join(img.ContainerConfig.Cmd, " "))
+		out.Set("CreatedBy", strings.Join(createdBy(img), " "))
+		out.Set("Comment", img.Comment)
 		out.SetList("Tags", lookupMap[img.ID])
 		out.SetInt64("Size", img.Size)
 		outs.Add(out)
@@ -44,3 +49,18 @@ func (s *TagStore) CmdHistory(job *engine.Job) engine.Status {
 	}
 	return engine.StatusOK
 }
+
+// createdBy returns the command which created the layer of img: the command
+// of its container, after the entrypoint it was run with unless the layer
+// only changed the metadata of the image, e.g. a CMD of a Dockerfile.
+func createdBy(img *image.Image) []string {
+	config := img.ContainerConfig
+	if len(config.Entrypoint) == 0 || isNop(config.Cmd) {
+		return config.Cmd
+	}
+	return append(append([]string{}, config.Entrypoint...), config.Cmd...)
+}
+
+func isNop(cmd []string) bool {
+	return len(cmd) > 0 && strings.HasPrefix(cmd[len(cmd)-1], "#(nop) ")
+}
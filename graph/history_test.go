@@ -0,0 +1,26 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/runconfig"
+)
+
+func TestCreatedBy(t *testing.T) {
+	for _, c := range []struct {
+		config   runconfig.Config
+		expected []string
+	}{
+		{runconfig.Config{Cmd: []string{"/bin/sh", "-c", "apt-get install -y curl"}}, []string{"/bin/sh", "-c", "apt-get install -y curl"}},
+		{runconfig.Config{Entrypoint: []string{"/entrypoint.sh"}, Cmd: []string{"/bin/sh", "-c", "make"}}, []string{"/entrypoint.sh", "/bin/sh", "-c", "make"}},
+		{runconfig.Config{Entrypoint: []string{"/entrypoint.sh"}, Cmd: []string{"/bin/sh", "-c", "#(nop) CMD [/bin/bash]"}}, []string{"/bin/sh", "-c", "#(nop) CMD [/bin/bash]"}},
+		{runconfig.Config{}, nil},
+	} {
+		img := &image.Image{ContainerConfig: c.config}
+		if cmd := createdBy(img); !reflect.DeepEqual(cmd, c.expected) {
+			t.Fatalf("Expected %v to be created by %v, got %v", c.config, c.expected, cmd)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package graph
 
 import (
+	"io/ioutil"
 	"net/http"
 	"net/url"
 
@@ -20,6 +21,7 @@ func (s *TagStore) CmdImport(job *engine.Job) engine.Status {
 		sf      = utils.NewStreamFormatter(job.GetenvBool("json"))
 		archive archive.ArchiveReader
 		resp    *http.Response
+		size    int
 	)
 	if len(job.Args) > 2 {
 		tag = job.Args[2]
@@ -27,6 +29,7 @@ func (s *TagStore) CmdImport(job *engine.Job) engine.Status {
 
 	if src == "-" {
 		archive = job.Stdin
+		size = job.GetenvInt("Size")
 	} else {
 		u, err := url.Parse(src)
 		if err != nil {
@@ -42,7 +45,12 @@ func (s *TagStore) CmdImport(job *engine.Job) engine.Status {
 		if err != nil {
 			return job.Error(err)
 		}
-		progressReader := utils.ProgressReader(resp.Body, int(resp.ContentLength), job.Stdout, sf, true, "", "Importing")
+		defer resp.Body.Close()
+		archive = resp.Body
+		size = int(resp.ContentLength)
+	}
+	if !job.GetenvBool("quiet") {
+		progressReader := utils.ProgressReader(ioutil.NopCloser(archive), size, job.Stdout, sf, true, "", "Importing")
 		defer progressReader.Close()
 		archive = progressReader
 	}
@@ -11,6 +11,15 @@ import (
 	"github.com/docker/docker/pkg/parsers/filters"
 )
 
+// imageSortKeys maps the names the images can be sorted by to the keys of
+// their description.
+var imageSortKeys = map[string]string{
+	"created": "Created",
+	"id":      "Id",
+	"name":    "RepoTags",
+	"size":    "VirtualSize",
+}
+
 func (s *TagStore) CmdImages(job *engine.Job) engine.Status {
 	var (
 		allImages   map[string]*image.Image
@@ -38,6 +47,10 @@ func (s *TagStore) CmdImages(job *engine.Job) engine.Status {
 	if err != nil {
 		return job.Error(err)
 	}
+	shared, err := s.sharedSizes()
+	if err != nil {
+		return job.Error(err)
+	}
 	lookup := make(map[string]*engine.Env)
 	s.Lock()
 	for name, repository := range s.Repositories {
@@ -60,14 +73,17 @@ func (s *TagStore) CmdImages(job *engine.Job) engine.Status {
 			} else {
 				// get the boolean list for if only the untagged images are requested
 				delete(allImages, id)
-				if filt_tagged {
+				annotations, match := s.matchAnnotations(id, imageFilters["annotation"])
+				if filt_tagged && match {
 					out := &engine.Env{}
 					out.Set("ParentId", image.Parent)
 					out.SetList("RepoTags", []string{fmt.Sprintf("%s:%s", name, tag)})
 					out.Set("Id", image.ID)
 					out.SetInt64("Created", image.Created.Unix())
 					out.SetInt64("Size", image.Size)
-					out.SetInt64("VirtualSize", image.GetParentsSize(0)+image.Size)
+					setSizes(out, image, shared[image.ID])
+					out.SetJson("Annotations", annotations)
+					s.setDigest(out, image.ID)
 					lookup[id] = out
 				}
 			}
@@ -76,7 +92,14 @@ func (s *TagStore) CmdImages(job *engine.Job) engine.Status {
 	}
 	s.Unlock()
 
-	outs := engine.NewTable("Created", len(lookup))
+	outs := engine.NewTable("", len(lookup))
+	// the ids order the entries equal by the requested keys
+	outs.SetKeys("-Created", "Id")
+	if sort := job.Getenv("sort"); sort != "" {
+		if err := outs.SetSort(sort+",id", imageSortKeys); err != nil {
+			return job.Error(err)
+		}
+	}
 	for _, value := range lookup {
 		outs.Add(value)
 	}
@@ -84,20 +107,89 @@ func (s *TagStore) CmdImages(job *engine.Job) engine.Status {
 	// Display images which aren't part of a repository/tag
 	if job.Getenv("filter") == "" {
 		for _, image := range allImages {
+			annotations, match := s.matchAnnotations(image.ID, imageFilters["annotation"])
+			if !match {
+				continue
+			}
 			out := &engine.Env{}
 			out.Set("ParentId", image.Parent)
 			out.SetList("RepoTags", []string{"<none>:<none>"})
 			out.Set("Id", image.ID)
 			out.SetInt64("Created", image.Created.Unix())
 			out.SetInt64("Size", image.Size)
-			out.SetInt64("VirtualSize", image.GetParentsSize(0)+image.Size)
+			setSizes(out, image, shared[image.ID])
+			out.SetJson("Annotations", annotations)
+			s.setDigest(out, image.ID)
 			outs.Add(out)
 		}
 	}
 
-	outs.ReverseSort()
+	outs.Sort()
 	if _, err := outs.WriteListTo(job.Stdout); err != nil {
 		return job.Error(err)
 	}
 	return engine.StatusOK
 }
+
+// setSizes sets the virtual size of img in out, and how much of it is in
+// layers shared with other images or unique to img.
+func setSizes(out *engine.Env, img *image.Image, shared int64) {
+	virtualSize := img.GetParentsSize(0) + img.Size
+	out.SetInt64("VirtualSize", virtualSize)
+	out.SetInt64("SharedSize", shared)
+	out.SetInt64("UniqueSize", virtualSize-shared)
+}
+
+// sharedSizes returns the size of the layers each image shares with other
+// images, which removing the image would not free.
+func (s *TagStore) sharedSizes() (map[string]int64, error) {
+	images, err := s.graph.Map()
+	if err != nil {
+		return nil, err
+	}
+	return layerSharing(images, s.ByID()), nil
+}
+
+// layerSharing returns, for each image of images, the total size of the
+// layers of its chain, its own and its parents', which are in the chain of
+// another image too. The images counted are the tagged ones and the ones
+// without children, as an untagged parent is only a layer of its children.
+func layerSharing(images map[string]*image.Image, tagged map[string][]string) map[string]int64 {
+	hasChildren := make(map[string]bool)
+	for _, img := range images {
+		hasChildren[img.Parent] = true
+	}
+	chain := func(id string, fn func(*image.Image)) {
+		for img := images[id]; img != nil; img = images[img.Parent] {
+			fn(img)
+		}
+	}
+
+	refs := make(map[string]int)
+	for id := range images {
+		if _, exists := tagged[id]; exists || !hasChildren[id] {
+			chain(id, func(layer *image.Image) {
+				refs[layer.ID]++
+			})
+		}
+	}
+	shared := make(map[string]int64)
+	for id := range images {
+		chain(id, func(layer *image.Image) {
+			if refs[layer.ID] > 1 && layer.Size > 0 {
+				shared[id] += layer.Size
+			}
+		})
+	}
+	return shared
+}
+
+// setDigest sets the digest of the image id in out, if it can be computed.
+func (s *TagStore) setDigest(out *engine.Env, id string) {
+	digest, err := s.graph.Digest(id)
+	if err != nil {
+		log.Printf("Warning: couldn't compute the digest of %s: %s", id, err)
+		return
+	}
+	out.Set("Digest", digest)
+}
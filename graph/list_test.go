@@ -0,0 +1,35 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/docker/docker/image"
+)
+
+func TestLayerSharing(t *testing.T) {
+	images := map[string]*image.Image{
+		"base":   {ID: "base", Size: 100},
+		"python": {ID: "python", Parent: "base", Size: 20},
+		"web":    {ID: "web", Parent: "python", Size: 3},
+		"worker": {ID: "worker", Parent: "python", Size: 5},
+		"build":  {ID: "build", Parent: "base", Size: 40},
+		"step":   {ID: "step", Parent: "build", Size: 1},
+		"alpine": {ID: "alpine", Size: 7},
+	}
+
+	// build is an untagged parent, so it only counts as a layer of step
+	shared := layerSharing(images, map[string][]string{"base": {"base:latest"}})
+	for id, expected := range map[string]int64{
+		"base":   100,
+		"python": 120,
+		"web":    120,
+		"worker": 120,
+		"build":  100,
+		"step":   100,
+		"alpine": 0,
+	} {
+		if shared[id] != expected {
+			t.Fatalf("Expected %s to share %d bytes, got %d", id, expected, shared[id])
+		}
+	}
+}
@@ -11,10 +11,13 @@ import (
 	"github.com/docker/docker/engine"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/utils"
 )
 
 // Loads a set of images into the repository. This is the complementary of ImageExport.
 // The input stream is an uncompressed tar ball containing images and metadata.
+// The progress of the layers loaded and the tags restored is written to the
+// output, as json with 'json', unless 'quiet' is set.
 func (s *TagStore) CmdLoad(job *engine.Job) engine.Status {
 	tmpImageDir, err := ioutil.TempDir("", "docker-import-")
 	if err != nil {
@@ -22,6 +25,16 @@ func (s *TagStore) CmdLoad(job *engine.Job) engine.Status {
 	}
 	defer os.RemoveAll(tmpImageDir)
 
+	var (
+		sf  = utils.NewStreamFormatter(job.GetenvBool("json"))
+		out io.Writer
+	)
+	if job.GetenvBool("quiet") {
+		out = ioutil.Discard
+	} else {
+		out = utils.NewWriteFlusher(job.Stdout)
+	}
+
 	var (
 		repoTarFile = path.Join(tmpImageDir, "repo.tar")
 		repoDir     = path.Join(tmpImageDir, "repo")
@@ -31,7 +44,8 @@ func (s *TagStore) CmdLoad(job *engine.Job) engine.Status {
 	if err != nil {
 		return job.Error(err)
 	}
-	if _, err := io.Copy(tarFile, job.Stdin); err != nil {
+	progress := utils.ProgressReader(ioutil.NopCloser(job.Stdin), job.GetenvInt("Size"), out, sf, false, "", "Receiving")
+	if _, err := io.Copy(tarFile, progress); err != nil {
 		return job.Error(err)
 	}
 	tarFile.Close()
@@ -64,7 +78,7 @@ func (s *TagStore) CmdLoad(job *engine.Job) engine.Status {
 
 	for _, d := range dirs {
 		if d.IsDir() {
-			if err := s.recursiveLoad(job.Eng, d.Name(), tmpImageDir); err != nil {
+			if err := s.recursiveLoad(job.Eng, d.Name(), tmpImageDir, out, sf); err != nil {
 				return job.Error(err)
 			}
 		}
@@ -82,6 +96,7 @@ func (s *TagStore) CmdLoad(job *engine.Job) engine.Status {
 				if err := s.Set(imageName, tag, address, true); err != nil {
 					return job.Error(err)
 				}
+				out.Write(sf.FormatStatus("", "Loaded image: %s:%s", imageName, tag))
 			}
 		}
 	} else if !os.IsNotExist(err) {
@@ -91,7 +106,7 @@ func (s *TagStore) CmdLoad(job *engine.Job) engine.Status {
 	return engine.StatusOK
 }
 
-func (s *TagStore) recursiveLoad(eng *engine.Engine, address, tmpImageDir string) error {
+func (s *TagStore) recursiveLoad(eng *engine.Engine, address, tmpImageDir string, out io.Writer, sf *utils.StreamFormatter) error {
 	if err := eng.Job("image_get", address).Run(); err != nil {
 		log.Debugf("Loading %s", address)
 
@@ -113,14 +128,21 @@ func (s *TagStore) recursiveLoad(eng *engine.Engine, address, tmpImageDir string
 		}
 		if img.Parent != "" {
 			if !s.graph.Exists(img.Parent) {
-				if err := s.recursiveLoad(eng, img.Parent, tmpImageDir); err != nil {
+				if err := s.recursiveLoad(eng, img.Parent, tmpImageDir, out, sf); err != nil {
 					return err
 				}
 			}
 		}
-		if err := s.graph.Register(imageJson, layer, img); err != nil {
+		fi, err := layer.Stat()
+		if err != nil {
+			return err
+		}
+		progress := utils.ProgressReader(layer, int(fi.Size()), out, sf, false, utils.TruncateID(img.ID), "Loading layer")
+		if err := s.graph.Register(imageJson, progress, img); err != nil {
 			return err
 		}
+		progress.Close()
+		out.Write(sf.FormatProgress(utils.TruncateID(img.ID), "Load complete", nil))
 	}
 	log.Debugf("Completed processing %s", address)
 
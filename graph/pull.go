@@ -29,6 +29,15 @@ func (s *TagStore) CmdPull(job *engine.Job) engine.Status {
 	if len(job.Args) > 1 {
 		tag = job.Args[1]
 	}
+	if tag == "" {
+		warning, err := s.CheckImplicitTag(localName)
+		if err != nil {
+			return job.Error(err)
+		}
+		if warning != "" {
+			job.Stdout.Write(sf.FormatStatus("", "%s", warning))
+		}
+	}
 
 	job.GetenvJson("authConfig", authConfig)
 	job.GetenvJson("metaHeaders", &metaHeaders)
@@ -51,29 +60,70 @@ func (s *TagStore) CmdPull(job *engine.Job) engine.Status {
 		return job.Error(err)
 	}
 
-	endpoint, err := registry.ExpandAndVerifyRegistryUrl(hostname)
-	if err != nil {
-		return job.Error(err)
+	if hostname != registry.IndexServerAddress() {
+		// use the v2 protocol with the private registries supporting it
+		r, err := registry.NewSessionV2(authConfig, registry.HTTPRequestFactory(metaHeaders), hostname, true)
+		if err == nil {
+			if err := s.pullV2Repository(r, job.Stdout, localName, remoteName, tag, sf); err != nil {
+				return job.Error(err)
+			}
+			s.pulled()
+			return engine.StatusOK
+		} else if err != registry.ErrNoV2 {
+			return job.Error(err)
+		}
+		log.Debugf("Registry %s does not support the v2 protocol, using v1", hostname)
 	}
 
-	r, err := registry.NewSession(authConfig, registry.HTTPRequestFactory(metaHeaders), endpoint, true)
+	endpoint, err := registry.ExpandAndVerifyRegistryUrl(hostname)
 	if err != nil {
 		return job.Error(err)
 	}
 
+	var mirrors []string
 	if endpoint == registry.IndexServerAddress() {
 		// If pull "index.docker.io/foo/bar", it's stored locally under "foo/bar"
 		localName = remoteName
+		// the mirrors only serve the images of the official index
+		for _, mirror := range s.mirrors {
+			if err := registry.CheckRegistryPolicy(mirror); err != nil {
+				job.Stdout.Write(sf.FormatStatus("", "Skipping the mirror %s: %s", mirror, err))
+				continue
+			}
+			mirrors = append(mirrors, mirror)
+		}
+		if err := registry.CheckRegistryPolicy(endpoint); err != nil {
+			if len(mirrors) == 0 {
+				return job.Error(err)
+			}
+			// the policy allows the mirrors only, which then serve the
+			// repositories of the index as well
+			endpoint = mirrors[0]
+		}
+	}
+
+	r, err := registry.NewSession(authConfig, registry.HTTPRequestFactory(metaHeaders), endpoint, true)
+	if err != nil {
+		return job.Error(err)
 	}
 
-	if err = s.pullRepository(r, job.Stdout, localName, remoteName, tag, sf, job.GetenvBool("parallel")); err != nil {
+	if err = s.pullRepository(r, job.Stdout, localName, remoteName, tag, sf, job.GetenvBool("parallel"), mirrors); err != nil {
 		return job.Error(err)
 	}
+	s.pulled()
 
 	return engine.StatusOK
 }
 
-func (s *TagStore) pullRepository(r *registry.Session, out io.Writer, localName, remoteName, askedTag string, sf *utils.StreamFormatter, parallel bool) error {
+func (s *TagStore) pulled() {
+	if s.onPull != nil {
+		s.onPull()
+	}
+}
+
+// pullRepository pulls the images of remoteName, trying each of the mirrors
+// before the endpoints of the registry.
+func (s *TagStore) pullRepository(r *registry.Session, out io.Writer, localName, remoteName, askedTag string, sf *utils.StreamFormatter, parallel bool, mirrors []string) error {
 	out.Write(sf.FormatStatus("", "Pulling repository %s", localName))
 
 	repoData, err := r.GetRepositoryData(remoteName)
@@ -86,6 +136,24 @@ func (s *TagStore) pullRepository(r *registry.Session, out io.Writer, localName,
 		}
 	}
 
+	// the index may hand out endpoints the policy of the daemon denies,
+	// the mirrors then serve the tags as well
+	var endpoints []string
+	for _, ep := range repoData.Endpoints {
+		if err := registry.CheckRegistryPolicy(ep); err != nil {
+			log.Debugf("Skipping the endpoint %s: %s", ep, err)
+			continue
+		}
+		endpoints = append(endpoints, ep)
+	}
+	if len(endpoints) == 0 {
+		endpoints = mirrors
+	}
+	if len(endpoints) == 0 {
+		return fmt.Errorf("None of the endpoints of %s is allowed by the policy of the daemon: %s", localName, strings.Join(repoData.Endpoints, ", "))
+	}
+	repoData.Endpoints = endpoints
+
 	log.Debugf("Retrieving the tag list")
 	tagsList, err := r.GetRemoteTags(repoData.Endpoints, remoteName, repoData.Tokens)
 	if err != nil {
@@ -107,6 +175,16 @@ func (s *TagStore) pullRepository(r *registry.Session, out io.Writer, localName,
 		for tag, id := range tagsList {
 			repoData.ImgList[id].Tag = tag
 		}
+	} else if isDigest(askedTag) {
+		// Otherwise, find the image with the digest and use only that one
+		id, err := findRemoteDigest(r, s.graph, repoData, tagsList, askedTag)
+		if err != nil {
+			return err
+		}
+		if id == "" {
+			return fmt.Errorf("No image with digest %s in repository %s", askedTag, localName)
+		}
+		repoData.ImgList[id].Tag = askedTag
 	} else {
 		// Otherwise, check that the tag exists and use only that one
 		id, exists := tagsList[askedTag]
@@ -154,18 +232,30 @@ func (s *TagStore) pullRepository(r *registry.Session, out io.Writer, localName,
 			out.Write(sf.FormatProgress(utils.TruncateID(img.ID), fmt.Sprintf("Pulling image (%s) from %s", img.Tag, localName), nil))
 			success := false
 			var lastErr error
-			for _, ep := range repoData.Endpoints {
-				out.Write(sf.FormatProgress(utils.TruncateID(img.ID), fmt.Sprintf("Pulling image (%s) from %s, endpoint: %s", img.Tag, localName, ep), nil))
+			for _, ep := range mirrors {
+				out.Write(sf.FormatProgress(utils.TruncateID(img.ID), fmt.Sprintf("Pulling image (%s) from %s, mirror: %s", img.Tag, localName, ep), nil))
 				if err := s.pullImage(r, out, img.ID, ep, repoData.Tokens, sf); err != nil {
-					// It's not ideal that only the last error is returned, it would be better to concatenate the errors.
-					// As the error is also given to the output stream the user will see the error.
-					lastErr = err
-					out.Write(sf.FormatProgress(utils.TruncateID(img.ID), fmt.Sprintf("Error pulling image (%s) from %s, endpoint: %s, %s", img.Tag, localName, ep, err), nil))
+					// fall back to the next mirror, then to the registry
+					out.Write(sf.FormatProgress(utils.TruncateID(img.ID), fmt.Sprintf("Error pulling image (%s) from %s, mirror: %s, %s", img.Tag, localName, ep, err), nil))
 					continue
 				}
 				success = true
 				break
 			}
+			if !success {
+				for _, ep := range repoData.Endpoints {
+					out.Write(sf.FormatProgress(utils.TruncateID(img.ID), fmt.Sprintf("Pulling image (%s) from %s, endpoint: %s", img.Tag, localName, ep), nil))
+					if err := s.pullImage(r, out, img.ID, ep, repoData.Tokens, sf); err != nil {
+						// It's not ideal that only the last error is returned, it would be better to concatenate the errors.
+						// As the error is also given to the output stream the user will see the error.
+						lastErr = err
+						out.Write(sf.FormatProgress(utils.TruncateID(img.ID), fmt.Sprintf("Error pulling image (%s) from %s, endpoint: %s, %s", img.Tag, localName, ep, err), nil))
+						continue
+					}
+					success = true
+					break
+				}
+			}
 			if !success {
 				err := fmt.Errorf("Error pulling image (%s) from %s, %v", img.Tag, localName, lastErr)
 				out.Write(sf.FormatProgress(utils.TruncateID(img.ID), err.Error(), nil))
@@ -199,6 +289,18 @@ func (s *TagStore) pullRepository(r *registry.Session, out io.Writer, localName,
 		}
 
 	}
+	if isDigest(askedTag) {
+		for id, img := range repoData.ImgList {
+			if img.Tag != askedTag {
+				continue
+			}
+			if err := s.SetDigest(localName, askedTag, id); err != nil {
+				return err
+			}
+		}
+		out.Write(sf.FormatStatus("", "Digest: %s", askedTag))
+		return nil
+	}
 	for tag, id := range tagsList {
 		if askedTag != "" && tag != askedTag {
 			continue
@@ -206,6 +308,11 @@ func (s *TagStore) pullRepository(r *registry.Session, out io.Writer, localName,
 		if err := s.Set(localName, tag, id, true); err != nil {
 			return err
 		}
+		if askedTag != "" {
+			if digest, err := s.graph.Digest(id); err == nil {
+				out.Write(sf.FormatStatus("", "Digest: %s", digest))
+			}
+		}
 	}
 
 	return nil
@@ -231,71 +338,84 @@ func (s *TagStore) pullImage(r *registry.Session, out io.Writer, imgID, endpoint
 		defer s.poolRemove("pull", "layer:"+id)
 
 		if !s.graph.Exists(id) {
-			out.Write(sf.FormatProgress(utils.TruncateID(id), "Pulling metadata", nil))
-			var (
-				imgJSON []byte
-				imgSize int
-				err     error
-				img     *image.Image
-			)
-			retries := 5
-			for j := 1; j <= retries; j++ {
-				imgJSON, imgSize, err = r.GetRemoteImageJSON(id, endpoint, token)
-				if err != nil && j == retries {
-					out.Write(sf.FormatProgress(utils.TruncateID(id), "Error pulling dependent layers", nil))
-					return err
-				} else if err != nil {
-					time.Sleep(time.Duration(j) * 500 * time.Millisecond)
-					continue
-				}
-				img, err = image.NewImgJSON(imgJSON)
-				if err != nil && j == retries {
-					out.Write(sf.FormatProgress(utils.TruncateID(id), "Error pulling dependent layers", nil))
-					return fmt.Errorf("Failed to parse json: %s", err)
-				} else if err != nil {
-					time.Sleep(time.Duration(j) * 500 * time.Millisecond)
-					continue
-				} else {
-					break
-				}
+			// wait for a download slot, shared with all the other pulls
+			s.downloads.acquire()
+			err := s.pullLayer(r, out, id, endpoint, token, sf)
+			s.downloads.release()
+			if err != nil {
+				return err
 			}
+		}
+		out.Write(sf.FormatProgress(utils.TruncateID(id), "Download complete", nil))
 
-			for j := 1; j <= retries; j++ {
-				// Get the layer
-				status := "Pulling fs layer"
-				if j > 1 {
-					status = fmt.Sprintf("Pulling fs layer [retries: %d]", j)
-				}
-				out.Write(sf.FormatProgress(utils.TruncateID(id), status, nil))
-				layer, err := r.GetRemoteImageLayer(img.ID, endpoint, token, int64(imgSize))
-				if uerr, ok := err.(*url.Error); ok {
-					err = uerr.Err
-				}
-				if terr, ok := err.(net.Error); ok && terr.Timeout() && j < retries {
-					time.Sleep(time.Duration(j) * 500 * time.Millisecond)
-					continue
-				} else if err != nil {
-					out.Write(sf.FormatProgress(utils.TruncateID(id), "Error pulling dependent layers", nil))
-					return err
-				}
-				defer layer.Close()
+	}
+	return nil
+}
 
-				err = s.graph.Register(imgJSON,
-					utils.ProgressReader(layer, imgSize, out, sf, false, utils.TruncateID(id), "Downloading"),
-					img)
-				if terr, ok := err.(net.Error); ok && terr.Timeout() && j < retries {
-					time.Sleep(time.Duration(j) * 500 * time.Millisecond)
-					continue
-				} else if err != nil {
-					out.Write(sf.FormatProgress(utils.TruncateID(id), "Error downloading dependent layers", nil))
-					return err
-				} else {
-					break
-				}
-			}
+// pullLayer fetches the metadata and the content of the layer id from
+// endpoint and registers it in the graph.
+func (s *TagStore) pullLayer(r *registry.Session, out io.Writer, id, endpoint string, token []string, sf *utils.StreamFormatter) error {
+	out.Write(sf.FormatProgress(utils.TruncateID(id), "Pulling metadata", nil))
+	var (
+		imgJSON []byte
+		imgSize int
+		err     error
+		img     *image.Image
+	)
+	retries := 5
+	for j := 1; j <= retries; j++ {
+		imgJSON, imgSize, err = r.GetRemoteImageJSON(id, endpoint, token)
+		if err != nil && j == retries {
+			out.Write(sf.FormatProgress(utils.TruncateID(id), "Error pulling dependent layers", nil))
+			return err
+		} else if err != nil {
+			time.Sleep(time.Duration(j) * 500 * time.Millisecond)
+			continue
 		}
-		out.Write(sf.FormatProgress(utils.TruncateID(id), "Download complete", nil))
+		img, err = image.NewImgJSON(imgJSON)
+		if err != nil && j == retries {
+			out.Write(sf.FormatProgress(utils.TruncateID(id), "Error pulling dependent layers", nil))
+			return fmt.Errorf("Failed to parse json: %s", err)
+		} else if err != nil {
+			time.Sleep(time.Duration(j) * 500 * time.Millisecond)
+			continue
+		} else {
+			break
+		}
+	}
 
+	for j := 1; j <= retries; j++ {
+		// Get the layer
+		status := "Pulling fs layer"
+		if j > 1 {
+			status = fmt.Sprintf("Pulling fs layer [retries: %d]", j)
+		}
+		out.Write(sf.FormatProgress(utils.TruncateID(id), status, nil))
+		layer, err := r.GetRemoteImageLayer(img.ID, endpoint, token, int64(imgSize))
+		if uerr, ok := err.(*url.Error); ok {
+			err = uerr.Err
+		}
+		if terr, ok := err.(net.Error); ok && terr.Timeout() && j < retries {
+			time.Sleep(time.Duration(j) * 500 * time.Millisecond)
+			continue
+		} else if err != nil {
+			out.Write(sf.FormatProgress(utils.TruncateID(id), "Error pulling dependent layers", nil))
+			return err
+		}
+		defer layer.Close()
+
+		err = s.graph.Register(imgJSON,
+			utils.ProgressReader(layer, imgSize, out, sf, false, utils.TruncateID(id), "Downloading"),
+			img)
+		if terr, ok := err.(net.Error); ok && terr.Timeout() && j < retries {
+			time.Sleep(time.Duration(j) * 500 * time.Millisecond)
+			continue
+		} else if err != nil {
+			out.Write(sf.FormatProgress(utils.TruncateID(id), "Error downloading dependent layers", nil))
+			return err
+		} else {
+			break
+		}
 	}
 	return nil
 }
@@ -0,0 +1,213 @@
+package graph
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/docker/docker/archive"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/registry"
+	"github.com/docker/docker/utils"
+)
+
+// pullV2Repository pulls the images of remoteName from a registry of the
+// v2 protocol, all its tags when askedTag is empty.
+func (s *TagStore) pullV2Repository(r *registry.SessionV2, out io.Writer, localName, remoteName, askedTag string, sf *utils.StreamFormatter) error {
+	out.Write(sf.FormatStatus("", "Pulling repository %s", localName))
+
+	tags := []string{askedTag}
+	if askedTag == "" {
+		var err error
+		if tags, err = r.GetV2RemoteTags(remoteName); err != nil {
+			return err
+		}
+	}
+	for _, tag := range tags {
+		if err := s.pullV2Tag(r, out, localName, remoteName, tag, sf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getV2Manifest fetches and checks the manifest of remoteName:tag, and
+// returns it with its digest.
+func getV2Manifest(r *registry.SessionV2, localName, remoteName, tag string) (*registry.ManifestData, string, error) {
+	log.Debugf("Pulling the manifest of %s:%s", remoteName, tag)
+	data, digest, err := r.GetV2ImageManifest(remoteName, tag)
+	if err != nil {
+		return nil, "", err
+	}
+	var manifest registry.ManifestData
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, "", fmt.Errorf("Failed to parse the manifest of %s:%s: %s", localName, tag, err)
+	}
+	if len(manifest.FSLayers) == 0 || len(manifest.FSLayers) != len(manifest.History) {
+		return nil, "", fmt.Errorf("Invalid manifest for %s:%s: %d layers for %d images", localName, tag, len(manifest.FSLayers), len(manifest.History))
+	}
+	return &manifest, digest, nil
+}
+
+// manifestImageDigest returns the digest of the image of a manifest, as
+// Graph.Digest does once the image is pulled, which is not the digest of
+// the manifest itself.
+func manifestImageDigest(manifest *registry.ManifestData) string {
+	history := make([][]byte, len(manifest.History))
+	for i, h := range manifest.History {
+		history[i] = []byte(h.V1Compatibility)
+	}
+	return image.HistoryDigest(history)
+}
+
+// findV2Digest returns the manifest of the tag of remoteName whose image
+// has the given digest, the registry only knowing the digests of the
+// manifests.
+func findV2Digest(r *registry.SessionV2, localName, remoteName, digest string) (*registry.ManifestData, string, error) {
+	tags, err := r.GetV2RemoteTags(remoteName)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, tag := range tags {
+		manifest, manifestDigest, err := getV2Manifest(r, localName, remoteName, tag)
+		if err != nil {
+			return nil, "", err
+		}
+		if manifestImageDigest(manifest) == digest {
+			return manifest, manifestDigest, nil
+		}
+	}
+	return nil, "", fmt.Errorf("No image with digest %s in repository %s", digest, localName)
+}
+
+// pullV2Tag pulls the layers of the manifest of remoteName:tag, tag being
+// the digest of the image or of its manifest when pulling by digest, and
+// tags the image.
+func (s *TagStore) pullV2Tag(r *registry.SessionV2, out io.Writer, localName, remoteName, tag string, sf *utils.StreamFormatter) error {
+	manifest, manifestDigest, err := getV2Manifest(r, localName, remoteName, tag)
+	if err != nil {
+		if !isDigest(tag) {
+			return err
+		}
+		// not the digest of a manifest, look for the image with the digest
+		if manifest, manifestDigest, err = findV2Digest(r, localName, remoteName, tag); err != nil {
+			return err
+		}
+	}
+	digest := manifestImageDigest(manifest)
+
+	var imgID string
+	for i := len(manifest.History) - 1; i >= 0; i-- {
+		imgJSON := []byte(manifest.History[i].V1Compatibility)
+		img, err := image.NewImgJSON(imgJSON)
+		if err != nil {
+			return fmt.Errorf("Failed to parse json: %s", err)
+		}
+		imgID = img.ID
+
+		// ensure no two downloads of the same layer happen at the same time
+		if c, err := s.poolAdd("pull", "layer:"+img.ID); err != nil {
+			log.Debugf("Image (id: %s) pull is already running, skipping: %v", img.ID, err)
+			<-c
+		}
+		defer s.poolRemove("pull", "layer:"+img.ID)
+
+		if !s.graph.Exists(img.ID) {
+			// wait for a download slot, shared with all the other pulls
+			s.downloads.acquire()
+			err := s.pullV2Layer(r, out, remoteName, manifest.FSLayers[i].BlobSum, imgJSON, img, sf)
+			s.downloads.release()
+			if err != nil {
+				return err
+			}
+		}
+		out.Write(sf.FormatProgress(utils.TruncateID(img.ID), "Download complete", nil))
+	}
+
+	if tag == digest {
+		if err := s.SetDigest(localName, tag, imgID); err != nil {
+			return err
+		}
+	} else if isDigest(tag) {
+		if err := s.SetManifestDigest(localName, tag, imgID); err != nil {
+			return err
+		}
+	} else if err := s.Set(localName, tag, imgID, true); err != nil {
+		return err
+	}
+	out.Write(sf.FormatStatus("", "Digest: %s", digest))
+	if manifestDigest != "" {
+		out.Write(sf.FormatStatus("", "Manifest digest: %s", manifestDigest))
+	}
+	return nil
+}
+
+// pullV2Layer downloads the blob of img and registers it in the graph once
+// its content matches blobSum. The blob is buffered on the disk of the graph
+// meanwhile, so that a corrupted or forged layer is never registered.
+func (s *TagStore) pullV2Layer(r *registry.SessionV2, out io.Writer, remoteName, blobSum string, imgJSON []byte, img *image.Image, sf *utils.StreamFormatter) error {
+	out.Write(sf.FormatProgress(utils.TruncateID(img.ID), "Pulling fs layer", nil))
+	layer, size, err := r.GetV2ImageBlobReader(remoteName, blobSum)
+	if err != nil {
+		out.Write(sf.FormatProgress(utils.TruncateID(img.ID), "Error pulling dependent layers", nil))
+		return err
+	}
+	defer layer.Close()
+
+	tmp, err := s.graph.Mktemp("")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	verifier := newBlobVerifier(layer, blobSum)
+	blob, err := archive.NewTempArchive(utils.ProgressReader(ioutil.NopCloser(verifier), int(size), out, sf, false, utils.TruncateID(img.ID), "Downloading"), tmp)
+	if err != nil {
+		out.Write(sf.FormatProgress(utils.TruncateID(img.ID), "Error downloading dependent layers", nil))
+		return err
+	}
+	defer blob.Close()
+	if err := verifier.verify(); err != nil {
+		out.Write(sf.FormatProgress(utils.TruncateID(img.ID), "Error verifying dependent layers", nil))
+		return err
+	}
+	if err := s.graph.Register(imgJSON, blob, img); err != nil {
+		out.Write(sf.FormatProgress(utils.TruncateID(img.ID), "Error registering dependent layers", nil))
+		return err
+	}
+	return nil
+}
+
+// blobVerifier hashes a blob while it is read, to check it against its
+// digest once read.
+type blobVerifier struct {
+	io.Reader
+	hash   hash.Hash
+	digest string
+}
+
+func newBlobVerifier(src io.Reader, digest string) *blobVerifier {
+	h := sha256.New()
+	return &blobVerifier{
+		Reader: io.TeeReader(src, h),
+		hash:   h,
+		digest: digest,
+	}
+}
+
+func (v *blobVerifier) verify() error {
+	// the layer archive may end before the end of the blob
+	if _, err := io.Copy(ioutil.Discard, v.Reader); err != nil {
+		return err
+	}
+	if digest := digestPrefix + hex.EncodeToString(v.hash.Sum(nil)); digest != v.digest {
+		return fmt.Errorf("Invalid layer: expected the digest %s, got %s", v.digest, digest)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package graph
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/registry"
+	"github.com/docker/docker/utils"
+)
+
+func TestPullV2LayerDigest(t *testing.T) {
+	tmp, err := utils.TestDirectory("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	store := mkTestTagStore(tmp, t)
+	defer store.graph.driver.Cleanup()
+
+	layer, err := fakeTar()
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob, err := ioutil.ReadAll(layer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(blob)
+	blobSum := digestPrefix + hex.EncodeToString(sum[:])
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
+		if strings.HasPrefix(r.URL.Path, "/v2/foo/bar/blobs/") {
+			w.Write(blob)
+		}
+	}))
+	defer server.Close()
+	r, err := registry.NewSessionV2(nil, registry.HTTPRequestFactory(nil), strings.TrimPrefix(server.URL, "http://"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pull := func(id, blobSum string) error {
+		img := &image.Image{ID: id}
+		imgJSON, err := json.Marshal(img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store.pullV2Layer(r, ioutil.Discard, "foo/bar", blobSum, imgJSON, img, utils.NewStreamFormatter(false))
+	}
+
+	// a layer which does not match its digest is not registered
+	forged := digestPrefix + strings.Repeat("0", 64)
+	if err := pull("forged", forged); err == nil || !strings.Contains(err.Error(), forged) {
+		t.Fatalf("Expected a digest error, got %v", err)
+	}
+	if store.graph.Exists("forged") {
+		t.Fatal("Expected the forged layer not to be registered")
+	}
+
+	if err := pull("verified", blobSum); err != nil {
+		t.Fatal(err)
+	}
+	if !store.graph.Exists("verified") {
+		t.Fatal("Expected the verified layer to be registered")
+	}
+
+	// the blobs are not left in the graph
+	if entries, err := ioutil.ReadDir(store.graph.Root + "/_tmp"); err != nil || len(entries) != 0 {
+		t.Fatalf("Expected no temporary file, got %v (%v)", entries, err)
+	}
+}
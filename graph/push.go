@@ -132,6 +132,9 @@ func (s *TagStore) pushRepository(r *registry.Session, out io.Writer, localName,
 				if err := r.PushRegistryTag(remoteName, imgId, tag, ep, repoData.Tokens); err != nil {
 					return err
 				}
+				if digest, err := s.graph.Digest(imgId); err == nil {
+					out.Write(sf.FormatStatus("", "%s: digest: %s", tag, digest))
+				}
 			}
 		}
 	}
@@ -214,6 +217,21 @@ func (s *TagStore) CmdPush(job *engine.Job) engine.Status {
 		return job.Error(err)
 	}
 
+	if localRepo, exists := s.Repositories[localName]; exists && hostname != registry.IndexServerAddress() {
+		// use the v2 protocol with the private registries supporting it
+		r, err := registry.NewSessionV2(authConfig, registry.HTTPRequestFactory(metaHeaders), hostname, false)
+		if err == nil {
+			job.Stdout.Write(sf.FormatStatus("", "The push refers to a repository [%s]", localName))
+			if err := s.pushV2Repository(r, job.Stdout, localName, remoteName, localRepo, tag, sf); err != nil {
+				return job.Error(err)
+			}
+			return engine.StatusOK
+		} else if err != registry.ErrNoV2 {
+			return job.Error(err)
+		}
+		log.Debugf("Registry %s does not support the v2 protocol, using v1", hostname)
+	}
+
 	endpoint, err := registry.ExpandAndVerifyRegistryUrl(hostname)
 	if err != nil {
 		return job.Error(err)
@@ -0,0 +1,128 @@
+package graph
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+
+	"github.com/docker/docker/archive"
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/registry"
+	"github.com/docker/docker/utils"
+)
+
+// v2ChunkSize is the size of the requests uploading a layer to a v2
+// registry. The smaller layers are uploaded in a single request.
+const v2ChunkSize = 10 * 1024 * 1024
+
+// pushV2Repository pushes the tags of localRepo, or only tag if not empty,
+// to a registry of the v2 protocol.
+func (s *TagStore) pushV2Repository(r *registry.SessionV2, out io.Writer, localName, remoteName string, localRepo map[string]string, tag string, sf *utils.StreamFormatter) error {
+	out = utils.NewWriteFlusher(out)
+	if tag != "" {
+		if _, exists := localRepo[tag]; !exists {
+			return fmt.Errorf("Tag does not exist: %s", tag)
+		}
+	}
+
+	// the digests of the layers already pushed, shared by the tags
+	blobSums := make(map[string]string)
+	for t, id := range localRepo {
+		if tag != "" && t != tag {
+			continue
+		}
+		out.Write(sf.FormatStatus("", "Pushing tag %s:%s", localName, t))
+		manifest := &registry.ManifestData{
+			SchemaVersion: 1,
+			Name:          remoteName,
+			Tag:           t,
+		}
+		for img, err := s.graph.Get(id); img != nil; img, err = img.GetParent() {
+			if err != nil {
+				return err
+			}
+			if manifest.Architecture == "" {
+				manifest.Architecture = img.Architecture
+			}
+			jsonRaw, err := img.RawJson()
+			if err != nil {
+				return err
+			}
+			blobSum, exists := blobSums[img.ID]
+			if !exists {
+				if blobSum, err = s.pushV2Layer(r, out, remoteName, img.ID, sf); err != nil {
+					return err
+				}
+				blobSums[img.ID] = blobSum
+			}
+			manifest.FSLayers = append(manifest.FSLayers, &registry.FSLayer{BlobSum: blobSum})
+			manifest.History = append(manifest.History, &registry.ManifestHistory{V1Compatibility: string(jsonRaw)})
+		}
+		if manifest.Architecture == "" {
+			manifest.Architecture = runtime.GOARCH
+		}
+
+		signed, err := registry.SignManifest(manifest)
+		if err != nil {
+			return err
+		}
+		manifestDigest, err := r.PutV2ImageManifest(remoteName, t, signed)
+		if err != nil {
+			return err
+		}
+		// the digest of the image, as shown by docker images --digests,
+		// then the digest of the manifest in the registry
+		if digest, err := s.graph.Digest(id); err == nil {
+			out.Write(sf.FormatStatus("", "%s: digest: %s", t, digest))
+		}
+		if manifestDigest != "" {
+			out.Write(sf.FormatStatus("", "%s: manifest digest: %s", t, manifestDigest))
+		}
+	}
+	return nil
+}
+
+// pushV2Layer uploads the layer of imgID as a gzipped archive, unless the
+// registry already has it, and returns its digest.
+func (s *TagStore) pushV2Layer(r *registry.SessionV2, out io.Writer, remoteName, imgID string, sf *utils.StreamFormatter) (string, error) {
+	out.Write(sf.FormatProgress(utils.TruncateID(imgID), "Pushing", nil))
+	layerData, err := s.graph.TempLayerArchive(imgID, archive.Gzip, sf, out)
+	if err != nil {
+		return "", fmt.Errorf("Failed to generate layer archive: %s", err)
+	}
+	defer os.RemoveAll(layerData.Name())
+
+	h := sha256.New()
+	if _, err := io.Copy(h, layerData.File); err != nil {
+		return "", err
+	}
+	if _, err := layerData.Seek(0, 0); err != nil {
+		return "", err
+	}
+	blobSum := digestPrefix + hex.EncodeToString(h.Sum(nil))
+
+	exists, err := r.HeadV2ImageBlob(remoteName, blobSum)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		out.Write(sf.FormatProgress(utils.TruncateID(imgID), "Image already exists", nil))
+		return blobSum, nil
+	}
+
+	log.Debugf("rendered layer for %s of [%d] size", imgID, layerData.Size)
+	layer := utils.ProgressReader(layerData, int(layerData.Size), out, sf, false, utils.TruncateID(imgID), "Pushing")
+	if layerData.Size > v2ChunkSize {
+		err = r.PutV2ImageBlobChunked(remoteName, blobSum, layer, v2ChunkSize)
+	} else {
+		err = r.PutV2ImageBlob(remoteName, blobSum, layer, layerData.Size)
+	}
+	if err != nil {
+		return "", err
+	}
+	out.Write(sf.FormatProgress(utils.TruncateID(imgID), "Image successfully pushed", nil))
+	return blobSum, nil
+}
@@ -17,10 +17,12 @@ func (s *TagStore) Install(eng *engine.Engine) error {
 		"image_get":      s.CmdGet,
 		"image_inspect":  s.CmdLookup,
 		"image_tarlayer": s.CmdTarLayer,
+		"image_annotate": s.CmdAnnotate,
 		"image_export":   s.CmdImageExport,
 		"history":        s.CmdHistory,
 		"images":         s.CmdImages,
 		"viz":            s.CmdViz,
+		"images_graph":   s.CmdImagesGraph,
 		"load":           s.CmdLoad,
 		"import":         s.CmdImport,
 		"pull":           s.CmdPull,
@@ -108,7 +110,7 @@ func (s *TagStore) CmdGet(job *engine.Job) engine.Status {
 		//	- Comment: initially created to fulfill the "every image is a git commit"
 		//		metaphor, in practice people either ignore it or use it as a
 		//		generic description field which it isn't. On deprecation shortlist.
-		res.SetAuto("Created", img.Created)
+		res.SetAuto("Created", img.Created.UTC())
 		res.Set("Author", img.Author)
 		res.Set("Os", img.OS)
 		res.Set("Architecture", img.Architecture)
@@ -140,7 +142,7 @@ func (s *TagStore) CmdLookup(job *engine.Job) engine.Status {
 		out.Set("Id", image.ID)
 		out.Set("Parent", image.Parent)
 		out.Set("Comment", image.Comment)
-		out.SetAuto("Created", image.Created)
+		out.SetAuto("Created", image.Created.UTC())
 		out.Set("Container", image.Container)
 		out.SetJson("ContainerConfig", image.ContainerConfig)
 		out.Set("DockerVersion", image.DockerVersion)
@@ -149,6 +151,11 @@ func (s *TagStore) CmdLookup(job *engine.Job) engine.Status {
 		out.Set("Architecture", image.Architecture)
 		out.Set("Os", image.OS)
 		out.SetInt64("Size", image.Size)
+		annotations, err := s.graph.Annotations(image.ID)
+		if err != nil {
+			return job.Error(err)
+		}
+		out.SetJson("Annotations", annotations)
 		if _, err = out.WriteTo(job.Stdout); err != nil {
 			return job.Error(err)
 		}
@@ -164,7 +171,7 @@ func (s *TagStore) CmdTarLayer(job *engine.Job) engine.Status {
 	}
 	name := job.Args[0]
 	if image, err := s.LookupImage(name); err == nil && image != nil {
-		fs, err := image.TarLayer()
+		fs, err := s.graph.TarLayer(image)
 		if err != nil {
 			return job.Error(err)
 		}
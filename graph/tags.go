@@ -17,15 +17,35 @@ import (
 
 const DEFAULTTAG = "latest"
 
+// The policies of the images given without a tag or digest, which mean their
+// DEFAULTTAG, or all their tags for a pull.
+const (
+	ImplicitTagAllow = "allow"
+	ImplicitTagWarn  = "warn"
+	ImplicitTagDeny  = "deny"
+)
+
 type TagStore struct {
 	path         string
 	graph        *Graph
 	Repositories map[string]Repository
+	// the images pulled by digest, by repository and digest
+	Digests map[string]Repository `json:",omitempty"`
+	// the images pulled by the digest of their v2 manifest, which is not
+	// the digest of the image, by repository and manifest digest
+	ManifestDigests map[string]Repository `json:",omitempty"`
 	sync.Mutex
 	// FIXME: move push/pull-related fields
 	// to a helper type
 	pullingPool map[string]chan struct{}
 	pushingPool map[string]chan struct{}
+	downloads   *downloadManager
+	// registry endpoints tried before the official index
+	mirrors []string
+	// called after each successful pull
+	onPull func()
+	// what to do with the images given without a tag or digest
+	implicitTag string
 }
 
 type Repository map[string]string
@@ -41,6 +61,7 @@ func NewTagStore(path string, graph *Graph) (*TagStore, error) {
 		Repositories: make(map[string]Repository),
 		pullingPool:  make(map[string]chan struct{}),
 		pushingPool:  make(map[string]chan struct{}),
+		downloads:    newDownloadManager(0),
 	}
 	// Load the json file if it exists, otherwise create it.
 	if err := store.reload(); os.IsNotExist(err) {
@@ -53,6 +74,51 @@ func NewTagStore(path string, graph *Graph) (*TagStore, error) {
 	return store, nil
 }
 
+// SetMirrors sets the registry mirrors, as v1 endpoints, tried before the
+// official index when pulling its images.
+func (store *TagStore) SetMirrors(mirrors []string) {
+	store.mirrors = mirrors
+}
+
+// SetImplicitTagPolicy sets what to do with the images pulled or run without
+// a tag or digest: ImplicitTagAllow, the default, ImplicitTagWarn or
+// ImplicitTagDeny.
+func (store *TagStore) SetImplicitTagPolicy(policy string) error {
+	switch policy {
+	case "", ImplicitTagAllow, ImplicitTagWarn, ImplicitTagDeny:
+		store.implicitTag = policy
+		return nil
+	}
+	return fmt.Errorf("Invalid implicit tag policy %s, valid policies are %s, %s and %s", policy, ImplicitTagAllow, ImplicitTagWarn, ImplicitTagDeny)
+}
+
+// CheckImplicitTag applies the implicit tag policy to name. It returns an
+// error when name has no tag or digest and the policy denies it, and a
+// warning to show when the policy warns about it. The IDs of the images
+// are explicit.
+func (store *TagStore) CheckImplicitTag(name string) (string, error) {
+	if store.implicitTag == "" || store.implicitTag == ImplicitTagAllow {
+		return "", nil
+	}
+	if _, tag := parsers.ParseRepositoryTag(name); tag != "" {
+		return "", nil
+	}
+	if repo, _ := store.Get(name); repo == nil {
+		if img, _ := store.graph.Get(name); img != nil {
+			return "", nil
+		}
+	}
+	if store.implicitTag == ImplicitTagDeny {
+		return "", fmt.Errorf("%s has no tag or digest, give one such as %s:%s (the daemon runs with --implicit-tag=%s)", name, name, DEFAULTTAG, ImplicitTagDeny)
+	}
+	return fmt.Sprintf("Warning: %s has no tag or digest, which will be refused with --implicit-tag=%s", name, ImplicitTagDeny), nil
+}
+
+// OnPull sets a function called after each successful pull.
+func (store *TagStore) OnPull(f func()) {
+	store.onPull = f
+}
+
 func (store *TagStore) save() error {
 	// Store the json ball
 	jsonData, err := json.Marshal(store)
@@ -124,6 +190,9 @@ func (store *TagStore) ImageName(id string) string {
 }
 
 func (store *TagStore) DeleteAll(id string) error {
+	if err := store.deleteDigests(id); err != nil {
+		return err
+	}
 	names, exists := store.ByID()[id]
 	if !exists || len(names) == 0 {
 		return nil
@@ -150,6 +219,21 @@ func (store *TagStore) Delete(repoName, tag string) (bool, error) {
 	if err := store.reload(); err != nil {
 		return false, err
 	}
+	if isDigest(tag) {
+		for _, digests := range []map[string]Repository{store.Digests, store.ManifestDigests} {
+			if _, exists := digests[repoName][tag]; exists {
+				delete(digests[repoName], tag)
+				if len(digests[repoName]) == 0 {
+					delete(digests, repoName)
+				}
+				deleted = true
+			}
+		}
+		if !deleted {
+			return false, nil
+		}
+		return true, store.save()
+	}
 	if r, exists := store.Repositories[repoName]; exists {
 		if tag != "" {
 			if _, exists2 := r[tag]; exists2 {
@@ -222,7 +306,11 @@ func (store *TagStore) GetImage(repoName, tagOrID string) (*image.Image, error)
 	defer store.Unlock()
 	if err != nil {
 		return nil, err
-	} else if repo == nil {
+	}
+	if isDigest(tagOrID) {
+		return store.lookupDigest(repoName, repo, tagOrID)
+	}
+	if repo == nil {
 		return nil, nil
 	}
 	if revision, exists := repo[tagOrID]; exists {
@@ -2,14 +2,17 @@ package graph
 
 import (
 	"bytes"
+	"fmt"
 	"github.com/docker/docker/daemon/graphdriver"
 	_ "github.com/docker/docker/daemon/graphdriver/vfs" // import the vfs driver so it is used in the tests
 	"github.com/docker/docker/image"
+	"github.com/docker/docker/registry"
 	"github.com/docker/docker/utils"
 	"github.com/docker/docker/vendor/src/code.google.com/p/go/src/pkg/archive/tar"
 	"io"
 	"os"
 	"path"
+	"strings"
 	"testing"
 )
 
@@ -114,3 +117,234 @@ func TestLookupImage(t *testing.T) {
 		t.Errorf("Expected 1 image, none found")
 	}
 }
+
+func TestImageDigest(t *testing.T) {
+	tmp, err := utils.TestDirectory("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	store := mkTestTagStore(tmp, t)
+	defer store.graph.driver.Cleanup()
+
+	img, err := store.LookupImage(testImageName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(digest) != len("sha256:")+64 || digest[:7] != "sha256:" {
+		t.Fatalf("Unexpected digest %s", digest)
+	}
+	if again, err := img.Digest(); err != nil || again != digest {
+		t.Fatalf("Expected the digest to be stable, got %s then %s (%v)", digest, again, err)
+	}
+}
+
+func TestLookupImageByDigest(t *testing.T) {
+	tmp, err := utils.TestDirectory("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	store := mkTestTagStore(tmp, t)
+	defer store.graph.driver.Cleanup()
+
+	digest, err := store.graph.Digest(testImageID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img, err := store.LookupImage(testImageName + "@" + digest); err != nil {
+		t.Fatal(err)
+	} else if img == nil || img.ID != testImageID {
+		t.Errorf("Expected image %s for %s", testImageID, digest)
+	}
+	if _, err := store.LookupImage(testImageName + "@sha256:fail"); err == nil {
+		t.Errorf("Expected error, none found")
+	}
+
+	// an image pulled by digest is found without any tag
+	if err := store.SetDigest("other", digest, testImageID); err != nil {
+		t.Fatal(err)
+	}
+	if img, err := store.LookupImage("other@" + digest); err != nil {
+		t.Fatal(err)
+	} else if img == nil || img.ID != testImageID {
+		t.Errorf("Expected image %s for other@%s", testImageID, digest)
+	}
+	if deleted, err := store.Delete("other", digest); err != nil || !deleted {
+		t.Fatalf("Expected the digest to be deleted, got %v (%v)", deleted, err)
+	}
+	if _, err := store.LookupImage("other@" + digest); err == nil {
+		t.Errorf("Expected error, none found")
+	}
+
+	// and so is an image pulled by the digest of its v2 manifest
+	manifestDigest := "sha256:" + strings.Repeat("0", 64)
+	if err := store.SetManifestDigest("other", manifestDigest, testImageID); err != nil {
+		t.Fatal(err)
+	}
+	if img, err := store.LookupImage("other@" + manifestDigest); err != nil {
+		t.Fatal(err)
+	} else if img == nil || img.ID != testImageID {
+		t.Errorf("Expected image %s for other@%s", testImageID, manifestDigest)
+	}
+	if err := store.DeleteAll(testImageID); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.ManifestDigests) != 0 {
+		t.Errorf("Expected the manifest digests of the image to be deleted, got %v", store.ManifestDigests)
+	}
+}
+
+func TestManifestImageDigest(t *testing.T) {
+	tmp, err := utils.TestDirectory("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	store := mkTestTagStore(tmp, t)
+	defer store.graph.driver.Cleanup()
+
+	// the digest of a manifest, from the json of its images, is the one of
+	// the image once pulled
+	manifest := &registry.ManifestData{}
+	for img, err := store.graph.Get(testImageID); img != nil; img, err = img.GetParent() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		jsonRaw, err := img.RawJson()
+		if err != nil {
+			t.Fatal(err)
+		}
+		manifest.History = append(manifest.History, &registry.ManifestHistory{V1Compatibility: string(jsonRaw)})
+	}
+	digest, err := store.graph.Digest(testImageID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := manifestImageDigest(manifest); d != digest {
+		t.Fatalf("Expected the digest %s, got %s", digest, d)
+	}
+}
+
+func TestFindRemoteDigest(t *testing.T) {
+	tmp, err := utils.TestDirectory("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	store := mkTestTagStore(tmp, t)
+	defer store.graph.driver.Cleanup()
+
+	var (
+		histories = map[string][]string{
+			"child1": {"child1", "base"},
+			"child2": {"child2", "base"},
+		}
+		historyCalls []string
+		jsonCalls    []string
+	)
+	newTestRemoteImages := func() *remoteImages {
+		historyCalls, jsonCalls = nil, nil
+		ri := newRemoteImages(nil, store.graph, nil)
+		ri.getHistory = func(id, endpoint string) ([]string, error) {
+			if endpoint == "down" {
+				return nil, fmt.Errorf("%s is down", endpoint)
+			}
+			historyCalls = append(historyCalls, id)
+			return histories[id], nil
+		}
+		ri.getJSON = func(id, endpoint string) ([]byte, error) {
+			jsonCalls = append(jsonCalls, id)
+			return []byte(`{"id":"` + id + `"}`), nil
+		}
+		return ri
+	}
+	tagsList := map[string]string{"a": "child1", "b": "child2", "c": "child1"}
+	digest := image.HistoryDigest([][]byte{[]byte(`{"id":"child2"}`), []byte(`{"id":"base"}`)})
+
+	// one ancestry per image, and the JSON of the shared parent once
+	id, err := newTestRemoteImages().find([]string{"down", "up"}, tagsList, digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "child2" {
+		t.Fatalf("Expected child2, got %q", id)
+	}
+	if strings.Join(historyCalls, ",") != "child1,child2" {
+		t.Errorf("Expected the ancestries of child1 and child2, got %v", historyCalls)
+	}
+	if strings.Join(jsonCalls, ",") != "child1,base,child2" {
+		t.Errorf("Expected the JSON of each image once, got %v", jsonCalls)
+	}
+
+	// the first match stops the search
+	first := image.HistoryDigest([][]byte{[]byte(`{"id":"child1"}`), []byte(`{"id":"base"}`)})
+	if id, err := newTestRemoteImages().find([]string{"up"}, tagsList, first); err != nil || id != "child1" {
+		t.Fatalf("Expected child1, got %q (%v)", id, err)
+	}
+	if len(historyCalls) != 1 {
+		t.Errorf("Expected a single ancestry, got %v", historyCalls)
+	}
+
+	// the images of the graph are not fetched
+	local, err := store.graph.Digest(testImageID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err := newTestRemoteImages().find([]string{"up"}, map[string]string{"latest": testImageID}, local); err != nil || id != testImageID {
+		t.Fatalf("Expected %s, got %q (%v)", testImageID, id, err)
+	}
+	if len(historyCalls) != 0 || len(jsonCalls) != 0 {
+		t.Errorf("Expected no request, got %v and %v", historyCalls, jsonCalls)
+	}
+
+	if id, err := newTestRemoteImages().find([]string{"up"}, tagsList, "sha256:none"); err != nil || id != "" {
+		t.Fatalf("Expected no image, got %q (%v)", id, err)
+	}
+	if _, err := newTestRemoteImages().find([]string{"down"}, tagsList, digest); err == nil {
+		t.Fatal("Expected the error of the endpoint")
+	}
+}
+
+func TestCheckImplicitTag(t *testing.T) {
+	tmp, err := utils.TestDirectory("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	store := mkTestTagStore(tmp, t)
+	defer store.graph.driver.Cleanup()
+
+	if err := store.SetImplicitTagPolicy("sometimes"); err == nil {
+		t.Fatal("Expected an error for an invalid policy")
+	}
+	for _, policy := range []string{ImplicitTagAllow, ImplicitTagWarn, ImplicitTagDeny} {
+		if err := store.SetImplicitTagPolicy(policy); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{testImageName + ":" + DEFAULTTAG, testImageName + "@sha256:0123", testImageID} {
+			if warning, err := store.CheckImplicitTag(name); err != nil || warning != "" {
+				t.Errorf("Expected %s to be explicit with --implicit-tag=%s, got %q, %v", name, policy, warning, err)
+			}
+		}
+		warning, err := store.CheckImplicitTag(testImageName)
+		switch policy {
+		case ImplicitTagAllow:
+			if err != nil || warning != "" {
+				t.Errorf("Expected %s to be allowed, got %q, %v", testImageName, warning, err)
+			}
+		case ImplicitTagWarn:
+			if err != nil || warning == "" {
+				t.Errorf("Expected a warning for %s, got %q, %v", testImageName, warning, err)
+			}
+		case ImplicitTagDeny:
+			if err == nil {
+				t.Errorf("Expected %s to be denied", testImageName)
+			}
+		}
+	}
+}
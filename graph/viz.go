@@ -1,6 +1,7 @@
 package graph
 
 import (
+	"sort"
 	"strings"
 
 	"github.com/docker/docker/engine"
@@ -36,3 +37,111 @@ func (s *TagStore) CmdViz(job *engine.Job) engine.Status {
 	job.Stdout.Write([]byte(" base [style=invisible]\n}\n"))
 	return engine.StatusOK
 }
+
+type graphNode struct {
+	Id          string
+	ParentId    string
+	RepoTags    []string
+	Created     int64
+	Size        int64
+	VirtualSize int64
+}
+
+// A graphEdge links an image to one of its children; Size is the size of
+// the layer the child adds.
+type graphEdge struct {
+	Parent string
+	Child  string
+	Size   int64
+}
+
+// CmdImagesGraph outputs the parent/child graph of the images as JSON nodes
+// and edges. Given an image, the graph is limited to its parents and its
+// children.
+func (s *TagStore) CmdImagesGraph(job *engine.Job) engine.Status {
+	if len(job.Args) > 1 {
+		return job.Errorf("Usage: %s [IMAGE]", job.Name)
+	}
+	images, err := s.graph.Map()
+	if err != nil {
+		return job.Error(err)
+	}
+	if len(job.Args) == 1 {
+		img, err := s.LookupImage(job.Args[0])
+		if err != nil {
+			return job.Error(err)
+		}
+		if img == nil {
+			return job.Errorf("No such image: %s", job.Args[0])
+		}
+		images = relatedImages(images, img.ID)
+	}
+
+	nodes, edges := imagesGraph(images, s.ByID())
+	out := &engine.Env{}
+	out.SetJson("Nodes", nodes)
+	out.SetJson("Edges", edges)
+	if _, err := out.WriteTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+// relatedImages returns the image id with its parents and its children.
+func relatedImages(images map[string]*image.Image, id string) map[string]*image.Image {
+	related := make(map[string]*image.Image)
+	for img := images[id]; img != nil; img = images[img.Parent] {
+		related[img.ID] = img
+	}
+	byParent := make(map[string][]*image.Image)
+	for _, img := range images {
+		byParent[img.Parent] = append(byParent[img.Parent], img)
+	}
+	for queue := byParent[id]; len(queue) > 0; queue = queue[1:] {
+		related[queue[0].ID] = queue[0]
+		queue = append(queue, byParent[queue[0].ID]...)
+	}
+	return related
+}
+
+func imagesGraph(images map[string]*image.Image, tags map[string][]string) ([]*graphNode, []*graphEdge) {
+	virtualSizes := make(map[string]int64)
+	var virtualSize func(img *image.Image) int64
+	virtualSize = func(img *image.Image) int64 {
+		if size, exists := virtualSizes[img.ID]; exists {
+			return size
+		}
+		size := img.Size
+		if parent, exists := images[img.Parent]; exists {
+			size += virtualSize(parent)
+		}
+		virtualSizes[img.ID] = size
+		return size
+	}
+
+	ids := make([]string, 0, len(images))
+	for id := range images {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var (
+		nodes = make([]*graphNode, 0, len(ids))
+		edges = []*graphEdge{}
+	)
+	for _, id := range ids {
+		img := images[id]
+		nodes = append(nodes, &graphNode{
+			Id:          img.ID,
+			ParentId:    img.Parent,
+			RepoTags:    tags[img.ID],
+			Created:     img.Created.Unix(),
+			Size:        img.Size,
+			VirtualSize: virtualSize(img),
+		})
+		if _, exists := images[img.Parent]; exists {
+			edges = append(edges, &graphEdge{Parent: img.Parent, Child: img.ID, Size: img.Size})
+		}
+	}
+	return nodes, edges
+}
@@ -0,0 +1,34 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/docker/docker/image"
+)
+
+func TestImagesGraph(t *testing.T) {
+	images := map[string]*image.Image{
+		"base":   {ID: "base", Size: 100},
+		"python": {ID: "python", Parent: "base", Size: 20},
+		"web":    {ID: "web", Parent: "python", Size: 3},
+		"alpine": {ID: "alpine", Size: 7},
+	}
+
+	nodes, edges := imagesGraph(images, map[string][]string{"web": {"web:latest"}})
+	if len(nodes) != 4 {
+		t.Fatalf("Expected 4 nodes, got %d", len(nodes))
+	}
+	for _, node := range nodes {
+		if node.Id == "web" && (node.VirtualSize != 123 || len(node.RepoTags) != 1) {
+			t.Fatalf("Unexpected node %#v", node)
+		}
+	}
+	if len(edges) != 2 {
+		t.Fatalf("Expected 2 edges, got %d", len(edges))
+	}
+
+	related := relatedImages(images, "python")
+	if len(related) != 3 || related["alpine"] != nil {
+		t.Fatalf("Expected python to be related to base and web only, got %v", related)
+	}
+}
@@ -80,6 +80,7 @@ DEFAULT_BUNDLES=(
 	cover
 	cross
 	tgz
+	man
 	ubuntu
 )
 
@@ -136,6 +137,11 @@ LDFLAGS='
 	-X '$DOCKER_PKG'/dockerversion.GITCOMMIT "'$GITCOMMIT'"
 	-X '$DOCKER_PKG'/dockerversion.VERSION "'$VERSION'"
 '
+if [ "$DOCKER_EXPERIMENTAL" ]; then
+	LDFLAGS+='
+	-X '$DOCKER_PKG'/dockerversion.EXPERIMENTAL "true"
+'
+fi
 LDFLAGS_STATIC='-linkmode external'
 EXTLDFLAGS_STATIC='-static'
 BUILDFLAGS=( -a -tags "netgo static_build $DOCKER_BUILDTAGS" )
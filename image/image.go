@@ -1,6 +1,8 @@
 package image
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -172,6 +174,34 @@ func (img *Image) RawJson() ([]byte, error) {
 	return buf, nil
 }
 
+// Digest returns a sha256 digest of the json of the image and of all its
+// parents. The json of a layer never changes once registered, so the digest
+// identifies the exact content of the image, wherever its tags point later.
+func (img *Image) Digest() (string, error) {
+	var history [][]byte
+	if err := img.WalkHistory(func(img *Image) error {
+		jsonData, err := img.RawJson()
+		if err != nil {
+			return err
+		}
+		history = append(history, jsonData)
+		return nil
+	}); err != nil {
+		return "", err
+	}
+	return HistoryDigest(history), nil
+}
+
+// HistoryDigest returns the digest of an image from the json of the image
+// and of all its parents, the image first, as returned by Digest.
+func HistoryDigest(history [][]byte) string {
+	h := sha256.New()
+	for _, jsonData := range history {
+		h.Write(jsonData)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
 // TarLayer returns a tar archive of the image's filesystem layer.
 func (img *Image) TarLayer() (arch archive.Archive, err error) {
 	if img.graph == nil {
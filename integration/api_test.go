@@ -487,7 +487,8 @@ func TestPostContainersStart(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 
 	r := httptest.NewRecorder()
-	if err := server.ServeRequest(eng, api.APIVERSION, r, req); err != nil {
+	// the API 1.14 responds without the ports
+	if err := server.ServeRequest(eng, "1.14", r, req); err != nil {
 		t.Fatal(err)
 	}
 	assertHttpNotError(r, t)
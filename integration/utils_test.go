@@ -184,8 +184,10 @@ func newTestEngine(t log.Fataler, autorestart bool, root string) *engine.Engine
 		AutoRestart: autorestart,
 		ExecDriver:  "native",
 		// Either InterContainerCommunication or EnableIptables must be set,
-		// otherwise NewDaemon will fail because of conflicting settings.
+		// and either EnableUserlandProxy or EnableIptables, otherwise
+		// NewDaemon will fail because of conflicting settings.
 		InterContainerCommunication: true,
+		EnableUserlandProxy:         true,
 	}
 	d, err := daemon.NewDaemon(cfg, eng)
 	if err != nil {
@@ -88,7 +88,10 @@ func validateProto(proto string) bool {
 }
 
 // We will receive port specs in the format of ip:public:private/proto and these need to be
-// parsed in the internal types
+// parsed in the internal types. The ports may be ranges, as 8000-8100, which
+// are expanded into a binding per port: the host range must then be as long
+// as the container range, or the container port must be a single port, which
+// is bound to a free port of the host range.
 func ParsePortSpecs(ports []string) (map[Port]struct{}, map[Port][]PortBinding, error) {
 	var (
 		exposedPorts = make(map[Port]struct{}, len(ports))
@@ -125,31 +128,46 @@ func ParsePortSpecs(ports []string) (map[Port]struct{}, map[Port][]PortBinding,
 		if containerPort == "" {
 			return nil, nil, fmt.Errorf("No port specified: %s<empty>", rawPort)
 		}
-		if _, err := strconv.ParseUint(containerPort, 10, 16); err != nil {
+		startPort, endPort, err := parsers.ParsePortRange(containerPort)
+		if err != nil {
 			return nil, nil, fmt.Errorf("Invalid containerPort: %s", containerPort)
 		}
-		if _, err := strconv.ParseUint(hostPort, 10, 16); hostPort != "" && err != nil {
-			return nil, nil, fmt.Errorf("Invalid hostPort: %s", hostPort)
+		var startHostPort, endHostPort uint64
+		if hostPort != "" {
+			if startHostPort, endHostPort, err = parsers.ParsePortRange(hostPort); err != nil {
+				return nil, nil, fmt.Errorf("Invalid hostPort: %s", hostPort)
+			}
+			if startPort != endPort && endPort-startPort != endHostPort-startHostPort {
+				return nil, nil, fmt.Errorf("Invalid ranges specified for container and host ports: %s and %s", containerPort, hostPort)
+			}
 		}
 
 		if !validateProto(proto) {
 			return nil, nil, fmt.Errorf("Invalid proto: %s", proto)
 		}
 
-		port := NewPort(proto, containerPort)
-		if _, exists := exposedPorts[port]; !exists {
-			exposedPorts[port] = struct{}{}
-		}
-
-		binding := PortBinding{
-			HostIp:   rawIp,
-			HostPort: hostPort,
-		}
-		bslice, exists := bindings[port]
-		if !exists {
-			bslice = []PortBinding{}
+		for i := uint64(0); i <= endPort-startPort; i++ {
+			if startPort != endPort {
+				containerPort = strconv.FormatUint(startPort+i, 10)
+				if hostPort != "" {
+					hostPort = strconv.FormatUint(startHostPort+i, 10)
+				}
+			}
+			port := NewPort(proto, containerPort)
+			if _, exists := exposedPorts[port]; !exists {
+				exposedPorts[port] = struct{}{}
+			}
+
+			binding := PortBinding{
+				HostIp:   rawIp,
+				HostPort: hostPort,
+			}
+			bslice, exists := bindings[port]
+			if !exists {
+				bslice = []PortBinding{}
+			}
+			bindings[port] = append(bslice, binding)
 		}
-		bindings[port] = append(bslice, binding)
 	}
 	return exposedPorts, bindings, nil
 }
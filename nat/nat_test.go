@@ -187,3 +187,37 @@ func TestParsePortSpecs(t *testing.T) {
 		t.Fatal("Received no error while trying to parse a hostname instead of ip")
 	}
 }
+
+func TestParsePortSpecsWithRange(t *testing.T) {
+	portMap, bindingMap, err := ParsePortSpecs([]string{"1234-1236/tcp", "0.0.0.0:2345-2347:3345-3347/udp", "8000-8100:80"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[Port]PortBinding{
+		"1234/tcp": {},
+		"1235/tcp": {},
+		"1236/tcp": {},
+		"3345/udp": {HostIp: "0.0.0.0", HostPort: "2345"},
+		"3346/udp": {HostIp: "0.0.0.0", HostPort: "2346"},
+		"3347/udp": {HostIp: "0.0.0.0", HostPort: "2347"},
+		"80/tcp":   {HostPort: "8000-8100"},
+	}
+	if len(portMap) != len(expected) || len(bindingMap) != len(expected) {
+		t.Fatalf("Expected %d ports, got %v and %v", len(expected), portMap, bindingMap)
+	}
+	for port, binding := range expected {
+		if _, ok := portMap[port]; !ok {
+			t.Fatalf("%s should be exposed", port)
+		}
+		if bindings := bindingMap[port]; len(bindings) != 1 || bindings[0] != binding {
+			t.Fatalf("Expected %v for %s, got %v", binding, port, bindings)
+		}
+	}
+
+	for _, spec := range []string{"1234-1236:2345-2346", "2345:1234-1236", "1236-1234", "1234-:1234"} {
+		if _, _, err := ParsePortSpecs([]string{spec}); err == nil {
+			t.Fatalf("Expected an error for %s", spec)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package opts
 import (
 	"fmt"
 	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -11,6 +12,8 @@ import (
 	"github.com/docker/docker/api"
 	flag "github.com/docker/docker/pkg/mflag"
 	"github.com/docker/docker/pkg/parsers"
+	"github.com/docker/docker/pkg/ulimit"
+	"github.com/docker/docker/utils"
 )
 
 func ListVar(values *[]string, names []string, usage string) {
@@ -32,6 +35,14 @@ func DnsSearchListVar(values *[]string, names []string, usage string) {
 	flag.Var(newListOptsRef(values, ValidateDnsSearch), names, usage)
 }
 
+func UlimitListVar(values *[]string, names []string, usage string) {
+	flag.Var(newListOptsRef(values, ValidateUlimit), names, usage)
+}
+
+func MirrorListVar(values *[]string, names []string, usage string) {
+	flag.Var(newListOptsRef(values, ValidateMirror), names, usage)
+}
+
 func IPVar(value *net.IP, names []string, defaultValue, usage string) {
 	flag.Var(NewIpOpt(value, defaultValue), names, usage)
 }
@@ -127,10 +138,20 @@ func ValidateAttach(val string) (string, error) {
 	return val, fmt.Errorf("valid streams are STDIN, STDOUT and STDERR.")
 }
 
+// validLinkAlias matches the link aliases, which name a host entry and,
+// in upper case, the prefix of the environment variables of a link.
+var validLinkAlias = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// ValidateLink validates a link as NAME:ALIAS. A container can be linked
+// several times under different aliases.
 func ValidateLink(val string) (string, error) {
-	if _, err := parsers.PartParser("name:alias", val); err != nil {
+	parts, err := parsers.PartParser("name:alias", val)
+	if err != nil {
 		return val, err
 	}
+	if !validLinkAlias.MatchString(parts["alias"]) {
+		return val, fmt.Errorf("Invalid link alias %q in %s", parts["alias"], val)
+	}
 	return val, nil
 }
 
@@ -164,6 +185,14 @@ func ValidateEnv(val string) (string, error) {
 	return fmt.Sprintf("%s=%s", val, os.Getenv(val)), nil
 }
 
+// ValidateBuildContext validates an additional build context, as NAME=SOURCE.
+func ValidateBuildContext(val string) (string, error) {
+	if _, _, err := utils.ParseBuildContext(val); err != nil {
+		return "", err
+	}
+	return val, nil
+}
+
 func ValidateIPAddress(val string) (string, error) {
 	var ip = net.ParseIP(strings.TrimSpace(val))
 	if ip != nil {
@@ -172,6 +201,42 @@ func ValidateIPAddress(val string) (string, error) {
 	return "", fmt.Errorf("%s is not an ip address", val)
 }
 
+// Validates an entry of /etc/hosts given as HOST:IP
+func ValidateExtraHost(val string) (string, error) {
+	parts := strings.SplitN(val, ":", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+		return "", fmt.Errorf("bad format for add-host: %s", val)
+	}
+	if _, err := ValidateIPAddress(parts[1]); err != nil {
+		return "", fmt.Errorf("invalid IP address in add-host: %s", parts[1])
+	}
+	return val, nil
+}
+
+// Validates a ulimit given as NAME=SOFT[:HARD]
+func ValidateUlimit(val string) (string, error) {
+	if _, err := ulimit.Parse(val); err != nil {
+		return "", err
+	}
+	return val, nil
+}
+
+// Validates a registry mirror given as scheme://host[:port] and returns its
+// v1 endpoint.
+func ValidateMirror(val string) (string, error) {
+	uri, err := url.Parse(val)
+	if err != nil {
+		return "", fmt.Errorf("%s is not a valid URI", val)
+	}
+	if uri.Scheme != "http" && uri.Scheme != "https" {
+		return "", fmt.Errorf("Unsupported scheme %s for the mirror %s", uri.Scheme, val)
+	}
+	if (uri.Path != "" && uri.Path != "/") || uri.RawQuery != "" || uri.Fragment != "" {
+		return "", fmt.Errorf("The mirror %s must not have a path, a query or a fragment", val)
+	}
+	return fmt.Sprintf("%s://%s/v1/", uri.Scheme, uri.Host), nil
+}
+
 // Validates domain for resolvconf search configuration.
 // A zero length domain is represented by .
 func ValidateDnsSearch(val string) (string, error) {
@@ -181,6 +246,16 @@ func ValidateDnsSearch(val string) (string, error) {
 	return validateDomain(val)
 }
 
+// ValidateServiceAlias validates a service alias of the embedded DNS, a
+// domain name, and returns it in lower case as the resolver compares it.
+func ValidateServiceAlias(val string) (string, error) {
+	alias, err := validateDomain(val)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToLower(strings.TrimSuffix(alias, ".")), nil
+}
+
 func validateDomain(val string) (string, error) {
 	alpha := regexp.MustCompile(`[a-zA-Z]`)
 	if alpha.FindString(val) == "" {
@@ -27,12 +27,61 @@ func TestValidateIPAddress(t *testing.T) {
 
 }
 
+func TestValidateExtraHost(t *testing.T) {
+	for _, valid := range []string{`myhost:1.2.3.4`, `db.local:10.0.0.2`, `ipv6:2001:db8::1`} {
+		if ret, err := ValidateExtraHost(valid); err != nil || ret != valid {
+			t.Fatalf("ValidateExtraHost(`%s`) got %s %s", valid, ret, err)
+		}
+	}
+	for _, invalid := range []string{`myhost`, `:1.2.3.4`, `myhost:`, `myhost:1.2.3`} {
+		if ret, err := ValidateExtraHost(invalid); err == nil || ret != "" {
+			t.Fatalf("ValidateExtraHost(`%s`) got %s %s", invalid, ret, err)
+		}
+	}
+}
+
+func TestValidateLink(t *testing.T) {
+	for _, valid := range []string{`db:db`, `db:primary`, `/db:replica-check`, `db:db_1.local`} {
+		if ret, err := ValidateLink(valid); err != nil || ret != valid {
+			t.Fatalf("ValidateLink(`%s`) got %s %s", valid, ret, err)
+		}
+	}
+	for _, invalid := range []string{`db`, `db:`, `db:a/b`, `db:-db`, `db:a:b`} {
+		if _, err := ValidateLink(invalid); err == nil {
+			t.Fatalf("ValidateLink(`%s`) should have failed", invalid)
+		}
+	}
+}
+
 func TestListOpts(t *testing.T) {
 	o := NewListOpts(nil)
 	o.Set("foo")
 	o.String()
 }
 
+func TestValidateMirror(t *testing.T) {
+	valid := map[string]string{
+		"http://mirror.example.com":        "http://mirror.example.com/v1/",
+		"https://mirror.example.com:5000/": "https://mirror.example.com:5000/v1/",
+	}
+	for mirror, expected := range valid {
+		if ret, err := ValidateMirror(mirror); err != nil || ret != expected {
+			t.Fatalf("ValidateMirror(`%s`) got %s %s, expected %s", mirror, ret, err, expected)
+		}
+	}
+	invalid := []string{
+		"mirror.example.com",
+		"ftp://mirror.example.com",
+		"http://mirror.example.com/v1/",
+		"http://mirror.example.com?q=1",
+	}
+	for _, mirror := range invalid {
+		if ret, err := ValidateMirror(mirror); err == nil {
+			t.Fatalf("ValidateMirror(`%s`) should have failed, got %s", mirror, ret)
+		}
+	}
+}
+
 func TestValidateDnsSearch(t *testing.T) {
 	valid := []string{
 		`.`,
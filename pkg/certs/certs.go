@@ -0,0 +1,116 @@
+// Package certs generates the CA, server and client certificates needed to
+// protect a daemon listening on tcp:// with --tlsverify.
+package certs
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"time"
+)
+
+const keySize = 2048
+
+// KeyPair is a certificate and its private key.
+type KeyPair struct {
+	Cert *x509.Certificate
+	Key  *rsa.PrivateKey
+}
+
+// CertPEM returns the certificate PEM encoded.
+func (kp *KeyPair) CertPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: kp.Cert.Raw})
+}
+
+// KeyPEM returns the private key PEM encoded.
+func (kp *KeyPair) KeyPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(kp.Key)})
+}
+
+// NewCA returns a self signed certificate authority valid for the given
+// duration.
+func NewCA(name string, validity time.Duration) (*KeyPair, error) {
+	template, err := newTemplate(name, validity)
+	if err != nil {
+		return nil, err
+	}
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature
+	return sign(template, nil)
+}
+
+// NewServer returns a certificate signed by ca for a daemon reachable with
+// the given host names and IP addresses.
+func NewServer(ca *KeyPair, name string, hosts []string, validity time.Duration) (*KeyPair, error) {
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("A server certificate needs at least one host")
+	}
+	template, err := newTemplate(name, validity)
+	if err != nil {
+		return nil, err
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	return sign(template, ca)
+}
+
+// NewClient returns a certificate signed by ca for a client of the daemon.
+func NewClient(ca *KeyPair, name string, validity time.Duration) (*KeyPair, error) {
+	template, err := newTemplate(name, validity)
+	if err != nil {
+		return nil, err
+	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	return sign(template, ca)
+}
+
+func newTemplate(name string, validity time.Duration) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		// allow for some clock skew between the machines
+		NotBefore: now.Add(-time.Hour),
+		NotAfter:  now.Add(validity),
+	}, nil
+}
+
+// sign creates a new key and its certificate from template, signed by ca
+// or self signed when ca is nil.
+func sign(template *x509.Certificate, ca *KeyPair) (*KeyPair, error) {
+	key, err := rsa.GenerateKey(rand.Reader, keySize)
+	if err != nil {
+		return nil, err
+	}
+	parent, parentKey := template, key
+	if ca != nil {
+		parent, parentKey = ca.Cert, ca.Key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyPair{Cert: cert, Key: key}, nil
+}
@@ -0,0 +1,58 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+	"time"
+)
+
+func TestCertificates(t *testing.T) {
+	ca, err := NewCA("test CA", 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := NewServer(ca, "server", []string{"docker.example.com", "10.0.0.1"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewClient(ca, "client", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Cert)
+	for _, host := range []string{"docker.example.com", "10.0.0.1"} {
+		if _, err := server.Cert.Verify(x509.VerifyOptions{
+			DNSName:   host,
+			Roots:     roots,
+			KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		}); err != nil {
+			t.Fatalf("Expected the server certificate to be valid for %s: %s", host, err)
+		}
+	}
+	if _, err := server.Cert.Verify(x509.VerifyOptions{DNSName: "other.example.com", Roots: roots}); err == nil {
+		t.Fatal("Expected the server certificate not to be valid for another host")
+	}
+	if _, err := client.Cert.Verify(x509.VerifyOptions{
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		t.Fatalf("Expected the client certificate to be valid: %s", err)
+	}
+	if _, err := client.Cert.Verify(x509.VerifyOptions{
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}); err == nil {
+		t.Fatal("Expected the client certificate not to be valid for a server")
+	}
+
+	if _, err := tls.X509KeyPair(client.CertPEM(), client.KeyPEM()); err != nil {
+		t.Fatalf("Expected the PEM files to be loadable: %s", err)
+	}
+
+	if _, err := NewServer(ca, "server", nil, time.Hour); err == nil {
+		t.Fatal("Expected an error for a server certificate without hosts")
+	}
+}
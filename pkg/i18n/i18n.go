@@ -0,0 +1,95 @@
+// Package i18n translates the messages shown to the users with catalogs,
+// keyed by the English messages, chosen by the locale of the environment.
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Dir is the directory of the catalogs which are not registered, as
+// LANG.json, e.g. fr.json or pt_BR.json. Distributions can set it when
+// linking the client.
+var Dir = "/usr/share/docker/locale"
+
+// Catalog maps the messages to their translations.
+type Catalog map[string]string
+
+var (
+	catalogsLock sync.RWMutex
+	catalogs     = map[string]Catalog{}
+)
+
+// Register registers the catalog of a language, such as "fr" or "pt_BR".
+// A registered catalog takes precedence over the one of Dir.
+func Register(lang string, catalog Catalog) {
+	catalogsLock.Lock()
+	catalogs[lang] = catalog
+	catalogsLock.Unlock()
+}
+
+// Locale returns the locale of the messages, from LC_ALL, LC_MESSAGES or
+// LANG, in that order.
+func Locale() string {
+	for _, key := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// languages returns the languages of a locale, from the most specific:
+// "pt_BR.UTF-8" is pt_BR then pt. The C and POSIX locales have none.
+func languages(locale string) []string {
+	if i := strings.IndexAny(locale, ".@"); i >= 0 {
+		locale = locale[:i]
+	}
+	if locale == "" || locale == "C" || locale == "POSIX" {
+		return nil
+	}
+	langs := []string{locale}
+	if i := strings.Index(locale, "_"); i > 0 {
+		langs = append(langs, locale[:i])
+	}
+	return langs
+}
+
+// Find returns the catalog of the locale, registered or in Dir. Without
+// one, it returns nil, which translates every message to itself.
+func Find(locale string) (Catalog, error) {
+	for _, lang := range languages(locale) {
+		catalogsLock.RLock()
+		catalog, exists := catalogs[lang]
+		catalogsLock.RUnlock()
+		if exists {
+			return catalog, nil
+		}
+
+		content, err := ioutil.ReadFile(filepath.Join(Dir, lang+".json"))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(content, &catalog); err != nil {
+			return nil, fmt.Errorf("Invalid catalog %s: %s", filepath.Join(Dir, lang+".json"), err)
+		}
+		return catalog, nil
+	}
+	return nil, nil
+}
+
+// Translate returns the translation of msg, or msg when the catalog has
+// none.
+func (c Catalog) Translate(msg string) string {
+	if translation := c[msg]; translation != "" {
+		return translation
+	}
+	return msg
+}
@@ -0,0 +1,63 @@
+package i18n
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLanguages(t *testing.T) {
+	for locale, expected := range map[string][]string{
+		"":                  nil,
+		"C":                 nil,
+		"POSIX.UTF-8":       nil,
+		"fr":                {"fr"},
+		"pt_BR.UTF-8":       {"pt_BR", "pt"},
+		"de_DE@euro":        {"de_DE", "de"},
+		"sr_RS.UTF-8@latin": {"sr_RS", "sr"},
+	} {
+		if langs := languages(locale); !reflect.DeepEqual(langs, expected) {
+			t.Errorf("%q: expected %v, got %v", locale, expected, langs)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-i18n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(dir string) { Dir = dir }(Dir)
+	Dir = dir
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "fr.json"), []byte(`{"List containers":"Lister les conteneurs"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	Register("pt_BR", Catalog{"List containers": "Listar contêineres"})
+	defer delete(catalogs, "pt_BR")
+
+	for locale, expected := range map[string]string{
+		"fr_FR.UTF-8": "Lister les conteneurs",
+		"pt_BR.UTF-8": "Listar contêineres",
+		"pt_PT.UTF-8": "List containers",
+		"C":           "List containers",
+	} {
+		catalog, err := Find(locale)
+		if err != nil {
+			t.Fatalf("%q: %s", locale, err)
+		}
+		if msg := catalog.Translate("List containers"); msg != expected {
+			t.Errorf("%q: expected %q, got %q", locale, expected, msg)
+		}
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "de.json"), []byte(`{`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Find("de_DE"); err == nil {
+		t.Fatal("Expected an error for an invalid catalog")
+	}
+}
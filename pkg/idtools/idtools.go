@@ -0,0 +1,161 @@
+// Package idtools maps the user and group IDs of the containers running in
+// a user namespace to the IDs of the host, as given by the subordinate IDs
+// of a user in /etc/subuid and /etc/subgid (see subuid(5)).
+package idtools
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// IDMap maps the Size IDs from ContainerID in the container to the ones from
+// HostID on the host.
+type IDMap struct {
+	ContainerID int
+	HostID      int
+	Size        int
+}
+
+// The files of the subordinate IDs, variables for the tests.
+var (
+	SubuidPath = "/etc/subuid"
+	SubgidPath = "/etc/subgid"
+)
+
+// OverflowID is the ID the IDs of the host which are not mapped in the
+// container are shown as, as the kernel does.
+const OverflowID = 65534
+
+// NewIDMappings returns the mappings of the subordinate IDs of username and
+// groupname. The ranges of each are mapped one after the other from the ID 0
+// of the container.
+func NewIDMappings(username, groupname string) ([]IDMap, []IDMap, error) {
+	uidMaps, err := parseSubIDs(SubuidPath, username)
+	if err != nil {
+		return nil, nil, err
+	}
+	gidMaps, err := parseSubIDs(SubgidPath, groupname)
+	if err != nil {
+		return nil, nil, err
+	}
+	return uidMaps, gidMaps, nil
+}
+
+// parseSubIDs reads the ranges of name in a file of subordinate IDs, made of
+// lines such as "dockremap:100000:65536".
+func parseSubIDs(path, name string) ([]IDMap, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var (
+		maps    []IDMap
+		next    int
+		scanner = bufio.NewScanner(f)
+	)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.Split(line, ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("Invalid line in %s: %s", path, line)
+		}
+		if parts[0] != name {
+			continue
+		}
+		start, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid start of a range in %s: %s", path, line)
+		}
+		size, err := strconv.Atoi(parts[2])
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("Invalid size of a range in %s: %s", path, line)
+		}
+		maps = append(maps, IDMap{ContainerID: next, HostID: start, Size: size})
+		next += size
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(maps) == 0 {
+		return nil, fmt.Errorf("No subordinate IDs of %s in %s", name, path)
+	}
+	return maps, nil
+}
+
+// ToHost returns the ID of the host the ID of the container id is mapped to.
+func ToHost(id int, maps []IDMap) (int, error) {
+	if maps == nil {
+		return id, nil
+	}
+	for _, m := range maps {
+		if id >= m.ContainerID && id < m.ContainerID+m.Size {
+			return m.HostID + id - m.ContainerID, nil
+		}
+	}
+	return -1, fmt.Errorf("The ID %d is not mapped to the host", id)
+}
+
+// ToContainer returns the ID of the container the ID of the host id is
+// mapped to, or OverflowID when it is not mapped.
+func ToContainer(id int, maps []IDMap) int {
+	if maps == nil {
+		return id
+	}
+	for _, m := range maps {
+		if id >= m.HostID && id < m.HostID+m.Size {
+			return m.ContainerID + id - m.HostID
+		}
+	}
+	return OverflowID
+}
+
+// RootPair returns the uid and gid of the host the root of the container is
+// mapped to.
+func RootPair(uidMaps, gidMaps []IDMap) (int, int, error) {
+	uid, err := ToHost(0, uidMaps)
+	if err != nil {
+		return -1, -1, err
+	}
+	gid, err := ToHost(0, gidMaps)
+	if err != nil {
+		return -1, -1, err
+	}
+	return uid, gid, nil
+}
+
+// ChownPath gives uid and gid the directories between root, excluded, and
+// path, included, which are owned by the root of the host, so that the root
+// of the containers can reach path. root must be a parent of path.
+func ChownPath(root, path string, uid, gid int) error {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return err
+	}
+	if rel == "." || strings.HasPrefix(rel, "..") {
+		return nil
+	}
+	current := root
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		fi, err := os.Lstat(current)
+		if err != nil {
+			return err
+		}
+		if stat, ok := fi.Sys().(*syscall.Stat_t); ok && stat.Uid == 0 {
+			if err := os.Lchown(current, uid, gid); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package idtools
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestNewIDMappings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-idtools-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	SubuidPath = filepath.Join(dir, "subuid")
+	SubgidPath = filepath.Join(dir, "subgid")
+	if err := ioutil.WriteFile(SubuidPath, []byte("other:200000:65536\ndockremap:100000:1000\ndockremap:300000:64536\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(SubgidPath, []byte("# groups\ndockremap:100000:65536\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	uidMaps, gidMaps, err := NewIDMappings("dockremap", "dockremap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(uidMaps) != 2 || uidMaps[1] != (IDMap{ContainerID: 1000, HostID: 300000, Size: 64536}) {
+		t.Fatalf("Expected the two ranges of dockremap one after the other, got %v", uidMaps)
+	}
+	if uid, gid, err := RootPair(uidMaps, gidMaps); err != nil || uid != 100000 || gid != 100000 {
+		t.Fatalf("Expected the root pair 100000:100000, got %d:%d %v", uid, gid, err)
+	}
+	if id, err := ToHost(1500, uidMaps); err != nil || id != 300500 {
+		t.Fatalf("Expected 1500 to be mapped to 300500, got %d %v", id, err)
+	}
+	if _, err := ToHost(65536, uidMaps); err == nil {
+		t.Fatal("Expected an error for an ID out of the ranges")
+	}
+	if id := ToContainer(300500, uidMaps); id != 1500 {
+		t.Fatalf("Expected 300500 to be mapped to 1500, got %d", id)
+	}
+	if id := ToContainer(0, uidMaps); id != OverflowID {
+		t.Fatalf("Expected the root of the host to be the overflow ID, got %d", id)
+	}
+	if _, _, err := NewIDMappings("missing", "dockremap"); err == nil {
+		t.Fatal("Expected an error for a user without subordinate IDs")
+	}
+}
+
+func TestChownPath(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("chown needs root")
+	}
+	dir, err := ioutil.TempDir("", "docker-idtools-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	leaf := filepath.Join(dir, "a", "b")
+	if err := os.MkdirAll(leaf, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ChownPath(dir, leaf, 100000, 100000); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{filepath.Join(dir, "a"), leaf} {
+		fi, err := os.Stat(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stat := fi.Sys().(*syscall.Stat_t); stat.Uid != 100000 || stat.Gid != 100000 {
+			t.Fatalf("Expected %s to be owned by 100000:100000, got %d:%d", p, stat.Uid, stat.Gid)
+		}
+	}
+	fi, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat := fi.Sys().(*syscall.Stat_t); stat.Uid != 0 {
+		t.Fatal("Expected the root not to be changed")
+	}
+}
@@ -26,27 +26,37 @@ var (
 type Chain struct {
 	Name   string
 	Bridge string
+	// HairpinMode forwards the published ports without a userland proxy:
+	// the traffic from the host itself and from the containers of Bridge
+	// is forwarded by the rules of the chain too.
+	HairpinMode bool
 }
 
 func init() {
 	supportsXlock = exec.Command("iptables", "--wait", "-L", "-n").Run() == nil
 }
 
-func NewChain(name, bridge string) (*Chain, error) {
+func NewChain(name, bridge string, hairpinMode bool) (*Chain, error) {
 	if output, err := Raw("-t", "nat", "-N", name); err != nil {
 		return nil, err
 	} else if len(output) != 0 {
 		return nil, fmt.Errorf("Error creating new iptables chain: %s", output)
 	}
 	chain := &Chain{
-		Name:   name,
-		Bridge: bridge,
+		Name:        name,
+		Bridge:      bridge,
+		HairpinMode: hairpinMode,
 	}
 
 	if err := chain.Prerouting(Add, "-m", "addrtype", "--dst-type", "LOCAL"); err != nil {
 		return nil, fmt.Errorf("Failed to inject docker in PREROUTING chain: %s", err)
 	}
-	if err := chain.Output(Add, "-m", "addrtype", "--dst-type", "LOCAL", "!", "--dst", "127.0.0.0/8"); err != nil {
+	outputArgs := []string{"-m", "addrtype", "--dst-type", "LOCAL"}
+	if !hairpinMode {
+		// the userland proxy serves the loopback addresses
+		outputArgs = append(outputArgs, "!", "--dst", "127.0.0.0/8")
+	}
+	if err := chain.Output(Add, outputArgs...); err != nil {
 		return nil, fmt.Errorf("Failed to inject docker in OUTPUT chain: %s", err)
 	}
 	return chain, nil
@@ -67,13 +77,17 @@ func (c *Chain) Forward(action Action, ip net.IP, port int, proto, dest_addr str
 		// value" by both iptables and ip6tables.
 		daddr = "0/0"
 	}
-	if output, err := Raw("-t", "nat", fmt.Sprint(action), c.Name,
+	args := []string{"-t", "nat", fmt.Sprint(action), c.Name,
 		"-p", proto,
 		"-d", daddr,
-		"--dport", strconv.Itoa(port),
-		"!", "-i", c.Bridge,
-		"-j", "DNAT",
-		"--to-destination", net.JoinHostPort(dest_addr, strconv.Itoa(dest_port))); err != nil {
+		"--dport", strconv.Itoa(port)}
+	if !c.HairpinMode {
+		// the userland proxy serves the containers of the bridge
+		args = append(args, "!", "-i", c.Bridge)
+	}
+	args = append(args, "-j", "DNAT",
+		"--to-destination", net.JoinHostPort(dest_addr, strconv.Itoa(dest_port)))
+	if output, err := Raw(args...); err != nil {
 		return err
 	} else if len(output) != 0 {
 		return fmt.Errorf("Error iptables forward: %s", output)
@@ -95,6 +109,21 @@ func (c *Chain) Forward(action Action, ip net.IP, port int, proto, dest_addr str
 		return fmt.Errorf("Error iptables forward: %s", output)
 	}
 
+	if c.HairpinMode {
+		// a container reaching its own published port gets the replies
+		// from the address it connected to
+		if output, err := Raw("-t", "nat", fmt.Sprint(action), "POSTROUTING",
+			"-p", proto,
+			"-s", dest_addr,
+			"-d", dest_addr,
+			"--dport", strconv.Itoa(dest_port),
+			"-j", "MASQUERADE"); err != nil {
+			return err
+		} else if len(output) != 0 {
+			return fmt.Errorf("Error iptables forward: %s", output)
+		}
+	}
+
 	return nil
 }
 
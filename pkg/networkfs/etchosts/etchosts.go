@@ -1,9 +1,11 @@
 package etchosts
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
 	"io/ioutil"
+	"strings"
 )
 
 var defaultContent = map[string]string{
@@ -41,3 +43,42 @@ func Build(path, IP, hostname, domainname string, extraContent *map[string]strin
 
 	return ioutil.WriteFile(path, content.Bytes(), 0644)
 }
+
+// Add appends an entry for hostname to the hosts file at path, replacing
+// any previous entry for the same hostname.
+func Add(path, IP, hostname string) error {
+	content, err := removeHost(path, hostname)
+	if err != nil {
+		return err
+	}
+	content.WriteString(fmt.Sprintf("%s\t%s\n", IP, hostname))
+	return ioutil.WriteFile(path, content.Bytes(), 0644)
+}
+
+// Remove deletes the entries for hostname from the hosts file at path.
+func Remove(path, hostname string) error {
+	content, err := removeHost(path, hostname)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, content.Bytes(), 0644)
+}
+
+// removeHost returns the content of the hosts file at path without the
+// lines mapping hostname.
+func removeHost(path, hostname string) (*bytes.Buffer, error) {
+	old, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	content := bytes.NewBuffer(nil)
+	scanner := bufio.NewScanner(bytes.NewReader(old))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if fields := strings.Fields(line); len(fields) == 2 && fields[1] == hostname {
+			continue
+		}
+		content.WriteString(line + "\n")
+	}
+	return content, scanner.Err()
+}
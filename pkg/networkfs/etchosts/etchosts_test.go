@@ -72,3 +72,46 @@ func TestBuildNoIP(t *testing.T) {
 		t.Fatalf("Expected to find '%s' got '%s'", expected, content)
 	}
 }
+
+func TestAddRemove(t *testing.T) {
+	file, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+
+	if err := Build(file.Name(), "10.11.12.13", "testhostname", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := Add(file.Name(), "10.11.12.14", "peer"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Add(file.Name(), "10.11.12.15", "peer"); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "10.11.12.15\tpeer\n"; !bytes.Contains(content, []byte(expected)) {
+		t.Fatalf("Expected to find '%s' got '%s'", expected, content)
+	}
+	if unexpected := "10.11.12.14\tpeer\n"; bytes.Contains(content, []byte(unexpected)) {
+		t.Fatalf("Did not expect to find '%s' got '%s'", unexpected, content)
+	}
+
+	if err := Remove(file.Name(), "peer"); err != nil {
+		t.Fatal(err)
+	}
+	content, err = ioutil.ReadFile(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(content, []byte("peer")) {
+		t.Fatalf("Expected peer to be removed, got '%s'", content)
+	}
+	if expected := "10.11.12.13\ttesthostname\n"; !bytes.Contains(content, []byte(expected)) {
+		t.Fatalf("Expected to find '%s' got '%s'", expected, content)
+	}
+}
@@ -28,6 +28,11 @@ func ParseHost(defaultHost string, defaultUnix, addr string) (string, error) {
 		addr = strings.TrimPrefix(addr, "tcp://")
 	case strings.HasPrefix(addr, "fd://"):
 		return addr, nil
+	case strings.HasPrefix(addr, "npipe://"):
+		if addr == "npipe://" {
+			return "", fmt.Errorf("Invalid bind address format: %s", addr)
+		}
+		return addr, nil
 	case addr == "":
 		proto = "unix"
 		addr = defaultUnix
@@ -66,10 +71,14 @@ func ParseHost(defaultHost string, defaultUnix, addr string) (string, error) {
 	return fmt.Sprintf("%s://%s:%d", proto, host, port), nil
 }
 
-// Get a repos name and returns the right reposName + tag
+// Get a repos name and returns the right reposName + tag, or digest when
+// given as NAME@DIGEST.
 // The tag can be confusing because of a port in a repository name.
 //     Ex: localhost.localdomain:5000/samalba/hipache:latest
 func ParseRepositoryTag(repos string) (string, string) {
+	if n := strings.Index(repos, "@"); n >= 0 {
+		return repos[:n], repos[n+1:]
+	}
 	n := strings.LastIndex(repos, ":")
 	if n < 0 {
 		return repos, ""
@@ -108,3 +117,34 @@ func ParseKeyValueOpt(opt string) (string, string, error) {
 	}
 	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), nil
 }
+
+// ParsePortRange parses a port, as 80, or a range of ports, as 8000-8100,
+// and returns its first and last ports.
+func ParsePortRange(ports string) (uint64, uint64, error) {
+	if ports == "" {
+		return 0, 0, fmt.Errorf("Empty string specified for ports")
+	}
+	if !strings.Contains(ports, "-") {
+		port, err := strconv.ParseUint(ports, 10, 16)
+		if err != nil {
+			return 0, 0, err
+		}
+		return port, port, nil
+	}
+	parts := strings.Split(ports, "-")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("Invalid range specified for the ports: %s", ports)
+	}
+	start, err := strconv.ParseUint(parts[0], 10, 16)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := strconv.ParseUint(parts[1], 10, 16)
+	if err != nil {
+		return 0, 0, err
+	}
+	if end < start {
+		return 0, 0, fmt.Errorf("Invalid range specified for the ports: %s", ports)
+	}
+	return start, end, nil
+}
@@ -33,6 +33,12 @@ func TestParseHost(t *testing.T) {
 	if addr, err := ParseHost(defaultHttpHost, defaultUnix, "unix://"); err != nil || addr != "unix:///var/run/docker.sock" {
 		t.Errorf("unix:///var/run/docker.sock -> expected unix:///var/run/docker.sock, got %s", addr)
 	}
+	if addr, err := ParseHost(defaultHttpHost, defaultUnix, "npipe:////./pipe/docker_engine"); err != nil || addr != "npipe:////./pipe/docker_engine" {
+		t.Errorf("npipe:////./pipe/docker_engine -> expected npipe:////./pipe/docker_engine, got %s", addr)
+	}
+	if addr, err := ParseHost(defaultHttpHost, defaultUnix, "npipe://"); err == nil {
+		t.Errorf("npipe:// address expected error return, but err == nil, got %s", addr)
+	}
 	if addr, err := ParseHost(defaultHttpHost, defaultUnix, "udp://127.0.0.1"); err == nil {
 		t.Errorf("udp protocol address expected error return, but err == nil. Got %s", addr)
 	}
@@ -60,6 +66,9 @@ func TestParseRepositoryTag(t *testing.T) {
 	if repo, tag := ParseRepositoryTag("url:5000/repo:tag"); repo != "url:5000/repo" || tag != "tag" {
 		t.Errorf("Expected repo: '%s' and tag: '%s', got '%s' and '%s'", "url:5000/repo", "tag", repo, tag)
 	}
+	if repo, digest := ParseRepositoryTag("url:5000/repo@sha256:abc"); repo != "url:5000/repo" || digest != "sha256:abc" {
+		t.Errorf("Expected repo: '%s' and digest: '%s', got '%s' and '%s'", "url:5000/repo", "sha256:abc", repo, digest)
+	}
 }
 
 func TestParsePortMapping(t *testing.T) {
@@ -81,3 +90,24 @@ func TestParsePortMapping(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestParsePortRange(t *testing.T) {
+	for ports, expected := range map[string][2]uint64{
+		"80":        {80, 80},
+		"8000-8100": {8000, 8100},
+		"53-53":     {53, 53},
+	} {
+		start, end, err := ParsePortRange(ports)
+		if err != nil {
+			t.Fatalf("%s: %s", ports, err)
+		}
+		if start != expected[0] || end != expected[1] {
+			t.Fatalf("%s: expected %v, got %d-%d", ports, expected, start, end)
+		}
+	}
+	for _, ports := range []string{"", "-1", "80-", "8100-8000", "1-2-3", "a-b", "65536", "1-65536"} {
+		if _, _, err := ParsePortRange(ports); err == nil {
+			t.Fatalf("Expected an error for %q", ports)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+// Package plugins discovers the plugins extending the daemon and manages
+// their lifecycle. A plugin is an external process speaking JSON over HTTP,
+// found by its spec in one of SpecsPaths:
+//
+//   - NAME.sock, the unix socket the plugin listens on
+//   - NAME.spec, a file holding the address of the plugin, such as
+//     unix:///path/to/plugin.sock or tcp://10.0.0.5:8080
+//
+// A plugin is activated the first time a subsystem of the daemon asks for
+// it: the handshake POST /Plugin.Activate returns the interfaces it
+// implements, e.g. {"Implements": ["VolumeDriver"]}. The activated plugins
+// are checked with the same handshake, and activated again on their next
+// use when they failed.
+package plugins
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/pkg/log"
+)
+
+// SpecsPaths are the directories the specs of the plugins are looked up in,
+// in order: the first spec found for a name wins.
+var SpecsPaths = []string{"/run/docker/plugins", "/etc/docker/plugins", "/usr/lib/docker/plugins"}
+
+const callTimeout = 30 * time.Second
+
+var (
+	ErrNotFound      = errors.New("plugin not found")
+	ErrNotImplements = errors.New("plugin does not implement the requested interface")
+
+	plugins = make(map[string]*Plugin)
+	lock    sync.Mutex
+)
+
+// The status of a plugin.
+const (
+	StatusInactive  = "inactive"
+	StatusActive    = "active"
+	StatusUnhealthy = "unhealthy"
+)
+
+// Plugin is a plugin found in SpecsPaths.
+type Plugin struct {
+	Name string
+	Addr string
+	// Implements are the interfaces of the plugin, known once it is
+	// activated. The plugins written before the handshake implement any
+	// interface they are asked for.
+	Implements []string
+	Status     string
+	// Err is why the last activation or health check failed
+	Err string
+
+	legacy bool
+	client *http.Client
+}
+
+type activateResponse struct {
+	Implements []string
+}
+
+func newPlugin(name, addr string) (*Plugin, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	var network, address string
+	switch u.Scheme {
+	case "unix":
+		network, address = "unix", u.Path
+	case "tcp":
+		network, address = "tcp", u.Host
+	default:
+		return nil, fmt.Errorf("Unsupported address %s for plugin %s", addr, name)
+	}
+	return &Plugin{
+		Name:   name,
+		Addr:   addr,
+		Status: StatusInactive,
+		client: &http.Client{
+			Transport: &http.Transport{
+				Dial: func(string, string) (net.Conn, error) {
+					return net.DialTimeout(network, address, callTimeout)
+				},
+			},
+		},
+	}, nil
+}
+
+// Call posts args, in JSON, to the method of the plugin, such as
+// VolumeDriver.Mount, and decodes the response in ret.
+func (p *Plugin) Call(method string, args, ret interface{}) error {
+	body, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	// the host is ignored, the transport always dials the plugin
+	resp, err := p.client.Post("http://plugin/"+method, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &callError{method, resp.StatusCode, string(bytes.TrimSpace(data))}
+	}
+	if err := json.Unmarshal(data, ret); err != nil {
+		return fmt.Errorf("invalid %s response: %s", method, err)
+	}
+	return nil
+}
+
+type callError struct {
+	method string
+	code   int
+	body   string
+}
+
+func (e *callError) Error() string {
+	return fmt.Sprintf("%s returned %d: %s", e.method, e.code, e.body)
+}
+
+// handshake asks the plugin for the interfaces it implements. The plugins
+// written before the handshake answer it with a 404, and are legacy.
+func (p *Plugin) handshake() (implements []string, legacy bool, err error) {
+	var resp activateResponse
+	if err := p.Call("Plugin.Activate", struct{}{}, &resp); err != nil {
+		if cerr, ok := err.(*callError); ok && cerr.code == http.StatusNotFound {
+			return nil, true, nil
+		}
+		return nil, false, err
+	}
+	return resp.Implements, false, nil
+}
+
+// activate does the handshake of an inactive or unhealthy plugin. The lock
+// is held.
+func (p *Plugin) activate() error {
+	if p.Status == StatusActive {
+		return nil
+	}
+	implements, legacy, err := p.handshake()
+	if err != nil {
+		p.Status = StatusUnhealthy
+		p.Err = err.Error()
+		return fmt.Errorf("Error activating plugin %s: %s", p.Name, err)
+	}
+	p.Implements, p.legacy = implements, legacy
+	p.Status = StatusActive
+	p.Err = ""
+	return nil
+}
+
+func (p *Plugin) implements(iface string) bool {
+	if p.legacy {
+		return true
+	}
+	for _, i := range p.Implements {
+		if i == iface {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup returns the plugin called name, discovering it from its spec the
+// first time. The lock is held.
+func lookup(name string) (*Plugin, error) {
+	if p, exists := plugins[name]; exists {
+		return p, nil
+	}
+	for _, dir := range SpecsPaths {
+		addr, err := readSpec(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		if addr == "" {
+			continue
+		}
+		p, err := newPlugin(name, addr)
+		if err != nil {
+			return nil, err
+		}
+		plugins[name] = p
+		return p, nil
+	}
+	return nil, ErrNotFound
+}
+
+// readSpec returns the address of the plugin called name in dir, or "" when
+// it has no spec there.
+func readSpec(dir, name string) (string, error) {
+	socket := filepath.Join(dir, name+".sock")
+	if fi, err := os.Stat(socket); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		return "unix://" + socket, nil
+	}
+	content, err := ioutil.ReadFile(filepath.Join(dir, name+".spec"))
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	addr := strings.TrimSpace(string(content))
+	if addr == "" {
+		return "", fmt.Errorf("Empty spec for plugin %s in %s", name, dir)
+	}
+	return addr, nil
+}
+
+// Get returns the plugin called name implementing iface, such as
+// VolumeDriver, activating it if needed.
+func Get(name, iface string) (*Plugin, error) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	p, err := lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.activate(); err != nil {
+		return nil, err
+	}
+	if !p.implements(iface) {
+		return nil, ErrNotImplements
+	}
+	return p, nil
+}
+
+// List returns the plugins found in SpecsPaths, sorted by name, without
+// activating them.
+func List() ([]Plugin, error) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	for _, dir := range SpecsPaths {
+		entries, err := ioutil.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		for _, fi := range entries {
+			ext := filepath.Ext(fi.Name())
+			if ext != ".sock" && ext != ".spec" {
+				continue
+			}
+			if _, err := lookup(strings.TrimSuffix(fi.Name(), ext)); err != nil && err != ErrNotFound {
+				log.Debugf("Error discovering plugin %s: %s", fi.Name(), err)
+			}
+		}
+	}
+
+	list := make([]Plugin, 0, len(plugins))
+	for _, p := range plugins {
+		list = append(list, *p)
+	}
+	sort.Sort(byName(list))
+	return list, nil
+}
+
+type byName []Plugin
+
+func (s byName) Len() int           { return len(s) }
+func (s byName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byName) Less(i, j int) bool { return s[i].Name < s[j].Name }
+
+// CheckHealth does the handshake of the active plugins again. The plugins
+// which fail are unhealthy until they are activated again on their next
+// use.
+func CheckHealth() {
+	lock.Lock()
+	var active []*Plugin
+	for _, p := range plugins {
+		if p.Status == StatusActive {
+			active = append(active, p)
+		}
+	}
+	lock.Unlock()
+
+	// the lock is not held while waiting for the plugins
+	for _, p := range active {
+		implements, legacy, err := p.handshake()
+		lock.Lock()
+		if err != nil {
+			log.Errorf("Plugin %s is unhealthy: %s", p.Name, err)
+			p.Status = StatusUnhealthy
+			p.Err = err.Error()
+		} else {
+			p.Implements, p.legacy = implements, legacy
+		}
+		lock.Unlock()
+	}
+}
+
+// WatchHealth checks the health of the active plugins at each interval.
+func WatchHealth(interval time.Duration) {
+	for _ = range time.Tick(interval) {
+		CheckHealth()
+	}
+}
@@ -0,0 +1,103 @@
+package plugins
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func serve(t *testing.T, socket string, implements []string) net.Listener {
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	if implements != nil {
+		mux.HandleFunc("/Plugin.Activate", func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(&activateResponse{Implements: implements})
+		})
+	}
+	// a stopped plugin closes its connections too
+	srv := &http.Server{Handler: mux}
+	srv.SetKeepAlivesEnabled(false)
+	go srv.Serve(l)
+	return l
+}
+
+func TestPlugins(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-plugins-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	SpecsPaths = []string{dir}
+
+	defer serve(t, filepath.Join(dir, "volumes.sock"), []string{"VolumeDriver"}).Close()
+	// a plugin written before the handshake
+	defer serve(t, filepath.Join(dir, "legacy.sock"), nil).Close()
+	// a plugin found by its spec, which does not run
+	if err := ioutil.WriteFile(filepath.Join(dir, "gone.spec"), []byte("unix://"+filepath.Join(dir, "gone")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 || list[0].Name != "gone" || list[1].Name != "legacy" || list[2].Name != "volumes" {
+		t.Fatalf("Expected the plugins gone, legacy and volumes, got %v", list)
+	}
+	for _, p := range list {
+		if p.Status != StatusInactive {
+			t.Fatalf("Expected %s not to be activated by List, got %s", p.Name, p.Status)
+		}
+	}
+
+	if _, err := Get("missing", "VolumeDriver"); err != ErrNotFound {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+	if _, err := Get("volumes", "NetworkDriver"); err != ErrNotImplements {
+		t.Fatalf("Expected ErrNotImplements, got %v", err)
+	}
+	if p, err := Get("volumes", "VolumeDriver"); err != nil || p.Status != StatusActive {
+		t.Fatalf("Expected an active plugin, got %v %v", p, err)
+	}
+	if _, err := Get("legacy", "VolumeDriver"); err != nil {
+		t.Fatalf("Expected a legacy plugin to implement any interface, got %v", err)
+	}
+	if _, err := Get("gone", "VolumeDriver"); err == nil {
+		t.Fatal("Expected an error activating a plugin which does not run")
+	}
+	if list, _ := List(); list[0].Status != StatusUnhealthy || list[0].Err == "" {
+		t.Fatalf("Expected gone to be unhealthy, got %v", list[0])
+	}
+}
+
+func TestCheckHealth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-plugins-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	SpecsPaths = []string{dir}
+
+	l := serve(t, filepath.Join(dir, "flaky.sock"), []string{"VolumeDriver"})
+	if _, err := Get("flaky", "VolumeDriver"); err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+	CheckHealth()
+	if p := plugins["flaky"]; p.Status != StatusUnhealthy {
+		t.Fatalf("Expected the stopped plugin to be unhealthy, got %s", p.Status)
+	}
+
+	// the plugin is activated again on its next use
+	defer serve(t, filepath.Join(dir, "flaky.sock"), []string{"VolumeDriver"}).Close()
+	if p, err := Get("flaky", "VolumeDriver"); err != nil || p.Status != StatusActive {
+		t.Fatalf("Expected the plugin to be activated again, got %v %v", p, err)
+	}
+}
@@ -0,0 +1,360 @@
+package seccomp
+
+// defaultAllowed are the system calls allowed by the default profile. Are
+// left out the ones which change the host rather than the container, such
+// as the kernel modules, the clock, the mounts, the swap, the keyrings or
+// the reboot, and the ones which reach other processes or namespaces, such
+// as ptrace, setns, unshare and process_vm_readv. The system calls of the
+// 32-bit binaries, such as socketcall or mmap2, are allowed as their 64-bit
+// equivalents.
+var defaultAllowed = []string{
+	"accept",
+	"accept4",
+	"access",
+	"alarm",
+	"arch_prctl",
+	"bind",
+	"brk",
+	"capget",
+	"capset",
+	"chdir",
+	"chmod",
+	"chown",
+	"chown32",
+	"chroot",
+	"clock_getres",
+	"clock_gettime",
+	"clock_nanosleep",
+	"close",
+	"connect",
+	"creat",
+	"dup",
+	"dup2",
+	"dup3",
+	"epoll_create",
+	"epoll_create1",
+	"epoll_ctl",
+	"epoll_ctl_old",
+	"epoll_pwait",
+	"epoll_wait",
+	"epoll_wait_old",
+	"eventfd",
+	"eventfd2",
+	"execve",
+	"exit",
+	"exit_group",
+	"faccessat",
+	"fadvise64",
+	"fadvise64_64",
+	"fallocate",
+	"fanotify_init",
+	"fanotify_mark",
+	"fchdir",
+	"fchmod",
+	"fchmodat",
+	"fchown",
+	"fchown32",
+	"fchownat",
+	"fcntl",
+	"fcntl64",
+	"fdatasync",
+	"fgetxattr",
+	"flistxattr",
+	"flock",
+	"fork",
+	"fremovexattr",
+	"fsetxattr",
+	"fstat",
+	"fstat64",
+	"fstatat64",
+	"fstatfs",
+	"fstatfs64",
+	"fsync",
+	"ftruncate",
+	"ftruncate64",
+	"futex",
+	"futimesat",
+	"getcpu",
+	"getcwd",
+	"getdents",
+	"getdents64",
+	"getegid",
+	"getegid32",
+	"geteuid",
+	"geteuid32",
+	"getgid",
+	"getgid32",
+	"getgroups",
+	"getgroups32",
+	"getitimer",
+	"getpeername",
+	"getpgid",
+	"getpgrp",
+	"getpid",
+	"getppid",
+	"getpriority",
+	"getrandom",
+	"getresgid",
+	"getresgid32",
+	"getresuid",
+	"getresuid32",
+	"getrlimit",
+	"get_robust_list",
+	"getrusage",
+	"getsid",
+	"getsockname",
+	"getsockopt",
+	"get_thread_area",
+	"gettid",
+	"gettimeofday",
+	"getuid",
+	"getuid32",
+	"getxattr",
+	"inotify_add_watch",
+	"inotify_init",
+	"inotify_init1",
+	"inotify_rm_watch",
+	"io_cancel",
+	"ioctl",
+	"io_destroy",
+	"io_getevents",
+	"ioprio_get",
+	"ioprio_set",
+	"io_setup",
+	"io_submit",
+	"ipc",
+	"kill",
+	"lchown",
+	"lchown32",
+	"lgetxattr",
+	"link",
+	"linkat",
+	"listen",
+	"listxattr",
+	"llistxattr",
+	"_llseek",
+	"lremovexattr",
+	"lseek",
+	"lsetxattr",
+	"lstat",
+	"lstat64",
+	"madvise",
+	"memfd_create",
+	"mincore",
+	"mkdir",
+	"mkdirat",
+	"mknod",
+	"mknodat",
+	"mlock",
+	"mlockall",
+	"mmap",
+	"mmap2",
+	"modify_ldt",
+	"mprotect",
+	"mq_getsetattr",
+	"mq_notify",
+	"mq_open",
+	"mq_timedreceive",
+	"mq_timedsend",
+	"mq_unlink",
+	"mremap",
+	"msgctl",
+	"msgget",
+	"msgrcv",
+	"msgsnd",
+	"msync",
+	"munlock",
+	"munlockall",
+	"munmap",
+	"nanosleep",
+	"newfstatat",
+	"_newselect",
+	"nice",
+	"oldfstat",
+	"oldlstat",
+	"oldolduname",
+	"oldstat",
+	"olduname",
+	"open",
+	"openat",
+	"pause",
+	"pipe",
+	"pipe2",
+	"poll",
+	"ppoll",
+	"prctl",
+	"pread64",
+	"preadv",
+	"prlimit64",
+	"pselect6",
+	"pwrite64",
+	"pwritev",
+	"read",
+	"readahead",
+	"readdir",
+	"readlink",
+	"readlinkat",
+	"readv",
+	"recvfrom",
+	"recvmmsg",
+	"recvmsg",
+	"remap_file_pages",
+	"removexattr",
+	"rename",
+	"renameat",
+	"renameat2",
+	"restart_syscall",
+	"rmdir",
+	"rt_sigaction",
+	"rt_sigpending",
+	"rt_sigprocmask",
+	"rt_sigqueueinfo",
+	"rt_sigreturn",
+	"rt_sigsuspend",
+	"rt_sigtimedwait",
+	"rt_tgsigqueueinfo",
+	"sched_getaffinity",
+	"sched_getattr",
+	"sched_getparam",
+	"sched_get_priority_max",
+	"sched_get_priority_min",
+	"sched_getscheduler",
+	"sched_rr_get_interval",
+	"sched_setaffinity",
+	"sched_setattr",
+	"sched_setparam",
+	"sched_setscheduler",
+	"sched_yield",
+	"seccomp",
+	"select",
+	"semctl",
+	"semget",
+	"semop",
+	"semtimedop",
+	"sendfile",
+	"sendfile64",
+	"sendmmsg",
+	"sendmsg",
+	"sendto",
+	"setdomainname",
+	"setfsgid",
+	"setfsgid32",
+	"setfsuid",
+	"setfsuid32",
+	"setgid",
+	"setgid32",
+	"setgroups",
+	"setgroups32",
+	"sethostname",
+	"setitimer",
+	"setpgid",
+	"setpriority",
+	"setregid",
+	"setregid32",
+	"setresgid",
+	"setresgid32",
+	"setresuid",
+	"setresuid32",
+	"setreuid",
+	"setreuid32",
+	"setrlimit",
+	"set_robust_list",
+	"setsid",
+	"setsockopt",
+	"set_thread_area",
+	"set_tid_address",
+	"setuid",
+	"setuid32",
+	"setxattr",
+	"sgetmask",
+	"shmat",
+	"shmctl",
+	"shmdt",
+	"shmget",
+	"shutdown",
+	"sigaction",
+	"sigaltstack",
+	"signal",
+	"signalfd",
+	"signalfd4",
+	"sigpending",
+	"sigprocmask",
+	"sigreturn",
+	"sigsuspend",
+	"socket",
+	"socketcall",
+	"socketpair",
+	"splice",
+	"ssetmask",
+	"stat",
+	"stat64",
+	"statfs",
+	"statfs64",
+	"symlink",
+	"symlinkat",
+	"sync",
+	"sync_file_range",
+	"syncfs",
+	"sysinfo",
+	"syslog",
+	"tee",
+	"tgkill",
+	"time",
+	"timer_create",
+	"timer_delete",
+	"timerfd_create",
+	"timerfd_gettime",
+	"timerfd_settime",
+	"timer_getoverrun",
+	"timer_gettime",
+	"timer_settime",
+	"times",
+	"tkill",
+	"truncate",
+	"truncate64",
+	"ugetrlimit",
+	"umask",
+	"uname",
+	"unlink",
+	"unlinkat",
+	"utime",
+	"utimensat",
+	"utimes",
+	"vfork",
+	"vmsplice",
+	"wait4",
+	"waitid",
+	"waitpid",
+	"write",
+	"writev",
+}
+
+// cloneNamespaceFlags are the CLONE_NEW* flags of clone, creating
+// namespaces: CLONE_NEWNS, CLONE_NEWUTS, CLONE_NEWIPC, CLONE_NEWUSER,
+// CLONE_NEWPID and CLONE_NEWNET.
+const cloneNamespaceFlags = 0x7e020000
+
+// DefaultProfile returns the profile of the containers started without
+// another one. It allows the system calls of defaultAllowed, clone without
+// the flags creating namespaces, and personality for the usual personas;
+// the others fail with EPERM.
+func DefaultProfile() *Profile {
+	profile := &Profile{DefaultAction: ActErrno}
+	for _, name := range defaultAllowed {
+		profile.Syscalls = append(profile.Syscalls, &Syscall{Name: name, Action: ActAllow})
+	}
+	profile.Syscalls = append(profile.Syscalls, &Syscall{
+		Name:   "clone",
+		Action: ActAllow,
+		Args:   []*Arg{{Index: 0, Value: cloneNamespaceFlags, ValueTwo: 0, Op: OpMaskedEqual}},
+	})
+	// PER_LINUX, PER_LINUX32, UNAME26 and the query of the current persona
+	for _, persona := range []uint64{0x0, 0x8, 0x20000, 0xffffffff} {
+		profile.Syscalls = append(profile.Syscalls, &Syscall{
+			Name:   "personality",
+			Action: ActAllow,
+			Args:   []*Arg{{Index: 0, Value: persona, Op: OpEqualTo}},
+		})
+	}
+	return profile
+}
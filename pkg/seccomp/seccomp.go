@@ -0,0 +1,121 @@
+// Package seccomp describes the profiles of the seccomp filters (see
+// seccomp(2)) restricting the system calls of the containers, and compiles
+// them to BPF programs on linux.
+//
+// The profiles are written in JSON, as:
+//
+//	{
+//		"defaultAction": "SCMP_ACT_ERRNO",
+//		"syscalls": [
+//			{"name": "read", "action": "SCMP_ACT_ALLOW"},
+//			{"name": "clone", "action": "SCMP_ACT_ALLOW", "args": [
+//				{"index": 0, "value": 2080505856, "valueTwo": 0, "op": "SCMP_CMP_MASKED_EQ"}
+//			]}
+//		]
+//	}
+//
+// The first rule matching a system call, with all its arguments, gives its
+// action. The others get the default action.
+package seccomp
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Action is what the filter does with a system call.
+type Action string
+
+const (
+	// ActKill kills the process.
+	ActKill Action = "SCMP_ACT_KILL"
+	// ActTrap sends SIGSYS to the process.
+	ActTrap Action = "SCMP_ACT_TRAP"
+	// ActErrno fails the system call with EPERM.
+	ActErrno Action = "SCMP_ACT_ERRNO"
+	// ActAllow runs the system call.
+	ActAllow Action = "SCMP_ACT_ALLOW"
+)
+
+// Operator compares an argument of a system call to the values of an Arg.
+type Operator string
+
+const (
+	// OpEqualTo matches the arguments equal to Value.
+	OpEqualTo Operator = "SCMP_CMP_EQ"
+	// OpNotEqual matches the arguments not equal to Value.
+	OpNotEqual Operator = "SCMP_CMP_NE"
+	// OpMaskedEqual matches the arguments equal to ValueTwo once masked
+	// with Value.
+	OpMaskedEqual Operator = "SCMP_CMP_MASKED_EQ"
+)
+
+// Profile is the seccomp filter of a container.
+type Profile struct {
+	DefaultAction Action     `json:"defaultAction"`
+	Syscalls      []*Syscall `json:"syscalls"`
+}
+
+// Syscall is a rule of a profile, matching a system call by name and,
+// when given, by its arguments.
+type Syscall struct {
+	Name   string `json:"name"`
+	Action Action `json:"action"`
+	Args   []*Arg `json:"args"`
+}
+
+// Arg matches the argument Index, from 0 to 5, of a system call.
+type Arg struct {
+	Index    uint     `json:"index"`
+	Value    uint64   `json:"value"`
+	ValueTwo uint64   `json:"valueTwo"`
+	Op       Operator `json:"op"`
+}
+
+// ParseProfile parses and validates a profile written in JSON.
+func ParseProfile(data []byte) (*Profile, error) {
+	var profile Profile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return nil, fmt.Errorf("Invalid seccomp profile: %s", err)
+	}
+	if err := profile.Validate(); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// Validate checks the actions and the arguments of the rules. The names of
+// the system calls are only known when the profile is compiled for an
+// architecture.
+func (p *Profile) Validate() error {
+	if err := validateAction(p.DefaultAction); err != nil {
+		return err
+	}
+	for _, s := range p.Syscalls {
+		if s.Name == "" {
+			return fmt.Errorf("Invalid seccomp profile: a rule has no system call name")
+		}
+		if err := validateAction(s.Action); err != nil {
+			return err
+		}
+		for _, arg := range s.Args {
+			if arg.Index > 5 {
+				return fmt.Errorf("Invalid seccomp profile: %s has no argument %d", s.Name, arg.Index)
+			}
+			switch arg.Op {
+			case OpEqualTo, OpNotEqual, OpMaskedEqual:
+			default:
+				return fmt.Errorf("Invalid seccomp profile: unsupported operator %q for %s", arg.Op, s.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func validateAction(action Action) error {
+	switch action {
+	case ActKill, ActTrap, ActErrno, ActAllow:
+		return nil
+	}
+	return fmt.Errorf("Invalid seccomp profile: unsupported action %q", action)
+}
@@ -0,0 +1,202 @@
+// +build linux
+
+package seccomp
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	prGetSeccomp      = 21 // PR_GET_SECCOMP
+	prSetSeccomp      = 22 // PR_SET_SECCOMP
+	seccompModeFilter = 2  // SECCOMP_MODE_FILTER
+
+	retKill  = 0x00000000 // SECCOMP_RET_KILL
+	retTrap  = 0x00030000 // SECCOMP_RET_TRAP
+	retErrno = 0x00050000 // SECCOMP_RET_ERRNO
+	retAllow = 0x7fff0000 // SECCOMP_RET_ALLOW
+
+	// __X32_SYSCALL_BIT, set in the numbers of the system calls of the x32
+	// binaries, which have the audit architecture of x86_64
+	x32SyscallBit = 0x40000000
+
+	// the offsets in struct seccomp_data
+	offsetNr   = 0
+	offsetArch = 4
+	offsetArgs = 16
+)
+
+// arch is an architecture whose system calls the filter checks.
+type arch struct {
+	// the AUDIT_ARCH_* of its system calls
+	audit uint32
+	// the numbers of its system calls
+	numbers map[string]uint32
+	// the system calls with the x32 bit are rejected
+	x32 bool
+}
+
+// Supported returns whether the kernel filters system calls and the
+// profiles are compiled for this architecture.
+func Supported() bool {
+	if len(arches) == 0 {
+		return false
+	}
+	_, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prGetSeccomp, 0, 0)
+	return errno != syscall.EINVAL
+}
+
+// Compile returns the BPF program of the profile, with the rules of the
+// profile for each of the architectures the kernel runs, whose system calls
+// have different numbers. The system calls an architecture does not have
+// are left out, so that a profile written for several of them is accepted.
+// The system calls of other architectures, and of x32, are killed.
+func Compile(p *Profile) ([]syscall.SockFilter, error) {
+	if len(arches) == 0 {
+		return nil, fmt.Errorf("The seccomp profiles are not supported on %s", runtime.GOARCH)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	var sections [][]syscall.SockFilter
+	for _, a := range arches {
+		section, err := compileArch(p, a)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, section)
+	}
+
+	// jump to the rules of the architecture of the system call, the
+	// sections are too long for the offsets of the conditional jumps
+	prog := []syscall.SockFilter{stmt(syscall.BPF_LD|syscall.BPF_W|syscall.BPF_ABS, offsetArch)}
+	target := 1 + 2*len(arches) + 1
+	for i, section := range sections {
+		prog = append(prog, jump(syscall.BPF_JMP|syscall.BPF_JEQ|syscall.BPF_K, arches[i].audit, 0, 1))
+		prog = append(prog, stmt(syscall.BPF_JMP|syscall.BPF_JA, uint32(target-len(prog)-1)))
+		target += len(section)
+	}
+	prog = append(prog, stmt(syscall.BPF_RET|syscall.BPF_K, retKill))
+	for _, section := range sections {
+		prog = append(prog, section...)
+	}
+	if len(prog) > 4096 {
+		return nil, fmt.Errorf("Invalid seccomp profile: too many rules")
+	}
+	return prog, nil
+}
+
+// compileArch returns the instructions of the rules of the profile for the
+// system calls of a, which end with the default action.
+func compileArch(p *Profile, a arch) ([]syscall.SockFilter, error) {
+	var prog []syscall.SockFilter
+	if a.x32 {
+		prog = append(prog,
+			stmt(syscall.BPF_LD|syscall.BPF_W|syscall.BPF_ABS, offsetNr),
+			jump(syscall.BPF_JMP|syscall.BPF_JGE|syscall.BPF_K, x32SyscallBit, 0, 1),
+			stmt(syscall.BPF_RET|syscall.BPF_K, retKill))
+	}
+	for _, s := range p.Syscalls {
+		nr, exists := a.numbers[s.Name]
+		if !exists {
+			continue
+		}
+		rule := compileArgs(s.Args)
+		rule = append(rule, stmt(syscall.BPF_RET|syscall.BPF_K, actionValue(s.Action)))
+		if len(rule) > 255 {
+			return nil, fmt.Errorf("Invalid seccomp profile: too many arguments for %s", s.Name)
+		}
+		prog = append(prog,
+			stmt(syscall.BPF_LD|syscall.BPF_W|syscall.BPF_ABS, offsetNr),
+			jump(syscall.BPF_JMP|syscall.BPF_JEQ|syscall.BPF_K, nr, 0, uint8(len(rule))))
+		prog = append(prog, rule...)
+	}
+	return append(prog, stmt(syscall.BPF_RET|syscall.BPF_K, actionValue(p.DefaultAction))), nil
+}
+
+// compileArgs returns the instructions checking args, which jump right
+// after the last one when an argument does not match. The arguments are
+// compared as two 32-bit words, the lower one first as on little-endian
+// architectures.
+func compileArgs(args []*Arg) []syscall.SockFilter {
+	const (
+		ld  = syscall.BPF_LD | syscall.BPF_W | syscall.BPF_ABS
+		and = syscall.BPF_ALU | syscall.BPF_AND | syscall.BPF_K
+		jeq = syscall.BPF_JMP | syscall.BPF_JEQ | syscall.BPF_K
+	)
+	length := 0
+	for _, arg := range args {
+		if arg.Op == OpMaskedEqual {
+			length += 6
+		} else {
+			length += 4
+		}
+	}
+
+	var instructions []syscall.SockFilter
+	// fail is the offset of a jump from the next instruction to the end
+	fail := func() uint8 {
+		return uint8(length - len(instructions))
+	}
+	for _, arg := range args {
+		lo := offsetArgs + 8*uint32(arg.Index)
+		hi := lo + 4
+		switch arg.Op {
+		case OpEqualTo:
+			instructions = append(instructions, stmt(ld, lo))
+			instructions = append(instructions, jump(jeq, uint32(arg.Value), 0, fail()))
+			instructions = append(instructions, stmt(ld, hi))
+			instructions = append(instructions, jump(jeq, uint32(arg.Value>>32), 0, fail()))
+		case OpNotEqual:
+			instructions = append(instructions, stmt(ld, lo))
+			instructions = append(instructions, jump(jeq, uint32(arg.Value), 0, 2))
+			instructions = append(instructions, stmt(ld, hi))
+			instructions = append(instructions, jump(jeq, uint32(arg.Value>>32), fail(), 0))
+		case OpMaskedEqual:
+			instructions = append(instructions, stmt(ld, lo))
+			instructions = append(instructions, stmt(and, uint32(arg.Value)))
+			instructions = append(instructions, jump(jeq, uint32(arg.ValueTwo), 0, fail()))
+			instructions = append(instructions, stmt(ld, hi))
+			instructions = append(instructions, stmt(and, uint32(arg.Value>>32)))
+			instructions = append(instructions, jump(jeq, uint32(arg.ValueTwo>>32), 0, fail()))
+		}
+	}
+	return instructions
+}
+
+func actionValue(action Action) uint32 {
+	switch action {
+	case ActTrap:
+		return retTrap
+	case ActErrno:
+		return retErrno | uint32(syscall.EPERM)
+	case ActAllow:
+		return retAllow
+	}
+	return retKill
+}
+
+func stmt(code uint16, k uint32) syscall.SockFilter {
+	return syscall.SockFilter{Code: code, K: k}
+}
+
+func jump(code uint16, k uint32, jt, jf uint8) syscall.SockFilter {
+	return syscall.SockFilter{Code: code, Jt: jt, Jf: jf, K: k}
+}
+
+// Load applies the filter to the calling thread, which must be locked and
+// have CAP_SYS_ADMIN. The threads and processes it starts, and the programs
+// it executes, keep the filter.
+func Load(filter []syscall.SockFilter) error {
+	prog := syscall.SockFprog{
+		Len:    uint16(len(filter)),
+		Filter: &filter[0],
+	}
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetSeccomp, seccompModeFilter, uintptr(unsafe.Pointer(&prog))); errno != 0 {
+		return fmt.Errorf("Error loading the seccomp filter: %s", errno)
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+// +build linux,amd64
+
+package seccomp
+
+import (
+	"encoding/binary"
+	"syscall"
+	"testing"
+)
+
+// run interprets the instructions of a filter compiled by Compile on the
+// system call nr with args, and returns its verdict.
+func run(t *testing.T, prog []syscall.SockFilter, arch, nr uint32, args ...uint64) uint32 {
+	data := make([]byte, offsetArgs+6*8)
+	binary.LittleEndian.PutUint32(data[offsetNr:], nr)
+	binary.LittleEndian.PutUint32(data[offsetArch:], arch)
+	for i, arg := range args {
+		binary.LittleEndian.PutUint64(data[offsetArgs+8*i:], arg)
+	}
+	var acc uint32
+	for pc := 0; pc < len(prog); pc++ {
+		ins := prog[pc]
+		switch ins.Code {
+		case syscall.BPF_LD | syscall.BPF_W | syscall.BPF_ABS:
+			acc = binary.LittleEndian.Uint32(data[ins.K:])
+		case syscall.BPF_ALU | syscall.BPF_AND | syscall.BPF_K:
+			acc &= ins.K
+		case syscall.BPF_JMP | syscall.BPF_JEQ | syscall.BPF_K:
+			if acc == ins.K {
+				pc += int(ins.Jt)
+			} else {
+				pc += int(ins.Jf)
+			}
+		case syscall.BPF_JMP | syscall.BPF_JGE | syscall.BPF_K:
+			if acc >= ins.K {
+				pc += int(ins.Jt)
+			} else {
+				pc += int(ins.Jf)
+			}
+		case syscall.BPF_JMP | syscall.BPF_JA:
+			pc += int(ins.K)
+		case syscall.BPF_RET | syscall.BPF_K:
+			return ins.K
+		default:
+			t.Fatalf("Unexpected instruction %#v", ins)
+		}
+	}
+	t.Fatal("The filter did not return")
+	return 0
+}
+
+func TestParseProfile(t *testing.T) {
+	valid := `{"defaultAction": "SCMP_ACT_ERRNO", "syscalls": [
+		{"name": "read", "action": "SCMP_ACT_ALLOW"},
+		{"name": "clone", "action": "SCMP_ACT_ALLOW", "args": [{"index": 0, "value": 2080505856, "valueTwo": 0, "op": "SCMP_CMP_MASKED_EQ"}]}
+	]}`
+	profile, err := ParseProfile([]byte(valid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.DefaultAction != ActErrno || len(profile.Syscalls) != 2 || profile.Syscalls[1].Args[0].Op != OpMaskedEqual {
+		t.Fatalf("Unexpected profile %#v", profile)
+	}
+
+	for _, invalid := range []string{
+		`{"defaultAction": "SCMP_ACT_ERRNO"`,
+		`{"defaultAction": "SCMP_ACT_NOTHING"}`,
+		`{"defaultAction": "SCMP_ACT_ERRNO", "syscalls": [{"name": "read", "action": "SCMP_ACT_TRACE"}]}`,
+		`{"defaultAction": "SCMP_ACT_ERRNO", "syscalls": [{"action": "SCMP_ACT_ALLOW"}]}`,
+		`{"defaultAction": "SCMP_ACT_ERRNO", "syscalls": [{"name": "read", "action": "SCMP_ACT_ALLOW", "args": [{"index": 6, "op": "SCMP_CMP_EQ"}]}]}`,
+		`{"defaultAction": "SCMP_ACT_ERRNO", "syscalls": [{"name": "read", "action": "SCMP_ACT_ALLOW", "args": [{"index": 0, "op": "SCMP_CMP_GT"}]}]}`,
+	} {
+		if _, err := ParseProfile([]byte(invalid)); err == nil {
+			t.Errorf("Expected an error for %s", invalid)
+		}
+	}
+}
+
+func TestCompile(t *testing.T) {
+	profile := &Profile{
+		DefaultAction: ActErrno,
+		Syscalls: []*Syscall{
+			{Name: "read", Action: ActAllow},
+			{Name: "no_such_syscall", Action: ActAllow},
+			{Name: "kill", Action: ActKill, Args: []*Arg{{Index: 1, Value: 9, Op: OpEqualTo}}},
+			{Name: "kill", Action: ActTrap, Args: []*Arg{
+				{Index: 0, Value: 1, Op: OpNotEqual},
+				{Index: 1, Value: 0xf0, ValueTwo: 0x10, Op: OpMaskedEqual},
+			}},
+			{Name: "kill", Action: ActAllow},
+		},
+	}
+	prog, err := Compile(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		read      = syscallNumbers["read"]
+		write     = syscallNumbers["write"]
+		kill      = syscallNumbers["kill"]
+		read386   = syscallNumbersI386["read"]
+		write386  = syscallNumbersI386["write"]
+		kill386   = syscallNumbersI386["kill"]
+		errno     = uint32(retErrno | uint32(syscall.EPERM))
+		auditArm  = uint32(0x40000028)
+		x32Offset = uint32(x32SyscallBit)
+	)
+	for _, c := range []struct {
+		arch, nr uint32
+		args     []uint64
+		expected uint32
+	}{
+		{auditArch, read, nil, retAllow},
+		{auditArch, write, nil, errno},
+		{auditArm, read, nil, retKill},
+		{auditArch, x32Offset | read, nil, retKill},
+		{auditArch, x32Offset | write, nil, retKill},
+		{auditArchI386, read386, nil, retAllow},
+		{auditArchI386, write386, nil, errno},
+		{auditArchI386, read, nil, errno},
+		{auditArchI386, kill386, []uint64{2, 9}, retKill},
+		{auditArchI386, kill386, []uint64{2, 0x1f}, retTrap},
+		{auditArchI386, kill386, []uint64{1, 0x1f}, retAllow},
+		{auditArch, kill, []uint64{2, 9}, retKill},
+		{auditArch, kill, []uint64{2, 9 | 1<<32}, retAllow},
+		{auditArch, kill, []uint64{2, 0x1f}, retTrap},
+		{auditArch, kill, []uint64{1 << 32, 0x10}, retTrap},
+		{auditArch, kill, []uint64{1, 0x1f}, retAllow},
+		{auditArch, kill, []uint64{2, 0x2f}, retAllow},
+	} {
+		if verdict := run(t, prog, c.arch, c.nr, c.args...); verdict != c.expected {
+			t.Errorf("Expected %#x for the system call %d with %v on %#x, got %#x", c.expected, c.nr, c.args, c.arch, verdict)
+		}
+	}
+}
+
+func TestCompileX32(t *testing.T) {
+	// the deny rules of a profile allowing the rest apply to x32 as well
+	prog, err := Compile(&Profile{
+		DefaultAction: ActAllow,
+		Syscalls:      []*Syscall{{Name: "mount", Action: ActErrno}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	errno := uint32(retErrno | uint32(syscall.EPERM))
+	for _, c := range []struct {
+		arch, nr uint32
+		expected uint32
+	}{
+		{auditArch, syscallNumbers["mount"], errno},
+		{auditArch, x32SyscallBit | syscallNumbers["mount"], retKill},
+		{auditArch, x32SyscallBit | syscallNumbers["read"], retKill},
+		{auditArch, syscallNumbers["read"], retAllow},
+		{auditArchI386, syscallNumbersI386["mount"], errno},
+		{auditArchI386, syscallNumbersI386["read"], retAllow},
+	} {
+		if verdict := run(t, prog, c.arch, c.nr); verdict != c.expected {
+			t.Errorf("Expected %#x for the system call %#x on %#x, got %#x", c.expected, c.nr, c.arch, verdict)
+		}
+	}
+}
+
+func TestDefaultProfile(t *testing.T) {
+	prog, err := Compile(DefaultProfile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	errno := uint32(retErrno | uint32(syscall.EPERM))
+	for _, c := range []struct {
+		name     string
+		args     []uint64
+		expected uint32
+	}{
+		{"read", nil, retAllow},
+		{"execve", nil, retAllow},
+		{"mount", nil, errno},
+		{"setns", nil, errno},
+		{"unshare", nil, errno},
+		{"ptrace", nil, errno},
+		{"clone", []uint64{syscall.CLONE_VM | syscall.CLONE_THREAD}, retAllow},
+		{"clone", []uint64{syscall.CLONE_NEWUSER}, errno},
+		{"personality", []uint64{0xffffffff}, retAllow},
+		{"personality", []uint64{0x0400000}, errno},
+	} {
+		if verdict := run(t, prog, auditArch, syscallNumbers[c.name], c.args...); verdict != c.expected {
+			t.Errorf("Expected %#x for %s %v, got %#x", c.expected, c.name, c.args, verdict)
+		}
+	}
+
+	// the 32-bit binaries
+	for _, c := range []struct {
+		name     string
+		args     []uint64
+		expected uint32
+	}{
+		{"read", nil, retAllow},
+		{"mmap2", nil, retAllow},
+		{"socketcall", nil, retAllow},
+		{"socket", nil, retAllow},
+		{"stat64", nil, retAllow},
+		{"mount", nil, errno},
+		{"setns", nil, errno},
+		{"vm86", nil, errno},
+		{"clone", []uint64{syscall.CLONE_VM | syscall.CLONE_THREAD}, retAllow},
+		{"clone", []uint64{syscall.CLONE_NEWUSER}, errno},
+		{"personality", []uint64{0x8}, retAllow},
+	} {
+		if verdict := run(t, prog, auditArchI386, syscallNumbersI386[c.name], c.args...); verdict != c.expected {
+			t.Errorf("Expected %#x for the i386 %s %v, got %#x", c.expected, c.name, c.args, verdict)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+// +build !linux
+
+package seccomp
+
+// Supported returns whether the kernel filters system calls.
+func Supported() bool {
+	return false
+}
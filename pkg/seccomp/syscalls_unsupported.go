@@ -0,0 +1,6 @@
+// +build linux,!amd64
+
+package seccomp
+
+// The profiles are only compiled for amd64.
+var arches []arch
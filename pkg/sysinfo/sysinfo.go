@@ -12,6 +12,10 @@ import (
 type SysInfo struct {
 	MemoryLimit            bool
 	SwapLimit              bool
+	CpuCfsQuota            bool
+	BlkioWeight            bool
+	MemorySwappiness       bool
+	OomKillDisable         bool
 	IPv4ForwardingDisabled bool
 	AppArmor               bool
 }
@@ -35,6 +39,42 @@ func New(quiet bool) *SysInfo {
 		if !sysInfo.SwapLimit && !quiet {
 			log.Printf("WARNING: Your kernel does not support cgroup swap limit.")
 		}
+
+		_, err = ioutil.ReadFile(path.Join(cgroupMemoryMountpoint, "memory.swappiness"))
+		sysInfo.MemorySwappiness = err == nil
+		if !sysInfo.MemorySwappiness && !quiet {
+			log.Printf("WARNING: Your kernel does not support cgroup memory swappiness.")
+		}
+
+		_, err = ioutil.ReadFile(path.Join(cgroupMemoryMountpoint, "memory.oom_control"))
+		sysInfo.OomKillDisable = err == nil
+		if !sysInfo.OomKillDisable && !quiet {
+			log.Printf("WARNING: Your kernel does not support cgroup OOM kill disable.")
+		}
+	}
+
+	if cgroupCpuMountpoint, err := cgroups.FindCgroupMountpoint("cpu"); err != nil {
+		if !quiet {
+			log.Printf("WARNING: %s\n", err)
+		}
+	} else {
+		_, err := ioutil.ReadFile(path.Join(cgroupCpuMountpoint, "cpu.cfs_quota_us"))
+		sysInfo.CpuCfsQuota = err == nil
+		if !sysInfo.CpuCfsQuota && !quiet {
+			log.Printf("WARNING: Your kernel does not support cgroup cfs quotas.")
+		}
+	}
+
+	if cgroupBlkioMountpoint, err := cgroups.FindCgroupMountpoint("blkio"); err != nil {
+		if !quiet {
+			log.Printf("WARNING: %s\n", err)
+		}
+	} else {
+		_, err := ioutil.ReadFile(path.Join(cgroupBlkioMountpoint, "blkio.weight"))
+		sysInfo.BlkioWeight = err == nil
+		if !sysInfo.BlkioWeight && !quiet {
+			log.Printf("WARNING: Your kernel does not support cgroup blkio weight.")
+		}
 	}
 
 	// Check if AppArmor seems to be enabled on this system.
@@ -1,6 +1,7 @@
 package system
 
 import (
+	"bytes"
 	"syscall"
 	"unsafe"
 )
@@ -23,6 +24,11 @@ func Lgetxattr(path string, attr string) ([]byte, error) {
 		return nil, nil
 	}
 	if errno == syscall.ERANGE {
+		// ask for the size of the value, as sz is not set on errors
+		sz, _, errno = syscall.Syscall6(syscall.SYS_LGETXATTR, uintptr(unsafe.Pointer(pathBytes)), uintptr(unsafe.Pointer(attrBytes)), 0, 0, 0, 0)
+		if errno != 0 {
+			return nil, errno
+		}
 		dest = make([]byte, sz)
 		destBytes := unsafe.Pointer(&dest[0])
 		sz, _, errno = syscall.Syscall6(syscall.SYS_LGETXATTR, uintptr(unsafe.Pointer(pathBytes)), uintptr(unsafe.Pointer(attrBytes)), uintptr(destBytes), uintptr(len(dest)), 0, 0)
@@ -34,6 +40,38 @@ func Lgetxattr(path string, attr string) ([]byte, error) {
 	return dest[:sz], nil
 }
 
+// Llistxattr returns the names of the extended attributes of path, without
+// following symlinks. It returns a nil slice if the filesystem does not
+// support extended attributes.
+func Llistxattr(path string) ([]string, error) {
+	pathBytes, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	sz, _, errno := syscall.Syscall(syscall.SYS_LLISTXATTR, uintptr(unsafe.Pointer(pathBytes)), 0, 0)
+	if errno == syscall.ENOTSUP {
+		return nil, nil
+	}
+	if errno != 0 {
+		return nil, errno
+	}
+	if sz == 0 {
+		return nil, nil
+	}
+	dest := make([]byte, sz)
+	sz, _, errno = syscall.Syscall(syscall.SYS_LLISTXATTR, uintptr(unsafe.Pointer(pathBytes)), uintptr(unsafe.Pointer(&dest[0])), uintptr(len(dest)))
+	if errno != 0 {
+		return nil, errno
+	}
+	var names []string
+	for _, name := range bytes.Split(dest[:sz], []byte{0}) {
+		if len(name) > 0 {
+			names = append(names, string(name))
+		}
+	}
+	return names, nil
+}
+
 var _zero uintptr
 
 func Lsetxattr(path string, attr string, data []byte, flags int) error {
@@ -2,39 +2,63 @@ package systemd
 
 import (
 	"errors"
+	"fmt"
 	"net"
+	"os"
 	"strconv"
+	"strings"
 
 	"github.com/coreos/go-systemd/activation"
 )
 
 // ListenFD returns the specified socket activated files as a slice of
-// net.Listeners or all of the activated files if "*" is given.
-func ListenFD(addr string) ([]net.Listener, error) {
+// net.Listeners or all of the activated files if "*" is given, along with
+// their names. A socket can also be specified by its name, the
+// FileDescriptorName of its systemd socket unit up to the first comma.
+func ListenFD(addr string) ([]net.Listener, []string, error) {
 	// socket activation
 	listeners, err := activation.Listeners(false)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if listeners == nil || len(listeners) == 0 {
-		return nil, errors.New("No sockets found")
+		return nil, nil, errors.New("No sockets found")
 	}
+	names := listenFDNames(len(listeners))
 
 	// default to all fds just like unix:// and tcp://
 	if addr == "" {
 		addr = "*"
 	}
 
-	fdNum, _ := strconv.Atoi(addr)
-	fdOffset := fdNum - 3
-	if (addr != "*") && (len(listeners) < int(fdOffset)+1) {
-		return nil, errors.New("Too few socket activated files passed in")
+	if addr == "*" {
+		return listeners, names, nil
 	}
 
-	if addr == "*" {
-		return listeners, nil
+	fdNum, err := strconv.Atoi(addr)
+	if err != nil {
+		for i, name := range names {
+			if name == addr || strings.SplitN(name, ",", 2)[0] == addr {
+				return []net.Listener{listeners[i]}, []string{name}, nil
+			}
+		}
+		return nil, nil, fmt.Errorf("No socket activated file named %s", addr)
 	}
+	fdOffset := fdNum - 3
+	if fdOffset < 0 || len(listeners) < fdOffset+1 {
+		return nil, nil, errors.New("Too few socket activated files passed in")
+	}
+
+	return []net.Listener{listeners[fdOffset]}, []string{names[fdOffset]}, nil
+}
 
-	return []net.Listener{listeners[fdOffset]}, nil
+// listenFDNames returns the names systemd gave to the n socket activated
+// files in LISTEN_FDNAMES, empty for the ones it did not name.
+func listenFDNames(n int) []string {
+	names := make([]string, n)
+	if env := os.Getenv("LISTEN_FDNAMES"); env != "" {
+		copy(names, strings.Split(env, ":"))
+	}
+	return names
 }
@@ -0,0 +1,48 @@
+package timeutils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RFC3339NanoFixed is time.RFC3339Nano with a fixed number of digits, so
+// the dates of a same time zone sort as strings.
+const RFC3339NanoFixed = "2006-01-02T15:04:05.000000000Z07:00"
+
+// Format returns t as an RFC 3339 date in UTC, with nanoseconds.
+func Format(t time.Time) string {
+	return t.UTC().Format(RFC3339NanoFixed)
+}
+
+// FormatUnix returns the unix timestamp sec as Format does.
+func FormatUnix(sec int64) string {
+	return Format(time.Unix(sec, 0))
+}
+
+// Parse returns the time of a timestamp given as a unix timestamp in
+// seconds, with an optional fraction, or as an RFC 3339 date, in the time
+// zone loc when the date has none.
+func Parse(value string, loc *time.Location) (time.Time, error) {
+	if value = strings.TrimSpace(value); value == "" {
+		return time.Time{}, fmt.Errorf("Empty timestamp")
+	}
+	if sec, err := strconv.ParseFloat(value, 64); err == nil {
+		whole := int64(sec)
+		return time.Unix(whole, int64((sec-float64(whole))*1e9)), nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	// the dates without time zone, or truncated, e.g. 2014-09-01T10:00
+	format := "2006-01-02T15:04:05.999999999"
+	if len(value) < len(format) {
+		format = format[:len(value)]
+	}
+	t, err := time.ParseInLocation(format, value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid timestamp: %s", value)
+	}
+	return t, nil
+}
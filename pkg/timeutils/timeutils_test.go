@@ -0,0 +1,46 @@
+package timeutils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormat(t *testing.T) {
+	paris := time.FixedZone("CEST", 2*60*60)
+	if s := Format(time.Date(2014, 9, 1, 12, 0, 0, 500, paris)); s != "2014-09-01T10:00:00.000000500Z" {
+		t.Fatalf("Unexpected date %s", s)
+	}
+	if s := FormatUnix(1409565600); s != "2014-09-01T10:00:00.000000000Z" {
+		t.Fatalf("Unexpected date %s", s)
+	}
+}
+
+func TestParse(t *testing.T) {
+	expected := time.Date(2014, 9, 1, 10, 0, 0, 0, time.UTC)
+	for _, value := range []string{
+		"1409565600",
+		"1409565600.0",
+		"2014-09-01T10:00:00Z",
+		"2014-09-01T10:00:00.000000000Z",
+		"2014-09-01T12:00:00+02:00",
+		"2014-09-01T10:00:00",
+		"2014-09-01T10:00",
+	} {
+		parsed, err := Parse(value, time.UTC)
+		if err != nil {
+			t.Fatalf("%s: %s", value, err)
+		}
+		if !parsed.Equal(expected) {
+			t.Fatalf("%s: expected %s, got %s", value, expected, parsed)
+		}
+	}
+
+	if parsed, err := Parse("1409565600.5", time.UTC); err != nil || parsed.Sub(expected) != 500*time.Millisecond {
+		t.Fatalf("Unexpected time %s (%v)", parsed, err)
+	}
+	for _, value := range []string{"", "yesterday", "2014-13-01"} {
+		if _, err := Parse(value, time.UTC); err == nil {
+			t.Fatalf("Expected an error for %q", value)
+		}
+	}
+}
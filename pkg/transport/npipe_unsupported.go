@@ -0,0 +1,19 @@
+// +build !windows
+
+package transport
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+var errNoPipes = errors.New("Named pipes are only supported on Windows")
+
+func dialPipe(addr string, timeout time.Duration) (net.Conn, error) {
+	return nil, errNoPipes
+}
+
+func isNoPipe(err error) bool {
+	return false
+}
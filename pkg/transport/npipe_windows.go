@@ -0,0 +1,53 @@
+package transport
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+const errorPipeBusy syscall.Errno = 231
+
+// dialPipe opens the named pipe addr, as //./pipe/NAME, waiting up to
+// timeout, or forever if it is 0, while all its instances are busy.
+func dialPipe(addr string, timeout time.Duration) (net.Conn, error) {
+	name := filepath.FromSlash(addr)
+	deadline := time.Now().Add(timeout)
+	for {
+		f, err := os.OpenFile(name, os.O_RDWR, 0)
+		if err == nil {
+			return &pipeConn{File: f, addr: pipeAddr(addr)}, nil
+		}
+		pathErr, ok := err.(*os.PathError)
+		if !ok || pathErr.Err != errorPipeBusy || (timeout > 0 && time.Now().After(deadline)) {
+			return nil, err
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// isNoPipe returns whether err is the error of opening a named pipe which
+// does not exist.
+func isNoPipe(err error) bool {
+	return err == syscall.ERROR_FILE_NOT_FOUND
+}
+
+type pipeAddr string
+
+func (a pipeAddr) Network() string { return "npipe" }
+func (a pipeAddr) String() string  { return string(a) }
+
+// pipeConn is a connection over a named pipe. The pipe is opened for
+// synchronous I/O, so it has no deadlines.
+type pipeConn struct {
+	*os.File
+	addr pipeAddr
+}
+
+func (c *pipeConn) LocalAddr() net.Addr                { return c.addr }
+func (c *pipeConn) RemoteAddr() net.Addr               { return c.addr }
+func (c *pipeConn) SetDeadline(t time.Time) error      { return nil }
+func (c *pipeConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *pipeConn) SetWriteDeadline(t time.Time) error { return nil }
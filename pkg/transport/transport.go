@@ -0,0 +1,168 @@
+// Package transport connects the clients of the API to the daemon, over a
+// unix socket, tcp or, on Windows, a named pipe.
+package transport
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"time"
+)
+
+// New returns a transport for the requests to the daemon listening at addr
+// over proto, as "unix", "tcp" or "npipe". It keeps the connections alive
+// between the requests, so the transport should be reused rather than
+// created for each request. The requests over tcp go through the proxy of
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY, if any. connectTimeout and
+// responseTimeout, when not 0, limit the time to connect and to receive
+// the headers of a response.
+func New(proto, addr string, tlsConfig *tls.Config, connectTimeout, responseTimeout time.Duration) *http.Transport {
+	tr := &http.Transport{
+		TLSClientConfig: tlsConfig,
+		Dial: func(network, _ string) (net.Conn, error) {
+			return dial(proto, addr, connectTimeout)
+		},
+		ResponseHeaderTimeout: responseTimeout,
+	}
+	if proto == "tcp" {
+		// a daemon on the network may only be reachable through the proxy
+		// of the environment, which addr is then
+		tr.Proxy = http.ProxyFromEnvironment
+		tr.Dial = func(network, addr string) (net.Conn, error) {
+			return dial(network, addr, connectTimeout)
+		}
+	}
+	return tr
+}
+
+// Dial connects to the daemon listening at addr over proto, for the
+// requests which hijack their connection, e.g. to attach to a container,
+// and which a transport can not carry. The connections over tcp go through
+// the proxy of the environment, if any, and use TLS with tlsConfig if it
+// is not nil.
+func Dial(proto, addr string, tlsConfig *tls.Config, timeout time.Duration) (net.Conn, error) {
+	if proto != "tcp" {
+		return dial(proto, addr, timeout)
+	}
+	scheme := "http"
+	if tlsConfig != nil {
+		scheme = "https"
+	}
+	proxyURL, err := http.ProxyFromEnvironment(&http.Request{URL: &url.URL{Scheme: scheme, Host: addr}})
+	if err != nil {
+		return nil, err
+	}
+	var conn net.Conn
+	if proxyURL != nil {
+		conn, err = dialProxy(proxyURL, addr, timeout)
+	} else {
+		conn, err = dial(proto, addr, timeout)
+	}
+	if err != nil || tlsConfig == nil {
+		return conn, err
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	tlsConn := tls.Client(conn, &tls.Config{
+		RootCAs:            tlsConfig.RootCAs,
+		Certificates:       tlsConfig.Certificates,
+		InsecureSkipVerify: tlsConfig.InsecureSkipVerify,
+		ServerName:         host,
+	})
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+func dial(proto, addr string, timeout time.Duration) (net.Conn, error) {
+	switch proto {
+	case "npipe":
+		return dialPipe(addr, timeout)
+	case "unix":
+		conn, err := net.DialTimeout(proto, addr, timeout)
+		if err != nil && rootError(err) == syscall.EACCES {
+			return nil, fmt.Errorf("Permission denied on %s: the user needs to be allowed to use the socket of the daemon, e.g. in the docker group", addr)
+		}
+		return conn, err
+	}
+	return net.DialTimeout(proto, addr, timeout)
+}
+
+// dialProxy opens a tunnel to addr through the HTTP proxy proxyURL.
+func dialProxy(proxyURL *url.URL, addr string, timeout time.Duration) (net.Conn, error) {
+	proxyAddr := proxyURL.Host
+	if _, _, err := net.SplitHostPort(proxyAddr); err != nil {
+		proxyAddr = net.JoinHostPort(proxyAddr, "80")
+	}
+	conn, err := net.DialTimeout("tcp", proxyAddr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	req := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		req.SetBasicAuth(user.Username(), password)
+		req.Header.Set("Proxy-Authorization", req.Header.Get("Authorization"))
+		req.Header.Del("Authorization")
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("Cannot connect to %s through the proxy %s: %s", addr, proxyURL.Host, resp.Status)
+	}
+	return conn, nil
+}
+
+// IsNoDaemon returns whether err, returned by a connection or a request to
+// the daemon, means that no daemon listens at the address: the connection
+// is refused, or the socket or the named pipe does not exist.
+func IsNoDaemon(err error) bool {
+	switch root := rootError(err); root {
+	case syscall.ECONNREFUSED, syscall.ENOENT:
+		return true
+	default:
+		return isNoPipe(root)
+	}
+}
+
+// rootError returns the error at the root of err, e.g. the system error of
+// a failed connection.
+func rootError(err error) error {
+	for {
+		switch e := err.(type) {
+		case *url.Error:
+			err = e.Err
+		case *net.OpError:
+			err = e.Err
+		case *os.SyscallError:
+			err = e.Err
+		case *os.PathError:
+			err = e.Err
+		default:
+			return err
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package transport
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestTransportKeepAlive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-transport-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "docker.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	var (
+		mu    sync.Mutex
+		conns = 0
+	)
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("OK"))
+		}),
+		ConnState: func(c net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				mu.Lock()
+				conns++
+				mu.Unlock()
+			}
+		},
+	}
+	go server.Serve(l)
+
+	client := &http.Client{Transport: New("unix", socket, nil, 0, 0)}
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get("http://docker/_ping")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil || string(body) != "OK" {
+			t.Fatalf("Unexpected response %q (%v)", body, err)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if conns != 1 {
+		t.Fatalf("Expected the requests to share a connection, got %d connections", conns)
+	}
+}
+
+func TestIsNoDaemon(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-transport-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	_, err = Dial("unix", filepath.Join(dir, "missing.sock"), nil, 0)
+	if err == nil || !IsNoDaemon(err) {
+		t.Fatalf("Expected no daemon on a missing socket, got %v", err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	client := &http.Client{Transport: New("tcp", addr, nil, 0, 0)}
+	if _, err := client.Get("http://" + addr + "/_ping"); err == nil || !IsNoDaemon(err) {
+		t.Fatalf("Expected no daemon on a closed port, got %v", err)
+	}
+}
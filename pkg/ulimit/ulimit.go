@@ -0,0 +1,78 @@
+// Package ulimit parses and applies the resource limits (see setrlimit(2))
+// of container processes.
+package ulimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Human friendly version of Rlimit
+type Ulimit struct {
+	Name string
+	Hard int64
+	Soft int64
+}
+
+// The values of the RLIMIT_* constants of linux, as the syscall package
+// does not define all of them. Containers only run on linux, but the
+// client validates ulimits on every platform.
+var ulimitNameMapping = map[string]int{
+	"core":       4,  // RLIMIT_CORE
+	"cpu":        0,  // RLIMIT_CPU
+	"data":       2,  // RLIMIT_DATA
+	"fsize":      1,  // RLIMIT_FSIZE
+	"locks":      10, // RLIMIT_LOCKS
+	"memlock":    8,  // RLIMIT_MEMLOCK
+	"msgqueue":   12, // RLIMIT_MSGQUEUE
+	"nice":       13, // RLIMIT_NICE
+	"nofile":     7,  // RLIMIT_NOFILE
+	"nproc":      6,  // RLIMIT_NPROC
+	"rss":        5,  // RLIMIT_RSS
+	"rtprio":     14, // RLIMIT_RTPRIO
+	"rttime":     15, // RLIMIT_RTTIME
+	"sigpending": 11, // RLIMIT_SIGPENDING
+	"stack":      3,  // RLIMIT_STACK
+}
+
+// Parse parses a ulimit given as NAME=SOFT[:HARD]. The hard limit is the
+// soft limit when it is omitted.
+func Parse(val string) (*Ulimit, error) {
+	parts := strings.SplitN(val, "=", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid ulimit argument: %s", val)
+	}
+	if _, exists := ulimitNameMapping[parts[0]]; !exists {
+		return nil, fmt.Errorf("invalid ulimit type: %s", parts[0])
+	}
+
+	limits := strings.SplitN(parts[1], ":", 2)
+	soft, err := strconv.ParseInt(limits[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ulimit value: %s", val)
+	}
+	hard := soft
+	if len(limits) == 2 {
+		if hard, err = strconv.ParseInt(limits[1], 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid ulimit value: %s", val)
+		}
+	}
+	if soft > hard {
+		return nil, fmt.Errorf("ulimit soft limit must be less than or equal to hard limit: %d > %d", soft, hard)
+	}
+	return &Ulimit{Name: parts[0], Soft: soft, Hard: hard}, nil
+}
+
+func (u *Ulimit) String() string {
+	return fmt.Sprintf("%s=%d:%d", u.Name, u.Soft, u.Hard)
+}
+
+// FormatList formats ulimits for ApplyList.
+func FormatList(ulimits []*Ulimit) string {
+	vals := make([]string, len(ulimits))
+	for i, u := range ulimits {
+		vals[i] = u.String()
+	}
+	return strings.Join(vals, ",")
+}
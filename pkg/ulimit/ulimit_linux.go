@@ -0,0 +1,39 @@
+package ulimit
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+)
+
+// Apply sets the limit on the calling process. It is inherited by the
+// processes it starts.
+func (u *Ulimit) Apply() error {
+	resource, exists := ulimitNameMapping[u.Name]
+	if !exists {
+		return fmt.Errorf("invalid ulimit type: %s", u.Name)
+	}
+	rlimit := &syscall.Rlimit{Cur: uint64(u.Soft), Max: uint64(u.Hard)}
+	if err := syscall.Setrlimit(resource, rlimit); err != nil {
+		return fmt.Errorf("unable to set ulimit %s: %s", u, err)
+	}
+	return nil
+}
+
+// ApplyList applies ulimits given as a comma separated list, as passed by
+// the exec drivers to the container init.
+func ApplyList(list string) error {
+	if list == "" {
+		return nil
+	}
+	for _, val := range strings.Split(list, ",") {
+		u, err := Parse(val)
+		if err != nil {
+			return err
+		}
+		if err := u.Apply(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
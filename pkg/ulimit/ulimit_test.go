@@ -0,0 +1,32 @@
+package ulimit
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	u, err := Parse("nofile=1024:2048")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Name != "nofile" || u.Soft != 1024 || u.Hard != 2048 {
+		t.Fatalf("Unexpected ulimit %s", u)
+	}
+	if u.String() != "nofile=1024:2048" {
+		t.Fatalf("Unexpected string %s", u)
+	}
+
+	u, err = Parse("nproc=512")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Soft != 512 || u.Hard != 512 {
+		t.Fatalf("Expected the hard limit to default to the soft limit, got %s", u)
+	}
+
+	for _, invalid := range []string{"nofile", "files=1024", "nofile=a", "nofile=1024:b", "nofile=2048:1024"} {
+		if _, err := Parse(invalid); err == nil {
+			t.Fatalf("Expected an error for %s", invalid)
+		}
+	}
+}
@@ -17,6 +17,9 @@ import (
 // Where we store the config file
 const CONFIGFILE = ".dockercfg"
 
+// The key of the config file naming the credentials store
+const credsStoreKey = "credsStore"
+
 // Only used for user auth + account creation
 const INDEXSERVER = "https://index.docker.io/v1/"
 
@@ -35,8 +38,11 @@ type AuthConfig struct {
 }
 
 type ConfigFile struct {
-	Configs  map[string]AuthConfig `json:"configs,omitempty"`
-	rootPath string
+	Configs map[string]AuthConfig `json:"configs,omitempty"`
+	// CredentialsStore names the external helper keeping the credentials,
+	// the config file only keeping the email of the registries when set.
+	CredentialsStore string `json:"credsStore,omitempty"`
+	rootPath         string
 }
 
 func IndexServerAddress() string {
@@ -87,7 +93,8 @@ func LoadConfig(rootPath string) (*ConfigFile, error) {
 		return &configFile, err
 	}
 	// 要求配置文件为规范json格式，否则会解析失败
-	if err := json.Unmarshal(b, &configFile.Configs); err != nil {
+	var entries map[string]json.RawMessage
+	if err := json.Unmarshal(b, &entries); err != nil {
 		arr := strings.Split(string(b), "\n")
 		if len(arr) < 2 {
 			return &configFile, fmt.Errorf("The Auth config file is empty")
@@ -109,11 +116,24 @@ func LoadConfig(rootPath string) (*ConfigFile, error) {
 		authConfig.ServerAddress = IndexServerAddress()
 		configFile.Configs[IndexServerAddress()] = authConfig
 	} else {
-		for k, authConfig := range configFile.Configs {
-			authConfig.Username, authConfig.Password, err = decodeAuth(authConfig.Auth)
-			if err != nil {
+		for k, entry := range entries {
+			if k == credsStoreKey {
+				if err := json.Unmarshal(entry, &configFile.CredentialsStore); err != nil {
+					return &configFile, fmt.Errorf("Invalid %s in the Auth config file: %s", credsStoreKey, err)
+				}
+				continue
+			}
+			var authConfig AuthConfig
+			if err := json.Unmarshal(entry, &authConfig); err != nil {
 				return &configFile, err
 			}
+			// the credentials of a credentials store are not in the file
+			if authConfig.Auth != "" {
+				authConfig.Username, authConfig.Password, err = decodeAuth(authConfig.Auth)
+				if err != nil {
+					return &configFile, err
+				}
+			}
 			authConfig.Auth = ""
 			configFile.Configs[k] = authConfig
 			authConfig.ServerAddress = k
@@ -125,16 +145,21 @@ func LoadConfig(rootPath string) (*ConfigFile, error) {
 // save the auth config
 func SaveConfig(configFile *ConfigFile) error {
 	confFile := path.Join(configFile.rootPath, CONFIGFILE)
-	if len(configFile.Configs) == 0 {
+	if len(configFile.Configs) == 0 && configFile.CredentialsStore == "" {
 		os.Remove(confFile)
 		return nil
 	}
 
-	configs := make(map[string]AuthConfig, len(configFile.Configs))
+	configs := make(map[string]interface{}, len(configFile.Configs)+1)
+	if configFile.CredentialsStore != "" {
+		configs[credsStoreKey] = configFile.CredentialsStore
+	}
 	for k, authConfig := range configFile.Configs {
 		authCopy := authConfig
 
-		authCopy.Auth = encodeAuth(&authCopy)
+		if authCopy.Username != "" || authCopy.Password != "" {
+			authCopy.Auth = encodeAuth(&authCopy)
+		}
 		authCopy.Username = ""
 		authCopy.Password = ""
 		authCopy.ServerAddress = ""
@@ -161,7 +186,7 @@ func Login(authConfig *AuthConfig, factory *utils.HTTPRequestFactory) (string, e
 		client  = &http.Client{
 			Transport: &http.Transport{
 				DisableKeepAlives: true,
-				Proxy:             http.ProxyFromEnvironment,
+				Proxy:             proxy,
 			},
 			CheckRedirect: AddRequiredHeadersToRedirectedRequests,
 		}
@@ -169,11 +194,23 @@ func Login(authConfig *AuthConfig, factory *utils.HTTPRequestFactory) (string, e
 		serverAddress = authConfig.ServerAddress
 	)
 
+	if err = checkOnline(); err != nil {
+		return "", err
+	}
+
 	if serverAddress == "" {
 		serverAddress = IndexServerAddress()
 	}
+	if err = CheckRegistryPolicy(serverAddress); err != nil {
+		return "", err
+	}
 
 	loginAgainstOfficialIndex := serverAddress == IndexServerAddress()
+	if loginAgainstOfficialIndex {
+		if err = checkIndex(); err != nil {
+			return "", err
+		}
+	}
 
 	// to avoid sending the server address to the server it should be removed before being marshalled
 	authCopy := *authConfig
@@ -272,19 +309,6 @@ func (config *ConfigFile) ResolveAuthConfig(hostname string) AuthConfig {
 		return c
 	}
 
-	convertToHostname := func(url string) string {
-		stripped := url
-		if strings.HasPrefix(url, "http://") {
-			stripped = strings.Replace(url, "http://", "", 1)
-		} else if strings.HasPrefix(url, "https://") {
-			stripped = strings.Replace(url, "https://", "", 1)
-		}
-
-		nameParts := strings.SplitN(stripped, "/", 2)
-
-		return nameParts[0]
-	}
-
 	// Maybe they have a legacy config file, we will iterate the keys converting
 	// them to the new format and testing
 	normalizedHostename := convertToHostname(hostname)
@@ -297,3 +321,31 @@ func (config *ConfigFile) ResolveAuthConfig(hostname string) AuthConfig {
 	// When all else fails, return an empty auth config
 	return AuthConfig{}
 }
+
+func convertToHostname(url string) string {
+	stripped := url
+	if strings.HasPrefix(url, "http://") {
+		stripped = strings.Replace(url, "http://", "", 1)
+	} else if strings.HasPrefix(url, "https://") {
+		stripped = strings.Replace(url, "https://", "", 1)
+	}
+
+	nameParts := strings.SplitN(stripped, "/", 2)
+
+	return nameParts[0]
+}
+
+// NormalizeServerAddress returns the key of the credentials of a registry in
+// the config file: the index server address for the index, the hostname of
+// the registry otherwise, so "https://myregistry:5000/v1/" and
+// "myregistry:5000" share their credentials.
+func NormalizeServerAddress(serverAddress string) string {
+	if serverAddress == "" {
+		return IndexServerAddress()
+	}
+	hostname := convertToHostname(serverAddress)
+	if hostname == convertToHostname(IndexServerAddress()) {
+		return IndexServerAddress()
+	}
+	return hostname
+}
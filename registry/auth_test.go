@@ -147,3 +147,48 @@ func TestResolveAuthConfigFullURL(t *testing.T) {
 		}
 	}
 }
+
+func TestNormalizeServerAddress(t *testing.T) {
+	for addr, expected := range map[string]string{
+		"":                            IndexServerAddress(),
+		IndexServerAddress():          IndexServerAddress(),
+		"index.docker.io":             IndexServerAddress(),
+		"myregistry:5000":             "myregistry:5000",
+		"https://myregistry:5000/v1/": "myregistry:5000",
+		"http://myregistry:5000/v2/":  "myregistry:5000",
+		"registry.example.com/v1/":    "registry.example.com",
+	} {
+		if normalized := NormalizeServerAddress(addr); normalized != expected {
+			t.Errorf("%q -> %q != %q", addr, normalized, expected)
+		}
+	}
+}
+
+func TestCredentialsStorePostSave(t *testing.T) {
+	configFile, err := setupTempConfigFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configFile.rootPath)
+
+	// with a credentials store, the file only keeps the emails
+	configFile.CredentialsStore = "secretservice"
+	configFile.Configs["myregistry:5000"] = AuthConfig{Email: "docker@myregistry"}
+	if err := SaveConfig(configFile); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadConfig(configFile.rootPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.CredentialsStore != "secretservice" {
+		t.Fatalf("Expected the credentials store secretservice, got %q", loaded.CredentialsStore)
+	}
+	if authConfig := loaded.Configs["myregistry:5000"]; authConfig.Username != "" || authConfig.Email != "docker@myregistry" {
+		t.Fatalf("Unexpected auth config %+v", authConfig)
+	}
+	if authConfig := loaded.Configs["testIndex"]; authConfig.Username != "docker-user" || authConfig.Password != "docker-pass" {
+		t.Fatalf("Unexpected auth config %+v", authConfig)
+	}
+}
@@ -0,0 +1,66 @@
+package registry
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+// The registries reached with plain HTTP, or with HTTPS without verifying
+// their certificate, besides the ones on the loopback network.
+var (
+	insecureHosts    []string
+	insecureNetworks []*net.IPNet
+)
+
+// SetInsecureRegistries sets the registries which are not required to
+// serve HTTPS with a verified certificate, each a host, a host:port or a
+// CIDR network. It must be called before any request to a registry.
+func SetInsecureRegistries(registries []string) {
+	insecureHosts, insecureNetworks = nil, nil
+	for _, registry := range registries {
+		if _, network, err := net.ParseCIDR(registry); err == nil {
+			insecureNetworks = append(insecureNetworks, network)
+		} else {
+			insecureHosts = append(insecureHosts, registry)
+		}
+	}
+}
+
+// IsSecure returns whether the registry at hostname, given as host[:port]
+// or as a URL, must serve HTTPS with a verified certificate. A host given to
+// SetInsecureRegistries without a port matches all its ports.
+func IsSecure(hostname string) bool {
+	if strings.Contains(hostname, "://") {
+		if u, err := url.Parse(hostname); err == nil {
+			hostname = u.Host
+		}
+	}
+	host := hostname
+	if h, _, err := net.SplitHostPort(hostname); err == nil {
+		host = h
+	}
+	for _, insecure := range insecureHosts {
+		if insecure == hostname || insecure == host {
+			return false
+		}
+	}
+
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else if resolved, err := lookupIP(host); err == nil {
+		ips = resolved
+	}
+	for _, ip := range ips {
+		if ip.IsLoopback() {
+			return false
+		}
+		for _, network := range insecureNetworks {
+			if network.Contains(ip) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,168 @@
+package registry
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ManifestData is an image manifest of the v2 registry protocol, schema
+// version 1. The layers and their history are listed from the image to
+// its base.
+type ManifestData struct {
+	SchemaVersion int                `json:"schemaVersion"`
+	Name          string             `json:"name"`
+	Tag           string             `json:"tag"`
+	Architecture  string             `json:"architecture"`
+	FSLayers      []*FSLayer         `json:"fsLayers"`
+	History       []*ManifestHistory `json:"history"`
+}
+
+type FSLayer struct {
+	BlobSum string `json:"blobSum"`
+}
+
+// ManifestHistory holds the v1 json of an image, which gives its ID and
+// its parent.
+type ManifestHistory struct {
+	V1Compatibility string `json:"v1Compatibility"`
+}
+
+const manifestIndent = "   "
+
+var (
+	signingKey     *ecdsa.PrivateKey
+	signingKeyErr  error
+	signingKeyOnce sync.Once
+)
+
+// SignManifest returns the json of the manifest with a JSON web signature,
+// as the v2 registries require. The manifest is signed with a key generated
+// for the life of the daemon: the registry only checks that the manifest
+// was not altered, not who signed it.
+func SignManifest(manifest *ManifestData) ([]byte, error) {
+	signingKeyOnce.Do(func() {
+		signingKey, signingKeyErr = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	})
+	if signingKeyErr != nil {
+		return nil, signingKeyErr
+	}
+	content, err := json.MarshalIndent(manifest, "", manifestIndent)
+	if err != nil {
+		return nil, err
+	}
+	return signJSON(content, signingKey)
+}
+
+type jsonWebKey struct {
+	Curve string `json:"crv"`
+	KeyID string `json:"kid"`
+	Type  string `json:"kty"`
+	X     string `json:"x"`
+	Y     string `json:"y"`
+}
+
+type signatureHeader struct {
+	JWK       *jsonWebKey `json:"jwk"`
+	Algorithm string      `json:"alg"`
+}
+
+type jsonSignature struct {
+	Header    *signatureHeader `json:"header"`
+	Signature string           `json:"signature"`
+	Protected string           `json:"protected"`
+}
+
+// signJSON adds a "signatures" member to the indented json object content.
+// The protected header records where the signatures are inserted, so the
+// registry can restore the signed content.
+func signJSON(content []byte, key *ecdsa.PrivateKey) ([]byte, error) {
+	closeIndex := bytes.LastIndex(content, []byte("}"))
+	if closeIndex < 0 {
+		return nil, fmt.Errorf("Invalid json content to sign")
+	}
+	formatLength := len(bytes.TrimRight(content[:closeIndex], " \t\r\n"))
+	protected, err := json.Marshal(map[string]interface{}{
+		"formatLength": formatLength,
+		"formatTail":   base64URLEncode(content[formatLength:]),
+		"time":         time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return nil, err
+	}
+	protected64 := base64URLEncode(protected)
+	signed := sha256.Sum256([]byte(protected64 + "." + base64URLEncode(content)))
+	r, s, err := ecdsa.Sign(rand.Reader, key, signed[:])
+	if err != nil {
+		return nil, err
+	}
+	jwk, err := newJSONWebKey(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	signatures, err := json.MarshalIndent([]*jsonSignature{{
+		Header: &signatureHeader{
+			JWK:       jwk,
+			Algorithm: "ES256",
+		},
+		Signature: base64URLEncode(append(padBytes(r.Bytes(), 32), padBytes(s.Bytes(), 32)...)),
+		Protected: protected64,
+	}}, manifestIndent, manifestIndent)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bytes.NewBuffer(nil)
+	buf.Write(content[:formatLength])
+	buf.WriteString(",\n" + manifestIndent + `"signatures": `)
+	buf.Write(signatures)
+	buf.WriteString("\n}")
+	return buf.Bytes(), nil
+}
+
+func newJSONWebKey(pub *ecdsa.PublicKey) (*jsonWebKey, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonWebKey{
+		Curve: "P-256",
+		KeyID: keyID(der),
+		Type:  "EC",
+		X:     base64URLEncode(padBytes(pub.X.Bytes(), 32)),
+		Y:     base64URLEncode(padBytes(pub.Y.Bytes(), 32)),
+	}, nil
+}
+
+// keyID returns the ID of a public key from its DER encoding: the base32
+// encoding of the first 240 bits of its sha256, in groups of 4 characters.
+func keyID(der []byte) string {
+	sum := sha256.Sum256(der)
+	s := strings.TrimRight(base32.StdEncoding.EncodeToString(sum[:30]), "=")
+	var groups []string
+	for i := 0; i < len(s); i += 4 {
+		groups = append(groups, s[i:i+4])
+	}
+	return strings.Join(groups, ":")
+}
+
+func base64URLEncode(b []byte) string {
+	return strings.TrimRight(base64.URLEncoding.EncodeToString(b), "=")
+}
+
+func padBytes(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	return append(make([]byte, size-len(b)), b...)
+}
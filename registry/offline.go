@@ -0,0 +1,24 @@
+package registry
+
+import "errors"
+
+// ErrOffline is returned instead of reaching a registry when the daemon is
+// offline.
+var ErrOffline = errors.New("The daemon runs with --offline and does not reach the registries")
+
+var offline bool
+
+// SetOffline makes the sessions, the logins and the checks of the
+// endpoints fail right away with ErrOffline, rather than after the
+// timeouts of the DNS and of the connections. It must be called before any
+// request to a registry.
+func SetOffline(value bool) {
+	offline = value
+}
+
+func checkOnline() error {
+	if offline {
+		return ErrOffline
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package registry
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// The glob patterns of the registries the daemon may, or may not, pull
+// from and push to.
+var (
+	allowedRegistries []string
+	deniedRegistries  []string
+)
+
+// SetRegistryPolicy restricts the registries the sessions are opened with
+// to the ones matching a pattern of allowed, when it is not empty, and not
+// matching any pattern of denied. The patterns are globs, as in path.Match,
+// of a host or a host:port; a host matches all its ports. It must be
+// called before any request to a registry.
+func SetRegistryPolicy(allowed, denied []string) error {
+	for _, pattern := range append(append([]string{}, allowed...), denied...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("Invalid registry pattern %s: %s", pattern, err)
+		}
+	}
+	allowedRegistries, deniedRegistries = allowed, denied
+	return nil
+}
+
+// CheckRegistryPolicy returns an error when the policy set by
+// SetRegistryPolicy does not allow the registry at hostname, given as
+// host[:port] or as a URL. The official index is index.docker.io.
+func CheckRegistryPolicy(hostname string) error {
+	if strings.Contains(hostname, "://") {
+		if u, err := url.Parse(hostname); err == nil {
+			hostname = u.Host
+		}
+	}
+	if matchRegistry(deniedRegistries, hostname) {
+		return fmt.Errorf("The registry %s is denied by the policy of the daemon", hostname)
+	}
+	if len(allowedRegistries) > 0 && !matchRegistry(allowedRegistries, hostname) {
+		return fmt.Errorf("The registry %s is not allowed by the policy of the daemon", hostname)
+	}
+	return nil
+}
+
+func matchRegistry(patterns []string, hostname string) bool {
+	host := hostname
+	if h, _, err := net.SplitHostPort(hostname); err == nil {
+		host = h
+	}
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, hostname); matched {
+			return true
+		}
+		if matched, _ := path.Match(pattern, host); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// proxy returns the proxy of a request to a registry, the one given by the
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables unless
+// SetProxy overrode it.
+var proxy = http.ProxyFromEnvironment
+
+// SetProxy overrides the proxy of the connections to the registries: an
+// empty string uses the environment, "none" connects directly and anything
+// else is the URL of the proxy, as http://host:port. It must be called
+// before any request to a registry.
+func SetProxy(rawurl string) error {
+	switch rawurl {
+	case "":
+		proxy = http.ProxyFromEnvironment
+	case "none":
+		proxy = nil
+	default:
+		u, err := url.Parse(rawurl)
+		if err != nil {
+			return fmt.Errorf("Invalid registry proxy %s: %s", rawurl, err)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("Invalid registry proxy %s: expected http://host[:port] or https://host[:port]", rawurl)
+		}
+		proxy = http.ProxyURL(u)
+	}
+	return nil
+}
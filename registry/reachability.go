@@ -0,0 +1,103 @@
+package registry
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/pkg/log"
+)
+
+// reachabilityTTL is how long the DNS lookups of the registries and the
+// checks of the official index are cached, so that a host without DNS or
+// network access waits for their timeouts once rather than on each request.
+var reachabilityTTL = time.Minute
+
+// indexAddress is the host:port the official index is checked at, a
+// variable for the tests.
+var indexAddress = "index.docker.io:443"
+
+type cachedLookup struct {
+	ips     []net.IP
+	err     error
+	checked time.Time
+}
+
+var (
+	lookupsLock sync.Mutex
+	lookups     = make(map[string]*cachedLookup)
+
+	// indexLock is held during the checks of the index, so that the
+	// requests made meanwhile wait for their result rather than starting
+	// their own.
+	indexLock    sync.Mutex
+	indexErr     error
+	indexChecked time.Time
+)
+
+// lookupIP resolves host, from the cache when it was resolved, or failed
+// to, within reachabilityTTL.
+func lookupIP(host string) ([]net.IP, error) {
+	lookupsLock.Lock()
+	cached, exists := lookups[host]
+	lookupsLock.Unlock()
+	if exists && time.Since(cached.checked) < reachabilityTTL {
+		return cached.ips, cached.err
+	}
+
+	ips, err := net.LookupIP(host)
+	lookupsLock.Lock()
+	lookups[host] = &cachedLookup{ips: ips, err: err, checked: time.Now()}
+	lookupsLock.Unlock()
+	return ips, err
+}
+
+// checkIndex returns an error when the official index could not be
+// reached within reachabilityTTL, without waiting for the timeouts again.
+// The index is not checked when it is reached through a proxy, which
+// resolves it itself.
+func checkIndex() error {
+	indexLock.Lock()
+	defer indexLock.Unlock()
+	if !indexChecked.IsZero() && time.Since(indexChecked) < reachabilityTTL {
+		return indexErr
+	}
+
+	indexErr = nil
+	if !indexProxied() {
+		conn, err := net.DialTimeout("tcp", indexAddress, 5*time.Second)
+		if err != nil {
+			indexErr = fmt.Errorf("The official index %s is unreachable: %s", IndexServerAddress(), err)
+		} else {
+			conn.Close()
+		}
+	}
+	indexChecked = time.Now()
+	return indexErr
+}
+
+func indexProxied() bool {
+	if proxy == nil {
+		return false
+	}
+	u, err := url.Parse(IndexServerAddress())
+	if err != nil {
+		return false
+	}
+	proxyURL, err := proxy(&http.Request{URL: u})
+	return err == nil && proxyURL != nil
+}
+
+// PreflightIndex checks in the background whether the official index can be
+// reached, so that the first pull, push, search or login against it knows
+// right away when it cannot. It never blocks.
+func PreflightIndex() {
+	go func() {
+		if err := checkIndex(); err != nil {
+			log.Infof("%s, the requests to it fail right away for %s", err, reachabilityTTL)
+		}
+	}()
+}
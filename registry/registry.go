@@ -33,8 +33,11 @@ const (
 	ConnectTimeout
 )
 
-func newClient(jar http.CookieJar, roots *x509.CertPool, cert *tls.Certificate, timeout TimeoutType) *http.Client {
-	tlsConfig := tls.Config{RootCAs: roots}
+func newClient(jar http.CookieJar, roots *x509.CertPool, cert *tls.Certificate, timeout TimeoutType, secure bool) *http.Client {
+	tlsConfig := tls.Config{
+		RootCAs:            roots,
+		InsecureSkipVerify: !secure,
+	}
 
 	if cert != nil {
 		tlsConfig.Certificates = append(tlsConfig.Certificates, *cert)
@@ -42,7 +45,7 @@ func newClient(jar http.CookieJar, roots *x509.CertPool, cert *tls.Certificate,
 
 	httpTransport := &http.Transport{
 		DisableKeepAlives: true,
-		Proxy:             http.ProxyFromEnvironment,
+		Proxy:             proxy,
 		TLSClientConfig:   &tlsConfig,
 	}
 
@@ -77,6 +80,12 @@ func newClient(jar http.CookieJar, roots *x509.CertPool, cert *tls.Certificate,
 }
 
 func doRequest(req *http.Request, jar http.CookieJar, timeout TimeoutType) (*http.Response, *http.Client, error) {
+	// every registry, endpoint or mirror reached goes through the policy,
+	// not only the one the session was opened with
+	if err := CheckRegistryPolicy(req.URL.Host); err != nil {
+		return nil, nil, err
+	}
+
 	hasFile := func(files []os.FileInfo, name string) bool {
 		for _, f := range files {
 			if f.Name() == name {
@@ -131,8 +140,9 @@ func doRequest(req *http.Request, jar http.CookieJar, timeout TimeoutType) (*htt
 		}
 	}
 
+	secure := IsSecure(req.URL.Host)
 	if len(certs) == 0 {
-		client := newClient(jar, pool, nil, timeout)
+		client := newClient(jar, pool, nil, timeout, secure)
 		res, err := client.Do(req)
 		if err != nil {
 			return nil, nil, err
@@ -140,7 +150,7 @@ func doRequest(req *http.Request, jar http.CookieJar, timeout TimeoutType) (*htt
 		return res, client, nil
 	} else {
 		for i, cert := range certs {
-			client := newClient(jar, pool, cert, timeout)
+			client := newClient(jar, pool, cert, timeout, secure)
 			res, err := client.Do(req)
 			if i == len(certs)-1 {
 				// If this is the last cert, always return the result
@@ -262,6 +272,9 @@ func ResolveRepositoryName(reposName string) (string, string, error) {
 // to a full url. if it already is a url, there will be no change.
 // The registry is pinged to test if it http or https
 func ExpandAndVerifyRegistryUrl(hostname string) (string, error) {
+	if err := checkOnline(); err != nil {
+		return "", err
+	}
 	if strings.HasPrefix(hostname, "http:") || strings.HasPrefix(hostname, "https:") {
 		// if there is no slash after https:// (8 characters) then we have no path in the url
 		if strings.LastIndex(hostname, "/") < 9 {
@@ -275,6 +288,9 @@ func ExpandAndVerifyRegistryUrl(hostname string) (string, error) {
 	}
 	endpoint := fmt.Sprintf("https://%s/v1/", hostname)
 	if _, err := pingRegistryEndpoint(endpoint); err != nil {
+		if IsSecure(hostname) {
+			return "", fmt.Errorf("Invalid Registry endpoint %s: %s. If this private registry only supports HTTP or HTTPS with an unknown CA certificate, add `--insecure-registry %s` to the arguments of the daemon", endpoint, err, hostname)
+		}
 		log.Debugf("Registry %s does not work (%s), falling back to http", endpoint, err)
 		endpoint = fmt.Sprintf("http://%s/v1/", hostname)
 		if _, err = pingRegistryEndpoint(endpoint); err != nil {
@@ -303,6 +319,9 @@ func trustedLocation(req *http.Request) bool {
 }
 
 func AddRequiredHeadersToRedirectedRequests(req *http.Request, via []*http.Request) error {
+	if err := CheckRegistryPolicy(req.URL.Host); err != nil {
+		return err
+	}
 	if via != nil && via[0] != nil {
 		if trustedLocation(req) && trustedLocation(via[0]) {
 			req.Header = via[0].Header
@@ -2,10 +2,12 @@ package registry
 
 import (
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/docker/docker/utils"
 )
@@ -300,3 +302,195 @@ func TestAddRequiredHeadersToRedirectedRequests(t *testing.T) {
 		}
 	}
 }
+
+func TestIsSecure(t *testing.T) {
+	SetInsecureRegistries([]string{"registry.example.com", "other.example.com:5000", "10.1.0.0/16"})
+	defer SetInsecureRegistries(nil)
+
+	for hostname, expected := range map[string]bool{
+		"registry.example.com":             false,
+		"registry.example.com:5000":        false,
+		"other.example.com:5000":           false,
+		"other.example.com:5001":           true,
+		"10.1.2.3:5000":                    false,
+		"10.2.2.3:5000":                    true,
+		"127.0.0.1:5000":                   false,
+		"https://registry.example.com/v1/": false,
+		"https://10.2.2.3/v1/":             true,
+	} {
+		if secure := IsSecure(hostname); secure != expected {
+			t.Fatalf("Expected IsSecure(%s) to be %t", hostname, expected)
+		}
+	}
+}
+
+func TestSetProxy(t *testing.T) {
+	defer SetProxy("")
+
+	req, err := http.NewRequest("GET", "https://registry.example.com/v1/_ping", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetProxy("http://proxy.example.com:3128"); err != nil {
+		t.Fatal(err)
+	}
+	if u, err := proxy(req); err != nil || u == nil || u.Host != "proxy.example.com:3128" {
+		t.Fatalf("Expected the requests to go through proxy.example.com:3128, got %v (%v)", u, err)
+	}
+	if err := SetProxy("none"); err != nil {
+		t.Fatal(err)
+	}
+	if proxy != nil {
+		t.Fatal("Expected no proxy")
+	}
+	for _, invalid := range []string{"proxy.example.com:3128", "ftp://proxy.example.com", "http://"} {
+		if err := SetProxy(invalid); err == nil {
+			t.Fatalf("Expected %s to be an invalid proxy", invalid)
+		}
+	}
+}
+
+func TestRegistryPolicy(t *testing.T) {
+	defer SetRegistryPolicy(nil, nil)
+
+	if err := SetRegistryPolicy([]string{"[a-"}, nil); err == nil {
+		t.Fatal("Expected an error for an invalid pattern")
+	}
+	if err := SetRegistryPolicy(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckRegistryPolicy(IndexServerAddress()); err != nil {
+		t.Fatalf("Expected all the registries to be allowed without a policy, got %s", err)
+	}
+
+	if err := SetRegistryPolicy([]string{"*.corp.example.com", "localhost:5000"}, []string{"untrusted.corp.example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	for hostname, allowed := range map[string]bool{
+		"registry.corp.example.com":            true,
+		"registry.corp.example.com:5000":       true,
+		"https://mirror.corp.example.com/v1/":  true,
+		"localhost:5000":                       true,
+		"localhost:5001":                       false,
+		"untrusted.corp.example.com":           false,
+		"untrusted.corp.example.com:443":       false,
+		"corp.example.com":                     false,
+		"registry.example.com":                 false,
+		IndexServerAddress():                   false,
+		"index.docker.io.corp.example.com:443": true,
+	} {
+		if err := CheckRegistryPolicy(hostname); (err == nil) != allowed {
+			t.Errorf("Expected %s to be allowed: %v, got %v", hostname, allowed, err)
+		}
+	}
+}
+
+func TestRegistryPolicyRequests(t *testing.T) {
+	defer SetRegistryPolicy(nil, nil)
+	if err := SetRegistryPolicy([]string{"mirror.corp.example.com"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// the endpoints and the redirects are checked, not only the registry
+	// the session was opened with
+	req, err := http.NewRequest("GET", "https://registry-1.docker.io/v1/images/"+IMAGE_ID+"/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := doRequest(req, nil, NoTimeout); err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Fatalf("Expected the endpoint to be denied, got %v", err)
+	}
+	redirect, err := http.NewRequest("GET", "https://cdn.example.com/layer", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := AddRequiredHeadersToRedirectedRequests(redirect, []*http.Request{req}); err == nil {
+		t.Fatal("Expected the redirect to be denied")
+	}
+}
+
+func TestOffline(t *testing.T) {
+	SetOffline(true)
+	defer SetOffline(false)
+
+	if _, err := ExpandAndVerifyRegistryUrl("registry.invalid:5000"); err != ErrOffline {
+		t.Errorf("Expected ErrOffline checking an endpoint, got %v", err)
+	}
+	if _, err := NewSession(&AuthConfig{}, HTTPRequestFactory(nil), makeURL("/v1/"), true); err != ErrOffline {
+		t.Errorf("Expected ErrOffline opening a v1 session, got %v", err)
+	}
+	if _, err := NewSessionV2(&AuthConfig{}, HTTPRequestFactory(nil), "registry.invalid:5000", true); err != ErrOffline {
+		t.Errorf("Expected ErrOffline opening a v2 session, got %v", err)
+	}
+	if _, err := Login(&AuthConfig{Username: "user", Password: "pass"}, HTTPRequestFactory(nil)); err != ErrOffline {
+		t.Errorf("Expected ErrOffline logging in, got %v", err)
+	}
+
+	SetOffline(false)
+	if _, err := NewSession(&AuthConfig{}, HTTPRequestFactory(nil), makeURL("/v1/"), true); err != nil {
+		t.Errorf("Expected a v1 session once online, got %v", err)
+	}
+}
+
+func TestIndexReachability(t *testing.T) {
+	defer SetProxy("")
+	defer func(address string) {
+		indexAddress = address
+		indexChecked = time.Time{}
+	}(indexAddress)
+	if err := SetProxy("none"); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexAddress = l.Addr().String()
+	l.Close()
+	indexChecked = time.Time{}
+	if err := checkIndex(); err == nil {
+		t.Fatal("Expected an error checking an unreachable index")
+	}
+	if _, err := NewSession(&AuthConfig{}, HTTPRequestFactory(nil), IndexServerAddress(), true); err == nil {
+		t.Fatal("Expected an error opening a session with an unreachable index")
+	}
+
+	l, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	indexAddress = l.Addr().String()
+	if err := checkIndex(); err == nil {
+		t.Fatal("Expected the failure to be cached")
+	}
+	indexChecked = time.Now().Add(-reachabilityTTL)
+	if err := checkIndex(); err != nil {
+		t.Fatalf("Expected the index to be checked again once the cache expired, got %s", err)
+	}
+
+	if err := SetProxy("http://proxy.example.com:3128"); err != nil {
+		t.Fatal(err)
+	}
+	indexAddress = "127.0.0.1:1"
+	indexChecked = time.Time{}
+	if err := checkIndex(); err != nil {
+		t.Fatalf("Expected the index not to be checked through a proxy, got %s", err)
+	}
+}
+
+func TestLookupCache(t *testing.T) {
+	lookupsLock.Lock()
+	lookups["cached.example.com"] = &cachedLookup{ips: []net.IP{net.ParseIP("127.0.0.1")}, checked: time.Now()}
+	lookupsLock.Unlock()
+	defer func() {
+		lookupsLock.Lock()
+		delete(lookups, "cached.example.com")
+		lookupsLock.Unlock()
+	}()
+
+	if IsSecure("cached.example.com:5000") {
+		t.Fatal("Expected the cached loopback address to make the registry insecure")
+	}
+}
@@ -40,6 +40,19 @@ func (s *Service) Auth(job *engine.Job) engine.Status {
 	job.GetenvJson("authConfig", authConfig)
 	// TODO: this is only done here because auth and registry need to be merged into one pkg
 	if addr := authConfig.ServerAddress; addr != "" && addr != IndexServerAddress() {
+		// log in with the v2 protocol when the registry supports it
+		r, err := NewSessionV2(authConfig, HTTPRequestFactory(nil), convertToHostname(addr), true)
+		if err == nil {
+			status, err := r.Login()
+			if err != nil {
+				return job.Error(err)
+			}
+			job.Printf("%s\n", status)
+			return engine.StatusOK
+		} else if err != ErrNoV2 {
+			return job.Error(err)
+		}
+
 		addr, err = ExpandAndVerifyRegistryUrl(addr)
 		if err != nil {
 			return job.Error(err)
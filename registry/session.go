@@ -31,6 +31,17 @@ type Session struct {
 }
 
 func NewSession(authConfig *AuthConfig, factory *utils.HTTPRequestFactory, indexEndpoint string, timeout bool) (r *Session, err error) {
+	if err := checkOnline(); err != nil {
+		return nil, err
+	}
+	if err := CheckRegistryPolicy(indexEndpoint); err != nil {
+		return nil, err
+	}
+	if indexEndpoint == IndexServerAddress() {
+		if err := checkIndex(); err != nil {
+			return nil, err
+		}
+	}
 	r = &Session{
 		authConfig:    authConfig,
 		indexEndpoint: indexEndpoint,
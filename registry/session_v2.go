@@ -0,0 +1,530 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/utils"
+)
+
+var ErrNoV2 = errors.New("The registry does not support the v2 protocol")
+
+// SessionV2 talks to a registry with the v2 protocol, where an image is
+// stored as a manifest referring to its layers by digest.
+type SessionV2 struct {
+	authConfig *AuthConfig
+	reqFactory *utils.HTTPRequestFactory
+	endpoint   string
+	jar        *cookiejar.Jar
+	timeout    TimeoutType
+	challenge  *authChallenge
+
+	// the bearer tokens, by scope
+	tokensLock sync.Mutex
+	tokens     map[string]*bearerToken
+}
+
+// defaultTokenExpiry is how long the tokens given without expires_in last,
+// as in the token specification. A token is renewed tokenRenewMargin before
+// it expires, so that it does not expire on the way to the registry.
+const (
+	defaultTokenExpiry = 60 * time.Second
+	tokenRenewMargin   = 5 * time.Second
+)
+
+type bearerToken struct {
+	token   string
+	expires time.Time
+}
+
+// authChallenge is the authentication asked by a registry in the
+// WWW-Authenticate header of its 401 responses.
+type authChallenge struct {
+	Scheme     string
+	Parameters map[string]string
+}
+
+// NewSessionV2 returns a session with the registry of hostname, or ErrNoV2
+// when it does not support the v2 protocol. The policy of the daemon may
+// not allow the registry.
+func NewSessionV2(authConfig *AuthConfig, factory *utils.HTTPRequestFactory, hostname string, timeout bool) (r *SessionV2, err error) {
+	if err := checkOnline(); err != nil {
+		return nil, err
+	}
+	if err := CheckRegistryPolicy(hostname); err != nil {
+		return nil, err
+	}
+	r = &SessionV2{
+		authConfig: authConfig,
+		reqFactory: factory,
+		tokens:     make(map[string]*bearerToken),
+	}
+	if timeout {
+		r.timeout = ReceiveTimeout
+	}
+	r.jar, err = cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	if r.endpoint, r.challenge, err = pingV2(hostname); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// pingV2 returns the v2 endpoint of hostname, over HTTP only for the
+// registries allowed with --insecure-registry, and the authentication it
+// asks for.
+func pingV2(hostname string) (string, *authChallenge, error) {
+	schemes := []string{"https"}
+	if !IsSecure(hostname) {
+		schemes = append(schemes, "http")
+	}
+	var lastErr error
+	for _, scheme := range schemes {
+		endpoint := fmt.Sprintf("%s://%s/v2/", scheme, hostname)
+		req, err := http.NewRequest("GET", endpoint, nil)
+		if err != nil {
+			return "", nil, err
+		}
+		res, _, err := doRequest(req, nil, ConnectTimeout)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		res.Body.Close()
+		if !strings.HasPrefix(res.Header.Get("Docker-Distribution-API-Version"), "registry/2.") {
+			return "", nil, ErrNoV2
+		}
+		switch res.StatusCode {
+		case 200:
+			return endpoint, nil, nil
+		case 401:
+			return endpoint, parseAuthChallenge(res.Header.Get("WWW-Authenticate")), nil
+		}
+		return "", nil, ErrNoV2
+	}
+	log.Debugf("Registry %s does not answer the v2 ping: %s", hostname, lastErr)
+	return "", nil, ErrNoV2
+}
+
+// parseAuthChallenge parses a header such as
+// `Bearer realm="https://auth.example.com/token",service="registry"`.
+func parseAuthChallenge(header string) *authChallenge {
+	parts := strings.SplitN(strings.TrimSpace(header), " ", 2)
+	if parts[0] == "" {
+		return nil
+	}
+	challenge := &authChallenge{
+		Scheme:     strings.ToLower(parts[0]),
+		Parameters: make(map[string]string),
+	}
+	if len(parts) == 1 {
+		return challenge
+	}
+	for _, param := range strings.Split(parts[1], ",") {
+		kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		challenge.Parameters[strings.ToLower(kv[0])] = strings.Trim(kv[1], `"`)
+	}
+	return challenge
+}
+
+// getToken returns a bearer token for scope from the authentication server
+// of the registry, logged in with the credentials of the session if any. An
+// empty scope only checks the credentials. The tokens are cached until they
+// expire.
+func (r *SessionV2) getToken(scope string) (string, error) {
+	r.tokensLock.Lock()
+	defer r.tokensLock.Unlock()
+	if cached, exists := r.tokens[scope]; exists && time.Now().Add(tokenRenewMargin).Before(cached.expires) {
+		return cached.token, nil
+	}
+
+	realm := r.challenge.Parameters["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("No realm in the authentication challenge of %s", r.endpoint)
+	}
+	v := url.Values{}
+	if scope != "" {
+		v.Set("scope", scope)
+	}
+	if service := r.challenge.Parameters["service"]; service != "" {
+		v.Set("service", service)
+	}
+	req, err := r.reqFactory.NewRequest("GET", realm+"?"+v.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	if r.authConfig != nil && r.authConfig.Username != "" {
+		req.SetBasicAuth(r.authConfig.Username, r.authConfig.Password)
+	}
+	res, _, err := doRequest(req, r.jar, r.timeout)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		if res.StatusCode == 401 {
+			return "", errLoginRequired
+		}
+		return "", utils.NewHTTPRequestError(fmt.Sprintf("Server error: %d trying to get a token for %s", res.StatusCode, scope), res)
+	}
+	var tokenResponse struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&tokenResponse); err != nil {
+		return "", err
+	}
+	token := tokenResponse.Token
+	if token == "" {
+		token = tokenResponse.AccessToken
+	}
+	if token == "" {
+		return "", fmt.Errorf("No token in the response of %s", realm)
+	}
+	expiry := defaultTokenExpiry
+	if tokenResponse.ExpiresIn > 0 {
+		expiry = time.Duration(tokenResponse.ExpiresIn) * time.Second
+	}
+	r.tokens[scope] = &bearerToken{token: token, expires: time.Now().Add(expiry)}
+	return token, nil
+}
+
+// dropToken forgets the token of scope, refused by the registry, unless it
+// was already renewed.
+func (r *SessionV2) dropToken(scope, token string) {
+	r.tokensLock.Lock()
+	defer r.tokensLock.Unlock()
+	if cached, exists := r.tokens[scope]; exists && cached.token == token {
+		delete(r.tokens, scope)
+	}
+}
+
+// doRequest sends req with the authorization needed to access remote, for
+// pulling or, if push is true, pushing. When the registry refuses a bearer
+// token, which may have been revoked, a new one is asked for and a request
+// without body is sent again.
+func (r *SessionV2) doRequest(req *http.Request, remote string, push bool) (*http.Response, error) {
+	scope := "repository:" + remote + ":pull"
+	if push {
+		scope += ",push"
+	}
+	for retried := false; ; retried = true {
+		var token string
+		if r.challenge != nil {
+			switch r.challenge.Scheme {
+			case "bearer":
+				var err error
+				if token, err = r.getToken(scope); err != nil {
+					return nil, err
+				}
+				req.Header.Set("Authorization", "Bearer "+token)
+			case "basic":
+				if r.authConfig != nil && r.authConfig.Username != "" {
+					req.SetBasicAuth(r.authConfig.Username, r.authConfig.Password)
+				}
+			}
+		}
+		res, _, err := doRequest(req, r.jar, r.timeout)
+		if err != nil {
+			return nil, err
+		}
+		if res.StatusCode != 401 {
+			return res, nil
+		}
+		res.Body.Close()
+		if token == "" || retried || req.Body != nil {
+			return nil, errLoginRequired
+		}
+		r.dropToken(scope, token)
+	}
+}
+
+// Login checks the credentials of the session against the registry.
+func (r *SessionV2) Login() (string, error) {
+	req, err := r.reqFactory.NewRequest("GET", r.endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	if r.challenge != nil {
+		switch r.challenge.Scheme {
+		case "bearer":
+			token, err := r.getToken("")
+			if err == errLoginRequired {
+				return "", fmt.Errorf("Wrong login/password, please try again")
+			} else if err != nil {
+				return "", err
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+		case "basic":
+			req.SetBasicAuth(r.authConfig.Username, r.authConfig.Password)
+		}
+	}
+	res, _, err := doRequest(req, r.jar, r.timeout)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case 200:
+		return "Login Succeeded", nil
+	case 401:
+		return "", fmt.Errorf("Wrong login/password, please try again")
+	}
+	return "", utils.NewHTTPRequestError(fmt.Sprintf("Login: unexpected status code %d from %s", res.StatusCode, r.endpoint), res)
+}
+
+func (r *SessionV2) url(remote, kind, reference string) string {
+	return r.endpoint + remote + "/" + kind + "/" + reference
+}
+
+// GetV2RemoteTags returns the tags of remote.
+func (r *SessionV2) GetV2RemoteTags(remote string) ([]string, error) {
+	req, err := r.reqFactory.NewRequest("GET", r.url(remote, "tags", "list"), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := r.doRequest(req, remote, false)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		return nil, utils.NewHTTPRequestError(fmt.Sprintf("Server error: %d trying to fetch the tags of %s", res.StatusCode, remote), res)
+	}
+	var tags struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&tags); err != nil {
+		return nil, err
+	}
+	return tags.Tags, nil
+}
+
+// GetV2ImageManifest returns the manifest of remote for reference, a tag
+// or a digest, and the digest of the manifest.
+func (r *SessionV2) GetV2ImageManifest(remote, reference string) ([]byte, string, error) {
+	req, err := r.reqFactory.NewRequest("GET", r.url(remote, "manifests", reference), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	res, err := r.doRequest(req, remote, false)
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		if res.StatusCode == 404 {
+			return nil, "", fmt.Errorf("Tag %s not found in repository %s", reference, remote)
+		}
+		return nil, "", utils.NewHTTPRequestError(fmt.Sprintf("Server error: %d trying to fetch the manifest of %s:%s", res.StatusCode, remote, reference), res)
+	}
+	manifest, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("Error while reading the http response: %s", err)
+	}
+	return manifest, res.Header.Get("Docker-Content-Digest"), nil
+}
+
+// PutV2ImageManifest tags the signed manifest as remote:tag and returns its
+// digest.
+func (r *SessionV2) PutV2ImageManifest(remote, tag string, manifest []byte) (string, error) {
+	req, err := r.reqFactory.NewRequest("PUT", r.url(remote, "manifests", tag), bytes.NewReader(manifest))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/vnd.docker.distribution.manifest.v1+prettyjws")
+	res, err := r.doRequest(req, remote, true)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 201 && res.StatusCode != 202 {
+		errBody, _ := ioutil.ReadAll(res.Body)
+		return "", utils.NewHTTPRequestError(fmt.Sprintf("Server error: %d trying to push the manifest of %s:%s: %s", res.StatusCode, remote, tag, errBody), res)
+	}
+	return res.Header.Get("Docker-Content-Digest"), nil
+}
+
+// HeadV2ImageBlob returns whether the blob with the given digest already
+// exists in remote.
+func (r *SessionV2) HeadV2ImageBlob(remote, digest string) (bool, error) {
+	req, err := r.reqFactory.NewRequest("HEAD", r.url(remote, "blobs", digest), nil)
+	if err != nil {
+		return false, err
+	}
+	res, err := r.doRequest(req, remote, true)
+	if err != nil {
+		return false, err
+	}
+	res.Body.Close()
+	switch res.StatusCode {
+	case 200:
+		return true, nil
+	case 404:
+		return false, nil
+	}
+	return false, utils.NewHTTPRequestError(fmt.Sprintf("Server error: %d trying to check the blob %s", res.StatusCode, digest), res)
+}
+
+// GetV2ImageBlobReader returns the content of the blob with the given
+// digest, and its size or -1 when unknown.
+func (r *SessionV2) GetV2ImageBlobReader(remote, digest string) (io.ReadCloser, int64, error) {
+	req, err := r.reqFactory.NewRequest("GET", r.url(remote, "blobs", digest), nil)
+	if err != nil {
+		return nil, -1, err
+	}
+	res, err := r.doRequest(req, remote, false)
+	if err != nil {
+		return nil, -1, err
+	}
+	if res.StatusCode != 200 {
+		res.Body.Close()
+		return nil, -1, fmt.Errorf("Server error: Status %d while fetching blob %s", res.StatusCode, digest)
+	}
+	size := int64(-1)
+	if hdr := res.Header.Get("Content-Length"); hdr != "" {
+		if size, err = strconv.ParseInt(hdr, 10, 64); err != nil {
+			size = -1
+		}
+	}
+	return res.Body, size, nil
+}
+
+// PutV2ImageBlob uploads the blob with the given digest to remote in a
+// single request.
+func (r *SessionV2) PutV2ImageBlob(remote, digest string, blob io.Reader, size int64) error {
+	location, err := r.startV2BlobUpload(remote)
+	if err != nil {
+		return err
+	}
+	return r.finishV2BlobUpload(remote, location, digest, blob, size)
+}
+
+// PutV2ImageBlobChunked uploads the blob with the given digest to remote
+// in requests of at most chunkSize bytes, for the large blobs which a proxy
+// would not let through in one request.
+func (r *SessionV2) PutV2ImageBlobChunked(remote, digest string, blob io.Reader, chunkSize int64) error {
+	location, err := r.startV2BlobUpload(remote)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, chunkSize)
+	offset := int64(0)
+	for {
+		n, err := io.ReadFull(blob, buf)
+		if n > 0 {
+			if location, err = r.patchV2BlobUpload(remote, location, buf[:n], offset); err != nil {
+				return err
+			}
+			offset += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+	return r.finishV2BlobUpload(remote, location, digest, nil, 0)
+}
+
+// startV2BlobUpload returns the location where to upload a new blob.
+func (r *SessionV2) startV2BlobUpload(remote string) (string, error) {
+	req, err := r.reqFactory.NewRequest("POST", r.url(remote, "blobs", "uploads/"), nil)
+	if err != nil {
+		return "", err
+	}
+	res, err := r.doRequest(req, remote, true)
+	if err != nil {
+		return "", err
+	}
+	res.Body.Close()
+	if res.StatusCode != 202 {
+		return "", utils.NewHTTPRequestError(fmt.Sprintf("Server error: %d trying to start a blob upload to %s", res.StatusCode, remote), res)
+	}
+	return r.location(res)
+}
+
+func (r *SessionV2) patchV2BlobUpload(remote, location string, chunk []byte, offset int64) (string, error) {
+	req, err := r.reqFactory.NewRequest("PATCH", location, bytes.NewReader(chunk))
+	if err != nil {
+		return "", err
+	}
+	req.ContentLength = int64(len(chunk))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Range", fmt.Sprintf("%d-%d", offset, offset+int64(len(chunk))-1))
+	res, err := r.doRequest(req, remote, true)
+	if err != nil {
+		return "", err
+	}
+	res.Body.Close()
+	if res.StatusCode != 202 {
+		return "", utils.NewHTTPRequestError(fmt.Sprintf("Server error: %d trying to upload a blob chunk to %s", res.StatusCode, remote), res)
+	}
+	return r.location(res)
+}
+
+// finishV2BlobUpload completes the upload at location with the last part
+// of the blob, if any.
+func (r *SessionV2) finishV2BlobUpload(remote, location, digest string, blob io.Reader, size int64) error {
+	u, err := url.Parse(location)
+	if err != nil {
+		return err
+	}
+	q := u.Query()
+	q.Set("digest", digest)
+	u.RawQuery = q.Encode()
+	if blob == nil {
+		blob = bytes.NewReader(nil)
+	}
+	req, err := r.reqFactory.NewRequest("PUT", u.String(), blob)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	res, err := r.doRequest(req, remote, true)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 201 {
+		errBody, _ := ioutil.ReadAll(res.Body)
+		return utils.NewHTTPRequestError(fmt.Sprintf("Server error: %d trying to upload the blob %s: %s", res.StatusCode, digest, errBody), res)
+	}
+	return nil
+}
+
+// location returns the absolute URL of the Location header of res.
+func (r *SessionV2) location(res *http.Response) (string, error) {
+	location := res.Header.Get("Location")
+	if location == "" {
+		return "", fmt.Errorf("No location in the response of %s", res.Request.URL)
+	}
+	base, err := url.Parse(r.endpoint)
+	if err != nil {
+		return "", err
+	}
+	u, err := base.Parse(location)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
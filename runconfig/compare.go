@@ -13,10 +13,18 @@ func Compare(a, b *Config) bool {
 		a.Memory != b.Memory ||
 		a.MemorySwap != b.MemorySwap ||
 		a.CpuShares != b.CpuShares ||
+		a.CpuQuota != b.CpuQuota ||
+		a.CpuPeriod != b.CpuPeriod ||
+		a.BlkioWeight != b.BlkioWeight ||
+		a.OomKillDisable != b.OomKillDisable ||
 		a.OpenStdin != b.OpenStdin ||
 		a.Tty != b.Tty {
 		return false
 	}
+	if (a.MemorySwappiness == nil) != (b.MemorySwappiness == nil) ||
+		(a.MemorySwappiness != nil && *a.MemorySwappiness != *b.MemorySwappiness) {
+		return false
+	}
 	if len(a.Cmd) != len(b.Cmd) ||
 		len(a.Env) != len(b.Env) ||
 		len(a.PortSpecs) != len(b.PortSpecs) ||
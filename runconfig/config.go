@@ -9,29 +9,34 @@ import (
 // Here, "portable" means "independent from the host we are running on".
 // Non-portable information *should* appear in HostConfig.
 type Config struct {
-	Hostname        string
-	Domainname      string
-	User            string
-	Memory          int64  // Memory limit (in bytes)
-	MemorySwap      int64  // Total memory usage (memory + swap); set `-1' to disable swap
-	CpuShares       int64  // CPU shares (relative weight vs. other containers)
-	Cpuset          string // Cpuset 0-2, 0,1
-	AttachStdin     bool
-	AttachStdout    bool
-	AttachStderr    bool
-	PortSpecs       []string // Deprecated - Can be in the format of 8080/tcp
-	ExposedPorts    map[nat.Port]struct{}
-	Tty             bool // Attach standard streams to a tty, including stdin if it is not closed.
-	OpenStdin       bool // Open stdin
-	StdinOnce       bool // If true, close stdin after the 1 attached client disconnects.
-	Env             []string
-	Cmd             []string
-	Image           string // Name of the image as it was passed by the operator (eg. could be symbolic)
-	Volumes         map[string]struct{}
-	WorkingDir      string
-	Entrypoint      []string
-	NetworkDisabled bool
-	OnBuild         []string
+	Hostname         string
+	Domainname       string
+	User             string
+	Memory           int64  // Memory limit (in bytes)
+	MemorySwap       int64  // Total memory usage (memory + swap); set `-1' to disable swap
+	CpuShares        int64  // CPU shares (relative weight vs. other containers)
+	CpuQuota         int64  // CPU time (in usecs) the container can use in each CPU period
+	CpuPeriod        int64  // Length (in usecs) of the CPU period; 0 to use the kernel default
+	Cpuset           string // Cpuset 0-2, 0,1
+	BlkioWeight      int64  // Block IO weight (relative weight vs. other containers, 10 to 1000)
+	MemorySwappiness *int64 // Tendency to swap the memory out, 0 to 100; the one of the host when nil
+	OomKillDisable   bool   // Pause the processes rather than kill them when the memory limit is reached
+	AttachStdin      bool
+	AttachStdout     bool
+	AttachStderr     bool
+	PortSpecs        []string // Deprecated - Can be in the format of 8080/tcp
+	ExposedPorts     map[nat.Port]struct{}
+	Tty              bool // Attach standard streams to a tty, including stdin if it is not closed.
+	OpenStdin        bool // Open stdin
+	StdinOnce        bool // If true, close stdin after the 1 attached client disconnects.
+	Env              []string
+	Cmd              []string
+	Image            string // Name of the image as it was passed by the operator (eg. could be symbolic)
+	Volumes          map[string]struct{}
+	WorkingDir       string
+	Entrypoint       []string
+	NetworkDisabled  bool
+	OnBuild          []string
 }
 
 func ContainerConfigFromJob(job *engine.Job) *Config {
@@ -42,7 +47,11 @@ func ContainerConfigFromJob(job *engine.Job) *Config {
 		Memory:          job.GetenvInt64("Memory"),
 		MemorySwap:      job.GetenvInt64("MemorySwap"),
 		CpuShares:       job.GetenvInt64("CpuShares"),
+		CpuQuota:        job.GetenvInt64("CpuQuota"),
+		CpuPeriod:       job.GetenvInt64("CpuPeriod"),
 		Cpuset:          job.Getenv("Cpuset"),
+		BlkioWeight:     job.GetenvInt64("BlkioWeight"),
+		OomKillDisable:  job.GetenvBool("OomKillDisable"),
 		AttachStdin:     job.GetenvBool("AttachStdin"),
 		AttachStdout:    job.GetenvBool("AttachStdout"),
 		AttachStderr:    job.GetenvBool("AttachStderr"),
@@ -54,6 +63,7 @@ func ContainerConfigFromJob(job *engine.Job) *Config {
 		NetworkDisabled: job.GetenvBool("NetworkDisabled"),
 	}
 	job.GetenvJson("ExposedPorts", &config.ExposedPorts)
+	job.GetenvJson("MemorySwappiness", &config.MemorySwappiness)
 	job.GetenvJson("Volumes", &config.Volumes)
 	if PortSpecs := job.GetenvList("PortSpecs"); PortSpecs != nil {
 		config.PortSpecs = PortSpecs
@@ -0,0 +1,69 @@
+package runconfig
+
+// FieldFlags maps every field of Config and HostConfig, named as
+// "Config.Field" or "HostConfig.Field", to the flags of docker run and
+// docker create which set it. It keeps the command line on par with the
+// create API: a field added to either structure needs a flag here.
+//
+// The image and the command come from the arguments rather than from a
+// flag, PortSpecs is deprecated and OnBuild is only set by the builder, so
+// they map to no flag.
+var FieldFlags = map[string][]string{
+	"Config.Hostname":         {"-hostname"},
+	"Config.Domainname":       {"-hostname"},
+	"Config.User":             {"-user"},
+	"Config.Memory":           {"-memory"},
+	"Config.MemorySwap":       {"-memory-swap"},
+	"Config.CpuShares":        {"-cpu-shares"},
+	"Config.CpuQuota":         {"-cpu-quota"},
+	"Config.CpuPeriod":        {"-cpu-period"},
+	"Config.Cpuset":           {"-cpuset"},
+	"Config.BlkioWeight":      {"-blkio-weight"},
+	"Config.MemorySwappiness": {"-memory-swappiness"},
+	"Config.OomKillDisable":   {"-oom-kill-disable"},
+	"Config.AttachStdin":      {"-attach", "-interactive"},
+	"Config.AttachStdout":     {"-attach", "-detach"},
+	"Config.AttachStderr":     {"-attach", "-detach"},
+	"Config.PortSpecs":        nil,
+	"Config.ExposedPorts":     {"-expose", "-publish"},
+	"Config.Tty":              {"-tty"},
+	"Config.OpenStdin":        {"-interactive"},
+	"Config.StdinOnce":        {"-interactive", "-attach"},
+	"Config.Env":              {"-env", "-env-file"},
+	"Config.Cmd":              nil,
+	"Config.Image":            nil,
+	"Config.Volumes":          {"-volume"},
+	"Config.WorkingDir":       {"-workdir"},
+	"Config.Entrypoint":       {"-entrypoint"},
+	"Config.NetworkDisabled":  {"-networking"},
+	"Config.OnBuild":          nil,
+
+	"HostConfig.Binds":           {"-volume"},
+	"HostConfig.ContainerIDFile": {"-cidfile"},
+	"HostConfig.LxcConf":         {"-lxc-conf"},
+	"HostConfig.Privileged":      {"-privileged"},
+	"HostConfig.PortBindings":    {"-publish"},
+	"HostConfig.Links":           {"-link"},
+	"HostConfig.PublishAllPorts": {"-publish-all"},
+	"HostConfig.Dns":             {"-dns"},
+	"HostConfig.DnsSearch":       {"-dns-search"},
+	"HostConfig.ExtraHosts":      {"-add-host"},
+	"HostConfig.VolumesFrom":     {"-volumes-from"},
+	"HostConfig.Devices":         {"-device"},
+	"HostConfig.NetworkMode":     {"-net"},
+	"HostConfig.PidMode":         {"-pid"},
+	"HostConfig.IpcMode":         {"-ipc"},
+	"HostConfig.UTSMode":         {"-uts"},
+	"HostConfig.CapAdd":          {"-cap-add"},
+	"HostConfig.CapDrop":         {"-cap-drop"},
+	"HostConfig.RestartPolicy":   {"-restart"},
+	"HostConfig.VolumeDriver":    {"-volume-driver"},
+	"HostConfig.ReadonlyRootfs":  {"-read-only"},
+	"HostConfig.Ulimits":         {"-ulimit"},
+	"HostConfig.Tmpfs":           {"-tmpfs"},
+	"HostConfig.SecurityOpt":     {"-security-opt"},
+	"HostConfig.PreStart":        {"-pre-start"},
+	"HostConfig.PostStop":        {"-post-stop"},
+	"HostConfig.CgroupParent":    {"-cgroup-parent"},
+	"HostConfig.ServiceAliases":  {"-service-alias"},
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/docker/docker/engine"
 	"github.com/docker/docker/nat"
+	"github.com/docker/docker/pkg/ulimit"
 	"github.com/docker/docker/utils"
 )
 
@@ -19,6 +20,73 @@ func (n NetworkMode) IsContainer() bool {
 	return len(parts) > 1 && parts[0] == "container"
 }
 
+// joinedContainer returns the container of a "container:<name|id>" mode,
+// or "" for the other modes.
+func joinedContainer(mode string) string {
+	parts := strings.SplitN(mode, ":", 2)
+	if len(parts) > 1 && parts[0] == "container" {
+		return parts[1]
+	}
+	return ""
+}
+
+// PidMode is the PID namespace of a container: its own when empty, the one
+// of the host with "host", or the one of another container with
+// "container:<name|id>".
+type PidMode string
+
+func (n PidMode) IsHost() bool {
+	return n == "host"
+}
+
+func (n PidMode) IsContainer() bool {
+	return n.Container() != ""
+}
+
+// Container returns the container whose PID namespace is joined.
+func (n PidMode) Container() string {
+	return joinedContainer(string(n))
+}
+
+func (n PidMode) Valid() bool {
+	return n == "" || n.IsHost() || n.IsContainer()
+}
+
+// IpcMode is the IPC namespace, holding the System V IPC objects and the
+// POSIX message queues, of a container: its own when empty, the one of the
+// host with "host", or the one of another container with
+// "container:<name|id>".
+type IpcMode string
+
+func (n IpcMode) IsHost() bool {
+	return n == "host"
+}
+
+func (n IpcMode) IsContainer() bool {
+	return n.Container() != ""
+}
+
+// Container returns the container whose IPC namespace is joined.
+func (n IpcMode) Container() string {
+	return joinedContainer(string(n))
+}
+
+func (n IpcMode) Valid() bool {
+	return n == "" || n.IsHost() || n.IsContainer()
+}
+
+// UTSMode is the UTS namespace, holding the hostname, of a container: its
+// own when empty, or the one of the host with "host".
+type UTSMode string
+
+func (n UTSMode) IsHost() bool {
+	return n == "host"
+}
+
+func (n UTSMode) Valid() bool {
+	return n == "" || n.IsHost()
+}
+
 type DeviceMapping struct {
 	PathOnHost        string
 	PathInContainer   string
@@ -40,12 +108,25 @@ type HostConfig struct {
 	PublishAllPorts bool
 	Dns             []string
 	DnsSearch       []string
+	ExtraHosts      []string // entries added to /etc/hosts, as HOST:IP
 	VolumesFrom     []string
 	Devices         []DeviceMapping
 	NetworkMode     NetworkMode
+	PidMode         PidMode
+	IpcMode         IpcMode
+	UTSMode         UTSMode
 	CapAdd          []string
 	CapDrop         []string
 	RestartPolicy   RestartPolicy
+	VolumeDriver    string
+	ReadonlyRootfs  bool
+	Ulimits         []*ulimit.Ulimit
+	Tmpfs           map[string]string // tmpfs mount options keyed by path in the container
+	SecurityOpt     []string          // seccomp=unconfined or seccomp=<profile in JSON>
+	PreStart        []string          // commands run on the host before each start of the container
+	PostStop        []string          // commands run on the host each time the container stops
+	CgroupParent    string            // cgroup the cgroup of the container is created in, the one of the daemon when empty
+	ServiceAliases  []string          // names the embedded DNS answers with the container, round-robin with the other containers of the name
 }
 
 func ContainerHostConfigFromJob(job *engine.Job) *HostConfig {
@@ -54,12 +135,20 @@ func ContainerHostConfigFromJob(job *engine.Job) *HostConfig {
 		Privileged:      job.GetenvBool("Privileged"),
 		PublishAllPorts: job.GetenvBool("PublishAllPorts"),
 		NetworkMode:     NetworkMode(job.Getenv("NetworkMode")),
+		PidMode:         PidMode(job.Getenv("PidMode")),
+		IpcMode:         IpcMode(job.Getenv("IpcMode")),
+		UTSMode:         UTSMode(job.Getenv("UTSMode")),
+		VolumeDriver:    job.Getenv("VolumeDriver"),
+		ReadonlyRootfs:  job.GetenvBool("ReadonlyRootfs"),
+		CgroupParent:    job.Getenv("CgroupParent"),
 	}
 
 	job.GetenvJson("LxcConf", &hostConfig.LxcConf)
 	job.GetenvJson("PortBindings", &hostConfig.PortBindings)
 	job.GetenvJson("Devices", &hostConfig.Devices)
 	job.GetenvJson("RestartPolicy", &hostConfig.RestartPolicy)
+	job.GetenvJson("Tmpfs", &hostConfig.Tmpfs)
+	job.GetenvJson("Ulimits", &hostConfig.Ulimits)
 	if Binds := job.GetenvList("Binds"); Binds != nil {
 		hostConfig.Binds = Binds
 	}
@@ -72,6 +161,9 @@ func ContainerHostConfigFromJob(job *engine.Job) *HostConfig {
 	if DnsSearch := job.GetenvList("DnsSearch"); DnsSearch != nil {
 		hostConfig.DnsSearch = DnsSearch
 	}
+	if ExtraHosts := job.GetenvList("ExtraHosts"); ExtraHosts != nil {
+		hostConfig.ExtraHosts = ExtraHosts
+	}
 	if VolumesFrom := job.GetenvList("VolumesFrom"); VolumesFrom != nil {
 		hostConfig.VolumesFrom = VolumesFrom
 	}
@@ -81,6 +173,18 @@ func ContainerHostConfigFromJob(job *engine.Job) *HostConfig {
 	if CapDrop := job.GetenvList("CapDrop"); CapDrop != nil {
 		hostConfig.CapDrop = CapDrop
 	}
+	if ServiceAliases := job.GetenvList("ServiceAliases"); ServiceAliases != nil {
+		hostConfig.ServiceAliases = ServiceAliases
+	}
+	if SecurityOpt := job.GetenvList("SecurityOpt"); SecurityOpt != nil {
+		hostConfig.SecurityOpt = SecurityOpt
+	}
+	if PreStart := job.GetenvList("PreStart"); PreStart != nil {
+		hostConfig.PreStart = PreStart
+	}
+	if PostStop := job.GetenvList("PostStop"); PostStop != nil {
+		hostConfig.PostStop = PostStop
+	}
 
 	return hostConfig
 }
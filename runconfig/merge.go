@@ -20,6 +20,18 @@ func Merge(userConf, imageConf *Config) error {
 	if userConf.CpuShares == 0 {
 		userConf.CpuShares = imageConf.CpuShares
 	}
+	if userConf.CpuQuota == 0 {
+		userConf.CpuQuota = imageConf.CpuQuota
+	}
+	if userConf.CpuPeriod == 0 {
+		userConf.CpuPeriod = imageConf.CpuPeriod
+	}
+	if userConf.BlkioWeight == 0 {
+		userConf.BlkioWeight = imageConf.BlkioWeight
+	}
+	if userConf.MemorySwappiness == nil {
+		userConf.MemorySwappiness = imageConf.MemorySwappiness
+	}
 	if len(userConf.ExposedPorts) == 0 {
 		userConf.ExposedPorts = imageConf.ExposedPorts
 	} else if imageConf.ExposedPorts != nil {
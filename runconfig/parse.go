@@ -11,7 +11,9 @@ import (
 	"github.com/docker/docker/opts"
 	flag "github.com/docker/docker/pkg/mflag"
 	"github.com/docker/docker/pkg/parsers"
+	"github.com/docker/docker/pkg/seccomp"
 	"github.com/docker/docker/pkg/sysinfo"
+	"github.com/docker/docker/pkg/ulimit"
 	"github.com/docker/docker/pkg/units"
 	"github.com/docker/docker/utils"
 )
@@ -23,8 +25,14 @@ var (
 	ErrConflictNetworkHostname            = fmt.Errorf("Conflicting options: -h and the network mode (--net)")
 	ErrConflictHostNetworkAndLinks        = fmt.Errorf("Conflicting options: --net=host can't be used with links. This would result in undefined behavior.")
 	ErrConflictRestartPolicyAndAutoRemove = fmt.Errorf("Conflicting options: --restart and --rm")
+	ErrConflictContainerNetworkAndHosts   = fmt.Errorf("Conflicting options: --add-host and the network mode (--net=container), the container uses the /etc/hosts of the other one")
+	ErrMemorySwapWithoutMemory            = fmt.Errorf("Conflicting options: --memory-swap needs a memory limit (-m)")
+	ErrConflictUTSHostname                = fmt.Errorf("Conflicting options: -h and --uts=host, the container has the hostname of the host")
 )
 
+// DefaultTmpfsOptions are the mount options of a tmpfs given without options.
+const DefaultTmpfsOptions = "rw,nosuid,nodev,noexec"
+
 //FIXME Only used in tests
 func Parse(args []string, sysInfo *sysinfo.SysInfo) (*Config, *HostConfig, *flag.FlagSet, error) {
 	cmd := flag.NewFlagSet("run", flag.ContinueOnError)
@@ -45,22 +53,30 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 		flVolumes = opts.NewListOpts(opts.ValidatePath)
 		flLinks   = opts.NewListOpts(opts.ValidateLink)
 		flEnv     = opts.NewListOpts(opts.ValidateEnv)
-		flDevices = opts.NewListOpts(opts.ValidatePath)
+		flDevices = opts.NewListOpts(nil)
 
 		flPublish     = opts.NewListOpts(nil)
 		flExpose      = opts.NewListOpts(nil)
 		flDns         = opts.NewListOpts(opts.ValidateIPAddress)
 		flDnsSearch   = opts.NewListOpts(opts.ValidateDnsSearch)
+		flExtraHosts  = opts.NewListOpts(opts.ValidateExtraHost)
 		flVolumesFrom = opts.NewListOpts(nil)
 		flLxcOpts     = opts.NewListOpts(nil)
 		flEnvFile     = opts.NewListOpts(nil)
 		flCapAdd      = opts.NewListOpts(nil)
 		flCapDrop     = opts.NewListOpts(nil)
+		flPreStart    = opts.NewListOpts(nil)
+		flPostStop    = opts.NewListOpts(nil)
+		flTmpfs       = opts.NewListOpts(nil)
+		flServices    = opts.NewListOpts(opts.ValidateServiceAlias)
+		flSecurityOpt = opts.NewListOpts(nil)
+		flUlimits     = opts.NewListOpts(opts.ValidateUlimit)
 
 		flAutoRemove      = cmd.Bool([]string{"#rm", "-rm"}, false, "Automatically remove the container when it exits (incompatible with -d)")
 		flDetach          = cmd.Bool([]string{"d", "-detach"}, false, "Detached mode: run container in the background and print new container ID")
 		flNetwork         = cmd.Bool([]string{"#n", "#-networking"}, true, "Enable networking for this container")
 		flPrivileged      = cmd.Bool([]string{"#privileged", "-privileged"}, false, "Give extended privileges to this container")
+		flReadonlyRootfs  = cmd.Bool([]string{"-read-only"}, false, "Mount the container's root filesystem as read only")
 		flPublishAll      = cmd.Bool([]string{"P", "-publish-all"}, false, "Publish all exposed ports to the host interfaces")
 		flStdin           = cmd.Bool([]string{"i", "-interactive"}, false, "Keep STDIN open even if not attached")
 		flTty             = cmd.Bool([]string{"t", "-tty"}, false, "Allocate a pseudo-TTY")
@@ -68,21 +84,36 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 		flEntrypoint      = cmd.String([]string{"#entrypoint", "-entrypoint"}, "", "Overwrite the default ENTRYPOINT of the image")
 		flHostname        = cmd.String([]string{"h", "-hostname"}, "", "Container host name")
 		flMemoryString    = cmd.String([]string{"m", "-memory"}, "", "Memory limit (format: <number><optional unit>, where unit = b, k, m or g)")
+		flMemorySwap      = cmd.String([]string{"-memory-swap"}, "", "Total memory usage (memory + swap), '-1' to disable swap (format: <number><optional unit>, where unit = b, k, m or g)")
 		flUser            = cmd.String([]string{"u", "-user"}, "", "Username or UID")
 		flWorkingDir      = cmd.String([]string{"w", "-workdir"}, "", "Working directory inside the container")
 		flCpuShares       = cmd.Int64([]string{"c", "-cpu-shares"}, 0, "CPU shares (relative weight)")
+		flCpuQuota        = cmd.Int64([]string{"-cpu-quota"}, 0, "CPU time (in microseconds) the container can use in each CPU period")
+		flCpuPeriod       = cmd.Int64([]string{"-cpu-period"}, 0, "Length (in microseconds) of the CPU period used by --cpu-quota")
 		flCpuset          = cmd.String([]string{"-cpuset"}, "", "CPUs in which to allow execution (0-3, 0,1)")
+		flBlkioWeight     = cmd.Int64([]string{"-blkio-weight"}, 0, "Block IO weight (relative weight, between 10 and 1000)")
+		flSwappiness      = cmd.Int64([]string{"-memory-swappiness"}, -1, "Tendency of the kernel to swap the memory of the container out, between 0 and 100\n-1 keeps the swappiness of the host")
+		flOomKillDisable  = cmd.Bool([]string{"-oom-kill-disable"}, false, "Pause the processes of the container rather than kill them when it reaches its memory limit")
 		flNetMode         = cmd.String([]string{"-net"}, "bridge", "Set the Network mode for the container\n'bridge': creates a new network stack for the container on the docker bridge\n'none': no networking for this container\n'container:<name|id>': reuses another container network stack\n'host': use the host network stack inside the container.  Note: the host mode gives the container full access to local system services such as D-bus and is therefore considered insecure.")
+		flPidMode         = cmd.String([]string{"-pid"}, "", "PID namespace of the container\n'host': use the PID namespace of the host, the container sees and can signal all the processes of the host\n'container:<name|id>': join the PID namespace of another running container, the container stops with it")
+		flIpcMode         = cmd.String([]string{"-ipc"}, "", "IPC namespace of the container\n'host': use the IPC namespace of the host\n'container:<name|id>': join the IPC namespace of another running container")
+		flUTSMode         = cmd.String([]string{"-uts"}, "", "UTS namespace of the container\n'host': use the UTS namespace of the host, the container has the hostname of the host")
 		flRestartPolicy   = cmd.String([]string{"-restart"}, "", "Restart policy to apply when a container exits (no, on-failure, always)")
+		flVolumeDriver    = cmd.String([]string{"-volume-driver"}, "", "Volume driver providing the named volumes of the container (e.g., -v name:/container)")
+		flCgroupParent    = cmd.String([]string{"-cgroup-parent"}, "", "Create the cgroup of the container in this cgroup, relative to the one of the daemon, instead of the default of the daemon\nwith systemd: a slice (e.g. web.slice)")
 		// For documentation purpose
 		_ = cmd.Bool([]string{"#sig-proxy", "-sig-proxy"}, true, "Proxy received signals to the process (even in non-TTY mode). SIGCHLD, SIGSTOP, and SIGKILL are not proxied.")
 		_ = cmd.String([]string{"#name", "-name"}, "", "Assign a name to the container")
+		_ = cmd.String([]string{"-pull"}, "", "Pull the image before creating the container (always, missing, never)")
 	)
 
 	cmd.Var(&flAttach, []string{"a", "-attach"}, "Attach to STDIN, STDOUT or STDERR.")
-	cmd.Var(&flVolumes, []string{"v", "-volume"}, "Bind mount a volume (e.g., from the host: -v /host:/container, from Docker: -v /container)")
+	cmd.Var(&flVolumes, []string{"v", "-volume"}, "Bind mount a volume (e.g., from the host: -v /host:/container, from Docker: -v /container, from a volume driver: -v name:/container)")
 	cmd.Var(&flLinks, []string{"#link", "-link"}, "Add link to another container in the form of name:alias")
-	cmd.Var(&flDevices, []string{"-device"}, "Add a host device to the container (e.g. --device=/dev/sdc:/dev/xvdc)")
+	cmd.Var(&flDevices, []string{"-device"}, "Add a host device to the container (e.g. --device=/dev/sdc:/dev/xvdc:rwm)\nthe container can read (r), write (w) and mknod (m) it, all by default")
+	cmd.Var(&flUlimits, []string{"-ulimit"}, "Set a ulimit of the container, as NAME=SOFT[:HARD] (e.g. --ulimit=nofile=1024:2048)")
+	cmd.Var(&flTmpfs, []string{"-tmpfs"}, "Mount a tmpfs in the container (e.g. --tmpfs=/run:rw,size=64m)")
+	cmd.Var(&flSecurityOpt, []string{"-security-opt"}, "Set a security option of the container\n'seccomp=/path/profile.json': filter the system calls of the container with this seccomp profile instead of the default one\n'seccomp=unconfined': do not filter them")
 	cmd.Var(&flEnv, []string{"e", "-env"}, "Set environment variables")
 	cmd.Var(&flEnvFile, []string{"-env-file"}, "Read in a line delimited file of environment variables")
 
@@ -90,11 +121,15 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 	cmd.Var(&flExpose, []string{"#expose", "-expose"}, "Expose a port from the container without publishing it to your host")
 	cmd.Var(&flDns, []string{"#dns", "-dns"}, "Set custom DNS servers")
 	cmd.Var(&flDnsSearch, []string{"-dns-search"}, "Set custom DNS search domains")
+	cmd.Var(&flExtraHosts, []string{"-add-host"}, "Add a custom host-to-IP mapping to /etc/hosts (host:ip)")
+	cmd.Var(&flServices, []string{"-service-alias"}, "Resolve this name to the container with the embedded DNS of the daemon, round-robin with the other containers of the name")
 	cmd.Var(&flVolumesFrom, []string{"#volumes-from", "-volumes-from"}, "Mount volumes from the specified container(s)")
 	cmd.Var(&flLxcOpts, []string{"#lxc-conf", "-lxc-conf"}, "(lxc exec-driver only) Add custom lxc options --lxc-conf=\"lxc.cgroup.cpuset.cpus = 0,1\"")
 
 	cmd.Var(&flCapAdd, []string{"-cap-add"}, "Add Linux capabilities")
 	cmd.Var(&flCapDrop, []string{"-cap-drop"}, "Drop Linux capabilities")
+	cmd.Var(&flPreStart, []string{"-pre-start"}, "Run a shell command on the host before each start of the container")
+	cmd.Var(&flPostStop, []string{"-post-stop"}, "Run a shell command on the host each time the container stops")
 
 	if err := cmd.Parse(args); err != nil {
 		return nil, nil, cmd, err
@@ -103,6 +138,20 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 	// Check if the kernel supports memory limit cgroup.
 	if sysInfo != nil && *flMemoryString != "" && !sysInfo.MemoryLimit {
 		*flMemoryString = ""
+		*flMemorySwap = ""
+	}
+	if sysInfo != nil && !sysInfo.CpuCfsQuota {
+		*flCpuQuota = 0
+		*flCpuPeriod = 0
+	}
+	if sysInfo != nil && !sysInfo.BlkioWeight {
+		*flBlkioWeight = 0
+	}
+	if sysInfo != nil && !sysInfo.MemorySwappiness {
+		*flSwappiness = -1
+	}
+	if sysInfo != nil && !sysInfo.OomKillDisable {
+		*flOomKillDisable = false
 	}
 
 	// Validate input params
@@ -124,6 +173,29 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 		return nil, nil, cmd, ErrConflictHostNetworkAndLinks
 	}
 
+	if strings.HasPrefix(*flNetMode, "container:") && flExtraHosts.Len() > 0 {
+		return nil, nil, cmd, ErrConflictContainerNetworkAndHosts
+	}
+
+	pidMode := PidMode(*flPidMode)
+	if !pidMode.Valid() {
+		return nil, nil, cmd, fmt.Errorf("--pid: invalid PID mode: %s", *flPidMode)
+	}
+	ipcMode := IpcMode(*flIpcMode)
+	if !ipcMode.Valid() {
+		return nil, nil, cmd, fmt.Errorf("--ipc: invalid IPC mode: %s", *flIpcMode)
+	}
+	utsMode := UTSMode(*flUTSMode)
+	if !utsMode.Valid() {
+		return nil, nil, cmd, fmt.Errorf("--uts: invalid UTS mode: %s", *flUTSMode)
+	}
+	if utsMode.IsHost() && *flHostname != "" {
+		return nil, nil, cmd, ErrConflictUTSHostname
+	}
+	if *flCgroupParent != "" && !ValidCgroupParent(*flCgroupParent) {
+		return nil, nil, cmd, fmt.Errorf("--cgroup-parent: invalid cgroup %s, it must be relative to the cgroup of the daemon", *flCgroupParent)
+	}
+
 	// If neither -d or -a are set, attach to everything by default
 	if flAttach.Len() == 0 && !*flDetach {
 		if !*flDetach {
@@ -144,6 +216,31 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 		flMemory = parsedMemory
 	}
 
+	var memorySwap int64
+	if *flMemorySwap == "-1" {
+		memorySwap = -1
+	} else if *flMemorySwap != "" {
+		parsedMemorySwap, err := units.RAMInBytes(*flMemorySwap)
+		if err != nil {
+			return nil, nil, cmd, err
+		}
+		if flMemory == 0 {
+			return nil, nil, cmd, ErrMemorySwapWithoutMemory
+		}
+		if parsedMemorySwap < flMemory {
+			return nil, nil, cmd, fmt.Errorf("Invalid --memory-swap: %s, it must be larger than the memory limit", *flMemorySwap)
+		}
+		memorySwap = parsedMemorySwap
+	}
+
+	var swappiness *int64
+	if *flSwappiness != -1 {
+		if *flSwappiness < 0 || *flSwappiness > 100 {
+			return nil, nil, cmd, fmt.Errorf("Invalid --memory-swappiness: %d, it must be between 0 and 100, or -1", *flSwappiness)
+		}
+		swappiness = flSwappiness
+	}
+
 	var binds []string
 	// add any bind targets to the list of container volumes
 	for bind := range flVolumes.GetMap() {
@@ -201,9 +298,17 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 		if strings.Contains(e, ":") {
 			return nil, nil, cmd, fmt.Errorf("Invalid port format for --expose: %s", e)
 		}
-		p := nat.NewPort(nat.SplitProtoPort(e))
-		if _, exists := ports[p]; !exists {
-			ports[p] = struct{}{}
+		// the port may be a range, as 8000-8100
+		proto, port := nat.SplitProtoPort(e)
+		start, end, err := parsers.ParsePortRange(port)
+		if err != nil {
+			return nil, nil, cmd, fmt.Errorf("Invalid range format for --expose: %s, error: %s", e, err)
+		}
+		for i := start; i <= end; i++ {
+			p := nat.NewPort(proto, strconv.FormatUint(i, 10))
+			if _, exists := ports[p]; !exists {
+				ports[p] = struct{}{}
+			}
 		}
 	}
 
@@ -217,6 +322,33 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 		deviceMappings = append(deviceMappings, deviceMapping)
 	}
 
+	tmpfs := make(map[string]string)
+	for _, t := range flTmpfs.GetAll() {
+		dest, options, err := ParseTmpfs(t)
+		if err != nil {
+			return nil, nil, cmd, err
+		}
+		tmpfs[dest] = options
+	}
+
+	var securityOpt []string
+	for _, opt := range flSecurityOpt.GetAll() {
+		parsed, err := ParseSecurityOpt(opt)
+		if err != nil {
+			return nil, nil, cmd, err
+		}
+		securityOpt = append(securityOpt, parsed)
+	}
+
+	var ulimits []*ulimit.Ulimit
+	for _, u := range flUlimits.GetAll() {
+		parsed, err := ulimit.Parse(u)
+		if err != nil {
+			return nil, nil, cmd, err
+		}
+		ulimits = append(ulimits, parsed)
+	}
+
 	// collect all the environment variables for the container
 	envVariables := []string{}
 	for _, ef := range flEnvFile.GetAll() {
@@ -244,26 +376,32 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 	}
 
 	config := &Config{
-		Hostname:        hostname,
-		Domainname:      domainname,
-		PortSpecs:       nil, // Deprecated
-		ExposedPorts:    ports,
-		User:            *flUser,
-		Tty:             *flTty,
-		NetworkDisabled: !*flNetwork,
-		OpenStdin:       *flStdin,
-		Memory:          flMemory,
-		CpuShares:       *flCpuShares,
-		Cpuset:          *flCpuset,
-		AttachStdin:     flAttach.Get("stdin"),
-		AttachStdout:    flAttach.Get("stdout"),
-		AttachStderr:    flAttach.Get("stderr"),
-		Env:             envVariables,
-		Cmd:             runCmd,
-		Image:           image,
-		Volumes:         flVolumes.GetMap(),
-		Entrypoint:      entrypoint,
-		WorkingDir:      *flWorkingDir,
+		Hostname:         hostname,
+		Domainname:       domainname,
+		PortSpecs:        nil, // Deprecated
+		ExposedPorts:     ports,
+		User:             *flUser,
+		Tty:              *flTty,
+		NetworkDisabled:  !*flNetwork,
+		OpenStdin:        *flStdin,
+		Memory:           flMemory,
+		MemorySwap:       memorySwap,
+		CpuShares:        *flCpuShares,
+		CpuQuota:         *flCpuQuota,
+		CpuPeriod:        *flCpuPeriod,
+		Cpuset:           *flCpuset,
+		BlkioWeight:      *flBlkioWeight,
+		MemorySwappiness: swappiness,
+		OomKillDisable:   *flOomKillDisable,
+		AttachStdin:      flAttach.Get("stdin"),
+		AttachStdout:     flAttach.Get("stdout"),
+		AttachStderr:     flAttach.Get("stderr"),
+		Env:              envVariables,
+		Cmd:              runCmd,
+		Image:            image,
+		Volumes:          flVolumes.GetMap(),
+		Entrypoint:       entrypoint,
+		WorkingDir:       *flWorkingDir,
 	}
 
 	hostConfig := &HostConfig{
@@ -276,12 +414,25 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 		PublishAllPorts: *flPublishAll,
 		Dns:             flDns.GetAll(),
 		DnsSearch:       flDnsSearch.GetAll(),
+		ExtraHosts:      flExtraHosts.GetAll(),
 		VolumesFrom:     flVolumesFrom.GetAll(),
 		NetworkMode:     netMode,
+		PidMode:         pidMode,
+		IpcMode:         ipcMode,
+		UTSMode:         utsMode,
 		Devices:         deviceMappings,
 		CapAdd:          flCapAdd.GetAll(),
 		CapDrop:         flCapDrop.GetAll(),
 		RestartPolicy:   restartPolicy,
+		VolumeDriver:    *flVolumeDriver,
+		ReadonlyRootfs:  *flReadonlyRootfs,
+		Ulimits:         ulimits,
+		Tmpfs:           tmpfs,
+		SecurityOpt:     securityOpt,
+		PreStart:        flPreStart.GetAll(),
+		PostStop:        flPostStop.GetAll(),
+		CgroupParent:    *flCgroupParent,
+		ServiceAliases:  flServices.GetAll(),
 	}
 
 	if sysInfo != nil && flMemory > 0 && !sysInfo.SwapLimit {
@@ -381,6 +532,9 @@ func parseNetMode(netMode string) (NetworkMode, error) {
 	return NetworkMode(netMode), nil
 }
 
+// ParseDevice parses a device of the host given to a container as
+// SRC[:DST][:PERMISSIONS], with the cgroup permissions r, w and m of the
+// container on it, all of them by default.
 func ParseDevice(device string) (DeviceMapping, error) {
 	src := ""
 	dst := ""
@@ -391,7 +545,12 @@ func ParseDevice(device string) (DeviceMapping, error) {
 		permissions = arr[2]
 		fallthrough
 	case 2:
-		dst = arr[1]
+		// SRC:PERMISSIONS, as the devices are at absolute paths
+		if len(arr) == 2 && ValidDevicePermissions(arr[1]) {
+			permissions = arr[1]
+		} else {
+			dst = arr[1]
+		}
 		fallthrough
 	case 1:
 		src = arr[0]
@@ -402,6 +561,13 @@ func ParseDevice(device string) (DeviceMapping, error) {
 	if dst == "" {
 		dst = src
 	}
+	if !path.IsAbs(src) || !path.IsAbs(dst) {
+		return DeviceMapping{}, fmt.Errorf("Invalid device specification: %s, the paths of the device must be absolute", device)
+	}
+	if !ValidDevicePermissions(permissions) {
+		return DeviceMapping{}, fmt.Errorf("Invalid device permissions: %s, use a combination of r (read), w (write) and m (mknod)", permissions)
+	}
+	src, dst = path.Clean(src), path.Clean(dst)
 
 	deviceMapping := DeviceMapping{
 		PathOnHost:        src,
@@ -410,3 +576,77 @@ func ParseDevice(device string) (DeviceMapping, error) {
 	}
 	return deviceMapping, nil
 }
+
+// ValidDevicePermissions returns whether permissions are cgroup permissions
+// on a device: r, w and m, each at most once.
+func ValidDevicePermissions(permissions string) bool {
+	if permissions == "" {
+		return false
+	}
+	for i, c := range permissions {
+		if !strings.ContainsRune("rwm", c) || strings.ContainsRune(permissions[i+1:], c) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidCgroupParent returns whether parent can hold the cgroups of the
+// containers: a path relative to the cgroup of the daemon, which does not
+// leave it.
+func ValidCgroupParent(parent string) bool {
+	if parent == "" || path.IsAbs(parent) {
+		return false
+	}
+	for _, part := range strings.Split(path.Clean(parent), "/") {
+		if part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseTmpfs parses a tmpfs mount given as PATH[:OPTIONS]. The default
+// options are used when none are given.
+func ParseTmpfs(tmpfs string) (string, string, error) {
+	var (
+		parts   = strings.SplitN(tmpfs, ":", 2)
+		dest    = path.Clean(parts[0])
+		options = DefaultTmpfsOptions
+	)
+	if !path.IsAbs(dest) || dest == "/" {
+		return "", "", fmt.Errorf("Invalid tmpfs specification: %s, the path must be absolute and cannot be /", tmpfs)
+	}
+	if len(parts) == 2 {
+		if parts[1] == "" {
+			return "", "", fmt.Errorf("Invalid tmpfs specification: %s", tmpfs)
+		}
+		options = parts[1]
+	}
+	return dest, options, nil
+}
+
+// ParseSecurityOpt parses a security option given as KEY=VALUE. The only
+// key is seccomp, with unconfined or the path of a profile as value. The
+// profile is read, and the option returned with its content in place of
+// its path, as the daemon may not run on the same host.
+func ParseSecurityOpt(opt string) (string, error) {
+	parts := strings.SplitN(opt, "=", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return "", fmt.Errorf("Invalid security option: %s, expected KEY=VALUE", opt)
+	}
+	if parts[0] != "seccomp" {
+		return "", fmt.Errorf("Invalid security option: %s, the only option is seccomp", opt)
+	}
+	if parts[1] == "unconfined" {
+		return opt, nil
+	}
+	data, err := ioutil.ReadFile(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("Error reading the seccomp profile: %s", err)
+	}
+	if _, err := seccomp.ParseProfile(data); err != nil {
+		return "", err
+	}
+	return "seccomp=" + string(data), nil
+}
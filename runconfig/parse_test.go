@@ -1,8 +1,12 @@
 package runconfig
 
 import (
+	"io/ioutil"
+	"os"
+	"reflect"
 	"testing"
 
+	"github.com/docker/docker/nat"
 	"github.com/docker/docker/pkg/parsers"
 )
 
@@ -48,3 +52,298 @@ func TestNetHostname(t *testing.T) {
 		t.Fatalf("Expected error ErrConflictNetworkHostname, got: %s", err)
 	}
 }
+
+func TestParseTmpfs(t *testing.T) {
+	tests := map[string][2]string{
+		"/run":                    {"/run", DefaultTmpfsOptions},
+		"/run/":                   {"/run", DefaultTmpfsOptions},
+		"/tmp:rw,size=64m":        {"/tmp", "rw,size=64m"},
+		"/var/cache:ro,mode=1777": {"/var/cache", "ro,mode=1777"},
+	}
+	for spec, expected := range tests {
+		dest, options, err := ParseTmpfs(spec)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %s", spec, err)
+		}
+		if dest != expected[0] || options != expected[1] {
+			t.Fatalf("Expected %s to be parsed as %v, got %s %s", spec, expected, dest, options)
+		}
+	}
+
+	for _, invalid := range []string{"run", "/", "/run:", ""} {
+		if _, _, err := ParseTmpfs(invalid); err == nil {
+			t.Fatalf("Expected an error for tmpfs %q", invalid)
+		}
+	}
+}
+
+func TestParseSecurityOpt(t *testing.T) {
+	f, err := ioutil.TempFile("", "seccomp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	profile := `{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"name": "mkdir", "action": "SCMP_ACT_ERRNO"}]}`
+	if _, err := f.WriteString(profile); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	if opt, err := ParseSecurityOpt("seccomp=unconfined"); err != nil || opt != "seccomp=unconfined" {
+		t.Fatalf("Expected seccomp=unconfined, got %s, %v", opt, err)
+	}
+	if opt, err := ParseSecurityOpt("seccomp=" + f.Name()); err != nil || opt != "seccomp="+profile {
+		t.Fatalf("Expected the profile of %s, got %s, %v", f.Name(), opt, err)
+	}
+	for _, invalid := range []string{"seccomp", "seccomp=", "apparmor=unconfined", "seccomp=/no/such/profile.json"} {
+		if _, err := ParseSecurityOpt(invalid); err == nil {
+			t.Fatalf("Expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestParseUlimits(t *testing.T) {
+	_, hostConfig, _, err := Parse([]string{"--ulimit", "nofile=1024:2048", "--ulimit", "nproc=512", "img", "cmd"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hostConfig.Ulimits) != 2 {
+		t.Fatalf("Expected 2 ulimits, got %d", len(hostConfig.Ulimits))
+	}
+	if u := hostConfig.Ulimits[0]; u.Name != "nofile" || u.Soft != 1024 || u.Hard != 2048 {
+		t.Fatalf("Unexpected ulimit %s", u)
+	}
+	if u := hostConfig.Ulimits[1]; u.Name != "nproc" || u.Soft != 512 || u.Hard != 512 {
+		t.Fatalf("Unexpected ulimit %s", u)
+	}
+
+	if _, _, _, err := Parse([]string{"--ulimit", "nofile=2048:1024", "img", "cmd"}, nil); err == nil {
+		t.Fatal("Expected an error for a soft limit above the hard limit")
+	}
+}
+
+func TestParsePortRanges(t *testing.T) {
+	config, hostConfig, _, err := Parse([]string{"-p", "8000-8002:9000-9002", "--expose", "7000-7001/udp", "img", "cmd"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.ExposedPorts) != 5 {
+		t.Fatalf("Expected 5 exposed ports, got %v", config.ExposedPorts)
+	}
+	for _, port := range []nat.Port{"9000/tcp", "9001/tcp", "9002/tcp", "7000/udp", "7001/udp"} {
+		if _, exists := config.ExposedPorts[port]; !exists {
+			t.Fatalf("Expected %s to be exposed, got %v", port, config.ExposedPorts)
+		}
+	}
+	if b := hostConfig.PortBindings["9001/tcp"]; len(b) != 1 || b[0].HostPort != "8001" {
+		t.Fatalf("Expected 9001/tcp to be published on 8001, got %v", b)
+	}
+
+	if _, _, _, err := Parse([]string{"--expose", "7001-7000", "img", "cmd"}, nil); err == nil {
+		t.Fatal("Expected an error for an invalid range")
+	}
+}
+
+func TestParseMemorySwap(t *testing.T) {
+	config, _, _, err := Parse([]string{"-m", "64m", "--memory-swap", "128m", "img", "cmd"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Memory != 64*1024*1024 || config.MemorySwap != 128*1024*1024 {
+		t.Fatalf("Unexpected memory limits %d and %d", config.Memory, config.MemorySwap)
+	}
+
+	if config, _, _, err = Parse([]string{"-m", "64m", "--memory-swap", "-1", "img", "cmd"}, nil); err != nil {
+		t.Fatal(err)
+	} else if config.MemorySwap != -1 {
+		t.Fatalf("Expected the swap to be disabled, got %d", config.MemorySwap)
+	}
+
+	if _, _, _, err := Parse([]string{"--memory-swap", "128m", "img", "cmd"}, nil); err != ErrMemorySwapWithoutMemory {
+		t.Fatalf("Expected %q, got %v", ErrMemorySwapWithoutMemory, err)
+	}
+	if _, _, _, err := Parse([]string{"-m", "64m", "--memory-swap", "32m", "img", "cmd"}, nil); err == nil {
+		t.Fatal("Expected an error for a swap limit below the memory limit")
+	}
+}
+
+func TestParseMemorySwappiness(t *testing.T) {
+	config, _, _, err := Parse([]string{"img", "cmd"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.MemorySwappiness != nil || config.OomKillDisable {
+		t.Fatalf("Expected the swappiness and the OOM killer of the host, got %v and %v", config.MemorySwappiness, config.OomKillDisable)
+	}
+
+	config, _, _, err = Parse([]string{"-m", "64m", "--memory-swappiness", "0", "--oom-kill-disable", "img", "cmd"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.MemorySwappiness == nil || *config.MemorySwappiness != 0 {
+		t.Fatalf("Expected a swappiness of 0, got %v", config.MemorySwappiness)
+	}
+	if !config.OomKillDisable {
+		t.Fatal("Expected the OOM killer to be disabled")
+	}
+
+	for _, invalid := range []string{"-2", "101"} {
+		if _, _, _, err := Parse([]string{"--memory-swappiness", invalid, "img", "cmd"}, nil); err == nil {
+			t.Fatalf("Expected an error for the swappiness %s", invalid)
+		}
+	}
+}
+
+func TestFieldFlags(t *testing.T) {
+	_, _, cmd, err := Parse([]string{"img"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withoutFlag := map[string]bool{
+		"Config.PortSpecs": true,
+		"Config.Cmd":       true,
+		"Config.Image":     true,
+		"Config.OnBuild":   true,
+	}
+	fields := 0
+	for _, v := range []interface{}{Config{}, HostConfig{}} {
+		typ := reflect.TypeOf(v)
+		for i := 0; i < typ.NumField(); i++ {
+			name := typ.Name() + "." + typ.Field(i).Name
+			flags, exists := FieldFlags[name]
+			if !exists {
+				t.Errorf("%s has no flag in FieldFlags", name)
+				continue
+			}
+			fields++
+			if len(flags) == 0 && !withoutFlag[name] {
+				t.Errorf("%s is not set by any flag", name)
+			}
+			for _, f := range flags {
+				if cmd.Lookup(f) == nil {
+					t.Errorf("%s is set by an undefined flag: %s", name, f)
+				}
+			}
+		}
+	}
+	if fields != len(FieldFlags) {
+		t.Errorf("FieldFlags has %d entries for %d fields", len(FieldFlags), fields)
+	}
+}
+
+func TestParseExtraHosts(t *testing.T) {
+	_, hostConfig, _, err := Parse([]string{"--add-host", "db:10.0.0.2", "--add-host", "ipv6:2001:db8::1", "img", "cmd"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hostConfig.ExtraHosts) != 2 || hostConfig.ExtraHosts[0] != "db:10.0.0.2" || hostConfig.ExtraHosts[1] != "ipv6:2001:db8::1" {
+		t.Fatalf("Unexpected extra hosts %v", hostConfig.ExtraHosts)
+	}
+
+	if _, _, _, err := Parse([]string{"--add-host", "db", "img", "cmd"}, nil); err == nil {
+		t.Fatal("Expected an error for an entry without an IP")
+	}
+	if _, _, _, err := Parse([]string{"--net=container:other", "--add-host", "db:10.0.0.2", "img", "cmd"}, nil); err != ErrConflictContainerNetworkAndHosts {
+		t.Fatalf("Expected %q, got %v", ErrConflictContainerNetworkAndHosts, err)
+	}
+}
+
+func TestParseServiceAliases(t *testing.T) {
+	_, hostConfig, _, err := Parse([]string{"--service-alias", "Web", "--service-alias", "api.local.", "img", "cmd"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hostConfig.ServiceAliases) != 2 || hostConfig.ServiceAliases[0] != "web" || hostConfig.ServiceAliases[1] != "api.local" {
+		t.Fatalf("Unexpected service aliases %v", hostConfig.ServiceAliases)
+	}
+
+	if _, _, _, err := Parse([]string{"--service-alias", "-web", "img", "cmd"}, nil); err == nil {
+		t.Fatal("Expected an error for an invalid service alias")
+	}
+}
+
+func TestParseNamespaceModes(t *testing.T) {
+	_, hostConfig, _, err := Parse([]string{"--pid=host", "--uts=host", "img", "cmd"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hostConfig.PidMode.IsHost() || !hostConfig.UTSMode.IsHost() {
+		t.Fatalf("Expected the host namespaces, got %q and %q", hostConfig.PidMode, hostConfig.UTSMode)
+	}
+
+	if _, hostConfig, _, _ := Parse([]string{"img", "cmd"}, nil); hostConfig.PidMode != "" || hostConfig.UTSMode != "" {
+		t.Fatalf("Expected the namespaces of the container, got %q and %q", hostConfig.PidMode, hostConfig.UTSMode)
+	}
+	_, hostConfig, _, err = Parse([]string{"--pid=container:db", "--ipc=container:db", "img", "cmd"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hostConfig.PidMode.Container() != "db" || hostConfig.IpcMode.Container() != "db" {
+		t.Fatalf("Expected the namespaces of db, got %q and %q", hostConfig.PidMode, hostConfig.IpcMode)
+	}
+	if _, _, _, err := Parse([]string{"--pid=container:", "img", "cmd"}, nil); err == nil {
+		t.Fatal("Expected an error for an invalid PID mode")
+	}
+	if _, _, _, err := Parse([]string{"--ipc=other", "img", "cmd"}, nil); err == nil {
+		t.Fatal("Expected an error for an invalid IPC mode")
+	}
+	if _, _, _, err := Parse([]string{"--uts=other", "img", "cmd"}, nil); err == nil {
+		t.Fatal("Expected an error for an invalid UTS mode")
+	}
+	if _, _, _, err := Parse([]string{"-h=name", "--uts=host", "img", "cmd"}, nil); err != ErrConflictUTSHostname {
+		t.Fatalf("Expected %q, got %v", ErrConflictUTSHostname, err)
+	}
+}
+
+func TestParseDevice(t *testing.T) {
+	tests := map[string]DeviceMapping{
+		"/dev/snd":                 {"/dev/snd", "/dev/snd", "rwm"},
+		"/dev/sdc:/dev/xvdc":       {"/dev/sdc", "/dev/xvdc", "rwm"},
+		"/dev/sdc:/dev/xvdc:r":     {"/dev/sdc", "/dev/xvdc", "r"},
+		"/dev/snd:rw":              {"/dev/snd", "/dev/snd", "rw"},
+		"/dev/fuse/:/dev/fuse:mrw": {"/dev/fuse", "/dev/fuse", "mrw"},
+		"/dev/nvidia0::wr":         {"/dev/nvidia0", "/dev/nvidia0", "wr"},
+	}
+	for spec, expected := range tests {
+		device, err := ParseDevice(spec)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %s", spec, err)
+		}
+		if device != expected {
+			t.Fatalf("Expected %s to be parsed as %v, got %v", spec, expected, device)
+		}
+	}
+
+	for _, invalid := range []string{"snd", "/dev/snd:snd", "/dev/sdc:/dev/xvdc:rwx", "/dev/sdc:/dev/xvdc:rr", "/dev/sdc:/dev/xvdc:", "/a:/b:rw:m"} {
+		if _, err := ParseDevice(invalid); err == nil {
+			t.Fatalf("Expected an error for device %q", invalid)
+		}
+	}
+}
+
+func TestParseCgroupParent(t *testing.T) {
+	_, hostConfig, _, err := Parse([]string{"--cgroup-parent=web.slice", "img", "cmd"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hostConfig.CgroupParent != "web.slice" {
+		t.Fatalf("Expected the cgroup parent web.slice, got %q", hostConfig.CgroupParent)
+	}
+
+	for _, valid := range []string{"web", "tenants/a", "./web", "web/../api"} {
+		if !ValidCgroupParent(valid) {
+			t.Fatalf("Expected %q to be a valid cgroup parent", valid)
+		}
+	}
+	for _, invalid := range []string{"", "/", "/sys/fs/cgroup", "..", "../web", "web/../.."} {
+		if ValidCgroupParent(invalid) {
+			t.Fatalf("Expected %q to be an invalid cgroup parent", invalid)
+		}
+		if invalid == "" {
+			continue
+		}
+		if _, _, _, err := Parse([]string{"--cgroup-parent=" + invalid, "img", "cmd"}, nil); err == nil {
+			t.Fatalf("Expected an error for the cgroup parent %q", invalid)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/docker/docker/pkg/term"
+	"github.com/docker/docker/pkg/timeutils"
 	"github.com/docker/docker/pkg/units"
 )
 
@@ -72,16 +73,69 @@ func (p *JSONProgress) String() string {
 	return pbBox + numbersBox + timeLeftBox
 }
 
+// plainString returns the progress without the bar nor the time left, for
+// the outputs which are not terminals.
+func (p *JSONProgress) plainString() string {
+	if p.Current <= 0 && p.Total <= 0 {
+		return ""
+	}
+	current := units.HumanSize(int64(p.Current))
+	if p.Total <= 0 {
+		return current
+	}
+	return fmt.Sprintf("%v/%v", current, units.HumanSize(int64(p.Total)))
+}
+
+// JSONBuildStep describes a step of a build once it succeeded.
+type JSONBuildStep struct {
+	Step        string        `json:"step"`
+	Instruction string        `json:"instruction"`
+	Cached      bool          `json:"cached"`
+	ImageID     string        `json:"imageId,omitempty"`
+	Duration    time.Duration `json:"duration"` // in nanoseconds
+}
+
 type JSONMessage struct {
-	Stream          string        `json:"stream,omitempty"`
-	Status          string        `json:"status,omitempty"`
-	Progress        *JSONProgress `json:"progressDetail,omitempty"`
-	ProgressMessage string        `json:"progress,omitempty"` //deprecated
-	ID              string        `json:"id,omitempty"`
-	From            string        `json:"from,omitempty"`
-	Time            int64         `json:"time,omitempty"`
-	Error           *JSONError    `json:"errorDetail,omitempty"`
-	ErrorMessage    string        `json:"error,omitempty"` //deprecated
+	Stream          string         `json:"stream,omitempty"`
+	Status          string         `json:"status,omitempty"`
+	Progress        *JSONProgress  `json:"progressDetail,omitempty"`
+	ProgressMessage string         `json:"progress,omitempty"` //deprecated
+	ID              string         `json:"id,omitempty"`
+	From            string         `json:"from,omitempty"`
+	Time            int64          `json:"time,omitempty"`
+	Error           *JSONError     `json:"errorDetail,omitempty"`
+	ErrorMessage    string         `json:"error,omitempty"` //deprecated
+	BuildStep       *JSONBuildStep `json:"buildStep,omitempty"`
+}
+
+// UnmarshalJSON decodes a message whose time is a unix timestamp or, as in
+// the events of the API since 1.15, an RFC 3339 date.
+func (jm *JSONMessage) UnmarshalJSON(data []byte) error {
+	type message JSONMessage
+	var aux struct {
+		*message
+		Time json.RawMessage `json:"time,omitempty"`
+	}
+	aux.message = (*message)(jm)
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if len(aux.Time) == 0 || string(aux.Time) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(aux.Time, &jm.Time); err == nil {
+		return nil
+	}
+	var value string
+	if err := json.Unmarshal(aux.Time, &value); err != nil {
+		return err
+	}
+	t, err := timeutils.Parse(value, time.UTC)
+	if err != nil {
+		return err
+	}
+	jm.Time = t.Unix()
+	return nil
 }
 
 func (jm *JSONMessage) Display(out io.Writer, isTerminal bool) error {
@@ -91,6 +145,10 @@ func (jm *JSONMessage) Display(out io.Writer, isTerminal bool) error {
 		}
 		return jm.Error
 	}
+	// the steps of a build are already displayed from the stream
+	if jm.BuildStep != nil {
+		return nil
+	}
 	var endl string
 	if isTerminal && jm.Stream == "" && jm.Progress != nil {
 		// <ESC>[2K = erase entire current line
@@ -120,11 +178,55 @@ func (jm *JSONMessage) Display(out io.Writer, isTerminal bool) error {
 	return nil
 }
 
+// displayPlain writes jm as a timestamped line without escape sequences.
+// A progress update is only written when its status changes or every 10
+// percent, printed keeps what was last written for each ID.
+func (jm *JSONMessage) displayPlain(out io.Writer, printed map[string]string) error {
+	if jm.Error != nil || jm.BuildStep != nil || jm.Stream != "" {
+		return jm.Display(out, false)
+	}
+	detail := jm.ProgressMessage
+	if jm.Progress != nil {
+		detail = jm.Progress.plainString()
+	}
+	if jm.ID != "" && (jm.Progress != nil || jm.ProgressMessage != "") {
+		key := jm.Status
+		if jm.Progress != nil && jm.Progress.Total > 0 {
+			key += fmt.Sprintf(" %d", jm.Progress.Current*10/jm.Progress.Total)
+		}
+		if printed[jm.ID] == key {
+			return nil
+		}
+		printed[jm.ID] = key
+	}
+	t := time.Now()
+	if jm.Time != 0 {
+		t = time.Unix(jm.Time, 0)
+	}
+	fmt.Fprintf(out, "%s ", t.Format(time.RFC3339))
+	if jm.ID != "" {
+		fmt.Fprintf(out, "%s: ", jm.ID)
+	}
+	if jm.From != "" {
+		fmt.Fprintf(out, "(from %s) ", jm.From)
+	}
+	if detail != "" {
+		fmt.Fprintf(out, "%s %s\n", jm.Status, detail)
+	} else {
+		fmt.Fprintf(out, "%s\n", jm.Status)
+	}
+	return nil
+}
+
+// DisplayJSONMessagesStream displays the messages of in on out. On a
+// terminal the progress bars are updated in place, otherwise each update is
+// written on its own line.
 func DisplayJSONMessagesStream(in io.Reader, out io.Writer, terminalFd uintptr, isTerminal bool) error {
 	var (
-		dec  = json.NewDecoder(in)
-		ids  = make(map[string]int)
-		diff = 0
+		dec     = json.NewDecoder(in)
+		ids     = make(map[string]int)
+		printed = make(map[string]string)
+		diff    = 0
 	)
 	for {
 		var jm JSONMessage
@@ -135,6 +237,12 @@ func DisplayJSONMessagesStream(in io.Reader, out io.Writer, terminalFd uintptr,
 			return err
 		}
 
+		if !isTerminal {
+			if err := jm.displayPlain(out, printed); err != nil {
+				return err
+			}
+			continue
+		}
 		if jm.Progress != nil {
 			jm.Progress.terminalFd = terminalFd
 		}
@@ -1,6 +1,9 @@
 package utils
 
 import (
+	"bytes"
+	"encoding/json"
+	"strings"
 	"testing"
 )
 
@@ -36,3 +39,69 @@ func TestProgress(t *testing.T) {
 		t.Fatalf("Expected %q, got %q", expected, jp4.String())
 	}
 }
+
+func TestDisplayBuildStep(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	jm := JSONMessage{BuildStep: &JSONBuildStep{Step: "0", Instruction: "FROM"}}
+	if err := jm.Display(out, false); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("Expected the build step not to be displayed, got %q", out.String())
+	}
+}
+
+func TestJSONMessageTime(t *testing.T) {
+	for _, data := range []string{
+		`{"status":"start","time":1409598000}`,
+		`{"status":"start","time":"2014-09-01T19:00:00.000000000Z"}`,
+		`{"status":"start","time":"2014-09-01T21:00:00+02:00"}`,
+	} {
+		var jm JSONMessage
+		if err := json.Unmarshal([]byte(data), &jm); err != nil {
+			t.Fatalf("%s: %s", data, err)
+		}
+		if jm.Status != "start" || jm.Time != 1409598000 {
+			t.Fatalf("%s: got %q at %d", data, jm.Status, jm.Time)
+		}
+	}
+	var jm JSONMessage
+	if err := json.Unmarshal([]byte(`{"time":"yesterday"}`), &jm); err == nil {
+		t.Fatal("Expected an error for an invalid time")
+	}
+}
+
+func TestDisplayPlainJSONMessagesStream(t *testing.T) {
+	in := strings.NewReader(`{"status":"Pulling fs layer","id":"abc"}
+{"status":"Downloading","progressDetail":{"current":1,"total":100},"id":"abc"}
+{"status":"Downloading","progressDetail":{"current":5,"total":100},"id":"abc"}
+{"status":"Downloading","progressDetail":{"current":50,"total":100},"id":"abc"}
+{"status":"Download complete","progressDetail":{},"id":"abc"}
+{"status":"Status: done"}
+`)
+	out := bytes.NewBuffer(nil)
+	if err := DisplayJSONMessagesStream(in, out, 0, false); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "\x1b") || strings.Contains(out.String(), "\r") {
+		t.Fatalf("Expected no escape sequences, got %q", out.String())
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	expected := []string{
+		"abc: Pulling fs layer",
+		"abc: Downloading 1 B/100 B",
+		"abc: Downloading 50 B/100 B",
+		"abc: Download complete",
+		"Status: done",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d lines, got %q", len(expected), lines)
+	}
+	for i, line := range lines {
+		// each line starts with a timestamp
+		parts := strings.SplitN(line, " ", 2)
+		if len(parts) != 2 || parts[1] != expected[i] {
+			t.Fatalf("Expected %q after the timestamp, got %q", expected[i], line)
+		}
+	}
+}
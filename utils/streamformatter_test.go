@@ -65,3 +65,14 @@ func TestFormatProgress(t *testing.T) {
 		t.Fatal("Original progress not equals progress from FormatProgress")
 	}
 }
+
+func TestFormatBuildStep(t *testing.T) {
+	step := &JSONBuildStep{Step: "1", Instruction: "RUN", Cached: true, ImageID: "abc", Duration: 1500}
+	if res := NewStreamFormatter(false).FormatBuildStep(step); res != nil {
+		t.Fatalf("Expected no build step record without json, got %q", res)
+	}
+	res := NewStreamFormatter(true).FormatBuildStep(step)
+	if string(res) != `{"buildStep":{"step":"1","instruction":"RUN","cached":true,"imageId":"abc","duration":1500}}`+"\r\n" {
+		t.Fatalf("%q", res)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
@@ -390,6 +391,29 @@ func IsGIT(str string) bool {
 	return strings.HasPrefix(str, "git://") || strings.HasPrefix(str, "github.com/") || strings.HasPrefix(str, "git@github.com:") || (strings.HasSuffix(str, ".git") && IsURL(str))
 }
 
+// BuildContextImagePrefix marks the source of an additional build context
+// which is an image.
+const BuildContextImagePrefix = "docker-image://"
+
+var validBuildContextName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// ParseBuildContext parses an additional context of a build, as NAME=SOURCE,
+// where SOURCE is a directory, the URL of a tarball, a git repository or an
+// image as docker-image://IMAGE.
+func ParseBuildContext(val string) (name string, source string, err error) {
+	parts := strings.SplitN(val, "=", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return "", "", fmt.Errorf("Invalid build context %s: expected NAME=SOURCE", val)
+	}
+	if !validBuildContextName.MatchString(parts[0]) {
+		return "", "", fmt.Errorf("Invalid build context name %s: only [a-zA-Z0-9_.-] are allowed", parts[0])
+	}
+	if parts[1] == BuildContextImagePrefix {
+		return "", "", fmt.Errorf("Invalid build context %s: no image given", val)
+	}
+	return parts[0], parts[1], nil
+}
+
 // CheckLocalDns looks into the /etc/resolv.conf,
 // it returns true if there is a local nameserver or if there is no nameserver.
 func CheckLocalDns(resolvConf []byte) bool {
@@ -689,3 +713,57 @@ func Matches(relFilePath string, patterns []string) (bool, error) {
 	}
 	return false, nil
 }
+
+// ReadDockerignore reads the exclusion patterns of a .dockerignore file.
+// Blank lines and lines starting with # are skipped, and the patterns are
+// cleaned so that "/tmp/" and "./tmp" both match the tmp directory.
+func ReadDockerignore(r io.Reader) ([]string, error) {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var excludes []string
+	for _, pattern := range strings.Split(string(content), "\n") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" || strings.HasPrefix(pattern, "#") {
+			continue
+		}
+		pattern = strings.TrimPrefix(filepath.Clean(pattern), "/")
+		if pattern == "" || pattern == "." {
+			continue
+		}
+		if _, err := filepath.Match(pattern, "Dockerfile"); err != nil {
+			return nil, fmt.Errorf("Bad .dockerignore pattern: '%s', error: %s", pattern, err)
+		}
+		excludes = append(excludes, pattern)
+	}
+	return excludes, nil
+}
+
+// ContextTarSize estimates the size of the tar archive of the files of
+// srcPath which do not match excludes: a 512 bytes header per entry, the
+// content of the regular files padded to 512 bytes and two final blocks.
+func ContextTarSize(srcPath string, excludes []string) int64 {
+	size := int64(1024)
+	filepath.Walk(srcPath, func(filePath string, f os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		relFilePath, err := filepath.Rel(srcPath, filePath)
+		if err != nil {
+			return nil
+		}
+		if skip, _ := Matches(relFilePath, excludes); skip {
+			if f.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		size += 512
+		if f.Mode().IsRegular() {
+			size += (f.Size() + 511) / 512 * 512
+		}
+		return nil
+	})
+	return size
+}
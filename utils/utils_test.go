@@ -155,3 +155,46 @@ func TestReadSymlinkedDirectoryToFile(t *testing.T) {
 		t.Errorf("failed to remove symlink: %s", err)
 	}
 }
+
+func TestReadDockerignore(t *testing.T) {
+	excludes, err := ReadDockerignore(bytes.NewBufferString("# editor files\n*.swp\n\n  .git  \n/tmp/\n./build\n/\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"*.swp", ".git", "tmp", "build"}
+	if len(excludes) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, excludes)
+	}
+	for i := range expected {
+		if excludes[i] != expected[i] {
+			t.Fatalf("Expected %v, got %v", expected, excludes)
+		}
+	}
+
+	if _, err := ReadDockerignore(bytes.NewBufferString("[")); err == nil {
+		t.Fatal("Expected an error for a bad pattern")
+	}
+}
+
+func TestParseBuildContext(t *testing.T) {
+	valid := map[string][2]string{
+		"lib=../lib":                          {"lib", "../lib"},
+		"base=docker-image://busybox:latest":  {"base", "docker-image://busybox:latest"},
+		"src_1.x=https://example.com/src.tgz": {"src_1.x", "https://example.com/src.tgz"},
+		"a=b=c":                               {"a", "b=c"},
+	}
+	for val, expected := range valid {
+		name, source, err := ParseBuildContext(val)
+		if err != nil {
+			t.Fatalf("%s: %s", val, err)
+		}
+		if name != expected[0] || source != expected[1] {
+			t.Fatalf("%s: expected %v, got %s and %s", val, expected, name, source)
+		}
+	}
+	for _, val := range []string{"", "lib", "lib=", "=../lib", "-lib=../lib", "l/ib=../lib", "base=docker-image://"} {
+		if _, _, err := ParseBuildContext(val); err == nil {
+			t.Fatalf("Expected an error for %q", val)
+		}
+	}
+}